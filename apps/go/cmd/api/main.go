@@ -14,6 +14,7 @@ import (
 	"pipelogiq/internal/db"
 	"pipelogiq/internal/logger"
 	"pipelogiq/internal/mq"
+	"pipelogiq/internal/ratelimit"
 	"pipelogiq/internal/store"
 	"pipelogiq/internal/telemetry"
 )
@@ -28,7 +29,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	logg := logger.New(cfg.LogLevel)
+	logg := logger.New(cfg.LogLevel, cfg.LogFormat, "pipelogiq-api", cfg.AppID)
 	otelShutdown, err := telemetry.Init(ctx, "pipelogiq-api", logg)
 	if err != nil {
 		logg.Error("opentelemetry init failed", "err", err)
@@ -50,6 +51,13 @@ func main() {
 	defer dbConn.Close()
 
 	mqClient := mq.NewClient(cfg.RabbitURL, logg)
+	mqClient.SetReconnectConfig(mq.ReconnectConfig{
+		InitialInterval: cfg.Reconnect.InitialInterval,
+		MaxInterval:     cfg.Reconnect.MaxInterval,
+		MaxElapsedTime:  cfg.Reconnect.MaxElapsedTime,
+		ReconnectDelay:  cfg.Reconnect.Delay,
+		Jitter:          cfg.Reconnect.Jitter,
+	})
 	defer mqClient.Close()
 
 	st := store.New(dbConn, logg)
@@ -59,6 +67,13 @@ func main() {
 
 	// External API (API-key auth, for SDK clients and workers)
 	externalServer := api.NewExternalServer(cfg, st, mqClient, logg)
+	externalServer.AttachPolicies(internalServer)
+	limiterStore, err := ratelimit.New(cfg.RateLimitStore, cfg.RedisURL)
+	if err != nil {
+		logg.Error("rate limit store init failed", "err", err)
+		os.Exit(1)
+	}
+	externalServer.SetLimiterStore(limiterStore)
 
 	errCh := make(chan error, 2)
 	go func() {
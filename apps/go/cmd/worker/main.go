@@ -9,12 +9,17 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+
 	"pipelogiq/internal/alerts"
 	"pipelogiq/internal/config"
+	"pipelogiq/internal/datadog"
 	"pipelogiq/internal/db"
 	"pipelogiq/internal/logger"
 	"pipelogiq/internal/mq"
 	observabilityrepo "pipelogiq/internal/observability/repo"
+	"pipelogiq/internal/ratelimit"
+	"pipelogiq/internal/sentry"
 	"pipelogiq/internal/store"
 	"pipelogiq/internal/telemetry"
 	"pipelogiq/internal/worker"
@@ -30,7 +35,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	logg := logger.New(cfg.LogLevel)
+	logg := logger.New(cfg.LogLevel, cfg.LogFormat, "pipelogiq-worker", cfg.AppID)
 	otelShutdown, err := telemetry.Init(ctx, "pipelogiq-worker", logg)
 	if err != nil {
 		logg.Error("opentelemetry init failed", "err", err)
@@ -52,12 +57,34 @@ func main() {
 	defer dbConn.Close()
 
 	mqClient := mq.NewClient(cfg.RabbitURL, logg)
+	mqClient.SetReconnectConfig(mq.ReconnectConfig{
+		InitialInterval: cfg.Reconnect.InitialInterval,
+		MaxInterval:     cfg.Reconnect.MaxInterval,
+		MaxElapsedTime:  cfg.Reconnect.MaxElapsedTime,
+		ReconnectDelay:  cfg.Reconnect.Delay,
+		Jitter:          cfg.Reconnect.Jitter,
+	})
 	defer mqClient.Close()
 
 	store := store.New(dbConn, logg)
-	alertsNotifier := alerts.New(observabilityrepo.NewSQLRepository(store.DB()), logg)
+	store.SetWorkerOfflineAfter(cfg.WorkerOfflineAfter)
+	store.SetStageOutputMaxBytes(cfg.StageOutputMaxBytes)
+	store.SetMaxStageAttempts(cfg.StageMaxAttempts)
+	observabilityRepo := observabilityrepo.NewSQLRepository(store.DB())
+	alertsNotifier := alerts.New(observabilityRepo, logg)
 	store.SetAlertSink(alertsNotifier)
+	datadogForwarder := datadog.New(observabilityRepo, prometheus.DefaultGatherer, "pipelogiq-worker", logg)
+	store.AddAlertSink(datadogForwarder)
+	go datadogForwarder.Run(ctx)
+	store.AddAlertSink(sentry.New(observabilityRepo, "pipelogiq-worker", logg))
 	w := worker.New(cfg, store, mqClient, logg)
+	limiterStore, err := ratelimit.New(cfg.RateLimitStore, cfg.RedisURL)
+	if err != nil {
+		logg.Error("rate limit store init failed", "err", err)
+		os.Exit(1)
+	}
+	w.SetLimiterStore(limiterStore)
+	w.SetQueueAlertSink(alertsNotifier)
 
 	if err := w.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
 		logg.Error("worker exited", "err", err)
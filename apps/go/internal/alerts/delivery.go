@@ -0,0 +1,173 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	observabilitymodel "pipelogiq/internal/observability/model"
+)
+
+const (
+	// defaultSendRetries is how many times a failed send is retried per
+	// channel unless the alerting config sets sendMaxRetries.
+	defaultSendRetries  = 2
+	sendInitialInterval = 500 * time.Millisecond
+	sendMaxInterval     = 5 * time.Second
+	// sendMaxElapsed bounds how long one alert keeps retrying a channel.
+	sendMaxElapsed = 30 * time.Second
+
+	// alertDeliveryRetention is how long the delivery log is kept; old rows
+	// are pruned at most once per alertDeliveryPruneEvery.
+	alertDeliveryRetention  = 30 * 24 * time.Hour
+	alertDeliveryPruneEvery = time.Hour
+)
+
+// send delivers alert to every configured channel. Each channel is retried in
+// its own goroutine, detached from ctx, so a slow or failing channel neither
+// delays the others nor holds up the caller that emitted the event.
+func (n *Notifier) send(ctx context.Context, cfg runtimeConfig, alert outboundAlert) {
+	ctx = context.WithoutCancel(ctx)
+	if cfg.telegramEnabled {
+		go func() {
+			if err := n.deliver(ctx, cfg, "telegram", alert, n.sendTelegram); err != nil {
+				n.recordSendFailure(ctx, "telegram", err)
+			}
+		}()
+	}
+	if cfg.webhookEnabled {
+		go n.deliverWebhooks(ctx, cfg, alert)
+	}
+}
+
+// deliver calls sendFn with exponential backoff until it succeeds, fails
+// permanently, or runs out of retries, and logs the outcome under channel.
+// It returns the final error.
+func (n *Notifier) deliver(ctx context.Context, cfg runtimeConfig, channel string, alert outboundAlert,
+	sendFn func(context.Context, runtimeConfig, outboundAlert) error) error {
+	ctx, cancel := context.WithTimeout(ctx, sendMaxElapsed)
+	defer cancel()
+
+	exp := backoff.NewExponentialBackOff()
+	exp.InitialInterval = sendInitialInterval
+	exp.MaxInterval = sendMaxInterval
+	exp.MaxElapsedTime = sendMaxElapsed
+
+	attempts := 0
+	err := backoff.Retry(func() error {
+		attempts++
+		return sendFn(ctx, cfg, alert)
+	}, backoff.WithContext(backoff.WithMaxRetries(exp, uint64(cfg.sendRetries)), ctx))
+	record := observabilitymodel.AlertDeliveryRecord{
+		Event:     alert.Event,
+		Channel:   channel,
+		DedupeKey: alert.DedupeKey,
+		Status:    observabilitymodel.AlertDeliverySent,
+		Attempts:  attempts,
+	}
+	if err == nil {
+		if attempts > 1 {
+			n.logger.Info("alert sent after retry", "channel", channel, "event", alert.Event, "attempts", attempts)
+		}
+		n.recordDelivery(ctx, record)
+		return nil
+	}
+
+	record.Status = observabilitymodel.AlertDeliveryFailed
+	record.Error = err.Error()
+	n.recordDelivery(ctx, record)
+
+	n.logger.Error("alert send failed", "channel", channel, "event", alert.Event, "attempts", attempts, "err", err)
+	return fmt.Errorf("after %d attempts: %w", attempts, err)
+}
+
+// recordSendFailure records a channel that could not deliver an alert
+// against the alerting integration's health.
+func (n *Notifier) recordSendFailure(ctx context.Context, channel string, err error) {
+	healthCtx, cancelHealth := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancelHealth()
+	message := fmt.Sprintf("%s alert send failed: %v", channel, err)
+	if healthErr := n.repo.RecordHealthFailure(healthCtx, observabilitymodel.IntegrationTypeAlerting, time.Now().UTC(), message); healthErr != nil {
+		n.logger.Error("record alerting health failure failed", "err", healthErr)
+	}
+}
+
+// postJSON makes one send attempt bounded by cfg.sendTimeout, adding header
+// to the request. Client errors other than 408 and 429 are permanent;
+// everything else may be retried.
+func (n *Notifier) postJSON(ctx context.Context, cfg runtimeConfig, channel, url string, body []byte, header http.Header) error {
+	timeout := cfg.sendTimeout
+	if timeout <= 0 {
+		timeout = defaultHTTPTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return backoff.Permanent(err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("%s status %d", channel, resp.StatusCode)
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return backoff.Permanent(err)
+	}
+	return err
+}
+
+// recordSuppressed logs an alert that was not sent, with the reason.
+func (n *Notifier) recordSuppressed(ctx context.Context, alert outboundAlert, reason string) {
+	n.recordDelivery(ctx, observabilitymodel.AlertDeliveryRecord{
+		Event:     alert.Event,
+		DedupeKey: alert.DedupeKey,
+		Status:    observabilitymodel.AlertDeliverySuppressed,
+		Reason:    reason,
+	})
+}
+
+// recordDelivery writes record to the alert delivery log. Failures are only
+// logged; the log must never hold up alerting.
+func (n *Notifier) recordDelivery(ctx context.Context, record observabilitymodel.AlertDeliveryRecord) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+	record.TS = now
+	if err := n.repo.InsertAlertDelivery(ctx, record); err != nil {
+		n.logger.Warn("record alert delivery failed", "event", record.Event, "status", record.Status, "err", err)
+		return
+	}
+
+	n.mu.Lock()
+	due := now.Sub(n.lastPrune) >= alertDeliveryPruneEvery
+	if due {
+		n.lastPrune = now
+	}
+	n.mu.Unlock()
+	if !due {
+		return
+	}
+	if _, err := n.repo.PruneAlertDeliveries(ctx, now.Add(-alertDeliveryRetention)); err != nil {
+		n.logger.Warn("prune alert deliveries failed", "err", err)
+	}
+}
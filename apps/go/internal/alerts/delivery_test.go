@@ -0,0 +1,88 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cenkalti/backoff/v4"
+
+	observabilitymodel "pipelogiq/internal/observability/model"
+)
+
+func TestPostJSONPermanentErrors(t *testing.T) {
+	tests := []struct {
+		status    int
+		wantErr   bool
+		permanent bool
+	}{
+		{http.StatusOK, false, false},
+		{http.StatusNoContent, false, false},
+		{http.StatusBadRequest, true, true},
+		{http.StatusUnauthorized, true, true},
+		{http.StatusNotFound, true, true},
+		{http.StatusRequestTimeout, true, false},
+		{http.StatusTooManyRequests, true, false},
+		{http.StatusInternalServerError, true, false},
+		{http.StatusServiceUnavailable, true, false},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+		}))
+		n := newTestNotifier(&fakeRepo{}, runtimeConfig{})
+		err := n.postJSON(context.Background(), runtimeConfig{}, "webhook", srv.URL, []byte(`{}`), nil)
+		srv.Close()
+
+		var permanent *backoff.PermanentError
+		if (err != nil) != tt.wantErr || errors.As(err, &permanent) != tt.permanent {
+			t.Errorf("status %d: postJSON() error = %v, want error %v, permanent %v", tt.status, err, tt.wantErr, tt.permanent)
+		}
+	}
+}
+
+func TestDeliverRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		retries      int
+		wantAttempts int
+		wantStatus   string
+	}{
+		{"retried until accepted", []int{http.StatusServiceUnavailable, http.StatusOK}, 2, 2, observabilitymodel.AlertDeliverySent},
+		{"rate limited then accepted", []int{http.StatusTooManyRequests, http.StatusOK}, 2, 2, observabilitymodel.AlertDeliverySent},
+		{"out of retries", []int{http.StatusBadGateway, http.StatusBadGateway}, 1, 2, observabilitymodel.AlertDeliveryFailed},
+		{"client error is not retried", []int{http.StatusNotFound, http.StatusOK}, 2, 1, observabilitymodel.AlertDeliveryFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				i := int(calls.Add(1)) - 1
+				w.WriteHeader(tt.statuses[min(i, len(tt.statuses)-1)])
+			}))
+			defer srv.Close()
+
+			repo := &fakeRepo{}
+			cfg := runtimeConfig{sendRetries: tt.retries}
+			n := newTestNotifier(repo, cfg)
+			err := n.deliver(context.Background(), cfg, "webhook", outboundAlert{Event: "stage_failed"},
+				func(ctx context.Context, cfg runtimeConfig, alert outboundAlert) error {
+					return n.postJSON(ctx, cfg, "webhook", srv.URL, []byte(`{}`), nil)
+				})
+
+			if (err == nil) != (tt.wantStatus == observabilitymodel.AlertDeliverySent) {
+				t.Fatalf("deliver() error = %v, want status %s", err, tt.wantStatus)
+			}
+			if got := int(calls.Load()); got != tt.wantAttempts {
+				t.Fatalf("endpoint called %d times, want %d", got, tt.wantAttempts)
+			}
+			if len(repo.deliveries) != 1 || repo.deliveries[0].Status != tt.wantStatus || repo.deliveries[0].Attempts != tt.wantAttempts {
+				t.Fatalf("delivery log = %+v, want one %s row with %d attempts", repo.deliveries, tt.wantStatus, tt.wantAttempts)
+			}
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package alerts
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -10,8 +9,11 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
+
 	observabilitymodel "pipelogiq/internal/observability/model"
 	observabilityrepo "pipelogiq/internal/observability/repo"
 	"pipelogiq/internal/store"
@@ -19,9 +21,14 @@ import (
 )
 
 const (
+	// defaultHTTPTimeout bounds a single send attempt unless the alerting
+	// config sets sendTimeoutSeconds.
 	defaultHTTPTimeout  = 4 * time.Second
 	configCacheTTL      = 5 * time.Second
 	defaultDedupeWindow = 5 * time.Minute
+	// defaultWorkerStartupGrace suppresses worker_failed alerts right after
+	// bootstrap, while a worker may still be connecting to its broker.
+	defaultWorkerStartupGrace = 30 * time.Second
 )
 
 type Notifier struct {
@@ -32,7 +39,12 @@ type Notifier struct {
 	mu          sync.Mutex
 	cachedCfg   runtimeConfig
 	cacheLoaded time.Time
-	recentSent  map[string]time.Time
+	// dedupeUntil holds, per dedupe key, when the key may alert again.
+	dedupeUntil map[string]time.Time
+	limiter     *alertRateLimiter
+	offline     *workerOfflineAggregator
+	// lastPrune is when old alert_delivery rows were last removed.
+	lastPrune time.Time
 }
 
 type runtimeConfig struct {
@@ -42,10 +54,28 @@ type runtimeConfig struct {
 	telegramBotToken   string
 	telegramChatID     string
 	webhookEnabled     bool
-	webhookURL         string
+	webhooks           []webhookEndpoint
 	dedupeWindow       time.Duration
+	workerStartupGrace time.Duration
 	sendResolved       bool
 	configuredChannels []string
+	// sendRetries is how many times a failed send is retried per channel,
+	// and sendTimeout bounds each attempt.
+	sendRetries int
+	sendTimeout time.Duration
+
+	// dedupeWindowByEvent overrides dedupeWindow for single events.
+	dedupeWindowByEvent map[string]time.Duration
+	// maxAlertsPerMinute caps sent alerts across all events, and
+	// maxAlertsPerMinuteByEvent per event; 0 means no cap.
+	maxAlertsPerMinute        int
+	maxAlertsPerMinuteByEvent map[string]int
+	// messageTemplates holds a parsed message template per channel.
+	messageTemplates map[string]*template.Template
+	// workerOfflineAggregateWindow collects the worker_heartbeat_lost alerts
+	// of an application for this long before sending them; 0 sends each one
+	// right away.
+	workerOfflineAggregateWindow time.Duration
 }
 
 type outboundAlert struct {
@@ -57,21 +87,31 @@ type outboundAlert struct {
 	DedupeKey   string         `json:"dedupeKey,omitempty"`
 	Details     map[string]any `json:"details,omitempty"`
 	ChannelHint []string       `json:"channels,omitempty"`
+
+	// applicationID and applicationName identify the application of a
+	// worker alert, for aggregating worker_heartbeat_lost alerts.
+	applicationID   int
+	applicationName string
 }
 
-var _ store.AlertSink = (*Notifier)(nil)
+var (
+	_ store.AlertSink         = (*Notifier)(nil)
+	_ store.PipelineAlertSink = (*Notifier)(nil)
+	_ store.APIKeyAlertSink   = (*Notifier)(nil)
+	_ store.StageSLAAlertSink = (*Notifier)(nil)
+)
 
 func New(repo observabilityrepo.Repository, logger *slog.Logger) *Notifier {
 	if logger == nil {
 		logger = slog.Default()
 	}
 	return &Notifier{
-		repo:   repo,
-		logger: logger,
-		client: &http.Client{
-			Timeout: defaultHTTPTimeout,
-		},
-		recentSent: make(map[string]time.Time),
+		repo:        repo,
+		logger:      logger,
+		client:      &http.Client{},
+		dedupeUntil: make(map[string]time.Time),
+		limiter:     newAlertRateLimiter(),
+		offline:     newWorkerOfflineAggregator(),
 	}
 }
 
@@ -88,9 +128,32 @@ func (n *Notifier) NotifyWorkerEvent(ctx context.Context, event store.WorkerAler
 	if !ok {
 		return
 	}
+	if alert.Event == "worker_failed" && n.withinStartupGrace(ctx, event) {
+		n.logger.Info("worker alert suppressed during startup grace window",
+			"workerId", event.WorkerID,
+			"eventType", event.EventType,
+			"bootstrappedAt", event.BootstrappedAt,
+		)
+		n.recordSuppressed(ctx, alert, "startup_grace")
+		return
+	}
 	n.dispatch(ctx, alert)
 }
 
+func (n *Notifier) withinStartupGrace(ctx context.Context, event store.WorkerAlertEvent) bool {
+	if event.BootstrappedAt.IsZero() {
+		return false
+	}
+	cfg, err := n.loadConfig(ctx)
+	if err != nil || !cfg.enabled || cfg.workerStartupGrace <= 0 {
+		return false
+	}
+	if _, ok := cfg.enabledEvents["worker_failed"]; !ok {
+		return false
+	}
+	return event.TS.Sub(event.BootstrappedAt) < cfg.workerStartupGrace
+}
+
 func (n *Notifier) NotifyPolicyEvent(ctx context.Context, event types.PolicyEvent) {
 	alert, ok := mapPolicyEvent(event)
 	if !ok {
@@ -99,6 +162,26 @@ func (n *Notifier) NotifyPolicyEvent(ctx context.Context, event types.PolicyEven
 	n.dispatch(ctx, alert)
 }
 
+func (n *Notifier) NotifyPipelineStuck(ctx context.Context, event store.PipelineStuckEvent) {
+	n.dispatch(ctx, mapPipelineStuckEvent(event))
+}
+
+func (n *Notifier) NotifyAPIKeyExpiring(ctx context.Context, event store.APIKeyExpiringEvent) {
+	n.dispatch(ctx, mapAPIKeyExpiringEvent(event))
+}
+
+func (n *Notifier) NotifyStageSLABreach(ctx context.Context, event store.StageSLABreachEvent) {
+	n.dispatch(ctx, mapStageSLABreachEvent(event))
+}
+
+func (n *Notifier) NotifyQueueEvent(ctx context.Context, event types.QueueDepthEvent) {
+	alert, ok := mapQueueEvent(event)
+	if !ok {
+		return
+	}
+	n.dispatch(ctx, alert)
+}
+
 func (n *Notifier) SendTestAlert(ctx context.Context) error {
 	cfg, err := n.loadConfig(ctx)
 	if err != nil {
@@ -126,7 +209,7 @@ func (n *Notifier) SendTestAlert(ctx context.Context) error {
 		}
 	}
 	if cfg.webhookEnabled {
-		if err := n.sendWebhook(ctx, cfg, alert); err != nil {
+		if err := n.sendWebhooks(ctx, cfg, alert); err != nil {
 			return fmt.Errorf("webhook: %w", err)
 		}
 	}
@@ -146,22 +229,45 @@ func (n *Notifier) dispatch(ctx context.Context, alert outboundAlert) {
 	if _, ok := cfg.enabledEvents[alert.Event]; !ok {
 		return
 	}
-	if alert.DedupeKey != "" && cfg.dedupeWindow > 0 && n.shouldSuppress(alert.DedupeKey, cfg.dedupeWindow) {
+	window := cfg.dedupeWindowFor(alert.Event)
+	if alert.DedupeKey == "" {
+		window = 0
+	}
+	if window > 0 && n.deduped(alert.DedupeKey) {
+		n.suppressDuplicate(ctx, alert)
 		return
 	}
-
-	alert.ChannelHint = cfg.configuredChannels
-
-	if cfg.telegramEnabled {
-		if err := n.sendTelegram(ctx, cfg, alert); err != nil {
-			n.logger.Error("telegram alert send failed", "err", err, "event", alert.Event)
+	if n.holdWorkerOffline(cfg, alert) {
+		// A held alert leaves with its batch, which the rate limit admits on
+		// its own, so its key is recorded now.
+		if window > 0 {
+			n.claimDedupe(alert.DedupeKey, window)
 		}
+		return
 	}
-	if cfg.webhookEnabled {
-		if err := n.sendWebhook(ctx, cfg, alert); err != nil {
-			n.logger.Error("webhook alert send failed", "err", err, "event", alert.Event)
-		}
+	n.sendLimited(ctx, cfg, alert, window)
+}
+
+// sendLimited sends alert unless the alert rate limit drops it. A positive
+// dedupeWindow records alert's dedupe key once the limit admits it, so an
+// alert the limit dropped does not hold back the next one with its key.
+func (n *Notifier) sendLimited(ctx context.Context, cfg runtimeConfig, alert outboundAlert, dedupeWindow time.Duration) {
+	if !n.limitRate(cfg, alert) {
+		n.recordSuppressed(ctx, alert, "rate_limit")
+		return
 	}
+	if dedupeWindow > 0 && !n.claimDedupe(alert.DedupeKey, dedupeWindow) {
+		n.suppressDuplicate(ctx, alert)
+		return
+	}
+
+	alert.ChannelHint = cfg.configuredChannels
+	n.send(ctx, cfg, alert)
+}
+
+func (n *Notifier) suppressDuplicate(ctx context.Context, alert outboundAlert) {
+	suppressedAlerts.WithLabelValues(alert.Event, "dedupe").Inc()
+	n.recordSuppressed(ctx, alert, "dedupe")
 }
 
 func (n *Notifier) loadConfig(ctx context.Context) (runtimeConfig, error) {
@@ -209,7 +315,7 @@ func parseRuntimeConfig(config map[string]any) runtimeConfig {
 
 	telegramToken := parseString(config["telegramBotToken"])
 	telegramChatID := parseString(config["telegramChatId"])
-	webhookURL := parseString(config["webhookUrl"])
+	webhooks := parseWebhookEndpoints(config)
 	dedupeWindow := defaultDedupeWindow
 	if raw, ok := parseFloat(config["dedupeWindowSeconds"]); ok && raw > 0 {
 		dedupeWindow = time.Duration(raw * float64(time.Second))
@@ -217,12 +323,67 @@ func parseRuntimeConfig(config map[string]any) runtimeConfig {
 	if raw, ok := parseFloat(config["dedupeWindowSeconds"]); ok && raw <= 0 {
 		dedupeWindow = 0
 	}
+	workerStartupGrace := defaultWorkerStartupGrace
+	if raw, ok := parseFloat(config["workerStartupGraceSeconds"]); ok && raw >= 0 {
+		workerStartupGrace = time.Duration(raw * float64(time.Second))
+	}
+	var workerOfflineAggregateWindow time.Duration
+	if raw, ok := parseFloat(config["workerOfflineAggregateSeconds"]); ok && raw > 0 {
+		workerOfflineAggregateWindow = time.Duration(raw * float64(time.Second))
+	}
 	sendResolved, _ := parseBool(config["sendResolved"])
+	dedupeWindowByEvent := map[string]time.Duration{}
+	for event, seconds := range parseFloatMap(config["dedupeWindowSecondsByEvent"]) {
+		if seconds > 0 {
+			dedupeWindowByEvent[event] = time.Duration(seconds * float64(time.Second))
+		}
+	}
+	maxAlertsPerMinute := defaultMaxAlertsPerMinute
+	if raw, ok := parseFloat(config["maxAlertsPerMinute"]); ok && raw >= 0 {
+		maxAlertsPerMinute = int(raw)
+	}
+	sendRetries := defaultSendRetries
+	if raw, ok := parseFloat(config["sendMaxRetries"]); ok && raw >= 0 {
+		sendRetries = int(raw)
+	}
+	sendTimeout := defaultHTTPTimeout
+	if raw, ok := parseFloat(config["sendTimeoutSeconds"]); ok && raw > 0 {
+		sendTimeout = time.Duration(raw * float64(time.Second))
+	}
+	maxAlertsPerMinuteByEvent := map[string]int{}
+	for event, limit := range parseFloatMap(config["maxAlertsPerMinuteByEvent"]) {
+		if limit > 0 {
+			maxAlertsPerMinuteByEvent[event] = int(limit)
+		}
+	}
+
+	messageTemplates := map[string]*template.Template{}
+	if raw, ok := config["messageTemplates"].(map[string]any); ok {
+		for channel, text := range raw {
+			channel = strings.ToLower(strings.TrimSpace(channel))
+			if text, ok := text.(string); ok && strings.TrimSpace(text) != "" {
+				// Templates are validated on save; one that still fails to
+				// parse keeps the default format.
+				if tmpl, err := parseMessageTemplate(channel, text); err == nil {
+					messageTemplates[channel] = tmpl
+				}
+			}
+		}
+	}
 
 	cfg := runtimeConfig{
-		enabledEvents: eventSet,
-		dedupeWindow:  dedupeWindow,
-		sendResolved:  sendResolved,
+		enabledEvents:             eventSet,
+		dedupeWindow:              dedupeWindow,
+		dedupeWindowByEvent:       dedupeWindowByEvent,
+		maxAlertsPerMinute:        maxAlertsPerMinute,
+		maxAlertsPerMinuteByEvent: maxAlertsPerMinuteByEvent,
+		messageTemplates:          messageTemplates,
+		workerStartupGrace:        workerStartupGrace,
+		sendResolved:              sendResolved,
+		sendRetries:               sendRetries,
+		sendTimeout:               sendTimeout,
+
+		workerOfflineAggregateWindow: workerOfflineAggregateWindow,
 	}
 
 	if _, ok := channelSet["telegram"]; ok && telegramToken != "" && telegramChatID != "" {
@@ -231,9 +392,9 @@ func parseRuntimeConfig(config map[string]any) runtimeConfig {
 		cfg.telegramChatID = telegramChatID
 		cfg.configuredChannels = append(cfg.configuredChannels, "telegram")
 	}
-	if _, ok := channelSet["webhook"]; ok && webhookURL != "" {
+	if _, ok := channelSet["webhook"]; ok && len(webhooks) > 0 {
 		cfg.webhookEnabled = true
-		cfg.webhookURL = webhookURL
+		cfg.webhooks = webhooks
 		cfg.configuredChannels = append(cfg.configuredChannels, "webhook")
 	}
 
@@ -241,84 +402,57 @@ func parseRuntimeConfig(config map[string]any) runtimeConfig {
 	return cfg
 }
 
-func (n *Notifier) shouldSuppress(key string, window time.Duration) bool {
+func (cfg runtimeConfig) dedupeWindowFor(event string) time.Duration {
+	if window, ok := cfg.dedupeWindowByEvent[event]; ok {
+		return window
+	}
+	return cfg.dedupeWindow
+}
+
+// deduped reports whether an alert with key was sent within its dedupe
+// window.
+func (n *Notifier) deduped(key string) bool {
 	now := time.Now().UTC()
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
-	for k, ts := range n.recentSent {
-		if now.Sub(ts) > window {
-			delete(n.recentSent, k)
+	for k, until := range n.dedupeUntil {
+		if now.After(until) {
+			delete(n.dedupeUntil, k)
 		}
 	}
 
-	if ts, ok := n.recentSent[key]; ok && now.Sub(ts) <= window {
-		return true
-	}
-	n.recentSent[key] = now
-	return false
+	until, ok := n.dedupeUntil[key]
+	return ok && !now.After(until)
 }
 
-func (n *Notifier) sendTelegram(ctx context.Context, cfg runtimeConfig, alert outboundAlert) error {
-	payload := map[string]any{
-		"chat_id": cfg.telegramChatID,
-		"text":    formatTelegramText(alert),
-	}
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return err
-	}
-
-	reqCtx, cancel := context.WithTimeout(ctx, defaultHTTPTimeout)
-	defer cancel()
-
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", cfg.telegramBotToken)
-	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, url, bytes.NewReader(body))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "application/json")
+// claimDedupe records that an alert with key is sent, holding back others
+// with the key for window. It returns false if a concurrent alert claimed
+// key first.
+func (n *Notifier) claimDedupe(key string, window time.Duration) bool {
+	now := time.Now().UTC()
+	n.mu.Lock()
+	defer n.mu.Unlock()
 
-	resp, err := n.client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 300 {
-		return fmt.Errorf("telegram status %d", resp.StatusCode)
+	if until, ok := n.dedupeUntil[key]; ok && !now.After(until) {
+		return false
 	}
-	return nil
+	n.dedupeUntil[key] = now.Add(window)
+	return true
 }
 
-func (n *Notifier) sendWebhook(ctx context.Context, cfg runtimeConfig, alert outboundAlert) error {
+func (n *Notifier) sendTelegram(ctx context.Context, cfg runtimeConfig, alert outboundAlert) error {
 	payload := map[string]any{
-		"source":  "pipelogiq",
-		"channel": "webhook",
-		"alert":   alert,
+		"chat_id": cfg.telegramChatID,
+		"text":    n.renderChannelMessage(cfg, "telegram", alert, formatTelegramText),
 	}
 	body, err := json.Marshal(payload)
 	if err != nil {
-		return err
-	}
-
-	reqCtx, cancel := context.WithTimeout(ctx, defaultHTTPTimeout)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, cfg.webhookURL, bytes.NewReader(body))
-	if err != nil {
-		return err
+		return backoff.Permanent(err)
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := n.client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 300 {
-		return fmt.Errorf("webhook status %d", resp.StatusCode)
-	}
-	return nil
+	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", cfg.telegramBotToken)
+	return n.postJSON(ctx, cfg, "telegram", url, body, nil)
 }
 
 func mapStageEvent(event store.StageAlertEvent) (outboundAlert, bool) {
@@ -344,6 +478,16 @@ func mapStageEvent(event store.StageAlertEvent) (outboundAlert, bool) {
 			DedupeKey: fmt.Sprintf("stage_failed:%d:%d", event.PipelineID, event.StageID),
 			Details:   baseDetails,
 		}, true
+	case strings.EqualFold(event.Source, "pending_watcher") && strings.EqualFold(event.NewStatus, types.StageStatusRetryScheduled):
+		return outboundAlert{
+			Event:     "stage_timeout_retry",
+			Title:     "Stage timed out, retry scheduled",
+			Message:   fmt.Sprintf("Pipeline %d stage %d timed out and was scheduled for retry (%s)", event.PipelineID, event.StageID, strings.TrimSpace(event.StageName)),
+			Severity:  "warning",
+			Timestamp: ts,
+			DedupeKey: fmt.Sprintf("stage_timeout_retry:%d:%d:%d", event.PipelineID, event.StageID, event.RetryAttempt),
+			Details:   baseDetails,
+		}, true
 	case strings.EqualFold(event.Source, "rerun_stage"):
 		return outboundAlert{
 			Event:     "stage_rerun_manual",
@@ -437,6 +581,9 @@ func mapWorkerEvent(event store.WorkerAlertEvent) (outboundAlert, bool) {
 				Timestamp: ts,
 				DedupeKey: fmt.Sprintf("worker_offline:%s", event.WorkerID),
 				Details:   details,
+
+				applicationID:   event.ApplicationID,
+				applicationName: strings.TrimSpace(event.ApplicationName),
 			}, true
 		case types.WorkerStateStopped:
 			return outboundAlert{
@@ -510,6 +657,135 @@ func mapPolicyEvent(event types.PolicyEvent) (outboundAlert, bool) {
 	}
 }
 
+// mapPipelineStuckEvent dedupes per pipeline, so a pipeline that stays stuck
+// alerts again only after the dedupe window.
+func mapPipelineStuckEvent(event store.PipelineStuckEvent) outboundAlert {
+	details := map[string]any{
+		"pipelineId":    event.PipelineID,
+		"pipelineName":  event.PipelineName,
+		"applicationId": event.ApplicationID,
+		"lastChangeAt":  event.LastChangeAt.UTC().Format(time.RFC3339),
+	}
+	stuckFor := event.TS.Sub(event.LastChangeAt).Round(time.Second)
+	message := fmt.Sprintf("Pipeline '%s' (id=%d) has had no stage status change for %s",
+		event.PipelineName, event.PipelineID, stuckFor)
+	if event.StageID > 0 {
+		details["stageId"] = event.StageID
+		details["stageName"] = event.StageName
+		details["stageStatus"] = event.StageStatus
+		details["stageHandler"] = event.StageHandler
+		message += fmt.Sprintf("; current stage '%s' is %s", event.StageName, event.StageStatus)
+	}
+	return outboundAlert{
+		Event:     "pipeline_stuck",
+		Title:     "Pipeline stuck",
+		Message:   message,
+		Severity:  "warning",
+		Timestamp: event.TS.UTC().Format(time.RFC3339),
+		DedupeKey: fmt.Sprintf("pipeline_stuck:%d", event.PipelineID),
+		Details:   details,
+	}
+}
+
+// mapAPIKeyExpiringEvent dedupes per key and day; the store also reports a
+// key at most once a day.
+func mapAPIKeyExpiringEvent(event store.APIKeyExpiringEvent) outboundAlert {
+	keyName := strings.TrimSpace(event.KeyName)
+	if keyName == "" {
+		keyName = fmt.Sprintf("#%d", event.APIKeyID)
+	}
+	day := "days"
+	if event.DaysRemaining == 1 {
+		day = "day"
+	}
+	return outboundAlert{
+		Event: "api_key_expiring",
+		Title: "API key expiring",
+		Message: fmt.Sprintf("API key '%s' of application '%s' (id=%d) expires in %d %s",
+			keyName, event.ApplicationName, event.ApplicationID, event.DaysRemaining, day),
+		Severity:  "warning",
+		Timestamp: event.TS.UTC().Format(time.RFC3339),
+		DedupeKey: fmt.Sprintf("api_key_expiring:%d:%s", event.APIKeyID, event.TS.UTC().Format(time.DateOnly)),
+		Details: map[string]any{
+			"apiKeyId":        event.APIKeyID,
+			"apiKeyName":      keyName,
+			"applicationId":   event.ApplicationID,
+			"applicationName": event.ApplicationName,
+			"expiresAt":       event.ExpiresAt.UTC().Format(time.RFC3339),
+			"daysRemaining":   event.DaysRemaining,
+		},
+	}
+}
+
+// mapStageSLABreachEvent dedupes per stage dispatch; the store also reports
+// a dispatch at most once.
+func mapStageSLABreachEvent(event store.StageSLABreachEvent) outboundAlert {
+	expected := "expected duration"
+	if event.Basis == store.StageSLABasisBaseline {
+		expected = "handler baseline"
+	}
+	return outboundAlert{
+		Event: "stage_sla_breach",
+		Title: "Stage running long",
+		Message: fmt.Sprintf("Stage '%s' (id=%d) of pipeline '%s' (id=%d) has been %s for %s, over %gx its %s of %s",
+			event.StageName, event.StageID, event.PipelineName, event.PipelineID, event.StageStatus,
+			event.Elapsed.Round(time.Second), event.Factor, expected, event.Expected),
+		Severity:  "warning",
+		Timestamp: event.TS.UTC().Format(time.RFC3339),
+		DedupeKey: fmt.Sprintf("stage_sla_breach:%d:%d", event.StageID, event.StartedAt.UnixMilli()),
+		Details: map[string]any{
+			"pipelineId":    event.PipelineID,
+			"pipelineName":  event.PipelineName,
+			"applicationId": event.ApplicationID,
+			"stageId":       event.StageID,
+			"stageName":     event.StageName,
+			"stageStatus":   event.StageStatus,
+			"stageHandler":  event.StageHandler,
+			"startedAt":     event.StartedAt.UTC().Format(time.RFC3339),
+			"expectedMs":    event.Expected.Milliseconds(),
+			"elapsedMs":     event.Elapsed.Milliseconds(),
+			"basis":         event.Basis,
+			"factor":        event.Factor,
+		},
+	}
+}
+
+// mapQueueEvent dedupes per queue, so a queue that stays over its threshold
+// alerts again only after the dedupe window.
+func mapQueueEvent(event types.QueueDepthEvent) (outboundAlert, bool) {
+	ts := event.TS.UTC().Format(time.RFC3339)
+	details := map[string]any{
+		"queue": event.Queue,
+		"depth": event.Depth,
+	}
+
+	switch event.Type {
+	case types.QueueEventBacklogHigh:
+		details["threshold"] = event.Threshold
+		return outboundAlert{
+			Event:     "queue_backlog_high",
+			Title:     "Queue backlog high",
+			Message:   fmt.Sprintf("Queue %s has %d messages (threshold %d)", event.Queue, event.Depth, event.Threshold),
+			Severity:  "warning",
+			Timestamp: ts,
+			DedupeKey: "queue_backlog_high:" + event.Queue,
+			Details:   details,
+		}, true
+	case types.QueueEventDLQMessage:
+		return outboundAlert{
+			Event:     "dlq_message_detected",
+			Title:     "DLQ message detected",
+			Message:   fmt.Sprintf("Dead-letter queue %s has %d messages", event.Queue, event.Depth),
+			Severity:  "error",
+			Timestamp: ts,
+			DedupeKey: "dlq_message_detected:" + event.Queue,
+			Details:   details,
+		}, true
+	default:
+		return outboundAlert{}, false
+	}
+}
+
 func formatTelegramText(alert outboundAlert) string {
 	var b strings.Builder
 	b.WriteString("[")
@@ -588,6 +864,23 @@ func parseFloat(raw any) (float64, bool) {
 	return 0, false
 }
 
+// parseFloatMap reads an object of event name to number; other values are
+// skipped.
+func parseFloatMap(raw any) map[string]float64 {
+	values, ok := raw.(map[string]any)
+	if !ok {
+		return nil
+	}
+	out := make(map[string]float64, len(values))
+	for key, value := range values {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if f, ok := parseFloat(value); ok && key != "" {
+			out[key] = f
+		}
+	}
+	return out
+}
+
 func parseStringList(raw any) []string {
 	out := make([]string, 0)
 	seen := map[string]struct{}{}
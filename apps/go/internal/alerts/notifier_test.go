@@ -0,0 +1,117 @@
+package alerts
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	observabilitymodel "pipelogiq/internal/observability/model"
+	observabilityrepo "pipelogiq/internal/observability/repo"
+	"pipelogiq/internal/store"
+	"pipelogiq/internal/types"
+)
+
+// fakeRepo records alert deliveries and health failures; the other
+// repository methods are not used by these tests.
+type fakeRepo struct {
+	observabilityrepo.Repository
+
+	mu             sync.Mutex
+	deliveries     []observabilitymodel.AlertDeliveryRecord
+	healthFailures []string
+}
+
+func (r *fakeRepo) InsertAlertDelivery(_ context.Context, record observabilitymodel.AlertDeliveryRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries = append(r.deliveries, record)
+	return nil
+}
+
+func (r *fakeRepo) PruneAlertDeliveries(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (r *fakeRepo) RecordHealthFailure(_ context.Context, _ observabilitymodel.IntegrationType, _ time.Time, message string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.healthFailures = append(r.healthFailures, message)
+	return nil
+}
+
+func (r *fakeRepo) suppressed() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []string
+	for _, d := range r.deliveries {
+		if d.Status == observabilitymodel.AlertDeliverySuppressed {
+			out = append(out, d.DedupeKey+":"+d.Reason)
+		}
+	}
+	return out
+}
+
+// newTestNotifier returns a Notifier whose cached config is cfg.
+func newTestNotifier(repo *fakeRepo, cfg runtimeConfig) *Notifier {
+	n := New(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	n.storeCachedConfig(cfg)
+	return n
+}
+
+func TestDispatchDedupesOnlyAdmittedAlerts(t *testing.T) {
+	repo := &fakeRepo{}
+	n := newTestNotifier(repo, runtimeConfig{
+		enabled:                   true,
+		enabledEvents:             map[string]struct{}{"stage_failed": {}},
+		dedupeWindow:              time.Hour,
+		maxAlertsPerMinuteByEvent: map[string]int{"stage_failed": 1},
+	})
+	ctx := context.Background()
+	alert := func(key string) outboundAlert {
+		return outboundAlert{Event: "stage_failed", DedupeKey: key}
+	}
+
+	n.dispatch(ctx, alert("a"))
+	n.dispatch(ctx, alert("b"))
+	n.dispatch(ctx, alert("a"))
+
+	// The next rate window admits b, which the limit dropped before it was
+	// ever sent.
+	n.limiter = newAlertRateLimiter()
+	n.dispatch(ctx, alert("b"))
+	n.dispatch(ctx, alert("b"))
+
+	want := []string{"b:rate_limit", "a:dedupe", "b:dedupe"}
+	got := repo.suppressed()
+	if len(got) != len(want) {
+		t.Fatalf("suppressed = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("suppressed = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestMapStageEventTimeoutRetryDedupesPerAttempt(t *testing.T) {
+	event := store.StageAlertEvent{
+		PipelineID:   7,
+		StageID:      42,
+		OldStatus:    types.StageStatusRunning,
+		NewStatus:    types.StageStatusRetryScheduled,
+		Source:       "pending_watcher",
+		RetryAttempt: 2,
+		TS:           time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	first, ok := mapStageEvent(event)
+	if !ok || first.Event != "stage_timeout_retry" || first.DedupeKey != "stage_timeout_retry:7:42:2" {
+		t.Fatalf("mapStageEvent() = %+v, %v, want stage_timeout_retry keyed on the attempt", first, ok)
+	}
+	event.TS = event.TS.Add(time.Second)
+	if again, _ := mapStageEvent(event); again.DedupeKey != first.DedupeKey {
+		t.Fatalf("DedupeKey changed with the timestamp: %q, %q", first.DedupeKey, again.DedupeKey)
+	}
+}
@@ -0,0 +1,162 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// defaultMaxAlertsPerMinute caps alerts sent by one process per minute
+	// across all events.
+	defaultMaxAlertsPerMinute = 60
+	alertRateWindow           = time.Minute
+	// suppressedSummaryEvent is sent once a rate window in which alerts were
+	// dropped ends. It bypasses enabledEvents and the rate limit itself.
+	suppressedSummaryEvent = "alerts_suppressed"
+)
+
+var suppressedAlerts = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "alerts_suppressed_total",
+	Help: "Number of alerts not sent, by event and reason (dedupe/rate_limit)",
+}, []string{"event", "reason"})
+
+func init() {
+	prometheus.MustRegister(suppressedAlerts)
+}
+
+// alertRateLimiter counts alerts in fixed one-minute windows, globally and
+// per event, and keeps the alerts it dropped until they are summarized.
+type alertRateLimiter struct {
+	mu          sync.Mutex
+	windowStart time.Time
+	total       int
+	perEvent    map[string]int
+	suppressed  map[string]int
+	// summaryDue is when the pending summary is sent; zero if none is pending.
+	summaryDue time.Time
+}
+
+func newAlertRateLimiter() *alertRateLimiter {
+	return &alertRateLimiter{
+		perEvent:   make(map[string]int),
+		suppressed: make(map[string]int),
+	}
+}
+
+// allow reports whether an alert for event may be sent at now. maxTotal and
+// maxPerEvent of 0 mean no cap. When the alert is dropped and no summary is
+// pending yet, scheduleAt is the end of the current window, when the caller
+// should send one.
+func (l *alertRateLimiter) allow(now time.Time, event string, maxTotal, maxPerEvent int) (ok bool, scheduleAt time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.windowStart) >= alertRateWindow {
+		l.windowStart = now
+		l.total = 0
+		clear(l.perEvent)
+	}
+
+	if (maxTotal > 0 && l.total >= maxTotal) || (maxPerEvent > 0 && l.perEvent[event] >= maxPerEvent) {
+		l.suppressed[event]++
+		if l.summaryDue.IsZero() {
+			l.summaryDue = l.windowStart.Add(alertRateWindow)
+			return false, l.summaryDue
+		}
+		return false, time.Time{}
+	}
+
+	l.total++
+	l.perEvent[event]++
+	return true, time.Time{}
+}
+
+// takeSuppressed returns and resets the alerts dropped since the last summary.
+func (l *alertRateLimiter) takeSuppressed() map[string]int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := l.suppressed
+	l.suppressed = make(map[string]int)
+	l.summaryDue = time.Time{}
+	return out
+}
+
+// limitRate applies the per-minute caps to an alert about to be sent.
+func (n *Notifier) limitRate(cfg runtimeConfig, alert outboundAlert) bool {
+	ok, scheduleAt := n.limiter.allow(time.Now().UTC(), alert.Event, cfg.maxAlertsPerMinute, cfg.maxAlertsPerMinuteByEvent[alert.Event])
+	if ok {
+		return true
+	}
+	suppressedAlerts.WithLabelValues(alert.Event, "rate_limit").Inc()
+	if !scheduleAt.IsZero() {
+		time.AfterFunc(time.Until(scheduleAt), n.sendSuppressedSummary)
+	}
+	return false
+}
+
+// sendSuppressedSummary tells operators how many alerts the rate limit
+// dropped, so throttling is never silent.
+func (n *Notifier) sendSuppressedSummary() {
+	counts := n.limiter.takeSuppressed()
+	if len(counts) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*defaultHTTPTimeout)
+	defer cancel()
+	cfg, err := n.loadConfig(ctx)
+	if err != nil {
+		n.logger.Error("alerts config load failed", "err", err)
+		return
+	}
+	if !cfg.enabled {
+		return
+	}
+
+	alert := suppressedSummaryAlert(counts, cfg.maxAlertsPerMinute, time.Now().UTC())
+	alert.ChannelHint = cfg.configuredChannels
+	n.send(ctx, cfg, alert)
+}
+
+func suppressedSummaryAlert(counts map[string]int, maxPerMinute int, now time.Time) outboundAlert {
+	events := make([]string, 0, len(counts))
+	total := 0
+	details := map[string]any{}
+	for event, count := range counts {
+		events = append(events, event)
+		total += count
+	}
+	sort.Strings(events)
+	parts := make([]string, 0, len(events))
+	for _, event := range events {
+		parts = append(parts, fmt.Sprintf("%s=%d", event, counts[event]))
+		details[event] = counts[event]
+	}
+
+	noun := "alerts"
+	if total == 1 {
+		noun = "alert"
+	}
+	message := fmt.Sprintf("%d %s suppressed by the alert rate limit (%s)", total, noun, strings.Join(parts, ", "))
+	if maxPerMinute > 0 {
+		message += fmt.Sprintf("; limit %d per minute", maxPerMinute)
+	}
+	return outboundAlert{
+		Event:     suppressedSummaryEvent,
+		Title:     "Alerts suppressed",
+		Message:   message,
+		Severity:  "warning",
+		Timestamp: now.Format(time.RFC3339),
+		Details: map[string]any{
+			"suppressed":         total,
+			"suppressedByEvent":  details,
+			"maxAlertsPerMinute": maxPerMinute,
+		},
+	}
+}
@@ -0,0 +1,129 @@
+package alerts
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// maxTemplateLength bounds a message template, and maxRenderedLength a
+// rendered message (Telegram's own limit).
+const (
+	maxTemplateLength = 4096
+	maxRenderedLength = 4096
+)
+
+// templateFuncs is the whole function set available to message templates on
+// top of text/template's builtins. None of them reaches outside the alert.
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+	"default": func(fallback, value any) any {
+		if value == nil || value == "" {
+			return fallback
+		}
+		return value
+	},
+}
+
+// templateAlert is what a message template renders: the alert's fields and
+// its Details map.
+type templateAlert struct {
+	Event     string
+	Title     string
+	Message   string
+	Severity  string
+	Timestamp string
+	DedupeKey string
+	Details   map[string]any
+}
+
+func newTemplateAlert(alert outboundAlert) templateAlert {
+	details := alert.Details
+	if details == nil {
+		details = map[string]any{}
+	}
+	return templateAlert{
+		Event:     alert.Event,
+		Title:     alert.Title,
+		Message:   alert.Message,
+		Severity:  alert.Severity,
+		Timestamp: alert.Timestamp,
+		DedupeKey: alert.DedupeKey,
+		Details:   details,
+	}
+}
+
+// parseMessageTemplate parses a channel's message template. Missing Details
+// keys render as empty values.
+func parseMessageTemplate(channel, text string) (*template.Template, error) {
+	if len(text) > maxTemplateLength {
+		return nil, fmt.Errorf("template is longer than %d bytes", maxTemplateLength)
+	}
+	return template.New(channel).Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+}
+
+func renderMessageTemplate(tmpl *template.Template, alert outboundAlert) (string, error) {
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, newTemplateAlert(alert)); err != nil {
+		return "", err
+	}
+	text := strings.TrimSpace(b.String())
+	if text == "" {
+		return "", errors.New("template rendered an empty message")
+	}
+	if len(text) > maxRenderedLength {
+		return "", fmt.Errorf("rendered message is longer than %d bytes", maxRenderedLength)
+	}
+	return text, nil
+}
+
+// ValidateMessageTemplate parses a message template and renders it against a
+// sample alert, so templates that would fail at send time are rejected when
+// the alerting config is saved.
+func ValidateMessageTemplate(channel, text string) error {
+	tmpl, err := parseMessageTemplate(channel, text)
+	if err != nil {
+		return err
+	}
+	_, err = renderMessageTemplate(tmpl, sampleTemplateAlert())
+	return err
+}
+
+func sampleTemplateAlert() outboundAlert {
+	return outboundAlert{
+		Event:     "stage_failed",
+		Title:     "Stage failed",
+		Message:   "Pipeline 42 stage 7 failed (charge-card)",
+		Severity:  "error",
+		Timestamp: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC).Format(time.RFC3339),
+		DedupeKey: "stage_failed:42:7",
+		Details: map[string]any{
+			"pipelineId":   42,
+			"pipelineName": "checkout",
+			"stageId":      7,
+			"stageName":    "charge-card",
+			"oldStatus":    "Running",
+			"newStatus":    "Failed",
+		},
+	}
+}
+
+// renderChannelMessage renders the channel's template, falling back to
+// fallback when none is set or rendering fails.
+func (n *Notifier) renderChannelMessage(cfg runtimeConfig, channel string, alert outboundAlert, fallback func(outboundAlert) string) string {
+	tmpl, ok := cfg.messageTemplates[channel]
+	if !ok {
+		return fallback(alert)
+	}
+	text, err := renderMessageTemplate(tmpl, alert)
+	if err != nil {
+		n.logger.Error("alert template render failed, using default format", "err", err, "channel", channel, "event", alert.Event)
+		return fallback(alert)
+	}
+	return text
+}
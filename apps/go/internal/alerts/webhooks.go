@@ -0,0 +1,164 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"pipelogiq/internal/webhooksig"
+)
+
+// MaxWebhookNameLength is the longest webhook endpoint name, in characters,
+// that fits the alert_delivery channel column behind the "webhook:" prefix.
+const MaxWebhookNameLength = 56
+
+// webhookEndpoint is one target of the webhook channel.
+type webhookEndpoint struct {
+	url    string
+	secret string
+	// label names the endpoint in the delivery log and in errors: its
+	// configured name, or the URL host so credentials in the URL stay out,
+	// cut to MaxWebhookNameLength.
+	label string
+}
+
+// parseWebhookEndpoints returns the single webhookUrl, if set, followed by
+// the entries of the webhooks list. Entries without a URL and repeated URLs
+// are skipped. webhookSecret signs webhookUrl and every entry without its own
+// secret.
+func parseWebhookEndpoints(config map[string]any) []webhookEndpoint {
+	var endpoints []webhookEndpoint
+	defaultSecret := parseString(config["webhookSecret"])
+	seen := map[string]struct{}{}
+	add := func(rawURL, secret, name string) {
+		if rawURL == "" {
+			return
+		}
+		if _, ok := seen[rawURL]; ok {
+			return
+		}
+		seen[rawURL] = struct{}{}
+		if name == "" {
+			name = webhookHost(rawURL)
+		}
+		if runes := []rune(name); len(runes) > MaxWebhookNameLength {
+			name = string(runes[:MaxWebhookNameLength])
+		}
+		if secret == "" {
+			secret = defaultSecret
+		}
+		endpoints = append(endpoints, webhookEndpoint{url: rawURL, secret: secret, label: name})
+	}
+
+	add(parseString(config["webhookUrl"]), "", "")
+	if list, ok := config["webhooks"].([]any); ok {
+		for _, item := range list {
+			entry, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			add(parseString(entry["url"]), parseString(entry["secret"]), parseString(entry["name"]))
+		}
+	}
+	return endpoints
+}
+
+func webhookHost(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return "webhook"
+}
+
+// deliverWebhooks delivers alert to every webhook endpoint at once, each with
+// its own retries and delivery log entry. The channel only counts as failed,
+// against the integration's health, when no endpoint accepted the alert.
+func (n *Notifier) deliverWebhooks(ctx context.Context, cfg runtimeConfig, alert outboundAlert) {
+	errs := make([]error, len(cfg.webhooks))
+	var wg sync.WaitGroup
+	for i, endpoint := range cfg.webhooks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = n.deliver(ctx, cfg, "webhook:"+endpoint.label, alert, func(ctx context.Context, cfg runtimeConfig, alert outboundAlert) error {
+				return n.sendWebhook(ctx, cfg, endpoint, alert)
+			})
+		}()
+	}
+	wg.Wait()
+
+	if err := allWebhooksFailed(cfg.webhooks, errs); err != nil {
+		n.recordSendFailure(ctx, "webhook", err)
+	}
+}
+
+// sendWebhooks makes one send to every webhook endpoint and succeeds when at
+// least one of them accepts the alert.
+func (n *Notifier) sendWebhooks(ctx context.Context, cfg runtimeConfig, alert outboundAlert) error {
+	errs := make([]error, len(cfg.webhooks))
+	var wg sync.WaitGroup
+	for i, endpoint := range cfg.webhooks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = n.sendWebhook(ctx, cfg, endpoint, alert)
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			n.logger.Warn("alert webhook endpoint failed", "endpoint", cfg.webhooks[i].label, "event", alert.Event, "err", err)
+		}
+	}
+	return allWebhooksFailed(cfg.webhooks, errs)
+}
+
+// allWebhooksFailed combines the errors of all endpoints, or returns nil when
+// any endpoint succeeded.
+func allWebhooksFailed(endpoints []webhookEndpoint, errs []error) error {
+	failures := make([]string, 0, len(errs))
+	for i, err := range errs {
+		if err == nil {
+			return nil
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v", endpoints[i].label, err))
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(failures, "; "))
+}
+
+func (n *Notifier) sendWebhook(ctx context.Context, cfg runtimeConfig, endpoint webhookEndpoint, alert outboundAlert) error {
+	payload := map[string]any{
+		"source":  "pipelogiq",
+		"channel": "webhook",
+		"alert":   alert,
+	}
+	if _, ok := cfg.messageTemplates["webhook"]; ok {
+		payload["text"] = n.renderChannelMessage(cfg, "webhook", alert, func(a outboundAlert) string { return a.Message })
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return backoff.Permanent(err)
+	}
+
+	var header http.Header
+	if endpoint.secret != "" {
+		timestamp := time.Now().Unix()
+		header = http.Header{}
+		header.Set(webhooksig.TimestampHeader, strconv.FormatInt(timestamp, 10))
+		header.Set(webhooksig.SignatureHeader, webhooksig.Sign(endpoint.secret, timestamp, body))
+	}
+	return n.postJSON(ctx, cfg, "webhook", strings.TrimSpace(endpoint.url), body, header)
+}
@@ -0,0 +1,86 @@
+package alerts
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	observabilitymodel "pipelogiq/internal/observability/model"
+)
+
+func TestParseWebhookEndpointsSecrets(t *testing.T) {
+	endpoints := parseWebhookEndpoints(map[string]any{
+		"webhookUrl":    "https://hooks.example.com/a",
+		"webhookSecret": "shared",
+		"webhooks": []any{
+			map[string]any{"url": "https://hooks.example.com/b"},
+			map[string]any{"url": "https://hooks.example.com/c", "secret": "own"},
+		},
+	})
+	want := []string{"shared", "shared", "own"}
+	if len(endpoints) != len(want) {
+		t.Fatalf("got %d endpoints, want %d", len(endpoints), len(want))
+	}
+	for i, endpoint := range endpoints {
+		if endpoint.secret != want[i] {
+			t.Errorf("endpoint %d secret = %q, want %q", i, endpoint.secret, want[i])
+		}
+	}
+}
+
+func TestParseWebhookEndpointsCutsLongLabels(t *testing.T) {
+	host := strings.Repeat("a", 60) + ".example.com"
+	endpoints := parseWebhookEndpoints(map[string]any{
+		"webhooks": []any{map[string]any{"url": "https://" + host + "/hook"}},
+	})
+	if len(endpoints) != 1 || endpoints[0].label != host[:MaxWebhookNameLength] {
+		t.Fatalf("endpoints = %+v, want one labelled with the first %d characters of the host", endpoints, MaxWebhookNameLength)
+	}
+}
+
+func TestDeliverWebhooksOneEndpointFailing(t *testing.T) {
+	var okCalls, badCalls atomic.Int32
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		okCalls.Add(1)
+	}))
+	defer ok.Close()
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		badCalls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer bad.Close()
+
+	repo := &fakeRepo{}
+	cfg := runtimeConfig{
+		webhookEnabled: true,
+		webhooks: parseWebhookEndpoints(map[string]any{
+			"webhooks": []any{
+				map[string]any{"url": ok.URL, "name": "ok"},
+				map[string]any{"url": bad.URL, "name": "bad"},
+			},
+		}),
+		sendRetries: 2,
+	}
+	n := newTestNotifier(repo, cfg)
+	n.deliverWebhooks(context.Background(), cfg, outboundAlert{Event: "stage_failed", DedupeKey: "k"})
+
+	if okCalls.Load() != 1 || badCalls.Load() != 1 {
+		t.Fatalf("calls = ok %d, bad %d, want one each", okCalls.Load(), badCalls.Load())
+	}
+	status := map[string]string{}
+	for _, d := range repo.deliveries {
+		status[d.Channel] = d.Status
+	}
+	want := map[string]string{"webhook:ok": observabilitymodel.AlertDeliverySent, "webhook:bad": observabilitymodel.AlertDeliveryFailed}
+	if !reflect.DeepEqual(status, want) {
+		t.Fatalf("delivery log = %v, want %v", status, want)
+	}
+	// One endpoint accepted the alert, so the channel is healthy.
+	if len(repo.healthFailures) != 0 {
+		t.Fatalf("health failures = %v, want none", repo.healthFailures)
+	}
+}
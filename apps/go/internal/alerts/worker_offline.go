@@ -0,0 +1,123 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// workerOfflineBatch collects the worker_heartbeat_lost alerts of one
+// application while its aggregation window is open.
+type workerOfflineBatch struct {
+	applicationName string
+	alerts          []outboundAlert
+}
+
+// workerOfflineAggregator holds worker_heartbeat_lost alerts per application
+// so that workers going offline together, e.g. during a deploy, raise one
+// alert instead of one each.
+type workerOfflineAggregator struct {
+	mu      sync.Mutex
+	batches map[int]*workerOfflineBatch
+}
+
+func newWorkerOfflineAggregator() *workerOfflineAggregator {
+	return &workerOfflineAggregator{batches: make(map[int]*workerOfflineBatch)}
+}
+
+// add holds alert in the batch of its application and reports whether it
+// opened that batch, in which case the caller flushes it once the window
+// ends.
+func (a *workerOfflineAggregator) add(alert outboundAlert) (opened bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	batch, ok := a.batches[alert.applicationID]
+	if !ok {
+		batch = &workerOfflineBatch{applicationName: alert.applicationName}
+		a.batches[alert.applicationID] = batch
+	}
+	batch.alerts = append(batch.alerts, alert)
+	return !ok
+}
+
+// take removes and returns the batch of applicationID; nil if none is open.
+func (a *workerOfflineAggregator) take(applicationID int) *workerOfflineBatch {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	batch := a.batches[applicationID]
+	delete(a.batches, applicationID)
+	return batch
+}
+
+// holdWorkerOffline starts or joins the aggregation window of a
+// worker_heartbeat_lost alert and reports whether the alert is held. Alerts
+// of unknown applications, and all alerts when
+// workerOfflineAggregateSeconds is unset, are not held.
+func (n *Notifier) holdWorkerOffline(cfg runtimeConfig, alert outboundAlert) bool {
+	if alert.Event != "worker_heartbeat_lost" || alert.applicationID == 0 || cfg.workerOfflineAggregateWindow <= 0 {
+		return false
+	}
+	if n.offline.add(alert) {
+		applicationID := alert.applicationID
+		time.AfterFunc(cfg.workerOfflineAggregateWindow, func() { n.flushWorkerOffline(applicationID) })
+	}
+	return true
+}
+
+// flushWorkerOffline sends the alerts held for applicationID once its window
+// ends: the alert itself when one worker went offline, otherwise a single
+// summary.
+func (n *Notifier) flushWorkerOffline(applicationID int) {
+	batch := n.offline.take(applicationID)
+	if batch == nil || len(batch.alerts) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*defaultHTTPTimeout)
+	defer cancel()
+	cfg, err := n.loadConfig(ctx)
+	if err != nil {
+		n.logger.Error("alerts config load failed", "err", err)
+		return
+	}
+	if !cfg.enabled {
+		return
+	}
+	n.sendLimited(ctx, cfg, workerOfflineAlert(applicationID, batch, time.Now().UTC()), 0)
+}
+
+// workerOfflineAlert returns the one alert of a batch, or a summary listing
+// the offline workers in details.workerIds.
+func workerOfflineAlert(applicationID int, batch *workerOfflineBatch, now time.Time) outboundAlert {
+	if len(batch.alerts) == 1 {
+		return batch.alerts[0]
+	}
+
+	workerIDs := make([]string, 0, len(batch.alerts))
+	for _, alert := range batch.alerts {
+		workerIDs = append(workerIDs, parseString(alert.Details["workerId"]))
+	}
+
+	application := fmt.Sprintf("application %d", applicationID)
+	if batch.applicationName != "" {
+		application = fmt.Sprintf("application %s (%d)", batch.applicationName, applicationID)
+	}
+	ts := now.Format(time.RFC3339)
+	return outboundAlert{
+		Event:     "worker_heartbeat_lost",
+		Title:     "Workers heartbeat lost",
+		Message:   fmt.Sprintf("%d workers of %s are offline", len(batch.alerts), application),
+		Severity:  "error",
+		Timestamp: ts,
+		DedupeKey: fmt.Sprintf("worker_offline:app:%d:%s", applicationID, ts),
+		Details: map[string]any{
+			"applicationId":   applicationID,
+			"applicationName": batch.applicationName,
+			"workerCount":     len(batch.alerts),
+			"workerIds":       workerIDs,
+		},
+	}
+}
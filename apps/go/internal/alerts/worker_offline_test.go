@@ -0,0 +1,77 @@
+package alerts
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"pipelogiq/internal/store"
+	"pipelogiq/internal/types"
+)
+
+func offlineAlert(t *testing.T, workerID string, applicationID int) outboundAlert {
+	t.Helper()
+	alert, ok := mapWorkerEvent(store.WorkerAlertEvent{
+		WorkerID:        workerID,
+		TS:              time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Level:           "INFO",
+		EventType:       "worker.state_changed",
+		Details:         map[string]any{"from": types.WorkerStateReady, "to": types.WorkerStateOffline},
+		ApplicationID:   applicationID,
+		ApplicationName: "billing",
+	})
+	if !ok || alert.Event != "worker_heartbeat_lost" {
+		t.Fatalf("mapWorkerEvent() = %+v, %v, want worker_heartbeat_lost", alert, ok)
+	}
+	return alert
+}
+
+func TestWorkerOfflineAggregatorBatchesPerApplication(t *testing.T) {
+	agg := newWorkerOfflineAggregator()
+	if !agg.add(offlineAlert(t, "w1", 7)) {
+		t.Fatal("first alert of application 7 did not open a batch")
+	}
+	if agg.add(offlineAlert(t, "w2", 7)) {
+		t.Fatal("second alert of application 7 opened another batch")
+	}
+	if !agg.add(offlineAlert(t, "w3", 8)) {
+		t.Fatal("first alert of application 8 did not open a batch")
+	}
+
+	now := time.Date(2026, 1, 2, 3, 5, 0, 0, time.UTC)
+	summary := workerOfflineAlert(7, agg.take(7), now)
+	if summary.Title != "Workers heartbeat lost" || summary.Message != "2 workers of application billing (7) are offline" {
+		t.Fatalf("summary = %q: %q", summary.Title, summary.Message)
+	}
+	if got, want := summary.Details["workerIds"], []string{"w1", "w2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("workerIds = %v, want %v", got, want)
+	}
+	if summary.DedupeKey != "worker_offline:app:7:2026-01-02T03:05:00Z" {
+		t.Fatalf("DedupeKey = %q", summary.DedupeKey)
+	}
+	if agg.take(7) != nil {
+		t.Fatal("batch of application 7 still open after take")
+	}
+
+	single := workerOfflineAlert(8, agg.take(8), now)
+	if single.DedupeKey != "worker_offline:w3" || single.Message != "Worker w3 is offline" {
+		t.Fatalf("single = %+v, want the per-worker alert", single)
+	}
+}
+
+func TestHoldWorkerOffline(t *testing.T) {
+	n := New(nil, nil)
+	alert := offlineAlert(t, "w1", 7)
+	if n.holdWorkerOffline(runtimeConfig{}, alert) {
+		t.Fatal("alert held without an aggregation window")
+	}
+
+	cfg := runtimeConfig{workerOfflineAggregateWindow: time.Hour}
+	unknownApp := offlineAlert(t, "w2", 0)
+	if n.holdWorkerOffline(cfg, unknownApp) {
+		t.Fatal("alert of an unknown application held")
+	}
+	if !n.holdWorkerOffline(cfg, alert) {
+		t.Fatal("alert not held within the aggregation window")
+	}
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+
+	"pipelogiq/internal/config"
+	"pipelogiq/internal/logger"
+)
+
+// accessLogMiddleware logs one line per request with its request id and the
+// trace id of the span started by otelhttp, so it must be mounted after both.
+// Requests are logged at ACCESS_LOG_LEVEL ("off" disables the log), 5xx
+// responses at warn or above. Health checks are not logged.
+func accessLogMiddleware(log *slog.Logger, cfg config.APIConfig) func(http.Handler) http.Handler {
+	if cfg.AccessLogLevel == "off" {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	level := logger.ParseLevel(cfg.AccessLogLevel)
+	skip := map[string]bool{
+		cfg.HealthLivenessEndpoint: true,
+		cfg.HealthReadyEndpoint:    true,
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skip[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Duration("latency", time.Since(start)),
+				slog.String("requestId", middleware.GetReqID(r.Context())),
+			}
+			if sc := trace.SpanContextFromContext(r.Context()); sc.HasTraceID() {
+				attrs = append(attrs, slog.String("traceId", sc.TraceID().String()))
+			}
+			if cfg.AccessLogBodySize {
+				attrs = append(attrs,
+					slog.Int64("requestBytes", r.ContentLength),
+					slog.Int("responseBytes", ww.BytesWritten()),
+				)
+			}
+
+			lvl := level
+			if status >= http.StatusInternalServerError && lvl < slog.LevelWarn {
+				lvl = slog.LevelWarn
+			}
+			log.LogAttrs(r.Context(), lvl, "http request", attrs...)
+		})
+	}
+}
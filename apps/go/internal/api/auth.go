@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -41,24 +42,24 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidPayload, "invalid request body")
 		return
 	}
 
 	if req.Email == "" || req.Password == "" {
-		http.Error(w, "email and password are required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "email and password are required")
 		return
 	}
 
 	user, storedHash, err := s.store.GetUserByEmail(ctx, req.Email)
 	if err != nil {
-		http.Error(w, "invalid credentials", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, errCodeInvalidCredentials, "invalid credentials")
 		return
 	}
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(storedHash), []byte(req.Password)); err != nil {
-		http.Error(w, "invalid credentials", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, errCodeInvalidCredentials, "invalid credentials")
 		return
 	}
 
@@ -66,7 +67,7 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	token, err := generateJWT(user.ID, user.Email)
 	if err != nil {
 		s.logger.Error("generate jwt failed", "err", err)
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "internal error")
 		return
 	}
 
@@ -87,7 +88,7 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleGetCurrentUser(w http.ResponseWriter, r *http.Request) {
 	userID := getUserIDFromContext(r.Context())
 	if userID == 0 {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "unauthorized")
 		return
 	}
 
@@ -96,7 +97,7 @@ func (s *Server) handleGetCurrentUser(w http.ResponseWriter, r *http.Request) {
 
 	user, err := s.store.GetUserByID(ctx, userID)
 	if err != nil {
-		http.Error(w, "user not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, errCodeUserNotFound, "user not found")
 		return
 	}
 
@@ -151,7 +152,7 @@ func (s *Server) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		cookie, err := r.Cookie(authCookieName)
 		if err != nil {
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "unauthorized")
 			return
 		}
 
@@ -164,7 +165,7 @@ func (s *Server) authMiddleware(next http.Handler) http.Handler {
 				Path:   "/",
 				MaxAge: -1,
 			})
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "unauthorized")
 			return
 		}
 
@@ -187,6 +188,36 @@ func (s *Server) optionalAuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// handleWS authenticates the WebSocket handshake before upgrading. Browsers
+// send the auth cookie; other clients can pass the JWT as an
+// "Authorization: Bearer" header or a "token" query parameter.
+func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
+	token := ""
+	if cookie, err := r.Cookie(authCookieName); err == nil {
+		token = cookie.Value
+	}
+	if token == "" {
+		if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+			token = strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+		}
+	}
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	if token == "" {
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "unauthorized")
+		return
+	}
+
+	claims, err := parseJWT(token)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "unauthorized")
+		return
+	}
+
+	s.hub.ServeWS(w, r, claims.UserID)
+}
+
 type contextKey string
 
 const userIDKey contextKey = "userID"
@@ -197,9 +228,3 @@ func getUserIDFromContext(ctx context.Context) int {
 	}
 	return 0
 }
-
-func writeJSONError(w http.ResponseWriter, message string, status int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
-}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"pipelogiq/internal/constants"
+	"pipelogiq/internal/types"
+)
+
+var errBrokerCredentialsNotProvisioned = errors.New("rabbit worker credentials are not provisioned")
+
+// workerBrokerURL returns the RabbitMQ URL handed to external workers. When
+// worker credentials are provisioned they replace the credentials (and, if
+// set, the vhost) of the server's own URL. Otherwise the raw URL is returned
+// only while RABBIT_EXPOSE_URL allows it.
+func (s *ExternalServer) workerBrokerURL() (string, error) {
+	raw := strings.TrimSpace(s.cfg.RabbitURL)
+	if s.cfg.RabbitWorkerUsername == "" {
+		if !s.cfg.RabbitExposeURL {
+			return "", errBrokerCredentialsNotProvisioned
+		}
+		return raw, nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("parse rabbit url: %w", err)
+	}
+	u.User = url.UserPassword(s.cfg.RabbitWorkerUsername, s.cfg.RabbitWorkerPassword)
+	if vhost := s.cfg.RabbitWorkerVHost; vhost != "" {
+		u.Path = "/" + vhost
+		u.RawPath = "/" + url.PathEscape(vhost)
+	}
+	return u.String(), nil
+}
+
+// workerBrokerSettings is the part of the bootstrap response that comes from
+// the server's environment rather than the database.
+type workerBrokerSettings struct {
+	MessageBroker types.WorkerBrokerInfo        `json:"messageBroker"`
+	Queues        types.WorkerQueueTopology     `json:"queues"`
+	Heartbeat     types.WorkerHeartbeatContract `json:"heartbeat"`
+}
+
+func (s *ExternalServer) workerBrokerConfig(brokerURL string) workerBrokerSettings {
+	resultShards := s.cfg.ResultQueueShards
+	if resultShards < 1 {
+		resultShards = 1
+	}
+	return workerBrokerSettings{
+		MessageBroker: types.WorkerBrokerInfo{
+			Type:              "rabbitmq",
+			ConnectionString:  brokerURL,
+			Prefetch:          s.cfg.QueuePrefetch,
+			TopologyOwnership: s.cfg.QueueTopologyOwnership,
+			DLQEnabled:        s.cfg.QueueDLQEnabled,
+			DLQTTLSec:         int64(s.cfg.QueueDLQMessageTTL.Seconds()),
+			QueueType:         s.cfg.QueueType,
+			MaxLength:         s.cfg.QueueMaxLength,
+			Overflow:          s.cfg.QueueOverflow,
+		},
+		Queues: types.WorkerQueueTopology{
+			StageResult:         constants.StageResult,
+			StageResultShards:   resultShards,
+			StageResultPattern:  resultQueuePattern(s.cfg.ResultQueueShards),
+			StageSetStatus:      constants.StageSetStatus,
+			StageUpdatedFanout:  constants.StageUpdated + ".fanout",
+			StageNextPattern:    "{appId}_{handler}_" + constants.StageNext,
+			StageNextEnvPattern: stageNextEnvPattern(s.cfg.StageEnvironmentRouting),
+		},
+		Heartbeat: types.WorkerHeartbeatContract{
+			IntervalSec:     int64(s.cfg.WorkerHeartbeatInterval.Seconds()),
+			OfflineAfterSec: int64(s.cfg.WorkerOfflineAfter.Seconds()),
+		},
+	}
+}
+
+// syncBrokerConfigVersion bumps the shared config version when this
+// instance hands workers different broker settings than the instance that
+// started before it, so workers bootstrapped with the old settings are told
+// to bootstrap again.
+func (s *ExternalServer) syncBrokerConfigVersion(ctx context.Context) error {
+	brokerURL, _ := s.workerBrokerURL()
+	raw, err := json.Marshal(s.workerBrokerConfig(brokerURL))
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(raw)
+	version, err := s.store.SyncBrokerConfigVersion(ctx, hex.EncodeToString(sum[:]))
+	if err != nil {
+		return err
+	}
+	s.logger.Info("worker config version", "version", version)
+	return nil
+}
+
+// redactBrokerURL masks the password of a broker URL handed to a worker so
+// it can be stored and shown; an unparsable URL is dropped entirely.
+func redactBrokerURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return u.Redacted()
+}
+
+// writeWorkerBrokerError answers a request whose broker URL cannot be handed
+// out; it reports whether err was set.
+func (s *ExternalServer) writeWorkerBrokerError(w http.ResponseWriter, err error) bool {
+	if reqErr := s.workerBrokerError(err); reqErr != nil {
+		writeRequestError(w, reqErr)
+		return true
+	}
+	return false
+}
+
+func (s *ExternalServer) workerBrokerError(err error) *requestError {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, errBrokerCredentialsNotProvisioned):
+		return newRequestError(http.StatusServiceUnavailable, errCodeUnavailable, err.Error())
+	default:
+		s.logger.Error("resolve worker broker url failed", "err", err)
+		return newRequestError(http.StatusInternalServerError, errCodeInternal, "failed to resolve rabbit connection")
+	}
+}
+
+// stageNextEnvPattern names the per-environment StageNext queues, which only
+// exist with STAGE_ENVIRONMENT_ROUTING.
+func stageNextEnvPattern(routing bool) string {
+	if !routing {
+		return ""
+	}
+	return "{appId}_{handler}_" + constants.StageNext + ".{environment}"
+}
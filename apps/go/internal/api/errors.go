@@ -0,0 +1,77 @@
+package api
+
+import "net/http"
+
+// Error codes returned in the "code" field of error responses. They are part
+// of the API contract: clients branch on them, so existing values must not
+// change.
+const (
+	errCodeInvalidPayload     = "invalid_payload"
+	errCodeInvalidRequest     = "invalid_request"
+	errCodePayloadTooLarge    = "payload_too_large"
+	errCodeUnauthorized       = "unauthorized"
+	errCodeInvalidCredentials = "invalid_credentials"
+	errCodeAPIKeyRequired     = "api_key_required"
+	errCodeInvalidAPIKey      = "invalid_api_key"
+	errCodeInsufficientScope  = "insufficient_scope"
+	errCodeForbidden          = "forbidden"
+	errCodeSessionRequired    = "session_required"
+	errCodeInvalidSession     = "invalid_session"
+	errCodeNotFound           = "not_found"
+	errCodePipelineNotFound   = "pipeline_not_found"
+	errCodePolicyNotFound     = "policy_not_found"
+	errCodeQueueNotFound      = "queue_not_found"
+	errCodeTokenNotFound      = "token_not_found"
+	errCodeUserNotFound       = "user_not_found"
+	errCodeWorkerNotFound     = "worker_not_found"
+	errCodePipelineNotRunning = "pipeline_not_running"
+	errCodePipelineRunning    = "pipeline_running"
+	errCodeHandlerUnavailable = "handler_unavailable"
+	errCodeRateLimited        = "rate_limited"
+	errCodeTooManyInFlight    = "too_many_inflight"
+	errCodeUnavailable        = "unavailable"
+	errCodeInternal           = "internal_error"
+)
+
+// errorEnvelope is the body of every error response. It has the same shape
+// as the observability API errors.
+type errorEnvelope struct {
+	Error apiError `json:"error"`
+}
+
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
+}
+
+// requestError is the outcome of a request that failed independently of its
+// transport, so the HTTP and gRPC worker APIs report the same codes.
+type requestError struct {
+	status  int
+	code    string
+	message string
+}
+
+func newRequestError(status int, code, message string) *requestError {
+	return &requestError{status: status, code: code, message: message}
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+// writeRequestError writes err as a JSON error response.
+func writeRequestError(w http.ResponseWriter, err *requestError) {
+	writeError(w, err.status, err.code, err.message)
+}
+
+// writeError writes a JSON error response with a stable code and a
+// human-readable message.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeErrorDetails(w, status, code, message, nil)
+}
+
+func writeErrorDetails(w http.ResponseWriter, status int, code, message string, details any) {
+	writeJSON(w, errorEnvelope{Error: apiError{Code: code, Message: message, Details: details}}, status)
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"strings"
 	"sync"
@@ -21,7 +22,9 @@ import (
 	"pipelogiq/internal/config"
 	"pipelogiq/internal/constants"
 	"pipelogiq/internal/mq"
+	"pipelogiq/internal/ratelimit"
 	"pipelogiq/internal/store"
+	"pipelogiq/internal/telemetry"
 	"pipelogiq/internal/types"
 	"pipelogiq/internal/version"
 )
@@ -34,25 +37,49 @@ type ExternalServer struct {
 	mq     *mq.Client
 	logger *slog.Logger
 	server *http.Server
+	// source is where POST /jobs/pull takes messages from; it is mq outside
+	// tests.
+	source jobSource
 
 	pendingMu sync.Mutex
 	pending   map[string]pendingAck
 
+	policies *policyRepository
+	limiter  *rateLimiter
+
 	metrics externalMetrics
 }
 
+// jobSource hands out queued messages to POST /jobs/pull; *mq.Client
+// implements it.
+type jobSource interface {
+	Get(ctx context.Context, queue string, opts mq.QueueOptions) (*mq.GetResult, error)
+	GetWait(ctx context.Context, queue string, opts mq.QueueOptions, wait time.Duration) ([]*mq.GetResult, error)
+}
+
 type pendingAck struct {
 	ack     func() error
 	nack    func(bool) error
 	queue   string
 	expires time.Time
+	// lease is the stage dispatch the message carries; its stageID is 0 for
+	// messages that are not stage jobs.
+	lease stageLease
+}
+
+// stageLease identifies one dispatch of a stage, as read from its job.
+type stageLease struct {
+	stageID        int
+	idempotencyKey string
 }
 
 type externalMetrics struct {
-	pipelinesCreated prometheus.Counter
-	stageJobsPulled  prometheus.Counter
-	stageJobsAcked   prometheus.Counter
-	stageJobsNacked  prometheus.Counter
+	pipelinesCreated  prometheus.Counter
+	stageJobsPulled   prometheus.Counter
+	stageJobsAcked    prometheus.Counter
+	stageJobsNacked   prometheus.Counter
+	stageJobsExtends  *prometheus.CounterVec
+	requestsThrottled *prometheus.CounterVec
 }
 
 func NewExternalServer(cfg config.APIConfig, st *store.Store, mqClient *mq.Client, logger *slog.Logger) *ExternalServer {
@@ -73,59 +100,98 @@ func NewExternalServer(cfg config.APIConfig, st *store.Store, mqClient *mq.Clien
 			Name: "ext_stage_jobs_nacked_total",
 			Help: "Number of stage jobs nacked/requeued via external gateway",
 		}),
+		stageJobsExtends: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ext_stage_jobs_extend_total",
+			Help: "Number of visibility extension requests via external gateway by result (granted/denied)",
+		}, []string{"result"}),
+		requestsThrottled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ext_requests_throttled_total",
+			Help: "Number of external API requests rejected by the per-key rate limiter, by route",
+		}, []string{"route"}),
 	}
-	prometheus.MustRegister(metrics.pipelinesCreated, metrics.stageJobsPulled, metrics.stageJobsAcked, metrics.stageJobsNacked)
+	prometheus.MustRegister(metrics.pipelinesCreated, metrics.stageJobsPulled, metrics.stageJobsAcked, metrics.stageJobsNacked, metrics.stageJobsExtends, metrics.requestsThrottled)
 
 	return &ExternalServer{
 		cfg:     cfg,
 		store:   st,
 		mq:      mqClient,
+		source:  mqClient,
 		logger:  logger,
 		pending: make(map[string]pendingAck),
+		limiter: newRateLimiter(ratelimit.NewMemoryStore(), st, cfg.ExternalRateLimitRPS, cfg.ExternalRateLimitBurst),
 		metrics: metrics,
 	}
 }
 
+// AttachPolicies shares the internal server's policy repository so triggers
+// reported by SDK workers land in the same store the dashboard reads.
+func (s *ExternalServer) AttachPolicies(internal *Server) {
+	s.policies = internal.policies
+}
+
+// SetLimiterStore counts the per-key rate limit in store instead of process
+// memory, so all API replicas share one limit.
+func (s *ExternalServer) SetLimiterStore(store ratelimit.LimiterStore) {
+	s.limiter = newRateLimiter(store, s.store, s.cfg.ExternalRateLimitRPS, s.cfg.ExternalRateLimitBurst)
+}
+
 func (s *ExternalServer) Run(ctx context.Context) error {
 	router := chi.NewRouter()
 
+	router.Use(telemetry.CapturePeerAddr)
 	router.Use(middleware.RequestID)
 	router.Use(middleware.RealIP)
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.Timeout(60 * time.Second))
 	router.Use(otelhttp.NewMiddleware("pipelogiq-api-external"))
-	router.Use(corsMiddleware)
+	router.Use(accessLogMiddleware(s.logger, s.cfg))
+	router.Use(corsMiddleware(s.cfg.CORSAllowedOrigins))
 
 	// Health and version
-	router.Get(s.cfg.HealthLivenessEndpoint, func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("ok"))
-	})
-	router.Get(s.cfg.HealthReadyEndpoint, func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("ok"))
-	})
+	router.Get(s.cfg.HealthLivenessEndpoint, handleLiveness)
+	router.Get(s.cfg.HealthReadyEndpoint, readinessHandler(s.store, s.mq, s.logger))
 	router.Get("/version", version.HandleVersion)
 
 	// External routes — no JWT, API key validated in handler
-	router.Post("/pipelines", s.handleCreatePipeline)
-	router.Post("/jobs/pull", s.handlePullJob)
-	router.Post("/jobs/ack", s.handleAckJob)
-	router.Post("/logs", s.handleSaveLog)
-	router.Post("/workers/bootstrap", s.handleWorkerBootstrap)
-	router.Post("/workers/heartbeat", s.handleWorkerHeartbeat)
-	router.Post("/workers/events", s.handleWorkerEvents)
-	router.Post("/workers/shutdown", s.handleWorkerShutdown)
-	router.Get("/rabbitmq/connection", s.handleGetRabbitConnection)
+	router.Group(func(r chi.Router) {
+		r.Use(s.rateLimitMiddleware)
+
+		r.Post("/pipelines", s.handleCreatePipeline)
+		r.Post("/jobs/pull", s.handlePullJob)
+		r.Post("/jobs/ack", s.handleAckJob)
+		r.Post("/jobs/extend", s.handleExtendJob)
+		r.Post("/logs", s.handleSaveLog)
+		r.Post("/workers/bootstrap", s.handleWorkerBootstrap)
+		r.Post("/workers/heartbeat", s.handleWorkerHeartbeat)
+		r.Post("/workers/events", s.handleWorkerEvents)
+		r.Post("/workers/shutdown", s.handleWorkerShutdown)
+		r.Get("/rabbitmq/connection", s.handleGetRabbitConnection)
+		r.Post("/policies/{id}/trigger", s.handlePolicyTrigger)
+	})
 
 	s.server = &http.Server{
 		Addr:    s.cfg.ExternalHTTPAddr,
 		Handler: router,
+		// Request contexts end on shutdown so long-polling pulls stop waiting.
+		BaseContext: func(net.Listener) context.Context { return ctx },
 	}
 
+	syncCtx, cancelSync := context.WithTimeout(ctx, 5*time.Second)
+	if err := s.syncBrokerConfigVersion(syncCtx); err != nil {
+		s.logger.Warn("sync worker config version failed", "err", err)
+	}
+	cancelSync()
+
 	go s.cleanupExpired(ctx)
 
-	errCh := make(chan error, 1)
+	errCh := make(chan error, 2)
+	if s.cfg.WorkerGRPCAddr != "" {
+		go func() {
+			if err := s.runWorkerGRPC(ctx); err != nil {
+				errCh <- err
+			}
+		}()
+	}
 	go func() {
 		s.logger.Info("external api listening", "addr", s.cfg.ExternalHTTPAddr)
 		if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -138,74 +204,114 @@ func (s *ExternalServer) Run(ctx context.Context) error {
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 		_ = s.server.Shutdown(shutdownCtx)
+		s.closeLimiterStore()
 		return nil
 	case err := <-errCh:
+		s.closeLimiterStore()
 		return err
 	}
 }
 
+// closeLimiterStore releases the rate limit store's connections on shutdown.
+func (s *ExternalServer) closeLimiterStore() {
+	if s.limiter == nil {
+		return
+	}
+	if err := ratelimit.Close(s.limiter.store); err != nil {
+		s.logger.Warn("close rate limit store failed", "err", err)
+	}
+}
+
 // --- Handlers ---
 
+// unservedStageHandlers lists the handlers of the non-event stages that no
+// online worker of the application advertises. Event stages are skipped: they
+// are published to their queue and wait there for a consumer.
+func (s *ExternalServer) unservedStageHandlers(ctx context.Context, appID int, stages []types.StageCreate) ([]string, error) {
+	handlers := make([]string, 0, len(stages))
+	for _, stage := range stages {
+		if stage.IsEvent || strings.TrimSpace(stage.StageHandler) == "" {
+			continue
+		}
+		handlers = append(handlers, stage.StageHandler)
+	}
+	return s.store.UnservedHandlers(ctx, appID, handlers)
+}
+
 func (s *ExternalServer) handleCreatePipeline(w http.ResponseWriter, r *http.Request) {
 	var req types.PipelineCreateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid payload", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidPayload, "invalid payload")
 		return
 	}
 	if req.Name == "" || len(req.Stages) == 0 {
-		http.Error(w, "name and stages are required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "name and stages are required")
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
 
-	appID, err := s.store.ValidateAPIKey(ctx, req.ApiKey)
+	auth, ok := s.authorizeAPIKey(ctx, w, req.ApiKey, types.APIKeyScopePipelinesWrite)
+	if !ok {
+		return
+	}
+	appID := auth.ApplicationID
+
+	strictHandlers := s.cfg.PipelineStrictHandlers
+	if req.StrictHandlers != nil {
+		strictHandlers = *req.StrictHandlers
+	}
+	unserved, err := s.unservedStageHandlers(ctx, appID, req.Stages)
 	if err != nil {
-		http.Error(w, "invalid api key", http.StatusUnauthorized)
+		// The check is advisory; never fail creation because of it.
+		s.logger.Warn("check stage handlers failed", "err", err, "applicationId", appID)
+	}
+	if strictHandlers && len(unserved) > 0 {
+		writeErrorDetails(w, http.StatusUnprocessableEntity, errCodeHandlerUnavailable,
+			"no online worker supports handlers: "+strings.Join(unserved, ", "),
+			map[string]any{"handlers": unserved})
 		return
 	}
 
 	pipeline, err := s.store.CreatePipeline(ctx, req, appID)
 	if err != nil {
+		if store.IsInvalidStageDependenciesError(err) || store.IsInvalidContextItemError(err) ||
+			store.IsInvalidPipelineLabelError(err) || store.IsInvalidPipelinePriorityError(err) ||
+			store.IsInvalidStageTimeoutActionError(err) {
+			writeError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+			return
+		}
 		s.logger.Error("create pipeline failed", "err", err)
-		http.Error(w, "failed to create pipeline", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to create pipeline")
 		return
 	}
 
 	s.metrics.pipelinesCreated.Inc()
+	for _, handler := range unserved {
+		pipeline.Warnings = append(pipeline.Warnings, fmt.Sprintf("no online worker supports handler %q", handler))
+	}
 
 	// Auto-fire event pipelines (single stage marked as event)
-	if pipeline.IsEvent != nil && *pipeline.IsEvent && len(pipeline.Stages) == 1 {
-		stage := pipeline.Stages[0]
-		msg := types.StageNextMessage{
-			AppID:            appID,
-			PipelineID:       &pipeline.ID,
-			StageID:          stage.ID,
-			TraceID:          pipeline.TraceID,
-			SpanID:           stage.SpanID,
-			StageHandlerName: stage.StageHandlerName,
-			Input:            deref(stage.Input),
-			ContextItems:     pipeline.PipelineContext,
-		}
-		body, _ := json.Marshal(msg)
-		opts := mq.QueueOptions{
-			Durable:     true,
-			DLQEnabled:  s.cfg.QueueDLQEnabled,
-			DLQTTL:      s.cfg.QueueDLQMessageTTL,
-			ContentType: "application/json",
-		}
-		queue := extStageQueueName(s.cfg.AppID, stage.StageHandlerName)
-		if err := s.mq.PublishWithRetry(ctx, queue, body, opts, nil); err != nil {
-			s.logger.Error("failed to publish event stage", "err", err, "queue", queue)
-		}
+	if err := publishEventPipeline(ctx, s.cfg, s.mq, appID, pipeline); err != nil {
+		s.logger.Error("failed to publish event stage", "err", err, "pipelineId", pipeline.ID)
 	}
 
 	writeJSON(w, pipeline, http.StatusOK)
 }
 
+// maxPullWait caps pullRequest.WaitMs and maxPullBatch caps pullRequest.MaxMessages.
+const (
+	maxPullWait  = 20 * time.Second
+	maxPullBatch = 100
+)
+
 type pullRequest struct {
 	Queue string `json:"queue"`
+	// WaitMs long-polls for up to this many milliseconds when the queue is empty.
+	WaitMs int `json:"waitMs,omitempty"`
+	// MaxMessages switches the response to a batch (array) of up to this many jobs.
+	MaxMessages int `json:"maxMessages,omitempty"`
 }
 
 type pullResponse struct {
@@ -214,116 +320,414 @@ type pullResponse struct {
 	MessageID string          `json:"messageId,omitempty"`
 	Payload   json.RawMessage `json:"payload"`
 	Headers   amqp.Table      `json:"headers,omitempty"`
+	ExpiresAt time.Time       `json:"expiresAt"`
 }
 
 func (s *ExternalServer) handlePullJob(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeOptionalAPIKey(r.Context(), w, r, types.APIKeyScopeJobsConsume) {
+		return
+	}
+
 	var req pullRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Queue) == "" {
-		http.Error(w, "queue is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "queue is required")
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	wait := min(time.Duration(max(req.WaitMs, 0))*time.Millisecond, maxPullWait)
+	batch := req.MaxMessages > 0
+	want := min(max(req.MaxMessages, 1), maxPullBatch)
+
+	// Pull no more than the in-flight budget currently allows.
+	s.pendingMu.Lock()
+	available := s.cfg.GatewayMaxInFlight - len(s.pending)
+	s.pendingMu.Unlock()
+	if available <= 0 {
+		writeError(w, http.StatusTooManyRequests, errCodeTooManyInFlight, "too many in-flight messages, try again")
+		return
+	}
+	want = min(want, available)
+
+	ctx, cancel := context.WithTimeout(r.Context(), wait+5*time.Second)
 	defer cancel()
 
+	// Waiting may hand over several messages at once, but never more than
+	// the request wants.
 	opts := mq.QueueOptions{
 		Durable:    true,
 		DLQEnabled: s.cfg.QueueDLQEnabled,
 		DLQTTL:     s.cfg.QueueDLQMessageTTL,
-		Prefetch:   1,
+		Prefetch:   min(s.pullPrefetch(req.Queue), want),
+		QueueType:  s.cfg.QueueType,
+		MaxLength:  s.cfg.QueueMaxLength,
+		Overflow:   s.cfg.QueueOverflow,
+	}
+
+	jobs := make([]pullResponse, 0, want)
+	limited := false
+	for len(jobs) < want && !limited {
+		var msgs []*mq.GetResult
+		var err error
+		if len(jobs) == 0 {
+			msgs, err = s.source.GetWait(ctx, req.Queue, opts, wait)
+		} else {
+			var msg *mq.GetResult
+			if msg, err = s.source.Get(ctx, req.Queue, opts); msg != nil {
+				msgs = []*mq.GetResult{msg}
+			}
+		}
+		if err != nil {
+			if len(jobs) > 0 {
+				// Hand out what was already pulled; the client retries for more.
+				s.logger.Warn("pull job batch cut short", "err", err, "queue", req.Queue, "pulled", len(jobs))
+				break
+			}
+			s.logger.Error("pull job failed", "err", err, "queue", req.Queue)
+			writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to pull")
+			return
+		}
+		if len(msgs) == 0 {
+			break
+		}
+
+		for i, msg := range msgs {
+			expires := time.Now().Add(s.cfg.GatewayVisibilityTTL)
+			lease, live := s.leaseStageJob(ctx, msg.Body, expires)
+			if !live {
+				// The stage was dispatched again or settled after this copy was
+				// requeued; running it would repeat the work.
+				_ = msg.Ack()
+				continue
+			}
+
+			job, ok := s.trackPending(msg, req.Queue, lease, expires)
+			if !ok {
+				// Concurrent pulls used up the budget mid-batch.
+				for _, rest := range msgs[i:] {
+					_ = rest.Nack(true)
+				}
+				limited = true
+				break
+			}
+			jobs = append(jobs, job)
+		}
 	}
 
-	msg, err := s.mq.Get(ctx, req.Queue, opts)
-	if err != nil {
-		s.logger.Error("pull job failed", "err", err, "queue", req.Queue)
-		http.Error(w, "failed to pull", http.StatusInternalServerError)
+	if len(jobs) == 0 {
+		if limited {
+			writeError(w, http.StatusTooManyRequests, errCodeTooManyInFlight, "too many in-flight messages, try again")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
 		return
 	}
-	if msg == nil {
-		w.WriteHeader(http.StatusNoContent)
+
+	s.metrics.stageJobsPulled.Add(float64(len(jobs)))
+	if batch {
+		writeJSON(w, jobs, http.StatusOK)
 		return
 	}
+	writeJSON(w, jobs[0], http.StatusOK)
+}
+
+// pullPrefetch returns how many messages POST /jobs/pull may take from queue
+// in one broker delivery.
+func (s *ExternalServer) pullPrefetch(queue string) int {
+	if n, ok := s.cfg.GatewayQueuePrefetch[queue]; ok {
+		return n
+	}
+	return s.cfg.GatewayPullPrefetch
+}
+
+// leaseStageJob records the lease of the stage job in body until expires, so
+// the stage is not reconciled as orphaned while a worker holds it. It reports
+// false when the job is stale. Bodies that are not stage jobs, and lease
+// errors, let the job through untracked.
+func (s *ExternalServer) leaseStageJob(ctx context.Context, body []byte, expires time.Time) (stageLease, bool) {
+	var job types.StageNextMessage
+	if err := json.Unmarshal(body, &job); err != nil || job.StageID == 0 || job.IdempotencyKey == "" {
+		return stageLease{}, true
+	}
+	lease := stageLease{stageID: job.StageID, idempotencyKey: job.IdempotencyKey}
+	live, err := s.store.LeaseStage(ctx, lease.stageID, lease.idempotencyKey, expires)
+	if err != nil {
+		s.logger.Warn("lease stage job failed", "err", err, "stageId", lease.stageID)
+		return stageLease{}, true
+	}
+	if !live {
+		s.logger.Info("dropped stale stage job", "stageId", lease.stageID, "idempotencyKey", lease.idempotencyKey)
+	}
+	return lease, live
+}
 
+// trackPending registers a pulled message under a new token, unless the
+// gateway already holds GatewayMaxInFlight unsettled messages.
+func (s *ExternalServer) trackPending(msg *mq.GetResult, queue string, lease stageLease, expires time.Time) (pullResponse, bool) {
 	token := uuid.NewString()
+
 	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
 	if len(s.pending) >= s.cfg.GatewayMaxInFlight {
-		s.pendingMu.Unlock()
-		_ = msg.Nack(true)
-		http.Error(w, "too many in-flight messages, try again", http.StatusTooManyRequests)
-		return
+		return pullResponse{}, false
 	}
 	s.pending[token] = pendingAck{
 		ack:     msg.Ack,
 		nack:    msg.Nack,
-		queue:   req.Queue,
-		expires: time.Now().Add(s.cfg.GatewayVisibilityTTL),
+		queue:   queue,
+		expires: expires,
+		lease:   lease,
 	}
-	s.pendingMu.Unlock()
-
-	s.metrics.stageJobsPulled.Inc()
-	writeJSON(w, pullResponse{
+	return pullResponse{
 		Token:     token,
-		Queue:     req.Queue,
+		Queue:     queue,
 		MessageID: msg.MessageID,
 		Payload:   json.RawMessage(msg.Body),
 		Headers:   msg.Headers,
-	}, http.StatusOK)
+		ExpiresAt: expires.UTC(),
+	}, true
 }
 
 type ackRequest struct {
-	Token   string `json:"token"`
-	Requeue bool   `json:"requeue"`
+	Token string `json:"token"`
+	// Tokens settles several pulled jobs in one call; the response then lists
+	// a result per token.
+	Tokens  []string `json:"tokens,omitempty"`
+	Requeue bool     `json:"requeue"`
+}
+
+type ackResult struct {
+	Token  string `json:"token"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
 }
 
 func (s *ExternalServer) handleAckJob(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeOptionalAPIKey(r.Context(), w, r, types.APIKeyScopeJobsConsume) {
+		return
+	}
+
 	var req ackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil ||
+		(strings.TrimSpace(req.Token) == "" && len(req.Tokens) == 0) {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "token is required")
+		return
+	}
+
+	if len(req.Tokens) == 0 {
+		switch err := s.settle(req.Token, req.Requeue); {
+		case errors.Is(err, errTokenNotFound):
+			writeError(w, http.StatusNotFound, errCodeTokenNotFound, "token not found")
+		case err != nil:
+			writeError(w, http.StatusInternalServerError, errCodeInternal, "ack failed")
+		default:
+			writeJSON(w, map[string]string{"status": "ok"}, http.StatusOK)
+		}
+		return
+	}
+
+	tokens := req.Tokens
+	if strings.TrimSpace(req.Token) != "" {
+		tokens = append([]string{req.Token}, tokens...)
+	}
+	results := make([]ackResult, 0, len(tokens))
+	for _, token := range tokens {
+		switch err := s.settle(token, req.Requeue); {
+		case errors.Is(err, errTokenNotFound):
+			results = append(results, ackResult{Token: token, Status: "not_found", Error: "token not found"})
+		case err != nil:
+			results = append(results, ackResult{Token: token, Status: "error", Error: err.Error()})
+		default:
+			results = append(results, ackResult{Token: token, Status: "ok"})
+		}
+	}
+	writeJSON(w, results, http.StatusOK)
+}
+
+var errTokenNotFound = errors.New("token not found")
+
+// settle acks or requeues the pending message behind token.
+func (s *ExternalServer) settle(token string, requeue bool) error {
+	s.pendingMu.Lock()
+	msg, ok := s.pending[token]
+	if ok {
+		delete(s.pending, token)
+	}
+	s.pendingMu.Unlock()
+
+	if !ok {
+		return errTokenNotFound
+	}
+
+	if requeue {
+		s.metrics.stageJobsNacked.Inc()
+		return msg.nack(true)
+	}
+	s.metrics.stageJobsAcked.Inc()
+	return msg.ack()
+}
+
+type extendRequest struct {
+	Token string `json:"token"`
+}
+
+type extendResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// handleExtendJob pushes a pulled job's visibility deadline forward by
+// GatewayVisibilityTTL so long-running handlers keep their lease.
+func (s *ExternalServer) handleExtendJob(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeOptionalAPIKey(r.Context(), w, r, types.APIKeyScopeJobsConsume) {
+		return
+	}
+
+	var req extendRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Token) == "" {
-		http.Error(w, "token is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "token is required")
 		return
 	}
 
+	now := time.Now()
 	s.pendingMu.Lock()
 	msg, ok := s.pending[req.Token]
-	if ok {
+	expired := ok && now.After(msg.expires)
+	if expired {
+		// Lapsed but not yet swept by cleanupExpired: release it now so the
+		// token cannot be revived after the message was due for redelivery.
 		delete(s.pending, req.Token)
+	} else if ok {
+		msg.expires = now.Add(s.cfg.GatewayVisibilityTTL)
+		s.pending[req.Token] = msg
 	}
 	s.pendingMu.Unlock()
 
+	if expired {
+		_ = msg.nack(true)
+	}
+	if !ok || expired {
+		s.metrics.stageJobsExtends.WithLabelValues("denied").Inc()
+		writeError(w, http.StatusNotFound, errCodeTokenNotFound, "token not found or expired")
+		return
+	}
+
+	if msg.lease.stageID != 0 {
+		live, err := s.store.LeaseStage(r.Context(), msg.lease.stageID, msg.lease.idempotencyKey, msg.expires)
+		if err != nil {
+			s.logger.Warn("renew stage lease failed", "err", err, "stageId", msg.lease.stageID)
+		} else if !live {
+			// The stage was reconciled away from this worker; release the job
+			// rather than let it run on.
+			s.pendingMu.Lock()
+			_, held := s.pending[req.Token]
+			delete(s.pending, req.Token)
+			s.pendingMu.Unlock()
+			if held {
+				_ = msg.ack()
+			}
+			s.metrics.stageJobsExtends.WithLabelValues("denied").Inc()
+			writeError(w, http.StatusNotFound, errCodeTokenNotFound, "job was reassigned")
+			return
+		}
+	}
+
+	s.metrics.stageJobsExtends.WithLabelValues("granted").Inc()
+	writeJSON(w, extendResponse{Token: req.Token, ExpiresAt: msg.expires.UTC()}, http.StatusOK)
+}
+
+func (s *ExternalServer) handlePolicyTrigger(w http.ResponseWriter, r *http.Request) {
+	if s.policies == nil {
+		writeError(w, http.StatusServiceUnavailable, errCodeUnavailable, "policies are not available")
+		return
+	}
+
+	var req types.PolicyTriggerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errCodeInvalidPayload, "invalid payload")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	auth, ok := s.authorizeAPIKey(ctx, w, extractAPIKey(r), types.APIKeyScopeJobsConsume)
 	if !ok {
-		http.Error(w, "token not found", http.StatusNotFound)
 		return
 	}
 
-	var err error
-	if req.Requeue {
-		err = msg.nack(true)
-		s.metrics.stageJobsNacked.Inc()
-	} else {
-		err = msg.ack()
-		s.metrics.stageJobsAcked.Inc()
+	if req.PipelineID != nil {
+		pipelineAppID, err := s.store.PipelineApplicationID(ctx, *req.PipelineID)
+		if err != nil || pipelineAppID != auth.ApplicationID {
+			writeError(w, http.StatusBadRequest, errCodePipelineNotFound, "pipeline not found")
+			return
+		}
+	}
+
+	appName, err := s.store.GetApplicationNameByID(ctx, auth.ApplicationID)
+	if err != nil {
+		s.logger.Error("load application for policy trigger failed", "err", err, "applicationId", auth.ApplicationID)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to resolve application")
+		return
+	}
+	actor := fmt.Sprintf("app:%s", appName)
+
+	details := cloneMap(req.Details)
+	if details == nil {
+		details = map[string]any{}
+	}
+	details["blocked"] = req.Blocked
+	details["applicationId"] = auth.ApplicationID
+	if reason := strings.TrimSpace(req.Reason); reason != "" {
+		details["reason"] = reason
+	}
+	if action := strings.TrimSpace(req.Action); action != "" {
+		details["action"] = action
+	}
+	if req.PipelineID != nil {
+		details["pipelineId"] = *req.PipelineID
+	}
+	if req.StageID != nil {
+		details["stageId"] = *req.StageID
 	}
 
+	event, err := s.policies.recordTrigger(chi.URLParam(r, "id"), actor, details)
 	if err != nil {
-		http.Error(w, "ack failed", http.StatusInternalServerError)
+		if errors.Is(err, errPolicyNotFound) {
+			writeError(w, http.StatusNotFound, errCodePolicyNotFound, "policy not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to record policy trigger")
 		return
 	}
-	writeJSON(w, map[string]string{"status": "ok"}, http.StatusOK)
+
+	writeJSON(w, event, http.StatusCreated)
 }
 
 func (s *ExternalServer) handleSaveLog(w http.ResponseWriter, r *http.Request) {
 	var req types.LogRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid payload", http.StatusBadRequest)
+	if reqErr := decodeIngestBody(w, r, s.cfg.IngestMaxBodyBytes, &req); reqErr != nil {
+		writeRequestError(w, reqErr)
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	log, err := s.store.SaveLog(ctx, req)
+	// The log is stored under the key's application, so the key must be valid
+	// and allowed to write.
+	apiKey := extractAPIKey(r)
+	if apiKey == "" {
+		apiKey = deref(req.ApiKey)
+	}
+	auth, ok := s.authorizeAPIKey(ctx, w, apiKey, types.APIKeyScopePipelinesWrite)
+	if !ok {
+		return
+	}
+
+	log, err := s.store.SaveLog(ctx, auth.ApplicationID, req)
 	if err != nil {
 		s.logger.Error("save log failed", "err", err)
-		http.Error(w, "failed to save log", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to save log")
 		return
 	}
 
@@ -334,242 +738,97 @@ func (s *ExternalServer) handleGetRabbitConnection(w http.ResponseWriter, r *htt
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	apiKey := extractAPIKey(r)
-	if strings.TrimSpace(apiKey) == "" {
-		http.Error(w, "api key is required", http.StatusUnauthorized)
+	if _, ok := s.authorizeAPIKey(ctx, w, extractAPIKey(r), types.APIKeyScopeWorkersRegister); !ok {
 		return
 	}
 
-	if _, err := s.store.ValidateAPIKey(ctx, apiKey); err != nil {
-		http.Error(w, "invalid api key", http.StatusUnauthorized)
+	if strings.TrimSpace(s.cfg.RabbitURL) == "" {
+		writeError(w, http.StatusServiceUnavailable, errCodeUnavailable, "rabbit connection is not configured")
 		return
 	}
-
-	if strings.TrimSpace(s.cfg.RabbitURL) == "" {
-		http.Error(w, "rabbit connection is not configured", http.StatusServiceUnavailable)
+	brokerURL, err := s.workerBrokerURL()
+	if s.writeWorkerBrokerError(w, err) {
 		return
 	}
 
 	writeJSON(w, types.RabbitConnectionResponse{
-		ConnectionString: s.cfg.RabbitURL,
+		ConnectionString: brokerURL,
 	}, http.StatusOK)
 }
 
 func (s *ExternalServer) handleWorkerBootstrap(w http.ResponseWriter, r *http.Request) {
 	var req types.WorkerBootstrapRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid payload", http.StatusBadRequest)
-		return
-	}
-	if strings.TrimSpace(req.WorkerName) == "" {
-		http.Error(w, "workerName is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidPayload, "invalid payload")
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
 
-	apiKey := extractAPIKey(r)
-	if strings.TrimSpace(apiKey) == "" {
-		http.Error(w, "api key is required", http.StatusUnauthorized)
-		return
-	}
-
-	appID, err := s.store.ValidateAPIKey(ctx, apiKey)
-	if err != nil {
-		http.Error(w, "invalid api key", http.StatusUnauthorized)
-		return
-	}
-
-	if strings.TrimSpace(s.cfg.RabbitURL) == "" {
-		http.Error(w, "rabbit connection is not configured", http.StatusServiceUnavailable)
+	response, reqErr := s.bootstrapWorker(ctx, extractAPIKey(r), req)
+	if reqErr != nil {
+		writeRequestError(w, reqErr)
 		return
 	}
 
-	appName, err := s.store.GetApplicationNameByID(ctx, appID)
-	if err != nil {
-		s.logger.Error("load application for bootstrap failed", "err", err, "applicationId", appID)
-		http.Error(w, "failed to resolve application", http.StatusInternalServerError)
-		return
-	}
-
-	sessionToken := uuid.NewString() + "." + uuid.NewString()
-	sessionExpiresAt := time.Now().UTC().Add(s.cfg.WorkerSessionTTL)
-	workerID, err := s.store.RegisterWorkerSession(
-		ctx,
-		appID,
-		s.cfg.AppID,
-		"rabbitmq",
-		req,
-		sessionToken,
-		sessionExpiresAt,
-	)
-	if err != nil {
-		s.logger.Error("register worker session failed", "err", err, "applicationId", appID)
-		http.Error(w, "failed to register worker", http.StatusInternalServerError)
-		return
-	}
-
-	traceTemplate := ""
-	logsTemplate := ""
-	if trace, logs, err := s.store.GetObservabilityLinkTemplates(ctx); err == nil {
-		traceTemplate = trace
-		logsTemplate = logs
-	} else {
-		s.logger.Warn("load observability templates failed for bootstrap", "err", err)
-	}
-
-	response := types.WorkerBootstrapResponse{
-		WorkerID:           workerID,
-		WorkerSessionToken: sessionToken,
-		ConfigVersion:      time.Now().UTC().Format(time.RFC3339),
-		Application: types.WorkerApplicationInfo{
-			ApplicationID:   appID,
-			ApplicationName: appName,
-			AppID:           s.cfg.AppID,
-		},
-		MessageBroker: types.WorkerBrokerInfo{
-			Type:              "rabbitmq",
-			ConnectionString:  s.cfg.RabbitURL,
-			Prefetch:          s.cfg.QueuePrefetch,
-			TopologyOwnership: s.cfg.QueueTopologyOwnership,
-			DLQEnabled:        s.cfg.QueueDLQEnabled,
-			DLQTTLSec:         int64(s.cfg.QueueDLQMessageTTL.Seconds()),
-		},
-		Queues: types.WorkerQueueTopology{
-			StageResult:        constants.StageResult,
-			StageSetStatus:     constants.StageSetStatus,
-			StageUpdatedFanout: constants.StageUpdated + ".fanout",
-			StageNextPattern:   "{appId}_{handler}_" + constants.StageNext,
-		},
-		Heartbeat: types.WorkerHeartbeatContract{
-			IntervalSec:     int64(s.cfg.WorkerHeartbeatInterval.Seconds()),
-			OfflineAfterSec: int64(s.cfg.WorkerOfflineAfter.Seconds()),
-		},
-		Observability: types.WorkerObservabilityInfo{
-			TraceLinkTemplate: traceTemplate,
-			LogsLinkTemplate:  logsTemplate,
-		},
-	}
-
 	writeJSON(w, response, http.StatusOK)
 }
 
 func (s *ExternalServer) handleWorkerHeartbeat(w http.ResponseWriter, r *http.Request) {
 	var req types.WorkerHeartbeatRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid payload", http.StatusBadRequest)
-		return
-	}
-	if strings.TrimSpace(req.WorkerID) == "" {
-		http.Error(w, "workerId is required", http.StatusBadRequest)
-		return
-	}
-
-	sessionToken := extractWorkerSessionToken(r)
-	if strings.TrimSpace(sessionToken) == "" {
-		http.Error(w, "worker session token is required", http.StatusUnauthorized)
+		writeError(w, http.StatusBadRequest, errCodeInvalidPayload, "invalid payload")
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	if err := s.store.UpdateWorkerHeartbeat(ctx, sessionToken, req); err != nil {
-		if store.IsInvalidWorkerSessionError(err) {
-			http.Error(w, "invalid worker session", http.StatusUnauthorized)
-			return
-		}
-		s.logger.Error("worker heartbeat failed", "err", err, "workerId", req.WorkerID)
-		http.Error(w, "failed to persist heartbeat", http.StatusInternalServerError)
+	configStale, reqErr := s.recordWorkerHeartbeat(ctx, extractWorkerSessionToken(r), req)
+	if reqErr != nil {
+		writeRequestError(w, reqErr)
 		return
 	}
 
-	writeJSON(w, map[string]any{
-		"status":   "ok",
-		"workerId": req.WorkerID,
-	}, http.StatusOK)
+	writeJSON(w, workerAck{Status: "ok", WorkerID: req.WorkerID, RebootstrapRequired: configStale}, http.StatusOK)
 }
 
 func (s *ExternalServer) handleWorkerEvents(w http.ResponseWriter, r *http.Request) {
 	var req types.WorkerEventsRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid payload", http.StatusBadRequest)
-		return
-	}
-	if strings.TrimSpace(req.WorkerID) == "" {
-		http.Error(w, "workerId is required", http.StatusBadRequest)
-		return
-	}
-	if len(req.Events) == 0 {
-		http.Error(w, "events are required", http.StatusBadRequest)
-		return
-	}
-	if len(req.Events) > s.cfg.WorkerEventsMaxBatch {
-		http.Error(w, "too many events in one batch", http.StatusBadRequest)
-		return
-	}
-
-	sessionToken := extractWorkerSessionToken(r)
-	if strings.TrimSpace(sessionToken) == "" {
-		http.Error(w, "worker session token is required", http.StatusUnauthorized)
+	if reqErr := decodeIngestBody(w, r, s.cfg.IngestMaxBodyBytes, &req); reqErr != nil {
+		writeRequestError(w, reqErr)
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	if err := s.store.SaveWorkerEvents(ctx, req.WorkerID, sessionToken, req.Events); err != nil {
-		if store.IsInvalidWorkerSessionError(err) {
-			http.Error(w, "invalid worker session", http.StatusUnauthorized)
-			return
-		}
-		s.logger.Error("save worker events failed", "err", err, "workerId", req.WorkerID, "count", len(req.Events))
-		http.Error(w, "failed to save worker events", http.StatusInternalServerError)
+	if reqErr := s.saveWorkerEvents(ctx, extractWorkerSessionToken(r), req); reqErr != nil {
+		writeRequestError(w, reqErr)
 		return
 	}
 
-	writeJSON(w, map[string]any{
-		"status":        "ok",
-		"acceptedCount": len(req.Events),
-		"workerId":      req.WorkerID,
-	}, http.StatusOK)
+	accepted := len(req.Events)
+	writeJSON(w, workerAck{Status: "ok", WorkerID: req.WorkerID, AcceptedCount: &accepted}, http.StatusOK)
 }
 
 func (s *ExternalServer) handleWorkerShutdown(w http.ResponseWriter, r *http.Request) {
 	var req types.WorkerShutdownRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid payload", http.StatusBadRequest)
-		return
-	}
-	if strings.TrimSpace(req.WorkerID) == "" {
-		http.Error(w, "workerId is required", http.StatusBadRequest)
-		return
-	}
-
-	sessionToken := extractWorkerSessionToken(r)
-	if strings.TrimSpace(sessionToken) == "" {
-		http.Error(w, "worker session token is required", http.StatusUnauthorized)
+		writeError(w, http.StatusBadRequest, errCodeInvalidPayload, "invalid payload")
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	if err := s.store.StopWorkerSession(ctx, req.WorkerID, sessionToken, req.Reason); err != nil {
-		if store.IsInvalidWorkerSessionError(err) {
-			http.Error(w, "invalid worker session", http.StatusUnauthorized)
-			return
-		}
-		s.logger.Error("worker shutdown update failed", "err", err, "workerId", req.WorkerID)
-		http.Error(w, "failed to stop worker session", http.StatusInternalServerError)
+	if reqErr := s.stopWorker(ctx, extractWorkerSessionToken(r), req); reqErr != nil {
+		writeRequestError(w, reqErr)
 		return
 	}
 
-	writeJSON(w, map[string]any{
-		"status":   "ok",
-		"workerId": req.WorkerID,
-	}, http.StatusOK)
+	writeJSON(w, workerAck{Status: "ok", WorkerID: req.WorkerID}, http.StatusOK)
 }
 
 func (s *ExternalServer) cleanupExpired(ctx context.Context) {
@@ -593,7 +852,61 @@ func (s *ExternalServer) cleanupExpired(ctx context.Context) {
 	}
 }
 
-func extStageQueueName(appID, handler string) string {
+// resultQueuePattern documents shard naming for SDKs; the authoritative queue
+// for a job is StageNextMessage.ResultQueue.
+func resultQueuePattern(shards int) string {
+	if shards < 2 {
+		return ""
+	}
+	return constants.StageResult + ".{fnv32a(handler) % stageResultShards}"
+}
+
+// publishEventPipeline publishes the single stage of an event pipeline
+// straight to its handler queue; the worker's publisher skips event stages.
+// Other pipelines are left to the publisher.
+func publishEventPipeline(ctx context.Context, cfg config.APIConfig, mqClient *mq.Client, appID int, pipeline *types.PipelineResponse) error {
+	if pipeline.IsEvent == nil || !*pipeline.IsEvent || len(pipeline.Stages) != 1 {
+		return nil
+	}
+	stage := pipeline.Stages[0]
+	msg := types.StageNextMessage{
+		AppID:            appID,
+		PipelineID:       &pipeline.ID,
+		StageID:          stage.ID,
+		TraceID:          pipeline.TraceID,
+		SpanID:           stage.SpanID,
+		StageHandlerName: stage.StageHandlerName,
+		Input:            deref(stage.Input),
+		ContextItems:     pipeline.PipelineContext,
+		IdempotencyKey:   store.NewStageIdempotencyKey(stage.ID, 0),
+		ResultQueue:      mq.ShardQueueName(constants.StageResult, stage.StageHandlerName, cfg.ResultQueueShards),
+	}
+	if cfg.StageEnvironmentRouting {
+		msg.Environment = store.PipelineEnvironment(pipeline.PipelineContext, pipeline.PipelineKeywords)
+	}
+	body, _ := json.Marshal(msg)
+	opts := mq.QueueOptions{
+		Durable:     true,
+		DLQEnabled:  cfg.QueueDLQEnabled,
+		DLQTTL:      cfg.QueueDLQMessageTTL,
+		ContentType: "application/json",
+		Confirm:     cfg.PublishConfirms,
+		QueueType:   cfg.QueueType,
+		MaxLength:   cfg.QueueMaxLength,
+		Overflow:    cfg.QueueOverflow,
+	}
+	queue := extStageQueueName(cfg.AppID, stage.StageHandlerName, msg.Environment)
+	pubCtx := telemetry.ContextWithStageSpan(ctx, msg.TraceID, msg.SpanID)
+	if err := mqClient.PublishWithRetry(pubCtx, queue, body, opts, nil); err != nil {
+		return fmt.Errorf("publish to %s: %w", queue, err)
+	}
+	return nil
+}
+
+func extStageQueueName(appID, handler, environment string) string {
+	if environment != "" {
+		return fmt.Sprintf("%s_%s_%s.%s", appID, handler, constants.StageNext, environment)
+	}
 	return fmt.Sprintf("%s_%s_%s", appID, handler, constants.StageNext)
 }
 
@@ -604,6 +917,45 @@ func deref(v *string) string {
 	return *v
 }
 
+// authorizeAPIKey validates apiKey and checks that it grants scope, writing a
+// 401 or 403 response when it does not.
+func (s *ExternalServer) authorizeAPIKey(ctx context.Context, w http.ResponseWriter, apiKey, scope string) (*types.APIKeyAuth, bool) {
+	auth, reqErr := s.checkAPIKey(ctx, apiKey, scope)
+	if reqErr != nil {
+		writeRequestError(w, reqErr)
+		return nil, false
+	}
+	return auth, true
+}
+
+func (s *ExternalServer) checkAPIKey(ctx context.Context, apiKey, scope string) (*types.APIKeyAuth, *requestError) {
+	if strings.TrimSpace(apiKey) == "" {
+		return nil, newRequestError(http.StatusUnauthorized, errCodeAPIKeyRequired, "api key is required")
+	}
+
+	auth, err := s.store.ValidateAPIKey(ctx, apiKey)
+	if err != nil {
+		return nil, newRequestError(http.StatusUnauthorized, errCodeInvalidAPIKey, "invalid api key")
+	}
+	if !auth.HasScope(scope) {
+		s.logger.Warn("api key scope denied", "applicationId", auth.ApplicationID, "scope", scope)
+		return nil, newRequestError(http.StatusForbidden, errCodeInsufficientScope, "api key lacks scope "+scope)
+	}
+	return auth, nil
+}
+
+// authorizeOptionalAPIKey guards the job gateway. A key that is sent must
+// grant scope; requests without one are only accepted with
+// GATEWAY_ALLOW_ANONYMOUS.
+func (s *ExternalServer) authorizeOptionalAPIKey(ctx context.Context, w http.ResponseWriter, r *http.Request, scope string) bool {
+	apiKey := extractAPIKey(r)
+	if apiKey == "" && s.cfg.GatewayAllowAnonymous {
+		return true
+	}
+	_, ok := s.authorizeAPIKey(ctx, w, apiKey, scope)
+	return ok
+}
+
 func extractAPIKey(r *http.Request) string {
 	if bearer := strings.TrimSpace(r.Header.Get("Authorization")); bearer != "" {
 		const prefix = "bearer "
@@ -0,0 +1,263 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"pipelogiq/internal/config"
+	"pipelogiq/internal/mq"
+	"pipelogiq/internal/store"
+	"pipelogiq/internal/store/storetest"
+	"pipelogiq/internal/types"
+)
+
+func TestSaveLogRequiresWriteKey(t *testing.T) {
+	db := storetest.NewDB(t)
+	if _, err := db.Exec(`
+		INSERT INTO application (id, name) VALUES (10, 'own');
+		INSERT INTO api_key (user_id, application_id, name, key, scopes) VALUES
+			(1, 10, 'writer', 'write-key', 'pipelines:write'),
+			(1, 10, 'consumer', 'consume-key', 'jobs:consume');
+	`); err != nil {
+		t.Fatalf("insert api keys: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := &ExternalServer{cfg: config.APIConfig{IngestMaxBodyBytes: 4 << 10}, store: store.New(db, logger), logger: logger}
+
+	tests := []struct {
+		name   string
+		header string
+		body   string
+		want   int
+	}{
+		{"no key", "", `{"message":"m"}`, http.StatusUnauthorized},
+		{"unknown key", "nope", `{"message":"m"}`, http.StatusUnauthorized},
+		{"key without write scope", "consume-key", `{"message":"m"}`, http.StatusForbidden},
+		{"write key in header", "write-key", `{"message":"header"}`, http.StatusOK},
+		{"write key in body", "", `{"apiKey":"write-key","message":"body"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/logs", strings.NewReader(tt.body))
+			if tt.header != "" {
+				req.Header.Set("X-API-Key", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			s.handleSaveLog(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("POST /logs = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	var logs []types.LogResponse
+	if err := db.Select(&logs, `SELECT id, application_id, log FROM log ORDER BY id`); err != nil {
+		t.Fatalf("list logs: %v", err)
+	}
+	if len(logs) != 2 || *logs[0].ApplicationID != 10 || *logs[1].ApplicationID != 10 {
+		t.Fatalf("stored logs = %+v, want the two accepted logs under application 10", logs)
+	}
+}
+
+func TestWorkerHeartbeatRequestsRebootstrap(t *testing.T) {
+	db := storetest.NewDB(t)
+	if _, err := db.Exec(`
+		INSERT INTO application (id, name) VALUES (10, 'own');
+		INSERT INTO worker_client (id, application_id, state, session_token, session_expires_at)
+		VALUES ('w1', 10, 'ready', 'tok', NOW() + INTERVAL '1 hour');
+	`); err != nil {
+		t.Fatalf("insert worker: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := &ExternalServer{store: store.New(db, logger), logger: logger}
+
+	heartbeat := func(token string) (int, workerAck) {
+		req := httptest.NewRequest(http.MethodPost, "/workers/heartbeat",
+			strings.NewReader(`{"workerId":"w1","state":"ready","configVersion":"1"}`))
+		req.Header.Set("X-Worker-Session", token)
+		rec := httptest.NewRecorder()
+		s.handleWorkerHeartbeat(rec, req)
+		var ack workerAck
+		_ = json.Unmarshal(rec.Body.Bytes(), &ack)
+		return rec.Code, ack
+	}
+
+	if code, ack := heartbeat("tok"); code != http.StatusOK || ack.RebootstrapRequired {
+		t.Fatalf("heartbeat at the current version = %d %+v, want 200 without rebootstrapRequired", code, ack)
+	}
+	if _, err := db.Exec(`UPDATE config_version SET version = 2 WHERE id = 1`); err != nil {
+		t.Fatalf("bump config version: %v", err)
+	}
+	if code, ack := heartbeat("tok"); code != http.StatusOK || !ack.RebootstrapRequired {
+		t.Fatalf("heartbeat after a config change = %d %+v, want 200 with rebootstrapRequired", code, ack)
+	}
+	if code, _ := heartbeat("wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("heartbeat with a wrong session = %d, want 401", code)
+	}
+}
+
+func TestCreatePipelineRejectsRepeatedLinkedStageNames(t *testing.T) {
+	db := storetest.NewDB(t)
+	if _, err := db.Exec(`
+		INSERT INTO application (id, name) VALUES (10, 'own');
+		INSERT INTO api_key (user_id, application_id, name, key, scopes) VALUES (1, 10, 'writer', 'write-key', 'pipelines:write');
+	`); err != nil {
+		t.Fatalf("insert api key: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := &ExternalServer{store: store.New(db, logger), logger: logger}
+
+	body := `{"apiKey":"write-key","name":"p","stages":[
+		{"name":"fetch","stageHandlerName":"fetch"},
+		{"name":"fetch","stageHandlerName":"fetch"},
+		{"name":"store","stageHandlerName":"store","options":{"dependsOn":["fetch"]}}
+	]}`
+	rec := httptest.NewRecorder()
+	s.handleCreatePipeline(rec, httptest.NewRequest(http.MethodPost, "/pipelines", strings.NewReader(body)))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "used more than once") {
+		t.Fatalf("POST /pipelines = %d %s, want 400 for the repeated stage name", rec.Code, rec.Body.String())
+	}
+	var count int
+	if err := db.Get(&count, `SELECT COUNT(*) FROM pipeline`); err != nil {
+		t.Fatalf("count pipelines: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("%d pipelines created, want none", count)
+	}
+}
+
+// fakeJobSource hands out queued bodies, at most opts.Prefetch per GetWait.
+type fakeJobSource struct {
+	queued   []string
+	acked    int
+	nacked   int
+	prefetch []int
+	// onWait runs before GetWait returns, to act as a concurrent pull.
+	onWait func()
+}
+
+func (f *fakeJobSource) message(body string) *mq.GetResult {
+	return &mq.GetResult{
+		Body: []byte(body),
+		Ack:  func() error { f.acked++; return nil },
+		Nack: func(bool) error { f.nacked++; return nil },
+	}
+}
+
+func (f *fakeJobSource) Get(_ context.Context, _ string, _ mq.QueueOptions) (*mq.GetResult, error) {
+	if len(f.queued) == 0 {
+		return nil, nil
+	}
+	msg := f.message(f.queued[0])
+	f.queued = f.queued[1:]
+	return msg, nil
+}
+
+func (f *fakeJobSource) GetWait(_ context.Context, _ string, opts mq.QueueOptions, _ time.Duration) ([]*mq.GetResult, error) {
+	f.prefetch = append(f.prefetch, opts.Prefetch)
+	n := min(max(opts.Prefetch, 1), len(f.queued))
+	msgs := make([]*mq.GetResult, 0, n)
+	for _, body := range f.queued[:n] {
+		msgs = append(msgs, f.message(body))
+	}
+	f.queued = f.queued[n:]
+	if f.onWait != nil {
+		f.onWait()
+	}
+	return msgs, nil
+}
+
+func TestPullJobBatch(t *testing.T) {
+	bodies := []string{`{"n":1}`, `{"n":2}`, `{"n":3}`, `{"n":4}`, `{"n":5}`}
+	tests := []struct {
+		name         string
+		maxInFlight  int
+		held         int
+		heldMidBatch int
+		body         string
+		wantStatus   int
+		wantJobs     int
+		wantPrefetch int
+		wantNacked   int
+	}{
+		{"single job", 10, 0, 0, `{"queue":"q"}`, http.StatusOK, 1, 1, 0},
+		{"batch over prefetch", 10, 0, 0, `{"queue":"q","maxMessages":4}`, http.StatusOK, 4, 3, 0},
+		{"batch capped by in-flight budget", 10, 8, 0, `{"queue":"q","maxMessages":4}`, http.StatusOK, 2, 2, 0},
+		{"budget used up mid-batch", 10, 0, 8, `{"queue":"q","maxMessages":4}`, http.StatusOK, 2, 3, 1},
+		{"no budget", 10, 10, 0, `{"queue":"q","maxMessages":4}`, http.StatusTooManyRequests, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &fakeJobSource{queued: bodies}
+			s := &ExternalServer{
+				cfg: config.APIConfig{
+					GatewayAllowAnonymous: true,
+					GatewayMaxInFlight:    tt.maxInFlight,
+					GatewayVisibilityTTL:  time.Minute,
+					GatewayPullPrefetch:   1,
+					GatewayQueuePrefetch:  map[string]int{"q": 3},
+				},
+				source:  source,
+				logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+				pending: map[string]pendingAck{},
+				metrics: externalMetrics{stageJobsPulled: prometheus.NewCounter(prometheus.CounterOpts{Name: "test_pulled"})},
+			}
+			hold := func(n int) {
+				s.pendingMu.Lock()
+				defer s.pendingMu.Unlock()
+				for i := 0; i < n; i++ {
+					s.pending[fmt.Sprintf("held-%d", i)] = pendingAck{}
+				}
+			}
+			hold(tt.held)
+			source.onWait = func() { hold(tt.heldMidBatch) }
+
+			rec := httptest.NewRecorder()
+			s.handlePullJob(rec, httptest.NewRequest(http.MethodPost, "/jobs/pull", strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("POST /jobs/pull = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantJobs == 0 {
+				return
+			}
+
+			var jobs []pullResponse
+			if !strings.Contains(tt.body, "maxMessages") {
+				var job pullResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &job); err != nil {
+					t.Fatalf("decode job: %v", err)
+				}
+				jobs = append(jobs, job)
+			} else if err := json.Unmarshal(rec.Body.Bytes(), &jobs); err != nil {
+				t.Fatalf("decode batch: %v", err)
+			}
+			if len(jobs) != tt.wantJobs {
+				t.Fatalf("pulled %d jobs, want %d", len(jobs), tt.wantJobs)
+			}
+			for i, job := range jobs {
+				if string(job.Payload) != bodies[i] || job.Token == "" {
+					t.Fatalf("job %d = %+v, want payload %s with a token", i, job, bodies[i])
+				}
+			}
+			if len(source.prefetch) != 1 || source.prefetch[0] != tt.wantPrefetch {
+				t.Fatalf("GetWait prefetch = %v, want [%d]", source.prefetch, tt.wantPrefetch)
+			}
+			if source.nacked != tt.wantNacked || source.acked != 0 {
+				t.Fatalf("acked %d, nacked %d, want 0 acked, %d nacked", source.acked, source.nacked, tt.wantNacked)
+			}
+			if got := len(s.pending) - tt.held - tt.heldMidBatch; got != tt.wantJobs {
+				t.Fatalf("tracked %d pending jobs, want %d", got, tt.wantJobs)
+			}
+		})
+	}
+}
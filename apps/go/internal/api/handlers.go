@@ -3,19 +3,31 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 
+	observabilityservice "pipelogiq/internal/observability/service"
+	"pipelogiq/internal/store"
 	"pipelogiq/internal/types"
 )
 
 // Pipeline handlers
 
 func (s *Server) handleGetPipelines(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r.Context())
+	if userID == 0 {
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "unauthorized")
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
 
@@ -24,18 +36,28 @@ func (s *Server) handleGetPipelines(w http.ResponseWriter, r *http.Request) {
 		PageSize:          parseQueryIntPtr(r.URL.Query().Get("pageSize")),
 		ApplicationID:     parseQueryIntPtr(r.URL.Query().Get("applicationId")),
 		Search:            parseQueryStringPtr(r.URL.Query().Get("search")),
+		TraceID:           parseQueryStringPtr(r.URL.Query().Get("traceId")),
 		Keywords:          r.URL.Query()["keywords"],
+		KeywordMatch:      r.URL.Query().Get("keywordMatch"),
+		Labels:            r.URL.Query()["labels"],
 		Statuses:          r.URL.Query()["statuses"],
 		PipelineStartFrom: parseQueryStringPtr(r.URL.Query().Get("pipelineStartFrom")),
 		PipelineStartTo:   parseQueryStringPtr(r.URL.Query().Get("pipelineStartTo")),
 		PipelineEndFrom:   parseQueryStringPtr(r.URL.Query().Get("pipelineEndFrom")),
 		PipelineEndTo:     parseQueryStringPtr(r.URL.Query().Get("pipelineEndTo")),
+		SortBy:            parseQueryStringPtr(r.URL.Query().Get("sortBy")),
+		SortDir:           parseQueryStringPtr(r.URL.Query().Get("sortDir")),
 	}
 
-	result, err := s.store.GetPipelines(ctx, req)
+	result, err := s.store.GetPipelines(ctx, userID, req)
 	if err != nil {
+		if store.IsInvalidPipelineSortError(err) || store.IsInvalidKeywordMatchError(err) ||
+			store.IsInvalidPipelineLabelError(err) {
+			writeError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+			return
+		}
 		s.logger.Error("get pipelines failed", "err", err)
-		http.Error(w, "failed to get pipelines", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to get pipelines")
 		return
 	}
 
@@ -45,7 +67,10 @@ func (s *Server) handleGetPipelines(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleRerunStage(w http.ResponseWriter, r *http.Request) {
 	var req types.RerunStageRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid payload", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidPayload, "invalid payload")
+		return
+	}
+	if !s.authorizeStage(w, r, req.StageID) {
 		return
 	}
 
@@ -54,7 +79,7 @@ func (s *Server) handleRerunStage(w http.ResponseWriter, r *http.Request) {
 
 	if err := s.store.RerunStage(ctx, req.StageID, req.RerunAllNextStages); err != nil {
 		s.logger.Error("rerun stage failed", "err", err)
-		http.Error(w, "failed to rerun stage", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to rerun stage")
 		return
 	}
 
@@ -64,7 +89,10 @@ func (s *Server) handleRerunStage(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleSkipStage(w http.ResponseWriter, r *http.Request) {
 	var req types.SkipStageRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid payload", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidPayload, "invalid payload")
+		return
+	}
+	if !s.authorizeStage(w, r, req.StageID) {
 		return
 	}
 
@@ -73,18 +101,216 @@ func (s *Server) handleSkipStage(w http.ResponseWriter, r *http.Request) {
 
 	if err := s.store.SkipStage(ctx, req.StageID); err != nil {
 		s.logger.Error("skip stage failed", "err", err)
-		http.Error(w, "failed to skip stage", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to skip stage")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+const maxBulkStageIDs = 500
+
+func (s *Server) handleBulkRerunStages(w http.ResponseWriter, r *http.Request) {
+	s.handleBulkStages(w, r, "rerun", s.store.BulkRerunStages)
+}
+
+func (s *Server) handleBulkSkipStages(w http.ResponseWriter, r *http.Request) {
+	s.handleBulkStages(w, r, "skip", s.store.BulkSkipStages)
+}
+
+func (s *Server) handleBulkStages(
+	w http.ResponseWriter,
+	r *http.Request,
+	action string,
+	run func(context.Context, int, types.BulkStageRequest) ([]types.BulkStageResult, error),
+) {
+	userID := getUserIDFromContext(r.Context())
+	if userID == 0 {
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "unauthorized")
+		return
+	}
+
+	var req types.BulkStageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errCodeInvalidPayload, "invalid payload")
+		return
+	}
+
+	switch {
+	case req.PipelineID == nil && len(req.StageIDs) == 0:
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "stageIds or pipelineId is required")
+		return
+	case req.PipelineID != nil && len(req.StageIDs) > 0:
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "provide either stageIds or pipelineId")
+		return
+	case len(req.StageIDs) > maxBulkStageIDs:
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("at most %d stageIds are allowed", maxBulkStageIDs))
+		return
+	case req.Status != "" && !isKnownStageStatus(req.Status):
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid status")
+		return
+	}
+	if req.PipelineID != nil && !s.authorizePipeline(w, r, *req.PipelineID) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	results, err := run(ctx, userID, req)
+	if err != nil {
+		s.logger.Error("bulk "+action+" stages failed", "err", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to "+action+" stages")
+		return
+	}
+
+	writeJSON(w, types.BulkStageResponse{Results: results}, http.StatusOK)
+}
+
+func isKnownStageStatus(status string) bool {
+	switch status {
+	case types.StageStatusNotStarted, types.StageStatusRunning, types.StageStatusPending,
+		types.StageStatusRetryScheduled, types.StageStatusCompleted, types.StageStatusFailed,
+		types.StageStatusSkipped, types.StageStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func (s *Server) handleCancelPipeline(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid id")
+		return
+	}
+	if !s.authorizePipeline(w, r, id) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := s.store.CancelPipeline(ctx, id); err != nil {
+		switch {
+		case store.IsPipelineNotFoundError(err):
+			writeError(w, http.StatusNotFound, errCodePipelineNotFound, "not found")
+		case store.IsPipelineNotRunningError(err):
+			writeError(w, http.StatusConflict, errCodePipelineNotRunning, err.Error())
+		default:
+			s.logger.Error("cancel pipeline failed", "pipeline_id", id, "err", err)
+			writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to cancel pipeline")
+		}
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
 
+type replayPipelineRequest struct {
+	// CopyContext carries the context items the original run ended with
+	// over to the new run.
+	CopyContext bool `json:"copyContext"`
+}
+
+// handleReplayPipeline starts a finished pipeline again as a new pipeline,
+// leaving the original run as it is. The body is optional.
+func (s *Server) handleReplayPipeline(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid id")
+		return
+	}
+	var req replayPipelineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, errCodeInvalidPayload, "invalid payload")
+		return
+	}
+	if !s.authorizePipeline(w, r, id) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	pipeline, err := s.store.ClonePipeline(ctx, id, req.CopyContext)
+	if err != nil {
+		switch {
+		case store.IsPipelineNotFoundError(err):
+			writeError(w, http.StatusNotFound, errCodePipelineNotFound, "not found")
+		case store.IsPipelineStillRunningError(err):
+			writeError(w, http.StatusConflict, errCodePipelineRunning, err.Error())
+		default:
+			s.logger.Error("replay pipeline failed", "pipeline_id", id, "err", err)
+			writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to replay pipeline")
+		}
+		return
+	}
+	if pipeline.ApplicationID != nil {
+		if err := publishEventPipeline(ctx, s.cfg, s.mq, *pipeline.ApplicationID, pipeline); err != nil {
+			s.logger.Error("failed to publish event stage", "err", err, "pipelineId", pipeline.ID)
+		}
+	}
+
+	writeJSON(w, pipeline, http.StatusCreated)
+}
+
+func (s *Server) handleDeletePipeline(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r.Context())
+	if userID == 0 {
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "unauthorized")
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid id")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	appID, err := s.store.PipelineApplicationID(ctx, id)
+	if err != nil {
+		if store.IsPipelineNotFoundError(err) {
+			writeError(w, http.StatusNotFound, errCodePipelineNotFound, "not found")
+			return
+		}
+		s.logger.Error("load pipeline application failed", "pipeline_id", id, "err", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to delete pipeline")
+		return
+	}
+
+	hasAccess, err := s.store.UserHasApplication(ctx, userID, appID)
+	if err != nil {
+		s.logger.Error("check application access failed", "err", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to delete pipeline")
+		return
+	}
+	if !hasAccess {
+		writeError(w, http.StatusNotFound, errCodeNotFound, "not found")
+		return
+	}
+
+	if err := s.store.DeletePipeline(ctx, id, appID); err != nil {
+		if store.IsPipelineNotFoundError(err) {
+			writeError(w, http.StatusNotFound, errCodePipelineNotFound, "not found")
+			return
+		}
+		s.logger.Error("delete pipeline failed", "pipeline_id", id, "err", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to delete pipeline")
+		return
+	}
+
+	s.logger.Info("pipeline deleted", "pipeline_id", id, "application_id", appID, "user_id", userID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (s *Server) handleGetPipelineLogs(w http.ResponseWriter, r *http.Request) {
 	pipelineIDStr := chi.URLParam(r, "pipelineId")
 	pipelineID, err := strconv.Atoi(pipelineIDStr)
 	if err != nil {
-		http.Error(w, "invalid pipeline id", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid pipeline id")
 		return
 	}
 
@@ -95,13 +321,16 @@ func (s *Server) handleGetPipelineLogs(w http.ResponseWriter, r *http.Request) {
 			stageID = &id
 		}
 	}
+	if !s.authorizePipeline(w, r, pipelineID) {
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
 	logs, err := s.store.GetStageLogs(ctx, pipelineID, stageID)
 	if err != nil {
-		http.Error(w, "not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, errCodeNotFound, "not found")
 		return
 	}
 
@@ -113,17 +342,18 @@ func (s *Server) handleGetPipelineLogs(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleGetApplications(w http.ResponseWriter, r *http.Request) {
 	userID := getUserIDFromContext(r.Context())
 	if userID == 0 {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "unauthorized")
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	apps, err := s.store.GetUserApplications(ctx, userID)
+	excludeDisabled := strings.EqualFold(r.URL.Query().Get("excludeDisabled"), "true")
+	apps, err := s.store.GetUserApplications(ctx, userID, excludeDisabled)
 	if err != nil {
 		s.logger.Error("get applications failed", "err", err)
-		http.Error(w, "failed to get applications", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to get applications")
 		return
 	}
 
@@ -133,18 +363,18 @@ func (s *Server) handleGetApplications(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleSaveApplication(w http.ResponseWriter, r *http.Request) {
 	userID := getUserIDFromContext(r.Context())
 	if userID == 0 {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "unauthorized")
 		return
 	}
 
 	var req types.SaveApplicationRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid payload", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidPayload, "invalid payload")
 		return
 	}
 
 	if strings.TrimSpace(req.Name) == "" {
-		http.Error(w, "name is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "name is required")
 		return
 	}
 
@@ -154,25 +384,312 @@ func (s *Server) handleSaveApplication(w http.ResponseWriter, r *http.Request) {
 	apps, err := s.store.SaveApplication(ctx, userID, req)
 	if err != nil {
 		s.logger.Error("save application failed", "err", err)
-		http.Error(w, "failed to save application", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to save application")
 		return
 	}
 
 	writeJSON(w, apps, http.StatusOK)
 }
 
+// applicationFromRequest parses the {id} URL param and checks that the current
+// user may access that application. It writes the error response itself.
+func (s *Server) applicationFromRequest(w http.ResponseWriter, r *http.Request) (int, bool) {
+	userID := getUserIDFromContext(r.Context())
+	if userID == 0 {
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "unauthorized")
+		return 0, false
+	}
+
+	appID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid id")
+		return 0, false
+	}
+
+	hasAccess, err := s.store.UserHasApplication(r.Context(), userID, appID)
+	if err != nil {
+		s.logger.Error("check application access failed", "err", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to load application")
+		return 0, false
+	}
+	if !hasAccess {
+		writeError(w, http.StatusNotFound, errCodeNotFound, "not found")
+		return 0, false
+	}
+	return appID, true
+}
+
+// authorizePipeline checks that the current user may read the pipeline, that
+// is, that it belongs to one of their applications. It writes the error
+// response itself. Another application's pipeline is reported as not found,
+// so its existence is not revealed.
+func (s *Server) authorizePipeline(w http.ResponseWriter, r *http.Request, pipelineID int) bool {
+	userID := getUserIDFromContext(r.Context())
+	if userID == 0 {
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "unauthorized")
+		return false
+	}
+
+	hasAccess, err := s.store.UserCanAccessPipeline(r.Context(), userID, pipelineID)
+	if err != nil {
+		s.logger.Error("check pipeline access failed", "pipeline_id", pipelineID, "err", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to load pipeline")
+		return false
+	}
+	if !hasAccess {
+		writeError(w, http.StatusNotFound, errCodeNotFound, "not found")
+		return false
+	}
+	return true
+}
+
+// requireAdmin checks that the current user has the admin role, writing a 401
+// or 403 response when they do not. It guards resources shared by every
+// application, such as the broker's dead-letter queues.
+func (s *Server) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	userID := getUserIDFromContext(r.Context())
+	if userID == 0 {
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "unauthorized")
+		return false
+	}
+
+	user, err := s.store.GetUserByID(r.Context(), userID)
+	if err != nil {
+		s.logger.Error("load user failed", "user_id", userID, "err", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to load user")
+		return false
+	}
+	if user.Role != types.UserRoleAdmin {
+		writeError(w, http.StatusForbidden, errCodeForbidden, "admin role required")
+		return false
+	}
+	return true
+}
+
+// authorizeStage is authorizePipeline for the pipeline the stage belongs to.
+// A missing stage is reported as not found too.
+func (s *Server) authorizeStage(w http.ResponseWriter, r *http.Request, stageID int) bool {
+	pipelineID, err := s.store.GetStagePipelineID(r.Context(), stageID)
+	if err != nil {
+		if store.IsStageNotFoundError(err) {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "not found")
+			return false
+		}
+		s.logger.Error("get stage pipeline failed", "stage_id", stageID, "err", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to load stage")
+		return false
+	}
+	return s.authorizePipeline(w, r, pipelineID)
+}
+
+func (s *Server) handleGetFeatureFlags(w http.ResponseWriter, r *http.Request) {
+	appID, ok := s.applicationFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	flags, err := s.store.GetFeatureFlags(ctx, appID)
+	if err != nil {
+		s.logger.Error("get feature flags failed", "err", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to get feature flags")
+		return
+	}
+
+	writeJSON(w, flags, http.StatusOK)
+}
+
+func (s *Server) handleSetFeatureFlag(w http.ResponseWriter, r *http.Request) {
+	appID, ok := s.applicationFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.SetFeatureFlagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errCodeInvalidPayload, "invalid payload")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	flag := chi.URLParam(r, "flag")
+	if err := s.store.SetFeatureFlag(ctx, appID, flag, req.Enabled); err != nil {
+		if store.IsUnknownFeatureFlagError(err) {
+			writeError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+			return
+		}
+		s.logger.Error("set feature flag failed", "err", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to set feature flag")
+		return
+	}
+
+	s.logger.Info("feature flag updated", "application_id", appID, "flag", flag, "enabled", req.Enabled)
+
+	flags, err := s.store.GetFeatureFlags(ctx, appID)
+	if err != nil {
+		s.logger.Error("get feature flags failed", "err", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to get feature flags")
+		return
+	}
+
+	writeJSON(w, flags, http.StatusOK)
+}
+
+func (s *Server) handleDisableApplication(w http.ResponseWriter, r *http.Request) {
+	s.setApplicationDisabled(w, r, true)
+}
+
+func (s *Server) handleEnableApplication(w http.ResponseWriter, r *http.Request) {
+	s.setApplicationDisabled(w, r, false)
+}
+
+func (s *Server) setApplicationDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	userID := getUserIDFromContext(r.Context())
+	if userID == 0 {
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "unauthorized")
+		return
+	}
+
+	appID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid id")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if disabled {
+		err = s.store.DisableApplication(ctx, userID, appID)
+	} else {
+		err = s.store.EnableApplication(ctx, userID, appID)
+	}
+	if err != nil {
+		if store.IsApplicationNotFoundError(err) {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "not found")
+			return
+		}
+		s.logger.Error("update application state failed", "err", err, "applicationId", appID, "disabled", disabled)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to update application")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleGetApplicationStats(w http.ResponseWriter, r *http.Request) {
+	appID, ok := s.applicationFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	timeRange := strings.TrimSpace(r.URL.Query().Get("range"))
+	if timeRange == "" {
+		timeRange = "24h"
+	}
+	rangeDuration := observabilityservice.ParseTimeRangeDuration(timeRange)
+	if rangeDuration <= 0 {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "range must be one of 15m, 1h, 6h, 24h, 7d")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	stats, err := s.store.GetPipelineStats(ctx, appID, rangeDuration)
+	if err != nil {
+		s.logger.Error("get pipeline stats failed", "err", err, "applicationId", appID)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to get pipeline stats")
+		return
+	}
+	stats.Range = timeRange
+
+	writeJSON(w, stats, http.StatusOK)
+}
+
+func (s *Server) handleGetApplicationWebhook(w http.ResponseWriter, r *http.Request) {
+	appID, ok := s.applicationFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	hook, err := s.store.GetApplicationWebhook(ctx, appID)
+	if err != nil {
+		s.logger.Error("get application webhook failed", "err", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to get webhook")
+		return
+	}
+
+	writeJSON(w, webhookResponse(hook), http.StatusOK)
+}
+
+func (s *Server) handleSaveApplicationWebhook(w http.ResponseWriter, r *http.Request) {
+	appID, ok := s.applicationFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.SaveApplicationWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errCodeInvalidPayload, "invalid payload")
+		return
+	}
+	req.URL = strings.TrimSpace(req.URL)
+	if req.URL != "" {
+		parsed, err := url.Parse(req.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "url must be an absolute http(s) URL")
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := s.store.SetApplicationWebhook(ctx, appID, req.URL, req.Secret); err != nil {
+		s.logger.Error("save application webhook failed", "err", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to save webhook")
+		return
+	}
+
+	s.logger.Info("application webhook updated", "application_id", appID, "enabled", req.URL != "")
+
+	hook, err := s.store.GetApplicationWebhook(ctx, appID)
+	if err != nil {
+		s.logger.Error("get application webhook failed", "err", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to get webhook")
+		return
+	}
+
+	writeJSON(w, webhookResponse(hook), http.StatusOK)
+}
+
+func webhookResponse(hook *types.ApplicationWebhook) types.ApplicationWebhookResponse {
+	if hook == nil {
+		return types.ApplicationWebhookResponse{}
+	}
+	return types.ApplicationWebhookResponse{URL: hook.URL, HasSecret: hook.Secret != ""}
+}
+
 // ApiKey handlers
 
 func (s *Server) handleGenerateApiKey(w http.ResponseWriter, r *http.Request) {
 	userID := getUserIDFromContext(r.Context())
 	if userID == 0 {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "unauthorized")
 		return
 	}
 
 	var req types.GenerateApiKeyRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid payload", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidPayload, "invalid payload")
 		return
 	}
 
@@ -180,17 +697,17 @@ func (s *Server) handleGenerateApiKey(w http.ResponseWriter, r *http.Request) {
 	hasNewApplication := req.NewApplication != nil
 
 	if hasExistingApplication && hasNewApplication {
-		http.Error(w, "provide either applicationId or newApplication", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "provide either applicationId or newApplication")
 		return
 	}
 
 	if !hasExistingApplication && !hasNewApplication {
-		http.Error(w, "applicationId or newApplication is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "applicationId or newApplication is required")
 		return
 	}
 
 	if hasNewApplication && strings.TrimSpace(req.NewApplication.Name) == "" {
-		http.Error(w, "newApplication.name is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "newApplication.name is required")
 		return
 	}
 
@@ -204,14 +721,18 @@ func (s *Server) handleGenerateApiKey(w http.ResponseWriter, r *http.Request) {
 		if strings.Contains(errMsg, "applicationid or newapplication is required") ||
 			strings.Contains(errMsg, "provide either applicationid or newapplication") ||
 			strings.Contains(errMsg, "newapplication.name is required") {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+			return
+		}
+		if store.IsInvalidAPIKeyScopeError(err) {
+			writeError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 			return
 		}
 		if strings.Contains(errMsg, "application not found or access denied") {
-			http.Error(w, err.Error(), http.StatusNotFound)
+			writeError(w, http.StatusNotFound, errCodeNotFound, err.Error())
 			return
 		}
-		http.Error(w, "failed to generate api key", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to generate api key")
 		return
 	}
 
@@ -222,7 +743,7 @@ func (s *Server) handleGetApiKeys(w http.ResponseWriter, r *http.Request) {
 	appIDStr := r.URL.Query().Get("applicationId")
 	appID, err := strconv.Atoi(appIDStr)
 	if err != nil {
-		http.Error(w, "applicationId is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "applicationId is required")
 		return
 	}
 
@@ -232,7 +753,37 @@ func (s *Server) handleGetApiKeys(w http.ResponseWriter, r *http.Request) {
 	keys, err := s.store.GetApiKeys(ctx, appID)
 	if err != nil {
 		s.logger.Error("get api keys failed", "err", err)
-		http.Error(w, "failed to get api keys", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to get api keys")
+		return
+	}
+
+	writeJSON(w, keys, http.StatusOK)
+}
+
+func (s *Server) handleGetExpiringApiKeys(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r.Context())
+	if userID == 0 {
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "unauthorized")
+		return
+	}
+
+	within := s.cfg.APIKeyExpiryWarnWithin
+	if raw := r.URL.Query().Get("withinDays"); raw != "" {
+		days, err := strconv.Atoi(raw)
+		if err != nil || days <= 0 {
+			writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "withinDays must be a positive integer")
+			return
+		}
+		within = time.Duration(days) * 24 * time.Hour
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	keys, err := s.store.ListExpiringAPIKeys(ctx, userID, within)
+	if err != nil {
+		s.logger.Error("list expiring api keys failed", "err", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to get expiring api keys")
 		return
 	}
 
@@ -242,7 +793,7 @@ func (s *Server) handleGetApiKeys(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleDisableApiKey(w http.ResponseWriter, r *http.Request) {
 	var req types.DisableApiKeyRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid payload", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidPayload, "invalid payload")
 		return
 	}
 
@@ -251,7 +802,7 @@ func (s *Server) handleDisableApiKey(w http.ResponseWriter, r *http.Request) {
 
 	if err := s.store.DisableApiKey(ctx, req.ApiKeyID); err != nil {
 		s.logger.Error("disable api key failed", "err", err)
-		http.Error(w, "failed to disable api key", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to disable api key")
 		return
 	}
 
@@ -269,7 +820,7 @@ func (s *Server) handleGetKeywords(w http.ResponseWriter, r *http.Request) {
 	keywords, err := s.store.GetKeywords(ctx, search)
 	if err != nil {
 		s.logger.Error("get keywords failed", "err", err)
-		http.Error(w, "failed to get keywords", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to get keywords")
 		return
 	}
 
@@ -279,20 +830,37 @@ func (s *Server) handleGetKeywords(w http.ResponseWriter, r *http.Request) {
 // Log handlers
 
 func (s *Server) handleGetLogsByAppID(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r.Context())
+	if userID == 0 {
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "unauthorized")
+		return
+	}
+
 	appIDStr := chi.URLParam(r, "appId")
 	appID, err := strconv.Atoi(appIDStr)
 	if err != nil {
-		http.Error(w, "invalid app id", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid app id")
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
+	hasAccess, err := s.store.UserHasApplication(ctx, userID, appID)
+	if err != nil {
+		s.logger.Error("check application access failed", "err", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to get logs")
+		return
+	}
+	if !hasAccess {
+		writeError(w, http.StatusNotFound, errCodeNotFound, "not found")
+		return
+	}
+
 	logs, err := s.store.GetLogsByAppID(ctx, appID)
 	if err != nil {
 		s.logger.Error("get logs failed", "err", err)
-		http.Error(w, "failed to get logs", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to get logs")
 		return
 	}
 
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+
+	"pipelogiq/internal/store"
+	"pipelogiq/internal/store/storetest"
+	"pipelogiq/internal/types"
+)
+
+// newPostgresTestServer returns a Server backed by a scratch Postgres schema
+// in which user 1 belongs to application 10 and user 2 to application 20.
+func newPostgresTestServer(t *testing.T) (*Server, *sqlx.DB) {
+	t.Helper()
+	db := storetest.NewDB(t)
+	if _, err := db.Exec(`INSERT INTO user_application (user_id, application_id) VALUES (1, 10), (2, 20)`); err != nil {
+		t.Fatalf("insert user applications: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return &Server{store: store.New(db, logger), logger: logger}, db
+}
+
+// serveAs routes r to s's handlers as the given user, the way authMiddleware
+// would after a successful login.
+func serveAs(s *Server, userID int, r *http.Request) *httptest.ResponseRecorder {
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userIDKey, userID)))
+		})
+	})
+	router.Get("/pipelines", s.handleGetPipelines)
+	router.Post("/pipelines/rerunStage", s.handleRerunStage)
+	router.Post("/pipelines/skipStage", s.handleSkipStage)
+	router.Post("/pipelines/{id}/replay", s.handleReplayPipeline)
+	router.Get("/logs/{appId}", s.handleGetLogsByAppID)
+	router.Get("/queues/{queue}/dlq", s.handlePeekDLQ)
+	router.Post("/queues/{queue}/dlq/requeue", s.handleRequeueDLQ)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, r)
+	return rec
+}
+
+func insertTestPipeline(t *testing.T, db *sqlx.DB, appID int, status string, completed bool) int {
+	t.Helper()
+	var id int
+	if err := db.QueryRow(`
+		INSERT INTO pipeline (application_id, name, status, is_completed) VALUES ($1, 'p', $2, $3) RETURNING id
+	`, appID, status, completed).Scan(&id); err != nil {
+		t.Fatalf("insert pipeline: %v", err)
+	}
+	return id
+}
+
+func insertTestStage(t *testing.T, db *sqlx.DB, pipelineID int, name, status string) int {
+	t.Helper()
+	var id int
+	if err := db.QueryRow(`
+		INSERT INTO stage (pipeline_id, name, stage_handler_name, status) VALUES ($1, $2, 'resize', $3) RETURNING id
+	`, pipelineID, name, status).Scan(&id); err != nil {
+		t.Fatalf("insert stage: %v", err)
+	}
+	return id
+}
+
+func TestStageActionsRejectForeignPipelines(t *testing.T) {
+	s, db := newPostgresTestServer(t)
+	foreign := insertTestPipeline(t, db, 20, types.PipelineStatusFailed, true)
+	stage := insertTestStage(t, db, foreign, "resize", types.StageStatusFailed)
+
+	for _, path := range []string{"/pipelines/rerunStage", "/pipelines/skipStage"} {
+		for _, stageID := range []int{stage, stage + 100} {
+			body := `{"stageId":` + strconv.Itoa(stageID) + `}`
+			rec := serveAs(s, 1, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("POST %s stage %d = %d, want 404", path, stageID, rec.Code)
+			}
+		}
+	}
+
+	var status string
+	if err := db.QueryRow(`SELECT status FROM stage WHERE id = $1`, stage).Scan(&status); err != nil {
+		t.Fatalf("load stage: %v", err)
+	}
+	if status != types.StageStatusFailed {
+		t.Fatalf("stage status = %s, want it left %s", status, types.StageStatusFailed)
+	}
+}
+
+func TestGetPipelinesListsOwnApplications(t *testing.T) {
+	s, db := newPostgresTestServer(t)
+	own := insertTestPipeline(t, db, 10, types.PipelineStatusRunning, false)
+	insertTestPipeline(t, db, 20, types.PipelineStatusRunning, false)
+
+	for _, query := range []string{"", "?applicationId=20"} {
+		rec := serveAs(s, 1, httptest.NewRequest(http.MethodGet, "/pipelines"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET /pipelines%s = %d, want 200", query, rec.Code)
+		}
+		var result types.PagedResult[types.PipelineResponse]
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatalf("decode pipelines: %v", err)
+		}
+		want := []int{own}
+		if query != "" {
+			want = nil
+		}
+		got := []int{}
+		for _, p := range result.Items {
+			got = append(got, p.ID)
+		}
+		if len(got) != len(want) || result.TotalCount != len(want) || (len(want) == 1 && got[0] != want[0]) {
+			t.Errorf("GET /pipelines%s = %v (total %d), want %v", query, got, result.TotalCount, want)
+		}
+	}
+}
+
+func TestGetLogsByAppIDRejectsForeignApplications(t *testing.T) {
+	s, db := newPostgresTestServer(t)
+	if _, err := db.Exec(`INSERT INTO log (application_id, log, log_level) VALUES (10, 'own', 'Info'), (20, 'foreign', 'Info')`); err != nil {
+		t.Fatalf("insert logs: %v", err)
+	}
+
+	if rec := serveAs(s, 1, httptest.NewRequest(http.MethodGet, "/logs/20", nil)); rec.Code != http.StatusNotFound {
+		t.Fatalf("GET /logs/20 = %d, want 404", rec.Code)
+	}
+	rec := serveAs(s, 1, httptest.NewRequest(http.MethodGet, "/logs/10", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /logs/10 = %d, want 200", rec.Code)
+	}
+	var logs []types.LogResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &logs); err != nil {
+		t.Fatalf("decode logs: %v", err)
+	}
+	if len(logs) != 1 || logs[0].Message == nil || *logs[0].Message != "own" {
+		t.Fatalf("GET /logs/10 = %+v, want the own log only", logs)
+	}
+}
+
+func TestDLQEndpointsRequireAdmin(t *testing.T) {
+	s, db := newPostgresTestServer(t)
+	if _, err := db.Exec(`INSERT INTO "user" (id, first_name, email, role) VALUES (1, 'regular', 'r@example.com', 'RegularUser')`); err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+
+	requests := []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/queues/q/dlq", nil),
+		httptest.NewRequest(http.MethodPost, "/queues/q/dlq/requeue", strings.NewReader(`{"count":1}`)),
+	}
+	for _, r := range requests {
+		if rec := serveAs(s, 1, r); rec.Code != http.StatusForbidden {
+			t.Errorf("%s %s as regular user = %d, want 403", r.Method, r.URL.Path, rec.Code)
+		}
+	}
+}
+
+func TestReplayPipeline(t *testing.T) {
+	s, db := newPostgresTestServer(t)
+	running := insertTestPipeline(t, db, 10, types.PipelineStatusRunning, false)
+	finished := insertTestPipeline(t, db, 10, types.PipelineStatusFailed, true)
+	insertTestStage(t, db, finished, "resize", types.StageStatusFailed)
+	foreign := insertTestPipeline(t, db, 20, types.PipelineStatusFailed, true)
+
+	replay := func(id int) *httptest.ResponseRecorder {
+		path := "/pipelines/" + strconv.Itoa(id) + "/replay"
+		return serveAs(s, 1, httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"copyContext":true}`)))
+	}
+	if rec := replay(running); rec.Code != http.StatusConflict {
+		t.Errorf("replay unfinished pipeline = %d, want 409", rec.Code)
+	}
+	if rec := replay(foreign); rec.Code != http.StatusNotFound {
+		t.Errorf("replay foreign pipeline = %d, want 404", rec.Code)
+	}
+
+	rec := replay(finished)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("replay finished pipeline = %d, want 201: %s", rec.Code, rec.Body.String())
+	}
+	var clone types.PipelineResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &clone); err != nil {
+		t.Fatalf("decode pipeline: %v", err)
+	}
+	if clone.ID == finished || len(clone.Stages) != 1 || clone.Stages[0].Status != types.StageStatusNotStarted {
+		t.Fatalf("replay = %+v, want a new pipeline with one NotStarted stage", clone)
+	}
+}
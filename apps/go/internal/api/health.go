@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"pipelogiq/internal/mq"
+	"pipelogiq/internal/store"
+	"pipelogiq/internal/types"
+)
+
+// readinessTimeout bounds each dependency check of the readiness endpoint.
+const readinessTimeout = 2 * time.Second
+
+// handleLiveness answers without touching any dependency, so a slow database
+// or broker never gets the process restarted.
+func handleLiveness(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
+
+// readinessHandler pings the database and the RabbitMQ connection and answers
+// 503 listing the unhealthy dependencies until both respond. The endpoint is
+// unauthenticated, so the errors are logged rather than returned.
+func readinessHandler(st *store.Store, mqClient *mq.Client, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		checks := map[string]func(context.Context) error{
+			"database": st.DB().PingContext,
+			"rabbitmq": mqClient.Ping,
+		}
+
+		resp := types.ReadinessResponse{Status: "ok", Checks: make(map[string]string, len(checks))}
+		status := http.StatusOK
+		for name, check := range checks {
+			ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+			err := check(ctx)
+			cancel()
+			if err != nil {
+				logger.Warn("readiness check failed", "dependency", name, "err", err)
+				resp.Checks[name] = "unavailable"
+				resp.Status = "unavailable"
+				status = http.StatusServiceUnavailable
+				continue
+			}
+			resp.Checks[name] = "ok"
+		}
+
+		writeJSON(w, resp, status)
+	}
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// decodeIngestBody decodes the JSON body of an ingestion request (worker
+// event batches and logs), which may be sent with Content-Encoding: gzip.
+// Both the body as sent and the decompressed JSON are capped at limit bytes,
+// so a small compressed body cannot expand into an unbounded one.
+func decodeIngestBody(w http.ResponseWriter, r *http.Request, limit int, target any) *requestError {
+	body := http.MaxBytesReader(w, r.Body, int64(limit))
+	var reader io.Reader = body
+	gzipped := false
+
+	switch encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return ingestBodyError(err, true)
+		}
+		defer gz.Close()
+		reader, gzipped = gz, true
+	default:
+		return newRequestError(http.StatusUnsupportedMediaType, errCodeInvalidRequest,
+			fmt.Sprintf("unsupported content encoding %q; use gzip or identity", encoding))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(reader, int64(limit)+1))
+	if err != nil {
+		return ingestBodyError(err, gzipped)
+	}
+	if len(data) > limit {
+		return newRequestError(http.StatusRequestEntityTooLarge, errCodePayloadTooLarge,
+			fmt.Sprintf("decompressed body exceeds %d bytes", limit))
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return newRequestError(http.StatusBadRequest, errCodeInvalidPayload, "invalid payload")
+	}
+	return nil
+}
+
+// ingestBodyError maps a failure reading the body: an oversized body answers
+// 413, anything else 400, described as a malformed gzip stream when gzipped.
+func ingestBodyError(err error, gzipped bool) *requestError {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return newRequestError(http.StatusRequestEntityTooLarge, errCodePayloadTooLarge,
+			fmt.Sprintf("body exceeds %d bytes", tooLarge.Limit))
+	}
+	if gzipped {
+		return newRequestError(http.StatusBadRequest, errCodeInvalidPayload, "malformed gzip body")
+	}
+	return newRequestError(http.StatusBadRequest, errCodeInvalidPayload, "failed to read body")
+}
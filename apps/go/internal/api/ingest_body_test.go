@@ -0,0 +1,95 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatalf("gzip write: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// failingReader returns err after its data.
+type failingReader struct {
+	data []byte
+	err  error
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	if len(f.data) == 0 {
+		return 0, f.err
+	}
+	n := copy(p, f.data)
+	f.data = f.data[n:]
+	return n, nil
+}
+
+func TestDecodeIngestBody(t *testing.T) {
+	const limit = 1024
+	payload := []byte(`{"message":"hello"}`)
+	compressed := gzipBytes(t, payload)
+	// Highly compressible JSON that is small on the wire but over the limit
+	// once decompressed.
+	bomb := gzipBytes(t, []byte(`{"message":"`+strings.Repeat("a", 4*limit)+`"}`))
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     io.Reader
+		status   int
+		message  string
+	}{
+		{"identity", "", bytes.NewReader(payload), 0, ""},
+		{"gzip", "gzip", bytes.NewReader(compressed), 0, ""},
+		{"x-gzip", "x-gzip", bytes.NewReader(compressed), 0, ""},
+		{"truncated gzip", "gzip", bytes.NewReader(compressed[:len(compressed)-6]), http.StatusBadRequest, "malformed gzip body"},
+		{"not gzip", "gzip", bytes.NewReader(payload), http.StatusBadRequest, "malformed gzip body"},
+		{"decompressed over limit", "gzip", bytes.NewReader(bomb), http.StatusRequestEntityTooLarge, "decompressed body exceeds 1024 bytes"},
+		{"raw over limit", "", strings.NewReader(`{"message":"` + strings.Repeat("a", 2*limit) + `"}`), http.StatusRequestEntityTooLarge, "body exceeds 1024 bytes"},
+		{"unsupported encoding", "br", bytes.NewReader(payload), http.StatusUnsupportedMediaType, `unsupported content encoding "br"; use gzip or identity`},
+		{"identity read error", "", &failingReader{data: payload[:5], err: errors.New("connection reset")}, http.StatusBadRequest, "failed to read body"},
+		{"invalid json", "", strings.NewReader(`{`), http.StatusBadRequest, "invalid payload"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/logs", tt.body)
+			if tt.encoding != "" {
+				r.Header.Set("Content-Encoding", tt.encoding)
+			}
+			var target struct {
+				Message string `json:"message"`
+			}
+			reqErr := decodeIngestBody(httptest.NewRecorder(), r, limit, &target)
+			if tt.status == 0 {
+				if reqErr != nil {
+					t.Fatalf("decodeIngestBody() error = %d %s", reqErr.status, reqErr.message)
+				}
+				if target.Message != "hello" {
+					t.Fatalf("decoded message = %q, want hello", target.Message)
+				}
+				return
+			}
+			if reqErr == nil {
+				t.Fatalf("decodeIngestBody() error = nil, want %d", tt.status)
+			}
+			if reqErr.status != tt.status || reqErr.message != tt.message {
+				t.Fatalf("decodeIngestBody() error = %d %q, want %d %q", reqErr.status, reqErr.message, tt.status, tt.message)
+			}
+		})
+	}
+}
@@ -8,4 +8,5 @@ import (
 
 func (s *Server) registerObservabilityRoutes(r chi.Router) {
 	observabilityhttp.RegisterRoutes(r, s.observabilityHandler)
+	r.Get("/queues", s.handleGetQueueStats)
 }
@@ -17,18 +17,30 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
 
+	"pipelogiq/internal/constants"
+	"pipelogiq/internal/mq"
+	"pipelogiq/internal/policyengine"
 	"pipelogiq/internal/types"
 )
 
 var errPolicyNotFound = errors.New("policy not found")
 
+// Bounds for Policy.Priority; higher values win when several policies of the
+// same type match a target.
+const (
+	minPolicyPriority = 0
+	maxPolicyPriority = 1000
+)
+
 type upsertPolicyRequest struct {
 	Name        string                  `json:"name"`
 	Description *string                 `json:"description,omitempty"`
 	Type        types.PolicyType        `json:"type"`
 	Status      *types.PolicyStatus     `json:"status,omitempty"`
 	Environment types.PolicyEnvironment `json:"environment"`
+	Priority    *int                    `json:"priority,omitempty"`
 	Targeting   types.PolicyTargeting   `json:"targeting"`
 	Rule        types.PolicyRule        `json:"rule"`
 }
@@ -37,6 +49,7 @@ type policyDetailResponse struct {
 	types.Policy
 	LastTriggeredAt     *time.Time `json:"lastTriggeredAt,omitempty"`
 	TriggerCountInRange int        `json:"triggerCountInRange"`
+	InactiveBySchedule  bool       `json:"inactiveBySchedule"`
 }
 
 type policyStoreSnapshot struct {
@@ -44,6 +57,31 @@ type policyStoreSnapshot struct {
 	Events   []types.PolicyEvent `json:"events"`
 }
 
+// Import modes: create always adds new policies, merge updates policies
+// matched by name and creates the rest, replace additionally deletes every
+// existing policy that is not part of the batch.
+const (
+	policyImportModeCreate  = "create"
+	policyImportModeMerge   = "merge"
+	policyImportModeReplace = "replace"
+)
+
+// maxPolicyImportSize caps how many policies one import request may carry.
+const maxPolicyImportSize = 500
+
+type policyImportRequest struct {
+	Mode     string                `json:"mode"`
+	Policies []upsertPolicyRequest `json:"policies"`
+}
+
+type policyImportResponse struct {
+	Mode     string         `json:"mode"`
+	Created  int            `json:"created"`
+	Updated  int            `json:"updated"`
+	Deleted  int            `json:"deleted"`
+	Policies []types.Policy `json:"policies"`
+}
+
 type policyListFilter struct {
 	Search     string
 	Type       *types.PolicyType
@@ -53,8 +91,13 @@ type policyListFilter struct {
 	Range      time.Duration
 	SortBy     string
 	SortDir    string
+	Limit      int
+	Offset     int
 }
 
+// maxPolicyListLimit caps the page size accepted by the policy list.
+const maxPolicyListLimit = 500
+
 type policyRepository struct {
 	mu            sync.RWMutex
 	policies      map[string]types.Policy
@@ -69,13 +112,23 @@ func newPolicyRepository(logger *slog.Logger) *policyRepository {
 	repo := &policyRepository{
 		policies: make(map[string]types.Policy),
 		events:   make(map[string][]types.PolicyEvent),
-		filePath: resolvePolicyStorePath(),
+		filePath: policyengine.StorePath(),
 		logger:   logger,
 	}
 
 	if err := repo.load(); err != nil {
 		logger.Error("load policy store failed; starting with empty store", "err", err, "path", repo.filePath)
 	}
+	// The worker refuses to start without the store file, so create it even
+	// before the first policy is saved.
+	if _, err := os.Stat(repo.filePath); errors.Is(err, os.ErrNotExist) {
+		repo.mu.Lock()
+		err := repo.saveLocked()
+		repo.mu.Unlock()
+		if err != nil {
+			logger.Error("create policy store failed", "err", err, "path", repo.filePath)
+		}
+	}
 
 	return repo
 }
@@ -86,33 +139,6 @@ func (r *policyRepository) setEventListener(listener func(types.PolicyEvent)) {
 	r.eventListener = listener
 }
 
-func resolvePolicyStorePath() string {
-	if envPath := strings.TrimSpace(os.Getenv("POLICY_STORE_PATH")); envPath != "" {
-		return envPath
-	}
-
-	candidates := []string{
-		"./data/policies.json",
-		"../../data/policies.json",
-	}
-
-	for _, candidate := range candidates {
-		if dirExists(filepath.Dir(candidate)) {
-			return candidate
-		}
-	}
-
-	return "./data/policies.json"
-}
-
-func dirExists(path string) bool {
-	info, err := os.Stat(path)
-	if err != nil {
-		return false
-	}
-	return info.IsDir()
-}
-
 func (r *policyRepository) load() error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -149,9 +175,28 @@ func (r *policyRepository) load() error {
 }
 
 func (r *policyRepository) saveLocked() error {
+	data, err := json.MarshalIndent(r.snapshotLocked(true), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(r.filePath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tempFile := fmt.Sprintf("%s.tmp", r.filePath)
+	if err := os.WriteFile(tempFile, data, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile, r.filePath)
+}
+
+func (r *policyRepository) snapshotLocked(includeEvents bool) policyStoreSnapshot {
 	snapshot := policyStoreSnapshot{
 		Policies: make([]types.Policy, 0, len(r.policies)),
-		Events:   make([]types.PolicyEvent, 0, len(r.events)*2),
+		Events:   []types.PolicyEvent{},
 	}
 
 	for _, policy := range r.policies {
@@ -161,6 +206,10 @@ func (r *policyRepository) saveLocked() error {
 		return snapshot.Policies[i].UpdatedAt.After(snapshot.Policies[j].UpdatedAt)
 	})
 
+	if !includeEvents {
+		return snapshot
+	}
+
 	for _, events := range r.events {
 		for _, event := range events {
 			snapshot.Events = append(snapshot.Events, clonePolicyEvent(event))
@@ -169,23 +218,14 @@ func (r *policyRepository) saveLocked() error {
 	sort.Slice(snapshot.Events, func(i, j int) bool {
 		return snapshot.Events[i].TS.Before(snapshot.Events[j].TS)
 	})
+	return snapshot
+}
 
-	data, err := json.MarshalIndent(snapshot, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	dir := filepath.Dir(r.filePath)
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return err
-	}
-
-	tempFile := fmt.Sprintf("%s.tmp", r.filePath)
-	if err := os.WriteFile(tempFile, data, 0o644); err != nil {
-		return err
-	}
+func (r *policyRepository) export(includeEvents bool) policyStoreSnapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-	return os.Rename(tempFile, r.filePath)
+	return r.snapshotLocked(includeEvents)
 }
 
 func (r *policyRepository) list(filter policyListFilter) types.PolicyListResponse {
@@ -195,7 +235,8 @@ func (r *policyRepository) list(filter policyListFilter) types.PolicyListRespons
 	if filter.Range <= 0 {
 		filter.Range = 24 * time.Hour
 	}
-	rangeStart := time.Now().UTC().Add(-filter.Range)
+	now := time.Now().UTC()
+	rangeStart := now.Add(-filter.Range)
 
 	items := make([]types.PolicyListItem, 0, len(r.policies))
 	for _, policy := range r.policies {
@@ -208,14 +249,24 @@ func (r *policyRepository) list(filter policyListFilter) types.PolicyListRespons
 			Policy:              clonePolicy(policy),
 			LastTriggeredAt:     lastTriggeredAt,
 			TriggerCountInRange: triggerCount,
+			InactiveBySchedule:  policy.Status == types.PolicyStatusActive && !policyengine.ScheduleActive(policy.Targeting.Schedule, now),
 		})
 	}
 
 	sortPolicies(items, filter.SortBy, filter.SortDir)
 
+	total := len(items)
+	start := min(filter.Offset, total)
+	end := total
+	if filter.Limit > 0 {
+		end = min(start+filter.Limit, total)
+	}
+
 	return types.PolicyListResponse{
-		Items:      items,
-		TotalCount: len(items),
+		Items:      items[start:end],
+		TotalCount: total,
+		Limit:      filter.Limit,
+		Offset:     filter.Offset,
 	}
 }
 
@@ -239,6 +290,23 @@ func (r *policyRepository) exists(policyID string) bool {
 }
 
 func (r *policyRepository) create(req upsertPolicyRequest, actor string) (types.Policy, error) {
+	policy := newPolicyFromRequest(req, actor)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.policies[policy.ID] = clonePolicy(policy)
+	r.appendEventLocked(policy.ID, actor, types.PolicyEventTypeCreated, map[string]any{
+		"version": policy.Version,
+	})
+	if err := r.saveLocked(); err != nil {
+		r.logger.Error("save policy store failed", "err", err)
+	}
+
+	return clonePolicy(policy), nil
+}
+
+func newPolicyFromRequest(req upsertPolicyRequest, actor string) types.Policy {
 	policy := types.Policy{
 		ID:          uuid.NewString(),
 		Name:        strings.TrimSpace(req.Name),
@@ -254,6 +322,9 @@ func (r *policyRepository) create(req upsertPolicyRequest, actor string) (types.
 	if req.Status != nil {
 		policy.Status = *req.Status
 	}
+	if req.Priority != nil {
+		policy.Priority = *req.Priority
+	}
 	if policy.Environment == "" {
 		policy.Environment = types.PolicyEnvironmentAll
 	}
@@ -263,20 +334,7 @@ func (r *policyRepository) create(req upsertPolicyRequest, actor string) (types.
 	policy.UpdatedAt = now
 	policy.CreatedBy = actor
 	policy.UpdatedBy = actor
-	policy = normalizePolicy(policy)
-
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	r.policies[policy.ID] = clonePolicy(policy)
-	r.appendEventLocked(policy.ID, actor, types.PolicyEventTypeCreated, map[string]any{
-		"version": policy.Version,
-	})
-	if err := r.saveLocked(); err != nil {
-		r.logger.Error("save policy store failed", "err", err)
-	}
-
-	return clonePolicy(policy), nil
+	return normalizePolicy(policy)
 }
 
 func (r *policyRepository) update(policyID string, req upsertPolicyRequest, actor string) (types.Policy, error) {
@@ -289,6 +347,21 @@ func (r *policyRepository) update(policyID string, req upsertPolicyRequest, acto
 	}
 
 	previousVersion := existing.Version
+	existing = applyUpsertRequest(existing, req, actor)
+
+	r.policies[policyID] = clonePolicy(existing)
+	r.appendEventLocked(policyID, actor, types.PolicyEventTypeUpdated, map[string]any{
+		"fromVersion": previousVersion,
+		"toVersion":   existing.Version,
+	})
+	if err := r.saveLocked(); err != nil {
+		r.logger.Error("save policy store failed", "err", err)
+	}
+
+	return clonePolicy(existing), nil
+}
+
+func applyUpsertRequest(existing types.Policy, req upsertPolicyRequest, actor string) types.Policy {
 	existing.Name = strings.TrimSpace(req.Name)
 	existing.Description = normalizeDescription(req.Description)
 	existing.Type = req.Type
@@ -298,22 +371,14 @@ func (r *policyRepository) update(policyID string, req upsertPolicyRequest, acto
 	if req.Status != nil {
 		existing.Status = *req.Status
 	}
+	if req.Priority != nil {
+		existing.Priority = *req.Priority
+	}
 
 	existing.Version++
 	existing.UpdatedAt = time.Now().UTC()
 	existing.UpdatedBy = actor
-	existing = normalizePolicy(existing)
-
-	r.policies[policyID] = clonePolicy(existing)
-	r.appendEventLocked(policyID, actor, types.PolicyEventTypeUpdated, map[string]any{
-		"fromVersion": previousVersion,
-		"toVersion":   existing.Version,
-	})
-	if err := r.saveLocked(); err != nil {
-		r.logger.Error("save policy store failed", "err", err)
-	}
-
-	return clonePolicy(existing), nil
+	return normalizePolicy(existing)
 }
 
 func (r *policyRepository) setStatus(policyID string, status types.PolicyStatus, actor string, eventType types.PolicyEventType) (types.Policy, error) {
@@ -422,12 +487,19 @@ func (r *policyRepository) insights(rangeDuration time.Duration) types.PolicyIns
 	if rangeDuration <= 0 {
 		rangeDuration = 24 * time.Hour
 	}
-	rangeStart := time.Now().UTC().Add(-rangeDuration)
+	now := time.Now().UTC()
+	rangeStart := now.Add(-rangeDuration)
 
 	activePolicies := 0
+	inactiveBySchedule := 0
 	for _, policy := range r.policies {
-		if policy.Status == types.PolicyStatusActive {
+		if policy.Status != types.PolicyStatusActive {
+			continue
+		}
+		if policyengine.ScheduleActive(policy.Targeting.Schedule, now) {
 			activePolicies++
+		} else {
+			inactiveBySchedule++
 		}
 	}
 
@@ -468,6 +540,7 @@ func (r *policyRepository) insights(rangeDuration time.Duration) types.PolicyIns
 
 	return types.PolicyInsightsResponse{
 		ActivePoliciesCount:     activePolicies,
+		InactiveByScheduleCount: inactiveBySchedule,
 		PoliciesTriggered:       triggered,
 		ActionsBlockedThrottled: blocked,
 		TopPolicy:               top,
@@ -514,6 +587,131 @@ func (r *policyRepository) computeTriggerStatsLocked(policyID string, rangeStart
 	return lastTriggeredAt, count, blocked
 }
 
+// importPolicies applies a validated batch atomically: the store is only
+// changed, and event listeners only notified, once the snapshot was written.
+func (r *policyRepository) importPolicies(reqs []upsertPolicyRequest, mode, actor string) (policyImportResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prevPolicies := make(map[string]types.Policy, len(r.policies))
+	for id, policy := range r.policies {
+		prevPolicies[id] = policy
+	}
+	prevEvents := make(map[string][]types.PolicyEvent, len(r.events))
+	for id, events := range r.events {
+		prevEvents[id] = events[:len(events):len(events)]
+	}
+
+	listener := r.eventListener
+	var pending []types.PolicyEvent
+	r.eventListener = func(event types.PolicyEvent) { pending = append(pending, event) }
+	defer func() { r.eventListener = listener }()
+
+	byName := make(map[string]types.Policy, len(r.policies))
+	if mode != policyImportModeCreate {
+		for _, policy := range r.policies {
+			key := strings.ToLower(policy.Name)
+			if current, ok := byName[key]; !ok || policy.UpdatedAt.After(current.UpdatedAt) {
+				byName[key] = policy
+			}
+		}
+	}
+
+	resp := policyImportResponse{Mode: mode, Policies: make([]types.Policy, 0, len(reqs))}
+	touched := make(map[string]struct{}, len(reqs))
+	for _, req := range reqs {
+		existing, ok := byName[strings.ToLower(strings.TrimSpace(req.Name))]
+		if ok {
+			previousVersion := existing.Version
+			updated := applyUpsertRequest(existing, req, actor)
+			r.policies[updated.ID] = clonePolicy(updated)
+			r.appendEventLocked(updated.ID, actor, types.PolicyEventTypeUpdated, map[string]any{
+				"fromVersion": previousVersion,
+				"toVersion":   updated.Version,
+				"imported":    true,
+			})
+			touched[updated.ID] = struct{}{}
+			resp.Updated++
+			resp.Policies = append(resp.Policies, clonePolicy(updated))
+			continue
+		}
+
+		created := newPolicyFromRequest(req, actor)
+		r.policies[created.ID] = clonePolicy(created)
+		r.appendEventLocked(created.ID, actor, types.PolicyEventTypeCreated, map[string]any{
+			"version":  created.Version,
+			"imported": true,
+		})
+		touched[created.ID] = struct{}{}
+		resp.Created++
+		resp.Policies = append(resp.Policies, clonePolicy(created))
+	}
+
+	if mode == policyImportModeReplace {
+		for id, policy := range prevPolicies {
+			if _, ok := touched[id]; ok {
+				continue
+			}
+			delete(r.policies, id)
+			r.appendEventLocked(id, actor, types.PolicyEventTypeDeleted, map[string]any{
+				"name":     policy.Name,
+				"version":  policy.Version,
+				"imported": true,
+			})
+			resp.Deleted++
+		}
+	}
+
+	if err := r.saveLocked(); err != nil {
+		r.policies = prevPolicies
+		r.events = prevEvents
+		return policyImportResponse{}, err
+	}
+
+	if listener != nil {
+		for _, event := range pending {
+			listener(event)
+		}
+	}
+	return resp, nil
+}
+
+// effective resolves the winning active policy per type for the target
+// described by event.
+func (r *policyRepository) effective(event types.PolicySimulateRequest, now time.Time) types.PolicyEffectiveResponse {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	candidates := make([]types.Policy, 0, len(r.policies))
+	for _, policy := range r.policies {
+		candidates = append(candidates, policy)
+	}
+
+	resolved := policyengine.ResolveEffective(candidates, event, now)
+	for i := range resolved {
+		resolved[i].Policy = clonePolicy(resolved[i].Policy)
+	}
+	return types.PolicyEffectiveResponse{Policies: resolved}
+}
+
+// recordTrigger appends a triggered event reported by a worker or SDK.
+func (r *policyRepository) recordTrigger(policyID, actor string, details map[string]any) (types.PolicyEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.policies[policyID]; !ok {
+		return types.PolicyEvent{}, errPolicyNotFound
+	}
+
+	r.appendEventLocked(policyID, actor, types.PolicyEventTypeTriggered, details)
+	if err := r.saveLocked(); err != nil {
+		r.logger.Error("save policy store failed", "err", err)
+	}
+
+	events := r.events[policyID]
+	return clonePolicyEvent(events[len(events)-1]), nil
+}
+
 func (r *policyRepository) appendEventLocked(policyID, actor string, eventType types.PolicyEventType, details map[string]any) {
 	event := types.PolicyEvent{
 		ID:       uuid.NewString(),
@@ -571,10 +769,28 @@ func (s *Server) handleGetPolicies(w http.ResponseWriter, r *http.Request) {
 		SortDir:    query.Get("sortDir"),
 	}
 
+	if limitVal := strings.TrimSpace(query.Get("limit")); limitVal != "" {
+		parsed, err := strconv.Atoi(limitVal)
+		if err != nil || parsed <= 0 {
+			writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid limit")
+			return
+		}
+		filter.Limit = min(parsed, maxPolicyListLimit)
+	}
+
+	if offsetVal := strings.TrimSpace(query.Get("offset")); offsetVal != "" {
+		parsed, err := strconv.Atoi(offsetVal)
+		if err != nil || parsed < 0 {
+			writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid offset")
+			return
+		}
+		filter.Offset = parsed
+	}
+
 	if typeVal := strings.TrimSpace(query.Get("type")); typeVal != "" {
 		parsed := types.PolicyType(typeVal)
 		if !isValidPolicyType(parsed) {
-			http.Error(w, "invalid type", http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid type")
 			return
 		}
 		filter.Type = &parsed
@@ -583,7 +799,7 @@ func (s *Server) handleGetPolicies(w http.ResponseWriter, r *http.Request) {
 	if statusVal := strings.TrimSpace(query.Get("status")); statusVal != "" {
 		parsed := types.PolicyStatus(statusVal)
 		if !isValidPolicyStatus(parsed) {
-			http.Error(w, "invalid status", http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid status")
 			return
 		}
 		filter.Status = &parsed
@@ -592,7 +808,7 @@ func (s *Server) handleGetPolicies(w http.ResponseWriter, r *http.Request) {
 	if envVal := strings.TrimSpace(query.Get("env")); envVal != "" {
 		parsed := types.PolicyEnvironment(envVal)
 		if !isValidPolicyEnvironment(parsed) {
-			http.Error(w, "invalid env", http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid env")
 			return
 		}
 		filter.Env = &parsed
@@ -605,19 +821,19 @@ func (s *Server) handleGetPolicies(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleCreatePolicy(w http.ResponseWriter, r *http.Request) {
 	var req upsertPolicyRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid payload", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidPayload, "invalid payload")
 		return
 	}
 
 	if err := validateUpsertPolicyRequest(req, true); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 		return
 	}
 
 	actor := s.resolvePolicyActor(r.Context())
 	policy, err := s.policies.create(req, actor)
 	if err != nil {
-		http.Error(w, "failed to create policy", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to create policy")
 		return
 	}
 
@@ -628,7 +844,7 @@ func (s *Server) handleGetPolicy(w http.ResponseWriter, r *http.Request) {
 	policyID := chi.URLParam(r, "id")
 	policy, ok := s.policies.get(policyID)
 	if !ok {
-		http.Error(w, "policy not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, errCodePolicyNotFound, "policy not found")
 		return
 	}
 
@@ -639,6 +855,7 @@ func (s *Server) handleGetPolicy(w http.ResponseWriter, r *http.Request) {
 		Policy:              policy,
 		LastTriggeredAt:     lastTriggeredAt,
 		TriggerCountInRange: triggerCount,
+		InactiveBySchedule:  policy.Status == types.PolicyStatusActive && !policyengine.ScheduleActive(policy.Targeting.Schedule, time.Now()),
 	}, http.StatusOK)
 }
 
@@ -647,12 +864,12 @@ func (s *Server) handleUpdatePolicy(w http.ResponseWriter, r *http.Request) {
 
 	var req upsertPolicyRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid payload", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidPayload, "invalid payload")
 		return
 	}
 
 	if err := validateUpsertPolicyRequest(req, false); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 		return
 	}
 
@@ -660,10 +877,10 @@ func (s *Server) handleUpdatePolicy(w http.ResponseWriter, r *http.Request) {
 	policy, err := s.policies.update(policyID, req, actor)
 	if err != nil {
 		if errors.Is(err, errPolicyNotFound) {
-			http.Error(w, "policy not found", http.StatusNotFound)
+			writeError(w, http.StatusNotFound, errCodePolicyNotFound, "policy not found")
 			return
 		}
-		http.Error(w, "failed to update policy", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to update policy")
 		return
 	}
 
@@ -677,10 +894,10 @@ func (s *Server) handleDuplicatePolicy(w http.ResponseWriter, r *http.Request) {
 	duplicated, err := s.policies.duplicate(policyID, actor)
 	if err != nil {
 		if errors.Is(err, errPolicyNotFound) {
-			http.Error(w, "policy not found", http.StatusNotFound)
+			writeError(w, http.StatusNotFound, errCodePolicyNotFound, "policy not found")
 			return
 		}
-		http.Error(w, "failed to duplicate policy", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to duplicate policy")
 		return
 	}
 
@@ -714,12 +931,12 @@ func (s *Server) handlePolicyStatusTransition(
 
 	currentPolicy, ok := s.policies.get(policyID)
 	if !ok {
-		http.Error(w, "policy not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, errCodePolicyNotFound, "policy not found")
 		return
 	}
 
 	if requiredCurrent != "" && currentPolicy.Status != requiredCurrent {
-		http.Error(w, fmt.Sprintf("policy must be %s", requiredCurrent), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("policy must be %s", requiredCurrent))
 		return
 	}
 
@@ -727,26 +944,131 @@ func (s *Server) handlePolicyStatusTransition(
 	updatedPolicy, err := s.policies.setStatus(policyID, targetStatus, actor, eventType)
 	if err != nil {
 		if errors.Is(err, errPolicyNotFound) {
-			http.Error(w, "policy not found", http.StatusNotFound)
+			writeError(w, http.StatusNotFound, errCodePolicyNotFound, "policy not found")
 			return
 		}
-		http.Error(w, "failed to update status", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to update status")
 		return
 	}
 
 	writeJSON(w, updatedPolicy, http.StatusOK)
 }
 
+func (s *Server) handleExportPolicies(w http.ResponseWriter, r *http.Request) {
+	includeEvents, _ := strconv.ParseBool(r.URL.Query().Get("includeEvents"))
+	writeJSON(w, s.policies.export(includeEvents), http.StatusOK)
+}
+
+func (s *Server) handleImportPolicies(w http.ResponseWriter, r *http.Request) {
+	var req policyImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errCodeInvalidPayload, "invalid payload")
+		return
+	}
+
+	req.Mode = strings.ToLower(strings.TrimSpace(req.Mode))
+	if req.Mode == "" {
+		req.Mode = policyImportModeCreate
+	}
+	if !isOneOf(req.Mode, policyImportModeCreate, policyImportModeMerge, policyImportModeReplace) {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "mode must be one of: create, merge, replace")
+		return
+	}
+	if len(req.Policies) == 0 {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "policies are required")
+		return
+	}
+	if len(req.Policies) > maxPolicyImportSize {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("at most %d policies can be imported at once", maxPolicyImportSize))
+		return
+	}
+
+	names := make(map[string]struct{}, len(req.Policies))
+	for i, policy := range req.Policies {
+		if err := validateUpsertPolicyRequest(policy, true); err != nil {
+			writeError(w, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("policy %d (%s): %v", i, policy.Name, err))
+			return
+		}
+		if req.Mode == policyImportModeCreate {
+			continue
+		}
+		name := strings.ToLower(strings.TrimSpace(policy.Name))
+		if _, ok := names[name]; ok {
+			writeError(w, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("policy %d (%s): duplicate name in batch", i, policy.Name))
+			return
+		}
+		names[name] = struct{}{}
+	}
+
+	actor := s.resolvePolicyActor(r.Context())
+	resp, err := s.policies.importPolicies(req.Policies, req.Mode, actor)
+	if err != nil {
+		s.logger.Error("import policies failed", "err", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to import policies")
+		return
+	}
+
+	writeJSON(w, resp, http.StatusOK)
+}
+
+func (s *Server) handleGetEffectivePolicies(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	event := types.PolicySimulateRequest{
+		PipelineID:  strings.TrimSpace(query.Get("pipelineId")),
+		Stage:       strings.TrimSpace(query.Get("stage")),
+		Handler:     strings.TrimSpace(query.Get("handler")),
+		Environment: types.PolicyEnvironment(strings.ToLower(strings.TrimSpace(query.Get("env")))),
+		Tags:        dedupeNonEmpty(strings.Split(query.Get("tags"), ",")),
+	}
+	if event.Environment != "" && !isValidPolicyEnvironment(event.Environment) {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid environment")
+		return
+	}
+
+	writeJSON(w, s.policies.effective(event, time.Now().UTC()), http.StatusOK)
+}
+
+func (s *Server) handleSimulatePolicy(w http.ResponseWriter, r *http.Request) {
+	policy, ok := s.policies.get(chi.URLParam(r, "id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, errCodePolicyNotFound, "policy not found")
+		return
+	}
+
+	var req types.PolicySimulateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errCodeInvalidPayload, "invalid payload")
+		return
+	}
+	if req.Environment != "" && !isValidPolicyEnvironment(req.Environment) {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid environment")
+		return
+	}
+
+	now := time.Now().UTC()
+	if req.At != nil {
+		now = *req.At
+	}
+
+	decision, err := policyengine.Evaluate(policy, req, now)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, decision, http.StatusOK)
+}
+
 func (s *Server) handleDeletePolicy(w http.ResponseWriter, r *http.Request) {
 	policyID := chi.URLParam(r, "id")
 	actor := s.resolvePolicyActor(r.Context())
 
 	if err := s.policies.delete(policyID, actor); err != nil {
 		if errors.Is(err, errPolicyNotFound) {
-			http.Error(w, "policy not found", http.StatusNotFound)
+			writeError(w, http.StatusNotFound, errCodePolicyNotFound, "policy not found")
 			return
 		}
-		http.Error(w, "failed to delete policy", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to delete policy")
 		return
 	}
 
@@ -757,7 +1079,7 @@ func (s *Server) handleGetPolicyAudit(w http.ResponseWriter, r *http.Request) {
 	policyID := chi.URLParam(r, "id")
 	events := s.policies.audit(policyID)
 	if len(events) == 0 && !s.policies.exists(policyID) {
-		http.Error(w, "policy not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, errCodePolicyNotFound, "policy not found")
 		return
 	}
 
@@ -773,74 +1095,41 @@ func (s *Server) handleGetPolicyInsights(w http.ResponseWriter, r *http.Request)
 	writeJSON(w, insights, http.StatusOK)
 }
 
+// handleGetPolicyTargetOptions lists the pipelines, stages, handlers and tags
+// a policy can target. ?search= keeps values containing the text (ignoring
+// case) and ?limit= caps each list at up to POLICY_TARGET_OPTIONS_LIMIT.
+// Results are cached for POLICY_TARGET_OPTIONS_CACHE_TTL, so they may lag
+// behind new pipelines by that long.
 func (s *Server) handleGetPolicyTargetOptions(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	pipelines := make([]types.PolicyTargetOption, 0)
-	pipelineRows := []struct {
-		ID   int    `db:"id"`
-		Name string `db:"name"`
-	}{}
-	if err := s.store.DB().SelectContext(ctx, &pipelineRows, `
-		SELECT id, COALESCE(name, '') AS name
-		FROM pipeline
-		ORDER BY created_at DESC
-		LIMIT 500
-	`); err == nil {
-		for _, row := range pipelineRows {
-			pipelines = append(pipelines, types.PolicyTargetOption{
-				ID:   strconv.Itoa(row.ID),
-				Name: row.Name,
-			})
+	search := strings.TrimSpace(r.URL.Query().Get("search"))
+	limit := s.cfg.PolicyTargetOptionsLimit
+	if value := strings.TrimSpace(r.URL.Query().Get("limit")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "limit must be a positive integer")
+			return
 		}
+		limit = min(parsed, limit)
 	}
 
-	stages := []string{}
-	_ = s.store.DB().SelectContext(ctx, &stages, `
-		SELECT DISTINCT COALESCE(name, '') AS name
-		FROM stage
-		WHERE COALESCE(name, '') <> ''
-		ORDER BY name
-		LIMIT 500
-	`)
-
-	handlers := []string{}
-	_ = s.store.DB().SelectContext(ctx, &handlers, `
-		SELECT DISTINCT COALESCE(stage_handler_name, '') AS stage_handler_name
-		FROM stage
-		WHERE COALESCE(stage_handler_name, '') <> ''
-		ORDER BY stage_handler_name
-		LIMIT 500
-	`)
-
-	tags := []string{}
-	_ = s.store.DB().SelectContext(ctx, &tags, `
-		SELECT DISTINCT COALESCE(value, '') AS value
-		FROM keyword
-		WHERE COALESCE(value, '') <> ''
-		ORDER BY value
-		LIMIT 500
-	`)
-
-	writeJSON(w, types.PolicyTargetOptionsResponse{
-		Environments: []types.PolicyEnvironment{
-			types.PolicyEnvironmentAll,
-			types.PolicyEnvironmentProd,
-			types.PolicyEnvironmentStaging,
-			types.PolicyEnvironmentDev,
-		},
-		Pipelines: pipelines,
-		Stages:    dedupeNonEmpty(stages),
-		Handlers:  dedupeNonEmpty(handlers),
-		Tags:      dedupeNonEmpty(tags),
-	}, http.StatusOK)
+	options, err := s.targetOptions.get(ctx, search, limit, func(ctx context.Context) (types.PolicyTargetOptionsResponse, error) {
+		return s.loadPolicyTargetOptions(ctx, search, limit)
+	})
+	if err != nil {
+		s.logger.Error("load policy target options failed", "err", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to load policy target options")
+		return
+	}
+	writeJSON(w, options, http.StatusOK)
 }
 
 func (s *Server) handlePreviewPolicyTargets(w http.ResponseWriter, r *http.Request) {
 	var req types.PolicyPreviewRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid payload", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidPayload, "invalid payload")
 		return
 	}
 
@@ -848,7 +1137,16 @@ func (s *Server) handlePreviewPolicyTargets(w http.ResponseWriter, r *http.Reque
 		req.Environment = types.PolicyEnvironmentAll
 	}
 	if !isValidPolicyEnvironment(req.Environment) {
-		http.Error(w, "invalid environment", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid environment")
+		return
+	}
+	req.EnvironmentMatch = strings.ToLower(strings.TrimSpace(req.EnvironmentMatch))
+	switch req.EnvironmentMatch {
+	case "":
+		req.EnvironmentMatch = s.cfg.PolicyPreviewEnvMatch
+	case types.PolicyEnvironmentMatchExact, types.PolicyEnvironmentMatchContains:
+	default:
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "environmentMatch must be exact or contains")
 		return
 	}
 
@@ -857,30 +1155,57 @@ func (s *Server) handlePreviewPolicyTargets(w http.ResponseWriter, r *http.Reque
 
 	preview, err := s.previewPolicyMatches(ctx, req)
 	if err != nil {
-		http.Error(w, "failed to preview matches", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to preview matches")
 		return
 	}
 
 	writeJSON(w, preview, http.StatusOK)
 }
 
+// previewPolicyMatches counts the pipelines, stages and handlers a policy
+// with req's environment and targeting would apply to. A pipeline's
+// environment comes from its environment/env context item, else from an
+// environment/env keyword, else from the environments reported by the
+// application's workers (any of which may match).
 func (s *Server) previewPolicyMatches(ctx context.Context, req types.PolicyPreviewRequest) (types.PolicyPreviewResponse, error) {
 	pipelineRows := []struct {
-		ID          int    `db:"id"`
-		Environment string `db:"environment"`
+		ID            int    `db:"id"`
+		ApplicationID int    `db:"application_id"`
+		ContextEnv    string `db:"context_env"`
+		KeywordEnv    string `db:"keyword_env"`
 	}{}
 
 	err := s.store.DB().SelectContext(ctx, &pipelineRows, `
-		SELECT p.id,
-			COALESCE(MAX(CASE WHEN LOWER(pci.key) IN ('environment', 'env') THEN LOWER(pci.value) END), '') AS environment
+		SELECT p.id, COALESCE(p.application_id, 0) AS application_id,
+			COALESCE((
+				SELECT LOWER(MAX(pci.value)) FROM pipeline_context_item pci
+				WHERE pci.pipeline_id = p.id AND LOWER(pci.key) IN ('environment', 'env')
+			), '') AS context_env,
+			COALESCE((
+				SELECT LOWER(MAX(k.value)) FROM pipeline_keyword pk
+				JOIN keyword k ON k.id = pk.keyword_id
+				WHERE pk.pipeline_id = p.id AND LOWER(k.key) IN ('environment', 'env')
+			), '') AS keyword_env
 		FROM pipeline p
-		LEFT JOIN pipeline_context_item pci ON pci.pipeline_id = p.id
-		GROUP BY p.id
 	`)
 	if err != nil {
 		return types.PolicyPreviewResponse{}, err
 	}
 
+	workerEnvRows := []struct {
+		ApplicationID int    `db:"application_id"`
+		Environment   string `db:"environment"`
+	}{}
+	_ = s.store.DB().SelectContext(ctx, &workerEnvRows, `
+		SELECT DISTINCT application_id, LOWER(environment) AS environment
+		FROM worker_client
+		WHERE COALESCE(environment, '') <> ''
+	`)
+	workerEnvs := make(map[int][]string)
+	for _, row := range workerEnvRows {
+		workerEnvs[row.ApplicationID] = append(workerEnvs[row.ApplicationID], row.Environment)
+	}
+
 	tagRows := []struct {
 		PipelineID int    `db:"pipeline_id"`
 		Tag        string `db:"tag"`
@@ -935,26 +1260,33 @@ func (s *Server) previewPolicyMatches(ctx context.Context, req types.PolicyPrevi
 		tagsByPipeline[row.PipelineID][tag] = struct{}{}
 	}
 
+	var excluded types.PolicyPreviewExclusions
 	allowedPipelines := make(map[int]struct{})
 	for _, row := range pipelineRows {
 		pipelineID := strconv.Itoa(row.ID)
 		if len(pipelineFilter) > 0 {
 			if _, ok := pipelineFilter[strings.ToLower(pipelineID)]; !ok {
+				excluded.Pipelines++
 				continue
 			}
 		}
 
 		if req.Environment != types.PolicyEnvironmentAll {
-			if strings.ToLower(strings.TrimSpace(row.Environment)) != string(req.Environment) {
+			envs := workerEnvs[row.ApplicationID]
+			if env := strings.TrimSpace(row.ContextEnv); env != "" {
+				envs = []string{env}
+			} else if env := strings.TrimSpace(row.KeywordEnv); env != "" {
+				envs = []string{env}
+			}
+			if !matchesPreviewEnvironment(envs, req.Environment, req.EnvironmentMatch) {
+				excluded.Environment++
 				continue
 			}
 		}
 
 		tags := tagsByPipeline[row.ID]
-		if !containsAllTags(tags, tagsInclude) {
-			continue
-		}
-		if containsAnyTag(tags, tagsExclude) {
+		if !containsAllTags(tags, tagsInclude) || containsAnyTag(tags, tagsExclude) {
+			excluded.Tags++
 			continue
 		}
 
@@ -963,6 +1295,7 @@ func (s *Server) previewPolicyMatches(ctx context.Context, req types.PolicyPrevi
 
 	matchedStages := 0
 	handlers := make(map[string]struct{})
+	stagedPipelines := make(map[int]struct{})
 	for _, stage := range stageRows {
 		if _, ok := allowedPipelines[stage.PipelineID]; !ok {
 			continue
@@ -984,18 +1317,43 @@ func (s *Server) previewPolicyMatches(ctx context.Context, req types.PolicyPrevi
 		}
 
 		matchedStages++
+		stagedPipelines[stage.PipelineID] = struct{}{}
 		if handlerName != "" {
 			handlers[handlerName] = struct{}{}
 		}
 	}
 
+	// With stage or handler filters, a pipeline only counts when one of its
+	// stages matched.
+	matchedPipelines := len(allowedPipelines)
+	if len(stageFilter) > 0 || len(handlerFilter) > 0 {
+		matchedPipelines = len(stagedPipelines)
+		excluded.Stages = len(allowedPipelines) - matchedPipelines
+	}
+
 	return types.PolicyPreviewResponse{
-		Pipelines: len(allowedPipelines),
+		Pipelines: matchedPipelines,
 		Stages:    matchedStages,
 		Handlers:  len(handlers),
+		Total:     len(pipelineRows),
+		Excluded:  excluded,
 	}, nil
 }
 
+// matchesPreviewEnvironment reports whether any of the inferred environments
+// envs matches want under mode.
+func matchesPreviewEnvironment(envs []string, want types.PolicyEnvironment, mode string) bool {
+	for _, env := range envs {
+		if env == string(want) {
+			return true
+		}
+		if mode == types.PolicyEnvironmentMatchContains && strings.Contains(env, string(want)) {
+			return true
+		}
+	}
+	return false
+}
+
 func matchesPolicyFilter(policy types.Policy, filter policyListFilter) bool {
 	if filter.Type != nil && policy.Type != *filter.Type {
 		return false
@@ -1066,6 +1424,10 @@ func sortPolicies(items []types.PolicyListItem, sortBy, sortDir string) {
 			cmp = compareTime(left.UpdatedAt, right.UpdatedAt)
 		}
 
+		// Policies come from a map, so ties need a stable order for paging.
+		if cmp == 0 {
+			return left.ID < right.ID
+		}
 		if desc {
 			return cmp > 0
 		}
@@ -1123,10 +1485,16 @@ func validateUpsertPolicyRequest(req upsertPolicyRequest, isCreate bool) error {
 	if !isValidPolicyEnvironment(req.Environment) {
 		return errors.New("environment is invalid")
 	}
+	if req.Priority != nil && (*req.Priority < minPolicyPriority || *req.Priority > maxPolicyPriority) {
+		return fmt.Errorf("priority must be between %d and %d", minPolicyPriority, maxPolicyPriority)
+	}
 
 	if err := validateRuleByType(req.Type, req.Rule); err != nil {
 		return err
 	}
+	if err := policyengine.ValidateSchedule(req.Targeting.Schedule); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -1159,6 +1527,9 @@ func validateRuleByType(policyType types.PolicyType, rule types.PolicyRule) erro
 		if rule.MaxDelayMs != nil && *rule.MaxDelayMs <= 0 {
 			return errors.New("max delay must be greater than zero")
 		}
+		if rule.JitterMode != nil && !isOneOf(*rule.JitterMode, types.RetryJitterFull, types.RetryJitterDecorrelated) {
+			return errors.New("jitterMode must be full or decorrelated")
+		}
 	case types.PolicyTypeTimeout:
 		if rule.TimeoutMs == nil || *rule.TimeoutMs <= 0 {
 			return errors.New("timeout must be greater than zero")
@@ -1166,6 +1537,14 @@ func validateRuleByType(policyType types.PolicyType, rule types.PolicyRule) erro
 		if rule.AppliesTo == nil || !isOneOf(*rule.AppliesTo, "step", "external_call") {
 			return errors.New("appliesTo must be step or external_call")
 		}
+		if rule.OnTimeout != nil {
+			if !isOneOf(*rule.OnTimeout, types.TimeoutActionFail, types.TimeoutActionRetry) {
+				return errors.New("onTimeout must be fail or retry")
+			}
+			if !isOneOf(*rule.AppliesTo, "step") {
+				return errors.New("onTimeout requires appliesTo step")
+			}
+		}
 	case types.PolicyTypeCircuitBreaker:
 		if rule.FailureThreshold == nil || *rule.FailureThreshold <= 0 {
 			return errors.New("failure threshold must be greater than zero")
@@ -1179,6 +1558,13 @@ func validateRuleByType(policyType types.PolicyType, rule types.PolicyRule) erro
 		if rule.HalfOpenMaxCalls == nil || *rule.HalfOpenMaxCalls <= 0 {
 			return errors.New("half-open max calls must be greater than zero")
 		}
+	case types.PolicyTypeConcurrencyLimit:
+		if rule.MaxConcurrent == nil || *rule.MaxConcurrent <= 0 {
+			return errors.New("max concurrent must be greater than zero")
+		}
+		if rule.KeyBy == nil || !isOneOf(*rule.KeyBy, policyengine.ConcurrencyKeyGlobal, policyengine.ConcurrencyKeyHandler, policyengine.ConcurrencyKeyTenant) {
+			return errors.New("keyBy must be one of: global, handler, tenant")
+		}
 	default:
 		return errors.New("unsupported policy type")
 	}
@@ -1195,6 +1581,7 @@ func normalizePolicy(policy types.Policy) types.Policy {
 	if policy.Environment == "" {
 		policy.Environment = types.PolicyEnvironmentAll
 	}
+	policy.Priority = min(max(policy.Priority, minPolicyPriority), maxPolicyPriority)
 	policy.Targeting = normalizeTargeting(policy.Targeting)
 	policy.Rule = normalizePolicyRule(policy.Rule)
 	if policy.Version <= 0 {
@@ -1232,6 +1619,7 @@ func normalizeTargeting(targeting types.PolicyTargeting) types.PolicyTargeting {
 	targeting.Handlers = dedupeNonEmpty(targeting.Handlers)
 	targeting.TagsInclude = dedupeNonEmpty(targeting.TagsInclude)
 	targeting.TagsExclude = dedupeNonEmpty(targeting.TagsExclude)
+	targeting.Schedule = policyengine.CloneSchedule(targeting.Schedule)
 	return targeting
 }
 
@@ -1246,11 +1634,14 @@ func normalizePolicyRule(rule types.PolicyRule) types.PolicyRule {
 		BaseDelayMs:      cloneIntPtr(rule.BaseDelayMs),
 		MaxDelayMs:       cloneIntPtr(rule.MaxDelayMs),
 		Jitter:           cloneBoolPtr(rule.Jitter),
+		JitterMode:       cloneStringPtr(rule.JitterMode),
 		TimeoutMs:        cloneIntPtr(rule.TimeoutMs),
 		AppliesTo:        cloneStringPtr(rule.AppliesTo),
+		OnTimeout:        cloneStringPtr(rule.OnTimeout),
 		FailureThreshold: cloneIntPtr(rule.FailureThreshold),
 		OpenSeconds:      cloneIntPtr(rule.OpenSeconds),
 		HalfOpenMaxCalls: cloneIntPtr(rule.HalfOpenMaxCalls),
+		MaxConcurrent:    cloneIntPtr(rule.MaxConcurrent),
 	}
 
 	if rule.RetryOn != nil {
@@ -1268,10 +1659,18 @@ func normalizePolicyRule(rule types.PolicyRule) types.PolicyRule {
 		v := strings.ToLower(strings.TrimSpace(*normalized.Backoff))
 		normalized.Backoff = &v
 	}
+	if normalized.JitterMode != nil {
+		v := strings.ToLower(strings.TrimSpace(*normalized.JitterMode))
+		normalized.JitterMode = &v
+	}
 	if normalized.AppliesTo != nil {
 		v := strings.ToLower(strings.TrimSpace(*normalized.AppliesTo))
 		normalized.AppliesTo = &v
 	}
+	if normalized.OnTimeout != nil {
+		v := strings.ToLower(strings.TrimSpace(*normalized.OnTimeout))
+		normalized.OnTimeout = &v
+	}
 
 	return normalized
 }
@@ -1285,6 +1684,7 @@ func clonePolicy(policy types.Policy) types.Policy {
 		Handlers:    append([]string(nil), policy.Targeting.Handlers...),
 		TagsInclude: append([]string(nil), policy.Targeting.TagsInclude...),
 		TagsExclude: append([]string(nil), policy.Targeting.TagsExclude...),
+		Schedule:    policyengine.CloneSchedule(policy.Targeting.Schedule),
 	}
 	cloned.Rule = normalizePolicyRule(policy.Rule)
 	return cloned
@@ -1333,7 +1733,8 @@ func cloneStringPtr(v *string) *string {
 
 func isValidPolicyType(policyType types.PolicyType) bool {
 	switch policyType {
-	case types.PolicyTypeRateLimit, types.PolicyTypeRetry, types.PolicyTypeTimeout, types.PolicyTypeCircuitBreaker:
+	case types.PolicyTypeRateLimit, types.PolicyTypeRetry, types.PolicyTypeTimeout, types.PolicyTypeCircuitBreaker,
+		types.PolicyTypeConcurrencyLimit:
 		return true
 	default:
 		return false
@@ -1511,12 +1912,49 @@ func stringValue(value *string) string {
 	return strings.TrimSpace(*value)
 }
 
+// consumePolicyTriggers records policy triggers reported by the worker.
+// Triggers for policies deleted in the meantime are dropped.
+func (s *Server) consumePolicyTriggers(ctx context.Context) error {
+	opts := mq.ConsumeOptions{
+		QueueOptions: mq.QueueOptions{
+			Durable:     true,
+			DLQEnabled:  s.cfg.QueueDLQEnabled,
+			DLQTTL:      s.cfg.QueueDLQMessageTTL,
+			Prefetch:    s.cfg.QueuePrefetch,
+			ContentType: "application/json",
+		},
+		HandlerTimeout:   5 * time.Second,
+		DeadLetterOnFail: true,
+	}
+
+	handler := func(_ context.Context, d amqp.Delivery) error {
+		var msg types.PolicyTriggerMessage
+		if err := json.Unmarshal(d.Body, &msg); err != nil {
+			return err
+		}
+		if _, err := s.policies.recordTrigger(msg.PolicyID, "worker", msg.Details); err != nil {
+			if errors.Is(err, errPolicyNotFound) {
+				s.logger.Warn("dropping trigger for unknown policy", "policyId", msg.PolicyID)
+				return nil
+			}
+			return err
+		}
+		return nil
+	}
+
+	s.logger.Info("starting policy trigger consumer", "queue", constants.PolicyTrigger)
+	return s.mq.Consume(ctx, constants.PolicyTrigger, opts, handler)
+}
+
 func (s *Server) registerPolicyRoutes(r chi.Router) {
 	r.Get("/", s.handleGetPolicies)
 	r.Post("/", s.handleCreatePolicy)
 	r.Get("/insights", s.handleGetPolicyInsights)
 	r.Get("/targets", s.handleGetPolicyTargetOptions)
 	r.Post("/preview", s.handlePreviewPolicyTargets)
+	r.Get("/effective", s.handleGetEffectivePolicies)
+	r.Get("/export", s.handleExportPolicies)
+	r.Post("/import", s.handleImportPolicies)
 
 	r.Get("/{id}", s.handleGetPolicy)
 	r.Put("/{id}", s.handleUpdatePolicy)
@@ -1527,4 +1965,5 @@ func (s *Server) registerPolicyRoutes(r chi.Router) {
 	r.Post("/{id}/disable", s.handleDisablePolicy)
 	r.Post("/{id}/pause", s.handlePausePolicy)
 	r.Post("/{id}/resume", s.handleResumePolicy)
+	r.Post("/{id}/simulate", s.handleSimulatePolicy)
 }
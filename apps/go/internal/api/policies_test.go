@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"pipelogiq/internal/types"
+)
+
+func TestMatchesPreviewEnvironment(t *testing.T) {
+	tests := []struct {
+		envs []string
+		mode string
+		want bool
+	}{
+		{[]string{"prod"}, types.PolicyEnvironmentMatchExact, true},
+		{[]string{"production"}, types.PolicyEnvironmentMatchExact, false},
+		{[]string{"production"}, types.PolicyEnvironmentMatchContains, true},
+		{[]string{"staging", "prod"}, types.PolicyEnvironmentMatchExact, true},
+		{[]string{"dev"}, types.PolicyEnvironmentMatchContains, false},
+		{nil, types.PolicyEnvironmentMatchContains, false},
+	}
+	for _, tt := range tests {
+		if got := matchesPreviewEnvironment(tt.envs, types.PolicyEnvironmentProd, tt.mode); got != tt.want {
+			t.Errorf("matchesPreviewEnvironment(%v, prod, %s) = %v, want %v", tt.envs, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestPreviewPolicyTargetsRejectsUnknownMatchMode(t *testing.T) {
+	s := &Server{}
+	rec := httptest.NewRecorder()
+	s.handlePreviewPolicyTargets(rec, httptest.NewRequest(http.MethodPost, "/policies/preview",
+		strings.NewReader(`{"environment":"prod","environmentMatch":"fuzzy"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("POST /policies/preview = %d, want 400: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestPreviewPolicyMatches(t *testing.T) {
+	s, db := newPostgresTestServer(t)
+	ctx := context.Background()
+
+	contextEnv := insertTestPipeline(t, db, 10, types.PipelineStatusCompleted, true)
+	keywordEnv := insertTestPipeline(t, db, 10, types.PipelineStatusCompleted, true)
+	workerEnv := insertTestPipeline(t, db, 10, types.PipelineStatusCompleted, true)
+	insertTestPipeline(t, db, 20, types.PipelineStatusCompleted, true) // no environment at all
+	overridden := insertTestPipeline(t, db, 10, types.PipelineStatusCompleted, true)
+	insertTestStage(t, db, workerEnv, "resize", types.StageStatusCompleted)
+	if _, err := db.Exec(`
+		INSERT INTO pipeline_context_item (pipeline_id, key, value) VALUES ($1, 'environment', 'Production'), ($2, 'env', 'dev');
+		INSERT INTO keyword (id, key, value) VALUES (1, 'env', 'prod');
+		INSERT INTO pipeline_keyword (pipeline_id, keyword_id) VALUES ($3, 1);
+		INSERT INTO worker_client (id, application_id, environment) VALUES ('w1', 10, 'prod');
+	`, contextEnv, overridden, keywordEnv); err != nil {
+		t.Fatalf("seed environments: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		mode      string
+		targeting types.PolicyTargeting
+		want      int
+		excluded  types.PolicyPreviewExclusions
+	}{
+		// The context item wins over the keyword, which wins over workers.
+		{"exact", types.PolicyEnvironmentMatchExact, types.PolicyTargeting{}, 2,
+			types.PolicyPreviewExclusions{Environment: 3}},
+		{"contains", types.PolicyEnvironmentMatchContains, types.PolicyTargeting{}, 3,
+			types.PolicyPreviewExclusions{Environment: 2}},
+		{"pipeline filter", types.PolicyEnvironmentMatchExact, types.PolicyTargeting{Pipelines: []string{strconv.Itoa(keywordEnv)}}, 1,
+			types.PolicyPreviewExclusions{Pipelines: 4}},
+		{"excluded tag", types.PolicyEnvironmentMatchExact, types.PolicyTargeting{TagsExclude: []string{"prod"}}, 1,
+			types.PolicyPreviewExclusions{Environment: 3, Tags: 1}},
+		{"handler filter", types.PolicyEnvironmentMatchExact, types.PolicyTargeting{Handlers: []string{"resize"}}, 1,
+			types.PolicyPreviewExclusions{Environment: 3, Stages: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preview, err := s.previewPolicyMatches(ctx, types.PolicyPreviewRequest{
+				Environment:      types.PolicyEnvironmentProd,
+				Targeting:        tt.targeting,
+				EnvironmentMatch: tt.mode,
+			})
+			if err != nil {
+				t.Fatalf("previewPolicyMatches() error = %v", err)
+			}
+			if preview.Pipelines != tt.want || preview.Excluded != tt.excluded || preview.Total != 5 {
+				t.Fatalf("preview = %+v, want %d pipelines of 5, excluded %+v", preview, tt.want, tt.excluded)
+			}
+		})
+	}
+}
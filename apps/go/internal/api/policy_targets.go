@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"pipelogiq/internal/types"
+)
+
+// policyTargetCache keeps policy target options for a short TTL per search
+// and limit, so the policy editor does not rerun the distinct scans on every
+// keystroke.
+type policyTargetCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]policyTargetCacheEntry
+}
+
+type policyTargetCacheEntry struct {
+	options types.PolicyTargetOptionsResponse
+	expires time.Time
+}
+
+func newPolicyTargetCache(ttl time.Duration) *policyTargetCache {
+	return &policyTargetCache{ttl: ttl, entries: make(map[string]policyTargetCacheEntry)}
+}
+
+// get returns the cached options for search and limit, calling load on a
+// miss. Concurrent misses may each load; the last one wins.
+func (c *policyTargetCache) get(ctx context.Context, search string, limit int, load func(context.Context) (types.PolicyTargetOptionsResponse, error)) (types.PolicyTargetOptionsResponse, error) {
+	key := strconv.Itoa(limit) + "|" + search
+	now := time.Now()
+
+	if c.ttl > 0 {
+		c.mu.Lock()
+		entry, ok := c.entries[key]
+		c.mu.Unlock()
+		if ok && now.Before(entry.expires) {
+			return entry.options, nil
+		}
+	}
+
+	options, err := load(ctx)
+	if err != nil || c.ttl <= 0 {
+		return options, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = policyTargetCacheEntry{options: options, expires: now.Add(c.ttl)}
+	return options, nil
+}
+
+// loadPolicyTargetOptions reads up to limit values of each target kind,
+// keeping those that contain search (ignoring case) when it is set.
+func (s *Server) loadPolicyTargetOptions(ctx context.Context, search string, limit int) (types.PolicyTargetOptionsResponse, error) {
+	pattern := "%" + search + "%"
+	db := s.store.DB()
+
+	pipelineRows := []struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}{}
+	if err := db.SelectContext(ctx, &pipelineRows, `
+		SELECT id, COALESCE(name, '') AS name
+		FROM pipeline
+		WHERE $1 = '' OR COALESCE(name, '') ILIKE $2 OR CAST(id AS text) = $1
+		ORDER BY created_at DESC
+		LIMIT $3
+	`, search, pattern, limit); err != nil {
+		return types.PolicyTargetOptionsResponse{}, fmt.Errorf("list pipelines: %w", err)
+	}
+	pipelines := make([]types.PolicyTargetOption, 0, len(pipelineRows))
+	for _, row := range pipelineRows {
+		pipelines = append(pipelines, types.PolicyTargetOption{
+			ID:   strconv.Itoa(row.ID),
+			Name: row.Name,
+		})
+	}
+
+	lists := []struct {
+		name  string
+		query string
+		dest  *[]string
+	}{
+		{"stages", `
+			SELECT DISTINCT name FROM stage
+			WHERE COALESCE(name, '') <> '' AND ($1 = '' OR name ILIKE $2)
+			ORDER BY name
+			LIMIT $3`, new([]string)},
+		{"handlers", `
+			SELECT DISTINCT stage_handler_name FROM stage
+			WHERE COALESCE(stage_handler_name, '') <> '' AND ($1 = '' OR stage_handler_name ILIKE $2)
+			ORDER BY stage_handler_name
+			LIMIT $3`, new([]string)},
+		{"tags", `
+			SELECT DISTINCT value FROM keyword
+			WHERE COALESCE(value, '') <> '' AND ($1 = '' OR value ILIKE $2)
+			ORDER BY value
+			LIMIT $3`, new([]string)},
+	}
+	for _, list := range lists {
+		*list.dest = []string{}
+		if err := db.SelectContext(ctx, list.dest, list.query, search, pattern, limit); err != nil {
+			return types.PolicyTargetOptionsResponse{}, fmt.Errorf("list %s: %w", list.name, err)
+		}
+	}
+
+	return types.PolicyTargetOptionsResponse{
+		Environments: []types.PolicyEnvironment{
+			types.PolicyEnvironmentAll,
+			types.PolicyEnvironmentProd,
+			types.PolicyEnvironmentStaging,
+			types.PolicyEnvironmentDev,
+		},
+		Pipelines:   pipelines,
+		Stages:      dedupeNonEmpty(*lists[0].dest),
+		Handlers:    dedupeNonEmpty(*lists[1].dest),
+		Tags:        dedupeNonEmpty(*lists[2].dest),
+		GeneratedAt: time.Now().UTC(),
+	}, nil
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"pipelogiq/internal/types"
+)
+
+func TestPolicyTargetCache(t *testing.T) {
+	ctx := context.Background()
+	loads := 0
+	load := func(context.Context) (types.PolicyTargetOptionsResponse, error) {
+		loads++
+		return types.PolicyTargetOptionsResponse{Tags: []string{"t"}}, nil
+	}
+
+	cache := newPolicyTargetCache(time.Minute)
+	for _, call := range []struct {
+		search    string
+		limit     int
+		wantLoads int
+	}{
+		{"", 10, 1},
+		{"", 10, 1},
+		{"ord", 10, 2},
+		{"", 5, 3},
+		{"ord", 10, 3},
+	} {
+		if _, err := cache.get(ctx, call.search, call.limit, load); err != nil {
+			t.Fatalf("get(%q, %d) error = %v", call.search, call.limit, err)
+		}
+		if loads != call.wantLoads {
+			t.Fatalf("get(%q, %d): %d loads, want %d", call.search, call.limit, loads, call.wantLoads)
+		}
+	}
+
+	failing := func(context.Context) (types.PolicyTargetOptionsResponse, error) {
+		loads++
+		return types.PolicyTargetOptionsResponse{}, errors.New("db down")
+	}
+	if _, err := cache.get(ctx, "new", 10, failing); err == nil {
+		t.Fatal("get() hid the load error")
+	}
+	if _, err := cache.get(ctx, "new", 10, load); err != nil || loads != 5 {
+		t.Fatalf("get() after a failed load = %v with %d loads, want a fresh load", err, loads)
+	}
+
+	uncached := newPolicyTargetCache(0)
+	loads = 0
+	for range 2 {
+		_, _ = uncached.get(ctx, "", 10, load)
+	}
+	if loads != 2 {
+		t.Fatalf("zero TTL: %d loads, want 2", loads)
+	}
+}
+
+func TestGetPolicyTargetOptions(t *testing.T) {
+	s, db := newPostgresTestServer(t)
+	s.cfg.PolicyTargetOptionsLimit = 2
+	s.targetOptions = newPolicyTargetCache(0)
+
+	orders := insertTestPipeline(t, db, 10, types.PipelineStatusCompleted, true)
+	billing := insertTestPipeline(t, db, 10, types.PipelineStatusCompleted, true)
+	for id, name := range map[int]string{orders: "orders-nightly", billing: "billing"} {
+		if _, err := db.Exec(`UPDATE pipeline SET name = $2 WHERE id = $1`, id, name); err != nil {
+			t.Fatalf("name pipeline: %v", err)
+		}
+	}
+	insertTestStage(t, db, orders, "Charge Card", types.StageStatusCompleted)
+	insertTestStage(t, db, orders, "ship", types.StageStatusCompleted)
+	insertTestStage(t, db, billing, "invoice", types.StageStatusCompleted)
+	if _, err := db.Exec(`INSERT INTO keyword (key, value) VALUES ('team', 'team-a'), ('team', 'team-b')`); err != nil {
+		t.Fatalf("insert keywords: %v", err)
+	}
+
+	get := func(query string) (int, types.PolicyTargetOptionsResponse) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		s.handleGetPolicyTargetOptions(rec, httptest.NewRequest(http.MethodGet, "/policies/target-options"+query, nil))
+		var options types.PolicyTargetOptionsResponse
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &options); err != nil {
+				t.Fatalf("decode options: %v", err)
+			}
+		}
+		return rec.Code, options
+	}
+
+	code, options := get("?search=ORDER")
+	if code != http.StatusOK || len(options.Pipelines) != 1 || options.Pipelines[0].Name != "orders-nightly" {
+		t.Fatalf("search=ORDER = %d %+v, want only orders-nightly", code, options.Pipelines)
+	}
+	if _, options = get("?search=card"); !slices.Equal(options.Stages, []string{"Charge Card"}) || len(options.Tags) != 0 {
+		t.Fatalf("search=card = stages %v tags %v, want only Charge Card", options.Stages, options.Tags)
+	}
+	if _, options = get("?limit=1"); len(options.Pipelines) != 1 || len(options.Stages) != 1 || len(options.Tags) != 1 {
+		t.Fatalf("limit=1 = %+v, want one value per list", options)
+	}
+	// A larger limit is capped at POLICY_TARGET_OPTIONS_LIMIT.
+	if _, options = get("?limit=50"); len(options.Stages) != 2 || options.GeneratedAt.IsZero() {
+		t.Fatalf("limit=50 = %+v, want two stages and generatedAt", options)
+	}
+	for _, query := range []string{"?limit=0", "?limit=x"} {
+		if code, _ := get(query); code != http.StatusBadRequest {
+			t.Fatalf("%s = %d, want 400", query, code)
+		}
+	}
+}
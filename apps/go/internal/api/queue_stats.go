@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"pipelogiq/internal/constants"
+	"pipelogiq/internal/mq"
+	"pipelogiq/internal/store"
+)
+
+// queueStatsCacheTTL is how long GET /observability/queues serves the last
+// snapshot before inspecting the broker again.
+const queueStatsCacheTTL = 5 * time.Second
+
+type queueStats struct {
+	Queue   string `json:"queue"`
+	Handler string `json:"handler,omitempty"`
+	// Environment is set for the per-environment StageNext queues.
+	Environment string `json:"environment,omitempty"`
+	Messages    int    `json:"messages"`
+	Consumers   int    `json:"consumers"`
+	// DLQ and DLQMessages are set when dead-lettering is enabled.
+	DLQ         string `json:"dlq,omitempty"`
+	DLQMessages int    `json:"dlqMessages"`
+}
+
+type queueStatsResponse struct {
+	Queues      []queueStats `json:"queues"`
+	CollectedAt time.Time    `json:"collectedAt"`
+}
+
+// queueInspector reads broker-side queue stats; *mq.Client implements it.
+type queueInspector interface {
+	InspectQueue(ctx context.Context, queue string) (mq.QueueStat, error)
+}
+
+// queueStatsCache holds the last queue stats snapshot for queueStatsCacheTTL
+// so that dashboards polling the endpoint do not each hit the broker.
+type queueStatsCache struct {
+	mu       sync.Mutex
+	snapshot *queueStatsResponse
+}
+
+func (s *Server) handleGetQueueStats(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	// Holding the lock while loading makes concurrent misses share one load.
+	s.queueStats.mu.Lock()
+	defer s.queueStats.mu.Unlock()
+	if snapshot := s.queueStats.snapshot; snapshot != nil && time.Since(snapshot.CollectedAt) < queueStatsCacheTTL {
+		writeJSON(w, snapshot, http.StatusOK)
+		return
+	}
+
+	snapshot, err := s.loadQueueStats(ctx)
+	if err != nil {
+		s.logger.Error("read queue stats failed", "err", err)
+		writeError(w, http.StatusServiceUnavailable, errCodeUnavailable, "failed to read queue stats from the broker")
+		return
+	}
+	s.queueStats.snapshot = snapshot
+	writeJSON(w, snapshot, http.StatusOK)
+}
+
+// loadQueueStats inspects the StageNext queue of every known handler, with
+// STAGE_ENVIRONMENT_ROUTING also those of the environments of unfinished
+// pipelines, and the StageResult and StageSetStatus queues, plus their DLQs
+// when dead-lettering is enabled. Queues not declared yet report zeros.
+func (s *Server) loadQueueStats(ctx context.Context) (*queueStatsResponse, error) {
+	handlers, err := s.store.ListStageHandlerNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var envQueues []store.StageQueueEnvironment
+	if s.cfg.StageEnvironmentRouting {
+		if envQueues, err = s.store.ListStageQueueEnvironments(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	queues := make([]queueStats, 0, len(handlers)+len(envQueues)+s.cfg.ResultQueueShards+2)
+	for _, handler := range handlers {
+		queues = append(queues, queueStats{Queue: extStageQueueName(s.cfg.AppID, handler, ""), Handler: handler})
+	}
+	for _, q := range envQueues {
+		queues = append(queues, queueStats{
+			Queue: extStageQueueName(s.cfg.AppID, q.Handler, q.Environment), Handler: q.Handler, Environment: q.Environment,
+		})
+	}
+	queues = append(queues, queueStats{Queue: constants.StageResult}, queueStats{Queue: constants.StageSetStatus})
+	if s.cfg.ResultQueueShards > 1 {
+		for _, shard := range mq.ShardQueueNames(constants.StageResult, s.cfg.ResultQueueShards) {
+			queues = append(queues, queueStats{Queue: shard})
+		}
+	}
+
+	for i := range queues {
+		stat, err := s.inspectQueue(ctx, queues[i].Queue)
+		if err != nil {
+			return nil, err
+		}
+		queues[i].Messages, queues[i].Consumers = stat.Messages, stat.Consumers
+
+		if !s.cfg.QueueDLQEnabled {
+			continue
+		}
+		queues[i].DLQ = mq.DLQName(queues[i].Queue)
+		stat, err = s.inspectQueue(ctx, queues[i].DLQ)
+		if err != nil {
+			return nil, err
+		}
+		queues[i].DLQMessages = stat.Messages
+	}
+	return &queueStatsResponse{Queues: queues, CollectedAt: time.Now().UTC()}, nil
+}
+
+// inspectQueue reads the stats of queue, treating an undeclared queue as
+// empty.
+func (s *Server) inspectQueue(ctx context.Context, queue string) (mq.QueueStat, error) {
+	stat, err := s.inspector.InspectQueue(ctx, queue)
+	if errors.Is(err, mq.ErrQueueNotFound) {
+		return mq.QueueStat{}, nil
+	}
+	return stat, err
+}
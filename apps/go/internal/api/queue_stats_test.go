@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"pipelogiq/internal/constants"
+	"pipelogiq/internal/mq"
+	"pipelogiq/internal/types"
+)
+
+// fakeInspector reports stats for the queues it knows and ErrQueueNotFound
+// for the rest.
+type fakeInspector struct {
+	stats map[string]mq.QueueStat
+	err   error
+	calls int
+}
+
+func (f *fakeInspector) InspectQueue(_ context.Context, queue string) (mq.QueueStat, error) {
+	f.calls++
+	if f.err != nil {
+		return mq.QueueStat{}, f.err
+	}
+	stat, ok := f.stats[queue]
+	if !ok {
+		return mq.QueueStat{}, fmt.Errorf("%w: %s", mq.ErrQueueNotFound, queue)
+	}
+	return stat, nil
+}
+
+func TestGetQueueStatsServesFreshSnapshot(t *testing.T) {
+	inspector := &fakeInspector{}
+	s := &Server{
+		queueStats: &queueStatsCache{snapshot: &queueStatsResponse{
+			Queues:      []queueStats{{Queue: "cached", Messages: 3}},
+			CollectedAt: time.Now().UTC(),
+		}},
+		inspector: inspector,
+	}
+	rec := httptest.NewRecorder()
+	s.handleGetQueueStats(rec, httptest.NewRequest(http.MethodGet, "/observability/queues", nil))
+
+	var got queueStatsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET /observability/queues = %d %s, %v", rec.Code, rec.Body.String(), err)
+	}
+	if len(got.Queues) != 1 || got.Queues[0].Queue != "cached" || inspector.calls != 0 {
+		t.Fatalf("response = %+v after %d broker calls, want the cached snapshot", got, inspector.calls)
+	}
+}
+
+func TestLoadQueueStats(t *testing.T) {
+	s, db := newPostgresTestServer(t)
+	s.cfg.AppID = "app"
+	s.cfg.QueueDLQEnabled = true
+	s.cfg.ResultQueueShards = 2
+	pipelineID := insertTestPipeline(t, db, 10, types.PipelineStatusRunning, false)
+	insertTestStage(t, db, pipelineID, "first", types.StageStatusRunning)
+
+	stageQueue := extStageQueueName("app", "resize", "")
+	inspector := &fakeInspector{stats: map[string]mq.QueueStat{
+		stageQueue:                   {Messages: 7, Consumers: 2},
+		mq.DLQName(stageQueue):       {Messages: 1},
+		constants.StageResult:        {Messages: 4, Consumers: 1},
+		constants.StageResult + ".1": {Messages: 5, Consumers: 1},
+	}}
+	s.inspector = inspector
+
+	snapshot, err := s.loadQueueStats(context.Background())
+	if err != nil {
+		t.Fatalf("loadQueueStats() error = %v", err)
+	}
+	want := []queueStats{
+		{Queue: stageQueue, Handler: "resize", Messages: 7, Consumers: 2, DLQ: mq.DLQName(stageQueue), DLQMessages: 1},
+		{Queue: constants.StageResult, Messages: 4, Consumers: 1, DLQ: mq.DLQName(constants.StageResult)},
+		{Queue: constants.StageSetStatus, DLQ: mq.DLQName(constants.StageSetStatus)},
+		{Queue: constants.StageResult + ".0", DLQ: mq.DLQName(constants.StageResult + ".0")},
+		{Queue: constants.StageResult + ".1", Messages: 5, Consumers: 1, DLQ: mq.DLQName(constants.StageResult + ".1")},
+	}
+	if len(snapshot.Queues) != len(want) {
+		t.Fatalf("queues = %+v, want %+v", snapshot.Queues, want)
+	}
+	for i := range want {
+		if snapshot.Queues[i] != want[i] {
+			t.Fatalf("queue %d = %+v, want %+v", i, snapshot.Queues[i], want[i])
+		}
+	}
+
+	inspector.err = errors.New("broker down")
+	if _, err := s.loadQueueStats(context.Background()); err == nil {
+		t.Fatal("loadQueueStats() hid the broker error")
+	}
+	s.queueStats = &queueStatsCache{snapshot: &queueStatsResponse{CollectedAt: time.Now().Add(-time.Minute)}}
+	rec := httptest.NewRecorder()
+	s.handleGetQueueStats(rec, httptest.NewRequest(http.MethodGet, "/observability/queues", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("GET /observability/queues with a stale snapshot and no broker = %d, want 503", rec.Code)
+	}
+}
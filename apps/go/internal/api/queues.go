@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"pipelogiq/internal/mq"
+	"pipelogiq/internal/types"
+)
+
+const (
+	defaultDLQPeekLimit = 20
+	maxDLQBatch         = 500
+)
+
+type dlqPeekResponse struct {
+	Queue    string          `json:"queue"`
+	DLQ      string          `json:"dlq"`
+	Messages []mq.DLQMessage `json:"messages"`
+}
+
+type dlqRequeueRequest struct {
+	Count int `json:"count"`
+}
+
+type dlqRequeueResponse struct {
+	Queue    string `json:"queue"`
+	Requeued int    `json:"requeued"`
+}
+
+// handlePeekDLQ returns dead-lettered messages of a queue without removing
+// them. Queues are shared by every application, so only admins may peek.
+func (s *Server) handlePeekDLQ(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	queue := strings.TrimSpace(chi.URLParam(r, "queue"))
+	if queue == "" {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "queue is required")
+		return
+	}
+
+	limit := defaultDLQPeekLimit
+	if value := strings.TrimSpace(r.URL.Query().Get("limit")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid limit")
+			return
+		}
+		limit = min(parsed, maxDLQBatch)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	messages, err := s.mq.PeekDLQ(ctx, queue, limit)
+	if err != nil {
+		if errors.Is(err, mq.ErrQueueNotFound) {
+			writeError(w, http.StatusNotFound, errCodeQueueNotFound, err.Error())
+			return
+		}
+		s.logger.Error("peek dlq failed", "queue", queue, "err", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to read dead-letter queue")
+		return
+	}
+
+	writeJSON(w, dlqPeekResponse{
+		Queue:    queue,
+		DLQ:      mq.DLQName(queue),
+		Messages: messages,
+	}, http.StatusOK)
+}
+
+// handleRequeueDLQ moves dead-lettered messages back onto their queue. Like
+// handlePeekDLQ it is restricted to admins.
+func (s *Server) handleRequeueDLQ(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	queue := strings.TrimSpace(chi.URLParam(r, "queue"))
+	if queue == "" {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "queue is required")
+		return
+	}
+
+	var req dlqRequeueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errCodeInvalidPayload, "invalid payload")
+		return
+	}
+	if req.Count <= 0 || req.Count > maxDLQBatch {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "count must be between 1 and "+strconv.Itoa(maxDLQBatch))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	moved, err := s.mq.RequeueDLQ(ctx, queue, req.Count)
+	if err != nil {
+		if errors.Is(err, mq.ErrQueueNotFound) {
+			writeError(w, http.StatusNotFound, errCodeQueueNotFound, err.Error())
+			return
+		}
+		s.logger.Error("requeue dlq failed", "queue", queue, "moved", moved, "err", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to requeue dead-lettered messages")
+		return
+	}
+
+	writeJSON(w, dlqRequeueResponse{Queue: queue, Requeued: moved}, http.StatusOK)
+}
+
+// handleListDeadLetters returns the recorded history of dead-lettered
+// messages of the caller's applications, filtered by ?queue=, ?handler= and
+// ?stageId=.
+func (s *Server) handleListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	userID := getUserIDFromContext(r.Context())
+	if userID == 0 {
+		writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "unauthorized")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	limit := 0
+	if value := strings.TrimSpace(r.URL.Query().Get("limit")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			limit = parsed
+		}
+	}
+
+	items, err := s.store.ListDeadLetters(ctx, userID, types.DeadLetterListRequest{
+		Queue:   r.URL.Query().Get("queue"),
+		Handler: r.URL.Query().Get("handler"),
+		StageID: parseQueryIntPtr(r.URL.Query().Get("stageId")),
+		Limit:   limit,
+	})
+	if err != nil {
+		s.logger.Error("list dead letters failed", "err", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to list dead letters")
+		return
+	}
+
+	writeJSON(w, types.DeadLetterListResponse{Items: items}, http.StatusOK)
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"pipelogiq/internal/ratelimit"
+	"pipelogiq/internal/telemetry"
+)
+
+const (
+	// rateLimitCredentialTTL is how long a credential that validated keeps
+	// its own bucket before it is looked up again.
+	rateLimitCredentialTTL = time.Minute
+	// rateLimitInvalidCredentialTTL is how long a credential that failed to
+	// validate is sent to the address bucket without another lookup.
+	rateLimitInvalidCredentialTTL = 10 * time.Second
+	// maxRateLimitCredentials bounds the credentials remembered, valid and
+	// invalid each.
+	maxRateLimitCredentials = 10000
+	// maxCredentialLookups bounds the credential lookups an address may cause
+	// per credentialLookupWindow, so a stream of made-up keys cannot turn
+	// every request into a database query.
+	maxCredentialLookups   = 60
+	credentialLookupWindow = time.Minute
+)
+
+// rateLimitCredentials validates the credentials a request is keyed by,
+// without recording their use.
+type rateLimitCredentials interface {
+	APIKeyActive(ctx context.Context, key string) (bool, error)
+	WorkerSessionActive(ctx context.Context, token string) (bool, error)
+}
+
+// rateLimiter admits at most burst requests per key in any sliding window of
+// burst/rps seconds, so callers average rps with bursts of up to burst. Only
+// a credential that validates gets a bucket of its own; anything else shares
+// the bucket of its connection's address, so made-up keys buy no extra
+// requests.
+type rateLimiter struct {
+	store  ratelimit.LimiterStore
+	creds  rateLimitCredentials
+	limit  int
+	window time.Duration
+
+	mu      sync.Mutex
+	valid   map[string]time.Time // credential key -> when to validate again
+	invalid map[string]time.Time // credential key -> when to validate again
+}
+
+func newRateLimiter(store ratelimit.LimiterStore, creds rateLimitCredentials, rps, burst int) *rateLimiter {
+	if rps <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = rps
+	}
+	return &rateLimiter{
+		store:   store,
+		creds:   creds,
+		limit:   burst,
+		window:  time.Duration(float64(burst) / float64(rps) * float64(time.Second)),
+		valid:   make(map[string]time.Time),
+		invalid: make(map[string]time.Time),
+	}
+}
+
+// allow records a request for key. When the window is full it returns false
+// and how long until the next request is admitted.
+func (l *rateLimiter) allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	res, err := l.store.Allow(ctx, "ext:"+key, l.limit, l.window)
+	if err != nil {
+		return false, 0, err
+	}
+	return res.Allowed, res.RetryAfter, nil
+}
+
+// key identifies the caller by a valid API key, then a valid worker session
+// token, then the connection's address. peerAddr must be the address of the
+// connection, not one taken from forwarded headers. Credentials are hashed so
+// raw secrets are not retained.
+func (l *rateLimiter) key(ctx context.Context, apiKey, sessionToken, peerAddr string) string {
+	host, _, err := net.SplitHostPort(peerAddr)
+	if err != nil {
+		host = peerAddr
+	}
+	if apiKey != "" {
+		if key := "key:" + hashRateLimitKey(apiKey); l.credentialValid(ctx, host, key, apiKey, l.creds.APIKeyActive) {
+			return key
+		}
+	}
+	if sessionToken != "" {
+		if key := "session:" + hashRateLimitKey(sessionToken); l.credentialValid(ctx, host, key, sessionToken, l.creds.WorkerSessionActive) {
+			return key
+		}
+	}
+	return "ip:" + host
+}
+
+// credentialValid reports whether secret validates. A valid credential is
+// remembered for rateLimitCredentialTTL and an invalid one for
+// rateLimitInvalidCredentialTTL. Lookups are limited per address host, and
+// a credential that cannot be looked up, or whose lookup failed, counts as
+// invalid, so the caller falls back to its address bucket.
+func (l *rateLimiter) credentialValid(ctx context.Context, host, key, secret string, check func(context.Context, string) (bool, error)) bool {
+	now := time.Now()
+	l.mu.Lock()
+	validUntil, valid := l.valid[key]
+	invalidUntil, invalid := l.invalid[key]
+	l.mu.Unlock()
+	if valid && now.Before(validUntil) {
+		return true
+	}
+	if invalid && now.Before(invalidUntil) {
+		return false
+	}
+
+	res, err := l.store.Allow(ctx, "ext-lookup:"+host, maxCredentialLookups, credentialLookupWindow)
+	if err == nil && !res.Allowed {
+		return false
+	}
+
+	ok, err := check(ctx, secret)
+	if err != nil {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !ok {
+		rememberCredential(l.invalid, key, now, now.Add(rateLimitInvalidCredentialTTL))
+		return false
+	}
+	delete(l.invalid, key)
+	rememberCredential(l.valid, key, now, now.Add(rateLimitCredentialTTL))
+	return true
+}
+
+// rememberCredential stores key in m until until, first dropping expired
+// entries, or all of them, when m is full.
+func rememberCredential(m map[string]time.Time, key string, now, until time.Time) {
+	if len(m) >= maxRateLimitCredentials {
+		for k, expires := range m {
+			if !now.Before(expires) {
+				delete(m, k)
+			}
+		}
+		if len(m) >= maxRateLimitCredentials {
+			clear(m)
+		}
+	}
+	m[key] = until
+}
+
+func hashRateLimitKey(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:8])
+}
+
+// rateLimitMiddleware rejects callers whose sliding window is full with 429
+// and a Retry-After header. It must run inside a chi group so the matched
+// route pattern is available for the throttled-requests metric.
+func (s *ExternalServer) rateLimitMiddleware(next http.Handler) http.Handler {
+	if s.limiter == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := s.limiter.key(r.Context(), extractAPIKey(r), extractWorkerSessionToken(r), telemetry.PeerAddr(r))
+		ok, wait, err := s.limiter.allow(r.Context(), key)
+		if err != nil {
+			// Fail open: an unreachable limiter store must not take the API down.
+			s.logger.Warn("rate limit check failed", "err", err)
+			ok = true
+		}
+		if ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		route := r.URL.Path
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			if pattern := rctx.RoutePattern(); pattern != "" {
+				route = pattern
+			}
+		}
+		s.metrics.requestsThrottled.WithLabelValues(route).Inc()
+
+		retryAfter := int(math.Ceil(wait.Seconds()))
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		writeError(w, http.StatusTooManyRequests, errCodeRateLimited, "rate limit exceeded")
+	})
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"pipelogiq/internal/ratelimit"
+	"pipelogiq/internal/telemetry"
+)
+
+// fakeRateLimitCredentials accepts the listed API keys and session tokens
+// and counts lookups.
+type fakeRateLimitCredentials struct {
+	apiKeys  map[string]bool
+	sessions map[string]bool
+	lookups  int
+	err      error
+}
+
+func (f *fakeRateLimitCredentials) APIKeyActive(_ context.Context, key string) (bool, error) {
+	f.lookups++
+	return f.apiKeys[key], f.err
+}
+
+func (f *fakeRateLimitCredentials) WorkerSessionActive(_ context.Context, token string) (bool, error) {
+	f.lookups++
+	return f.sessions[token], f.err
+}
+
+func TestRateLimiterKey(t *testing.T) {
+	creds := &fakeRateLimitCredentials{apiKeys: map[string]bool{"good-key": true}, sessions: map[string]bool{"good-session": true}}
+	l := newRateLimiter(ratelimit.NewMemoryStore(), creds, 1, 1)
+	ctx := context.Background()
+
+	tests := []struct {
+		name, apiKey, session, want string
+	}{
+		{"valid api key", "good-key", "", "key:" + hashRateLimitKey("good-key")},
+		{"valid session", "", "good-session", "session:" + hashRateLimitKey("good-session")},
+		{"made-up api key", "random", "", "ip:203.0.113.7"},
+		{"made-up session", "", "random", "ip:203.0.113.7"},
+		{"bearer session tried as api key first", "good-session", "good-session", "session:" + hashRateLimitKey("good-session")},
+		{"no credentials", "", "", "ip:203.0.113.7"},
+	}
+	for _, tt := range tests {
+		if got := l.key(ctx, tt.apiKey, tt.session, "203.0.113.7:5123"); got != tt.want {
+			t.Errorf("%s: key() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// A valid credential is remembered and not looked up on every request.
+	creds.lookups = 0
+	l.key(ctx, "good-key", "", "203.0.113.7:5123")
+	if creds.lookups != 0 {
+		t.Fatalf("valid api key looked up %d times, want it cached", creds.lookups)
+	}
+
+	// A failing lookup falls back to the address.
+	creds.err = errors.New("db down")
+	if got := l.key(ctx, "other-key", "", "203.0.113.7:5123"); got != "ip:203.0.113.7" {
+		t.Fatalf("key() with failing lookup = %q, want the address bucket", got)
+	}
+}
+
+func TestRateLimiterLimitsInvalidCredentialLookups(t *testing.T) {
+	creds := &fakeRateLimitCredentials{apiKeys: map[string]bool{"good-key": true}}
+	l := newRateLimiter(ratelimit.NewMemoryStore(), creds, 1, 1)
+	ctx := context.Background()
+
+	// An invalid key is remembered and not looked up again right away.
+	for range 3 {
+		l.key(ctx, "bad-key", "", "203.0.113.7:5123")
+	}
+	if creds.lookups != 1 {
+		t.Fatalf("invalid api key looked up %d times, want 1", creds.lookups)
+	}
+
+	// Made-up keys from one address stop causing lookups once its budget is
+	// spent; other addresses keep theirs.
+	creds.lookups = 0
+	for i := range maxCredentialLookups + 10 {
+		if got := l.key(ctx, "random-"+strconv.Itoa(i), "", "203.0.113.7:5123"); got != "ip:203.0.113.7" {
+			t.Fatalf("key(made-up key) = %q, want the address bucket", got)
+		}
+	}
+	if creds.lookups != maxCredentialLookups-1 {
+		t.Fatalf("made-up keys caused %d lookups, want %d", creds.lookups, maxCredentialLookups-1)
+	}
+	if got := l.key(ctx, "good-key", "", "198.51.100.9:4000"); got != "key:"+hashRateLimitKey("good-key") {
+		t.Fatalf("key(valid key, other address) = %q, want its own bucket", got)
+	}
+}
+
+func TestRateLimitMiddlewareIgnoresSpoofedKeysAndForwardedAddresses(t *testing.T) {
+	creds := &fakeRateLimitCredentials{}
+	s := &ExternalServer{
+		limiter: newRateLimiter(ratelimit.NewMemoryStore(), creds, 1, 2),
+		metrics: externalMetrics{requestsThrottled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "test_requests_throttled_total",
+		}, []string{"route"})},
+	}
+	handler := telemetry.CapturePeerAddr(middleware.RealIP(s.rateLimitMiddleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))))
+
+	// A fresh made-up key and forwarded address on every request still
+	// share the bucket of the connection's address.
+	headers := []struct{ key, forwarded string }{
+		{"random-1", "10.0.0.1"},
+		{"random-2", "10.0.0.2"},
+		{"random-3", "10.0.0.3"},
+	}
+	var codes []int
+	for _, h := range headers {
+		req := httptest.NewRequest(http.MethodPost, "/jobs/pull", nil)
+		req.RemoteAddr = "203.0.113.7:5123"
+		req.Header.Set("X-API-Key", h.key)
+		req.Header.Set("X-Forwarded-For", h.forwarded)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		codes = append(codes, rec.Code)
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Fatalf("status codes = %v, want two admitted and the third throttled", codes)
+	}
+}
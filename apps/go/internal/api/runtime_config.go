@@ -0,0 +1,79 @@
+package api
+
+import (
+	"net/http"
+
+	"pipelogiq/internal/config"
+	"pipelogiq/internal/types"
+)
+
+// handleGetRuntimeConfig returns the non-secret settings of this API process,
+// for diagnosing a deployment without shell access.
+func (s *Server) handleGetRuntimeConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, runtimeConfig(s.cfg), http.StatusOK)
+}
+
+// runtimeConfig copies the settings that are safe to show from cfg. Fields
+// are listed one by one so that connection URLs, credentials and tokens, and
+// any setting added later, stay out until they are added here.
+func runtimeConfig(cfg config.APIConfig) types.RuntimeConfigResponse {
+	var queuePrefetch map[string]int
+	if len(cfg.GatewayQueuePrefetch) > 0 {
+		queuePrefetch = make(map[string]int, len(cfg.GatewayQueuePrefetch))
+		for queue, prefetch := range cfg.GatewayQueuePrefetch {
+			queuePrefetch[queue] = prefetch
+		}
+	}
+
+	return types.RuntimeConfigResponse{
+		AppID: cfg.AppID,
+		Logging: types.RuntimeLoggingConfig{
+			Level:             cfg.LogLevel,
+			Format:            cfg.LogFormat,
+			AccessLogLevel:    cfg.AccessLogLevel,
+			AccessLogBodySize: cfg.AccessLogBodySize,
+		},
+		Broker: types.RuntimeBrokerConfig{
+			Prefetch:                cfg.QueuePrefetch,
+			TopologyOwnership:       cfg.QueueTopologyOwnership,
+			DLQEnabled:              cfg.QueueDLQEnabled,
+			DLQTTLSec:               int64(cfg.QueueDLQMessageTTL.Seconds()),
+			QueueType:               cfg.QueueType,
+			MaxLength:               cfg.QueueMaxLength,
+			Overflow:                cfg.QueueOverflow,
+			ResultQueueShards:       cfg.ResultQueueShards,
+			PublisherConfirms:       cfg.PublishConfirms,
+			ExposeURL:               cfg.RabbitExposeURL,
+			WorkerCredentials:       cfg.RabbitWorkerUsername != "" && cfg.RabbitWorkerPassword != "",
+			ReconnectMaxIntervalSec: int64(cfg.Reconnect.MaxInterval.Seconds()),
+			ReconnectJitter:         cfg.Reconnect.Jitter,
+		},
+		Gateway: types.RuntimeGatewayConfig{
+			VisibilityTimeoutSec: int64(cfg.GatewayVisibilityTTL.Seconds()),
+			MaxInFlight:          cfg.GatewayMaxInFlight,
+			PullPrefetch:         cfg.GatewayPullPrefetch,
+			QueuePrefetch:        queuePrefetch,
+		},
+		Workers: types.RuntimeWorkerConfig{
+			HeartbeatIntervalSec: int64(cfg.WorkerHeartbeatInterval.Seconds()),
+			OfflineAfterSec:      int64(cfg.WorkerOfflineAfter.Seconds()),
+			SessionTTLSec:        int64(cfg.WorkerSessionTTL.Seconds()),
+			EventsMaxBatch:       cfg.WorkerEventsMaxBatch,
+			ListStaleAfterSec:    int64(cfg.WorkerListStaleAfter.Seconds()),
+			IngestMaxBodyBytes:   cfg.IngestMaxBodyBytes,
+			GRPCEnabled:          cfg.WorkerGRPCAddr != "",
+		},
+		External: types.RuntimeExternalAPIConfig{
+			RateLimitRPS:              cfg.ExternalRateLimitRPS,
+			RateLimitBurst:            cfg.ExternalRateLimitBurst,
+			RateLimitStore:            cfg.RateLimitStore,
+			PipelineStrictHandlers:    cfg.PipelineStrictHandlers,
+			APIKeyExpiryWarnWithinSec: int64(cfg.APIKeyExpiryWarnWithin.Seconds()),
+		},
+		Policies: types.RuntimePolicyConfig{
+			TargetOptionsLimit:       cfg.PolicyTargetOptionsLimit,
+			TargetOptionsCacheTTLSec: int64(cfg.PolicyTargetOptionsCacheTTL.Seconds()),
+			PreviewEnvMatch:          cfg.PolicyPreviewEnvMatch,
+		},
+	}
+}
@@ -11,17 +11,21 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 
 	"pipelogiq/internal/alerts"
 	"pipelogiq/internal/config"
 	"pipelogiq/internal/constants"
+	"pipelogiq/internal/datadog"
 	"pipelogiq/internal/mq"
 	observabilityhttp "pipelogiq/internal/observability/http"
 	observabilityrepo "pipelogiq/internal/observability/repo"
 	observabilityservice "pipelogiq/internal/observability/service"
+	"pipelogiq/internal/sentry"
 	"pipelogiq/internal/store"
+	"pipelogiq/internal/telemetry"
 	"pipelogiq/internal/types"
 	"pipelogiq/internal/version"
 )
@@ -32,17 +36,26 @@ type Server struct {
 	mq                   *mq.Client
 	hub                  *Hub
 	policies             *policyRepository
+	targetOptions        *policyTargetCache
+	queueStats           *queueStatsCache
+	inspector            queueInspector
+	observabilitySvc     *observabilityservice.Service
 	observabilityHandler *observabilityhttp.Handler
+	datadog              *datadog.Forwarder
 	logger               *slog.Logger
 	server               *http.Server
 }
 
 func NewServer(cfg config.APIConfig, st *store.Store, mqClient *mq.Client, logger *slog.Logger) *Server {
+	st.SetWorkerOfflineAfter(cfg.WorkerOfflineAfter)
 	observabilityRepo := observabilityrepo.NewSQLRepository(st.DB())
 	observabilitySvc := observabilityservice.New(observabilityRepo, logger)
 	observabilityHandler := observabilityhttp.NewHandler(observabilitySvc, logger)
 	alertsNotifier := alerts.New(observabilityRepo, logger)
 	st.SetAlertSink(alertsNotifier)
+	datadogForwarder := datadog.New(observabilityRepo, prometheus.DefaultGatherer, "pipelogiq-api", logger)
+	st.AddAlertSink(datadogForwarder)
+	st.AddAlertSink(sentry.New(observabilityRepo, "pipelogiq-api", logger))
 	policiesRepo := newPolicyRepository(logger)
 	policiesRepo.setEventListener(func(event types.PolicyEvent) {
 		go func(ev types.PolicyEvent) {
@@ -56,9 +69,14 @@ func NewServer(cfg config.APIConfig, st *store.Store, mqClient *mq.Client, logge
 		cfg:                  cfg,
 		store:                st,
 		mq:                   mqClient,
-		hub:                  NewHub(logger),
+		hub:                  NewHub(st, cfg.CORSAllowedOrigins, logger),
 		policies:             policiesRepo,
+		targetOptions:        newPolicyTargetCache(cfg.PolicyTargetOptionsCacheTTL),
+		queueStats:           &queueStatsCache{},
+		inspector:            mqClient,
+		observabilitySvc:     observabilitySvc,
 		observabilityHandler: observabilityHandler,
+		datadog:              datadogForwarder,
 		logger:               logger,
 	}
 }
@@ -67,23 +85,23 @@ func (s *Server) Run(ctx context.Context) error {
 	router := chi.NewRouter()
 
 	// Global middleware
+	router.Use(telemetry.CapturePeerAddr)
 	router.Use(middleware.RequestID)
 	router.Use(middleware.RealIP)
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.Timeout(60 * time.Second))
 	router.Use(otelhttp.NewMiddleware("pipelogiq-api-internal"))
-	router.Use(corsMiddleware)
+	router.Use(accessLogMiddleware(s.logger, s.cfg))
+	router.Use(corsMiddleware(s.cfg.CORSAllowedOrigins))
 
 	// Health and version endpoints
-	router.Get(s.cfg.HealthLivenessEndpoint, s.handleHealth)
-	router.Get(s.cfg.HealthReadyEndpoint, s.handleHealth)
+	router.Get(s.cfg.HealthLivenessEndpoint, handleLiveness)
+	router.Get(s.cfg.HealthReadyEndpoint, readinessHandler(s.store, s.mq, s.logger))
 	router.Get("/version", version.HandleVersion)
-	router.Handle("/metrics", promhttp.Handler())
+	router.Handle("/metrics", telemetry.GuardMetrics(promhttp.Handler(), s.cfg.MetricsToken, s.cfg.MetricsAllowedCIDRs))
 
-	// WebSocket endpoint (public, no auth)
-	router.Get("/ws", func(w http.ResponseWriter, r *http.Request) {
-		s.hub.ServeWS(w, r)
-	})
+	// WebSocket endpoint (authenticates the handshake itself)
+	router.Get("/ws", s.handleWS)
 
 	// Auth endpoints (public)
 	router.Post("/auth/login", s.handleLogin)
@@ -96,13 +114,24 @@ func (s *Server) Run(ctx context.Context) error {
 		// Auth
 		r.Get("/auth/me", s.handleGetCurrentUser)
 
+		// Runtime settings
+		r.Get("/config", s.handleGetRuntimeConfig)
+
 		// Pipeline endpoints
 		r.Get("/pipelines/{id}", s.handleGetPipeline)
+		r.Delete("/pipelines/{id}", s.handleDeletePipeline)
 		r.Get("/pipelines/{id}/stages", s.handleGetStages)
+		r.Get("/pipelines/{id}/stages/{stageId}/history", s.handleGetStageHistory)
 		r.Get("/pipelines/{id}/context", s.handleGetContext)
+		r.Get("/pipelines/{id}/graph", s.handleGetPipelineGraph)
+		r.Get("/pipelines/{id}/timeline", s.handleGetPipelineTimeline)
 		r.Get("/pipelines", s.handleGetPipelines)
 		r.Post("/pipelines/rerunStage", s.handleRerunStage)
 		r.Post("/pipelines/skipStage", s.handleSkipStage)
+		r.Post("/pipelines/rerunStages", s.handleBulkRerunStages)
+		r.Post("/pipelines/skipStages", s.handleBulkSkipStages)
+		r.Post("/pipelines/{id}/cancel", s.handleCancelPipeline)
+		r.Post("/pipelines/{id}/replay", s.handleReplayPipeline)
 		r.Get("/pipelines/logs/{pipelineId}", s.handleGetPipelineLogs)
 		r.Get("/pipelines/logs/{pipelineId}/{stageId}", s.handleGetPipelineLogs)
 		r.Get("/pipelines/stages/{pipelineId}", s.handleGetPipelineStagesAlt)
@@ -111,10 +140,18 @@ func (s *Server) Run(ctx context.Context) error {
 		// Application endpoints
 		r.Get("/applications", s.handleGetApplications)
 		r.Post("/applications", s.handleSaveApplication)
+		r.Get("/applications/{id}/featureFlags", s.handleGetFeatureFlags)
+		r.Put("/applications/{id}/featureFlags/{flag}", s.handleSetFeatureFlag)
+		r.Put("/applications/{id}/disable", s.handleDisableApplication)
+		r.Put("/applications/{id}/enable", s.handleEnableApplication)
+		r.Get("/applications/{id}/stats", s.handleGetApplicationStats)
+		r.Get("/applications/{id}/webhook", s.handleGetApplicationWebhook)
+		r.Put("/applications/{id}/webhook", s.handleSaveApplicationWebhook)
 
 		// ApiKey endpoints
 		r.Post("/apiKeys", s.handleGenerateApiKey)
 		r.Get("/apiKeys", s.handleGetApiKeys)
+		r.Get("/apiKeys/expiring", s.handleGetExpiringApiKeys)
 		r.Put("/apiKeys/disable", s.handleDisableApiKey)
 
 		// Keywords
@@ -123,9 +160,18 @@ func (s *Server) Run(ctx context.Context) error {
 		// Log endpoints
 		r.Get("/logs/{appId}", s.handleGetLogsByAppID)
 		r.Get("/workers", s.handleGetWorkers)
+		r.Get("/workers/capacity", s.handleGetWorkerCapacity)
+		r.Get("/handlers", s.handleGetHandlers)
 		r.Get("/workers/events", s.handleGetWorkerEvents)
+		r.Get("/workers/{workerId}", s.handleGetWorker)
+		r.Get("/workers/{workerId}/config", s.handleGetWorkerConfig)
 		r.Get("/workers/{workerId}/events", s.handleGetWorkerEvents)
 
+		// Dead-letter queue endpoints
+		r.Get("/queues/{queue}/dlq", s.handlePeekDLQ)
+		r.Post("/queues/{queue}/dlq/requeue", s.handleRequeueDLQ)
+		r.Get("/dead-letters", s.handleListDeadLetters)
+
 		// Observability endpoints
 		r.Route("/observability", s.registerObservabilityRoutes)
 
@@ -149,6 +195,15 @@ func (s *Server) Run(ctx context.Context) error {
 		}
 	}()
 
+	go func() {
+		if err := s.consumePolicyTriggers(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			s.logger.Error("policy trigger consumer exited", "err", err)
+		}
+	}()
+
+	go s.datadog.Run(ctx)
+	go s.observabilitySvc.RunHealthChecks(ctx, s.cfg.IntegrationCheckInterval)
+
 	errCh := make(chan error, 1)
 	go func() {
 		s.logger.Info("api listening", "addr", s.cfg.HTTPAddr)
@@ -168,37 +223,38 @@ func (s *Server) Run(ctx context.Context) error {
 	}
 }
 
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		origin := r.Header.Get("Origin")
-		if origin == "" {
-			origin = "*"
-		}
-
-		w.Header().Set("Access-Control-Allow-Origin", origin)
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, X-Requested-With")
-
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
-
-func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write([]byte("ok"))
+// corsMiddleware allows credentialed cross-origin requests only from the
+// API's own host and allowedOrigins; see originAllowed.
+func corsMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin != "" && originAllowed(r, allowedOrigins) {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, X-Requested-With")
+				w.Header().Add("Vary", "Origin")
+			}
+
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
 func (s *Server) handleGetPipeline(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "invalid id", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid id")
+		return
+	}
+	if !s.authorizePipeline(w, r, id) {
 		return
 	}
 
@@ -207,7 +263,7 @@ func (s *Server) handleGetPipeline(w http.ResponseWriter, r *http.Request) {
 
 	pipeline, err := s.store.GetPipelineFullDetail(ctx, id)
 	if err != nil {
-		http.Error(w, "not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, errCodeNotFound, "not found")
 		return
 	}
 	writeJSON(w, pipeline, http.StatusOK)
@@ -217,7 +273,10 @@ func (s *Server) handleGetStages(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "invalid id", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid id")
+		return
+	}
+	if !s.authorizePipeline(w, r, id) {
 		return
 	}
 
@@ -226,24 +285,108 @@ func (s *Server) handleGetStages(w http.ResponseWriter, r *http.Request) {
 
 	stages, err := s.store.GetPipelineStages(ctx, id)
 	if err != nil {
-		http.Error(w, "not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, errCodeNotFound, "not found")
 		return
 	}
 	writeJSON(w, stages, http.StatusOK)
 }
 
+// handleGetStageHistory returns the runs of a stage archived by reruns, so a
+// rerun's result can be compared with the earlier ones.
+func (s *Server) handleGetStageHistory(w http.ResponseWriter, r *http.Request) {
+	pipelineID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid id")
+		return
+	}
+	stageID, err := strconv.Atoi(chi.URLParam(r, "stageId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid stage id")
+		return
+	}
+	if !s.authorizePipeline(w, r, pipelineID) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	items, err := s.store.GetStageExecutionHistory(ctx, pipelineID, stageID)
+	if err != nil {
+		if store.IsStageNotFoundError(err) {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "stage not found")
+			return
+		}
+		s.logger.Error("get stage history failed", "err", err, "stageId", stageID)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to get stage history")
+		return
+	}
+	writeJSON(w, types.StageExecutionHistoryResponse{StageID: stageID, Items: items}, http.StatusOK)
+}
+
+// handleGetPipelineGraph returns the pipeline's stages as a graph for
+// rendering, with dependency cycles and the stages that run next.
+func (s *Server) handleGetPipelineGraph(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid id")
+		return
+	}
+	if !s.authorizePipeline(w, r, id) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	graph, err := s.store.GetPipelineGraph(ctx, id)
+	if err != nil {
+		s.logger.Error("get pipeline graph failed", "err", err, "pipelineId", id)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to get pipeline graph")
+		return
+	}
+	writeJSON(w, graph, http.StatusOK)
+}
+
+// handleGetPipelineTimeline returns the pipeline's stage and pipeline status
+// changes, oldest first.
+func (s *Server) handleGetPipelineTimeline(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid id")
+		return
+	}
+	if !s.authorizePipeline(w, r, id) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	timeline, err := s.store.GetPipelineTimeline(ctx, id)
+	if err != nil {
+		s.logger.Error("get pipeline timeline failed", "err", err, "pipelineId", id)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to get pipeline timeline")
+		return
+	}
+	writeJSON(w, timeline, http.StatusOK)
+}
+
 func (s *Server) handleGetContext(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "invalid id", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid id")
+		return
+	}
+	if !s.authorizePipeline(w, r, id) {
 		return
 	}
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 	ctxItems, err := s.store.GetPipelineContext(ctx, id)
 	if err != nil {
-		http.Error(w, "not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, errCodeNotFound, "not found")
 		return
 	}
 	writeJSON(w, ctxItems, http.StatusOK)
@@ -254,7 +397,10 @@ func (s *Server) handleGetPipelineStagesAlt(w http.ResponseWriter, r *http.Reque
 	idStr := chi.URLParam(r, "pipelineId")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "invalid id", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid id")
+		return
+	}
+	if !s.authorizePipeline(w, r, id) {
 		return
 	}
 
@@ -263,7 +409,7 @@ func (s *Server) handleGetPipelineStagesAlt(w http.ResponseWriter, r *http.Reque
 
 	stages, err := s.store.GetPipelineStages(ctx, id)
 	if err != nil {
-		http.Error(w, "not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, errCodeNotFound, "not found")
 		return
 	}
 	writeJSON(w, stages, http.StatusOK)
@@ -273,7 +419,10 @@ func (s *Server) handleGetPipelineContextAlt(w http.ResponseWriter, r *http.Requ
 	idStr := chi.URLParam(r, "pipelineId")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "invalid id", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid id")
+		return
+	}
+	if !s.authorizePipeline(w, r, id) {
 		return
 	}
 
@@ -282,7 +431,7 @@ func (s *Server) handleGetPipelineContextAlt(w http.ResponseWriter, r *http.Requ
 
 	ctxItems, err := s.store.GetPipelineContext(ctx, id)
 	if err != nil {
-		http.Error(w, "not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, errCodeNotFound, "not found")
 		return
 	}
 	writeJSON(w, ctxItems, http.StatusOK)
@@ -0,0 +1,372 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"pipelogiq/internal/types"
+)
+
+// workerServiceName is the gRPC service that mirrors the /workers HTTP
+// routes. Messages are JSON-encoded with the same fields as the HTTP bodies,
+// so there is no protobuf schema to generate clients from.
+const workerServiceName = "pipelogiq.worker.v1.WorkerService"
+
+// workerGRPCErrorDomain is the ErrorInfo domain carrying the HTTP API error
+// code of a failed call.
+const workerGRPCErrorDomain = "pipelogiq"
+
+// jsonCodec encodes gRPC messages as JSON. The server forces it for every
+// call, whatever content-subtype the client announces.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+// workerServiceServer is the handler type of workerServiceDesc.
+type workerServiceServer interface {
+	Bootstrap(ctx context.Context, req *types.WorkerBootstrapRequest) (*types.WorkerBootstrapResponse, error)
+	Heartbeat(stream grpc.ServerStream) error
+	Events(ctx context.Context, req *types.WorkerEventsRequest) (*workerAck, error)
+	Shutdown(ctx context.Context, req *types.WorkerShutdownRequest) (*workerAck, error)
+}
+
+var workerServiceDesc = grpc.ServiceDesc{
+	ServiceName: workerServiceName,
+	HandlerType: (*workerServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		unaryWorkerMethod("Bootstrap", workerServiceServer.Bootstrap),
+		unaryWorkerMethod("Events", workerServiceServer.Events),
+		unaryWorkerMethod("Shutdown", workerServiceServer.Shutdown),
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName: "Heartbeat",
+			Handler: func(srv any, stream grpc.ServerStream) error {
+				return srv.(workerServiceServer).Heartbeat(stream)
+			},
+			ClientStreams: true,
+		},
+	},
+}
+
+func unaryWorkerMethod[Req, Resp any](name string, call func(workerServiceServer, context.Context, *Req) (*Resp, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			req := new(Req)
+			if err := dec(req); err != nil {
+				return nil, grpcRequestError(newRequestError(http.StatusBadRequest, errCodeInvalidPayload, "invalid payload"))
+			}
+			handler := func(ctx context.Context, req any) (any, error) {
+				return call(srv.(workerServiceServer), ctx, req.(*Req))
+			}
+			if interceptor == nil {
+				return handler(ctx, req)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + workerServiceName + "/" + name}
+			return interceptor(ctx, req, info, handler)
+		},
+	}
+}
+
+// workerSessions is the worker session API shared with the HTTP handlers;
+// *ExternalServer implements it.
+type workerSessions interface {
+	bootstrapWorker(ctx context.Context, apiKey string, req types.WorkerBootstrapRequest) (types.WorkerBootstrapResponse, *requestError)
+	recordWorkerHeartbeat(ctx context.Context, sessionToken string, req types.WorkerHeartbeatRequest) (bool, *requestError)
+	saveWorkerEvents(ctx context.Context, sessionToken string, req types.WorkerEventsRequest) *requestError
+	stopWorker(ctx context.Context, sessionToken string, req types.WorkerShutdownRequest) *requestError
+}
+
+// workerGRPC serves the worker session API over gRPC. It shares the
+// validation, store calls and error codes of the HTTP handlers; credentials
+// come from the same headers, sent as metadata.
+type workerGRPC struct {
+	s workerSessions
+}
+
+func (g workerGRPC) Bootstrap(ctx context.Context, req *types.WorkerBootstrapRequest) (*types.WorkerBootstrapResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	response, reqErr := g.s.bootstrapWorker(ctx, apiKeyFromMetadata(ctx), *req)
+	if reqErr != nil {
+		return nil, grpcRequestError(reqErr)
+	}
+	return &response, nil
+}
+
+// Heartbeat records every heartbeat the worker sends on the stream. The
+// stream is meant to stay open for the life of the worker session; when the
+// worker closes it the reply counts the heartbeats accepted. The first
+// rejected heartbeat ends the stream with its error, and the first one with a
+// stale configVersion ends it early with rebootstrapRequired set.
+func (g workerGRPC) Heartbeat(stream grpc.ServerStream) error {
+	sessionToken := sessionTokenFromMetadata(stream.Context())
+	ack := workerAck{Status: "ok"}
+	accepted := 0
+	for {
+		var req types.WorkerHeartbeatRequest
+		if err := stream.RecvMsg(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				ack.AcceptedCount = &accepted
+				return stream.SendMsg(&ack)
+			}
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(stream.Context(), 5*time.Second)
+		configStale, reqErr := g.s.recordWorkerHeartbeat(ctx, sessionToken, req)
+		cancel()
+		if reqErr != nil {
+			return grpcRequestError(reqErr)
+		}
+		ack.WorkerID = req.WorkerID
+		accepted++
+		if configStale {
+			ack.AcceptedCount = &accepted
+			ack.RebootstrapRequired = true
+			return stream.SendMsg(&ack)
+		}
+	}
+}
+
+func (g workerGRPC) Events(ctx context.Context, req *types.WorkerEventsRequest) (*workerAck, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if reqErr := g.s.saveWorkerEvents(ctx, sessionTokenFromMetadata(ctx), *req); reqErr != nil {
+		return nil, grpcRequestError(reqErr)
+	}
+	accepted := len(req.Events)
+	return &workerAck{Status: "ok", WorkerID: req.WorkerID, AcceptedCount: &accepted}, nil
+}
+
+func (g workerGRPC) Shutdown(ctx context.Context, req *types.WorkerShutdownRequest) (*workerAck, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if reqErr := g.s.stopWorker(ctx, sessionTokenFromMetadata(ctx), *req); reqErr != nil {
+		return nil, grpcRequestError(reqErr)
+	}
+	return &workerAck{Status: "ok", WorkerID: req.WorkerID}, nil
+}
+
+// runWorkerGRPC serves the worker gRPC API on WorkerGRPCAddr until ctx is
+// done. Heartbeat streams are long-lived, so a graceful stop that does not
+// finish within 5 seconds closes them.
+func (s *ExternalServer) runWorkerGRPC(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.cfg.WorkerGRPCAddr)
+	if err != nil {
+		return fmt.Errorf("listen worker grpc: %w", err)
+	}
+
+	server := s.newWorkerGRPCServer(s)
+
+	go func() {
+		<-ctx.Done()
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			server.Stop()
+		}
+	}()
+
+	s.logger.Info("worker grpc listening", "addr", s.cfg.WorkerGRPCAddr)
+	if err := server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+// newWorkerGRPCServer returns a gRPC server for the worker service backed by
+// sessions, with the rate limit and panic recovery of s.
+func (s *ExternalServer) newWorkerGRPCServer(sessions workerSessions) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ForceServerCodec(jsonCodec{}),
+		grpc.ChainUnaryInterceptor(s.workerGRPCUnaryInterceptor),
+		grpc.ChainStreamInterceptor(s.workerGRPCStreamInterceptor),
+	)
+	server.RegisterService(&workerServiceDesc, workerGRPC{s: sessions})
+	return server
+}
+
+func (s *ExternalServer) workerGRPCUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer s.recoverWorkerGRPC(info.FullMethod, &err)
+	if err := s.allowWorkerGRPC(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// workerGRPCStreamInterceptor applies the rate limit when a stream opens and
+// to every message received after the first, so a long-lived heartbeat stream
+// is throttled like a series of HTTP heartbeats.
+func (s *ExternalServer) workerGRPCStreamInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer s.recoverWorkerGRPC(info.FullMethod, &err)
+	if err := s.allowWorkerGRPC(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, &rateLimitedStream{ServerStream: stream, s: s, method: info.FullMethod})
+}
+
+// rateLimitedStream checks the rate limit for each message it receives. The
+// first message is covered by the check made when the stream opened.
+type rateLimitedStream struct {
+	grpc.ServerStream
+	s        *ExternalServer
+	method   string
+	received int
+}
+
+func (r *rateLimitedStream) RecvMsg(m any) error {
+	if err := r.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	r.received++
+	if r.received == 1 {
+		return nil
+	}
+	return r.s.allowWorkerGRPC(r.Context(), r.method)
+}
+
+func (s *ExternalServer) recoverWorkerGRPC(method string, err *error) {
+	if r := recover(); r != nil {
+		s.logger.Error("worker grpc handler panicked", "method", method, "panic", r, "stack", string(debug.Stack()))
+		*err = grpcRequestError(newRequestError(http.StatusInternalServerError, errCodeInternal, "internal error"))
+	}
+}
+
+// allowWorkerGRPC applies the external API rate limit with the same keys as
+// the HTTP routes.
+func (s *ExternalServer) allowWorkerGRPC(ctx context.Context, method string) error {
+	if s.limiter == nil {
+		return nil
+	}
+	ok, wait, err := s.limiter.allow(ctx, s.grpcRateLimitKey(ctx))
+	if err != nil {
+		s.logger.Warn("rate limit check failed", "err", err)
+		return nil
+	}
+	if ok {
+		return nil
+	}
+
+	s.metrics.requestsThrottled.WithLabelValues(method).Inc()
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(retryAfter)))
+	return grpcRequestError(newRequestError(http.StatusTooManyRequests, errCodeRateLimited, "rate limit exceeded"))
+}
+
+func (s *ExternalServer) grpcRateLimitKey(ctx context.Context) string {
+	peerAddr := "unknown"
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		peerAddr = p.Addr.String()
+	}
+	return s.limiter.key(ctx, apiKeyFromMetadata(ctx), sessionTokenFromMetadata(ctx), peerAddr)
+}
+
+// grpcRequestError converts err to a gRPC status whose ErrorInfo reason is
+// the HTTP API error code.
+func grpcRequestError(err *requestError) error {
+	st := status.New(grpcCode(err.status), err.message)
+	if detailed, detailErr := st.WithDetails(&errdetails.ErrorInfo{Reason: err.code, Domain: workerGRPCErrorDomain}); detailErr == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+func grpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.Aborted
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
+
+// apiKeyFromMetadata reads the API key like extractAPIKey: a bearer
+// authorization, then x-api-key.
+func apiKeyFromMetadata(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if token := bearerToken(firstMetadataValue(md, "authorization")); token != "" {
+		return token
+	}
+	return firstMetadataValue(md, "x-api-key")
+}
+
+// sessionTokenFromMetadata reads the worker session token like
+// extractWorkerSessionToken: x-worker-session, x-worker-token, then a bearer
+// authorization.
+func sessionTokenFromMetadata(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if token := firstMetadataValue(md, "x-worker-session"); token != "" {
+		return token
+	}
+	if token := firstMetadataValue(md, "x-worker-token"); token != "" {
+		return token
+	}
+	return bearerToken(firstMetadataValue(md, "authorization"))
+}
+
+func firstMetadataValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+	return ""
+}
+
+func bearerToken(value string) string {
+	const prefix = "bearer "
+	if len(value) > len(prefix) && strings.EqualFold(value[:len(prefix)], prefix) {
+		return strings.TrimSpace(value[len(prefix):])
+	}
+	return ""
+}
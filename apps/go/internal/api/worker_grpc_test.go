@@ -0,0 +1,223 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"pipelogiq/internal/ratelimit"
+	"pipelogiq/internal/types"
+)
+
+// fakeWorkerSessions accepts the API key "key" and the session token
+// "session"; heartbeats in state "stale" report a stale configVersion.
+type fakeWorkerSessions struct {
+	heartbeats []types.WorkerHeartbeatRequest
+}
+
+func (*fakeWorkerSessions) bootstrapWorker(_ context.Context, apiKey string, req types.WorkerBootstrapRequest) (types.WorkerBootstrapResponse, *requestError) {
+	if apiKey != "key" {
+		return types.WorkerBootstrapResponse{}, newRequestError(http.StatusUnauthorized, errCodeInvalidAPIKey, "invalid api key")
+	}
+	return types.WorkerBootstrapResponse{WorkerID: req.WorkerName + "-1", WorkerSessionToken: "session", ConfigVersion: "v1"}, nil
+}
+
+func (f *fakeWorkerSessions) recordWorkerHeartbeat(_ context.Context, sessionToken string, req types.WorkerHeartbeatRequest) (bool, *requestError) {
+	if sessionToken != "session" {
+		return false, newRequestError(http.StatusUnauthorized, errCodeInvalidSession, "invalid worker session")
+	}
+	f.heartbeats = append(f.heartbeats, req)
+	return req.State == "stale", nil
+}
+
+func (*fakeWorkerSessions) saveWorkerEvents(context.Context, string, types.WorkerEventsRequest) *requestError {
+	return nil
+}
+
+func (*fakeWorkerSessions) stopWorker(context.Context, string, types.WorkerShutdownRequest) *requestError {
+	return nil
+}
+
+// dialWorkerGRPC serves sessions over an in-memory listener and returns a
+// client connection to it.
+func dialWorkerGRPC(t *testing.T, sessions workerSessions) *grpc.ClientConn {
+	t.Helper()
+	return dialWorkerGRPCServer(t, &ExternalServer{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}, sessions)
+}
+
+// dialWorkerGRPCServer is dialWorkerGRPC with the interceptors of s.
+func dialWorkerGRPCServer(t *testing.T, s *ExternalServer, sessions workerSessions) *grpc.ClientConn {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := s.newWorkerGRPCServer(sessions)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(jsonCodec{})),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() = %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestWorkerGRPCBootstrap(t *testing.T) {
+	conn := dialWorkerGRPC(t, &fakeWorkerSessions{})
+	method := "/" + workerServiceName + "/Bootstrap"
+	req := &types.WorkerBootstrapRequest{WorkerName: "resize"}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer key")
+	var resp types.WorkerBootstrapResponse
+	if err := conn.Invoke(ctx, method, req, &resp); err != nil {
+		t.Fatalf("Bootstrap() = %v", err)
+	}
+	if resp.WorkerID != "resize-1" || resp.WorkerSessionToken != "session" {
+		t.Fatalf("Bootstrap() = %+v, want worker resize-1 with a session", resp)
+	}
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "wrong")
+	err := conn.Invoke(ctx, method, req, &resp)
+	st := status.Convert(err)
+	if st.Code() != codes.Unauthenticated {
+		t.Fatalf("Bootstrap(wrong key) code = %v, want Unauthenticated", st.Code())
+	}
+	var info *errdetails.ErrorInfo
+	for _, detail := range st.Details() {
+		if d, ok := detail.(*errdetails.ErrorInfo); ok {
+			info = d
+		}
+	}
+	if info == nil || info.Reason != errCodeInvalidAPIKey || info.Domain != workerGRPCErrorDomain {
+		t.Fatalf("Bootstrap(wrong key) ErrorInfo = %v, want %s in %s", info, errCodeInvalidAPIKey, workerGRPCErrorDomain)
+	}
+}
+
+func TestWorkerGRPCHeartbeat(t *testing.T) {
+	sessions := &fakeWorkerSessions{}
+	conn := dialWorkerGRPC(t, sessions)
+	desc := &grpc.StreamDesc{StreamName: "Heartbeat", ClientStreams: true}
+	method := "/" + workerServiceName + "/Heartbeat"
+	open := func(token string) grpc.ClientStream {
+		t.Helper()
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "x-worker-session", token)
+		stream, err := conn.NewStream(ctx, desc, method)
+		if err != nil {
+			t.Fatalf("NewStream() = %v", err)
+		}
+		return stream
+	}
+
+	// Closing the stream reports the heartbeats accepted.
+	stream := open("session")
+	for range 2 {
+		if err := stream.SendMsg(&types.WorkerHeartbeatRequest{WorkerID: "w1", State: "running"}); err != nil {
+			t.Fatalf("SendMsg() = %v", err)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("CloseSend() = %v", err)
+	}
+	var ack workerAck
+	if err := stream.RecvMsg(&ack); err != nil {
+		t.Fatalf("RecvMsg() = %v", err)
+	}
+	if ack.WorkerID != "w1" || ack.AcceptedCount == nil || *ack.AcceptedCount != 2 || ack.RebootstrapRequired {
+		t.Fatalf("Heartbeat() ack = %+v, want 2 accepted for w1", ack)
+	}
+
+	// A stale configVersion ends the stream without waiting for the worker.
+	stream = open("session")
+	if err := stream.SendMsg(&types.WorkerHeartbeatRequest{WorkerID: "w1", State: "stale"}); err != nil {
+		t.Fatalf("SendMsg() = %v", err)
+	}
+	ack = workerAck{}
+	if err := stream.RecvMsg(&ack); err != nil {
+		t.Fatalf("RecvMsg(stale) = %v", err)
+	}
+	if !ack.RebootstrapRequired || ack.AcceptedCount == nil || *ack.AcceptedCount != 1 {
+		t.Fatalf("Heartbeat(stale) ack = %+v, want rebootstrapRequired after 1", ack)
+	}
+	if len(sessions.heartbeats) != 3 {
+		t.Fatalf("recorded %d heartbeats, want 3", len(sessions.heartbeats))
+	}
+
+	// A rejected heartbeat ends the stream with its error.
+	stream = open("expired")
+	if err := stream.SendMsg(&types.WorkerHeartbeatRequest{WorkerID: "w1", State: "running"}); err != nil {
+		t.Fatalf("SendMsg() = %v", err)
+	}
+	if err := stream.RecvMsg(&ack); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("Heartbeat(expired session) = %v, want Unauthenticated", err)
+	}
+}
+
+func TestWorkerGRPCHeartbeatRateLimitsEachMessage(t *testing.T) {
+	sessions := &fakeWorkerSessions{}
+	s := &ExternalServer{
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		limiter: newRateLimiter(ratelimit.NewMemoryStore(), &fakeRateLimitCredentials{}, 1, 3),
+		metrics: externalMetrics{requestsThrottled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "test_grpc_requests_throttled_total",
+		}, []string{"route"})},
+	}
+	conn := dialWorkerGRPCServer(t, s, sessions)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-worker-session", "session")
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{StreamName: "Heartbeat", ClientStreams: true}, "/"+workerServiceName+"/Heartbeat")
+	if err != nil {
+		t.Fatalf("NewStream() = %v", err)
+	}
+
+	// Opening the stream and the first heartbeat share one request of the
+	// burst of 3, so the fourth heartbeat is throttled.
+	for range 4 {
+		if err := stream.SendMsg(&types.WorkerHeartbeatRequest{WorkerID: "w1", State: "running"}); err != nil {
+			break
+		}
+	}
+	var ack workerAck
+	if err := stream.RecvMsg(&ack); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("RecvMsg() = %v, want ResourceExhausted", err)
+	}
+	if len(sessions.heartbeats) != 3 {
+		t.Fatalf("recorded %d heartbeats, want 3 before the limit", len(sessions.heartbeats))
+	}
+}
+
+func TestGRPCCode(t *testing.T) {
+	tests := []struct {
+		status int
+		want   codes.Code
+	}{
+		{http.StatusBadRequest, codes.InvalidArgument},
+		{http.StatusUnauthorized, codes.Unauthenticated},
+		{http.StatusForbidden, codes.PermissionDenied},
+		{http.StatusNotFound, codes.NotFound},
+		{http.StatusTooManyRequests, codes.ResourceExhausted},
+		{http.StatusServiceUnavailable, codes.Unavailable},
+		{http.StatusConflict, codes.Aborted},
+		{http.StatusInternalServerError, codes.Internal},
+	}
+	for _, tt := range tests {
+		if got := grpcCode(tt.status); got != tt.want {
+			t.Errorf("grpcCode(%d) = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"pipelogiq/internal/store"
+	"pipelogiq/internal/types"
+)
+
+// workerAck is the reply to heartbeats, event batches and shutdowns.
+// RebootstrapRequired answers a heartbeat whose configVersion is stale.
+type workerAck struct {
+	Status              string `json:"status"`
+	WorkerID            string `json:"workerId"`
+	AcceptedCount       *int   `json:"acceptedCount,omitempty"`
+	RebootstrapRequired bool   `json:"rebootstrapRequired,omitempty"`
+}
+
+// The methods below implement the worker session API for both the HTTP
+// handlers and the gRPC service, which only differ in how they read
+// credentials and report errors.
+
+func (s *ExternalServer) bootstrapWorker(ctx context.Context, apiKey string, req types.WorkerBootstrapRequest) (types.WorkerBootstrapResponse, *requestError) {
+	if strings.TrimSpace(req.WorkerName) == "" {
+		return types.WorkerBootstrapResponse{}, newRequestError(http.StatusBadRequest, errCodeInvalidRequest, "workerName is required")
+	}
+
+	auth, reqErr := s.checkAPIKey(ctx, apiKey, types.APIKeyScopeWorkersRegister)
+	if reqErr != nil {
+		return types.WorkerBootstrapResponse{}, reqErr
+	}
+	appID := auth.ApplicationID
+
+	if strings.TrimSpace(s.cfg.RabbitURL) == "" {
+		return types.WorkerBootstrapResponse{}, newRequestError(http.StatusServiceUnavailable, errCodeUnavailable, "rabbit connection is not configured")
+	}
+	brokerURL, err := s.workerBrokerURL()
+	if reqErr := s.workerBrokerError(err); reqErr != nil {
+		return types.WorkerBootstrapResponse{}, reqErr
+	}
+
+	appName, err := s.store.GetApplicationNameByID(ctx, appID)
+	if err != nil {
+		s.logger.Error("load application for bootstrap failed", "err", err, "applicationId", appID)
+		return types.WorkerBootstrapResponse{}, newRequestError(http.StatusInternalServerError, errCodeInternal, "failed to resolve application")
+	}
+
+	// Read the version first: a change racing this bootstrap then leaves the
+	// worker with an older version, and the next heartbeat corrects it. A
+	// worker without a version would never be told to bootstrap again.
+	configVersion, err := s.store.CurrentConfigVersion(ctx)
+	if err != nil {
+		s.logger.Error("load config version for bootstrap failed", "err", err, "applicationId", appID)
+		return types.WorkerBootstrapResponse{}, newRequestError(http.StatusInternalServerError, errCodeInternal, "failed to load config version")
+	}
+
+	sessionToken := uuid.NewString() + "." + uuid.NewString()
+	sessionExpiresAt := time.Now().UTC().Add(s.cfg.WorkerSessionTTL)
+	workerID, err := s.store.RegisterWorkerSession(
+		ctx,
+		appID,
+		s.cfg.AppID,
+		"rabbitmq",
+		req,
+		sessionToken,
+		sessionExpiresAt,
+	)
+	if err != nil {
+		s.logger.Error("register worker session failed", "err", err, "applicationId", appID)
+		return types.WorkerBootstrapResponse{}, newRequestError(http.StatusInternalServerError, errCodeInternal, "failed to register worker")
+	}
+
+	traceTemplate := ""
+	logsTemplate := ""
+	if trace, logs, err := s.store.GetObservabilityLinkTemplates(ctx); err == nil {
+		traceTemplate = trace
+		logsTemplate = logs
+	} else {
+		s.logger.Warn("load observability templates failed for bootstrap", "err", err)
+	}
+
+	broker := s.workerBrokerConfig(brokerURL)
+	resp := types.WorkerBootstrapResponse{
+		WorkerID:           workerID,
+		WorkerSessionToken: sessionToken,
+		ConfigVersion:      configVersion,
+		Application: types.WorkerApplicationInfo{
+			ApplicationID:   appID,
+			ApplicationName: appName,
+			AppID:           s.cfg.AppID,
+		},
+		MessageBroker: broker.MessageBroker,
+		Queues:        broker.Queues,
+		Heartbeat:     broker.Heartbeat,
+		Observability: types.WorkerObservabilityInfo{
+			TraceLinkTemplate: traceTemplate,
+			LogsLinkTemplate:  logsTemplate,
+		},
+	}
+
+	snapshot := resp
+	snapshot.WorkerSessionToken = ""
+	snapshot.MessageBroker.ConnectionString = redactBrokerURL(brokerURL)
+	if err := s.store.SaveWorkerBootstrapConfig(ctx, workerID, snapshot); err != nil {
+		s.logger.Warn("save worker bootstrap config failed", "err", err, "workerId", workerID)
+	}
+	return resp, nil
+}
+
+// recordWorkerHeartbeat stores a heartbeat and reports whether the worker
+// runs with a stale config version and should bootstrap again.
+func (s *ExternalServer) recordWorkerHeartbeat(ctx context.Context, sessionToken string, req types.WorkerHeartbeatRequest) (bool, *requestError) {
+	if strings.TrimSpace(req.WorkerID) == "" {
+		return false, newRequestError(http.StatusBadRequest, errCodeInvalidRequest, "workerId is required")
+	}
+	if strings.TrimSpace(sessionToken) == "" {
+		return false, newRequestError(http.StatusUnauthorized, errCodeSessionRequired, "worker session token is required")
+	}
+
+	configStale, err := s.store.UpdateWorkerHeartbeat(ctx, sessionToken, req)
+	if err != nil {
+		if store.IsInvalidWorkerSessionError(err) {
+			return false, newRequestError(http.StatusUnauthorized, errCodeInvalidSession, "invalid worker session")
+		}
+		s.logger.Error("worker heartbeat failed", "err", err, "workerId", req.WorkerID)
+		return false, newRequestError(http.StatusInternalServerError, errCodeInternal, "failed to persist heartbeat")
+	}
+	return configStale, nil
+}
+
+func (s *ExternalServer) saveWorkerEvents(ctx context.Context, sessionToken string, req types.WorkerEventsRequest) *requestError {
+	if strings.TrimSpace(req.WorkerID) == "" {
+		return newRequestError(http.StatusBadRequest, errCodeInvalidRequest, "workerId is required")
+	}
+	if len(req.Events) == 0 {
+		return newRequestError(http.StatusBadRequest, errCodeInvalidRequest, "events are required")
+	}
+	if len(req.Events) > s.cfg.WorkerEventsMaxBatch {
+		return newRequestError(http.StatusBadRequest, errCodeInvalidRequest, "too many events in one batch")
+	}
+	if strings.TrimSpace(sessionToken) == "" {
+		return newRequestError(http.StatusUnauthorized, errCodeSessionRequired, "worker session token is required")
+	}
+
+	if err := s.store.SaveWorkerEvents(ctx, req.WorkerID, sessionToken, req.Events); err != nil {
+		if store.IsInvalidWorkerSessionError(err) {
+			return newRequestError(http.StatusUnauthorized, errCodeInvalidSession, "invalid worker session")
+		}
+		s.logger.Error("save worker events failed", "err", err, "workerId", req.WorkerID, "count", len(req.Events))
+		return newRequestError(http.StatusInternalServerError, errCodeInternal, "failed to save worker events")
+	}
+	return nil
+}
+
+func (s *ExternalServer) stopWorker(ctx context.Context, sessionToken string, req types.WorkerShutdownRequest) *requestError {
+	if strings.TrimSpace(req.WorkerID) == "" {
+		return newRequestError(http.StatusBadRequest, errCodeInvalidRequest, "workerId is required")
+	}
+	if strings.TrimSpace(sessionToken) == "" {
+		return newRequestError(http.StatusUnauthorized, errCodeSessionRequired, "worker session token is required")
+	}
+
+	if err := s.store.StopWorkerSession(ctx, req.WorkerID, sessionToken, req.Reason); err != nil {
+		if store.IsInvalidWorkerSessionError(err) {
+			return newRequestError(http.StatusUnauthorized, errCodeInvalidSession, "invalid worker session")
+		}
+		s.logger.Error("worker shutdown update failed", "err", err, "workerId", req.WorkerID)
+		return newRequestError(http.StatusInternalServerError, errCodeInternal, "failed to stop worker session")
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 
+	"pipelogiq/internal/store"
 	"pipelogiq/internal/types"
 )
 
@@ -27,14 +28,22 @@ func (s *Server) handleGetWorkers(w http.ResponseWriter, r *http.Request) {
 	applicationID := parseQueryIntPtr(r.URL.Query().Get("applicationId"))
 	search := parseQueryStringPtr(r.URL.Query().Get("search"))
 
+	var stoppedAfter *time.Time
+	includeStale, _ := strconv.ParseBool(r.URL.Query().Get("includeStale"))
+	if !includeStale && s.cfg.WorkerListStaleAfter > 0 {
+		cutoff := time.Now().UTC().Add(-s.cfg.WorkerListStaleAfter)
+		stoppedAfter = &cutoff
+	}
+
 	workers, err := s.store.ListWorkers(ctx, types.WorkerListRequest{
 		ApplicationID: applicationID,
 		Search:        search,
 		Limit:         limit,
+		StoppedAfter:  stoppedAfter,
 	})
 	if err != nil {
 		s.logger.Error("list workers failed", "err", err)
-		http.Error(w, "failed to list workers", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to list workers")
 		return
 	}
 
@@ -77,6 +86,98 @@ func (s *Server) handleGetWorkers(w http.ResponseWriter, r *http.Request) {
 	}, http.StatusOK)
 }
 
+// handleGetWorker returns one worker with its recent heartbeat history;
+// ?limit= caps the number of heartbeats (default 120, max 1000).
+func (s *Server) handleGetWorker(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	workerID := strings.TrimSpace(chi.URLParam(r, "workerId"))
+	limit := 0
+	if value := strings.TrimSpace(r.URL.Query().Get("limit")); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			limit = parsed
+		}
+	}
+
+	detail, err := s.store.GetWorkerDetail(ctx, workerID, limit)
+	if err != nil {
+		s.logger.Error("get worker failed", "err", err, "workerId", workerID)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to get worker")
+		return
+	}
+	if detail == nil {
+		writeError(w, http.StatusNotFound, errCodeWorkerNotFound, "worker not found")
+		return
+	}
+
+	detail.EffectiveState = resolveEffectiveWorkerState(detail.WorkerStatusResponse, time.Now().UTC(), s.cfg.WorkerOfflineAfter)
+	detail.OfflineAfterSec = int64(s.cfg.WorkerOfflineAfter.Seconds())
+	writeJSON(w, detail, http.StatusOK)
+}
+
+// handleGetWorkerConfig returns the configuration the worker received at its
+// latest bootstrap, without the session token and broker password.
+func (s *Server) handleGetWorkerConfig(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	workerID := strings.TrimSpace(chi.URLParam(r, "workerId"))
+	config, err := s.store.GetWorkerBootstrapConfig(ctx, workerID)
+	if err != nil {
+		s.logger.Error("get worker config failed", "err", err, "workerId", workerID)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to get worker config")
+		return
+	}
+	if config == nil {
+		writeError(w, http.StatusNotFound, errCodeWorkerNotFound, "worker config not found")
+		return
+	}
+	writeJSON(w, config, http.StatusOK)
+}
+
+// handleGetWorkerCapacity reports per-handler capacity and utilization from
+// the latest heartbeats of the online workers.
+func (s *Server) handleGetWorkerCapacity(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	items, err := s.store.ListHandlerCapacity(ctx, parseQueryIntPtr(r.URL.Query().Get("applicationId")))
+	if err != nil {
+		s.logger.Error("list worker capacity failed", "err", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to list worker capacity")
+		return
+	}
+
+	writeJSON(w, types.HandlerCapacityListResponse{
+		Items:           items,
+		OfflineAfterSec: int64(s.cfg.WorkerOfflineAfter.Seconds()),
+	}, http.StatusOK)
+}
+
+// handleGetHandlers lists the handlers referenced by stages or advertised by
+// online workers, with how many online workers serve each.
+func (s *Server) handleGetHandlers(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	items, err := s.store.ListHandlers(ctx, parseQueryIntPtr(r.URL.Query().Get("applicationId")))
+	if err != nil {
+		s.logger.Error("list handlers failed", "err", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to list handlers")
+		return
+	}
+
+	writeJSON(w, types.HandlerRegistryResponse{
+		Items:           items,
+		OfflineAfterSec: int64(s.cfg.WorkerOfflineAfter.Seconds()),
+	}, http.StatusOK)
+}
+
+// handleGetWorkerEvents lists worker events newest first. ?level= keeps events
+// at or above a level (TRACE..ERROR), ?eventType= keeps one event type, and
+// ?before=/?after= page from the nextCursor or prevCursor of an earlier
+// response.
 func (s *Server) handleGetWorkerEvents(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
@@ -93,15 +194,33 @@ func (s *Server) handleGetWorkerEvents(w http.ResponseWriter, r *http.Request) {
 		workerID = &pathWorkerID
 	}
 
-	applicationID := parseQueryIntPtr(r.URL.Query().Get("applicationId"))
-	events, err := s.store.ListWorkerEvents(ctx, types.WorkerEventListRequest{
+	req := types.WorkerEventListRequest{
 		WorkerID:      workerID,
-		ApplicationID: applicationID,
+		ApplicationID: parseQueryIntPtr(r.URL.Query().Get("applicationId")),
+		MinLevel:      strings.TrimSpace(r.URL.Query().Get("level")),
+		EventType:     strings.TrimSpace(r.URL.Query().Get("eventType")),
 		Limit:         limit,
-	})
+	}
+	for _, param := range []struct {
+		name   string
+		cursor **types.WorkerEventCursor
+	}{{"before", &req.Before}, {"after", &req.After}} {
+		value := strings.TrimSpace(r.URL.Query().Get(param.name))
+		if value == "" {
+			continue
+		}
+		parsed, err := store.ParseWorkerEventCursor(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errCodeInvalidRequest, param.name+" must be a cursor or an RFC3339 timestamp")
+			return
+		}
+		*param.cursor = parsed
+	}
+
+	events, err := s.store.ListWorkerEvents(ctx, req)
 	if err != nil {
 		s.logger.Error("list worker events failed", "err", err)
-		http.Error(w, "failed to list worker events", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "failed to list worker events")
 		return
 	}
 
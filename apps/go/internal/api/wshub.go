@@ -1,44 +1,173 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool { return true },
+// wsAccessTTL is how long a client's access to an application is cached
+// before it is checked again.
+const wsAccessTTL = time.Minute
+
+// wsAccess checks which applications a user may receive updates of; the
+// store implements it.
+type wsAccess interface {
+	UserHasApplication(ctx context.Context, userID, appID int) (bool, error)
 }
 
-// Hub manages WebSocket connections and broadcasts messages to all clients.
+// Hub manages WebSocket connections and routes pipeline updates to the
+// clients subscribed to them.
 type Hub struct {
-	mu      sync.RWMutex
-	clients map[*Client]struct{}
-	logger  *slog.Logger
+	mu       sync.RWMutex
+	clients  map[*Client]*wsSubscription
+	access   wsAccess
+	upgrader websocket.Upgrader
+	logger   *slog.Logger
 }
 
 // Client wraps a single WebSocket connection.
 type Client struct {
-	hub  *Hub
-	conn *websocket.Conn
-	send chan []byte
+	hub    *Hub
+	conn   *websocket.Conn
+	send   chan []byte
+	userID int
+
+	accessMu sync.Mutex
+	apps     map[int]wsAppAccess
+}
+
+type wsAppAccess struct {
+	allowed   bool
+	checkedAt time.Time
 }
 
-func NewHub(logger *slog.Logger) *Hub {
+// canSee reports whether the client's user belongs to the application,
+// caching the answer for wsAccessTTL. Errors deny access without caching.
+func (c *Client) canSee(appID int) bool {
+	c.accessMu.Lock()
+	defer c.accessMu.Unlock()
+	if cached, ok := c.apps[appID]; ok && time.Since(cached.checkedAt) < wsAccessTTL {
+		return cached.allowed
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	allowed, err := c.hub.access.UserHasApplication(ctx, c.userID, appID)
+	if err != nil {
+		c.hub.logger.Warn("ws: check application access failed", "userId", c.userID, "applicationId", appID, "err", err)
+		return false
+	}
+	if c.apps == nil {
+		c.apps = map[int]wsAppAccess{}
+	}
+	c.apps[appID] = wsAppAccess{allowed: allowed, checkedAt: time.Now()}
+	return allowed
+}
+
+// wsSubscription holds the pipeline and application ids a client follows.
+// An empty subscription receives every update of the user's applications.
+type wsSubscription struct {
+	pipelines    map[int]struct{}
+	applications map[int]struct{}
+}
+
+func newWSSubscription() *wsSubscription {
+	return &wsSubscription{
+		pipelines:    map[int]struct{}{},
+		applications: map[int]struct{}{},
+	}
+}
+
+func (s *wsSubscription) empty() bool {
+	return len(s.pipelines) == 0 && len(s.applications) == 0
+}
+
+func (s *wsSubscription) matches(pipelineID int, applicationID *int) bool {
+	if s.empty() {
+		return true
+	}
+	if _, ok := s.pipelines[pipelineID]; ok {
+		return true
+	}
+	if applicationID != nil {
+		if _, ok := s.applications[*applicationID]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *wsSubscription) add(pipelineIDs, applicationIDs []int) {
+	for _, id := range pipelineIDs {
+		if id > 0 {
+			s.pipelines[id] = struct{}{}
+		}
+	}
+	for _, id := range applicationIDs {
+		if id > 0 {
+			s.applications[id] = struct{}{}
+		}
+	}
+}
+
+func (s *wsSubscription) remove(pipelineIDs, applicationIDs []int) {
+	for _, id := range pipelineIDs {
+		delete(s.pipelines, id)
+	}
+	for _, id := range applicationIDs {
+		delete(s.applications, id)
+	}
+}
+
+// wsClientMessage is sent by clients to change their subscription.
+type wsClientMessage struct {
+	Action         string `json:"action"`
+	PipelineIDs    []int  `json:"pipelineIds"`
+	ApplicationIDs []int  `json:"applicationIds"`
+}
+
+// wsSubscriptionMessage acknowledges the current subscription to the client.
+type wsSubscriptionMessage struct {
+	Type           string `json:"type"`
+	PipelineIDs    []int  `json:"pipelineIds"`
+	ApplicationIDs []int  `json:"applicationIds"`
+}
+
+// wsRoutingKey is the subset of the StageUpdated payload used for routing.
+type wsRoutingKey struct {
+	ID            int  `json:"id"`
+	ApplicationID *int `json:"applicationId"`
+}
+
+// NewHub creates a hub whose handshakes accept the API's own origin and
+// allowedOrigins; see originAllowed.
+func NewHub(access wsAccess, allowedOrigins []string, logger *slog.Logger) *Hub {
 	return &Hub{
-		clients: make(map[*Client]struct{}),
-		logger:  logger,
+		clients: make(map[*Client]*wsSubscription),
+		access:  access,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool {
+				return originAllowed(r, allowedOrigins)
+			},
+		},
+		logger: logger,
 	}
 }
 
-func (h *Hub) register(c *Client) {
+func (h *Hub) register(c *Client, sub *wsSubscription) {
 	h.mu.Lock()
-	h.clients[c] = struct{}{}
+	h.clients[c] = sub
 	h.mu.Unlock()
-	h.logger.Info("ws: client connected", "clients", h.clientCount())
+	h.logger.Info("ws: client connected", "clients", h.clientCount(), "userId", c.userID)
 }
 
 func (h *Hub) unregister(c *Client) {
@@ -48,7 +177,7 @@ func (h *Hub) unregister(c *Client) {
 		close(c.send)
 	}
 	h.mu.Unlock()
-	h.logger.Info("ws: client disconnected", "clients", h.clientCount())
+	h.logger.Info("ws: client disconnected", "clients", h.clientCount(), "userId", c.userID)
 }
 
 func (h *Hub) clientCount() int {
@@ -57,11 +186,74 @@ func (h *Hub) clientCount() int {
 	return len(h.clients)
 }
 
-// Broadcast sends a message to all connected clients.
+// updateSubscription applies a client message to the client's subscription
+// and returns the resulting acknowledgement payload.
+func (h *Hub) updateSubscription(c *Client, msg wsClientMessage) ([]byte, bool) {
+	h.mu.Lock()
+	sub, ok := h.clients[c]
+	if !ok {
+		h.mu.Unlock()
+		return nil, false
+	}
+	switch strings.ToLower(strings.TrimSpace(msg.Action)) {
+	case "subscribe":
+		sub.add(msg.PipelineIDs, msg.ApplicationIDs)
+	case "unsubscribe":
+		sub.remove(msg.PipelineIDs, msg.ApplicationIDs)
+	case "reset":
+		*sub = *newWSSubscription()
+	case "list":
+	default:
+		h.mu.Unlock()
+		return nil, false
+	}
+	ack := wsSubscriptionMessage{
+		Type:           "subscriptions",
+		PipelineIDs:    sortedIDs(sub.pipelines),
+		ApplicationIDs: sortedIDs(sub.applications),
+	}
+	h.mu.Unlock()
+
+	payload, err := json.Marshal(ack)
+	if err != nil {
+		return nil, false
+	}
+	return payload, true
+}
+
+// Broadcast routes a StageUpdated payload to the clients subscribed to its
+// pipeline or application whose user belongs to that application. Payloads
+// without a pipeline or application id are dropped.
 func (h *Hub) Broadcast(msg []byte) {
+	var key wsRoutingKey
+	if json.Unmarshal(msg, &key) != nil || key.ID <= 0 || key.ApplicationID == nil {
+		h.logger.Debug("ws: dropping unroutable payload")
+		return
+	}
+
+	h.mu.RLock()
+	var matched []*Client
+	for c, sub := range h.clients {
+		if sub.matches(key.ID, key.ApplicationID) {
+			matched = append(matched, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	// Access is checked outside the hub lock: a cache miss queries the store.
+	allowed := matched[:0]
+	for _, c := range matched {
+		if c.canSee(*key.ApplicationID) {
+			allowed = append(allowed, c)
+		}
+	}
+
 	h.mu.RLock()
 	defer h.mu.RUnlock()
-	for c := range h.clients {
+	for _, c := range allowed {
+		if _, ok := h.clients[c]; !ok {
+			continue
+		}
 		select {
 		case c.send <- msg:
 		default:
@@ -71,9 +263,10 @@ func (h *Hub) Broadcast(msg []byte) {
 }
 
 const (
-	writeWait  = 10 * time.Second
-	pongWait   = 60 * time.Second
-	pingPeriod = (pongWait * 9) / 10
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = (pongWait * 9) / 10
+	maxWSReadBytes = 16 << 10
 )
 
 func (c *Client) writePump() {
@@ -109,7 +302,7 @@ func (c *Client) readPump() {
 		c.conn.Close()
 	}()
 
-	c.conn.SetReadLimit(512)
+	c.conn.SetReadLimit(maxWSReadBytes)
 	_ = c.conn.SetReadDeadline(time.Now().Add(pongWait))
 	c.conn.SetPongHandler(func(string) error {
 		_ = c.conn.SetReadDeadline(time.Now().Add(pongWait))
@@ -117,28 +310,93 @@ func (c *Client) readPump() {
 	})
 
 	for {
-		_, _, err := c.conn.ReadMessage()
+		_, data, err := c.conn.ReadMessage()
 		if err != nil {
 			break
 		}
+		var msg wsClientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+		ack, ok := c.hub.updateSubscription(c, msg)
+		if !ok {
+			continue
+		}
+		select {
+		case c.send <- ack:
+		default:
+		}
 	}
 }
 
-// ServeWS handles a WebSocket upgrade request.
-func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+// ServeWS upgrades an authenticated request. The initial subscription can be
+// passed as pipelineIds/applicationIds query parameters and changed later
+// with subscribe/unsubscribe messages.
+func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request, userID int) {
+	query := r.URL.Query()
+	sub := newWSSubscription()
+	sub.add(parseWSIDs(query, "pipelineIds"), parseWSIDs(query, "applicationIds"))
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		h.logger.Error("ws: upgrade failed", "err", err)
 		return
 	}
 
 	client := &Client{
-		hub:  h,
-		conn: conn,
-		send: make(chan []byte, 256),
+		hub:    h,
+		conn:   conn,
+		send:   make(chan []byte, 256),
+		userID: userID,
 	}
-	h.register(client)
+	h.register(client, sub)
 
 	go client.writePump()
 	go client.readPump()
 }
+
+// originAllowed accepts requests without an Origin header (non-browser
+// clients), from the request's own host and from the allowed origins.
+func originAllowed(r *http.Request, allowedOrigins []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	normalized := strings.ToLower(u.Scheme + "://" + u.Host)
+	for _, allowed := range allowedOrigins {
+		if allowed == normalized {
+			return true
+		}
+	}
+	return false
+}
+
+// parseWSIDs accepts both repeated and comma-separated id parameters.
+func parseWSIDs(query url.Values, key string) []int {
+	var ids []int
+	for _, raw := range query[key] {
+		for _, part := range strings.Split(raw, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err == nil && id > 0 {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+func sortedIDs(set map[int]struct{}) []int {
+	ids := make([]int, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
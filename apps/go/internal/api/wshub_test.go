@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeWSAccess links users to applications: userID -> appIDs.
+type fakeWSAccess map[int][]int
+
+func (f fakeWSAccess) UserHasApplication(_ context.Context, userID, appID int) (bool, error) {
+	for _, id := range f[userID] {
+		if id == appID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestOriginAllowed(t *testing.T) {
+	allowed := []string{"https://dashboard.example.com"}
+	tests := []struct {
+		name   string
+		origin string
+		want   bool
+	}{
+		{"no origin", "", true},
+		{"same host", "http://api.example.com:8080", true},
+		{"allowed origin", "https://Dashboard.example.com", true},
+		{"other scheme of allowed host", "http://dashboard.example.com", false},
+		{"foreign origin", "https://evil.example", false},
+		{"null origin", "null", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://api.example.com:8080/ws", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if got := originAllowed(r, allowed); got != tt.want {
+				t.Fatalf("originAllowed(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServeWSRejectsForeignOrigin(t *testing.T) {
+	hub := NewHub(fakeWSAccess{}, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hub.ServeWS(w, r, 1)
+	}))
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Origin": {"https://evil.example"}})
+	if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Dial(foreign origin) = %v, %v, want 403", resp, err)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Origin": {srv.URL}})
+	if err != nil {
+		t.Fatalf("Dial(same origin) = %v", err)
+	}
+	_ = conn.Close()
+}
+
+func TestHubBroadcastFiltersByApplicationAccess(t *testing.T) {
+	hub := NewHub(fakeWSAccess{1: {10}, 2: {20}}, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	newClient := func(userID int, sub *wsSubscription) *Client {
+		c := &Client{hub: hub, send: make(chan []byte, 4), userID: userID}
+		hub.register(c, sub)
+		return c
+	}
+	subscribed := func(pipelineIDs, applicationIDs []int) *wsSubscription {
+		sub := newWSSubscription()
+		sub.add(pipelineIDs, applicationIDs)
+		return sub
+	}
+
+	owner := newClient(1, newWSSubscription())
+	outsider := newClient(2, newWSSubscription())
+	// Subscribing to another user's pipeline or application grants nothing.
+	snooper := newClient(2, subscribed([]int{5}, []int{10}))
+
+	hub.Broadcast([]byte(`{"id":5,"applicationId":10}`))
+	hub.Broadcast([]byte(`{"id":6}`))
+	hub.Broadcast([]byte(`not json`))
+
+	if got := len(owner.send); got != 1 {
+		t.Fatalf("owner received %d updates, want 1", got)
+	}
+	if got := len(outsider.send); got != 0 {
+		t.Fatalf("outsider received %d updates, want 0", got)
+	}
+	if got := len(snooper.send); got != 0 {
+		t.Fatalf("snooper received %d updates, want 0", got)
+	}
+
+	// A subscription still narrows the user's own updates.
+	narrowed := newClient(1, subscribed([]int{7}, nil))
+	hub.Broadcast([]byte(`{"id":5,"applicationId":10}`))
+	hub.Broadcast([]byte(`{"id":7,"applicationId":10}`))
+	if got := len(narrowed.send); got != 1 {
+		t.Fatalf("narrowed client received %d updates, want 1", got)
+	}
+}
@@ -2,6 +2,9 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -16,43 +19,128 @@ const (
 )
 
 type Common struct {
-	AppID        string
-	DatabaseURL  string
-	RabbitURL    string
-	LogLevel     string
-	MetricsAddr  string
-	PublishRetry struct {
+	AppID             string
+	DatabaseURL       string
+	RabbitURL         string
+	LogLevel          string
+	LogFormat         string // "json" or "text"
+	MetricsAddr       string
+	ResultQueueShards int
+	PublishConfirms   bool
+	// MetricsToken and MetricsAllowedCIDRs guard /metrics on the API and the
+	// worker metrics server; see telemetry.GuardMetrics. Both are unset by
+	// default, leaving metrics open to in-cluster scrapers.
+	MetricsToken        string
+	MetricsAllowedCIDRs []netip.Prefix
+	// APIKeyExpiryWarnWithin is how far ahead API key expiry is reported: the
+	// worker alerts on keys expiring within it and GET /apiKeys/expiring
+	// defaults to it.
+	APIKeyExpiryWarnWithin time.Duration
+	// RateLimitStore selects where rate limit windows are counted: "memory"
+	// (per process) or "redis" (shared by all replicas through RedisURL).
+	RateLimitStore string
+	RedisURL       string
+	// QueueType, QueueMaxLength and QueueOverflow are declared on the stage
+	// pipeline queues. They are opt-in: RabbitMQ refuses to redeclare an
+	// existing queue with different arguments.
+	QueueType      string
+	QueueMaxLength int
+	QueueOverflow  string
+	// StageEnvironmentRouting publishes the stages of a pipeline with an
+	// environment to a StageNext queue of that environment. It is opt-in:
+	// workers consuming only the default queues never see those stages.
+	StageEnvironmentRouting bool
+	PublishRetry            struct {
 		Base time.Duration
 		Max  time.Duration
 	}
+	Reconnect struct {
+		InitialInterval time.Duration
+		MaxInterval     time.Duration
+		MaxElapsedTime  time.Duration
+		Delay           time.Duration
+		Jitter          float64
+	}
 }
 
 type APIConfig struct {
 	Common
-	HTTPAddr                string
-	ExternalHTTPAddr        string
-	GatewayVisibilityTTL    time.Duration
-	GatewayMaxInFlight      int
-	QueuePrefetch           int
-	QueueTopologyOwnership  string
-	QueueDLQEnabled         bool
-	QueueDLQMessageTTL      time.Duration
-	WorkerHeartbeatInterval time.Duration
-	WorkerOfflineAfter      time.Duration
-	WorkerSessionTTL        time.Duration
-	WorkerEventsMaxBatch    int
-	HealthLivenessEndpoint  string
-	HealthReadyEndpoint     string
+	HTTPAddr                    string
+	ExternalHTTPAddr            string
+	GatewayVisibilityTTL        time.Duration
+	GatewayMaxInFlight          int
+	QueuePrefetch               int
+	QueueTopologyOwnership      string
+	QueueDLQEnabled             bool
+	QueueDLQMessageTTL          time.Duration
+	RabbitExposeURL             bool
+	RabbitWorkerUsername        string
+	RabbitWorkerPassword        string
+	RabbitWorkerVHost           string
+	WorkerHeartbeatInterval     time.Duration
+	WorkerOfflineAfter          time.Duration
+	WorkerSessionTTL            time.Duration
+	WorkerEventsMaxBatch        int
+	WorkerListStaleAfter        time.Duration
+	HealthLivenessEndpoint      string
+	HealthReadyEndpoint         string
+	ExternalRateLimitRPS        int
+	ExternalRateLimitBurst      int
+	PipelineStrictHandlers      bool
+	AccessLogLevel              string
+	AccessLogBodySize           bool
+	PolicyTargetOptionsLimit    int
+	PolicyTargetOptionsCacheTTL time.Duration
+	PolicyPreviewEnvMatch       string
+	IntegrationCheckInterval    time.Duration
+	WorkerGRPCAddr              string
+	IngestMaxBodyBytes          int
+	// GatewayPullPrefetch bounds how many messages POST /jobs/pull takes from
+	// a stage handler queue in one broker delivery; GatewayQueuePrefetch
+	// overrides it per queue name.
+	GatewayPullPrefetch  int
+	GatewayQueuePrefetch map[string]int
+	// CORSAllowedOrigins are the browser origins, besides the API's own, that
+	// may call the internal API with credentials and open /ws.
+	CORSAllowedOrigins []string
+	// GatewayAllowAnonymous lets POST /jobs/pull, /jobs/ack and /jobs/extend
+	// be called without an API key, as before API key scopes existed.
+	GatewayAllowAnonymous bool
 }
 
 type WorkerConfig struct {
 	Common
 	PollInterval           time.Duration
+	PublishBatchSize       int
+	PublishFanout          int
 	StagePendingTimeout    time.Duration
+	PipelineStuckAfter     time.Duration
+	APIKeyExpiryInterval   time.Duration
+	WorkerOfflineAfter     time.Duration
+	StageOutputMaxBytes    int
 	Prefetch               int
 	QueueTopologyOwnership string
 	QueueDLQEnabled        bool
 	QueueDLQMessageTTL     time.Duration
+	WorkerRetention        time.Duration
+	WorkerRetentionEvery   time.Duration
+	DeadLetterRetention    time.Duration
+	QueueMonitorInterval   time.Duration
+	QueueBacklogThreshold  int
+	DrainTimeout           time.Duration
+	StageLeaseGrace        time.Duration
+	StageMaxAttempts       int
+	// StageResultPrefetch and StageStatusPrefetch are the prefetch of the
+	// StageResult and StageSetStatus consumers; both default to Prefetch.
+	StageResultPrefetch int
+	StageStatusPrefetch int
+	// StageSLACheckInterval is how often in-flight stages are checked against
+	// their expected duration; a stage breaches once it has run longer than
+	// StageSLABreachFactor times its expectedDurationMs or, without one, the
+	// median of its handler's last StageSLABaselineSamples completed runs.
+	StageSLACheckInterval   time.Duration
+	StageSLABreachFactor    float64
+	StageSLABaselineSamples int
 }
 
 func LoadAPI() (APIConfig, error) {
@@ -62,21 +150,63 @@ func LoadAPI() (APIConfig, error) {
 	}
 
 	cfg := APIConfig{
-		Common:                  common,
-		HTTPAddr:                getEnv("HTTP_ADDR", ":8080"),
-		ExternalHTTPAddr:        getEnv("EXTERNAL_HTTP_ADDR", ":8081"),
-		GatewayVisibilityTTL:    getDuration("GATEWAY_VISIBILITY_TIMEOUT", time.Minute),
-		GatewayMaxInFlight:      getInt("GATEWAY_MAX_INFLIGHT", 128),
-		QueuePrefetch:           getInt("RABBIT_PREFETCH", 10),
-		QueueTopologyOwnership:  getTopologyOwnership("RABBIT_TOPOLOGY_OWNERSHIP", TopologyOwnershipServer),
-		QueueDLQEnabled:         getBool("RABBIT_DLQ_ENABLED", true),
-		QueueDLQMessageTTL:      getDuration("RABBIT_DLQ_TTL", 30*time.Second),
-		WorkerHeartbeatInterval: getDuration("WORKER_HEARTBEAT_INTERVAL", 15*time.Second),
-		WorkerOfflineAfter:      getDuration("WORKER_OFFLINE_AFTER", 45*time.Second),
-		WorkerSessionTTL:        getDuration("WORKER_SESSION_TTL", 24*time.Hour),
-		WorkerEventsMaxBatch:    getInt("WORKER_EVENTS_MAX_BATCH", 200),
-		HealthLivenessEndpoint:  getEnv("HEALTH_LIVENESS_PATH", "/healthz"),
-		HealthReadyEndpoint:     getEnv("HEALTH_READY_PATH", "/readyz"),
+		Common:                      common,
+		HTTPAddr:                    getEnv("HTTP_ADDR", ":8080"),
+		ExternalHTTPAddr:            getEnv("EXTERNAL_HTTP_ADDR", ":8081"),
+		GatewayVisibilityTTL:        getDuration("GATEWAY_VISIBILITY_TIMEOUT", time.Minute),
+		GatewayMaxInFlight:          getInt("GATEWAY_MAX_INFLIGHT", 128),
+		QueuePrefetch:               getInt("RABBIT_PREFETCH", 10),
+		QueueTopologyOwnership:      getTopologyOwnership("RABBIT_TOPOLOGY_OWNERSHIP", TopologyOwnershipServer),
+		QueueDLQEnabled:             getBool("RABBIT_DLQ_ENABLED", true),
+		QueueDLQMessageTTL:          getDuration("RABBIT_DLQ_TTL", 30*time.Second),
+		RabbitExposeURL:             getBool("RABBIT_EXPOSE_URL", true),
+		RabbitWorkerUsername:        getEnv("RABBIT_WORKER_USERNAME", ""),
+		RabbitWorkerPassword:        getEnv("RABBIT_WORKER_PASSWORD", ""),
+		RabbitWorkerVHost:           getEnv("RABBIT_WORKER_VHOST", ""),
+		WorkerHeartbeatInterval:     getDuration("WORKER_HEARTBEAT_INTERVAL", 15*time.Second),
+		WorkerOfflineAfter:          getDuration("WORKER_OFFLINE_AFTER", 45*time.Second),
+		WorkerSessionTTL:            getDuration("WORKER_SESSION_TTL", 24*time.Hour),
+		WorkerEventsMaxBatch:        getInt("WORKER_EVENTS_MAX_BATCH", 200),
+		WorkerListStaleAfter:        getDuration("WORKER_LIST_STALE_AFTER", 24*time.Hour),
+		HealthLivenessEndpoint:      getEnv("HEALTH_LIVENESS_PATH", "/healthz"),
+		HealthReadyEndpoint:         getEnv("HEALTH_READY_PATH", "/readyz"),
+		ExternalRateLimitRPS:        getInt("EXTERNAL_RATE_LIMIT_RPS", 50),
+		ExternalRateLimitBurst:      getInt("EXTERNAL_RATE_LIMIT_BURST", 100),
+		PipelineStrictHandlers:      getBool("PIPELINE_STRICT_HANDLERS", false),
+		AccessLogLevel:              strings.ToLower(getEnv("ACCESS_LOG_LEVEL", "info")),
+		AccessLogBodySize:           getBool("ACCESS_LOG_BODY_SIZE", false),
+		PolicyTargetOptionsLimit:    getInt("POLICY_TARGET_OPTIONS_LIMIT", 500),
+		PolicyTargetOptionsCacheTTL: getDuration("POLICY_TARGET_OPTIONS_CACHE_TTL", 5*time.Second),
+		PolicyPreviewEnvMatch:       strings.ToLower(getEnv("POLICY_PREVIEW_ENV_MATCH", "exact")),
+		IntegrationCheckInterval:    getDuration("INTEGRATION_CHECK_INTERVAL", 5*time.Minute),
+		WorkerGRPCAddr:              strings.TrimSpace(getEnv("WORKER_GRPC_ADDR", "")),
+		IngestMaxBodyBytes:          getInt("INGEST_MAX_BODY_BYTES", 4<<20),
+		GatewayPullPrefetch:         getInt("GATEWAY_PULL_PREFETCH", 1),
+		GatewayAllowAnonymous:       getBool("GATEWAY_ALLOW_ANONYMOUS", false),
+	}
+	if cfg.PolicyTargetOptionsLimit < 1 {
+		return APIConfig{}, fmt.Errorf("POLICY_TARGET_OPTIONS_LIMIT must be positive, got %d", cfg.PolicyTargetOptionsLimit)
+	}
+	if cfg.PolicyPreviewEnvMatch != "exact" && cfg.PolicyPreviewEnvMatch != "contains" {
+		return APIConfig{}, fmt.Errorf("POLICY_PREVIEW_ENV_MATCH must be exact or contains, got %q", cfg.PolicyPreviewEnvMatch)
+	}
+	if cfg.IntegrationCheckInterval < 0 {
+		return APIConfig{}, fmt.Errorf("INTEGRATION_CHECK_INTERVAL must not be negative, got %s", cfg.IntegrationCheckInterval)
+	}
+	if cfg.IngestMaxBodyBytes < 1 {
+		return APIConfig{}, fmt.Errorf("INGEST_MAX_BODY_BYTES must be positive, got %d", cfg.IngestMaxBodyBytes)
+	}
+	if cfg.QueuePrefetch < 1 {
+		return APIConfig{}, fmt.Errorf("RABBIT_PREFETCH must be positive, got %d", cfg.QueuePrefetch)
+	}
+	if cfg.GatewayPullPrefetch < 1 {
+		return APIConfig{}, fmt.Errorf("GATEWAY_PULL_PREFETCH must be positive, got %d", cfg.GatewayPullPrefetch)
+	}
+	if cfg.GatewayQueuePrefetch, err = parseQueuePrefetch(getEnv("GATEWAY_QUEUE_PREFETCH", "")); err != nil {
+		return APIConfig{}, fmt.Errorf("GATEWAY_QUEUE_PREFETCH must be a comma-separated list of queue=prefetch: %w", err)
+	}
+	if cfg.CORSAllowedOrigins, err = parseOrigins(getEnv("CORS_ALLOWED_ORIGINS", "")); err != nil {
+		return APIConfig{}, fmt.Errorf("CORS_ALLOWED_ORIGINS must be a comma-separated list of scheme://host[:port] origins: %w", err)
 	}
 
 	return cfg, nil
@@ -91,11 +221,54 @@ func LoadWorker() (WorkerConfig, error) {
 	cfg := WorkerConfig{
 		Common:                 common,
 		PollInterval:           getDuration("WORKER_POLL_INTERVAL", time.Second),
+		PublishBatchSize:       getInt("WORKER_PUBLISH_BATCH_SIZE", 10),
+		PublishFanout:          getInt("WORKER_PUBLISH_FANOUT", 4),
 		StagePendingTimeout:    getDuration("STAGE_PENDING_TIMEOUT", 5*time.Minute),
+		PipelineStuckAfter:     getDuration("PIPELINE_STUCK_AFTER", 30*time.Minute),
+		APIKeyExpiryInterval:   getDuration("API_KEY_EXPIRY_CHECK_INTERVAL", time.Hour),
+		WorkerOfflineAfter:     getDuration("WORKER_OFFLINE_AFTER", 45*time.Second),
+		StageOutputMaxBytes:    getInt("STAGE_OUTPUT_MAX_BYTES", 1<<20),
 		Prefetch:               getInt("RABBIT_PREFETCH", 5),
 		QueueTopologyOwnership: getTopologyOwnership("RABBIT_TOPOLOGY_OWNERSHIP", TopologyOwnershipServer),
 		QueueDLQEnabled:        getBool("RABBIT_DLQ_ENABLED", true),
 		QueueDLQMessageTTL:     getDuration("RABBIT_DLQ_TTL", 30*time.Second),
+		WorkerRetention:        getDuration("WORKER_RETENTION", 7*24*time.Hour),
+		WorkerRetentionEvery:   getDuration("WORKER_RETENTION_INTERVAL", time.Hour),
+		DeadLetterRetention:    getDuration("DEAD_LETTER_RETENTION", 30*24*time.Hour),
+		QueueMonitorInterval:   getDuration("QUEUE_MONITOR_INTERVAL", 30*time.Second),
+		QueueBacklogThreshold:  getInt("QUEUE_BACKLOG_THRESHOLD", 1000),
+		DrainTimeout:           getDuration("WORKER_DRAIN_TIMEOUT", 25*time.Second),
+		StageLeaseGrace:        getDuration("STAGE_LEASE_GRACE", time.Minute),
+		StageMaxAttempts:       getInt("STAGE_MAX_ATTEMPTS", 25),
+	}
+	cfg.StageResultPrefetch = getInt("RABBIT_PREFETCH_STAGE_RESULT", cfg.Prefetch)
+	cfg.StageStatusPrefetch = getInt("RABBIT_PREFETCH_STAGE_SET_STATUS", cfg.Prefetch)
+	cfg.StageSLACheckInterval = getDuration("STAGE_SLA_CHECK_INTERVAL", time.Minute)
+	cfg.StageSLABreachFactor = getFloat("STAGE_SLA_BREACH_FACTOR", 2)
+	cfg.StageSLABaselineSamples = getInt("STAGE_SLA_BASELINE_SAMPLES", 50)
+	if cfg.StageLeaseGrace < 0 {
+		return WorkerConfig{}, fmt.Errorf("STAGE_LEASE_GRACE must not be negative, got %s", cfg.StageLeaseGrace)
+	}
+	if cfg.StageMaxAttempts < 0 {
+		return WorkerConfig{}, fmt.Errorf("STAGE_MAX_ATTEMPTS must not be negative, got %d", cfg.StageMaxAttempts)
+	}
+	if cfg.Prefetch < 1 {
+		return WorkerConfig{}, fmt.Errorf("RABBIT_PREFETCH must be positive, got %d", cfg.Prefetch)
+	}
+	if cfg.StageResultPrefetch < 1 {
+		return WorkerConfig{}, fmt.Errorf("RABBIT_PREFETCH_STAGE_RESULT must be positive, got %d", cfg.StageResultPrefetch)
+	}
+	if cfg.StageStatusPrefetch < 1 {
+		return WorkerConfig{}, fmt.Errorf("RABBIT_PREFETCH_STAGE_SET_STATUS must be positive, got %d", cfg.StageStatusPrefetch)
+	}
+	if cfg.StageSLACheckInterval < 0 {
+		return WorkerConfig{}, fmt.Errorf("STAGE_SLA_CHECK_INTERVAL must not be negative, got %s", cfg.StageSLACheckInterval)
+	}
+	if cfg.StageSLABreachFactor < 1 {
+		return WorkerConfig{}, fmt.Errorf("STAGE_SLA_BREACH_FACTOR must be at least 1, got %g", cfg.StageSLABreachFactor)
+	}
+	if cfg.StageSLABaselineSamples < 1 {
+		return WorkerConfig{}, fmt.Errorf("STAGE_SLA_BASELINE_SAMPLES must be positive, got %d", cfg.StageSLABaselineSamples)
 	}
 
 	return cfg, nil
@@ -136,16 +309,69 @@ func loadCommon() (Common, error) {
 	}
 
 	logLevel := strings.ToLower(getEnv("LOG_LEVEL", "info"))
+	switch logLevel {
+	case "debug", "info", "warn", "warning", "error":
+	default:
+		return Common{}, fmt.Errorf("LOG_LEVEL must be debug, info, warn or error, got %q", logLevel)
+	}
+	logFormat := strings.ToLower(strings.TrimSpace(getEnv("LOG_FORMAT", "json")))
+	if logFormat != "json" && logFormat != "text" {
+		return Common{}, fmt.Errorf("LOG_FORMAT must be json or text, got %q", logFormat)
+	}
 
 	common := Common{
-		AppID:       appID,
-		DatabaseURL: dbURL,
-		RabbitURL:   rabbitURL,
-		LogLevel:    logLevel,
-		MetricsAddr: getEnv("METRICS_ADDR", ""),
+		AppID:             appID,
+		DatabaseURL:       dbURL,
+		RabbitURL:         rabbitURL,
+		LogLevel:          logLevel,
+		LogFormat:         logFormat,
+		MetricsAddr:       getEnv("METRICS_ADDR", ""),
+		ResultQueueShards: getInt("RESULT_QUEUE_SHARDS", 1),
+		PublishConfirms:   getBool("RABBIT_PUBLISHER_CONFIRMS", false),
+	}
+	common.MetricsToken = strings.TrimSpace(getEnv("METRICS_BEARER_TOKEN", ""))
+	allowed, err := parseIPAllowlist(getEnv("METRICS_ALLOWED_CIDRS", ""))
+	if err != nil {
+		return Common{}, fmt.Errorf("METRICS_ALLOWED_CIDRS must be a comma-separated list of IPs or CIDRs: %w", err)
+	}
+	common.MetricsAllowedCIDRs = allowed
+	common.APIKeyExpiryWarnWithin = getDuration("API_KEY_EXPIRY_WARN_WITHIN", 7*24*time.Hour)
+	common.RateLimitStore = strings.ToLower(strings.TrimSpace(getEnv("RATE_LIMIT_STORE", "memory")))
+	common.RedisURL = getEnv("REDIS_URL", "")
+	switch common.RateLimitStore {
+	case "memory":
+	case "redis":
+		if common.RedisURL == "" {
+			return Common{}, errors.New("REDIS_URL is required when RATE_LIMIT_STORE=redis")
+		}
+	default:
+		return Common{}, fmt.Errorf("RATE_LIMIT_STORE must be memory or redis, got %q", common.RateLimitStore)
+	}
+	common.QueueType = strings.ToLower(strings.TrimSpace(getEnv("RABBIT_QUEUE_TYPE", "")))
+	common.QueueMaxLength = getInt("RABBIT_QUEUE_MAX_LENGTH", 0)
+	common.QueueOverflow = strings.ToLower(strings.TrimSpace(getEnv("RABBIT_QUEUE_OVERFLOW", "")))
+	switch common.QueueType {
+	case "", "classic", "quorum":
+	default:
+		return Common{}, fmt.Errorf("RABBIT_QUEUE_TYPE must be classic or quorum, got %q", common.QueueType)
 	}
+	switch common.QueueOverflow {
+	case "", "drop-head", "reject-publish":
+	case "reject-publish-dlx":
+		if common.QueueType == "quorum" {
+			return Common{}, errors.New("RABBIT_QUEUE_OVERFLOW=reject-publish-dlx is not supported by quorum queues")
+		}
+	default:
+		return Common{}, fmt.Errorf("RABBIT_QUEUE_OVERFLOW must be drop-head, reject-publish or reject-publish-dlx, got %q", common.QueueOverflow)
+	}
+	common.StageEnvironmentRouting = getBool("STAGE_ENVIRONMENT_ROUTING", false)
 	common.PublishRetry.Base = getDuration("RABBIT_RETRY_BASE", 500*time.Millisecond)
 	common.PublishRetry.Max = getDuration("RABBIT_RETRY_MAX", 30*time.Second)
+	common.Reconnect.InitialInterval = getDuration("RABBIT_RECONNECT_INITIAL_INTERVAL", 500*time.Millisecond)
+	common.Reconnect.MaxInterval = getDuration("RABBIT_RECONNECT_MAX_INTERVAL", 30*time.Second)
+	common.Reconnect.MaxElapsedTime = getDuration("RABBIT_RECONNECT_MAX_ELAPSED", 0)
+	common.Reconnect.Delay = getDuration("RABBIT_RECONNECT_DELAY", time.Second)
+	common.Reconnect.Jitter = getFloat("RABBIT_RECONNECT_JITTER", 0.5)
 
 	return common, nil
 }
@@ -175,6 +401,15 @@ func getBool(key string, def bool) bool {
 	return def
 }
 
+func getFloat(key string, def float64) float64 {
+	if val := os.Getenv(key); val != "" {
+		if parsed, err := strconv.ParseFloat(val, 64); err == nil {
+			return parsed
+		}
+	}
+	return def
+}
+
 func getDuration(key string, def time.Duration) time.Duration {
 	if val := os.Getenv(key); val != "" {
 		if d, err := time.ParseDuration(val); err == nil {
@@ -199,6 +434,74 @@ func getTopologyOwnership(key, def string) string {
 	}
 }
 
+// parseIPAllowlist parses a comma-separated list of IP addresses and CIDR
+// ranges. A bare address allows that single host.
+func parseIPAllowlist(raw string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if strings.Contains(item, "/") {
+			prefix, err := netip.ParsePrefix(item)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(item)
+		if err != nil {
+			return nil, err
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// parseQueuePrefetch parses a comma-separated list of queue=prefetch pairs
+// with positive prefetch values.
+func parseQueuePrefetch(raw string) (map[string]int, error) {
+	prefetch := map[string]int{}
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		queue, value, ok := strings.Cut(item, "=")
+		queue = strings.TrimSpace(queue)
+		if !ok || queue == "" {
+			return nil, fmt.Errorf("%q is not queue=prefetch", item)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("prefetch of %s must be a positive integer, got %q", queue, value)
+		}
+		prefetch[queue] = n
+	}
+	return prefetch, nil
+}
+
+// parseOrigins parses a comma-separated list of origins and normalizes them
+// to lowercase scheme://host[:port].
+func parseOrigins(raw string) ([]string, error) {
+	var origins []string
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		u, err := url.Parse(item)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.Trim(u.Path, "/") != "" {
+			return nil, fmt.Errorf("%q is not an origin", item)
+		}
+		origins = append(origins, strings.ToLower(u.Scheme+"://"+u.Host))
+	}
+	return origins, nil
+}
+
 func firstNonEmpty(values ...string) string {
 	for _, v := range values {
 		if strings.TrimSpace(v) != "" {
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestParseQueuePrefetch(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    map[string]int
+		wantErr bool
+	}{
+		{"", map[string]int{}, false},
+		{"stage-a=4", map[string]int{"stage-a": 4}, false},
+		{" stage-a = 4 , stage-b=1,, ", map[string]int{"stage-a": 4, "stage-b": 1}, false},
+		{"stage-a=4,stage-a=2", map[string]int{"stage-a": 2}, false},
+		{"stage-a", nil, true},
+		{"=4", nil, true},
+		{"stage-a=0", nil, true},
+		{"stage-a=-1", nil, true},
+		{"stage-a=many", nil, true},
+		{"stage-a=", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseQueuePrefetch(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("parseQueuePrefetch(%q) error = %v, want error %v", tt.raw, err, tt.wantErr)
+		}
+		if !tt.wantErr && !maps.Equal(got, tt.want) {
+			t.Fatalf("parseQueuePrefetch(%q) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestLoadCommonLogSettings(t *testing.T) {
+	tests := []struct {
+		level, format string
+		wantErr       bool
+	}{
+		{"", "", false},
+		{"DEBUG", "Text", false},
+		{"warning", "json", false},
+		{"verbose", "json", true},
+		{"info", "logfmt", true},
+	}
+	for _, tt := range tests {
+		t.Setenv("APP_ID", "app-1")
+		t.Setenv("LOG_LEVEL", tt.level)
+		t.Setenv("LOG_FORMAT", tt.format)
+		_, err := loadCommon()
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("loadCommon() with LOG_LEVEL=%q LOG_FORMAT=%q: error = %v, want error %v", tt.level, tt.format, err, tt.wantErr)
+		}
+	}
+}
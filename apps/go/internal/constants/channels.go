@@ -7,4 +7,5 @@ const (
 	StageUpdated   = "StageUpdated"
 	StopPipeline   = "StopPipeline"
 	StageSetStatus = "StageSetStatus"
+	PolicyTrigger  = "PolicyTriggered"
 )
@@ -0,0 +1,161 @@
+// Package datadog forwards Pipelogiq metrics and events to Datadog when the
+// Datadog observability integration is connected.
+package datadog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSite        = "datadoghq.com"
+	defaultHTTPTimeout = 10 * time.Second
+
+	// Series metric types accepted by /api/v2/series.
+	metricTypeCount = 1
+	metricTypeGauge = 3
+)
+
+// Client talks to the Datadog HTTP intake for one site.
+type Client struct {
+	apiURL  string
+	logsURL string
+	apiKey  string
+	http    *http.Client
+}
+
+// NewClient returns a client for site (for example "datadoghq.eu" or
+// "us5.datadoghq.com"). A site given as an absolute URL is used as-is for
+// both the API and log intake, which allows proxies and local testing.
+func NewClient(site, apiKey string) *Client {
+	apiURL, logsURL := siteURLs(site)
+	return &Client{
+		apiURL:  apiURL,
+		logsURL: logsURL,
+		apiKey:  strings.TrimSpace(apiKey),
+		http:    &http.Client{Timeout: defaultHTTPTimeout},
+	}
+}
+
+func siteURLs(site string) (string, string) {
+	site = strings.TrimRight(strings.TrimSpace(site), "/")
+	if strings.HasPrefix(site, "http://") || strings.HasPrefix(site, "https://") {
+		return site, site
+	}
+	site = strings.TrimPrefix(site, "api.")
+	site = strings.TrimPrefix(site, "app.")
+	if site == "" {
+		site = defaultSite
+	}
+	return "https://api." + site, "https://http-intake.logs." + site
+}
+
+// Series is one metric submitted to /api/v2/series.
+type Series struct {
+	Metric   string   `json:"metric"`
+	Type     int      `json:"type"`
+	Interval int64    `json:"interval,omitempty"`
+	Points   []Point  `json:"points"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+type Point struct {
+	Timestamp int64   `json:"timestamp"`
+	Value     float64 `json:"value"`
+}
+
+// LogEntry is one event submitted to the log intake.
+type LogEntry struct {
+	Source   string `json:"ddsource"`
+	Tags     string `json:"ddtags,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+	Service  string `json:"service"`
+	Status   string `json:"status"`
+	Message  string `json:"message"`
+}
+
+// Validate checks the API key against /api/v1/validate.
+func (c *Client) Validate(ctx context.Context) error {
+	if c.apiKey == "" {
+		return errors.New("datadog apiKey is required")
+	}
+	resp, err := c.do(ctx, http.MethodGet, c.apiURL+"/api/v1/validate", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusUnauthorized:
+		return errors.New("datadog rejected the API key")
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("datadog validate returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Valid bool `json:"valid"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&body); err != nil {
+		return fmt.Errorf("decode datadog validate response: %w", err)
+	}
+	if !body.Valid {
+		return errors.New("datadog rejected the API key")
+	}
+	return nil
+}
+
+// SubmitSeries posts metrics to /api/v2/series.
+func (c *Client) SubmitSeries(ctx context.Context, series []Series) error {
+	if len(series) == 0 {
+		return nil
+	}
+	return c.post(ctx, c.apiURL+"/api/v2/series", map[string]any{"series": series})
+}
+
+// SubmitLogs posts events to the log intake.
+func (c *Client) SubmitLogs(ctx context.Context, entries []LogEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return c.post(ctx, c.logsURL+"/api/v2/logs", entries)
+}
+
+func (c *Client) post(ctx context.Context, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("datadog returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, method, url string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("DD-API-KEY", c.apiKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.http.Do(req)
+}
@@ -0,0 +1,322 @@
+package datadog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	observabilitymodel "pipelogiq/internal/observability/model"
+	observabilityrepo "pipelogiq/internal/observability/repo"
+	"pipelogiq/internal/store"
+	"pipelogiq/internal/types"
+)
+
+const (
+	flushInterval   = 30 * time.Second
+	configCacheTTL  = 5 * time.Second
+	metricNamespace = "pipelogiq."
+	logSource       = "pipelogiq"
+)
+
+// forwardedMetricPrefixes selects the stage and pipeline metrics sent to
+// Datadog from the process's Prometheus registry.
+var forwardedMetricPrefixes = []string{"stage_", "pipeline_", "pending_", "ext_stage_", "ext_pipeline_"}
+
+// Forwarder periodically submits stage and pipeline metrics to Datadog and,
+// when sendLogs is enabled, forwards stage and worker events as logs. It does
+// nothing unless the Datadog integration has passed its connection test.
+type Forwarder struct {
+	repo     observabilityrepo.Repository
+	gatherer prometheus.Gatherer
+	service  string
+	hostname string
+	logger   *slog.Logger
+
+	mu          sync.Mutex
+	cachedCfg   runtimeConfig
+	cacheLoaded time.Time
+	// counters holds the last cumulative value of each counter series so
+	// that only the increase since the previous flush is submitted.
+	counters map[string]float64
+}
+
+type runtimeConfig struct {
+	enabled  bool
+	site     string
+	apiKey   string
+	sendLogs bool
+	tags     []string
+}
+
+var _ store.AlertSink = (*Forwarder)(nil)
+
+func New(repo observabilityrepo.Repository, gatherer prometheus.Gatherer, service string, logger *slog.Logger) *Forwarder {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	hostname, _ := os.Hostname()
+	return &Forwarder{
+		repo:     repo,
+		gatherer: gatherer,
+		service:  service,
+		hostname: hostname,
+		logger:   logger,
+		counters: make(map[string]float64),
+	}
+}
+
+// Run flushes metrics every flushInterval until ctx is cancelled.
+func (f *Forwarder) Run(ctx context.Context) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := f.flush(ctx, now); err != nil {
+				f.logger.Warn("datadog metrics flush failed", "err", err)
+			}
+		}
+	}
+}
+
+func (f *Forwarder) flush(ctx context.Context, now time.Time) error {
+	cfg, err := f.loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if !cfg.enabled {
+		// Re-baseline counters when forwarding is turned back on.
+		f.mu.Lock()
+		clear(f.counters)
+		f.mu.Unlock()
+		return nil
+	}
+
+	families, err := f.gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("gather metrics: %w", err)
+	}
+	series := f.buildSeries(families, cfg.tags, now)
+	return NewClient(cfg.site, cfg.apiKey).SubmitSeries(ctx, series)
+}
+
+func (f *Forwarder) buildSeries(families []*dto.MetricFamily, extraTags []string, now time.Time) []Series {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ts := now.Unix()
+	interval := int64(flushInterval / time.Second)
+	var series []Series
+	for _, family := range families {
+		name := family.GetName()
+		if !forwarded(name) {
+			continue
+		}
+		metric := metricNamespace + strings.TrimSuffix(name, "_total")
+		for _, m := range family.GetMetric() {
+			tags := f.seriesTags(m.GetLabel(), extraTags)
+			key := name + "{" + strings.Join(tags, ",") + "}"
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				if delta, ok := f.counterDelta(key, m.GetCounter().GetValue()); ok {
+					series = append(series, countSeries(metric, delta, interval, ts, tags))
+				}
+			case dto.MetricType_GAUGE:
+				series = append(series, Series{
+					Metric: metric,
+					Type:   metricTypeGauge,
+					Points: []Point{{Timestamp: ts, Value: m.GetGauge().GetValue()}},
+					Tags:   tags,
+				})
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				if delta, ok := f.counterDelta(key+".count", float64(h.GetSampleCount())); ok {
+					series = append(series, countSeries(metric+".count", delta, interval, ts, tags))
+				}
+				if delta, ok := f.counterDelta(key+".sum", h.GetSampleSum()); ok {
+					series = append(series, countSeries(metric+".sum", delta, interval, ts, tags))
+				}
+			}
+		}
+	}
+	return series
+}
+
+// counterDelta records value and returns the increase since the previous
+// flush. The first observation only sets the baseline.
+func (f *Forwarder) counterDelta(key string, value float64) (float64, bool) {
+	prev, seen := f.counters[key]
+	f.counters[key] = value
+	if !seen {
+		return 0, false
+	}
+	if value < prev {
+		// The process restarted the counter.
+		return value, true
+	}
+	return value - prev, true
+}
+
+func countSeries(metric string, value float64, interval, ts int64, tags []string) Series {
+	return Series{
+		Metric:   metric,
+		Type:     metricTypeCount,
+		Interval: interval,
+		Points:   []Point{{Timestamp: ts, Value: value}},
+		Tags:     tags,
+	}
+}
+
+func (f *Forwarder) seriesTags(labels []*dto.LabelPair, extra []string) []string {
+	tags := make([]string, 0, len(labels)+len(extra)+1)
+	tags = append(tags, "service:"+f.service)
+	for _, label := range labels {
+		tags = append(tags, label.GetName()+":"+label.GetValue())
+	}
+	tags = append(tags, extra...)
+	sort.Strings(tags)
+	return tags
+}
+
+func forwarded(name string) bool {
+	for _, prefix := range forwardedMetricPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Forwarder) NotifyStageChange(ctx context.Context, event store.StageAlertEvent) {
+	status := "info"
+	switch event.NewStatus {
+	case types.StageStatusFailed:
+		status = "error"
+	case types.StageStatusCancelled, types.StageStatusSkipped:
+		status = "warning"
+	}
+	f.sendLog(ctx, status, fmt.Sprintf("Stage '%s' (id=%d) status changed: %s → %s [pipeline=%d, source=%s]",
+		event.StageName, event.StageID, event.OldStatus, event.NewStatus, event.PipelineID, event.Source),
+		fmt.Sprintf("pipeline_id:%d", event.PipelineID),
+		fmt.Sprintf("stage_id:%d", event.StageID),
+		"stage_status:"+event.NewStatus,
+	)
+}
+
+func (f *Forwarder) NotifyWorkerEvent(ctx context.Context, event store.WorkerAlertEvent) {
+	status := strings.ToLower(strings.TrimSpace(event.Level))
+	switch status {
+	case "warn":
+		status = "warning"
+	case "":
+		status = "info"
+	}
+	message := strings.TrimSpace(event.Message)
+	if message == "" {
+		message = event.EventType
+	}
+	f.sendLog(ctx, status, fmt.Sprintf("Worker %s: %s", event.WorkerID, message),
+		"worker_id:"+event.WorkerID,
+		"event_type:"+event.EventType,
+	)
+}
+
+func (f *Forwarder) sendLog(ctx context.Context, status, message string, tags ...string) {
+	cfg, err := f.loadConfig(ctx)
+	if err != nil {
+		f.logger.Error("datadog config load failed", "err", err)
+		return
+	}
+	if !cfg.enabled || !cfg.sendLogs {
+		return
+	}
+
+	entry := LogEntry{
+		Source:   logSource,
+		Tags:     strings.Join(append(tags, cfg.tags...), ","),
+		Hostname: f.hostname,
+		Service:  f.service,
+		Status:   status,
+		Message:  message,
+	}
+	if err := NewClient(cfg.site, cfg.apiKey).SubmitLogs(ctx, []LogEntry{entry}); err != nil {
+		f.logger.Warn("datadog log forward failed", "err", err)
+	}
+}
+
+func (f *Forwarder) loadConfig(ctx context.Context) (runtimeConfig, error) {
+	f.mu.Lock()
+	if time.Since(f.cacheLoaded) <= configCacheTTL {
+		cfg := f.cachedCfg
+		f.mu.Unlock()
+		return cfg, nil
+	}
+	f.mu.Unlock()
+
+	integration, err := f.repo.GetIntegration(ctx, observabilitymodel.IntegrationTypeDatadog)
+	if err != nil {
+		return runtimeConfig{}, err
+	}
+	cfg := runtimeConfig{}
+	if integration != nil {
+		cfg = parseRuntimeConfig(integration.Config)
+		connected := integration.Status == observabilitymodel.IntegrationStatusConnected ||
+			integration.Status == observabilitymodel.IntegrationStatusStale
+		cfg.enabled = cfg.enabled && connected
+	}
+
+	f.mu.Lock()
+	f.cachedCfg = cfg
+	f.cacheLoaded = time.Now().UTC()
+	f.mu.Unlock()
+	return cfg, nil
+}
+
+func parseRuntimeConfig(config map[string]any) runtimeConfig {
+	cfg := runtimeConfig{
+		site:   parseString(config["site"]),
+		apiKey: parseString(config["apiKey"]),
+	}
+	if v, ok := config["sendLogs"].(bool); ok {
+		cfg.sendLogs = v
+	}
+	switch raw := config["tags"].(type) {
+	case string:
+		cfg.tags = splitTags(strings.Split(raw, ","))
+	case []any:
+		values := make([]string, 0, len(raw))
+		for _, v := range raw {
+			values = append(values, parseString(v))
+		}
+		cfg.tags = splitTags(values)
+	}
+	cfg.enabled = cfg.site != "" && cfg.apiKey != ""
+	return cfg
+}
+
+func splitTags(values []string) []string {
+	tags := make([]string, 0, len(values))
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			tags = append(tags, v)
+		}
+	}
+	return tags
+}
+
+func parseString(raw any) string {
+	if v, ok := raw.(string); ok {
+		return strings.TrimSpace(v)
+	}
+	return ""
+}
@@ -1,19 +1,38 @@
 package logger
 
 import (
+	"io"
 	"log/slog"
 	"os"
 )
 
-func New(level string) *slog.Logger {
-	lvl := parseLevel(level)
-	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: lvl,
-	})
-	return slog.New(handler)
+// Log output formats accepted by New.
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// New returns a logger writing to stdout at level, as JSON unless format is
+// FormatText. Every line carries the service name and app id.
+func New(level, format, service, appID string) *slog.Logger {
+	return newLogger(os.Stdout, level, format, service, appID)
+}
+
+func newLogger(w io.Writer, level, format, service, appID string) *slog.Logger {
+	opts := &slog.HandlerOptions{
+		Level: ParseLevel(level),
+	}
+	var handler slog.Handler
+	if format == FormatText {
+		handler = slog.NewTextHandler(w, opts)
+	} else {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(handler).With("service", service, "appId", appID)
 }
 
-func parseLevel(level string) slog.Level {
+// ParseLevel maps a level name to a slog level, defaulting to info.
+func ParseLevel(level string) slog.Level {
 	switch level {
 	case "debug":
 		return slog.LevelDebug
@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNewAttachesServiceAndAppID(t *testing.T) {
+	var buf bytes.Buffer
+	newLogger(&buf, "warn", FormatJSON, "pipelogiq-api", "app-1").Info("dropped")
+	newLogger(&buf, "warn", FormatJSON, "pipelogiq-api", "app-1").Warn("kept", "queue", "q")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("logged %d lines, want only the warn line: %q", len(lines), buf.String())
+	}
+	var line map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &line); err != nil {
+		t.Fatalf("line is not JSON: %v", err)
+	}
+	if line["msg"] != "kept" || line["service"] != "pipelogiq-api" || line["appId"] != "app-1" || line["queue"] != "q" {
+		t.Fatalf("line = %v, want msg, service, appId and queue", line)
+	}
+}
+
+func TestNewTextFormat(t *testing.T) {
+	var buf bytes.Buffer
+	newLogger(&buf, "info", FormatText, "pipelogiq-worker", "app-1").Info("started")
+	if got := buf.String(); !strings.Contains(got, "msg=started service=pipelogiq-worker appId=app-1") {
+		t.Fatalf("line = %q, want key=value attributes", got)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]slog.Level{
+		"debug":   slog.LevelDebug,
+		"info":    slog.LevelInfo,
+		"warn":    slog.LevelWarn,
+		"warning": slog.LevelWarn,
+		"error":   slog.LevelError,
+		"":        slog.LevelInfo,
+	}
+	for level, want := range tests {
+		if got := ParseLevel(level); got != want {
+			t.Fatalf("ParseLevel(%q) = %v, want %v", level, got, want)
+		}
+	}
+}
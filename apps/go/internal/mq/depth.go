@@ -0,0 +1,46 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// QueueStat is what a passive declare reports about a queue.
+type QueueStat struct {
+	Messages  int
+	Consumers int
+}
+
+// QueueDepth returns the number of ready messages in queue. It returns
+// ErrQueueNotFound when the queue has not been declared.
+func (c *Client) QueueDepth(ctx context.Context, queue string) (int, error) {
+	stat, err := c.InspectQueue(ctx, queue)
+	if err != nil {
+		return 0, err
+	}
+	return stat.Messages, nil
+}
+
+// InspectQueue returns the ready message and consumer counts of queue. It
+// returns ErrQueueNotFound when the queue has not been declared.
+func (c *Client) InspectQueue(ctx context.Context, queue string) (QueueStat, error) {
+	ch, err := c.channel(ctx)
+	if err != nil {
+		return QueueStat{}, err
+	}
+	// A failed passive declare closes the channel, so each lookup uses its own.
+	defer ch.Close()
+
+	q, err := ch.QueueDeclarePassive(queue, false, false, false, false, nil)
+	if err != nil {
+		var amqpErr *amqp.Error
+		if errors.As(err, &amqpErr) && amqpErr.Code == amqp.NotFound {
+			return QueueStat{}, fmt.Errorf("%w: %s", ErrQueueNotFound, queue)
+		}
+		return QueueStat{}, err
+	}
+	return QueueStat{Messages: q.Messages, Consumers: q.Consumers}, nil
+}
@@ -0,0 +1,140 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ErrQueueNotFound is returned when a dead-letter queue has not been declared.
+var ErrQueueNotFound = errors.New("rabbitmq: queue not found")
+
+// DLQName returns the dead-letter queue name used for queue by declareQueue.
+func DLQName(queue string) string {
+	return queue + ".dlq"
+}
+
+// DLQMessage is a dead-lettered message as returned by PeekDLQ.
+type DLQMessage struct {
+	MessageID   string     `json:"messageId"`
+	ContentType string     `json:"contentType,omitempty"`
+	Timestamp   time.Time  `json:"timestamp"`
+	Redelivered bool       `json:"redelivered"`
+	Headers     amqp.Table `json:"headers,omitempty"`
+	Body        string     `json:"body"`
+}
+
+// PeekDLQ returns up to limit messages from the dead-letter queue of queue
+// without removing them; every fetched message is requeued before returning.
+func (c *Client) PeekDLQ(ctx context.Context, queue string, limit int) ([]DLQMessage, error) {
+	ch, err := c.openDLQChannel(ctx, queue)
+	if err != nil {
+		return nil, err
+	}
+	defer ch.Close()
+
+	messages := make([]DLQMessage, 0, limit)
+	var lastTag uint64
+	for len(messages) < limit {
+		if ctx.Err() != nil {
+			break
+		}
+		d, ok, err := ch.Get(DLQName(queue), false)
+		if err != nil {
+			return nil, fmt.Errorf("get dlq message: %w", err)
+		}
+		if !ok {
+			break
+		}
+		lastTag = d.DeliveryTag
+		messages = append(messages, DLQMessage{
+			MessageID:   d.MessageId,
+			ContentType: d.ContentType,
+			Timestamp:   d.Timestamp,
+			Redelivered: d.Redelivered,
+			Headers:     d.Headers,
+			Body:        string(d.Body),
+		})
+	}
+
+	if lastTag > 0 {
+		if err := ch.Nack(lastTag, true, true); err != nil {
+			return nil, fmt.Errorf("requeue peeked messages: %w", err)
+		}
+	}
+	return messages, ctx.Err()
+}
+
+// RequeueDLQ moves up to count messages from the dead-letter queue of queue
+// back onto queue, keeping their headers and message id. A message is only
+// removed from the DLQ after the broker confirmed the republish.
+func (c *Client) RequeueDLQ(ctx context.Context, queue string, count int) (int, error) {
+	ch, err := c.openDLQChannel(ctx, queue)
+	if err != nil {
+		return 0, err
+	}
+	defer ch.Close()
+
+	if _, err := ch.QueueDeclarePassive(queue, false, false, false, false, nil); err != nil {
+		var amqpErr *amqp.Error
+		if errors.As(err, &amqpErr) && amqpErr.Code == amqp.NotFound {
+			return 0, fmt.Errorf("%w: %s", ErrQueueNotFound, queue)
+		}
+		return 0, err
+	}
+
+	moved := 0
+	for moved < count {
+		if ctx.Err() != nil {
+			return moved, ctx.Err()
+		}
+		d, ok, err := ch.Get(DLQName(queue), false)
+		if err != nil {
+			return moved, fmt.Errorf("get dlq message: %w", err)
+		}
+		if !ok {
+			break
+		}
+
+		msg := amqp.Publishing{
+			Headers:       d.Headers,
+			ContentType:   d.ContentType,
+			DeliveryMode:  amqp.Persistent,
+			CorrelationId: d.CorrelationId,
+			MessageId:     d.MessageId,
+			Timestamp:     d.Timestamp,
+			Type:          d.Type,
+			Body:          d.Body,
+		}
+		if err := publishConfirmed(ctx, ch, queue, msg); err != nil {
+			_ = d.Nack(false, true)
+			return moved, fmt.Errorf("republish dlq message %s: %w", d.MessageId, err)
+		}
+		if err := d.Ack(false); err != nil {
+			return moved, fmt.Errorf("ack dlq message %s: %w", d.MessageId, err)
+		}
+		moved++
+	}
+
+	c.logger.Info("rabbitmq: requeued dead-lettered messages", "queue", queue, "count", moved)
+	return moved, nil
+}
+
+func (c *Client) openDLQChannel(ctx context.Context, queue string) (*amqp.Channel, error) {
+	ch, err := c.channel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ch.QueueDeclarePassive(DLQName(queue), false, false, false, false, nil); err != nil {
+		ch.Close()
+		var amqpErr *amqp.Error
+		if errors.As(err, &amqpErr) && amqpErr.Code == amqp.NotFound {
+			return nil, fmt.Errorf("%w: %s", ErrQueueNotFound, DLQName(queue))
+		}
+		return nil, err
+	}
+	return ch, nil
+}
@@ -0,0 +1,103 @@
+package mq
+
+import (
+	"context"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// defaultChannelPoolSize caps how many idle channels a Client keeps open.
+const defaultChannelPoolSize = 16
+
+type pooledChannel struct {
+	ch   *amqp.Channel
+	conn *amqp.Connection
+}
+
+// channelPool keeps a bounded set of idle channels for short-lived publish and
+// get operations. Channels are only reused while they and the connection they
+// were opened on are still open.
+type channelPool struct {
+	mu   sync.Mutex
+	idle []pooledChannel
+	max  int
+}
+
+func newChannelPool(max int) *channelPool {
+	return &channelPool{max: max}
+}
+
+func (p *channelPool) get() (pooledChannel, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for len(p.idle) > 0 {
+		pc := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if pc.healthy() {
+			return pc, true
+		}
+		_ = pc.ch.Close()
+	}
+	return pooledChannel{}, false
+}
+
+func (p *channelPool) put(pc pooledChannel) {
+	if !pc.healthy() {
+		_ = pc.ch.Close()
+		return
+	}
+
+	p.mu.Lock()
+	if len(p.idle) < p.max {
+		p.idle = append(p.idle, pc)
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+	_ = pc.ch.Close()
+}
+
+func (p *channelPool) close() {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	for _, pc := range idle {
+		_ = pc.ch.Close()
+	}
+}
+
+func (pc pooledChannel) healthy() bool {
+	return pc.ch != nil && !pc.ch.IsClosed() && pc.conn != nil && !pc.conn.IsClosed()
+}
+
+// acquireChannel borrows a channel from the pool, opening a new one when no
+// healthy idle channel is available. Callers hand it back with releaseChannel.
+func (c *Client) acquireChannel(ctx context.Context) (pooledChannel, error) {
+	if pc, ok := c.pool.get(); ok {
+		return pc, nil
+	}
+
+	conn, err := c.connection(ctx)
+	if err != nil {
+		return pooledChannel{}, err
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		return pooledChannel{}, err
+	}
+	return pooledChannel{ch: ch, conn: conn}, nil
+}
+
+// releaseChannel returns a borrowed channel to the pool. Channels that were put
+// into confirm mode are closed instead so plain publishers never inherit them.
+func (c *Client) releaseChannel(pc pooledChannel, reusable bool) {
+	if !reusable {
+		_ = pc.ch.Close()
+		return
+	}
+	c.pool.put(pc)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
@@ -15,12 +16,31 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 
 	"pipelogiq/internal/telemetry"
 )
 
 var rabbitTracer = otel.Tracer("pipelogiq/mq")
 
+// startSpan starts a messaging span. When tracing cannot sample anything it
+// skips span creation and returns ctx with a no-op span, so a zero sample
+// ratio costs nothing per message.
+func startSpan(ctx context.Context, name string, kind trace.SpanKind, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if !telemetry.TracingEnabled() {
+		return ctx, noop.Span{}
+	}
+	return rabbitTracer.Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+}
+
+// publishConfirmTimeout bounds how long a confirmed publish waits for the
+// broker's ack before the attempt is retried.
+const publishConfirmTimeout = 5 * time.Second
+
+var errPublishNacked = errors.New("rabbitmq: publish nacked by broker")
+
+// QueueOptions configures queue declaration and publishing. Confirm puts the
+// publishing channel into confirm mode and only treats a broker ack as success.
 type QueueOptions struct {
 	Durable     bool
 	AutoDelete  bool
@@ -28,12 +48,24 @@ type QueueOptions struct {
 	DLQTTL      time.Duration
 	Prefetch    int
 	ContentType string
+	Confirm     bool
+	// QueueType sets x-queue-type ("classic" or "quorum") on the queue and
+	// its DLQ. Empty declares without it, matching queues created before the
+	// option existed.
+	QueueType string
+	// MaxLength and Overflow set x-max-length and x-overflow on the queue;
+	// zero and empty leave them unset.
+	MaxLength int
+	Overflow  string
 }
 
 type ConsumeOptions struct {
 	QueueOptions
 	HandlerTimeout   time.Duration
 	DeadLetterOnFail bool
+	// OnDeadLetter, when set, is called with the handler error for every
+	// delivery DeadLetterOnFail rejects, before it is nacked.
+	OnDeadLetter func(ctx context.Context, d amqp.Delivery, cause error)
 }
 
 type Client struct {
@@ -42,13 +74,24 @@ type Client struct {
 
 	mu   sync.Mutex
 	conn *amqp.Connection
+
+	pool *channelPool
+
+	reconnect ReconnectConfig
 }
 
 func NewClient(url string, logger *slog.Logger) *Client {
-	return &Client{url: url, logger: logger}
+	return &Client{
+		url:       url,
+		logger:    logger,
+		pool:      newChannelPool(defaultChannelPoolSize),
+		reconnect: DefaultReconnectConfig(),
+	}
 }
 
 func (c *Client) Close() error {
+	c.pool.close()
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	if c.conn != nil && !c.conn.IsClosed() {
@@ -58,13 +101,10 @@ func (c *Client) Close() error {
 }
 
 func (c *Client) PublishWithRetry(ctx context.Context, queue string, body []byte, opts QueueOptions, headers amqp.Table) error {
-	ctx, span := rabbitTracer.Start(ctx, "rabbitmq.publish",
-		trace.WithSpanKind(trace.SpanKindProducer),
-		trace.WithAttributes(
-			attribute.String("messaging.system", "rabbitmq"),
-			attribute.String("messaging.destination.name", queue),
-			attribute.String("messaging.operation", "publish"),
-		),
+	ctx, span := startSpan(ctx, "rabbitmq.publish", trace.SpanKindProducer,
+		attribute.String("messaging.system", "rabbitmq"),
+		attribute.String("messaging.destination.name", queue),
+		attribute.String("messaging.operation", "publish"),
 	)
 	defer span.End()
 
@@ -74,12 +114,13 @@ func (c *Client) PublishWithRetry(ctx context.Context, queue string, body []byte
 	exp.MaxElapsedTime = 0 // never stop until ctx done
 
 	pub := func() error {
-		ch, err := c.channel(ctx)
+		pc, err := c.acquireChannel(ctx)
 		if err != nil {
 			span.RecordError(err)
 			return err
 		}
-		defer ch.Close()
+		defer c.releaseChannel(pc, !opts.Confirm)
+		ch := pc.ch
 
 		if err := declareQueue(ch, queue, opts); err != nil {
 			if isPreconditionFailed(err) {
@@ -108,7 +149,16 @@ func (c *Client) PublishWithRetry(ctx context.Context, queue string, body []byte
 			DeliveryMode: amqp.Persistent,
 		}
 
-		if err := ch.PublishWithContext(ctx, "", queue, false, false, msg); err != nil {
+		if !opts.Confirm {
+			if err := ch.PublishWithContext(ctx, "", queue, false, false, msg); err != nil {
+				span.RecordError(err)
+				return err
+			}
+			return nil
+		}
+
+		if err := publishConfirmed(ctx, ch, queue, msg); err != nil {
+			c.logger.Warn("rabbitmq: confirmed publish failed, retrying", "queue", queue, "err", err)
 			span.RecordError(err)
 			return err
 		}
@@ -128,6 +178,38 @@ func (c *Client) PublishWithRetry(ctx context.Context, queue string, body []byte
 	return nil
 }
 
+// publishConfirmed publishes msg on a confirm-mode channel and waits for the
+// broker's ack. A nack or a missing ack within publishConfirmTimeout is
+// returned as an error so the caller's backoff retries the publish.
+func publishConfirmed(ctx context.Context, ch *amqp.Channel, queue string, msg amqp.Publishing) error {
+	if err := ch.Confirm(false); err != nil {
+		return fmt.Errorf("enable publisher confirms: %w", err)
+	}
+
+	confirm, err := ch.PublishWithDeferredConfirmWithContext(ctx, "", queue, false, false, msg)
+	if err != nil {
+		return err
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, publishConfirmTimeout)
+	defer cancel()
+	acked, err := confirm.WaitContext(waitCtx)
+	if err != nil {
+		if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("rabbitmq: no publish confirm within %s", publishConfirmTimeout)
+		}
+		return err
+	}
+	if !acked {
+		return errPublishNacked
+	}
+	return nil
+}
+
+// Consume delivers messages from queue to handler until ctx is cancelled.
+// Handlers run on a context detached from ctx, so cancelling ctx lets the
+// delivery in progress finish (bounded by HandlerTimeout); the consumer is
+// then cancelled and unacked prefetched deliveries are requeued.
 func (c *Client) Consume(ctx context.Context, queue string, opts ConsumeOptions, handler func(context.Context, amqp.Delivery) error) error {
 	if handler == nil {
 		return errors.New("handler is nil")
@@ -141,7 +223,7 @@ func (c *Client) Consume(ctx context.Context, queue string, opts ConsumeOptions,
 		ch, err := c.channel(ctx)
 		if err != nil {
 			c.logger.Error("rabbitmq: failed to open channel", "err", err)
-			time.Sleep(time.Second)
+			c.waitReconnect(ctx)
 			continue
 		}
 
@@ -153,7 +235,7 @@ func (c *Client) Consume(ctx context.Context, queue string, opts ConsumeOptions,
 				return err
 			}
 			c.logger.Error("rabbitmq: declare queue failed", "queue", queue, "err", err)
-			time.Sleep(time.Second)
+			c.waitReconnect(ctx)
 			continue
 		}
 
@@ -163,11 +245,12 @@ func (c *Client) Consume(ctx context.Context, queue string, opts ConsumeOptions,
 			}
 		}
 
-		deliveries, err := ch.Consume(queue, "", false, false, false, false, nil)
+		consumerTag := "consume-" + uuid.NewString()
+		deliveries, err := ch.Consume(queue, consumerTag, false, false, false, false, nil)
 		if err != nil {
 			ch.Close()
 			c.logger.Error("rabbitmq: consume failed", "queue", queue, "err", err)
-			time.Sleep(time.Second)
+			c.waitReconnect(ctx)
 			continue
 		}
 
@@ -191,15 +274,18 @@ func (c *Client) Consume(ctx context.Context, queue string, opts ConsumeOptions,
 					goto reconnect
 				}
 
-				hctx := telemetry.ExtractAMQPContext(ctx, d.Headers)
-				hctx, span := rabbitTracer.Start(hctx, "rabbitmq.consume",
-					trace.WithSpanKind(trace.SpanKindConsumer),
-					trace.WithAttributes(
-						attribute.String("messaging.system", "rabbitmq"),
-						attribute.String("messaging.destination.name", queue),
-						attribute.String("messaging.operation", "process"),
-						attribute.String("messaging.message.id", d.MessageId),
-					),
+				// Shutdown raced with a buffered delivery: hand it back untouched.
+				if ctx.Err() != nil {
+					_ = d.Nack(false, true)
+					continue
+				}
+
+				hctx := telemetry.ExtractAMQPContext(context.WithoutCancel(ctx), d.Headers)
+				hctx, span := startSpan(hctx, "rabbitmq.consume", trace.SpanKindConsumer,
+					attribute.String("messaging.system", "rabbitmq"),
+					attribute.String("messaging.destination.name", queue),
+					attribute.String("messaging.operation", "process"),
+					attribute.String("messaging.message.id", d.MessageId),
 				)
 				var cancel context.CancelFunc
 				if opts.HandlerTimeout > 0 {
@@ -209,7 +295,13 @@ func (c *Client) Consume(ctx context.Context, queue string, opts ConsumeOptions,
 					span.RecordError(err)
 					span.SetStatus(codes.Error, err.Error())
 					c.logger.Error("rabbitmq: handler error", "queue", queue, "err", err)
-					if opts.DeadLetterOnFail {
+					// A handler cancelled during shutdown did not fail on its
+					// own; requeue it instead of dead-lettering.
+					shuttingDown := ctx.Err() != nil && errors.Is(err, context.Canceled)
+					if opts.DeadLetterOnFail && !shuttingDown {
+						if opts.OnDeadLetter != nil {
+							opts.OnDeadLetter(context.WithoutCancel(hctx), d, err)
+						}
 						_ = d.Nack(false, false)
 					} else {
 						_ = d.Nack(false, true)
@@ -232,7 +324,7 @@ func (c *Client) Consume(ctx context.Context, queue string, opts ConsumeOptions,
 				goto reconnect
 			case <-ctx.Done():
 				c.logger.Info("rabbitmq: stopping consumer", "queue", queue)
-				ch.Cancel("", false)
+				_ = ch.Cancel(consumerTag, false)
 				ch.Close()
 				return ctx.Err()
 			}
@@ -240,7 +332,7 @@ func (c *Client) Consume(ctx context.Context, queue string, opts ConsumeOptions,
 
 	reconnect:
 		ch.Close()
-		time.Sleep(time.Second)
+		c.waitReconnect(ctx)
 	}
 }
 
@@ -256,25 +348,23 @@ type GetResult struct {
 }
 
 func (c *Client) Get(ctx context.Context, queue string, opts QueueOptions) (*GetResult, error) {
-	ctx, span := rabbitTracer.Start(ctx, "rabbitmq.get",
-		trace.WithSpanKind(trace.SpanKindConsumer),
-		trace.WithAttributes(
-			attribute.String("messaging.system", "rabbitmq"),
-			attribute.String("messaging.destination.name", queue),
-			attribute.String("messaging.operation", "receive"),
-		),
+	ctx, span := startSpan(ctx, "rabbitmq.get", trace.SpanKindConsumer,
+		attribute.String("messaging.system", "rabbitmq"),
+		attribute.String("messaging.destination.name", queue),
+		attribute.String("messaging.operation", "receive"),
 	)
 	defer span.End()
 
-	ch, err := c.channel(ctx)
+	pc, err := c.acquireChannel(ctx)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
 		return nil, err
 	}
+	ch := pc.ch
 
 	if err := declareQueue(ch, queue, opts); err != nil {
-		ch.Close()
+		c.releaseChannel(pc, true)
 		if isPreconditionFailed(err) {
 			err = newQueueTopologyMismatchError(queue, err)
 		}
@@ -289,13 +379,13 @@ func (c *Client) Get(ctx context.Context, queue string, opts QueueOptions) (*Get
 
 	d, ok, err := ch.Get(queue, false)
 	if err != nil {
-		ch.Close()
+		c.releaseChannel(pc, true)
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
 		return nil, err
 	}
 	if !ok {
-		ch.Close()
+		c.releaseChannel(pc, true)
 		return nil, nil
 	}
 
@@ -307,17 +397,129 @@ func (c *Client) Get(ctx context.Context, queue string, opts QueueOptions) (*Get
 		Delivery:  d,
 	}
 	span.SetAttributes(attribute.String("messaging.message.id", d.MessageId))
+	// The delivery tag belongs to this channel, so it stays out of the pool
+	// until the message is settled.
+	var release sync.Once
 	res.Ack = func() error {
-		defer ch.Close()
+		defer release.Do(func() { c.releaseChannel(pc, true) })
 		return d.Ack(false)
 	}
 	res.Nack = func(requeue bool) error {
-		defer ch.Close()
+		defer release.Do(func() { c.releaseChannel(pc, true) })
 		return d.Nack(false, requeue)
 	}
 	return res, nil
 }
 
+// GetWait behaves like Get but, when the queue is empty, waits up to wait for
+// messages using a short-lived consumer. The consumer's prefetch is
+// opts.Prefetch, at least 1, and every message the broker hands it before it
+// is cancelled is returned; they share a channel that is closed once all of
+// them are settled. It returns nil when nothing arrives in time, and a single
+// message when Get finds one right away. ctx cancellation aborts the wait.
+func (c *Client) GetWait(ctx context.Context, queue string, opts QueueOptions, wait time.Duration) ([]*GetResult, error) {
+	res, err := c.Get(ctx, queue, opts)
+	if err != nil {
+		return nil, err
+	}
+	if res != nil {
+		return []*GetResult{res}, nil
+	}
+	if wait <= 0 {
+		return nil, nil
+	}
+
+	ctx, span := startSpan(ctx, "rabbitmq.get.wait", trace.SpanKindConsumer,
+		attribute.String("messaging.system", "rabbitmq"),
+		attribute.String("messaging.destination.name", queue),
+		attribute.String("messaging.operation", "receive"),
+	)
+	defer span.End()
+
+	ch, err := c.channel(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	// The prefetch bounds how many messages the broker hands this consumer.
+	if err := ch.Qos(max(opts.Prefetch, 1), 0, false); err != nil {
+		ch.Close()
+		return nil, err
+	}
+
+	consumerTag := "pull-" + uuid.NewString()
+	deliveries, err := ch.Consume(queue, consumerTag, false, false, false, false, nil)
+	if err != nil {
+		ch.Close()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case d, ok := <-deliveries:
+		if !ok {
+			ch.Close()
+			return nil, errors.New("rabbitmq: consumer closed while waiting")
+		}
+		// Stop further deliveries. Messages sent before the cancel stay
+		// buffered in deliveries, which is closed after them.
+		held := []amqp.Delivery{d}
+		if err := ch.Cancel(consumerTag, false); err == nil {
+			for d := range deliveries {
+				held = append(held, d)
+			}
+		}
+		span.SetAttributes(
+			attribute.String("messaging.message.id", d.MessageId),
+			attribute.Int("messaging.batch.message_count", len(held)),
+		)
+
+		// The delivery tags belong to ch, so it stays open until every
+		// held message is settled.
+		var unsettled atomic.Int32
+		unsettled.Store(int32(len(held)))
+		results := make([]*GetResult, 0, len(held))
+		for _, d := range held {
+			var settle sync.Once
+			done := func() {
+				settle.Do(func() {
+					if unsettled.Add(-1) == 0 {
+						ch.Close()
+					}
+				})
+			}
+			results = append(results, &GetResult{
+				Body:      d.Body,
+				Headers:   d.Headers,
+				MessageID: d.MessageId,
+				Queue:     queue,
+				Delivery:  d,
+				Ack: func() error {
+					defer done()
+					return d.Ack(false)
+				},
+				Nack: func(requeue bool) error {
+					defer done()
+					return d.Nack(false, requeue)
+				},
+			})
+		}
+		return results, nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	_ = ch.Cancel(consumerTag, false)
+	// Closing the channel requeues anything delivered after the cancel.
+	ch.Close()
+	return nil, nil
+}
+
 func (c *Client) channel(ctx context.Context) (*amqp.Channel, error) {
 	conn, err := c.connection(ctx)
 	if err != nil {
@@ -350,16 +552,12 @@ func (c *Client) connection(ctx context.Context) (*amqp.Connection, error) {
 		return err
 	}
 
-	exp := backoff.NewExponentialBackOff()
-	exp.InitialInterval = 500 * time.Millisecond
-	exp.MaxElapsedTime = 0 // keep retrying until ctx canceled
-
 	if err := backoff.Retry(func() error {
 		if ctx.Err() != nil {
 			return backoff.Permanent(ctx.Err())
 		}
 		return operation()
-	}, backoff.WithContext(exp, ctx)); err != nil {
+	}, backoff.WithContext(c.dialBackOff(), ctx)); err != nil {
 		return nil, fmt.Errorf("connect rabbitmq: %w", err)
 	}
 
@@ -368,25 +566,30 @@ func (c *Client) connection(ctx context.Context) (*amqp.Connection, error) {
 	return conn, nil
 }
 
+// Ping reports whether the broker connection is open, dialing it when it is
+// not. ctx bounds the dial, which otherwise retries until cancelled.
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.connection(ctx)
+	return err
+}
+
 // PublishToExchange publishes a message to a fanout exchange.
 func (c *Client) PublishToExchange(ctx context.Context, exchange string, body []byte) error {
-	ctx, span := rabbitTracer.Start(ctx, "rabbitmq.publish.fanout",
-		trace.WithSpanKind(trace.SpanKindProducer),
-		trace.WithAttributes(
-			attribute.String("messaging.system", "rabbitmq"),
-			attribute.String("messaging.destination.name", exchange),
-			attribute.String("messaging.operation", "publish"),
-		),
+	ctx, span := startSpan(ctx, "rabbitmq.publish.fanout", trace.SpanKindProducer,
+		attribute.String("messaging.system", "rabbitmq"),
+		attribute.String("messaging.destination.name", exchange),
+		attribute.String("messaging.operation", "publish"),
 	)
 	defer span.End()
 
-	ch, err := c.channel(ctx)
+	pc, err := c.acquireChannel(ctx)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
 		return err
 	}
-	defer ch.Close()
+	defer c.releaseChannel(pc, true)
+	ch := pc.ch
 
 	if err := ch.ExchangeDeclare(exchange, "fanout", true, false, false, false, nil); err != nil {
 		span.RecordError(err)
@@ -418,14 +621,14 @@ func (c *Client) SubscribeFanout(ctx context.Context, exchange string, handler f
 		ch, err := c.channel(ctx)
 		if err != nil {
 			c.logger.Error("rabbitmq: fanout channel failed", "exchange", exchange, "err", err)
-			time.Sleep(time.Second)
+			c.waitReconnect(ctx)
 			continue
 		}
 
 		if err := ch.ExchangeDeclare(exchange, "fanout", true, false, false, false, nil); err != nil {
 			ch.Close()
 			c.logger.Error("rabbitmq: declare fanout exchange failed", "exchange", exchange, "err", err)
-			time.Sleep(time.Second)
+			c.waitReconnect(ctx)
 			continue
 		}
 
@@ -433,14 +636,14 @@ func (c *Client) SubscribeFanout(ctx context.Context, exchange string, handler f
 		if err != nil {
 			ch.Close()
 			c.logger.Error("rabbitmq: declare exclusive queue failed", "err", err)
-			time.Sleep(time.Second)
+			c.waitReconnect(ctx)
 			continue
 		}
 
 		if err := ch.QueueBind(q.Name, "", exchange, false, nil); err != nil {
 			ch.Close()
 			c.logger.Error("rabbitmq: bind queue to exchange failed", "exchange", exchange, "err", err)
-			time.Sleep(time.Second)
+			c.waitReconnect(ctx)
 			continue
 		}
 
@@ -448,7 +651,7 @@ func (c *Client) SubscribeFanout(ctx context.Context, exchange string, handler f
 		if err != nil {
 			ch.Close()
 			c.logger.Error("rabbitmq: consume fanout failed", "exchange", exchange, "err", err)
-			time.Sleep(time.Second)
+			c.waitReconnect(ctx)
 			continue
 		}
 
@@ -461,14 +664,11 @@ func (c *Client) SubscribeFanout(ctx context.Context, exchange string, handler f
 					goto reconnect
 				}
 				handlerCtx := telemetry.ExtractAMQPContext(ctx, d.Headers)
-				handlerCtx, span := rabbitTracer.Start(handlerCtx, "rabbitmq.consume.fanout",
-					trace.WithSpanKind(trace.SpanKindConsumer),
-					trace.WithAttributes(
-						attribute.String("messaging.system", "rabbitmq"),
-						attribute.String("messaging.destination.name", exchange),
-						attribute.String("messaging.operation", "process"),
-						attribute.String("messaging.message.id", d.MessageId),
-					),
+				handlerCtx, span := startSpan(handlerCtx, "rabbitmq.consume.fanout", trace.SpanKindConsumer,
+					attribute.String("messaging.system", "rabbitmq"),
+					attribute.String("messaging.destination.name", exchange),
+					attribute.String("messaging.operation", "process"),
+					attribute.String("messaging.message.id", d.MessageId),
 				)
 				handler(handlerCtx, d.Body)
 				span.End()
@@ -485,15 +685,24 @@ func (c *Client) SubscribeFanout(ctx context.Context, exchange string, handler f
 
 	reconnect:
 		ch.Close()
-		time.Sleep(time.Second)
+		c.waitReconnect(ctx)
 	}
 }
 
 func declareQueue(ch *amqp.Channel, name string, opts QueueOptions) error {
 	args := amqp.Table{}
+	if opts.QueueType != "" {
+		args["x-queue-type"] = opts.QueueType
+	}
+	if opts.MaxLength > 0 {
+		args["x-max-length"] = int64(opts.MaxLength)
+	}
+	if opts.Overflow != "" {
+		args["x-overflow"] = opts.Overflow
+	}
 	if opts.DLQEnabled {
 		dlx := name + ".dlx"
-		dlq := name + ".dlq"
+		dlq := DLQName(name)
 		args["x-dead-letter-exchange"] = dlx
 		// declare DLX and DLQ first
 		if err := ch.ExchangeDeclare(dlx, "direct", true, false, false, false, nil); err != nil {
@@ -501,6 +710,9 @@ func declareQueue(ch *amqp.Channel, name string, opts QueueOptions) error {
 		}
 
 		dlqArgs := amqp.Table{}
+		if opts.QueueType != "" {
+			dlqArgs["x-queue-type"] = opts.QueueType
+		}
 		if opts.DLQTTL > 0 {
 			dlqArgs["x-message-ttl"] = int64(opts.DLQTTL / time.Millisecond)
 			dlqArgs["x-dead-letter-exchange"] = ""
@@ -533,7 +745,7 @@ type queueTopologyMismatchError struct {
 
 func (e *queueTopologyMismatchError) Error() string {
 	return fmt.Sprintf(
-		"rabbitmq queue topology mismatch for %q: %v (existing queue args differ; align publisher/consumer args or migrate queue explicitly)",
+		"rabbitmq queue topology mismatch for %q: %v (existing queue args differ; align publisher/consumer args, including RABBIT_QUEUE_TYPE/RABBIT_QUEUE_MAX_LENGTH/RABBIT_QUEUE_OVERFLOW, or migrate queue explicitly)",
 		e.queue,
 		e.err,
 	)
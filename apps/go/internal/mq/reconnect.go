@@ -0,0 +1,88 @@
+package mq
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+)
+
+// ReconnectConfig tunes how the client recovers from broker failures. Dials
+// back off exponentially from InitialInterval up to MaxInterval and give up
+// after MaxElapsedTime (zero retries until the context ends). Consumers wait
+// ReconnectDelay before reopening a failed channel. Jitter, between 0 and 1,
+// randomizes every wait by up to that fraction so many clients restarting
+// together do not reconnect in lockstep.
+type ReconnectConfig struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	MaxElapsedTime  time.Duration
+	ReconnectDelay  time.Duration
+	Jitter          float64
+}
+
+// DefaultReconnectConfig returns the settings used until SetReconnectConfig
+// overrides them.
+func DefaultReconnectConfig() ReconnectConfig {
+	return ReconnectConfig{
+		InitialInterval: 500 * time.Millisecond,
+		MaxInterval:     30 * time.Second,
+		ReconnectDelay:  time.Second,
+		Jitter:          0.5,
+	}
+}
+
+// SetReconnectConfig replaces the reconnect settings; zero fields keep their
+// defaults. It must be called before the client is used.
+func (c *Client) SetReconnectConfig(cfg ReconnectConfig) {
+	def := DefaultReconnectConfig()
+	if cfg.InitialInterval <= 0 {
+		cfg.InitialInterval = def.InitialInterval
+	}
+	if cfg.MaxInterval <= 0 {
+		cfg.MaxInterval = def.MaxInterval
+	}
+	if cfg.MaxInterval < cfg.InitialInterval {
+		cfg.MaxInterval = cfg.InitialInterval
+	}
+	if cfg.MaxElapsedTime < 0 {
+		cfg.MaxElapsedTime = 0
+	}
+	if cfg.ReconnectDelay <= 0 {
+		cfg.ReconnectDelay = def.ReconnectDelay
+	}
+	cfg.Jitter = min(max(cfg.Jitter, 0), 1)
+	c.reconnect = cfg
+}
+
+// dialBackOff returns the backoff policy for establishing the connection.
+func (c *Client) dialBackOff() *backoff.ExponentialBackOff {
+	exp := backoff.NewExponentialBackOff()
+	exp.InitialInterval = c.reconnect.InitialInterval
+	exp.MaxInterval = c.reconnect.MaxInterval
+	exp.MaxElapsedTime = c.reconnect.MaxElapsedTime
+	exp.RandomizationFactor = c.reconnect.Jitter
+	exp.Reset()
+	return exp
+}
+
+// waitReconnect sleeps for the jittered reconnect delay, returning early when
+// ctx ends.
+func (c *Client) waitReconnect(ctx context.Context) {
+	timer := time.NewTimer(jitter(c.reconnect.ReconnectDelay, c.reconnect.Jitter))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// jitter spreads d uniformly over [d*(1-factor), d*(1+factor)].
+func jitter(d time.Duration, factor float64) time.Duration {
+	if factor <= 0 || d <= 0 {
+		return d
+	}
+	delta := factor * float64(d)
+	return time.Duration(float64(d) - delta + rand.Float64()*2*delta)
+}
@@ -0,0 +1,29 @@
+package mq
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// ShardQueueName maps key onto one of shards queues named "<base>.<n>".
+// With fewer than two shards it returns base unchanged.
+func ShardQueueName(base, key string, shards int) string {
+	if shards < 2 {
+		return base
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return fmt.Sprintf("%s.%d", base, h.Sum32()%uint32(shards))
+}
+
+// ShardQueueNames lists every shard queue of base, or base alone when sharding is off.
+func ShardQueueNames(base string, shards int) []string {
+	if shards < 2 {
+		return []string{base}
+	}
+	names := make([]string, 0, shards)
+	for i := 0; i < shards; i++ {
+		names = append(names, fmt.Sprintf("%s.%d", base, i))
+	}
+	return names
+}
@@ -0,0 +1,45 @@
+package observabilityhttp
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"pipelogiq/internal/observability/model"
+	"pipelogiq/internal/observability/service"
+)
+
+// GetAlertHistory lists recorded alert deliveries: sent, failed and
+// suppressed alerts, with the suppression reason.
+func (h *Handler) GetAlertHistory(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	query := r.URL.Query()
+	limit := 0
+	if raw := strings.TrimSpace(query.Get("limit")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			h.writeError(w, &service.AppError{
+				Code:    "invalid_payload",
+				Message: "limit must be a positive integer",
+				Details: map[string]any{"field": "limit"},
+			})
+			return
+		}
+		limit = parsed
+	}
+
+	response, err := h.service.GetAlertHistory(ctx,
+		strings.TrimSpace(query.Get("event")), strings.TrimSpace(query.Get("status")), resolveTimeRangeParam(r), limit)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	if response == nil {
+		response = []model.AlertDeliveryEntry{}
+	}
+
+	writeJSON(w, response, http.StatusOK)
+}
@@ -2,6 +2,8 @@ package observabilityhttp
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
@@ -11,6 +13,7 @@ import (
 	"github.com/go-chi/chi/v5"
 
 	"pipelogiq/internal/observability/model"
+	"pipelogiq/internal/observability/service"
 )
 
 func TestHandlersHappyPath(t *testing.T) {
@@ -133,12 +136,99 @@ func TestHandlersHappyPath(t *testing.T) {
 	}
 }
 
+func TestHandlersMapAppErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		body         string
+		wantStatus   int
+		wantContains string
+	}{
+		{
+			name:         "invalid integration type",
+			err:          &service.AppError{Code: "invalid_integration_type", Message: "Unsupported integration type"},
+			wantStatus:   http.StatusBadRequest,
+			wantContains: `"code":"invalid_integration_type"`,
+		},
+		{
+			name: "invalid config",
+			err: &service.AppError{
+				Code:    "invalid_config",
+				Message: "OpenTelemetry tlsInsecure must be a boolean",
+				Details: map[string]any{"field": "tlsInsecure"},
+			},
+			wantStatus:   http.StatusBadRequest,
+			wantContains: `"field":"tlsInsecure"`,
+		},
+		{
+			name:         "integration not found",
+			err:          &service.AppError{Code: "integration_not_found", Message: "Integration not found"},
+			wantStatus:   http.StatusNotFound,
+			wantContains: `"code":"integration_not_found"`,
+		},
+		{
+			name: "integration not configured",
+			err: fmt.Errorf("save: %w", &service.AppError{
+				Code:    "integration_not_configured",
+				Message: "Integration is not configured",
+				Details: map[string]any{"missingKey": "endpoint"},
+			}),
+			wantStatus:   http.StatusConflict,
+			wantContains: `"missingKey":"endpoint"`,
+		},
+		{
+			name:         "config too large",
+			err:          &service.AppError{Code: "config_too_large", Message: "Config payload is too large"},
+			wantStatus:   http.StatusRequestEntityTooLarge,
+			wantContains: `"code":"config_too_large"`,
+		},
+		{
+			name:         "request body too large",
+			body:         `{"type":"opentelemetry","config":{"endpoint":"` + strings.Repeat("x", maxRequestBytes) + `"}}`,
+			wantStatus:   http.StatusRequestEntityTooLarge,
+			wantContains: `"code":"config_too_large"`,
+		},
+		{
+			name:         "unknown error",
+			err:          errors.New("boom"),
+			wantStatus:   http.StatusInternalServerError,
+			wantContains: `"code":"internal_error"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(&mockService{err: tt.err}, slog.Default())
+			router := chi.NewRouter()
+			RegisterRoutes(router, handler)
+
+			body := tt.body
+			if body == "" {
+				body = `{"type":"opentelemetry","config":{}}`
+			}
+			req := httptest.NewRequest(http.MethodPost, "/config", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+			if !strings.Contains(recorder.Body.String(), tt.wantContains) {
+				t.Fatalf("response body %q does not contain %q", recorder.Body.String(), tt.wantContains)
+			}
+		})
+	}
+}
+
 type mockService struct {
 	configResponse   model.ObservabilityConfigResponse
 	statusResponse   model.ObservabilityStatusResponse
 	testResponse     model.TestConnectionResult
 	tracesResponse   []model.TraceEntry
 	insightsResponse model.InsightsResponse
+	alertHistory     []model.AlertDeliveryEntry
+	err              error
 }
 
 func (m *mockService) GetConfig(context.Context) (model.ObservabilityConfigResponse, error) {
@@ -146,7 +236,7 @@ func (m *mockService) GetConfig(context.Context) (model.ObservabilityConfigRespo
 }
 
 func (m *mockService) SaveConfig(context.Context, model.SaveConfigRequest) (model.ObservabilityConfigResponse, error) {
-	return m.configResponse, nil
+	return m.configResponse, m.err
 }
 
 func (m *mockService) GetStatus(context.Context) (model.ObservabilityStatusResponse, error) {
@@ -154,7 +244,7 @@ func (m *mockService) GetStatus(context.Context) (model.ObservabilityStatusRespo
 }
 
 func (m *mockService) TestConnection(context.Context, model.TestConnectionRequest) (model.TestConnectionResult, error) {
-	return m.testResponse, nil
+	return m.testResponse, m.err
 }
 
 func (m *mockService) GetTraces(context.Context, string, string, string) ([]model.TraceEntry, error) {
@@ -164,3 +254,7 @@ func (m *mockService) GetTraces(context.Context, string, string, string) ([]mode
 func (m *mockService) GetInsights(context.Context, string) (model.InsightsResponse, error) {
 	return m.insightsResponse, nil
 }
+
+func (m *mockService) GetAlertHistory(context.Context, string, string, string, int) ([]model.AlertDeliveryEntry, error) {
+	return m.alertHistory, m.err
+}
@@ -43,14 +43,23 @@ func RegisterRoutes(r chi.Router, handler *Handler) {
 	r.Post("/test", handler.TestConnection)
 	r.Get("/traces", handler.GetTraces)
 	r.Get("/insights", handler.GetInsights)
+	r.Get("/alerts/history", handler.GetAlertHistory)
 }
 
 func decodeJSON(r *http.Request, target any) error {
-	limited := io.LimitReader(r.Body, maxRequestBytes)
+	limited := http.MaxBytesReader(nil, r.Body, maxRequestBytes)
 	decoder := json.NewDecoder(limited)
 	decoder.DisallowUnknownFields()
 
 	if err := decoder.Decode(target); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return &service.AppError{
+				Code:    "config_too_large",
+				Message: "Request payload is too large",
+				Details: map[string]any{"maxBytes": maxRequestBytes},
+			}
+		}
 		return &service.AppError{
 			Code:    "invalid_payload",
 			Message: "Invalid request payload",
@@ -106,14 +115,18 @@ func (h *Handler) writeError(w http.ResponseWriter, err error) {
 	}, http.StatusInternalServerError)
 }
 
+// statusForCode maps service.AppError codes to HTTP statuses. Unknown codes
+// are treated as server errors.
 func statusForCode(code string) int {
 	switch strings.TrimSpace(code) {
-	case "invalid_payload", "invalid_integration_type", "invalid_config", "config_too_large":
+	case "invalid_payload", "invalid_integration_type", "invalid_config":
 		return http.StatusBadRequest
 	case "integration_not_found":
 		return http.StatusNotFound
 	case "integration_not_configured":
-		return http.StatusUnprocessableEntity
+		return http.StatusConflict
+	case "config_too_large":
+		return http.StatusRequestEntityTooLarge
 	default:
 		return http.StatusInternalServerError
 	}
@@ -14,31 +14,35 @@ type ObservabilityConfigResponse struct {
 }
 
 type OtelStatus struct {
-	Configured          bool    `json:"configured"`
-	Connected           bool    `json:"connected"`
-	LastSuccessExportAt *string `json:"lastSuccessExportAt,omitempty"`
-	ExportRatePerMin    float64 `json:"exportRatePerMin"`
-	DropRate            float64 `json:"dropRate"`
-	LastError           *string `json:"lastError,omitempty"`
+	Status              IntegrationStatus `json:"status"`
+	Configured          bool              `json:"configured"`
+	Connected           bool              `json:"connected"`
+	LastSuccessExportAt *string           `json:"lastSuccessExportAt,omitempty"`
+	ExportRatePerMin    float64           `json:"exportRatePerMin"`
+	DropRate            float64           `json:"dropRate"`
+	LastError           *string           `json:"lastError,omitempty"`
 }
 
 type PrometheusStatus struct {
-	Configured     bool    `json:"configured"`
-	Connected      bool    `json:"connected"`
-	ScrapeEndpoint *string `json:"scrapeEndpoint,omitempty"`
-	LastScrapeAt   *string `json:"lastScrapeAt,omitempty"`
+	Status         IntegrationStatus `json:"status"`
+	Configured     bool              `json:"configured"`
+	Connected      bool              `json:"connected"`
+	ScrapeEndpoint *string           `json:"scrapeEndpoint,omitempty"`
+	LastScrapeAt   *string           `json:"lastScrapeAt,omitempty"`
 }
 
 type LogsStatus struct {
-	Configured             bool    `json:"configured"`
-	Provider               *string `json:"provider,omitempty"`
-	LinkTemplateConfigured bool    `json:"linkTemplateConfigured"`
+	Status                 IntegrationStatus `json:"status"`
+	Configured             bool              `json:"configured"`
+	Provider               *string           `json:"provider,omitempty"`
+	LinkTemplateConfigured bool              `json:"linkTemplateConfigured"`
 }
 
 type AlertingStatus struct {
-	Configured bool     `json:"configured"`
-	Channels   []string `json:"channels"`
-	Events     []string `json:"events"`
+	Status     IntegrationStatus `json:"status"`
+	Configured bool              `json:"configured"`
+	Channels   []string          `json:"channels"`
+	Events     []string          `json:"events"`
 }
 
 type ObservabilityStatusResponse struct {
@@ -73,6 +77,18 @@ type TraceEntry struct {
 	Timestamp    string `json:"timestamp"`
 }
 
+type AlertDeliveryEntry struct {
+	ID        int64  `json:"id"`
+	Event     string `json:"event"`
+	Channel   string `json:"channel,omitempty"`
+	DedupeKey string `json:"dedupeKey,omitempty"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Attempts  int    `json:"attempts"`
+	Timestamp string `json:"timestamp"`
+}
+
 type SlowestStage struct {
 	PipelineName string `json:"pipelineName"`
 	StageName    string `json:"stageName"`
@@ -84,6 +100,22 @@ type ErrorHotspot struct {
 	StageName    string  `json:"stageName"`
 	FailureRate  float64 `json:"failureRate"`
 	AvgRetries   float64 `json:"avgRetries"`
+	// FailuresByCategory counts failed runs by failure category (timeout,
+	// handler_error, circuit_open, cancelled, or unknown for older runs).
+	FailuresByCategory map[string]int `json:"failuresByCategory,omitempty"`
+}
+
+// HandlerInsight aggregates the runs of one stage handler across every
+// pipeline that uses it.
+type HandlerInsight struct {
+	HandlerName        string         `json:"handlerName"`
+	Runs               int            `json:"runs"`
+	Pipelines          int            `json:"pipelines"`
+	AvgMs              float64        `json:"avgMs"`
+	P95Ms              int            `json:"p95Ms"`
+	FailureRate        float64        `json:"failureRate"`
+	AvgRetries         float64        `json:"avgRetries"`
+	FailuresByCategory map[string]int `json:"failuresByCategory,omitempty"`
 }
 
 type InsightsSummary struct {
@@ -94,9 +126,11 @@ type InsightsSummary struct {
 }
 
 type InsightsResponse struct {
-	SlowestStages []SlowestStage  `json:"slowestStages"`
-	ErrorHotspots []ErrorHotspot  `json:"errorHotspots"`
-	Summary       InsightsSummary `json:"summary"`
+	SlowestStages      []SlowestStage   `json:"slowestStages"`
+	ErrorHotspots      []ErrorHotspot   `json:"errorHotspots"`
+	SlowestHandlers    []HandlerInsight `json:"slowestHandlers"`
+	ErrorProneHandlers []HandlerInsight `json:"errorProneHandlers"`
+	Summary            InsightsSummary  `json:"summary"`
 }
 
 type ErrorEnvelope struct {
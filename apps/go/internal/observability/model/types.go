@@ -42,8 +42,11 @@ const (
 	IntegrationStatusNotConfigured IntegrationStatus = "not_configured"
 	IntegrationStatusConfigured    IntegrationStatus = "configured"
 	IntegrationStatusConnected     IntegrationStatus = "connected"
-	IntegrationStatusDisconnected  IntegrationStatus = "disconnected"
-	IntegrationStatusError         IntegrationStatus = "error"
+	// IntegrationStatusStale marks an integration that has succeeded before
+	// but not within the freshness window.
+	IntegrationStatusStale        IntegrationStatus = "stale"
+	IntegrationStatusDisconnected IntegrationStatus = "disconnected"
+	IntegrationStatusError        IntegrationStatus = "error"
 )
 
 type IntegrationHealth struct {
@@ -84,11 +87,45 @@ type TraceRecord struct {
 type StageMetricRecord struct {
 	PipelineName string
 	StageName    string
+	HandlerName  string
 	Status       string
-	StartedAt    *time.Time
-	FinishedAt   *time.Time
+	RetryAttempt int
+	// FailureCategory is the stage's latest failure category; empty for
+	// stages that never failed or failed before categories were recorded.
+	FailureCategory string
+	StartedAt       *time.Time
+	FinishedAt      *time.Time
 }
 
 type PipelineSummaryRecord struct {
 	Status string
 }
+
+// Alert delivery statuses recorded in the alert_delivery log.
+const (
+	AlertDeliverySent       = "sent"
+	AlertDeliveryFailed     = "failed"
+	AlertDeliverySuppressed = "suppressed"
+)
+
+// AlertDeliveryRecord is one alert send attempt, or one alert that was not
+// sent. Channel is empty for alerts suppressed before a channel was chosen,
+// and Reason says why (dedupe, rate_limit, startup_grace).
+type AlertDeliveryRecord struct {
+	ID        int64
+	TS        time.Time
+	Event     string
+	Channel   string
+	DedupeKey string
+	Status    string
+	Reason    string
+	Error     string
+	Attempts  int
+}
+
+type AlertDeliveryFilter struct {
+	Event  string
+	Status string
+	Since  *time.Time
+	Limit  int
+}
@@ -19,4 +19,8 @@ type Repository interface {
 	ListTraces(ctx context.Context, filter model.TraceFilter) ([]model.TraceRecord, error)
 	ListStageMetrics(ctx context.Context, since time.Time) ([]model.StageMetricRecord, error)
 	ListPipelineSummaries(ctx context.Context, since time.Time) ([]model.PipelineSummaryRecord, error)
+
+	InsertAlertDelivery(ctx context.Context, record model.AlertDeliveryRecord) error
+	ListAlertDeliveries(ctx context.Context, filter model.AlertDeliveryFilter) ([]model.AlertDeliveryRecord, error)
+	PruneAlertDeliveries(ctx context.Context, before time.Time) (int64, error)
 }
@@ -128,6 +128,27 @@ func (r *SQLRepository) UpsertIntegrationConfig(
 		return fmt.Errorf("marshal config json: %w", err)
 	}
 
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// The previous config is read under a row lock so that of two concurrent
+	// saves of the same change only one bumps the config version. SQLite has
+	// no FOR UPDATE; it runs one write transaction at a time anyway.
+	lock := " FOR UPDATE"
+	if r.db.DriverName() == "sqlite" {
+		lock = ""
+	}
+	var previous string
+	err = tx.GetContext(ctx, &previous, r.db.Rebind(`SELECT config_json FROM observability_integration_config WHERE type = ?`+lock),
+		string(integrationType))
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	changed := previous != string(configJSON)
+
 	now := time.Now().UTC()
 	query := r.db.Rebind(`
 		INSERT INTO observability_integration_config (type, config_json, status, created_at, updated_at)
@@ -138,7 +159,18 @@ func (r *SQLRepository) UpsertIntegrationConfig(
 			updated_at = excluded.updated_at
 	`)
 
-	if _, err := r.db.ExecContext(ctx, query, string(integrationType), string(configJSON), string(status), now, now); err != nil {
+	if _, err := tx.ExecContext(ctx, query, string(integrationType), string(configJSON), string(status), now, now); err != nil {
+		return err
+	}
+	// Workers receive these settings at bootstrap; a change moves them to a
+	// new config version so their heartbeats ask them to bootstrap again.
+	if changed {
+		bump := r.db.Rebind(`UPDATE config_version SET version = version + 1, updated_at = ? WHERE id = 1`)
+		if _, err := tx.ExecContext(ctx, bump, now); err != nil {
+			return fmt.Errorf("bump config version: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
 		return err
 	}
 
@@ -276,7 +308,10 @@ func (r *SQLRepository) ListStageMetrics(ctx context.Context, since time.Time) (
 		SELECT
 			COALESCE(p.name, '') AS pipeline_name,
 			COALESCE(s.name, '') AS stage_name,
+			COALESCE(s.stage_handler_name, '') AS handler_name,
 			COALESCE(s.status, '') AS status,
+			COALESCE(s.retry_attempt, 0) AS retry_attempt,
+			COALESCE(s.failure_category, '') AS failure_category,
 			s.started_at,
 			s.finished_at
 		FROM stage s
@@ -293,11 +328,14 @@ func (r *SQLRepository) ListStageMetrics(ctx context.Context, since time.Time) (
 	result := make([]model.StageMetricRecord, 0, len(rows))
 	for _, row := range rows {
 		result = append(result, model.StageMetricRecord{
-			PipelineName: row.PipelineName,
-			StageName:    row.StageName,
-			Status:       row.Status,
-			StartedAt:    nullTimeToPtr(row.StartedAt),
-			FinishedAt:   nullTimeToPtr(row.FinishedAt),
+			PipelineName:    row.PipelineName,
+			StageName:       row.StageName,
+			HandlerName:     row.HandlerName,
+			Status:          row.Status,
+			RetryAttempt:    row.RetryAttempt,
+			FailureCategory: row.FailureCategory,
+			StartedAt:       nullTimeToPtr(row.StartedAt),
+			FinishedAt:      nullTimeToPtr(row.FinishedAt),
 		})
 	}
 
@@ -323,6 +361,82 @@ func (r *SQLRepository) ListPipelineSummaries(ctx context.Context, since time.Ti
 	return result, nil
 }
 
+func (r *SQLRepository) InsertAlertDelivery(ctx context.Context, record model.AlertDeliveryRecord) error {
+	ts := record.TS
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	query := r.db.Rebind(`
+		INSERT INTO alert_delivery (ts, event, channel, dedupe_key, status, reason, error, attempts)
+		VALUES (?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, NULLIF(?, ''), NULLIF(?, ''), ?)
+	`)
+	_, err := r.db.ExecContext(ctx, query,
+		ts.UTC(), record.Event, record.Channel, record.DedupeKey, record.Status, record.Reason, record.Error, record.Attempts)
+	return err
+}
+
+func (r *SQLRepository) ListAlertDeliveries(ctx context.Context, filter model.AlertDeliveryFilter) ([]model.AlertDeliveryRecord, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	builder := strings.Builder{}
+	builder.WriteString(`
+		SELECT id, ts, event, channel, dedupe_key, status, reason, error, attempts
+		FROM alert_delivery
+		WHERE 1=1
+	`)
+	args := make([]any, 0)
+	if filter.Event != "" {
+		builder.WriteString(` AND event = ? `)
+		args = append(args, filter.Event)
+	}
+	if filter.Status != "" {
+		builder.WriteString(` AND status = ? `)
+		args = append(args, filter.Status)
+	}
+	if filter.Since != nil {
+		builder.WriteString(` AND ts >= ? `)
+		args = append(args, filter.Since.UTC())
+	}
+	builder.WriteString(`
+		ORDER BY ts DESC, id DESC
+		LIMIT ?
+	`)
+	args = append(args, limit)
+
+	rows := []alertDeliveryRow{}
+	if err := r.db.SelectContext(ctx, &rows, r.db.Rebind(builder.String()), args...); err != nil {
+		return nil, err
+	}
+
+	result := make([]model.AlertDeliveryRecord, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, model.AlertDeliveryRecord{
+			ID:        row.ID,
+			TS:        row.TS.UTC(),
+			Event:     row.Event,
+			Channel:   row.Channel.String,
+			DedupeKey: row.DedupeKey.String,
+			Status:    row.Status,
+			Reason:    row.Reason.String,
+			Error:     row.Error.String,
+			Attempts:  row.Attempts,
+		})
+	}
+	return result, nil
+}
+
+func (r *SQLRepository) PruneAlertDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	query := r.db.Rebind(`DELETE FROM alert_delivery WHERE ts < ?`)
+	result, err := r.db.ExecContext(ctx, query, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 func (r *SQLRepository) ensureHealthRow(ctx context.Context, integrationType model.IntegrationType) error {
 	query := r.db.Rebind(`
 		INSERT INTO observability_integration_health (type)
@@ -387,6 +501,18 @@ func toIntegration(row integrationRow) (model.Integration, error) {
 	}, nil
 }
 
+type alertDeliveryRow struct {
+	ID        int64          `db:"id"`
+	TS        time.Time      `db:"ts"`
+	Event     string         `db:"event"`
+	Channel   sql.NullString `db:"channel"`
+	DedupeKey sql.NullString `db:"dedupe_key"`
+	Status    string         `db:"status"`
+	Reason    sql.NullString `db:"reason"`
+	Error     sql.NullString `db:"error"`
+	Attempts  int            `db:"attempts"`
+}
+
 type traceRow struct {
 	PipelineID   int          `db:"pipeline_id"`
 	PipelineName string       `db:"pipeline_name"`
@@ -398,11 +524,14 @@ type traceRow struct {
 }
 
 type stageMetricRow struct {
-	PipelineName string       `db:"pipeline_name"`
-	StageName    string       `db:"stage_name"`
-	Status       string       `db:"status"`
-	StartedAt    sql.NullTime `db:"started_at"`
-	FinishedAt   sql.NullTime `db:"finished_at"`
+	PipelineName    string       `db:"pipeline_name"`
+	StageName       string       `db:"stage_name"`
+	HandlerName     string       `db:"handler_name"`
+	Status          string       `db:"status"`
+	RetryAttempt    int          `db:"retry_attempt"`
+	FailureCategory string       `db:"failure_category"`
+	StartedAt       sql.NullTime `db:"started_at"`
+	FinishedAt      sql.NullTime `db:"finished_at"`
 }
 
 type pipelineSummaryRow struct {
@@ -44,6 +44,45 @@ func TestSQLRepository_UpsertAndGetIntegration(t *testing.T) {
 	}
 }
 
+func TestSQLRepository_UpsertIntegrationConfigBumpsConfigVersion(t *testing.T) {
+	db := setupTestDB(t)
+	repository := NewSQLRepository(db)
+	ctx := context.Background()
+
+	if err := repository.EnsureIntegrations(ctx, model.SupportedIntegrationTypes); err != nil {
+		t.Fatalf("EnsureIntegrations() error = %v", err)
+	}
+	version := func() int {
+		t.Helper()
+		var v int
+		if err := db.Get(&v, `SELECT version FROM config_version WHERE id = 1`); err != nil {
+			t.Fatalf("load config version: %v", err)
+		}
+		return v
+	}
+
+	config := map[string]any{"searchUrlTemplate": "https://graylog/search?q={traceId}"}
+	steps := []struct {
+		name   string
+		config map[string]any
+		status model.IntegrationStatus
+		want   int
+	}{
+		{"first save", config, model.IntegrationStatusConfigured, 2},
+		{"same config", config, model.IntegrationStatusConfigured, 2},
+		{"status change only", config, model.IntegrationStatusConnected, 2},
+		{"config change", map[string]any{"searchUrlTemplate": "https://logs/{traceId}"}, model.IntegrationStatusConfigured, 3},
+	}
+	for _, step := range steps {
+		if err := repository.UpsertIntegrationConfig(ctx, model.IntegrationTypeGraylog, step.config, step.status); err != nil {
+			t.Fatalf("%s: UpsertIntegrationConfig() error = %v", step.name, err)
+		}
+		if got := version(); got != step.want {
+			t.Fatalf("%s: config version = %d, want %d", step.name, got, step.want)
+		}
+	}
+}
+
 func TestSQLRepository_RecordHealthSuccessAndFailure(t *testing.T) {
 	db := setupTestDB(t)
 	repository := NewSQLRepository(db)
@@ -91,6 +130,52 @@ func TestSQLRepository_RecordHealthSuccessAndFailure(t *testing.T) {
 	}
 }
 
+func TestSQLRepository_AlertDeliveries(t *testing.T) {
+	db := setupTestDB(t)
+	repository := NewSQLRepository(db)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	records := []model.AlertDeliveryRecord{
+		{TS: now.Add(-48 * time.Hour), Event: "stage_failed", Channel: "webhook", Status: model.AlertDeliverySent, Attempts: 1},
+		{TS: now.Add(-2 * time.Minute), Event: "stage_failed", DedupeKey: "stage_failed:1:2", Status: model.AlertDeliverySuppressed, Reason: "dedupe"},
+		{TS: now.Add(-time.Minute), Event: "worker_failed", Channel: "telegram", Status: model.AlertDeliveryFailed, Error: "telegram status 502", Attempts: 3},
+	}
+	for _, record := range records {
+		if err := repository.InsertAlertDelivery(ctx, record); err != nil {
+			t.Fatalf("InsertAlertDelivery() error = %v", err)
+		}
+	}
+
+	got, err := repository.ListAlertDeliveries(ctx, model.AlertDeliveryFilter{})
+	if err != nil {
+		t.Fatalf("ListAlertDeliveries() error = %v", err)
+	}
+	if len(got) != 3 || got[0].Event != "worker_failed" || got[0].Error != "telegram status 502" || got[0].Attempts != 3 {
+		t.Fatalf("ListAlertDeliveries() = %+v, want newest first", got)
+	}
+	if got[1].Reason != "dedupe" || got[1].Channel != "" || got[1].DedupeKey != "stage_failed:1:2" {
+		t.Fatalf("suppressed record = %+v", got[1])
+	}
+
+	since := now.Add(-time.Hour)
+	got, err = repository.ListAlertDeliveries(ctx, model.AlertDeliveryFilter{Event: "stage_failed", Since: &since})
+	if err != nil {
+		t.Fatalf("ListAlertDeliveries(filter) error = %v", err)
+	}
+	if len(got) != 1 || got[0].Status != model.AlertDeliverySuppressed {
+		t.Fatalf("ListAlertDeliveries(filter) = %+v, want the suppressed stage_failed record", got)
+	}
+
+	pruned, err := repository.PruneAlertDeliveries(ctx, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("PruneAlertDeliveries() error = %v", err)
+	}
+	if pruned != 1 {
+		t.Fatalf("PruneAlertDeliveries() = %d, want 1", pruned)
+	}
+}
+
 func setupTestDB(t *testing.T) *sqlx.DB {
 	t.Helper()
 
@@ -115,6 +200,24 @@ func setupTestDB(t *testing.T) *sqlx.DB {
 		export_rate_per_min REAL NOT NULL DEFAULT 0,
 		drop_rate REAL NOT NULL DEFAULT 0
 	);
+	CREATE TABLE config_version (
+		id INTEGER PRIMARY KEY,
+		version INTEGER NOT NULL DEFAULT 1,
+		broker_fingerprint TEXT NULL,
+		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	INSERT INTO config_version (id, version) VALUES (1, 1);
+	CREATE TABLE alert_delivery (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		ts TIMESTAMP NOT NULL,
+		event TEXT NOT NULL,
+		channel TEXT NULL,
+		dedupe_key TEXT NULL,
+		status TEXT NOT NULL,
+		reason TEXT NULL,
+		error TEXT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0
+	);
 	`
 
 	if _, err := db.Exec(schema); err != nil {
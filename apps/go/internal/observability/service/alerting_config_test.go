@@ -0,0 +1,97 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateAlertingConfigRateLimits(t *testing.T) {
+	valid := map[string]any{
+		"maxAlertsPerMinute":            float64(30),
+		"sendMaxRetries":                float64(0),
+		"sendTimeoutSeconds":            1.5,
+		"maxAlertsPerMinuteByEvent":     map[string]any{"worker_failed": float64(5)},
+		"dedupeWindowSecondsByEvent":    map[string]any{"pipeline_stuck": float64(3600)},
+		"workerOfflineAggregateSeconds": float64(30),
+	}
+	if err := validateAlertingConfig(valid, false); err != nil {
+		t.Fatalf("validateAlertingConfig(valid) error = %v", err)
+	}
+
+	invalid := map[string]map[string]any{
+		"negative cap":                 {"maxAlertsPerMinute": float64(-1)},
+		"fractional cap":               {"maxAlertsPerMinute": 2.5},
+		"unknown event":                {"maxAlertsPerMinuteByEvent": map[string]any{"nope": float64(1)}},
+		"zero override":                {"dedupeWindowSecondsByEvent": map[string]any{"stage_failed": float64(0)}},
+		"override not map":             {"maxAlertsPerMinuteByEvent": "worker_failed=5"},
+		"negative retries":             {"sendMaxRetries": float64(-1)},
+		"zero timeout":                 {"sendTimeoutSeconds": float64(0)},
+		"negative offline aggregation": {"workerOfflineAggregateSeconds": float64(-1)},
+	}
+	for name, config := range invalid {
+		err := validateAlertingConfig(config, false)
+		appErr, ok := err.(*AppError)
+		if !ok || appErr.Code != "invalid_config" {
+			t.Errorf("%s: validateAlertingConfig() error = %v, want invalid_config", name, err)
+		}
+	}
+}
+
+func TestValidateAlertingConfigMessageTemplates(t *testing.T) {
+	valid := map[string]any{"messageTemplates": map[string]any{
+		"telegram": `{{.Severity | upper}} {{.Title}}: {{.Message}} ({{.Details.pipelineName | default "-"}})`,
+		"webhook":  "",
+	}}
+	if err := validateAlertingConfig(valid, false); err != nil {
+		t.Fatalf("validateAlertingConfig(valid) error = %v", err)
+	}
+
+	invalid := map[string]map[string]any{
+		"parse error":     {"messageTemplates": map[string]any{"telegram": "{{.Title"}},
+		"unknown field":   {"messageTemplates": map[string]any{"telegram": "{{.Nope}}"}},
+		"unknown func":    {"messageTemplates": map[string]any{"telegram": `{{env "HOME"}}`}},
+		"empty output":    {"messageTemplates": map[string]any{"telegram": "{{if false}}x{{end}}"}},
+		"unknown channel": {"messageTemplates": map[string]any{"pigeon": "{{.Title}}"}},
+		"not a string":    {"messageTemplates": map[string]any{"telegram": float64(1)}},
+	}
+	for name, config := range invalid {
+		err := validateAlertingConfig(config, false)
+		appErr, ok := err.(*AppError)
+		if !ok || appErr.Code != "invalid_config" {
+			t.Errorf("%s: validateAlertingConfig() error = %v, want invalid_config", name, err)
+		}
+	}
+}
+
+func TestValidateAlertingConfigWebhooks(t *testing.T) {
+	valid := map[string]any{
+		"channels":      []any{"webhook"},
+		"enabledEvents": []any{"stage_failed"},
+		"webhookSecret": "shared",
+		"webhooks": []any{
+			map[string]any{"url": "https://ops.example.com/hooks/pipelogiq", "name": "ops", "secret": "s3cret"},
+			map[string]any{"url": "http://audit.internal:8080/alerts", "name": strings.Repeat("ü", 56)},
+		},
+	}
+	if err := validateAlertingConfig(valid, true); err != nil {
+		t.Fatalf("validateAlertingConfig(valid) error = %v", err)
+	}
+
+	invalid := map[string]map[string]any{
+		"not a list":     {"webhooks": "https://ops.example.com"},
+		"entry not map":  {"webhooks": []any{"https://ops.example.com"}},
+		"missing url":    {"webhooks": []any{map[string]any{"name": "ops"}}},
+		"relative url":   {"webhooks": []any{map[string]any{"url": "ops.example.com/hook"}}},
+		"duplicate url":  {"webhooks": []any{map[string]any{"url": "https://a.example.com"}, map[string]any{"url": "https://a.example.com"}}},
+		"secret not str": {"webhooks": []any{map[string]any{"url": "https://a.example.com", "secret": float64(1)}}},
+		"name too long":  {"webhooks": []any{map[string]any{"url": "https://a.example.com", "name": strings.Repeat("n", 57)}}},
+		"shared secret":  {"webhookSecret": true},
+	}
+	for name, config := range invalid {
+		err := validateAlertingConfig(config, false)
+		appErr, ok := err.(*AppError)
+		if !ok || appErr.Code != "invalid_config" {
+			t.Errorf("%s: validateAlertingConfig() error = %v, want invalid_config", name, err)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"pipelogiq/internal/observability/model"
+)
+
+func TestOTLPHTTPExportProbe(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		wantErr    bool
+		wantPath   string
+		configPath string
+	}{
+		{name: "accepted", status: http.StatusOK, wantPath: "/v1/traces"},
+		{name: "accepted async", status: http.StatusAccepted, wantPath: "/v1/traces"},
+		{name: "bad request still reachable", status: http.StatusBadRequest, wantPath: "/v1/traces"},
+		{name: "root path", configPath: "/", status: http.StatusOK, wantPath: "/v1/traces"},
+		{name: "custom base path", configPath: "/otlp", status: http.StatusOK, wantPath: "/otlp/v1/traces"},
+		{name: "custom base path with slash", configPath: "/otlp/", status: http.StatusOK, wantPath: "/otlp/v1/traces"},
+		{name: "custom traces path kept", configPath: "/otlp/v1/traces", status: http.StatusOK, wantPath: "/otlp/v1/traces"},
+		{name: "not an otlp endpoint", status: http.StatusNotFound, wantErr: true, wantPath: "/v1/traces"},
+		{name: "server error", status: http.StatusBadGateway, wantErr: true, wantPath: "/v1/traces"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath, gotBody, gotContentType string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				gotContentType = r.Header.Get("Content-Type")
+				body, _ := io.ReadAll(r.Body)
+				gotBody = string(body)
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			svc := New(nil, nil)
+			err := svc.testOTLPHTTPExport(context.Background(), server.URL+tt.configPath, map[string]string{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("testOTLPHTTPExport() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if gotPath != tt.wantPath {
+				t.Fatalf("request path = %q, want %q", gotPath, tt.wantPath)
+			}
+			if gotContentType != "application/json" {
+				t.Fatalf("content type = %q, want application/json", gotContentType)
+			}
+			if !strings.Contains(gotBody, `"resourceSpans"`) {
+				t.Fatalf("request body %q is not an OTLP export payload", gotBody)
+			}
+		})
+	}
+}
+
+func TestOTLPHTTPExportProbeConnectionRefused(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	endpoint := server.URL
+	server.Close()
+
+	svc := New(nil, nil)
+	if err := svc.testOTLPHTTPExport(context.Background(), endpoint, nil); err == nil {
+		t.Fatal("expected error for unreachable endpoint")
+	}
+}
+
+func TestDatadogConnectivityCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		apiKey  string
+		wantErr bool
+	}{
+		{name: "valid key", status: http.StatusOK, body: `{"valid":true}`, apiKey: "good"},
+		{name: "rejected key", status: http.StatusForbidden, body: `{"errors":["Forbidden"]}`, apiKey: "bad", wantErr: true},
+		{name: "invalid response", status: http.StatusOK, body: `{"valid":false}`, apiKey: "good", wantErr: true},
+		{name: "server error", status: http.StatusBadGateway, apiKey: "good", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath, gotKey string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				gotKey = r.Header.Get("DD-API-KEY")
+				w.WriteHeader(tt.status)
+				_, _ = io.WriteString(w, tt.body)
+			}))
+			defer server.Close()
+
+			svc := New(nil, nil)
+			err := svc.runConnectivityCheck(context.Background(), model.IntegrationTypeDatadog, map[string]any{
+				"site":   server.URL,
+				"apiKey": tt.apiKey,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runConnectivityCheck() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if gotPath != "/api/v1/validate" {
+				t.Fatalf("request path = %q, want /api/v1/validate", gotPath)
+			}
+			if gotKey != tt.apiKey {
+				t.Fatalf("DD-API-KEY = %q, want %q", gotKey, tt.apiKey)
+			}
+		})
+	}
+}
+
+func TestSentryConnectivityCheck(t *testing.T) {
+	var gotPath, gotAuth, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("X-Sentry-Auth")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	dsn := strings.Replace(server.URL, "://", "://publickey@", 1) + "/42"
+	svc := New(nil, nil)
+	err := svc.runConnectivityCheck(context.Background(), model.IntegrationTypeSentry, map[string]any{
+		"dsn":         dsn,
+		"environment": "staging",
+	})
+	if err != nil {
+		t.Fatalf("runConnectivityCheck() err = %v", err)
+	}
+	if gotPath != "/api/42/envelope/" {
+		t.Fatalf("request path = %q, want /api/42/envelope/", gotPath)
+	}
+	if !strings.Contains(gotAuth, "sentry_key=publickey") {
+		t.Fatalf("X-Sentry-Auth = %q, want sentry_key=publickey", gotAuth)
+	}
+	if !strings.Contains(gotBody, `"environment":"staging"`) {
+		t.Fatalf("envelope does not carry the environment: %s", gotBody)
+	}
+
+	err = svc.runConnectivityCheck(context.Background(), model.IntegrationTypeSentry, map[string]any{
+		"dsn":         server.URL + "/42",
+		"environment": "staging",
+	})
+	if err == nil {
+		t.Fatal("runConnectivityCheck() with a DSN missing its key returned nil error")
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"pipelogiq/internal/observability/model"
+)
+
+// alertingProbeFields are the alerting config keys whose URL is probed when no
+// healthEndpoint is set, in order of preference.
+var alertingProbeFields = []string{"webhookUrl", "slackWebhookUrl", "whatsappWebhookUrl", "teamsWebhookUrl"}
+
+// RunHealthChecks re-runs the connectivity check of every configured
+// integration each interval until ctx is done, so statuses stay fresh without
+// a manual test. The checks of one round are spread evenly over the interval.
+// A non-positive interval disables the checks.
+func (s *Service) RunHealthChecks(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.checkIntegrations(ctx, interval)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) checkIntegrations(ctx context.Context, interval time.Duration) {
+	integrations, err := s.listOrderedIntegrations(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Warn("list integrations for health checks failed", "err", err)
+		}
+		return
+	}
+
+	var due []model.IntegrationType
+	for _, integration := range integrations {
+		if periodicCheckSupported(integration.Type, integration.Config) {
+			due = append(due, integration.Type)
+		}
+	}
+	if len(due) == 0 {
+		return
+	}
+
+	gap := interval / time.Duration(len(due))
+	for i, integrationType := range due {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(gap):
+			}
+		}
+		s.checkIntegration(ctx, integrationType)
+	}
+}
+
+// checkIntegration reloads the integration so a config saved since the round
+// started is the one that gets checked.
+func (s *Service) checkIntegration(ctx context.Context, integrationType model.IntegrationType) {
+	integration, err := s.repo.GetIntegration(ctx, integrationType)
+	if err != nil {
+		s.logger.Warn("load integration for health check failed", "err", err, "type", integrationType)
+		return
+	}
+	if integration == nil || !periodicCheckSupported(integrationType, integration.Config) {
+		return
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, s.testTimeout)
+	err = s.runConnectivityCheck(checkCtx, integrationType, integration.Config)
+	cancel()
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		s.logger.Info("integration health check failed", "type", integrationType, "err", err)
+	}
+	s.recordConnectivityResult(ctx, integrationType, time.Now().UTC(), err)
+}
+
+// periodicCheckSupported reports whether an integration is fully configured
+// and has a probe that is safe to repeat unattended. Sentry is skipped because
+// its probe files a test event. Grafana and alerting configs without an HTTP
+// endpoint (token-only channels) are only validated, so there is nothing to
+// re-check.
+func periodicCheckSupported(integrationType model.IntegrationType, config map[string]any) bool {
+	if err := validateConfigByType(integrationType, config, true); err != nil {
+		return false
+	}
+
+	switch integrationType {
+	case model.IntegrationTypeOpenTelemetry, model.IntegrationTypePrometheus,
+		model.IntegrationTypeGraylog, model.IntegrationTypeDatadog:
+		return true
+	case model.IntegrationTypeAlerting:
+		if requiredString(config, "healthEndpoint") != "" {
+			return true
+		}
+		for _, field := range alertingProbeFields {
+			if requiredString(config, field) != "" {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"testing"
+
+	"pipelogiq/internal/observability/model"
+)
+
+func TestPeriodicCheckSupported(t *testing.T) {
+	tests := []struct {
+		name            string
+		integrationType model.IntegrationType
+		config          map[string]any
+		want            bool
+	}{
+		{
+			name:            "configured opentelemetry",
+			integrationType: model.IntegrationTypeOpenTelemetry,
+			config:          map[string]any{"endpoint": "collector:4317", "protocol": "grpc"},
+			want:            true,
+		},
+		{
+			name:            "incomplete opentelemetry",
+			integrationType: model.IntegrationTypeOpenTelemetry,
+			config:          map[string]any{"endpoint": "collector:4317"},
+		},
+		{
+			name:            "alerting with webhook",
+			integrationType: model.IntegrationTypeAlerting,
+			config:          map[string]any{"channels": []any{"slack"}, "enabledEvents": []any{"stage_failed"}, "slackWebhookUrl": "https://hooks.slack.com/x"},
+			want:            true,
+		},
+		{
+			name:            "token-only alerting",
+			integrationType: model.IntegrationTypeAlerting,
+			config:          map[string]any{"channels": []any{"telegram"}, "enabledEvents": []any{"stage_failed"}, "telegramBotToken": "token", "telegramChatId": "1"},
+		},
+		{
+			name:            "sentry sends a test event",
+			integrationType: model.IntegrationTypeSentry,
+			config:          map[string]any{"dsn": "https://key@sentry.example.com/1", "environment": "prod"},
+		},
+		{
+			name:            "grafana has no probe",
+			integrationType: model.IntegrationTypeGrafana,
+			config:          map[string]any{"dashboardUrl": "https://grafana.example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := periodicCheckSupported(tt.integrationType, tt.config); got != tt.want {
+				t.Fatalf("periodicCheckSupported() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
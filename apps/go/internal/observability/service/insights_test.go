@@ -0,0 +1,108 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"pipelogiq/internal/observability/model"
+)
+
+func TestComputeStageInsightsAvgRetries(t *testing.T) {
+	started := time.Now().UTC().Add(-time.Minute)
+	finished := started.Add(10 * time.Second)
+	record := func(status string, retries int) model.StageMetricRecord {
+		return model.StageMetricRecord{
+			PipelineName: "pipeline-a",
+			StageName:    "charge",
+			Status:       status,
+			RetryAttempt: retries,
+			StartedAt:    &started,
+			FinishedAt:   &finished,
+		}
+	}
+
+	_, hotspots, _ := computeStageInsights([]model.StageMetricRecord{
+		record("Failed", 3),
+		record("Completed", 1),
+		record("Completed", 0),
+		record("Completed", 0),
+	})
+	if len(hotspots) != 1 {
+		t.Fatalf("hotspots = %d, want 1", len(hotspots))
+	}
+	if got := hotspots[0].AvgRetries; got != 1 {
+		t.Fatalf("AvgRetries = %v, want 1", got)
+	}
+	if got := hotspots[0].FailureRate; got != 25 {
+		t.Fatalf("FailureRate = %v, want 25", got)
+	}
+}
+
+func TestComputeStageInsightsFailuresByCategory(t *testing.T) {
+	started := time.Now().UTC().Add(-time.Minute)
+	record := func(status, category string) model.StageMetricRecord {
+		return model.StageMetricRecord{
+			PipelineName:    "pipeline-a",
+			StageName:       "charge",
+			Status:          status,
+			FailureCategory: category,
+			StartedAt:       &started,
+		}
+	}
+
+	_, hotspots, _ := computeStageInsights([]model.StageMetricRecord{
+		record("Failed", "timeout"),
+		record("Failed", "timeout"),
+		record("Failed", "handler_error"),
+		record("Failed", ""),
+		record("Completed", ""),
+	})
+	if len(hotspots) != 1 {
+		t.Fatalf("hotspots = %d, want 1", len(hotspots))
+	}
+	want := map[string]int{"timeout": 2, "handler_error": 1, "unknown": 1}
+	got := hotspots[0].FailuresByCategory
+	if len(got) != len(want) {
+		t.Fatalf("FailuresByCategory = %v, want %v", got, want)
+	}
+	for category, count := range want {
+		if got[category] != count {
+			t.Fatalf("FailuresByCategory = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestComputeHandlerInsights(t *testing.T) {
+	started := time.Now().UTC().Add(-time.Minute)
+	record := func(pipeline, handler, status string, durationMs int) model.StageMetricRecord {
+		finished := started.Add(time.Duration(durationMs) * time.Millisecond)
+		return model.StageMetricRecord{
+			PipelineName: pipeline,
+			StageName:    "stage",
+			HandlerName:  handler,
+			Status:       status,
+			StartedAt:    &started,
+			FinishedAt:   &finished,
+		}
+	}
+
+	slowest, errorProne := computeHandlerInsights([]model.StageMetricRecord{
+		record("pipeline-a", "charge", "Failed", 400),
+		record("pipeline-b", "charge", "Completed", 200),
+		record("pipeline-a", "notify", "Completed", 50),
+		record("pipeline-a", "", "Failed", 9000),
+	})
+	if len(slowest) != 2 || slowest[0].HandlerName != "charge" || slowest[1].HandlerName != "notify" {
+		t.Fatalf("slowest = %+v, want charge then notify", slowest)
+	}
+	charge := slowest[0]
+	if charge.Runs != 2 || charge.Pipelines != 2 || charge.AvgMs != 300 || charge.P95Ms != 400 {
+		t.Fatalf("charge = %+v, want 2 runs in 2 pipelines, avg 300ms, p95 400ms", charge)
+	}
+	if len(errorProne) != 1 || errorProne[0].HandlerName != "charge" || errorProne[0].FailureRate != 50 {
+		t.Fatalf("errorProne = %+v, want charge at 50%%", errorProne)
+	}
+	if got := errorProne[0].FailuresByCategory["unknown"]; got != 1 {
+		t.Fatalf("FailuresByCategory = %v, want unknown: 1", errorProne[0].FailuresByCategory)
+	}
+}
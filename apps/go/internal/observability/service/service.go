@@ -14,16 +14,28 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 
 	"pipelogiq/internal/alerts"
+	"pipelogiq/internal/datadog"
 	"pipelogiq/internal/observability/model"
 	"pipelogiq/internal/observability/repo"
+	"pipelogiq/internal/sentry"
 )
 
 const (
 	defaultFreshnessWindow = 10 * time.Minute
 	defaultTestTimeout     = 5 * time.Second
 	maxConfigPayloadBytes  = 64 * 1024
+	otlpHTTPTracesPath     = "/v1/traces"
+
+	defaultAlertHistoryLimit = 100
+	maxAlertHistoryLimit     = 500
+
+	// maxAlertingWebhooks caps the alerting webhooks list.
+	maxAlertingWebhooks = 10
 )
 
 type Interface interface {
@@ -33,6 +45,7 @@ type Interface interface {
 	TestConnection(ctx context.Context, req model.TestConnectionRequest) (model.TestConnectionResult, error)
 	GetTraces(ctx context.Context, search, status, timeRange string) ([]model.TraceEntry, error)
 	GetInsights(ctx context.Context, timeRange string) (model.InsightsResponse, error)
+	GetAlertHistory(ctx context.Context, event, status, timeRange string, limit int) ([]model.AlertDeliveryEntry, error)
 }
 
 type Service struct {
@@ -184,6 +197,7 @@ func (s *Service) GetStatus(ctx context.Context) (model.ObservabilityStatusRespo
 
 	return model.ObservabilityStatusResponse{
 		Otel: model.OtelStatus{
+			Status:              otelStatus,
 			Configured:          otelStatus != model.IntegrationStatusNotConfigured,
 			Connected:           otelStatus == model.IntegrationStatusConnected,
 			LastSuccessExportAt: formatTimePtr(otel.Health.LastSuccessAt),
@@ -192,17 +206,20 @@ func (s *Service) GetStatus(ctx context.Context) (model.ObservabilityStatusRespo
 			LastError:           otel.Health.LastError,
 		},
 		Prometheus: model.PrometheusStatus{
+			Status:         promStatus,
 			Configured:     promStatus != model.IntegrationStatusNotConfigured,
 			Connected:      promStatus == model.IntegrationStatusConnected,
 			ScrapeEndpoint: scrapeEndpoint,
 			LastScrapeAt:   lastScrapeAt,
 		},
 		Logs: model.LogsStatus{
+			Status:                 logsStatus,
 			Configured:             logsStatus != model.IntegrationStatusNotConfigured,
 			Provider:               provider,
 			LinkTemplateConfigured: hasNonEmptyString(logs.Config, "searchUrlTemplate"),
 		},
 		Alerting: model.AlertingStatus{
+			Status:     alertingStatus,
 			Configured: alertingStatus != model.IntegrationStatusNotConfigured,
 			Channels:   alertChannels,
 			Events:     alertEvents,
@@ -254,23 +271,14 @@ func (s *Service) TestConnection(ctx context.Context, req model.TestConnectionRe
 	latencyMs := int(time.Since(started).Milliseconds())
 	now := time.Now().UTC()
 
+	s.recordConnectivityResult(ctx, integrationType, now, err)
 	if err != nil {
-		errMsg := err.Error()
-		if updateErr := s.repo.RecordHealthFailure(ctx, integrationType, now, errMsg); updateErr != nil {
-			s.logger.Error("record health failure failed", "err", updateErr, "type", integrationType)
-		}
-		_ = s.repo.UpdateIntegrationStatus(ctx, integrationType, model.IntegrationStatusDisconnected)
 		return model.TestConnectionResult{
 			Success: false,
-			Message: errMsg,
+			Message: err.Error(),
 		}, nil
 	}
 
-	if updateErr := s.repo.RecordHealthSuccess(ctx, integrationType, now); updateErr != nil {
-		s.logger.Error("record health success failed", "err", updateErr, "type", integrationType)
-	}
-	_ = s.repo.UpdateIntegrationStatus(ctx, integrationType, model.IntegrationStatusConnected)
-
 	successMessage := "Connection established successfully"
 	if integrationType == model.IntegrationTypeAlerting {
 		successMessage = "Test alert sent successfully"
@@ -328,8 +336,48 @@ func (s *Service) GetTraces(ctx context.Context, search, status, timeRange strin
 	return entries, nil
 }
 
+// GetAlertHistory lists recorded alert deliveries, newest first. limit is
+// capped at maxAlertHistoryLimit; 0 uses the default.
+func (s *Service) GetAlertHistory(ctx context.Context, event, status, timeRange string, limit int) ([]model.AlertDeliveryEntry, error) {
+	if limit <= 0 {
+		limit = defaultAlertHistoryLimit
+	}
+	filter := model.AlertDeliveryFilter{
+		Event:  strings.TrimSpace(event),
+		Status: strings.TrimSpace(status),
+		Limit:  min(limit, maxAlertHistoryLimit),
+	}
+	if since := parseTimeRangeStart(timeRange); since != nil {
+		filter.Since = since
+	}
+
+	rows, err := s.repo.ListAlertDeliveries(ctx, filter)
+	if err != nil {
+		if isMissingTableError(err) {
+			return []model.AlertDeliveryEntry{}, nil
+		}
+		return nil, err
+	}
+
+	entries := make([]model.AlertDeliveryEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, model.AlertDeliveryEntry{
+			ID:        row.ID,
+			Event:     row.Event,
+			Channel:   row.Channel,
+			DedupeKey: row.DedupeKey,
+			Status:    row.Status,
+			Reason:    row.Reason,
+			Error:     row.Error,
+			Attempts:  row.Attempts,
+			Timestamp: row.TS.UTC().Format(time.RFC3339),
+		})
+	}
+	return entries, nil
+}
+
 func (s *Service) GetInsights(ctx context.Context, timeRange string) (model.InsightsResponse, error) {
-	rangeDuration := parseTimeRangeDuration(timeRange)
+	rangeDuration := ParseTimeRangeDuration(timeRange)
 	if rangeDuration <= 0 {
 		rangeDuration = time.Hour
 	}
@@ -352,12 +400,15 @@ func (s *Service) GetInsights(ctx context.Context, timeRange string) (model.Insi
 	}
 
 	slowestStages, hotspots, avgStageMs := computeStageInsights(stageMetrics)
+	slowestHandlers, errorProneHandlers := computeHandlerInsights(stageMetrics)
 	summary := computeSummaryInsights(pipelineSummaries, avgStageMs, rangeDuration)
 
 	return model.InsightsResponse{
-		SlowestStages: slowestStages,
-		ErrorHotspots: hotspots,
-		Summary:       summary,
+		SlowestStages:      slowestStages,
+		ErrorHotspots:      hotspots,
+		SlowestHandlers:    slowestHandlers,
+		ErrorProneHandlers: errorProneHandlers,
+		Summary:            summary,
 	}, nil
 }
 
@@ -410,11 +461,15 @@ func (s *Service) runConnectivityCheck(ctx context.Context, integrationType mode
 			return errors.New("graylog baseUrl is required")
 		}
 		return s.testHTTPReachability(ctx, endpoint, http.MethodGet, nil)
+	case model.IntegrationTypeDatadog:
+		return datadog.NewClient(requiredString(config, "site"), requiredString(config, "apiKey")).Validate(ctx)
+	case model.IntegrationTypeSentry:
+		return sentry.SendTestEvent(ctx, requiredString(config, "dsn"), requiredString(config, "environment"))
 	case model.IntegrationTypeAlerting:
 		if endpoint := requiredString(config, "healthEndpoint"); endpoint != "" {
 			return s.testHTTPReachability(ctx, endpoint, http.MethodGet, nil)
 		}
-		for _, field := range []string{"webhookUrl", "slackWebhookUrl", "whatsappWebhookUrl", "teamsWebhookUrl"} {
+		for _, field := range alertingProbeFields {
 			if endpoint := requiredString(config, field); endpoint != "" {
 				return s.testHTTPReachability(ctx, endpoint, http.MethodPost, nil)
 			}
@@ -427,6 +482,23 @@ func (s *Service) runConnectivityCheck(ctx context.Context, integrationType mode
 	}
 }
 
+// recordConnectivityResult stores the outcome of a connectivity check in the
+// integration's health and status.
+func (s *Service) recordConnectivityResult(ctx context.Context, integrationType model.IntegrationType, now time.Time, err error) {
+	if err != nil {
+		if updateErr := s.repo.RecordHealthFailure(ctx, integrationType, now, err.Error()); updateErr != nil {
+			s.logger.Error("record health failure failed", "err", updateErr, "type", integrationType)
+		}
+		_ = s.repo.UpdateIntegrationStatus(ctx, integrationType, model.IntegrationStatusDisconnected)
+		return
+	}
+
+	if updateErr := s.repo.RecordHealthSuccess(ctx, integrationType, now); updateErr != nil {
+		s.logger.Error("record health success failed", "err", updateErr, "type", integrationType)
+	}
+	_ = s.repo.UpdateIntegrationStatus(ctx, integrationType, model.IntegrationStatusConnected)
+}
+
 func (s *Service) testOpenTelemetry(ctx context.Context, config map[string]any) error {
 	endpoint := requiredString(config, "endpoint")
 	protocol := strings.ToLower(requiredString(config, "protocol"))
@@ -438,11 +510,21 @@ func (s *Service) testOpenTelemetry(ctx context.Context, config map[string]any)
 		return errors.New("opentelemetry protocol must be grpc or http")
 	}
 
+	headers, err := extractHeaders(config)
+	if err != nil {
+		return err
+	}
+
 	if protocol == "grpc" {
 		hostPort, err := normalizeEndpointHostPort(endpoint, "4317")
 		if err != nil {
 			return err
 		}
+		// A bare dial only proves the port is open; an empty export exercises the
+		// OTLP service itself. Opt-in so routine tests don't hit the collector.
+		if exportProbe, _ := optionalBool(config, "grpcExportProbe"); exportProbe {
+			return s.testOTLPGRPCExport(ctx, endpoint, hostPort, config, headers)
+		}
 		dialer := net.Dialer{Timeout: s.testTimeout}
 		conn, err := dialer.DialContext(ctx, "tcp", hostPort)
 		if err != nil {
@@ -452,12 +534,89 @@ func (s *Service) testOpenTelemetry(ctx context.Context, config map[string]any)
 		return nil
 	}
 
-	headers, err := extractHeaders(config)
+	return s.testOTLPHTTPExport(ctx, endpoint, headers)
+}
+
+// testOTLPHTTPExport posts an empty JSON ExportTraceServiceRequest to the
+// traces path. The endpoint is a base URL, as OTEL_EXPORTER_OTLP_ENDPOINT is:
+// /v1/traces is appended to its path unless the path already ends with it.
+// 200/202 mean the collector accepted it; 400 still proves an OTLP receiver
+// parsed the request. Anything else is treated as a failure.
+func (s *Service) testOTLPHTTPExport(ctx context.Context, endpoint string, headers map[string]string) error {
+	rawURL, err := parseHTTPURL(endpoint)
 	if err != nil {
 		return err
 	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if !strings.HasSuffix(parsed.Path, otlpHTTPTracesPath) {
+		parsed.Path = strings.TrimSuffix(parsed.Path, "/") + otlpHTTPTracesPath
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, parsed.String(), strings.NewReader(`{"resourceSpans":[]}`))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		if strings.TrimSpace(key) == "" || strings.TrimSpace(value) == "" {
+			continue
+		}
+		req.Header.Set(key, value)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("otlp http export failed: %w", err)
+	}
+	defer resp.Body.Close()
 
-	return s.testHTTPReachability(ctx, endpoint, http.MethodPost, headers)
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusBadRequest:
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("otlp http endpoint not found at %s (status 404)", parsed.Path)
+	default:
+		return fmt.Errorf("otlp http endpoint returned status %d", resp.StatusCode)
+	}
+}
+
+func (s *Service) testOTLPGRPCExport(
+	ctx context.Context,
+	endpoint string,
+	hostPort string,
+	config map[string]any,
+	headers map[string]string,
+) error {
+	insecure := !strings.HasPrefix(strings.ToLower(strings.TrimSpace(endpoint)), "https://")
+	if value, ok := optionalBool(config, "tlsInsecure"); ok {
+		insecure = value
+	}
+
+	opts := []otlptracegrpc.Option{
+		otlptracegrpc.WithEndpoint(hostPort),
+		otlptracegrpc.WithTimeout(s.testTimeout),
+		otlptracegrpc.WithRetry(otlptracegrpc.RetryConfig{Enabled: false}),
+	}
+	if insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	if len(headers) > 0 {
+		opts = append(opts, otlptracegrpc.WithHeaders(headers))
+	}
+
+	client := otlptracegrpc.NewClient(opts...)
+	if err := client.Start(ctx); err != nil {
+		return fmt.Errorf("otlp grpc client start failed: %w", err)
+	}
+	defer func() { _ = client.Stop(context.Background()) }()
+
+	if err := client.UploadTraces(ctx, nil); err != nil {
+		return fmt.Errorf("otlp grpc export failed: %w", err)
+	}
+	return nil
 }
 
 func (s *Service) testHTTPReachability(ctx context.Context, rawURL string, method string, headers map[string]string) error {
@@ -525,12 +684,10 @@ func computeIntegrationStatus(
 	}
 
 	if health.LastSuccessAt != nil {
-		if health.LastTestedAt == nil || !health.LastTestedAt.After(*health.LastSuccessAt) {
-			return model.IntegrationStatusConnected
-		}
 		if now.Sub(health.LastSuccessAt.UTC()) <= freshnessWindow {
 			return model.IntegrationStatusConnected
 		}
+		return model.IntegrationStatusStale
 	}
 
 	return model.IntegrationStatusConfigured
@@ -571,12 +728,14 @@ func validateConfigByType(integrationType model.IntegrationType, config map[stri
 		if _, err := extractHeaders(config); err != nil {
 			return err
 		}
-		if _, ok := optionalBool(config, "tlsInsecure"); !ok && config != nil {
-			if _, exists := config["tlsInsecure"]; exists {
-				return &AppError{
-					Code:    "invalid_config",
-					Message: "OpenTelemetry tlsInsecure must be a boolean",
-					Details: map[string]any{"type": integrationType, "field": "tlsInsecure"},
+		for _, field := range []string{"tlsInsecure", "grpcExportProbe"} {
+			if _, ok := optionalBool(config, field); !ok && config != nil {
+				if _, exists := config[field]; exists {
+					return &AppError{
+						Code:    "invalid_config",
+						Message: "OpenTelemetry " + field + " must be a boolean",
+						Details: map[string]any{"type": integrationType, "field": field},
+					}
 				}
 			}
 		}
@@ -667,10 +826,12 @@ func validateAlertingConfig(config map[string]any, strict bool) error {
 
 	allowedEvents := map[string]struct{}{
 		"stage_failed":          {},
+		"stage_timeout_retry":   {},
 		"stage_rerun_manual":    {},
 		"stage_skipped_manual":  {},
 		"pipeline_failed":       {},
 		"pipeline_stuck":        {},
+		"stage_sla_breach":      {},
 		"worker_started":        {},
 		"worker_failed":         {},
 		"worker_stopped":        {},
@@ -679,6 +840,7 @@ func validateAlertingConfig(config map[string]any, strict bool) error {
 		"policy_changed":        {},
 		"queue_backlog_high":    {},
 		"dlq_message_detected":  {},
+		"api_key_expiring":      {},
 	}
 	for _, event := range events {
 		if _, ok := allowedEvents[event]; !ok {
@@ -698,6 +860,135 @@ func validateAlertingConfig(config map[string]any, strict bool) error {
 		}
 	}
 
+	if grace, ok := optionalFloat(config, "workerStartupGraceSeconds"); ok && grace < 0 {
+		return &AppError{
+			Code:    "invalid_config",
+			Message: "Alerting workerStartupGraceSeconds must not be negative",
+			Details: map[string]any{"type": model.IntegrationTypeAlerting, "field": "workerStartupGraceSeconds"},
+		}
+	}
+
+	if window, ok := optionalFloat(config, "workerOfflineAggregateSeconds"); ok && window < 0 {
+		return &AppError{
+			Code:    "invalid_config",
+			Message: "Alerting workerOfflineAggregateSeconds must not be negative",
+			Details: map[string]any{"type": model.IntegrationTypeAlerting, "field": "workerOfflineAggregateSeconds"},
+		}
+	}
+
+	if limit, ok := optionalFloat(config, "maxAlertsPerMinute"); ok && (limit < 0 || limit != math.Trunc(limit)) {
+		return &AppError{
+			Code:    "invalid_config",
+			Message: "Alerting maxAlertsPerMinute must be a non-negative integer",
+			Details: map[string]any{"type": model.IntegrationTypeAlerting, "field": "maxAlertsPerMinute"},
+		}
+	}
+
+	if retries, ok := optionalFloat(config, "sendMaxRetries"); ok && (retries < 0 || retries != math.Trunc(retries)) {
+		return &AppError{
+			Code:    "invalid_config",
+			Message: "Alerting sendMaxRetries must be a non-negative integer",
+			Details: map[string]any{"type": model.IntegrationTypeAlerting, "field": "sendMaxRetries"},
+		}
+	}
+
+	if timeout, ok := optionalFloat(config, "sendTimeoutSeconds"); ok && timeout <= 0 {
+		return &AppError{
+			Code:    "invalid_config",
+			Message: "Alerting sendTimeoutSeconds must be greater than 0",
+			Details: map[string]any{"type": model.IntegrationTypeAlerting, "field": "sendTimeoutSeconds"},
+		}
+	}
+
+	for _, field := range []string{"dedupeWindowSecondsByEvent", "maxAlertsPerMinuteByEvent"} {
+		raw, exists := config[field]
+		if !exists || raw == nil {
+			continue
+		}
+		overrides, ok := raw.(map[string]any)
+		if !ok {
+			return &AppError{
+				Code:    "invalid_config",
+				Message: "Alerting " + field + " must be an object keyed by event",
+				Details: map[string]any{"type": model.IntegrationTypeAlerting, "field": field},
+			}
+		}
+		for event := range overrides {
+			if _, ok := allowedEvents[event]; !ok {
+				return &AppError{
+					Code:    "invalid_config",
+					Message: "Unknown alerting event",
+					Details: map[string]any{"type": model.IntegrationTypeAlerting, "field": field, "value": event},
+				}
+			}
+			if value, ok := optionalFloat(overrides, event); !ok || value <= 0 {
+				return &AppError{
+					Code:    "invalid_config",
+					Message: "Alerting " + field + " values must be greater than 0",
+					Details: map[string]any{"type": model.IntegrationTypeAlerting, "field": field, "value": event},
+				}
+			}
+		}
+	}
+
+	if raw, exists := config["webhooks"]; exists && raw != nil {
+		if err := validateAlertingWebhooks(raw); err != nil {
+			return err
+		}
+	}
+	if raw, exists := config["webhookSecret"]; exists && raw != nil {
+		if _, ok := raw.(string); !ok {
+			return &AppError{
+				Code:    "invalid_config",
+				Message: "Alerting webhookSecret must be a string",
+				Details: map[string]any{"type": model.IntegrationTypeAlerting, "field": "webhookSecret"},
+			}
+		}
+	}
+
+	if raw, exists := config["messageTemplates"]; exists && raw != nil {
+		templates, ok := raw.(map[string]any)
+		if !ok {
+			return &AppError{
+				Code:    "invalid_config",
+				Message: "Alerting messageTemplates must be an object keyed by channel",
+				Details: map[string]any{"type": model.IntegrationTypeAlerting, "field": "messageTemplates"},
+			}
+		}
+		for channel, rawTemplate := range templates {
+			if _, ok := allowedChannels[channel]; !ok {
+				return &AppError{
+					Code:    "invalid_config",
+					Message: "Unknown alerting channel",
+					Details: map[string]any{"type": model.IntegrationTypeAlerting, "field": "messageTemplates", "value": channel},
+				}
+			}
+			text, ok := rawTemplate.(string)
+			if !ok {
+				return &AppError{
+					Code:    "invalid_config",
+					Message: "Alerting message templates must be strings",
+					Details: map[string]any{"type": model.IntegrationTypeAlerting, "field": "messageTemplates", "channel": channel},
+				}
+			}
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			if err := alerts.ValidateMessageTemplate(channel, text); err != nil {
+				return &AppError{
+					Code:    "invalid_config",
+					Message: "Alerting message template is invalid",
+					Details: map[string]any{
+						"type":    model.IntegrationTypeAlerting,
+						"field":   "messageTemplates",
+						"channel": channel,
+						"error":   err.Error(),
+					},
+				}
+			}
+		}
+	}
+
 	if _, ok := optionalBool(config, "sendResolved"); !ok && config != nil {
 		if _, exists := config["sendResolved"]; exists {
 			return &AppError{
@@ -711,6 +1002,68 @@ func validateAlertingConfig(config map[string]any, strict bool) error {
 	return nil
 }
 
+// validateAlertingWebhooks checks the webhooks list: at most
+// maxAlertingWebhooks entries, each an object with an absolute http(s) url
+// that no other entry repeats, an optional string name of at most
+// alerts.MaxWebhookNameLength characters, and an optional string secret.
+func validateAlertingWebhooks(raw any) error {
+	invalid := func(message string, index int) error {
+		details := map[string]any{"type": model.IntegrationTypeAlerting, "field": "webhooks"}
+		if index >= 0 {
+			details["index"] = index
+		}
+		return &AppError{Code: "invalid_config", Message: message, Details: details}
+	}
+
+	list, ok := raw.([]any)
+	if !ok {
+		return invalid("Alerting webhooks must be an array", -1)
+	}
+	if len(list) > maxAlertingWebhooks {
+		return invalid(fmt.Sprintf("Alerting webhooks accepts at most %d endpoints", maxAlertingWebhooks), -1)
+	}
+
+	seen := make(map[string]struct{}, len(list))
+	for i, item := range list {
+		entry, ok := item.(map[string]any)
+		if !ok {
+			return invalid("Alerting webhooks entries must be objects", i)
+		}
+		rawURL, _ := entry["url"].(string)
+		rawURL = strings.TrimSpace(rawURL)
+		parsed, err := url.Parse(rawURL)
+		if rawURL == "" || err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return invalid("Alerting webhook url must be an absolute http or https URL", i)
+		}
+		if _, dup := seen[rawURL]; dup {
+			return invalid("Alerting webhook url is listed more than once", i)
+		}
+		seen[rawURL] = struct{}{}
+		for _, key := range []string{"name", "secret"} {
+			if value, exists := entry[key]; exists && value != nil {
+				if _, ok := value.(string); !ok {
+					return invalid("Alerting webhook "+key+" must be a string", i)
+				}
+			}
+		}
+		if name, _ := entry["name"].(string); utf8.RuneCountInString(name) > alerts.MaxWebhookNameLength {
+			return invalid(fmt.Sprintf("Alerting webhook name must be at most %d characters", alerts.MaxWebhookNameLength), i)
+		}
+	}
+	return nil
+}
+
+// hasAlertingWebhook reports whether the webhooks list has an entry with a url.
+func hasAlertingWebhook(config map[string]any) bool {
+	list, _ := config["webhooks"].([]any)
+	for _, item := range list {
+		if entry, ok := item.(map[string]any); ok && hasNonEmptyString(entry, "url") {
+			return true
+		}
+	}
+	return false
+}
+
 func alertingChannelHasConfig(config map[string]any, channel string) bool {
 	switch channel {
 	case "telegram":
@@ -722,7 +1075,7 @@ func alertingChannelHasConfig(config map[string]any, channel string) bool {
 	case "email":
 		return hasNonEmptyString(config, "emailRecipients")
 	case "webhook":
-		return hasNonEmptyString(config, "webhookUrl")
+		return hasNonEmptyString(config, "webhookUrl") || hasAlertingWebhook(config)
 	case "pagerduty":
 		return hasNonEmptyString(config, "pagerdutyRoutingKey")
 	case "teams":
@@ -770,7 +1123,9 @@ func validateConfigSize(config map[string]any) error {
 	return nil
 }
 
-func parseTimeRangeDuration(raw string) time.Duration {
+// ParseTimeRangeDuration maps a dashboard range (15m, 1h, 6h, 24h/1d, 7d) to
+// its duration. Unknown values return 0.
+func ParseTimeRangeDuration(raw string) time.Duration {
 	switch strings.ToLower(strings.TrimSpace(raw)) {
 	case "15m":
 		return 15 * time.Minute
@@ -788,7 +1143,7 @@ func parseTimeRangeDuration(raw string) time.Duration {
 }
 
 func parseTimeRangeStart(raw string) *time.Time {
-	duration := parseTimeRangeDuration(raw)
+	duration := ParseTimeRangeDuration(raw)
 	if duration <= 0 {
 		return nil
 	}
@@ -814,6 +1169,8 @@ func computeStageInsights(stageMetrics []model.StageMetricRecord) ([]model.Slowe
 		DurationsMs  []int
 		Total        int
 		Failed       int
+		Retries      int
+		ByCategory   map[string]int
 	}
 
 	buckets := make(map[string]*bucket)
@@ -822,17 +1179,10 @@ func computeStageInsights(stageMetrics []model.StageMetricRecord) ([]model.Slowe
 	now := time.Now().UTC()
 
 	for _, metric := range stageMetrics {
-		if metric.StartedAt == nil {
+		durationMs, ok := stageDurationMs(metric, now)
+		if !ok {
 			continue
 		}
-		end := now
-		if metric.FinishedAt != nil {
-			end = metric.FinishedAt.UTC()
-		}
-		durationMs := int(end.Sub(metric.StartedAt.UTC()).Milliseconds())
-		if durationMs < 0 {
-			durationMs = 0
-		}
 
 		key := metric.PipelineName + "::" + metric.StageName
 		if _, ok := buckets[key]; !ok {
@@ -842,7 +1192,12 @@ func computeStageInsights(stageMetrics []model.StageMetricRecord) ([]model.Slowe
 		buckets[key].Total++
 		if strings.EqualFold(metric.Status, "Failed") {
 			buckets[key].Failed++
+			if buckets[key].ByCategory == nil {
+				buckets[key].ByCategory = make(map[string]int)
+			}
+			buckets[key].ByCategory[failureCategoryLabel(metric.FailureCategory)]++
 		}
+		buckets[key].Retries += metric.RetryAttempt
 
 		totalDuration += durationMs
 		totalCount++
@@ -856,27 +1211,19 @@ func computeStageInsights(stageMetrics []model.StageMetricRecord) ([]model.Slowe
 			continue
 		}
 
-		sort.Ints(bucket.DurationsMs)
-		p95Index := int(math.Ceil(float64(len(bucket.DurationsMs))*0.95)) - 1
-		if p95Index < 0 {
-			p95Index = 0
-		}
-		if p95Index >= len(bucket.DurationsMs) {
-			p95Index = len(bucket.DurationsMs) - 1
-		}
-
 		slowest = append(slowest, model.SlowestStage{
 			PipelineName: bucket.PipelineName,
 			StageName:    bucket.StageName,
-			P95Ms:        bucket.DurationsMs[p95Index],
+			P95Ms:        p95Ms(bucket.DurationsMs),
 		})
 
 		if bucket.Failed > 0 && bucket.Total > 0 {
 			hotspots = append(hotspots, model.ErrorHotspot{
-				PipelineName: bucket.PipelineName,
-				StageName:    bucket.StageName,
-				FailureRate:  float64(bucket.Failed) / float64(bucket.Total) * 100,
-				AvgRetries:   0,
+				PipelineName:       bucket.PipelineName,
+				StageName:          bucket.StageName,
+				FailureRate:        float64(bucket.Failed) / float64(bucket.Total) * 100,
+				AvgRetries:         float64(bucket.Retries) / float64(bucket.Total),
+				FailuresByCategory: bucket.ByCategory,
 			})
 		}
 	}
@@ -903,6 +1250,131 @@ func computeStageInsights(stageMetrics []model.StageMetricRecord) ([]model.Slowe
 	return slowest, hotspots, avgStageMs
 }
 
+// computeHandlerInsights groups stage runs by handler, whichever pipeline ran
+// them, and returns the handlers with the highest p95 duration and those with
+// the highest failure rate. Stages without a handler are skipped.
+func computeHandlerInsights(stageMetrics []model.StageMetricRecord) ([]model.HandlerInsight, []model.HandlerInsight) {
+	type bucket struct {
+		DurationsMs []int
+		TotalMs     int
+		Failed      int
+		Retries     int
+		Pipelines   map[string]struct{}
+		ByCategory  map[string]int
+	}
+
+	buckets := make(map[string]*bucket)
+	now := time.Now().UTC()
+
+	for _, metric := range stageMetrics {
+		if metric.HandlerName == "" {
+			continue
+		}
+		durationMs, ok := stageDurationMs(metric, now)
+		if !ok {
+			continue
+		}
+
+		b, ok := buckets[metric.HandlerName]
+		if !ok {
+			b = &bucket{Pipelines: make(map[string]struct{})}
+			buckets[metric.HandlerName] = b
+		}
+		b.DurationsMs = append(b.DurationsMs, durationMs)
+		b.TotalMs += durationMs
+		b.Retries += metric.RetryAttempt
+		b.Pipelines[metric.PipelineName] = struct{}{}
+		if strings.EqualFold(metric.Status, "Failed") {
+			b.Failed++
+			if b.ByCategory == nil {
+				b.ByCategory = make(map[string]int)
+			}
+			b.ByCategory[failureCategoryLabel(metric.FailureCategory)]++
+		}
+	}
+
+	slowest := make([]model.HandlerInsight, 0, len(buckets))
+	errorProne := make([]model.HandlerInsight, 0, len(buckets))
+	for handler, b := range buckets {
+		runs := len(b.DurationsMs)
+		insight := model.HandlerInsight{
+			HandlerName:        handler,
+			Runs:               runs,
+			Pipelines:          len(b.Pipelines),
+			AvgMs:              float64(b.TotalMs) / float64(runs),
+			P95Ms:              p95Ms(b.DurationsMs),
+			FailureRate:        float64(b.Failed) / float64(runs) * 100,
+			AvgRetries:         float64(b.Retries) / float64(runs),
+			FailuresByCategory: b.ByCategory,
+		}
+		slowest = append(slowest, insight)
+		if b.Failed > 0 {
+			errorProne = append(errorProne, insight)
+		}
+	}
+
+	sort.Slice(slowest, func(i, j int) bool {
+		if slowest[i].P95Ms != slowest[j].P95Ms {
+			return slowest[i].P95Ms > slowest[j].P95Ms
+		}
+		return slowest[i].HandlerName < slowest[j].HandlerName
+	})
+	if len(slowest) > 10 {
+		slowest = slowest[:10]
+	}
+
+	sort.Slice(errorProne, func(i, j int) bool {
+		if errorProne[i].FailureRate != errorProne[j].FailureRate {
+			return errorProne[i].FailureRate > errorProne[j].FailureRate
+		}
+		return errorProne[i].Runs > errorProne[j].Runs
+	})
+	if len(errorProne) > 10 {
+		errorProne = errorProne[:10]
+	}
+
+	return slowest, errorProne
+}
+
+// stageDurationMs is the run time of a started stage, up to now for stages
+// still running. It reports false for stages that never started.
+func stageDurationMs(metric model.StageMetricRecord, now time.Time) (int, bool) {
+	if metric.StartedAt == nil {
+		return 0, false
+	}
+	end := now
+	if metric.FinishedAt != nil {
+		end = metric.FinishedAt.UTC()
+	}
+	durationMs := int(end.Sub(metric.StartedAt.UTC()).Milliseconds())
+	if durationMs < 0 {
+		durationMs = 0
+	}
+	return durationMs, true
+}
+
+// p95Ms sorts durationsMs in place and returns its 95th percentile.
+func p95Ms(durationsMs []int) int {
+	sort.Ints(durationsMs)
+	index := int(math.Ceil(float64(len(durationsMs))*0.95)) - 1
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(durationsMs) {
+		index = len(durationsMs) - 1
+	}
+	return durationsMs[index]
+}
+
+// failureCategoryLabel reports runs that failed before categories were
+// recorded as "unknown".
+func failureCategoryLabel(category string) string {
+	if category == "" {
+		return "unknown"
+	}
+	return category
+}
+
 func computeSummaryInsights(pipelines []model.PipelineSummaryRecord, avgStageMs float64, rangeDuration time.Duration) model.InsightsSummary {
 	total := len(pipelines)
 	failed := 0
@@ -932,9 +1404,11 @@ func computeSummaryInsights(pipelines []model.PipelineSummaryRecord, avgStageMs
 
 func emptyInsights() model.InsightsResponse {
 	return model.InsightsResponse{
-		SlowestStages: []model.SlowestStage{},
-		ErrorHotspots: []model.ErrorHotspot{},
-		Summary:       model.InsightsSummary{},
+		SlowestStages:      []model.SlowestStage{},
+		ErrorHotspots:      []model.ErrorHotspot{},
+		SlowestHandlers:    []model.HandlerInsight{},
+		ErrorProneHandlers: []model.HandlerInsight{},
+		Summary:            model.InsightsSummary{},
 	}
 }
 
@@ -50,7 +50,7 @@ func TestComputeIntegrationStatusMatrix(t *testing.T) {
 			integrationType: model.IntegrationTypeOpenTelemetry,
 		},
 		{
-			name: "connected when last test succeeded even if stale",
+			name: "stale when last success is older than freshness window",
 			config: map[string]any{
 				"endpoint": "collector:4317",
 				"protocol": "grpc",
@@ -59,9 +59,21 @@ func TestComputeIntegrationStatusMatrix(t *testing.T) {
 				LastSuccessAt: timePtr(now.Add(-24 * time.Hour)),
 				LastTestedAt:  timePtr(now.Add(-24 * time.Hour)),
 			},
-			expectedStatus:  model.IntegrationStatusConnected,
+			expectedStatus:  model.IntegrationStatusStale,
 			integrationType: model.IntegrationTypeOpenTelemetry,
 		},
+		{
+			name: "stale when a later test recorded no error",
+			config: map[string]any{
+				"scrapeEndpoint": "/metrics",
+			},
+			health: model.IntegrationHealth{
+				LastSuccessAt: timePtr(now.Add(-2 * time.Hour)),
+				LastTestedAt:  timePtr(now.Add(-time.Minute)),
+			},
+			expectedStatus:  model.IntegrationStatusStale,
+			integrationType: model.IntegrationTypePrometheus,
+		},
 		{
 			name: "disconnected when last test failed",
 			config: map[string]any{
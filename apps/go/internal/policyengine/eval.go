@@ -0,0 +1,500 @@
+package policyengine
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"pipelogiq/internal/types"
+)
+
+// Circuit breaker states reported in a PolicyDecision.
+const (
+	CircuitStateClosed = "closed"
+	CircuitStateOpen   = "open"
+)
+
+// Keys a concurrency limit counts in-flight stages by.
+const (
+	ConcurrencyKeyGlobal  = "global"
+	ConcurrencyKeyHandler = "handler"
+	ConcurrencyKeyTenant  = "tenant"
+)
+
+// ReasonThrottle is recorded on trigger events when a concurrency limit held
+// back a stage.
+const ReasonThrottle = "throttle"
+
+var scheduleDays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Evaluate decides what policy would do for event at now. It is the
+// single place policy semantics live so enforcement and simulation cannot
+// drift.
+func Evaluate(policy types.Policy, event types.PolicySimulateRequest, now time.Time) (types.PolicyDecision, error) {
+	decision := types.PolicyDecision{
+		PolicyID: policy.ID,
+		Active:   policy.Status == types.PolicyStatusActive,
+		InWindow: ScheduleActive(policy.Targeting.Schedule, now),
+	}
+
+	if reason := MismatchReason(policy, event); reason != "" {
+		decision.Reason = reason
+		return decision, nil
+	}
+	decision.Matched = true
+
+	if !decision.Active {
+		decision.Reason = fmt.Sprintf("policy is %s", policy.Status)
+		return decision, nil
+	}
+	if !decision.InWindow {
+		decision.Reason = "outside active schedule"
+		return decision, nil
+	}
+
+	switch policy.Type {
+	case types.PolicyTypeRateLimit:
+		return evaluateRateLimit(decision, policy.Rule, event.Timestamps)
+	case types.PolicyTypeRetry:
+		return evaluateRetry(decision, policy.Rule, event), nil
+	case types.PolicyTypeTimeout:
+		return evaluateTimeout(decision, policy.Rule, event.DurationMs)
+	case types.PolicyTypeCircuitBreaker:
+		return evaluateCircuitBreaker(decision, policy.Rule, event.Timestamps)
+	case types.PolicyTypeConcurrencyLimit:
+		return evaluateConcurrencyLimit(decision, policy.Rule, event.InFlight)
+	default:
+		return decision, errors.New("unsupported policy type")
+	}
+}
+
+// ResolveEffective picks, per policy type, the highest-priority policy
+// that is active, inside its schedule and matches event. Ties go to the most
+// recently updated policy, then to the lowest id, so the result is stable.
+func ResolveEffective(policies []types.Policy, event types.PolicySimulateRequest, now time.Time) []types.PolicyEffectiveEntry {
+	byType := make(map[types.PolicyType][]types.Policy)
+	for _, policy := range policies {
+		if policy.Status != types.PolicyStatusActive || !ScheduleActive(policy.Targeting.Schedule, now) {
+			continue
+		}
+		if MismatchReason(policy, event) != "" {
+			continue
+		}
+		byType[policy.Type] = append(byType[policy.Type], policy)
+	}
+
+	entries := make([]types.PolicyEffectiveEntry, 0, len(byType))
+	for policyType, matches := range byType {
+		sort.Slice(matches, func(i, j int) bool {
+			left, right := matches[i], matches[j]
+			if left.Priority != right.Priority {
+				return left.Priority > right.Priority
+			}
+			if !left.UpdatedAt.Equal(right.UpdatedAt) {
+				return left.UpdatedAt.After(right.UpdatedAt)
+			}
+			return left.ID < right.ID
+		})
+
+		overridden := make([]string, 0, len(matches)-1)
+		for _, policy := range matches[1:] {
+			overridden = append(overridden, policy.ID)
+		}
+		entries = append(entries, types.PolicyEffectiveEntry{
+			Type:       policyType,
+			Policy:     matches[0],
+			Overridden: overridden,
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Type < entries[j].Type })
+	return entries
+}
+
+// ScheduleActive reports whether now falls in one of the schedule's
+// windows. A nil schedule is always active; an unknown timezone never is.
+func ScheduleActive(schedule *types.PolicySchedule, now time.Time) bool {
+	if schedule == nil {
+		return true
+	}
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return false
+	}
+
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	today := local.Weekday()
+	yesterday := (today + 6) % 7
+
+	for _, window := range schedule.Windows {
+		start, errStart := parseScheduleClock(window.Start)
+		end, errEnd := parseScheduleClock(window.End)
+		if errStart != nil || errEnd != nil {
+			continue
+		}
+		if start < end {
+			if scheduleHasDay(window.Days, today) && minute >= start && minute < end {
+				return true
+			}
+			continue
+		}
+		if scheduleHasDay(window.Days, today) && minute >= start {
+			return true
+		}
+		if scheduleHasDay(window.Days, yesterday) && minute < end {
+			return true
+		}
+	}
+	return false
+}
+
+func scheduleHasDay(days []string, day time.Weekday) bool {
+	if len(days) == 0 {
+		return true
+	}
+	for _, raw := range days {
+		if d, ok := scheduleDays[strings.ToLower(strings.TrimSpace(raw))]; ok && d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// parseScheduleClock parses HH:MM into minutes since midnight.
+func parseScheduleClock(value string) (int, error) {
+	parsed, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, err
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+func ValidateSchedule(schedule *types.PolicySchedule) error {
+	if schedule == nil {
+		return nil
+	}
+	timezone := strings.TrimSpace(schedule.Timezone)
+	if timezone == "" {
+		return errors.New("schedule timezone is required")
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return errors.New("schedule timezone is invalid")
+	}
+	if len(schedule.Windows) == 0 {
+		return errors.New("schedule must have at least one window")
+	}
+	for _, window := range schedule.Windows {
+		for _, day := range window.Days {
+			if _, ok := scheduleDays[strings.ToLower(strings.TrimSpace(day))]; !ok {
+				return fmt.Errorf("schedule day %q is invalid", day)
+			}
+		}
+		start, err := parseScheduleClock(window.Start)
+		if err != nil {
+			return errors.New("schedule window start must be HH:MM")
+		}
+		end, err := parseScheduleClock(window.End)
+		if err != nil {
+			return errors.New("schedule window end must be HH:MM")
+		}
+		if start == end {
+			return errors.New("schedule window start and end must differ")
+		}
+	}
+	return nil
+}
+
+func CloneSchedule(schedule *types.PolicySchedule) *types.PolicySchedule {
+	if schedule == nil {
+		return nil
+	}
+	cloned := &types.PolicySchedule{
+		Timezone: strings.TrimSpace(schedule.Timezone),
+		Windows:  make([]types.PolicyScheduleWindow, 0, len(schedule.Windows)),
+	}
+	for _, window := range schedule.Windows {
+		days := make([]string, 0, len(window.Days))
+		for _, day := range dedupeFold(window.Days) {
+			days = append(days, strings.ToLower(day))
+		}
+		cloned.Windows = append(cloned.Windows, types.PolicyScheduleWindow{
+			Days:  days,
+			Start: strings.TrimSpace(window.Start),
+			End:   strings.TrimSpace(window.End),
+		})
+	}
+	return cloned
+}
+
+// MismatchReason returns why event falls outside the policy's
+// environment and targeting, or "" when the policy applies. Empty event
+// fields match any target.
+func MismatchReason(policy types.Policy, event types.PolicySimulateRequest) string {
+	if policy.Environment != types.PolicyEnvironmentAll && event.Environment != "" && event.Environment != policy.Environment {
+		return "environment does not match"
+	}
+
+	targeting := policy.Targeting
+	if len(targeting.Pipelines) > 0 && event.PipelineID != "" && !containsFold(targeting.Pipelines, event.PipelineID) {
+		return "pipeline is not targeted"
+	}
+	if len(targeting.Stages) > 0 && event.Stage != "" && !containsFold(targeting.Stages, event.Stage) {
+		return "stage is not targeted"
+	}
+	if len(targeting.Handlers) > 0 && event.Handler != "" && !containsFold(targeting.Handlers, event.Handler) {
+		return "handler is not targeted"
+	}
+
+	tags := make(map[string]struct{}, len(event.Tags))
+	for _, tag := range event.Tags {
+		tags[strings.ToLower(strings.TrimSpace(tag))] = struct{}{}
+	}
+	include := make(map[string]struct{}, len(targeting.TagsInclude))
+	for _, tag := range targeting.TagsInclude {
+		include[strings.ToLower(tag)] = struct{}{}
+	}
+	exclude := make(map[string]struct{}, len(targeting.TagsExclude))
+	for _, tag := range targeting.TagsExclude {
+		exclude[strings.ToLower(tag)] = struct{}{}
+	}
+	if !containsAll(tags, include) {
+		return "required tags are missing"
+	}
+	if containsAny(tags, exclude) {
+		return "event has an excluded tag"
+	}
+
+	return ""
+}
+
+// evaluateRateLimit replays timestamps through a sliding window that admits
+// limit+burst events per windowSeconds.
+func evaluateRateLimit(decision types.PolicyDecision, rule types.PolicyRule, timestamps []time.Time) (types.PolicyDecision, error) {
+	if len(timestamps) == 0 {
+		return decision, errors.New("timestamps are required for rate limit policies")
+	}
+	if rule.Limit == nil || rule.WindowSeconds == nil {
+		return decision, errors.New("rate limit policy is missing limit or window")
+	}
+
+	capacity := *rule.Limit
+	if rule.Burst != nil {
+		capacity += *rule.Burst
+	}
+	window := time.Duration(*rule.WindowSeconds) * time.Second
+
+	order := make([]int, len(timestamps))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return timestamps[order[i]].Before(timestamps[order[j]]) })
+
+	throttled := make([]bool, len(timestamps))
+	var admitted []time.Time
+	allowed := 0
+	for _, i := range order {
+		ts := timestamps[i]
+		for len(admitted) > 0 && !admitted[0].After(ts.Add(-window)) {
+			admitted = admitted[1:]
+		}
+		if len(admitted) >= capacity {
+			throttled[i] = true
+			continue
+		}
+		admitted = append(admitted, ts)
+		allowed++
+	}
+
+	throttledCount := len(timestamps) - allowed
+	decision.AllowedCount = &allowed
+	decision.ThrottledCount = &throttledCount
+	decision.Throttled = throttled
+	if throttledCount > 0 {
+		decision.Triggered = true
+		decision.Blocked = true
+		decision.Reason = "throttled"
+	}
+	return decision, nil
+}
+
+// evaluateRetry decides whether a failed attempt is retried and after which
+// delay. Attempt counts the attempts already made and defaults to 1. With
+// jitter enabled the delay is the upper bound of the randomized range.
+func evaluateRetry(decision types.PolicyDecision, rule types.PolicyRule, event types.PolicySimulateRequest) types.PolicyDecision {
+	attempt := max(event.Attempt, 1)
+	decision.Triggered = true
+
+	shouldRetry := false
+	switch {
+	case !isRetryableError(rule.RetryOn, event.ErrorCode, event.HTTPStatus):
+		decision.Reason = "error is not retryable"
+	case rule.MaxAttempts != nil && attempt >= *rule.MaxAttempts:
+		decision.Reason = "retries exhausted"
+	default:
+		shouldRetry = true
+		decision.Reason = "retry scheduled"
+		delay := retryDelayMs(rule, attempt)
+		decision.NextRetryDelayMs = &delay
+	}
+	decision.ShouldRetry = &shouldRetry
+	decision.Blocked = !shouldRetry
+	return decision
+}
+
+func isRetryableError(retryOn *types.RetryOnRule, errorCode string, httpStatus *int) bool {
+	if retryOn == nil || (len(retryOn.HTTPStatus) == 0 && len(retryOn.ErrorCodes) == 0) {
+		return true
+	}
+	if httpStatus != nil {
+		for _, status := range retryOn.HTTPStatus {
+			if status == *httpStatus {
+				return true
+			}
+		}
+	}
+	return errorCode != "" && containsFold(retryOn.ErrorCodes, errorCode)
+}
+
+// retryDelayMs returns the delay before the retry following attempt.
+func retryDelayMs(rule types.PolicyRule, attempt int) int {
+	if rule.BaseDelayMs == nil {
+		return 0
+	}
+	delay := *rule.BaseDelayMs
+	if rule.Backoff != nil && *rule.Backoff == "exponential" {
+		for i := 1; i < attempt; i++ {
+			if rule.MaxDelayMs != nil && delay >= *rule.MaxDelayMs {
+				break
+			}
+			if delay > math.MaxInt32/2 {
+				break
+			}
+			delay *= 2
+		}
+	}
+	if rule.MaxDelayMs != nil {
+		delay = min(delay, *rule.MaxDelayMs)
+	}
+	return delay
+}
+
+func evaluateTimeout(decision types.PolicyDecision, rule types.PolicyRule, durationMs *int) (types.PolicyDecision, error) {
+	if durationMs == nil {
+		return decision, errors.New("durationMs is required for timeout policies")
+	}
+	if rule.TimeoutMs != nil && *durationMs > *rule.TimeoutMs {
+		decision.Triggered = true
+		decision.Blocked = true
+		decision.Reason = "timeout exceeded"
+	}
+	return decision, nil
+}
+
+// evaluateConcurrencyLimit blocks when inFlight stages sharing the policy key
+// already reach maxConcurrent.
+func evaluateConcurrencyLimit(decision types.PolicyDecision, rule types.PolicyRule, inFlight *int) (types.PolicyDecision, error) {
+	if inFlight == nil {
+		return decision, errors.New("inFlight is required for concurrency limit policies")
+	}
+	if rule.MaxConcurrent != nil && *inFlight >= *rule.MaxConcurrent {
+		decision.Triggered = true
+		decision.Blocked = true
+		decision.Reason = ReasonThrottle
+	}
+	return decision, nil
+}
+
+// evaluateCircuitBreaker treats timestamps as failure times and reports the
+// breaker state as of the latest failure.
+func evaluateCircuitBreaker(decision types.PolicyDecision, rule types.PolicyRule, failures []time.Time) (types.PolicyDecision, error) {
+	if len(failures) == 0 {
+		return decision, errors.New("timestamps are required for circuit breaker policies")
+	}
+	if rule.FailureThreshold == nil || rule.WindowSeconds == nil {
+		return decision, errors.New("circuit breaker policy is missing threshold or window")
+	}
+
+	latest := failures[0]
+	for _, ts := range failures[1:] {
+		if ts.After(latest) {
+			latest = ts
+		}
+	}
+	windowStart := latest.Add(-time.Duration(*rule.WindowSeconds) * time.Second)
+
+	inWindow := 0
+	for _, ts := range failures {
+		if ts.After(windowStart) {
+			inWindow++
+		}
+	}
+
+	state := CircuitStateClosed
+	if inWindow >= *rule.FailureThreshold {
+		state = CircuitStateOpen
+		decision.Triggered = true
+		decision.Blocked = true
+		decision.Reason = "circuit open"
+	}
+	decision.CircuitState = &state
+	return decision, nil
+}
+
+func containsFold(items []string, value string) bool {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for _, item := range items {
+		if strings.ToLower(strings.TrimSpace(item)) == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAll(source, required map[string]struct{}) bool {
+	for tag := range required {
+		if _, ok := source[tag]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func containsAny(source, denied map[string]struct{}) bool {
+	for tag := range denied {
+		if _, ok := source[tag]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// dedupeFold trims values and drops empty and case-insensitive duplicates.
+func dedupeFold(values []string) []string {
+	seen := make(map[string]struct{}, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		trimmed := strings.TrimSpace(value)
+		if trimmed == "" {
+			continue
+		}
+		key := strings.ToLower(trimmed)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		result = append(result, trimmed)
+	}
+	return result
+}
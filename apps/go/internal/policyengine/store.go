@@ -0,0 +1,124 @@
+package policyengine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"pipelogiq/internal/types"
+)
+
+// StorePath returns the policy store file shared by the API, which owns it,
+// and the worker, which only reads it.
+func StorePath() string {
+	if envPath := strings.TrimSpace(os.Getenv("POLICY_STORE_PATH")); envPath != "" {
+		return envPath
+	}
+
+	candidates := []string{
+		"./data/policies.json",
+		"../../data/policies.json",
+	}
+
+	for _, candidate := range candidates {
+		if dirExists(filepath.Dir(candidate)) {
+			return candidate
+		}
+	}
+
+	return "./data/policies.json"
+}
+
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return info.IsDir()
+}
+
+// Cache is a read-only view of the policy store file that is reloaded when
+// the file changes, at most once per interval.
+type Cache struct {
+	path     string
+	interval time.Duration
+
+	mu        sync.Mutex
+	policies  []types.Policy
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+func NewCache(path string, interval time.Duration) *Cache {
+	return &Cache{path: path, interval: interval}
+}
+
+// Wait blocks until the policy store file exists, checking every second up
+// to timeout. The API creates the file when it starts; when the worker does
+// not see it, the two do not share the store and policies saved through the
+// API would never be enforced.
+func (c *Cache) Wait(ctx context.Context, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		_, err := os.Stat(c.path)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("policy store %s: %w", c.path, err)
+		}
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("policy store %s not found after %s; set POLICY_STORE_PATH to the file the API saves policies in", c.path, timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// Policies returns the cached policies, reloading the file first when it was
+// modified since the last load. A missing file is an error, and the policies
+// loaded last are kept.
+func (c *Cache) Policies() ([]types.Policy, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if !c.checkedAt.IsZero() && now.Sub(c.checkedAt) < c.interval {
+		return c.policies, nil
+	}
+	c.checkedAt = now
+
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return c.policies, err
+	}
+	if info.ModTime().Equal(c.modTime) {
+		return c.policies, nil
+	}
+
+	content, err := os.ReadFile(c.path)
+	if err != nil {
+		return c.policies, err
+	}
+	var snapshot struct {
+		Policies []types.Policy `json:"policies"`
+	}
+	if len(content) > 0 {
+		if err := json.Unmarshal(content, &snapshot); err != nil {
+			return c.policies, err
+		}
+	}
+
+	c.policies = snapshot.Policies
+	c.modTime = info.ModTime()
+	return c.policies, nil
+}
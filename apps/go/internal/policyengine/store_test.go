@@ -0,0 +1,41 @@
+package policyengine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheRequiresStoreFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.json")
+	cache := NewCache(path, 0)
+
+	if err := cache.Wait(context.Background(), 0); err == nil {
+		t.Fatal("Wait() on a missing store = nil, want an error")
+	}
+	if _, err := cache.Policies(); err == nil {
+		t.Fatal("Policies() on a missing store = nil error, want one")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"policies":[{"id":"p1","name":"limit"}]}`), 0o644); err != nil {
+		t.Fatalf("write store: %v", err)
+	}
+	if err := cache.Wait(context.Background(), time.Second); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+	policies, err := cache.Policies()
+	if err != nil || len(policies) != 1 || policies[0].ID != "p1" {
+		t.Fatalf("Policies() = %v, %v, want p1", policies, err)
+	}
+
+	// Losing the file keeps the policies loaded last.
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("remove store: %v", err)
+	}
+	policies, err = cache.Policies()
+	if err == nil || len(policies) != 1 {
+		t.Fatalf("Policies() after removal = %v, %v, want p1 and an error", policies, err)
+	}
+}
@@ -0,0 +1,55 @@
+// Package ratelimit counts events per key in a sliding window, either in
+// process memory or in Redis so that every API and worker replica shares the
+// same counters.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Backends selectable with RATE_LIMIT_STORE.
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// Result is the outcome of one Allow call.
+type Result struct {
+	Allowed bool
+	// RetryAfter is how long until the key admits another event; zero when
+	// Allowed.
+	RetryAfter time.Duration
+}
+
+// LimiterStore admits at most limit events per key in any sliding window of
+// the given length. Implementations are safe for concurrent use.
+type LimiterStore interface {
+	// Allow records one event for key when fewer than limit events were
+	// recorded in the last window, and reports whether it did.
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error)
+}
+
+// New returns the LimiterStore for backend. redisURL is only used by the
+// Redis backend.
+func New(backend, redisURL string) (LimiterStore, error) {
+	switch backend {
+	case "", BackendMemory:
+		return NewMemoryStore(), nil
+	case BackendRedis:
+		return NewRedisStore(redisURL)
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", backend)
+	}
+}
+
+// Close releases the connections of store when it holds any, as a RedisStore
+// does.
+func Close(store LimiterStore) error {
+	if closer, ok := store.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memorySweepInterval bounds how often idle keys are dropped.
+const memorySweepInterval = time.Minute
+
+// MemoryStore keeps a log of event times per key in process memory. Counts
+// are per process, so replicas each admit up to the limit.
+type MemoryStore struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	events    map[string][]time.Time
+	windows   map[string]time.Duration
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		now:     time.Now,
+		events:  make(map[string][]time.Time),
+		windows: make(map[string]time.Duration),
+	}
+}
+
+func (m *MemoryStore) Allow(_ context.Context, key string, limit int, window time.Duration) (Result, error) {
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now.Sub(m.lastSweep) >= memorySweepInterval {
+		m.sweep(now)
+	}
+
+	events := pruneEvents(m.events[key], now.Add(-window))
+	m.windows[key] = window
+	if len(events) >= limit {
+		m.events[key] = events
+		if len(events) == 0 {
+			return Result{RetryAfter: window}, nil
+		}
+		return Result{RetryAfter: events[0].Add(window).Sub(now)}, nil
+	}
+	m.events[key] = append(events, now)
+	return Result{Allowed: true}, nil
+}
+
+// sweep drops keys with no event left in their window.
+func (m *MemoryStore) sweep(now time.Time) {
+	m.lastSweep = now
+	for key, events := range m.events {
+		if len(pruneEvents(events, now.Add(-m.windows[key]))) == 0 {
+			delete(m.events, key)
+			delete(m.windows, key)
+		}
+	}
+}
+
+// pruneEvents drops the events at or before cutoff, matching the window
+// policyengine uses to simulate rate limits.
+func pruneEvents(events []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(events) && !events[i].After(cutoff) {
+		i++
+	}
+	return events[i:]
+}
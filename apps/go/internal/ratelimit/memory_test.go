@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreSlidingWindow(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	now := start
+	m := NewMemoryStore()
+	m.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	allow := func(key string) Result {
+		t.Helper()
+		res, err := m.Allow(ctx, key, 2, 10*time.Second)
+		if err != nil {
+			t.Fatalf("Allow(%q) error = %v", key, err)
+		}
+		return res
+	}
+
+	if !allow("a").Allowed {
+		t.Fatal("first event denied")
+	}
+	now = start.Add(4 * time.Second)
+	if !allow("a").Allowed {
+		t.Fatal("second event denied")
+	}
+	now = start.Add(6 * time.Second)
+	if res := allow("a"); res.Allowed || res.RetryAfter != 4*time.Second {
+		t.Fatalf("third event = %+v, want denied with RetryAfter 4s", res)
+	}
+	if !allow("b").Allowed {
+		t.Fatal("other key shares the window")
+	}
+
+	// The first event leaves the window exactly windowSeconds later.
+	now = start.Add(10 * time.Second)
+	if !allow("a").Allowed {
+		t.Fatal("event after the oldest expired denied")
+	}
+	if res := allow("a"); res.Allowed || res.RetryAfter != 4*time.Second {
+		t.Fatalf("event over the limit = %+v, want denied with RetryAfter 4s", res)
+	}
+
+	now = start.Add(time.Hour)
+	allow("c")
+	if _, ok := m.events["b"]; ok {
+		t.Error("idle key was not swept")
+	}
+}
+
+func TestNewUnknownBackend(t *testing.T) {
+	if _, err := New("memcached", ""); err == nil {
+		t.Fatal("New(memcached) error = nil")
+	}
+	if _, err := New(BackendRedis, ""); err == nil {
+		t.Fatal("New(redis) without a URL error = nil")
+	}
+}
+
+// closingStore is a LimiterStore that holds a connection.
+type closingStore struct {
+	*MemoryStore
+	closed bool
+}
+
+func (c *closingStore) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestClose(t *testing.T) {
+	if err := Close(NewMemoryStore()); err != nil {
+		t.Fatalf("Close(memory) error = %v", err)
+	}
+	store := &closingStore{MemoryStore: NewMemoryStore()}
+	if err := Close(store); err != nil || !store.closed {
+		t.Fatalf("Close() = %v, closed = %v; want the store closed", err, store.closed)
+	}
+}
@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisKeyPrefix = "pipelogiq:ratelimit:"
+
+// slidingWindowScript keeps one sorted set per key scored by event time in
+// milliseconds. The clock is Redis's own, so replicas with skewed clocks
+// still share one window. It returns {allowed, retryAfterMs}.
+var slidingWindowScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+if redis.call('ZCARD', KEYS[1]) < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[3])
+	redis.call('PEXPIRE', KEYS[1], window)
+	return {1, 0}
+end
+
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+if #oldest == 0 then
+	return {0, window}
+end
+return {0, tonumber(oldest[2]) + window - now}
+`)
+
+// RedisStore shares the sliding windows of all replicas through Redis.
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(redisURL string) (*RedisStore, error) {
+	if redisURL == "" {
+		return nil, fmt.Errorf("REDIS_URL is required for the redis rate limit store")
+	}
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse REDIS_URL: %w", err)
+	}
+	return &RedisStore{client: redis.NewClient(opts)}, nil
+}
+
+func (r *RedisStore) Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	member, err := randomMember()
+	if err != nil {
+		return Result{}, err
+	}
+	values, err := slidingWindowScript.Run(ctx, r.client, []string{redisKeyPrefix + key},
+		window.Milliseconds(), limit, member).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("rate limit script: %w", err)
+	}
+	if len(values) != 2 {
+		return Result{}, fmt.Errorf("rate limit script returned %d values", len(values))
+	}
+	if values[0] == 1 {
+		return Result{Allowed: true}, nil
+	}
+	return Result{RetryAfter: time.Duration(values[1]) * time.Millisecond}, nil
+}
+
+// Close releases the Redis connections.
+func (r *RedisStore) Close() error {
+	return r.client.Close()
+}
+
+// randomMember names one event in the sorted set; events recorded in the
+// same millisecond need distinct members to be counted separately.
+func randomMember() (string, error) {
+	var b [12]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("generate rate limit member: %w", err)
+	}
+	return hex.EncodeToString(b[:]), nil
+}
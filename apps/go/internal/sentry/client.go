@@ -0,0 +1,158 @@
+// Package sentry reports stage failures and worker errors to Sentry when the
+// Sentry observability integration is configured.
+package sentry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"pipelogiq/internal/version"
+)
+
+const defaultHTTPTimeout = 5 * time.Second
+
+// Client sends events to the envelope endpoint of one Sentry project.
+type Client struct {
+	dsn         string
+	envelopeURL string
+	publicKey   string
+	environment string
+	http        *http.Client
+}
+
+// NewClient parses a DSN of the form https://<key>@<host>[/<path>]/<project>.
+func NewClient(dsn, environment string) (*Client, error) {
+	dsn = strings.TrimSpace(dsn)
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sentry dsn: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.New("invalid sentry dsn: scheme must be http or https")
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, errors.New("invalid sentry dsn: missing public key")
+	}
+	path := strings.TrimRight(u.Path, "/")
+	idx := strings.LastIndex(path, "/")
+	projectID := path[idx+1:]
+	if idx < 0 || projectID == "" {
+		return nil, errors.New("invalid sentry dsn: missing project id")
+	}
+
+	envelope := url.URL{
+		Scheme: u.Scheme,
+		Host:   u.Host,
+		Path:   path[:idx] + "/api/" + projectID + "/envelope/",
+	}
+	return &Client{
+		dsn:         dsn,
+		envelopeURL: envelope.String(),
+		publicKey:   u.User.Username(),
+		environment: strings.TrimSpace(environment),
+		http:        &http.Client{Timeout: defaultHTTPTimeout},
+	}, nil
+}
+
+// Event is the subset of the Sentry event payload Pipelogiq sends.
+type Event struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Logger      string            `json:"logger"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Message     eventMessage      `json:"message"`
+	Fingerprint []string          `json:"fingerprint,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Extra       map[string]any    `json:"extra,omitempty"`
+}
+
+type eventMessage struct {
+	Formatted string `json:"formatted"`
+}
+
+// NewEvent returns an event with an id, timestamp and release filled in.
+func NewEvent(level, message string) Event {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return Event{
+		EventID:   hex.EncodeToString(b),
+		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+		Level:     level,
+		Platform:  "go",
+		Logger:    "pipelogiq",
+		Release:   "pipelogiq@" + version.Version,
+		Message:   eventMessage{Formatted: message},
+	}
+}
+
+// Capture sends event in a single-item envelope.
+func (c *Client) Capture(ctx context.Context, event Event) error {
+	if event.Environment == "" {
+		event.Environment = c.environment
+	}
+	body, err := c.envelope(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.envelopeURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf(
+		"Sentry sentry_version=7, sentry_key=%s, sentry_client=pipelogiq/%s", c.publicKey, version.Version))
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("sentry returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (c *Client) envelope(event Event) ([]byte, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	header, err := json.Marshal(map[string]string{
+		"event_id": event.EventID,
+		"dsn":      c.dsn,
+		"sent_at":  time.Now().UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return nil, err
+	}
+	item, err := json.Marshal(map[string]any{"type": "event", "length": len(payload)})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(header)
+	buf.WriteByte('\n')
+	buf.Write(item)
+	buf.WriteByte('\n')
+	buf.Write(payload)
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
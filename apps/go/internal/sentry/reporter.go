@@ -0,0 +1,149 @@
+package sentry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	observabilitymodel "pipelogiq/internal/observability/model"
+	observabilityrepo "pipelogiq/internal/observability/repo"
+	"pipelogiq/internal/store"
+	"pipelogiq/internal/types"
+)
+
+const configCacheTTL = 5 * time.Second
+
+// Reporter captures a Sentry event for every failed stage and every worker
+// error event. It does nothing unless the Sentry integration has passed its
+// connection test.
+type Reporter struct {
+	repo       observabilityrepo.Repository
+	service    string
+	serverName string
+	logger     *slog.Logger
+
+	mu          sync.Mutex
+	client      *Client
+	cacheLoaded time.Time
+}
+
+var _ store.AlertSink = (*Reporter)(nil)
+
+func New(repo observabilityrepo.Repository, service string, logger *slog.Logger) *Reporter {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	serverName, _ := os.Hostname()
+	return &Reporter{
+		repo:       repo,
+		service:    service,
+		serverName: serverName,
+		logger:     logger,
+	}
+}
+
+func (r *Reporter) NotifyStageChange(ctx context.Context, event store.StageAlertEvent) {
+	if event.NewStatus != types.StageStatusFailed {
+		return
+	}
+	ev := NewEvent("error", fmt.Sprintf("Stage '%s' failed in pipeline '%s'", event.StageName, event.PipelineName))
+	ev.Fingerprint = []string{"stage-failed", event.PipelineName, event.StageName}
+	ev.Tags = map[string]string{
+		"pipeline_id":   strconv.Itoa(event.PipelineID),
+		"pipeline_name": event.PipelineName,
+		"stage_id":      strconv.Itoa(event.StageID),
+		"stage_name":    event.StageName,
+		"source":        event.Source,
+	}
+	ev.Extra = map[string]any{
+		"oldStatus": event.OldStatus,
+		"newStatus": event.NewStatus,
+	}
+	r.capture(ctx, ev)
+}
+
+func (r *Reporter) NotifyWorkerEvent(ctx context.Context, event store.WorkerAlertEvent) {
+	toState, _ := event.Details["to"].(string)
+	isError := strings.EqualFold(strings.TrimSpace(event.Level), "error") ||
+		(event.EventType == "worker.state_changed" && strings.EqualFold(toState, types.WorkerStateError))
+	if !isError {
+		return
+	}
+
+	message := strings.TrimSpace(event.Message)
+	if message == "" {
+		message = event.EventType
+	}
+	ev := NewEvent("error", fmt.Sprintf("Worker %s: %s", event.WorkerID, message))
+	ev.Fingerprint = []string{"worker-error", event.WorkerID, event.EventType}
+	ev.Tags = map[string]string{
+		"worker_id":  event.WorkerID,
+		"event_type": event.EventType,
+	}
+	ev.Extra = event.Details
+	r.capture(ctx, ev)
+}
+
+// SendTestEvent captures an info-level event to verify the DSN.
+func SendTestEvent(ctx context.Context, dsn, environment string) error {
+	client, err := NewClient(dsn, environment)
+	if err != nil {
+		return err
+	}
+	ev := NewEvent("info", "Pipelogiq test event")
+	ev.Tags = map[string]string{"source": "observability.test"}
+	return client.Capture(ctx, ev)
+}
+
+func (r *Reporter) capture(ctx context.Context, ev Event) {
+	client, err := r.loadClient(ctx)
+	if err != nil {
+		r.logger.Error("sentry config load failed", "err", err)
+		return
+	}
+	if client == nil {
+		return
+	}
+	ev.ServerName = r.serverName
+	ev.Tags["service"] = r.service
+	if err := client.Capture(ctx, ev); err != nil {
+		r.logger.Warn("sentry capture failed", "err", err)
+	}
+}
+
+// loadClient returns nil when reporting is disabled.
+func (r *Reporter) loadClient(ctx context.Context) (*Client, error) {
+	r.mu.Lock()
+	if time.Since(r.cacheLoaded) <= configCacheTTL {
+		client := r.client
+		r.mu.Unlock()
+		return client, nil
+	}
+	r.mu.Unlock()
+
+	integration, err := r.repo.GetIntegration(ctx, observabilitymodel.IntegrationTypeSentry)
+	if err != nil {
+		return nil, err
+	}
+	var client *Client
+	if integration != nil && (integration.Status == observabilitymodel.IntegrationStatusConnected ||
+		integration.Status == observabilitymodel.IntegrationStatusStale) {
+		dsn, _ := integration.Config["dsn"].(string)
+		environment, _ := integration.Config["environment"].(string)
+		if client, err = NewClient(dsn, environment); err != nil {
+			r.logger.Warn("sentry integration has an invalid dsn", "err", err)
+			client = nil
+		}
+	}
+
+	r.mu.Lock()
+	r.client = client
+	r.cacheLoaded = time.Now().UTC()
+	r.mu.Unlock()
+	return client, nil
+}
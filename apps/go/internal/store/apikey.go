@@ -18,7 +18,7 @@ func (s *Store) GetApiKeys(ctx context.Context, applicationID int) ([]types.ApiK
 	keys := []types.ApiKeyResponse{}
 
 	err := s.db.SelectContext(ctx, &keys, `
-		SELECT id, application_id, name, key, created_at, disabled_at, expires_at, last_used
+		SELECT id, application_id, name, key, created_at, disabled_at, expires_at, last_used, scopes
 		FROM api_key
 		WHERE application_id = $1
 		ORDER BY id
@@ -28,6 +28,12 @@ func (s *Store) GetApiKeys(ctx context.Context, applicationID int) ([]types.ApiK
 		return nil, err
 	}
 
+	for i := range keys {
+		if keys[i].ScopeList != nil {
+			keys[i].Scopes = splitList(*keys[i].ScopeList)
+		}
+	}
+
 	return keys, nil
 }
 
@@ -42,6 +48,11 @@ func (s *Store) GenerateApiKey(ctx context.Context, userID int, req types.Genera
 		}
 	}()
 
+	scopes, err := normalizeAPIKeyScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
 	applicationID, err := resolveApplicationForAPIKey(ctx, tx, userID, req)
 	if err != nil {
 		return nil, err
@@ -56,10 +67,10 @@ func (s *Store) GenerateApiKey(ctx context.Context, userID int, req types.Genera
 	var id int
 
 	err = tx.QueryRowContext(ctx, `
-		INSERT INTO api_key (application_id, name, key, created_at, expires_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO api_key (application_id, name, key, created_at, expires_at, scopes)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id
-	`, applicationID, req.Name, key, now, req.ExpiresAt).Scan(&id)
+	`, applicationID, req.Name, key, now, req.ExpiresAt, joinList(scopes)).Scan(&id)
 
 	if err != nil {
 		return nil, fmt.Errorf("insert api key: %w", err)
@@ -76,9 +87,39 @@ func (s *Store) GenerateApiKey(ctx context.Context, userID int, req types.Genera
 		Key:           &key,
 		CreatedAt:     &now,
 		ExpiresAt:     req.ExpiresAt,
+		Scopes:        scopes,
 	}, nil
 }
 
+var errInvalidAPIKeyScope = errors.New("invalid api key scope")
+
+func IsInvalidAPIKeyScopeError(err error) bool {
+	return errors.Is(err, errInvalidAPIKeyScope)
+}
+
+// normalizeAPIKeyScopes trims and deduplicates scopes and rejects unknown ones.
+func normalizeAPIKeyScopes(scopes []string) ([]string, error) {
+	known := make(map[string]bool, len(types.APIKeyScopes))
+	for _, scope := range types.APIKeyScopes {
+		known[scope] = true
+	}
+
+	seen := make(map[string]bool, len(scopes))
+	var out []string
+	for _, raw := range scopes {
+		scope := strings.TrimSpace(raw)
+		if scope == "" || seen[scope] {
+			continue
+		}
+		if !known[scope] {
+			return nil, fmt.Errorf("%w: %s", errInvalidAPIKeyScope, scope)
+		}
+		seen[scope] = true
+		out = append(out, scope)
+	}
+	return out, nil
+}
+
 func resolveApplicationForAPIKey(ctx context.Context, tx *sqlx.Tx, userID int, req types.GenerateApiKeyRequest) (int, error) {
 	hasExisting := req.ApplicationID != nil && *req.ApplicationID > 0
 	hasNew := req.NewApplication != nil
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"pipelogiq/internal/types"
+)
+
+// DetectExpiringAPIKeys finds enabled API keys of enabled applications that
+// expire within the next `within` and emits an APIKeyExpiringEvent for each to
+// the sinks implementing APIKeyAlertSink. A key is reported at most once per
+// calendar day: the day is recorded on the key in the same statement, so
+// several worker replicas do not report it twice. It returns the number of
+// keys reported.
+func (s *Store) DetectExpiringAPIKeys(ctx context.Context, within time.Duration) (int, error) {
+	var rows []struct {
+		ID              int       `db:"id"`
+		Name            *string   `db:"name"`
+		ApplicationID   int       `db:"application_id"`
+		ApplicationName string    `db:"application_name"`
+		ExpiresAt       time.Time `db:"expires_at"`
+	}
+	if err := s.db.SelectContext(ctx, &rows, `
+		UPDATE api_key k
+		SET expiry_notified_on = CURRENT_DATE
+		FROM application a
+		WHERE a.id = k.application_id
+		  AND k.disabled_at IS NULL
+		  AND a.disabled_at IS NULL
+		  AND k.expires_at > NOW()
+		  AND k.expires_at <= NOW() + make_interval(secs => $1)
+		  AND (k.expiry_notified_on IS NULL OR k.expiry_notified_on < CURRENT_DATE)
+		RETURNING k.id, k.name, k.application_id, a.name AS application_name, k.expires_at
+	`, within.Seconds()); err != nil {
+		return 0, fmt.Errorf("detect expiring api keys: %w", err)
+	}
+
+	now := time.Now().UTC()
+	for _, row := range rows {
+		name := ""
+		if row.Name != nil {
+			name = *row.Name
+		}
+		expiresAt := row.ExpiresAt.UTC()
+		s.emitAPIKeyExpiringAlert(APIKeyExpiringEvent{
+			APIKeyID:        row.ID,
+			KeyName:         name,
+			ApplicationID:   row.ApplicationID,
+			ApplicationName: row.ApplicationName,
+			ExpiresAt:       expiresAt,
+			DaysRemaining:   daysUntil(now, expiresAt),
+			TS:              now,
+		})
+	}
+	return len(rows), nil
+}
+
+// ListExpiringAPIKeys lists the enabled API keys of the user's enabled
+// applications that expire within the next `within`, soonest first.
+func (s *Store) ListExpiringAPIKeys(ctx context.Context, userID int, within time.Duration) ([]types.ExpiringApiKeyResponse, error) {
+	keys := []types.ExpiringApiKeyResponse{}
+	if err := s.db.SelectContext(ctx, &keys, `
+		SELECT k.id, k.application_id, a.name AS application_name, k.name, k.expires_at
+		FROM api_key k
+		JOIN application a ON a.id = k.application_id
+		JOIN user_application ua ON ua.application_id = a.id
+		WHERE ua.user_id = $1
+		  AND k.disabled_at IS NULL
+		  AND a.disabled_at IS NULL
+		  AND k.expires_at > NOW()
+		  AND k.expires_at <= NOW() + make_interval(secs => $2)
+		ORDER BY k.expires_at, k.id
+	`, userID, within.Seconds()); err != nil {
+		return nil, fmt.Errorf("list expiring api keys: %w", err)
+	}
+
+	now := time.Now().UTC()
+	for i := range keys {
+		keys[i].ExpiresAt = keys[i].ExpiresAt.UTC()
+		keys[i].DaysRemaining = daysUntil(now, keys[i].ExpiresAt)
+	}
+	return keys, nil
+}
+
+// daysUntil rounds the time left up to whole days, so a key expiring later
+// today has one day remaining.
+func daysUntil(now, t time.Time) int {
+	left := t.Sub(now)
+	if left <= 0 {
+		return 0
+	}
+	return int(math.Ceil(left.Hours() / 24))
+}
@@ -0,0 +1,23 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDaysUntil(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[time.Duration]int{
+		-time.Hour:         0,
+		0:                  0,
+		3 * time.Hour:      1,
+		24 * time.Hour:     1,
+		25 * time.Hour:     2,
+		7 * 24 * time.Hour: 7,
+	}
+	for left, want := range tests {
+		if got := daysUntil(now, now.Add(left)); got != want {
+			t.Errorf("daysUntil(now+%s) = %d, want %d", left, got, want)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+
+	"pipelogiq/internal/types"
+)
+
+func TestNormalizeAPIKeyScopes(t *testing.T) {
+	got, err := normalizeAPIKeyScopes([]string{" jobs:consume", "", "pipelines:write", "jobs:consume "})
+	if err != nil {
+		t.Fatalf("normalizeAPIKeyScopes() error = %v", err)
+	}
+	want := []string{types.APIKeyScopeJobsConsume, types.APIKeyScopePipelinesWrite}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("normalizeAPIKeyScopes() = %v, want %v", got, want)
+	}
+
+	if got, err := normalizeAPIKeyScopes(nil); err != nil || len(got) != 0 {
+		t.Fatalf("normalizeAPIKeyScopes(nil) = %v, %v, want no scopes", got, err)
+	}
+
+	if _, err := normalizeAPIKeyScopes([]string{"jobs:consume", "jobs:admin"}); !IsInvalidAPIKeyScopeError(err) {
+		t.Fatalf("normalizeAPIKeyScopes(unknown) error = %v, want invalid scope", err)
+	}
+}
@@ -2,21 +2,31 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"pipelogiq/internal/types"
 )
 
-func (s *Store) GetUserApplications(ctx context.Context, userID int) ([]types.ApplicationResponse, error) {
+var errApplicationNotFound = errors.New("application not found")
+
+func IsApplicationNotFoundError(err error) bool {
+	return errors.Is(err, errApplicationNotFound)
+}
+
+// GetUserApplications lists the user's applications. Disabled applications are
+// left out when excludeDisabled is set.
+func (s *Store) GetUserApplications(ctx context.Context, userID int, excludeDisabled bool) ([]types.ApplicationResponse, error) {
 	apps := []types.ApplicationResponse{}
 
 	err := s.db.SelectContext(ctx, &apps, `
-		SELECT a.id, a.name, a.description
+		SELECT a.id, a.name, a.description, a.disabled_at
 		FROM application a
 		JOIN user_application ua ON ua.application_id = a.id
 		WHERE ua.user_id = $1
+		  AND (NOT $2 OR a.disabled_at IS NULL)
 		ORDER BY a.id
-	`, userID)
+	`, userID, excludeDisabled)
 
 	if err != nil {
 		return nil, err
@@ -75,5 +85,71 @@ func (s *Store) SaveApplication(ctx context.Context, userID int, req types.SaveA
 		return nil, err
 	}
 
-	return s.GetUserApplications(ctx, userID)
+	return s.GetUserApplications(ctx, userID, false)
+}
+
+// UserHasApplication reports whether the user is linked to the application.
+func (s *Store) UserHasApplication(ctx context.Context, userID, appID int) (bool, error) {
+	var hasAccess bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM user_application
+			WHERE user_id = $1 AND application_id = $2
+		)
+	`, userID, appID).Scan(&hasAccess)
+	if err != nil {
+		return false, fmt.Errorf("check application access: %w", err)
+	}
+	return hasAccess, nil
+}
+
+// UserCanAccessPipeline reports whether the pipeline belongs to one of the
+// user's applications. A pipeline that does not exist, or has no
+// application, is not accessible either.
+func (s *Store) UserCanAccessPipeline(ctx context.Context, userID, pipelineID int) (bool, error) {
+	var hasAccess bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM pipeline p
+			JOIN user_application ua ON ua.application_id = p.application_id
+			WHERE p.id = $2 AND ua.user_id = $1
+		)
+	`, userID, pipelineID).Scan(&hasAccess)
+	if err != nil {
+		return false, fmt.Errorf("check pipeline access: %w", err)
+	}
+	return hasAccess, nil
+}
+
+// DisableApplication retires one of the user's applications. Its pipelines
+// and API keys are kept, but the keys stop validating until the application
+// is enabled again. Disabling an already disabled application keeps the
+// original timestamp.
+func (s *Store) DisableApplication(ctx context.Context, userID, appID int) error {
+	return s.setApplicationDisabled(ctx, userID, appID, `COALESCE(disabled_at, NOW())`)
+}
+
+// EnableApplication reverses DisableApplication.
+func (s *Store) EnableApplication(ctx context.Context, userID, appID int) error {
+	return s.setApplicationDisabled(ctx, userID, appID, `NULL`)
+}
+
+func (s *Store) setApplicationDisabled(ctx context.Context, userID, appID int, disabledAt string) error {
+	res, err := s.db.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE application a SET disabled_at = %s
+		WHERE a.id = $1
+		  AND EXISTS (
+			SELECT 1 FROM user_application ua
+			WHERE ua.application_id = a.id AND ua.user_id = $2
+		  )
+	`, disabledAt), appID, userID)
+	if err != nil {
+		return fmt.Errorf("update application disabled_at: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errApplicationNotFound
+	}
+	return nil
 }
@@ -0,0 +1,236 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"pipelogiq/internal/types"
+)
+
+type bulkStageRow struct {
+	ID         int    `db:"id"`
+	PipelineID int    `db:"pipeline_id"`
+	Status     string `db:"status"`
+}
+
+// BulkRerunStages resets the selected stages to NotStarted in one transaction.
+// Stages that are currently dispatched to a worker are reported as failures.
+func (s *Store) BulkRerunStages(ctx context.Context, userID int, req types.BulkStageRequest) ([]types.BulkStageResult, error) {
+	return s.bulkUpdateStages(ctx, userID, req, types.StageStatusNotStarted, "bulk_rerun_stage",
+		func(status string) string {
+			switch status {
+			case types.StageStatusRunning, types.StageStatusPending:
+				return "stage is in progress"
+			}
+			return ""
+		},
+		func(ctx context.Context, tx *sqlx.Tx, ids []int) error {
+			if err := archiveStageExecutionsTx(ctx, tx, ids, "bulk_rerun_stage"); err != nil {
+				return err
+			}
+			if err := execIn(ctx, tx, `
+				UPDATE stage
+				SET status = ?, started_at = NULL, finished_at = NULL, is_skipped = false, retry_attempt = 0, next_retry_at = NULL,
+					failure_category = NULL, failure_detail = NULL
+				WHERE id IN (?)
+			`, types.StageStatusNotStarted, ids); err != nil {
+				return fmt.Errorf("reset stages: %w", err)
+			}
+			if err := execIn(ctx, tx, `UPDATE stage_io SET output = NULL, output_truncated = false, output_bytes = NULL WHERE stage_id IN (?)`, ids); err != nil {
+				return fmt.Errorf("clear stage outputs: %w", err)
+			}
+			return nil
+		},
+	)
+}
+
+// BulkSkipStages marks the selected stages as skipped in one transaction.
+// Stages that already finished successfully are reported as failures.
+func (s *Store) BulkSkipStages(ctx context.Context, userID int, req types.BulkStageRequest) ([]types.BulkStageResult, error) {
+	return s.bulkUpdateStages(ctx, userID, req, types.StageStatusSkipped, "bulk_skip_stage",
+		func(status string) string {
+			switch status {
+			case types.StageStatusCompleted, types.StageStatusSkipped:
+				return "stage already finished"
+			}
+			return ""
+		},
+		func(ctx context.Context, tx *sqlx.Tx, ids []int) error {
+			if err := execIn(ctx, tx, `
+				UPDATE stage
+				SET status = ?, is_skipped = true, finished_at = NOW(), next_retry_at = NULL
+				WHERE id IN (?)
+			`, types.StageStatusSkipped, ids); err != nil {
+				return fmt.Errorf("skip stages: %w", err)
+			}
+			return nil
+		},
+	)
+}
+
+// bulkUpdateStages runs apply for every selected stage that passes check, then
+// recomputes each affected pipeline's status once. Stages outside the user's
+// applications are reported as not found.
+func (s *Store) bulkUpdateStages(
+	ctx context.Context,
+	userID int,
+	req types.BulkStageRequest,
+	newStatus, source string,
+	check func(status string) string,
+	apply func(ctx context.Context, tx *sqlx.Tx, ids []int) error,
+) ([]types.BulkStageResult, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var requested []int
+	if requested, err = selectBulkStageIDs(ctx, tx, req); err != nil {
+		return nil, err
+	}
+
+	results := make([]types.BulkStageResult, 0, len(requested))
+	if len(requested) == 0 {
+		err = tx.Commit()
+		return results, err
+	}
+
+	var rows []bulkStageRow
+	var query string
+	var args []interface{}
+	if query, args, err = sqlx.In(`
+		SELECT s.id, s.pipeline_id, s.status
+		FROM stage s
+		JOIN pipeline p ON p.id = s.pipeline_id
+		JOIN user_application ua ON ua.application_id = p.application_id
+		WHERE s.id IN (?) AND ua.user_id = ?
+		ORDER BY s.id
+		FOR UPDATE OF s
+	`, requested, userID); err != nil {
+		return nil, fmt.Errorf("build stages query: %w", err)
+	}
+	if err = tx.SelectContext(ctx, &rows, tx.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("load stages: %w", err)
+	}
+	byID := make(map[int]bulkStageRow, len(rows))
+	for _, row := range rows {
+		byID[row.ID] = row
+	}
+
+	var affected []bulkStageRow
+	var affectedIDs []int
+	for _, id := range requested {
+		row, ok := byID[id]
+		if !ok {
+			results = append(results, types.BulkStageResult{StageID: id, Error: "stage not found"})
+			continue
+		}
+		if reason := check(row.Status); reason != "" {
+			results = append(results, types.BulkStageResult{StageID: id, Error: reason})
+			continue
+		}
+		results = append(results, types.BulkStageResult{StageID: id, Success: true})
+		affected = append(affected, row)
+		affectedIDs = append(affectedIDs, id)
+	}
+
+	if len(affectedIDs) > 0 {
+		if err = apply(ctx, tx, affectedIDs); err != nil {
+			return nil, err
+		}
+
+		seen := map[int]bool{}
+		for _, row := range affected {
+			if seen[row.PipelineID] {
+				continue
+			}
+			seen[row.PipelineID] = true
+			if err = recomputePipelineStatus(ctx, tx, row.PipelineID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	for _, row := range affected {
+		if row.Status != newStatus {
+			s.LogStageChange(ctx, row.PipelineID, row.ID, row.Status, newStatus, source)
+		}
+	}
+
+	return results, nil
+}
+
+// selectBulkStageIDs resolves the request to a deduplicated list of stage ids.
+func selectBulkStageIDs(ctx context.Context, tx *sqlx.Tx, req types.BulkStageRequest) ([]int, error) {
+	if req.PipelineID != nil {
+		var ids []int
+		if err := tx.SelectContext(ctx, &ids, `
+			SELECT id FROM stage
+			WHERE pipeline_id = $1
+			  AND ($2 = '' OR status = $2)
+			  AND COALESCE(is_event, false) = false
+			ORDER BY id
+		`, *req.PipelineID, req.Status); err != nil {
+			return nil, fmt.Errorf("select pipeline stages: %w", err)
+		}
+		return ids, nil
+	}
+
+	seen := make(map[int]bool, len(req.StageIDs))
+	ids := make([]int, 0, len(req.StageIDs))
+	for _, id := range req.StageIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// recomputePipelineStatus derives the pipeline status from its stages and
+// reopens or closes the pipeline accordingly.
+func recomputePipelineStatus(ctx context.Context, tx *sqlx.Tx, pipelineID int) error {
+	var statuses []string
+	if err := tx.SelectContext(ctx, &statuses, `SELECT status FROM stage WHERE pipeline_id=$1 ORDER BY id`, pipelineID); err != nil {
+		return fmt.Errorf("load stage statuses: %w", err)
+	}
+
+	status := computePipelineStatus(statuses)
+	var err error
+	switch status {
+	case types.PipelineStatusCompleted, types.PipelineStatusFailed, types.PipelineStatusCancelled:
+		_, err = tx.ExecContext(ctx, `
+			UPDATE pipeline SET status=$1, is_completed=true, finished_at=COALESCE(finished_at, NOW())
+			WHERE id=$2
+		`, status, pipelineID)
+	default:
+		_, err = tx.ExecContext(ctx, `
+			UPDATE pipeline SET status=$1, is_completed=false, finished_at=NULL
+			WHERE id=$2
+		`, status, pipelineID)
+	}
+	if err != nil {
+		return fmt.Errorf("update pipeline status: %w", err)
+	}
+	return nil
+}
+
+func execIn(ctx context.Context, tx *sqlx.Tx, query string, args ...interface{}) error {
+	query, args, err := sqlx.In(query, args...)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, tx.Rebind(query), args...)
+	return err
+}
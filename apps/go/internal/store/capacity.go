@@ -0,0 +1,201 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"pipelogiq/internal/types"
+)
+
+const defaultWorkerOfflineAfter = 45 * time.Second
+
+// SetWorkerOfflineAfter sets how long after its last heartbeat a worker stops
+// counting towards handler capacity. It must match the API's offline window.
+func (s *Store) SetWorkerOfflineAfter(d time.Duration) {
+	if d > 0 {
+		s.workerOfflineAfter = d
+	}
+}
+
+// handlerCapacityQuery aggregates, per application and handler, the workers
+// accepting work and their reported maxConcurrency capability. A handler has
+// a capacity only when every one of its workers reports maxConcurrency; one
+// worker without it makes the handler unbounded. Its four parameters, from
+// $first, are bound by handlerCapacityArgs.
+func handlerCapacityQuery(first int) string {
+	return fmt.Sprintf(`
+		SELECT
+			wc.application_id,
+			h.handler,
+			COUNT(*) AS online_workers,
+			CASE WHEN COUNT(*) = COUNT(c.max_concurrency) THEN SUM(c.max_concurrency) END AS max_concurrency,
+			SUM(wc.in_flight_jobs) AS in_flight_jobs
+		FROM worker_client wc
+		CROSS JOIN LATERAL (
+			SELECT wc.supported_handlers_json::jsonb AS handlers, wc.capabilities_json::jsonb AS caps
+		) j
+		CROSS JOIN LATERAL jsonb_array_elements_text(
+			CASE WHEN jsonb_typeof(j.handlers) = 'array' THEN j.handlers ELSE '[]'::jsonb END
+		) AS h(handler)
+		CROSS JOIN LATERAL (
+			SELECT CASE
+				WHEN jsonb_typeof(j.caps->'maxConcurrency') = 'number' AND (j.caps->>'maxConcurrency')::numeric >= 1
+				THEN FLOOR((j.caps->>'maxConcurrency')::numeric)::bigint
+			END AS max_concurrency
+		) c
+		WHERE wc.state IN ($%d, $%d, $%d)
+		  AND wc.last_seen_at >= NOW() - make_interval(secs => $%d)
+		GROUP BY wc.application_id, h.handler`,
+		first, first+1, first+2, first+3)
+}
+
+func (s *Store) handlerCapacityArgs() []any {
+	offlineAfter := s.workerOfflineAfter
+	if offlineAfter <= 0 {
+		offlineAfter = defaultWorkerOfflineAfter
+	}
+	return []any{
+		types.WorkerStateStarting, types.WorkerStateReady, types.WorkerStateDegraded,
+		offlineAfter.Seconds(),
+	}
+}
+
+// ListHandlerCapacity reports, per application and handler, the capacity of
+// the online workers and how much of it their latest heartbeats use.
+func (s *Store) ListHandlerCapacity(ctx context.Context, applicationID *int) ([]types.HandlerCapacity, error) {
+	var rows []struct {
+		ApplicationID    int    `db:"application_id"`
+		ApplicationName  string `db:"application_name"`
+		Handler          string `db:"handler"`
+		OnlineWorkers    int    `db:"online_workers"`
+		MaxConcurrency   *int   `db:"max_concurrency"`
+		InFlightJobs     int    `db:"in_flight_jobs"`
+		DispatchedStages int    `db:"dispatched_stages"`
+	}
+
+	args := append(s.handlerCapacityArgs(), types.StageStatusPending, types.StageStatusRunning)
+	filter := ""
+	if applicationID != nil && *applicationID > 0 {
+		args = append(args, *applicationID)
+		filter = fmt.Sprintf("WHERE hc.application_id = $%d", len(args))
+	}
+
+	if err := s.db.SelectContext(ctx, &rows, `
+		SELECT
+			hc.application_id,
+			a.name AS application_name,
+			hc.handler,
+			hc.online_workers,
+			hc.max_concurrency,
+			hc.in_flight_jobs,
+			(
+				SELECT COUNT(*) FROM stage sd
+				JOIN pipeline pd ON pd.id = sd.pipeline_id
+				WHERE pd.application_id = hc.application_id
+				  AND sd.stage_handler_name = hc.handler
+				  AND sd.status IN ($5, $6)
+			) AS dispatched_stages
+		FROM (`+handlerCapacityQuery(1)+`
+		) hc
+		JOIN application a ON a.id = hc.application_id
+		`+filter+`
+		ORDER BY a.name, hc.handler
+	`, args...); err != nil {
+		return nil, err
+	}
+
+	result := make([]types.HandlerCapacity, 0, len(rows))
+	for _, row := range rows {
+		item := types.HandlerCapacity{
+			ApplicationID:    row.ApplicationID,
+			ApplicationName:  row.ApplicationName,
+			Handler:          row.Handler,
+			OnlineWorkers:    row.OnlineWorkers,
+			MaxConcurrency:   row.MaxConcurrency,
+			InFlightJobs:     row.InFlightJobs,
+			DispatchedStages: row.DispatchedStages,
+		}
+		if row.MaxConcurrency != nil && *row.MaxConcurrency > 0 {
+			utilization := float64(row.InFlightJobs) / float64(*row.MaxConcurrency)
+			item.Utilization = &utilization
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// ListHandlers is the handler registry: every handler referenced by a stage or
+// advertised by an online worker, with its online worker count. A handler
+// seen only in stages has no live worker to run it.
+func (s *Store) ListHandlers(ctx context.Context, applicationID *int) ([]types.HandlerRegistryEntry, error) {
+	args := s.handlerCapacityArgs()
+	servedFilter, seenFilter := "", ""
+	if applicationID != nil && *applicationID > 0 {
+		args = append(args, *applicationID)
+		servedFilter = fmt.Sprintf("WHERE hc.application_id = $%d", len(args))
+		seenFilter = fmt.Sprintf("AND p.application_id = $%d", len(args))
+	}
+
+	items := []types.HandlerRegistryEntry{}
+	if err := s.db.SelectContext(ctx, &items, `
+		WITH served AS (
+			SELECT hc.handler, SUM(hc.online_workers) AS online_workers
+			FROM (`+handlerCapacityQuery(1)+`
+			) hc
+			`+servedFilter+`
+			GROUP BY hc.handler
+		),
+		seen AS (
+			SELECT s.stage_handler_name AS handler, MAX(s.created_at) AS last_stage_at
+			FROM stage s
+			JOIN pipeline p ON p.id = s.pipeline_id
+			WHERE COALESCE(s.stage_handler_name, '') <> ''
+			`+seenFilter+`
+			GROUP BY s.stage_handler_name
+		)
+		SELECT
+			COALESCE(seen.handler, served.handler) AS handler,
+			COALESCE(served.online_workers, 0) AS online_workers,
+			seen.handler IS NOT NULL AS seen_in_stages,
+			seen.last_stage_at
+		FROM seen
+		FULL OUTER JOIN served ON served.handler = seen.handler
+		ORDER BY 1
+	`, args...); err != nil {
+		return nil, fmt.Errorf("list handlers: %w", err)
+	}
+	return items, nil
+}
+
+// UnservedHandlers returns, in order and without repeats, the handlers no
+// online worker of the application advertises.
+func (s *Store) UnservedHandlers(ctx context.Context, applicationID int, handlers []string) ([]string, error) {
+	if len(handlers) == 0 {
+		return nil, nil
+	}
+
+	args := append(s.handlerCapacityArgs(), applicationID)
+	served := []string{}
+	if err := s.db.SelectContext(ctx, &served, `
+		SELECT hc.handler
+		FROM (`+handlerCapacityQuery(1)+`
+		) hc
+		WHERE `+fmt.Sprintf("hc.application_id = $%d", len(args)), args...); err != nil {
+		return nil, fmt.Errorf("list served handlers: %w", err)
+	}
+
+	seen := make(map[string]struct{}, len(served)+len(handlers))
+	for _, handler := range served {
+		seen[handler] = struct{}{}
+	}
+	var unserved []string
+	for _, handler := range handlers {
+		if _, ok := seen[handler]; ok {
+			continue
+		}
+		seen[handler] = struct{}{}
+		unserved = append(unserved, handler)
+	}
+	return unserved, nil
+}
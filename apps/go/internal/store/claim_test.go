@@ -0,0 +1,248 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"pipelogiq/internal/store/storetest"
+	"pipelogiq/internal/types"
+)
+
+func TestGetStagesToExecute_ConcurrentClaims(t *testing.T) {
+	db := setupPostgresTestDB(t)
+	ctx := context.Background()
+
+	const (
+		pipelines         = 25
+		stagesPerPipeline = 4
+		replicas          = 6
+	)
+	seedSequentialPipelines(t, db, pipelines, stagesPerPipeline)
+	total := pipelines * stagesPerPipeline
+
+	var (
+		mu      sync.Mutex
+		claimed = map[int]int{} // stage id -> replica
+		order   = map[int][]int{}
+		perRep  = make([]int, replicas)
+	)
+
+	deadline := time.Now().Add(30 * time.Second)
+	var wg sync.WaitGroup
+	for replica := 0; replica < replicas; replica++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			st := New(db, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			for time.Now().Before(deadline) {
+				mu.Lock()
+				done := len(claimed) == total
+				mu.Unlock()
+				if done {
+					return
+				}
+
+				// Half of the replicas use the single-stage path.
+				var msgs []*types.StageNextMessage
+				var err error
+				if replica%2 == 0 {
+					var msg *types.StageNextMessage
+					msg, err = st.GetStageToExecute(ctx)
+					if msg != nil {
+						msgs = append(msgs, msg)
+					}
+				} else {
+					msgs, err = st.GetStagesToExecute(ctx, 3)
+				}
+				if err != nil {
+					t.Errorf("replica %d: claim error = %v", replica, err)
+					return
+				}
+
+				mu.Lock()
+				for _, msg := range msgs {
+					if prev, dup := claimed[msg.StageID]; dup {
+						t.Errorf("stage %d claimed by replica %d and replica %d", msg.StageID, prev, replica)
+					}
+					claimed[msg.StageID] = replica
+					order[*msg.PipelineID] = append(order[*msg.PipelineID], msg.StageID)
+					perRep[replica]++
+				}
+				mu.Unlock()
+
+				// Complete each stage so the next one in its pipeline becomes eligible.
+				for _, msg := range msgs {
+					if _, err := db.ExecContext(ctx, `UPDATE stage SET status=$1 WHERE id=$2`,
+						types.StageStatusCompleted, msg.StageID); err != nil {
+						t.Errorf("complete stage %d: %v", msg.StageID, err)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(claimed) != total {
+		t.Fatalf("claimed %d stages, want %d", len(claimed), total)
+	}
+	for pipelineID, ids := range order {
+		for i := 1; i < len(ids); i++ {
+			if ids[i] <= ids[i-1] {
+				t.Errorf("pipeline %d: stages claimed out of order: %v", pipelineID, ids)
+				break
+			}
+		}
+	}
+	t.Logf("claims per replica: %v", perRep)
+}
+
+func TestGetStagesToExecute_OnePerPipeline(t *testing.T) {
+	db := setupPostgresTestDB(t)
+	ctx := context.Background()
+	seedSequentialPipelines(t, db, 3, 2)
+
+	st := New(db, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	msgs, err := st.GetStagesToExecute(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetStagesToExecute() error = %v", err)
+	}
+	if len(msgs) != 3 {
+		t.Fatalf("claimed %d stages, want 3", len(msgs))
+	}
+	seen := map[int]bool{}
+	for _, msg := range msgs {
+		if seen[*msg.PipelineID] {
+			t.Fatalf("pipeline %d claimed twice in one batch", *msg.PipelineID)
+		}
+		seen[*msg.PipelineID] = true
+	}
+
+	// Every pipeline now has a Pending stage, so nothing else is eligible.
+	more, err := st.GetStagesToExecute(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetStagesToExecute() error = %v", err)
+	}
+	if len(more) != 0 {
+		t.Fatalf("claimed %d stages while pipelines are blocked, want 0", len(more))
+	}
+}
+
+func TestGetStagesToExecute_Priority(t *testing.T) {
+	db := setupPostgresTestDB(t)
+	ctx := context.Background()
+	seedSequentialPipelines(t, db, 3, 1)
+
+	// The newest pipeline is urgent, the middle one is raised a little.
+	var ids []int
+	if err := db.Select(&ids, `SELECT id FROM pipeline ORDER BY id`); err != nil {
+		t.Fatalf("list pipelines: %v", err)
+	}
+	for id, priority := range map[int]int{ids[1]: 1, ids[2]: 9} {
+		if _, err := db.Exec(`UPDATE pipeline SET priority = $1 WHERE id = $2`, priority, id); err != nil {
+			t.Fatalf("set priority: %v", err)
+		}
+	}
+
+	st := New(db, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	for _, want := range []int{ids[2], ids[1], ids[0]} {
+		msg, err := st.GetStageToExecute(ctx)
+		if err != nil || msg == nil {
+			t.Fatalf("GetStageToExecute() = %v, %v", msg, err)
+		}
+		if *msg.PipelineID != want {
+			t.Fatalf("claimed pipeline %d, want %d", *msg.PipelineID, want)
+		}
+	}
+}
+
+func TestGetStagesToExecute_HandlerCapacity(t *testing.T) {
+	db := setupPostgresTestDB(t)
+	ctx := context.Background()
+	seedSequentialPipelines(t, db, 5, 1)
+
+	// Two online workers serve the handler with a combined capacity of 3; a
+	// stopped worker's capacity does not count.
+	for i, worker := range []struct {
+		state string
+		max   int
+	}{
+		{types.WorkerStateReady, 2},
+		{types.WorkerStateDegraded, 1},
+		{types.WorkerStateStopped, 10},
+	} {
+		if _, err := db.Exec(`
+			INSERT INTO worker_client (id, application_id, state, supported_handlers_json, capabilities_json)
+			VALUES ($1, 1, $2, '["handler"]', $3)
+		`, fmt.Sprintf("worker-%d", i), worker.state, fmt.Sprintf(`{"maxConcurrency": %d}`, worker.max)); err != nil {
+			t.Fatalf("insert worker: %v", err)
+		}
+	}
+
+	st := New(db, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	msgs, err := st.GetStagesToExecute(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetStagesToExecute() error = %v", err)
+	}
+	if len(msgs) != 3 {
+		t.Fatalf("claimed %d stages, want 3 (handler capacity)", len(msgs))
+	}
+
+	if _, err := db.ExecContext(ctx, `UPDATE stage SET status=$1 WHERE id=$2`,
+		types.StageStatusCompleted, msgs[0].StageID); err != nil {
+		t.Fatalf("complete stage: %v", err)
+	}
+	more, err := st.GetStagesToExecute(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetStagesToExecute() error = %v", err)
+	}
+	if len(more) != 1 {
+		t.Fatalf("claimed %d stages after one completed, want 1", len(more))
+	}
+
+	// A worker without maxConcurrency makes the handler unbounded.
+	if _, err := db.Exec(`
+		INSERT INTO worker_client (id, application_id, state, supported_handlers_json)
+		VALUES ('worker-unbounded', 1, $1, '["handler"]')
+	`, types.WorkerStateReady); err != nil {
+		t.Fatalf("insert worker: %v", err)
+	}
+	rest, err := st.GetStagesToExecute(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetStagesToExecute() error = %v", err)
+	}
+	if len(rest) != 1 {
+		t.Fatalf("claimed %d stages with an unbounded worker, want 1", len(rest))
+	}
+}
+
+func seedSequentialPipelines(t *testing.T, db *sqlx.DB, pipelines, stagesPerPipeline int) {
+	t.Helper()
+	for p := 0; p < pipelines; p++ {
+		var pipelineID int
+		if err := db.QueryRow(`
+			INSERT INTO pipeline (application_id, name, status) VALUES (1, $1, $2) RETURNING id
+		`, fmt.Sprintf("pipeline-%d", p), types.PipelineStatusNotStarted).Scan(&pipelineID); err != nil {
+			t.Fatalf("insert pipeline: %v", err)
+		}
+		for s := 0; s < stagesPerPipeline; s++ {
+			if _, err := db.Exec(`
+				INSERT INTO stage (pipeline_id, name, stage_handler_name, status) VALUES ($1, $2, 'handler', $3)
+			`, pipelineID, fmt.Sprintf("stage-%d", s), types.StageStatusNotStarted); err != nil {
+				t.Fatalf("insert stage: %v", err)
+			}
+		}
+	}
+}
+
+func setupPostgresTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+	return storetest.NewDB(t)
+}
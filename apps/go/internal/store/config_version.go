@@ -0,0 +1,34 @@
+package store
+
+import (
+	"context"
+	"strconv"
+)
+
+// CurrentConfigVersion returns the version of the configuration handed to
+// workers at bootstrap. It goes up whenever that configuration changes, so a
+// worker reporting an older version in its heartbeat is told to bootstrap
+// again.
+func (s *Store) CurrentConfigVersion(ctx context.Context) (string, error) {
+	var version int64
+	if err := s.db.GetContext(ctx, &version, `SELECT version FROM config_version WHERE id = 1`); err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(version, 10), nil
+}
+
+// SyncBrokerConfigVersion bumps the config version when fingerprint, a digest
+// of the broker settings an API instance hands to workers, differs from the
+// one recorded by the last instance that started. Broker settings come from
+// the environment, so this is how a redeploy with new settings reaches
+// workers bootstrapped by the previous one. It returns the current version.
+func (s *Store) SyncBrokerConfigVersion(ctx context.Context, fingerprint string) (string, error) {
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE config_version
+		SET version = version + 1, broker_fingerprint = $1, updated_at = NOW()
+		WHERE id = 1 AND broker_fingerprint IS DISTINCT FROM $1
+	`, fingerprint); err != nil {
+		return "", err
+	}
+	return s.CurrentConfigVersion(ctx)
+}
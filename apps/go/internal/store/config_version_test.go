@@ -0,0 +1,32 @@
+package store
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestSyncBrokerConfigVersion(t *testing.T) {
+	db := setupPostgresTestDB(t)
+	ctx := context.Background()
+	st := New(db, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	steps := []struct {
+		fingerprint string
+		want        string
+	}{
+		{"aaa", "2"},
+		{"aaa", "2"},
+		{"bbb", "3"},
+	}
+	for _, step := range steps {
+		got, err := st.SyncBrokerConfigVersion(ctx, step.fingerprint)
+		if err != nil {
+			t.Fatalf("SyncBrokerConfigVersion(%s) error = %v", step.fingerprint, err)
+		}
+		if got != step.want {
+			t.Fatalf("SyncBrokerConfigVersion(%s) = %s, want %s", step.fingerprint, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"pipelogiq/internal/types"
+)
+
+var errInvalidContextItem = errors.New("invalid context item")
+
+func IsInvalidContextItemError(err error) bool {
+	return errors.Is(err, errInvalidContextItem)
+}
+
+// Context item value types. Values are always stored as text; the type says
+// how a stage should read them.
+const (
+	contextValueString  = "string"
+	contextValueNumber  = "number"
+	contextValueBoolean = "boolean"
+	contextValueJSON    = "json"
+)
+
+// contextValueTypeAliases maps accepted spellings to a canonical value type.
+var contextValueTypeAliases = map[string]string{
+	"":        contextValueString,
+	"string":  contextValueString,
+	"text":    contextValueString,
+	"number":  contextValueNumber,
+	"int":     contextValueNumber,
+	"integer": contextValueNumber,
+	"float":   contextValueNumber,
+	"double":  contextValueNumber,
+	"decimal": contextValueNumber,
+	"boolean": contextValueBoolean,
+	"bool":    contextValueBoolean,
+	"json":    contextValueJSON,
+	"object":  contextValueJSON,
+	"array":   contextValueJSON,
+}
+
+// normalizeContextItems checks every item's value against its declared type
+// and returns the items with canonical types and values.
+func normalizeContextItems(items []types.ContextItem) ([]types.ContextItem, error) {
+	if len(items) == 0 {
+		return items, nil
+	}
+	normalized := make([]types.ContextItem, 0, len(items))
+	for _, item := range items {
+		n, err := normalizeContextItem(item)
+		if err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, n)
+	}
+	return normalized, nil
+}
+
+// partitionContextItems normalizes each item like normalizeContextItems but
+// keeps going past invalid ones: it returns the valid items and the errors of
+// the rest.
+func partitionContextItems(items []types.ContextItem) ([]types.ContextItem, []error) {
+	valid := make([]types.ContextItem, 0, len(items))
+	var rejected []error
+	for _, item := range items {
+		n, err := normalizeContextItem(item)
+		if err != nil {
+			rejected = append(rejected, err)
+			continue
+		}
+		valid = append(valid, n)
+	}
+	return valid, rejected
+}
+
+// normalizeContextItem canonicalizes the value type and coerces the value:
+// numbers and booleans are trimmed and booleans are written as true/false.
+// A value that does not parse as its type is rejected.
+func normalizeContextItem(item types.ContextItem) (types.ContextItem, error) {
+	valueType, ok := contextValueTypeAliases[strings.ToLower(strings.TrimSpace(item.ValueType))]
+	if !ok {
+		return item, fmt.Errorf("%w: %q has unknown valueType %q (want string, number, boolean or json)",
+			errInvalidContextItem, item.Key, item.ValueType)
+	}
+	item.ValueType = valueType
+
+	switch valueType {
+	case contextValueNumber:
+		value := strings.TrimSpace(item.Value)
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return item, fmt.Errorf("%w: %q is typed number but its value %q is not a finite number",
+				errInvalidContextItem, item.Key, item.Value)
+		}
+		item.Value = value
+	case contextValueBoolean:
+		b, err := strconv.ParseBool(strings.TrimSpace(item.Value))
+		if err != nil {
+			return item, fmt.Errorf("%w: %q is typed boolean but its value %q is not true or false",
+				errInvalidContextItem, item.Key, item.Value)
+		}
+		item.Value = strconv.FormatBool(b)
+	case contextValueJSON:
+		if !json.Valid([]byte(item.Value)) {
+			return item, fmt.Errorf("%w: %q is typed json but its value is not valid JSON",
+				errInvalidContextItem, item.Key)
+		}
+	}
+	return item, nil
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"testing"
+
+	"pipelogiq/internal/types"
+)
+
+func TestNormalizeContextItem(t *testing.T) {
+	tests := []struct {
+		name      string
+		item      types.ContextItem
+		wantType  string
+		wantValue string
+		wantErr   bool
+	}{
+		{name: "empty type defaults to string", item: types.ContextItem{Key: "k", Value: " any text "}, wantType: "string", wantValue: " any text "},
+		{name: "string", item: types.ContextItem{Key: "k", Value: "42", ValueType: "string"}, wantType: "string", wantValue: "42"},
+		{name: "number", item: types.ContextItem{Key: "k", Value: " 12.5 ", ValueType: "number"}, wantType: "number", wantValue: "12.5"},
+		{name: "number alias", item: types.ContextItem{Key: "k", Value: "-3", ValueType: "Integer"}, wantType: "number", wantValue: "-3"},
+		{name: "number exponent", item: types.ContextItem{Key: "k", Value: "1e3", ValueType: "number"}, wantType: "number", wantValue: "1e3"},
+		{name: "non-numeric number", item: types.ContextItem{Key: "k", Value: "twelve", ValueType: "number"}, wantErr: true},
+		{name: "empty number", item: types.ContextItem{Key: "k", Value: "", ValueType: "number"}, wantErr: true},
+		{name: "NaN number", item: types.ContextItem{Key: "k", Value: "NaN", ValueType: "number"}, wantErr: true},
+		{name: "infinite number", item: types.ContextItem{Key: "k", Value: "Inf", ValueType: "number"}, wantErr: true},
+		{name: "boolean", item: types.ContextItem{Key: "k", Value: "true", ValueType: "boolean"}, wantType: "boolean", wantValue: "true"},
+		{name: "boolean coerced", item: types.ContextItem{Key: "k", Value: " 0 ", ValueType: "bool"}, wantType: "boolean", wantValue: "false"},
+		{name: "invalid boolean", item: types.ContextItem{Key: "k", Value: "yes", ValueType: "boolean"}, wantErr: true},
+		{name: "json object", item: types.ContextItem{Key: "k", Value: `{"a":[1,2]}`, ValueType: "json"}, wantType: "json", wantValue: `{"a":[1,2]}`},
+		{name: "json scalar", item: types.ContextItem{Key: "k", Value: `"text"`, ValueType: "json"}, wantType: "json", wantValue: `"text"`},
+		{name: "json alias", item: types.ContextItem{Key: "k", Value: `[1]`, ValueType: "array"}, wantType: "json", wantValue: `[1]`},
+		{name: "malformed json", item: types.ContextItem{Key: "k", Value: `{"a":`, ValueType: "json"}, wantErr: true},
+		{name: "empty json", item: types.ContextItem{Key: "k", Value: "", ValueType: "json"}, wantErr: true},
+		{name: "unknown type", item: types.ContextItem{Key: "k", Value: "x", ValueType: "date"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeContextItem(tt.item)
+			if tt.wantErr {
+				if !IsInvalidContextItemError(err) {
+					t.Fatalf("normalizeContextItem() err = %v, want invalid context item error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("normalizeContextItem() err = %v", err)
+			}
+			if got.ValueType != tt.wantType || got.Value != tt.wantValue {
+				t.Fatalf("normalizeContextItem() = (%q, %q), want (%q, %q)", got.ValueType, got.Value, tt.wantType, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestNormalizeContextItemsStopsAtFirstError(t *testing.T) {
+	_, err := normalizeContextItems([]types.ContextItem{
+		{Key: "ok", Value: "1", ValueType: "number"},
+		{Key: "bad", Value: "one", ValueType: "number"},
+	})
+	if !IsInvalidContextItemError(err) {
+		t.Fatalf("normalizeContextItems() err = %v, want invalid context item error", err)
+	}
+}
+
+func TestPartitionContextItemsKeepsValidItems(t *testing.T) {
+	valid, rejected := partitionContextItems([]types.ContextItem{
+		{Key: "ok", Value: " 1 ", ValueType: "number"},
+		{Key: "bad", Value: "one", ValueType: "number"},
+		{Key: "flag", Value: "1", ValueType: "bool"},
+	})
+	if len(rejected) != 1 || !IsInvalidContextItemError(rejected[0]) {
+		t.Fatalf("partitionContextItems() rejected = %v, want one invalid context item error", rejected)
+	}
+	if len(valid) != 2 || valid[0].Key != "ok" || valid[0].Value != "1" || valid[1].Key != "flag" || valid[1].Value != "true" {
+		t.Fatalf("partitionContextItems() valid = %+v, want normalized ok and flag", valid)
+	}
+}
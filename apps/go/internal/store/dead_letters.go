@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"pipelogiq/internal/types"
+)
+
+// RecordDeadLetter stores a dead-lettered message. The stage's pipeline and
+// handler are filled in from rec.StageID when it names a stage.
+func (s *Store) RecordDeadLetter(ctx context.Context, rec types.DeadLetterRecord) error {
+	headersJSON, err := toJSONText(rec.Headers, "{}")
+	if err != nil {
+		// Broker headers may hold values JSON cannot encode; the reason
+		// matters more than the headers.
+		headersJSON = "{}"
+	}
+	if rec.DeadLetteredAt.IsZero() {
+		rec.DeadLetteredAt = time.Now().UTC()
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO dead_letter_message (queue, stage_id, pipeline_id, handler, reason, message_id, headers_json, dead_lettered_at)
+		VALUES (
+			$1, $2,
+			(SELECT pipeline_id FROM stage WHERE id = $2),
+			(SELECT stage_handler_name FROM stage WHERE id = $2),
+			$3, $4, $5, $6
+		)
+	`, rec.Queue, nullableInt(rec.StageID), rec.Reason, nullableStringVal(rec.MessageID), headersJSON, rec.DeadLetteredAt.UTC()); err != nil {
+		return fmt.Errorf("insert dead letter: %w", err)
+	}
+	return nil
+}
+
+// ListDeadLetters returns the recorded dead letters of pipelines in userID's
+// applications, newest first. Messages that name no stage belong to no
+// application and are not listed.
+func (s *Store) ListDeadLetters(ctx context.Context, userID int, req types.DeadLetterListRequest) ([]types.DeadLetterRecord, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	query := strings.Builder{}
+	query.WriteString(`
+		SELECT d.id, d.queue, d.stage_id, d.pipeline_id, d.handler, d.reason, d.message_id, d.headers_json, d.dead_lettered_at
+		FROM dead_letter_message d
+		JOIN pipeline p ON p.id = d.pipeline_id
+		WHERE p.application_id IN (SELECT application_id FROM user_application WHERE user_id = $1)
+	`)
+	args := make([]any, 0, 5)
+	args = append(args, userID)
+	if queue := strings.TrimSpace(req.Queue); queue != "" {
+		args = append(args, queue)
+		query.WriteString(fmt.Sprintf(" AND d.queue = $%d", len(args)))
+	}
+	if handler := strings.TrimSpace(req.Handler); handler != "" {
+		args = append(args, handler)
+		query.WriteString(fmt.Sprintf(" AND d.handler = $%d", len(args)))
+	}
+	if req.StageID != nil && *req.StageID > 0 {
+		args = append(args, *req.StageID)
+		query.WriteString(fmt.Sprintf(" AND d.stage_id = $%d", len(args)))
+	}
+	args = append(args, limit)
+	query.WriteString(fmt.Sprintf(" ORDER BY d.dead_lettered_at DESC, d.id DESC LIMIT $%d", len(args)))
+
+	var rows []struct {
+		ID             int64          `db:"id"`
+		Queue          string         `db:"queue"`
+		StageID        *int           `db:"stage_id"`
+		PipelineID     *int           `db:"pipeline_id"`
+		Handler        *string        `db:"handler"`
+		Reason         string         `db:"reason"`
+		MessageID      sql.NullString `db:"message_id"`
+		HeadersJSON    string         `db:"headers_json"`
+		DeadLetteredAt time.Time      `db:"dead_lettered_at"`
+	}
+	if err := s.db.SelectContext(ctx, &rows, query.String(), args...); err != nil {
+		return nil, err
+	}
+
+	result := make([]types.DeadLetterRecord, 0, len(rows))
+	for _, row := range rows {
+		headers := map[string]any{}
+		_ = json.Unmarshal([]byte(strings.TrimSpace(row.HeadersJSON)), &headers)
+		if len(headers) == 0 {
+			headers = nil
+		}
+		result = append(result, types.DeadLetterRecord{
+			ID:             row.ID,
+			Queue:          row.Queue,
+			StageID:        row.StageID,
+			PipelineID:     row.PipelineID,
+			Handler:        row.Handler,
+			Reason:         row.Reason,
+			MessageID:      row.MessageID.String,
+			Headers:        headers,
+			DeadLetteredAt: row.DeadLetteredAt.UTC(),
+		})
+	}
+	return result, nil
+}
+
+// PruneDeadLetters deletes dead letters recorded more than retention ago and
+// returns how many it removed.
+func (s *Store) PruneDeadLetters(ctx context.Context, retention time.Duration) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM dead_letter_message WHERE dead_lettered_at < $1`,
+		time.Now().UTC().Add(-retention))
+	if err != nil {
+		return 0, fmt.Errorf("prune dead letters: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"pipelogiq/internal/types"
+)
+
+func TestListDeadLettersScopedToUserApplications(t *testing.T) {
+	db := setupPostgresTestDB(t)
+	st := New(db, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	if _, err := db.Exec(`
+		INSERT INTO user_application (user_id, application_id) VALUES (1, 10), (2, 20);
+		INSERT INTO pipeline (id, application_id, name, status) VALUES (1, 10, 'own', 'Running'), (2, 20, 'foreign', 'Running');
+		INSERT INTO stage (id, pipeline_id, name, stage_handler_name, status) VALUES (1, 1, 's', 'resize', 'Running'), (2, 2, 's', 'resize', 'Running');
+	`); err != nil {
+		t.Fatalf("seed pipelines: %v", err)
+	}
+	ownStage, foreignStage := 1, 2
+	for _, rec := range []types.DeadLetterRecord{
+		{Queue: "StageResult", StageID: &ownStage, Reason: "own"},
+		{Queue: "StageResult", StageID: &foreignStage, Reason: "foreign"},
+		{Queue: "StageSetStatus", Reason: "no stage"},
+	} {
+		if err := st.RecordDeadLetter(ctx, rec); err != nil {
+			t.Fatalf("RecordDeadLetter(%s) error = %v", rec.Reason, err)
+		}
+	}
+
+	for userID, want := range map[int]string{1: "own", 2: "foreign"} {
+		items, err := st.ListDeadLetters(ctx, userID, types.DeadLetterListRequest{})
+		if err != nil {
+			t.Fatalf("ListDeadLetters(user %d) error = %v", userID, err)
+		}
+		if len(items) != 1 || items[0].Reason != want || items[0].Handler == nil || *items[0].Handler != "resize" {
+			t.Fatalf("ListDeadLetters(user %d) = %+v, want only the %s dead letter", userID, items, want)
+		}
+	}
+	if items, err := st.ListDeadLetters(ctx, 3, types.DeadLetterListRequest{}); err != nil || len(items) != 0 {
+		t.Fatalf("ListDeadLetters(user without applications) = %+v, %v, want none", items, err)
+	}
+}
+
+func TestPruneDeadLetters(t *testing.T) {
+	db := setupPostgresTestDB(t)
+	st := New(db, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	for _, at := range []time.Time{now.Add(-48 * time.Hour), now.Add(-2 * time.Hour), now} {
+		if err := st.RecordDeadLetter(ctx, types.DeadLetterRecord{Queue: "StageResult", Reason: "r", DeadLetteredAt: at}); err != nil {
+			t.Fatalf("RecordDeadLetter() error = %v", err)
+		}
+	}
+
+	pruned, err := st.PruneDeadLetters(ctx, 24*time.Hour)
+	if err != nil || pruned != 1 {
+		t.Fatalf("PruneDeadLetters() = %d, %v, want 1", pruned, err)
+	}
+	var left int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM dead_letter_message`).Scan(&left); err != nil || left != 2 {
+		t.Fatalf("dead letters left = %d, %v, want 2", left, err)
+	}
+}
@@ -0,0 +1,171 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"pipelogiq/internal/types"
+)
+
+var errInvalidStageDependencies = errors.New("invalid stage dependencies")
+
+func IsInvalidStageDependenciesError(err error) bool {
+	return errors.Is(err, errInvalidStageDependencies)
+}
+
+// validateStageDependencies checks that every dependsOn entry names a stage of
+// the same pipeline and that the declared dependencies form no cycle. Stages
+// refer to each other by name, so a pipeline whose stages declare dependsOn
+// or runInParallelWith must not repeat a stage name.
+func validateStageDependencies(stages []types.StageCreate) error {
+	linked := false
+	for _, st := range stages {
+		if st.Options != nil && (len(st.Options.DependsOn) > 0 || len(st.Options.RunInParallelWith) > 0) {
+			linked = true
+			break
+		}
+	}
+	byName := make(map[string]int, len(stages))
+	for i, st := range stages {
+		name := strings.TrimSpace(st.Name)
+		if _, dup := byName[name]; dup && linked {
+			return fmt.Errorf("%w: stage name %q is used more than once", errInvalidStageDependencies, name)
+		}
+		byName[name] = i
+	}
+
+	deps := make([][]int, len(stages))
+	for i, st := range stages {
+		if st.Options == nil {
+			continue
+		}
+		for _, raw := range st.Options.DependsOn {
+			name := strings.TrimSpace(raw)
+			if name == "" {
+				continue
+			}
+			j, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("%w: stage %q depends on unknown stage %q", errInvalidStageDependencies, st.Name, name)
+			}
+			if j == i {
+				return fmt.Errorf("%w: stage %q depends on itself", errInvalidStageDependencies, st.Name)
+			}
+			deps[i] = append(deps[i], j)
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(stages))
+	var visit func(i int, path []string) error
+	visit = func(i int, path []string) error {
+		switch state[i] {
+		case visiting:
+			return fmt.Errorf("%w: dependency cycle %s", errInvalidStageDependencies,
+				strings.Join(append(path, stages[i].Name), " -> "))
+		case done:
+			return nil
+		}
+		state[i] = visiting
+		for _, j := range deps[i] {
+			if err := visit(j, append(path, stages[i].Name)); err != nil {
+				return err
+			}
+		}
+		state[i] = done
+		return nil
+	}
+	for i := range stages {
+		if err := visit(i, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveStageLinks fills DependsOn and RunInParallelWith on each stage by
+// mapping the stored stage-name lists to ids of stages in the same pipeline.
+func (s *Store) resolveStageLinks(ctx context.Context, stages []types.StageResponse) error {
+	if len(stages) == 0 {
+		return nil
+	}
+
+	stageIDs := make([]int, 0, len(stages))
+	for _, st := range stages {
+		stageIDs = append(stageIDs, st.ID)
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT stage_id, depends_on, run_in_parallel_with
+		FROM stage_options
+		WHERE stage_id IN (?)
+		ORDER BY id
+	`, stageIDs)
+	if err != nil {
+		return fmt.Errorf("build stage options query: %w", err)
+	}
+
+	var rows []struct {
+		StageID           int            `db:"stage_id"`
+		DependsOn         sql.NullString `db:"depends_on"`
+		RunInParallelWith sql.NullString `db:"run_in_parallel_with"`
+	}
+	if err := s.db.SelectContext(ctx, &rows, s.db.Rebind(query), args...); err != nil {
+		return fmt.Errorf("query stage options: %w", err)
+	}
+
+	type links struct{ dependsOn, parallel []string }
+	byStage := make(map[int]links, len(rows))
+	for _, row := range rows {
+		// Later rows win, matching how the scheduler reads stage_options.
+		byStage[row.StageID] = links{
+			dependsOn: splitList(row.DependsOn.String),
+			parallel:  splitList(row.RunInParallelWith.String),
+		}
+	}
+
+	idsByName := make(map[int]map[string][]int)
+	for _, st := range stages {
+		if idsByName[st.PipelineID] == nil {
+			idsByName[st.PipelineID] = map[string][]int{}
+		}
+		idsByName[st.PipelineID][st.Name] = append(idsByName[st.PipelineID][st.Name], st.ID)
+	}
+
+	resolve := func(pipelineID int, names []string) []int {
+		var ids []int
+		for _, name := range names {
+			ids = append(ids, idsByName[pipelineID][name]...)
+		}
+		return ids
+	}
+
+	for i := range stages {
+		l, ok := byStage[stages[i].ID]
+		if !ok {
+			continue
+		}
+		stages[i].DependsOn = resolve(stages[i].PipelineID, l.dependsOn)
+		stages[i].RunInParallelWith = resolve(stages[i].PipelineID, l.parallel)
+	}
+	return nil
+}
+
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if trimmed := strings.TrimSpace(item); trimmed != "" {
+			items = append(items, trimmed)
+		}
+	}
+	return items
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"strings"
+	"testing"
+
+	"pipelogiq/internal/types"
+)
+
+func TestValidateStageDependencies(t *testing.T) {
+	stage := func(name string, dependsOn ...string) types.StageCreate {
+		st := types.StageCreate{Name: name, StageHandler: "h"}
+		if len(dependsOn) > 0 {
+			st.Options = &types.StageOptions{DependsOn: dependsOn}
+		}
+		return st
+	}
+	parallel := stage("b")
+	parallel.Options = &types.StageOptions{RunInParallelWith: []string{"a"}}
+
+	tests := []struct {
+		name    string
+		stages  []types.StageCreate
+		wantErr string
+	}{
+		{"no dependencies", []types.StageCreate{stage("a"), stage("b")}, ""},
+		{"repeated names without dependencies", []types.StageCreate{stage("a"), stage("a")}, ""},
+		{"diamond", []types.StageCreate{stage("a"), stage("b", "a"), stage("c", "a"), stage("d", "b", "c")}, ""},
+		{"unknown stage", []types.StageCreate{stage("a", "x")}, `depends on unknown stage "x"`},
+		{"self dependency", []types.StageCreate{stage("a", "a")}, "depends on itself"},
+		{"cycle", []types.StageCreate{stage("a", "b"), stage("b", "a")}, "dependency cycle"},
+		{"repeated name with dependencies", []types.StageCreate{stage("a"), stage("a"), stage("b", "a")}, `stage name "a" is used more than once`},
+		{"repeated name with parallel stages", []types.StageCreate{stage("a"), parallel, stage(" a ")}, `stage name "a" is used more than once`},
+	}
+	for _, tt := range tests {
+		err := validateStageDependencies(tt.stages)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: validateStageDependencies() error = %v", tt.name, err)
+			}
+			continue
+		}
+		if !IsInvalidStageDependenciesError(err) || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: validateStageDependencies() error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"pipelogiq/internal/types"
+)
+
+// Retry options applied at create time to stages that declare none, when
+// FeatureFlagDefaultStageRetries is enabled for the application.
+const (
+	defaultStageMaxRetries    = 3
+	defaultStageRetryInterval = 30
+)
+
+var errUnknownFeatureFlag = errors.New("unknown feature flag")
+
+func IsUnknownFeatureFlagError(err error) bool {
+	return errors.Is(err, errUnknownFeatureFlag)
+}
+
+// GetFeatureFlags returns every known flag with its effective value for the
+// application.
+func (s *Store) GetFeatureFlags(ctx context.Context, appID int) ([]types.FeatureFlag, error) {
+	var rows []struct {
+		Flag      string    `db:"flag"`
+		Enabled   bool      `db:"enabled"`
+		UpdatedAt time.Time `db:"updated_at"`
+	}
+	if err := s.db.SelectContext(ctx, &rows, `
+		SELECT flag, enabled, updated_at
+		FROM application_feature_flag
+		WHERE application_id = $1
+	`, appID); err != nil {
+		return nil, fmt.Errorf("query feature flags: %w", err)
+	}
+
+	flags := make([]types.FeatureFlag, 0, len(types.FeatureFlagDefaults))
+	byName := make(map[string]int, len(types.FeatureFlagDefaults))
+	for name, def := range types.FeatureFlagDefaults {
+		byName[name] = len(flags)
+		flags = append(flags, types.FeatureFlag{Flag: name, Enabled: def, Default: def})
+	}
+	for _, row := range rows {
+		i, ok := byName[row.Flag]
+		if !ok {
+			continue
+		}
+		updatedAt := row.UpdatedAt
+		flags[i].Enabled = row.Enabled
+		flags[i].UpdatedAt = &updatedAt
+	}
+
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Flag < flags[j].Flag })
+	return flags, nil
+}
+
+// SetFeatureFlag stores an explicit per-application override for a known flag.
+func (s *Store) SetFeatureFlag(ctx context.Context, appID int, flag string, enabled bool) error {
+	if _, ok := types.FeatureFlagDefaults[flag]; !ok {
+		return fmt.Errorf("%w: %s", errUnknownFeatureFlag, flag)
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO application_feature_flag (application_id, flag, enabled, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (application_id, flag)
+		DO UPDATE SET enabled = EXCLUDED.enabled, updated_at = NOW()
+	`, appID, flag, enabled)
+	if err != nil {
+		return fmt.Errorf("save feature flag: %w", err)
+	}
+	return nil
+}
+
+// featureEnabled resolves a single flag for the application, falling back to
+// the flag's default when there is no override.
+func featureEnabled(ctx context.Context, q sqlx.QueryerContext, appID int, flag string) (bool, error) {
+	var enabled bool
+	err := sqlx.GetContext(ctx, q, &enabled, `
+		SELECT enabled
+		FROM application_feature_flag
+		WHERE application_id = $1 AND flag = $2
+	`, appID, flag)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.FeatureFlagDefaults[flag], nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("load feature flag %s: %w", flag, err)
+	}
+	return enabled, nil
+}
+
+// withDefaultRetries returns a copy of stages where stages without retry
+// options get the default retry policy.
+func withDefaultRetries(stages []types.StageCreate) []types.StageCreate {
+	out := make([]types.StageCreate, len(stages))
+	for i, st := range stages {
+		out[i] = st
+		if st.IsEvent {
+			continue
+		}
+		var opt types.StageOptions
+		if st.Options != nil {
+			opt = *st.Options
+		}
+		if opt.MaxRetries == nil && opt.RetryInterval == nil {
+			maxRetries, retryInterval := defaultStageMaxRetries, defaultStageRetryInterval
+			opt.MaxRetries = &maxRetries
+			opt.RetryInterval = &retryInterval
+		}
+		out[i].Options = &opt
+	}
+	return out
+}
@@ -44,23 +44,9 @@ func (s *Store) GetStageLogs(ctx context.Context, pipelineID int, stageID *int)
 	return logs, nil
 }
 
-func (s *Store) SaveLog(ctx context.Context, req types.LogRequest) (*types.LogResponse, error) {
-	var appID *int
-
-	// Get application ID from API key if provided
-	if req.ApiKey != nil && *req.ApiKey != "" {
-		var id int
-		err := s.db.QueryRowContext(ctx, `
-			SELECT application_id FROM api_key WHERE key = $1 AND disabled_at IS NULL
-		`, *req.ApiKey).Scan(&id)
-		if err != nil && !errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("validate api key: %w", err)
-		}
-		if err == nil {
-			appID = &id
-		}
-	}
-
+// SaveLog stores a log line of the application appID, which the caller has
+// resolved from a valid API key.
+func (s *Store) SaveLog(ctx context.Context, appID int, req types.LogRequest) (*types.LogResponse, error) {
 	created := req.Created
 	if created == nil {
 		now := time.Now()
@@ -102,7 +88,7 @@ func (s *Store) SaveLog(ctx context.Context, req types.LogRequest) (*types.LogRe
 
 	return &types.LogResponse{
 		ID:            logID,
-		ApplicationID: appID,
+		ApplicationID: &appID,
 		Message:       req.Message,
 		LogLevel:      req.LogLevel,
 		CreatedAt:     created,
@@ -110,18 +96,20 @@ func (s *Store) SaveLog(ctx context.Context, req types.LogRequest) (*types.LogRe
 	}, nil
 }
 
-// LogStageChange inserts a stage status change entry into stage_log.
+// LogStageChange inserts a stage status change entry into stage_log and
+// stage_transition, the source of GetPipelineTimeline.
 // Best-effort: errors are logged but do not propagate.
 func (s *Store) LogStageChange(ctx context.Context, pipelineID, stageID int, oldStatus, newStatus, source string) {
 	// Fetch stage name for human-readable message.
 	var stageName string
 	var pipelineName string
+	var retryAttempt int
 	_ = s.db.QueryRowContext(ctx, `
-		SELECT s.name, COALESCE(p.name, '')
+		SELECT s.name, COALESCE(p.name, ''), COALESCE(s.retry_attempt, 0)
 		FROM stage s
 		LEFT JOIN pipeline p ON p.id = s.pipeline_id
 		WHERE s.id = $1
-	`, stageID).Scan(&stageName, &pipelineName)
+	`, stageID).Scan(&stageName, &pipelineName, &retryAttempt)
 
 	msg := fmt.Sprintf("Stage '%s' (id=%d) status changed: %s → %s [pipeline=%d, source=%s]",
 		stageName, stageID, oldStatus, newStatus, pipelineID, source)
@@ -135,6 +123,12 @@ func (s *Store) LogStageChange(ctx context.Context, pipelineID, stageID int, old
 	if err != nil {
 		s.logger.Error("failed to log stage change", "err", err)
 	}
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO stage_transition (pipeline_id, stage_id, from_status, to_status, source, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, pipelineID, stageID, nullableString(oldStatus), newStatus, source, now); err != nil {
+		s.logger.Error("failed to record stage transition", "err", err)
+	}
 
 	s.emitStageAlert(StageAlertEvent{
 		PipelineID:   pipelineID,
@@ -144,6 +138,7 @@ func (s *Store) LogStageChange(ctx context.Context, pipelineID, stageID int, old
 		OldStatus:    oldStatus,
 		NewStatus:    newStatus,
 		Source:       source,
+		RetryAttempt: retryAttempt,
 		TS:           now.UTC(),
 	})
 }
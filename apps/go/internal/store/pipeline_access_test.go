@@ -0,0 +1,57 @@
+package store
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestUserCanAccessPipeline(t *testing.T) {
+	db := setupPostgresTestDB(t)
+	ctx := context.Background()
+	st := New(db, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	// User 1 belongs to application 10, user 2 to application 20 and user 3
+	// to both.
+	if _, err := db.Exec(`
+		INSERT INTO user_application (user_id, application_id) VALUES (1, 10), (2, 20), (3, 10), (3, 20)
+	`); err != nil {
+		t.Fatalf("insert user applications: %v", err)
+	}
+	insertPipeline := func(appID any) int {
+		t.Helper()
+		var id int
+		if err := db.QueryRow(`INSERT INTO pipeline (application_id, name, status) VALUES ($1, 'p', 'Running') RETURNING id`, appID).Scan(&id); err != nil {
+			t.Fatalf("insert pipeline: %v", err)
+		}
+		return id
+	}
+	first := insertPipeline(10)
+	second := insertPipeline(20)
+	orphan := insertPipeline(nil)
+
+	cases := []struct {
+		name       string
+		userID     int
+		pipelineID int
+		want       bool
+	}{
+		{"own pipeline", 1, first, true},
+		{"other application's pipeline", 1, second, false},
+		{"other application's pipeline, reverse", 2, first, false},
+		{"member of both applications", 3, second, true},
+		{"pipeline without application", 1, orphan, false},
+		{"missing pipeline", 1, second + 100, false},
+		{"unknown user", 99, first, false},
+	}
+	for _, tc := range cases {
+		got, err := st.UserCanAccessPipeline(ctx, tc.userID, tc.pipelineID)
+		if err != nil {
+			t.Fatalf("%s: UserCanAccessPipeline() error = %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: UserCanAccessPipeline(user %d, pipeline %d) = %v, want %v", tc.name, tc.userID, tc.pipelineID, got, tc.want)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package store
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -12,7 +14,8 @@ import (
 	"pipelogiq/internal/types"
 )
 
-func (s *Store) GetPipelines(ctx context.Context, req types.GetPipelinesRequest) (*types.PagedResult[types.PipelineResponse], error) {
+// GetPipelines lists the pipelines of the user's applications matching req.
+func (s *Store) GetPipelines(ctx context.Context, userID int, req types.GetPipelinesRequest) (*types.PagedResult[types.PipelineResponse], error) {
 	pageNumber := 1
 	pageSize := 10
 
@@ -25,10 +28,15 @@ func (s *Store) GetPipelines(ctx context.Context, req types.GetPipelinesRequest)
 
 	offset := (pageNumber - 1) * pageSize
 
+	orderBy, err := pipelineOrderBy(req.SortBy, req.SortDir)
+	if err != nil {
+		return nil, err
+	}
+
 	// Build WHERE clause
-	conditions := []string{"1=1"}
-	args := []interface{}{}
-	argNum := 1
+	conditions := []string{"p.application_id IN (SELECT application_id FROM user_application WHERE user_id = $1)"}
+	args := []interface{}{userID}
+	argNum := 2
 
 	if req.ApplicationID != nil {
 		conditions = append(conditions, fmt.Sprintf("p.application_id = $%d", argNum))
@@ -78,11 +86,20 @@ func (s *Store) GetPipelines(ctx context.Context, req types.GetPipelinesRequest)
 		}
 	}
 
+	// Exact, case-insensitive trace id lookup
+	if req.TraceID != nil && *req.TraceID != "" {
+		conditions = append(conditions, fmt.Sprintf("LOWER(p.trace_id) = LOWER($%d)", argNum))
+		args = append(args, strings.TrimSpace(*req.TraceID))
+		argNum++
+	}
+
 	// Full-text search across keyword values, pipeline name, stage name/description
+	// and trace id (prefix match)
 	if req.Search != nil && *req.Search != "" {
 		searchPattern := "%" + *req.Search + "%"
 		conditions = append(conditions, fmt.Sprintf(`(
 			p.name ILIKE $%d
+			OR LOWER(p.trace_id) LIKE LOWER($%d) || '%%'
 			OR EXISTS (
 				SELECT 1 FROM stage s2 WHERE s2.pipeline_id = p.id
 				AND (s2.name ILIKE $%d OR s2.description ILIKE $%d)
@@ -96,26 +113,28 @@ func (s *Store) GetPipelines(ctx context.Context, req types.GetPipelinesRequest)
 				SELECT 1 FROM pipeline_context_item pci
 				WHERE pci.pipeline_id = p.id AND (pci.key ILIKE $%d OR pci.value ILIKE $%d)
 			)
-		)`, argNum, argNum, argNum, argNum, argNum, argNum))
-		args = append(args, searchPattern)
-		argNum++
+		)`, argNum, argNum+1, argNum, argNum, argNum, argNum, argNum))
+		args = append(args, searchPattern, strings.TrimSpace(*req.Search))
+		argNum += 2
 	}
 
 	// Keyword filter
-	if len(req.Keywords) > 0 {
-		keywordPlaceholders := make([]string, len(req.Keywords))
-		for i, kw := range req.Keywords {
-			keywordPlaceholders[i] = fmt.Sprintf("$%d", argNum)
-			args = append(args, kw)
-			argNum++
-		}
-		conditions = append(conditions, fmt.Sprintf(`
-			EXISTS (
-				SELECT 1 FROM pipeline_keyword pk
-				JOIN keyword k ON k.id = pk.keyword_id
-				WHERE pk.pipeline_id = p.id AND k.key IN (%s)
-			)
-		`, strings.Join(keywordPlaceholders, ",")))
+	keywordCondition, keywordArgs, argNum, err := pipelineKeywordCondition(req.Keywords, req.KeywordMatch, argNum)
+	if err != nil {
+		return nil, err
+	}
+	if keywordCondition != "" {
+		conditions = append(conditions, keywordCondition)
+		args = append(args, keywordArgs...)
+	}
+
+	labelCondition, labelArgs, argNum, err := pipelineLabelCondition(req.Labels, argNum)
+	if err != nil {
+		return nil, err
+	}
+	if labelCondition != "" {
+		conditions = append(conditions, labelCondition)
+		args = append(args, labelArgs...)
 	}
 
 	whereClause := strings.Join(conditions, " AND ")
@@ -123,7 +142,7 @@ func (s *Store) GetPipelines(ctx context.Context, req types.GetPipelinesRequest)
 	// Count total
 	var totalCount int
 	countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM pipeline p WHERE %s`, whereClause)
-	err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&totalCount)
+	err = s.db.QueryRowContext(ctx, countQuery, args...).Scan(&totalCount)
 	if err != nil {
 		return nil, fmt.Errorf("count pipelines: %w", err)
 	}
@@ -131,12 +150,12 @@ func (s *Store) GetPipelines(ctx context.Context, req types.GetPipelinesRequest)
 	// Get pipelines
 	args = append(args, pageSize, offset)
 	query := fmt.Sprintf(`
-		SELECT p.id, p.name, COALESCE(p.trace_id, '') AS trace_id, p.status, p.created_at, p.finished_at, p.application_id
+		SELECT p.id, p.name, COALESCE(p.trace_id, '') AS trace_id, p.status, p.created_at, p.finished_at, p.application_id, p.labels, p.priority
 		FROM pipeline p
 		WHERE %s
-		ORDER BY p.created_at DESC
+		ORDER BY %s
 		LIMIT $%d OFFSET $%d
-	`, whereClause, argNum, argNum+1)
+	`, whereClause, orderBy, argNum, argNum+1)
 
 	rows, err := s.db.QueryxContext(ctx, query, args...)
 	if err != nil {
@@ -155,6 +174,8 @@ func (s *Store) GetPipelines(ctx context.Context, req types.GetPipelinesRequest)
 			CreatedAt     time.Time  `db:"created_at"`
 			FinishedAt    *time.Time `db:"finished_at"`
 			ApplicationID *int       `db:"application_id"`
+			Labels        []byte     `db:"labels"`
+			Priority      int        `db:"priority"`
 		}
 		if err := rows.StructScan(&p); err != nil {
 			continue
@@ -173,6 +194,8 @@ func (s *Store) GetPipelines(ctx context.Context, req types.GetPipelinesRequest)
 			CreatedAt:     p.CreatedAt,
 			FinishedAt:    p.FinishedAt,
 			ApplicationID: p.ApplicationID,
+			Labels:        decodePipelineLabels(p.Labels),
+			Priority:      p.Priority,
 		}
 
 		pipelines = append(pipelines, pipeline)
@@ -202,6 +225,18 @@ func (s *Store) GetPipelines(ctx context.Context, req types.GetPipelinesRequest)
 	}, nil
 }
 
+// GetStagePipelineID returns the pipeline the stage belongs to.
+func (s *Store) GetStagePipelineID(ctx context.Context, stageID int) (int, error) {
+	var pipelineID int
+	if err := s.db.GetContext(ctx, &pipelineID, `SELECT pipeline_id FROM stage WHERE id = $1`, stageID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, errStageNotFound
+		}
+		return 0, fmt.Errorf("get stage pipeline: %w", err)
+	}
+	return pipelineID, nil
+}
+
 func (s *Store) RerunStage(ctx context.Context, stageID int, rerunAllNext bool) error {
 	tx, err := s.db.BeginTxx(ctx, nil)
 	if err != nil {
@@ -243,10 +278,19 @@ func (s *Store) RerunStage(ctx context.Context, stageID int, rerunAllNext bool)
 		}
 	}
 
+	archiveIDs := make([]int, 0, len(affectedStages))
+	for _, stage := range affectedStages {
+		archiveIDs = append(archiveIDs, stage.ID)
+	}
+	if err = archiveStageExecutionsTx(ctx, tx, archiveIDs, "rerun_stage"); err != nil {
+		return err
+	}
+
 	// Reset the stage
 	_, err = tx.ExecContext(ctx, `
 		UPDATE stage
-		SET status = $1, started_at = NULL, finished_at = NULL, is_skipped = false, retry_attempt = 0, next_retry_at = NULL
+		SET status = $1, started_at = NULL, finished_at = NULL, is_skipped = false, retry_attempt = 0, next_retry_at = NULL,
+			failure_category = NULL, failure_detail = NULL
 		WHERE id = $2
 	`, types.StageStatusNotStarted, stageID)
 	if err != nil {
@@ -254,13 +298,14 @@ func (s *Store) RerunStage(ctx context.Context, stageID int, rerunAllNext bool)
 	}
 
 	// Clear output
-	_, _ = tx.ExecContext(ctx, `UPDATE stage_io SET output = NULL WHERE stage_id = $1`, stageID)
+	_, _ = tx.ExecContext(ctx, `UPDATE stage_io SET output = NULL, output_truncated = false, output_bytes = NULL WHERE stage_id = $1`, stageID)
 
 	if rerunAllNext {
 		// Reset all subsequent stages
 		_, err = tx.ExecContext(ctx, `
 			UPDATE stage
-			SET status = $1, started_at = NULL, finished_at = NULL, is_skipped = false, retry_attempt = 0, next_retry_at = NULL
+			SET status = $1, started_at = NULL, finished_at = NULL, is_skipped = false, retry_attempt = 0, next_retry_at = NULL,
+				failure_category = NULL, failure_detail = NULL
 			WHERE pipeline_id = $2 AND id > $3
 		`, types.StageStatusNotStarted, pipelineID, stageID)
 		if err != nil {
@@ -269,7 +314,7 @@ func (s *Store) RerunStage(ctx context.Context, stageID int, rerunAllNext bool)
 
 		// Clear outputs of subsequent stages
 		_, _ = tx.ExecContext(ctx, `
-			UPDATE stage_io SET output = NULL
+			UPDATE stage_io SET output = NULL, output_truncated = false, output_bytes = NULL
 			WHERE stage_id IN (SELECT id FROM stage WHERE pipeline_id = $1 AND id > $2)
 		`, pipelineID, stageID)
 	}
@@ -357,6 +402,163 @@ func (s *Store) SkipStage(ctx context.Context, stageID int) error {
 	return nil
 }
 
+var (
+	errPipelineNotFound   = errors.New("pipeline not found")
+	errPipelineNotRunning = errors.New("pipeline already finished")
+)
+
+func IsPipelineNotFoundError(err error) bool {
+	return errors.Is(err, errPipelineNotFound)
+}
+
+func IsPipelineNotRunningError(err error) bool {
+	return errors.Is(err, errPipelineNotRunning)
+}
+
+// CancelPipeline moves every non-terminal stage of the pipeline to Cancelled
+// and marks the pipeline itself Cancelled. Results for stages already pulled by
+// workers are ignored by UpdateStageResult once they arrive.
+func (s *Store) CancelPipeline(ctx context.Context, pipelineID int) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var isCompleted bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT is_completed
+		FROM pipeline
+		WHERE id = $1
+		FOR UPDATE
+	`, pipelineID).Scan(&isCompleted)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = errPipelineNotFound
+		}
+		return err
+	}
+	if isCompleted {
+		err = errPipelineNotRunning
+		return err
+	}
+
+	cancelled := make([]struct {
+		ID     int    `db:"id"`
+		Status string `db:"status"`
+	}, 0)
+	if err = tx.SelectContext(ctx, &cancelled, `
+		SELECT id, status
+		FROM stage
+		WHERE pipeline_id = $1 AND status IN ($2,$3,$4,$5)
+		ORDER BY id
+		FOR UPDATE
+	`, pipelineID, types.StageStatusNotStarted, types.StageStatusPending,
+		types.StageStatusRunning, types.StageStatusRetryScheduled); err != nil {
+		return fmt.Errorf("load stages to cancel: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE stage
+		SET status = $1, finished_at = NOW(), next_retry_at = NULL, failure_category = $7, failure_detail = $8
+		WHERE pipeline_id = $2 AND status IN ($3,$4,$5,$6)
+	`, types.StageStatusCancelled, pipelineID, types.StageStatusNotStarted, types.StageStatusPending,
+		types.StageStatusRunning, types.StageStatusRetryScheduled,
+		types.StageFailureCancelled, marshalStageFailure(types.StageFailure{
+			Category: types.StageFailureCancelled,
+			Reason:   "pipeline_cancelled",
+			At:       time.Now().UTC(),
+		}))
+	if err != nil {
+		return fmt.Errorf("cancel stages: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE pipeline SET status = $1, is_completed = true, finished_at = NOW()
+		WHERE id = $2
+	`, types.PipelineStatusCancelled, pipelineID)
+	if err != nil {
+		return fmt.Errorf("cancel pipeline: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, stage := range cancelled {
+		s.LogStageChange(ctx, pipelineID, stage.ID, stage.Status, types.StageStatusCancelled, "cancel_pipeline")
+	}
+
+	return nil
+}
+
+// PipelineApplicationID returns the application that owns the pipeline.
+func (s *Store) PipelineApplicationID(ctx context.Context, pipelineID int) (int, error) {
+	var appID sql.NullInt64
+	err := s.db.QueryRowContext(ctx, `SELECT application_id FROM pipeline WHERE id = $1`, pipelineID).Scan(&appID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, errPipelineNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return int(appID.Int64), nil
+}
+
+// DeletePipeline removes the pipeline and all of its stage, log, option,
+// keyword and context rows. Only a pipeline of the given application is
+// deleted; otherwise errPipelineNotFound is returned.
+func (s *Store) DeletePipeline(ctx context.Context, pipelineID, appID int) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var lockedID int
+	err = tx.QueryRowContext(ctx, `
+		SELECT id FROM pipeline
+		WHERE id = $1 AND application_id = $2
+		FOR UPDATE
+	`, pipelineID, appID).Scan(&lockedID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = errPipelineNotFound
+		}
+		return err
+	}
+
+	steps := []struct {
+		name  string
+		query string
+	}{
+		{"stage logs", `DELETE FROM stage_log WHERE stage_id IN (SELECT id FROM stage WHERE pipeline_id = $1)`},
+		{"stage io", `DELETE FROM stage_io WHERE stage_id IN (SELECT id FROM stage WHERE pipeline_id = $1)`},
+		{"stage history", `DELETE FROM stage_execution_history WHERE stage_id IN (SELECT id FROM stage WHERE pipeline_id = $1)`},
+		{"stage transitions", `DELETE FROM stage_transition WHERE pipeline_id = $1`},
+		{"stage options", `DELETE FROM stage_options WHERE stage_id IN (SELECT id FROM stage WHERE pipeline_id = $1)`},
+		{"stages", `DELETE FROM stage WHERE pipeline_id = $1`},
+		{"pipeline keywords", `DELETE FROM pipeline_keyword WHERE pipeline_id = $1`},
+		{"pipeline context", `DELETE FROM pipeline_context_item WHERE pipeline_id = $1`},
+		{"pipeline", `DELETE FROM pipeline WHERE id = $1`},
+	}
+	for _, step := range steps {
+		if _, err = tx.ExecContext(ctx, step.query, pipelineID); err != nil {
+			return fmt.Errorf("delete %s: %w", step.name, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
 func (s *Store) GetStagesForPipelines(ctx context.Context, pipelineIDs []int) (map[int][]types.StageResponse, error) {
 	query, args, err := sqlx.In(`
 		SELECT
@@ -373,7 +575,10 @@ func (s *Store) GetStagesForPipelines(ctx context.Context, pipelineIDs []int) (m
 			s.is_skipped AS is_skipped,
 			s.is_event AS is_event,
 			io.input AS input,
-			io.output AS output
+			io.output AS output,
+			COALESCE(io.output_truncated, false) AS output_truncated,
+			io.output_bytes AS output_bytes,
+			s.failure_detail AS failure_detail
 		FROM stage s
 		LEFT JOIN stage_io io ON io.stage_id = s.id
 		WHERE s.pipeline_id IN (?)
@@ -390,6 +595,10 @@ func (s *Store) GetStagesForPipelines(ctx context.Context, pipelineIDs []int) (m
 		return nil, fmt.Errorf("query stages: %w", err)
 	}
 
+	if err := s.resolveStageLinks(ctx, stages); err != nil {
+		return nil, err
+	}
+
 	result := make(map[int][]types.StageResponse, len(pipelineIDs))
 	for i := range stages {
 		pid := stages[i].PipelineID
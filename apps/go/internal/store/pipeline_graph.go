@@ -0,0 +1,239 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"pipelogiq/internal/types"
+)
+
+// GetPipelineGraph returns the stages of a pipeline as graph nodes with their
+// dependsOn, runInParallelWith and, for sequential pipelines, sequence edges.
+// The mode and frontier follow the scheduler: see eligibleStagesQuery.
+func (s *Store) GetPipelineGraph(ctx context.Context, pipelineID int) (*types.PipelineGraph, error) {
+	var pipeline struct {
+		ApplicationID int  `db:"application_id"`
+		IsCompleted   bool `db:"is_completed"`
+	}
+	if err := s.db.GetContext(ctx, &pipeline, `
+		SELECT COALESCE(application_id, 0) AS application_id, COALESCE(is_completed, false) AS is_completed
+		FROM pipeline WHERE id=$1
+	`, pipelineID); err != nil {
+		return nil, err
+	}
+
+	stages, err := s.GetPipelineStages(ctx, pipelineID)
+	if err != nil {
+		return nil, err
+	}
+
+	dag, err := s.scheduledAsDAG(ctx, pipeline.ApplicationID, stages)
+	if err != nil {
+		return nil, err
+	}
+
+	graph := buildPipelineGraph(stages, dag, pipeline.IsCompleted)
+	graph.PipelineID = pipelineID
+	return graph, nil
+}
+
+// scheduledAsDAG reports whether the scheduler orders the stages by their
+// dependencies: they declare some and the application has the dag_execution
+// flag on.
+func (s *Store) scheduledAsDAG(ctx context.Context, appID int, stages []types.StageResponse) (bool, error) {
+	for _, st := range stages {
+		if len(st.DependsOn) > 0 {
+			return featureEnabled(ctx, s.db, appID, types.FeatureFlagDAGExecution)
+		}
+	}
+	return false, nil
+}
+
+// stageAdjacency returns the downstream adjacency of graph's nodes along the
+// edges the scheduler follows: dependsOn in dag mode, sequence otherwise.
+func stageAdjacency(graph *types.PipelineGraph) map[int][]int {
+	follow := types.PipelineGraphEdgeSequence
+	if graph.Mode == types.PipelineGraphModeDAG {
+		follow = types.PipelineGraphEdgeDependsOn
+	}
+	adjacency := make(map[int][]int, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		adjacency[node.ID] = []int{}
+	}
+	for _, edge := range graph.Edges {
+		if edge.Kind == follow {
+			adjacency[edge.From] = append(adjacency[edge.From], edge.To)
+		}
+	}
+	return adjacency
+}
+
+// buildPipelineGraph builds the graph of a single pipeline's stages, whose
+// DependsOn and RunInParallelWith are resolved to ids. dag tells whether the
+// scheduler orders them by their dependencies or by id. The frontier follows
+// eligibleStagesQuery: a completed pipeline, or one with a Pending stage, has
+// none, and a stage waiting out its next_retry_at is not in it yet.
+func buildPipelineGraph(stages []types.StageResponse, dag, completed bool) *types.PipelineGraph {
+	graph := &types.PipelineGraph{
+		Mode:     types.PipelineGraphModeSequential,
+		Nodes:    make([]types.PipelineGraphNode, 0, len(stages)),
+		Edges:    []types.PipelineGraphEdge{},
+		Frontier: []int{},
+	}
+	if dag {
+		graph.Mode = types.PipelineGraphModeDAG
+	}
+
+	status := make(map[int]string, len(stages))
+	blocked := completed
+	for _, st := range stages {
+		status[st.ID] = st.Status
+		if st.Status == types.StageStatusPending {
+			blocked = true
+		}
+	}
+	now := time.Now()
+	due := func(st types.StageResponse) bool {
+		switch st.Status {
+		case types.StageStatusNotStarted:
+			return st.NextRetryAt == nil || !st.NextRetryAt.After(now)
+		case types.StageStatusRetryScheduled:
+			return st.NextRetryAt != nil && !st.NextRetryAt.After(now)
+		}
+		return false
+	}
+	done := func(id int) bool {
+		return status[id] == types.StageStatusCompleted || status[id] == types.StageStatusSkipped
+	}
+
+	parallel := map[[2]int]bool{}
+	for _, st := range stages {
+		for _, dep := range st.DependsOn {
+			graph.Edges = append(graph.Edges, types.PipelineGraphEdge{From: dep, To: st.ID, Kind: types.PipelineGraphEdgeDependsOn})
+		}
+		for _, other := range st.RunInParallelWith {
+			pair := [2]int{min(st.ID, other), max(st.ID, other)}
+			if other == st.ID || parallel[pair] {
+				continue
+			}
+			parallel[pair] = true
+			graph.Edges = append(graph.Edges, types.PipelineGraphEdge{From: pair[0], To: pair[1], Kind: types.PipelineGraphEdgeRunInParallelWith})
+		}
+		if !dag && st.NextStageID != nil {
+			graph.Edges = append(graph.Edges, types.PipelineGraphEdge{From: st.ID, To: *st.NextStageID, Kind: types.PipelineGraphEdgeSequence})
+		}
+	}
+
+	graph.Cycles = findDependencyCycles(stages)
+	inCycle := map[int]bool{}
+	for _, cycle := range graph.Cycles {
+		for _, id := range cycle {
+			inCycle[id] = true
+		}
+	}
+
+	// earlierPending tracks, in id order, whether a sequential pipeline
+	// still has an unfinished stage ahead of the current one.
+	earlierPending := false
+	for _, st := range stages {
+		isEvent := st.IsEvent != nil && *st.IsEvent
+		isSkipped := st.IsSkipped != nil && *st.IsSkipped
+
+		ready := false
+		if !blocked && !isEvent && !isSkipped && due(st) {
+			if dag {
+				ready = !inCycle[st.ID]
+				for _, dep := range st.DependsOn {
+					ready = ready && done(dep)
+				}
+			} else {
+				ready = !earlierPending
+			}
+		}
+		if !isEvent && !done(st.ID) {
+			earlierPending = true
+		}
+
+		if ready {
+			graph.Frontier = append(graph.Frontier, st.ID)
+		}
+		graph.Nodes = append(graph.Nodes, types.PipelineGraphNode{
+			ID:               st.ID,
+			Name:             st.Name,
+			StageHandlerName: st.StageHandlerName,
+			Status:           st.Status,
+			IsEvent:          isEvent,
+			IsSkipped:        isSkipped,
+			InCycle:          inCycle[st.ID],
+			Frontier:         ready,
+		})
+	}
+	return graph
+}
+
+// findDependencyCycles returns the stage ids of each dependency cycle, each
+// sorted, using Tarjan's strongly connected components. Pipelines created
+// through the API are checked by validateStageDependencies, so cycles only
+// show up in data written some other way.
+func findDependencyCycles(stages []types.StageResponse) [][]int {
+	deps := make(map[int][]int, len(stages))
+	for _, st := range stages {
+		deps[st.ID] = st.DependsOn
+	}
+
+	index := map[int]int{}
+	low := map[int]int{}
+	onStack := map[int]bool{}
+	var stack []int
+	var cycles [][]int
+
+	var connect func(id int)
+	connect = func(id int) {
+		index[id] = len(index)
+		low[id] = index[id]
+		stack = append(stack, id)
+		onStack[id] = true
+
+		selfLoop := false
+		for _, dep := range deps[id] {
+			if dep == id {
+				selfLoop = true
+			}
+			if _, seen := index[dep]; !seen {
+				if _, known := deps[dep]; !known {
+					continue
+				}
+				connect(dep)
+				low[id] = min(low[id], low[dep])
+			} else if onStack[dep] {
+				low[id] = min(low[id], index[dep])
+			}
+		}
+		if low[id] != index[id] {
+			return
+		}
+
+		var component []int
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == id {
+				break
+			}
+		}
+		if len(component) > 1 || selfLoop {
+			sort.Ints(component)
+			cycles = append(cycles, component)
+		}
+	}
+
+	for _, st := range stages {
+		if _, seen := index[st.ID]; !seen {
+			connect(st.ID)
+		}
+	}
+	return cycles
+}
@@ -0,0 +1,203 @@
+package store
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"pipelogiq/internal/types"
+)
+
+func graphStage(id int, status string, dependsOn ...int) types.StageResponse {
+	return types.StageResponse{ID: id, Name: string(rune('a' + id - 1)), Status: status, DependsOn: dependsOn}
+}
+
+func TestBuildPipelineGraphDAGFrontier(t *testing.T) {
+	stages := []types.StageResponse{
+		graphStage(1, types.StageStatusCompleted),
+		graphStage(2, types.StageStatusNotStarted, 1),
+		graphStage(3, types.StageStatusRetryScheduled, 1),
+		graphStage(4, types.StageStatusNotStarted, 2, 3),
+	}
+	stages[1].RunInParallelWith = []int{3}
+	stages[2].RunInParallelWith = []int{2}
+	retryAt := time.Now().Add(-time.Second)
+	stages[2].NextRetryAt = &retryAt
+
+	graph := buildPipelineGraph(stages, true, false)
+	if graph.Mode != types.PipelineGraphModeDAG {
+		t.Fatalf("Mode = %q, want %q", graph.Mode, types.PipelineGraphModeDAG)
+	}
+	if want := []int{2, 3}; !reflect.DeepEqual(graph.Frontier, want) {
+		t.Fatalf("Frontier = %v, want %v", graph.Frontier, want)
+	}
+	wantEdges := []types.PipelineGraphEdge{
+		{From: 1, To: 2, Kind: types.PipelineGraphEdgeDependsOn},
+		{From: 2, To: 3, Kind: types.PipelineGraphEdgeRunInParallelWith},
+		{From: 1, To: 3, Kind: types.PipelineGraphEdgeDependsOn},
+		{From: 2, To: 4, Kind: types.PipelineGraphEdgeDependsOn},
+		{From: 3, To: 4, Kind: types.PipelineGraphEdgeDependsOn},
+	}
+	if !reflect.DeepEqual(graph.Edges, wantEdges) {
+		t.Fatalf("Edges = %v, want %v", graph.Edges, wantEdges)
+	}
+	if len(graph.Cycles) != 0 {
+		t.Fatalf("Cycles = %v, want none", graph.Cycles)
+	}
+}
+
+func TestBuildPipelineGraphSequential(t *testing.T) {
+	isEvent := true
+	stages := []types.StageResponse{
+		graphStage(1, types.StageStatusSkipped),
+		graphStage(2, types.StageStatusNotStarted),
+		graphStage(3, types.StageStatusNotStarted),
+		graphStage(4, types.StageStatusNotStarted),
+	}
+	stages[1].IsEvent = &isEvent
+	for i := range stages[:len(stages)-1] {
+		next := stages[i+1].ID
+		stages[i].NextStageID = &next
+	}
+
+	graph := buildPipelineGraph(stages, false, false)
+	if graph.Mode != types.PipelineGraphModeSequential {
+		t.Fatalf("Mode = %q, want %q", graph.Mode, types.PipelineGraphModeSequential)
+	}
+	// Event stage 2 is never dispatched and does not hold back stage 3.
+	if want := []int{3}; !reflect.DeepEqual(graph.Frontier, want) {
+		t.Fatalf("Frontier = %v, want %v", graph.Frontier, want)
+	}
+	if len(graph.Edges) != 3 || graph.Edges[0].Kind != types.PipelineGraphEdgeSequence {
+		t.Fatalf("Edges = %v, want 3 sequence edges", graph.Edges)
+	}
+
+	if graph := buildPipelineGraph(stages, false, true); len(graph.Frontier) != 0 {
+		t.Fatalf("completed pipeline Frontier = %v, want none", graph.Frontier)
+	}
+}
+
+func TestBuildPipelineGraphCycles(t *testing.T) {
+	stages := []types.StageResponse{
+		graphStage(1, types.StageStatusNotStarted),
+		graphStage(2, types.StageStatusNotStarted, 1, 4),
+		graphStage(3, types.StageStatusNotStarted, 2),
+		graphStage(4, types.StageStatusNotStarted, 3),
+		graphStage(5, types.StageStatusNotStarted, 5),
+		graphStage(6, types.StageStatusNotStarted, 4),
+	}
+
+	graph := buildPipelineGraph(stages, true, false)
+	if want := [][]int{{2, 3, 4}, {5}}; !reflect.DeepEqual(graph.Cycles, want) {
+		t.Fatalf("Cycles = %v, want %v", graph.Cycles, want)
+	}
+	if want := []int{1}; !reflect.DeepEqual(graph.Frontier, want) {
+		t.Fatalf("Frontier = %v, want %v", graph.Frontier, want)
+	}
+	for _, node := range graph.Nodes {
+		if want := node.ID >= 2 && node.ID <= 5; node.InCycle != want {
+			t.Errorf("node %d InCycle = %v, want %v", node.ID, node.InCycle, want)
+		}
+	}
+}
+
+func TestStageAdjacencyFollowsScheduler(t *testing.T) {
+	stages := []types.StageResponse{
+		graphStage(1, types.StageStatusCompleted),
+		graphStage(2, types.StageStatusNotStarted, 1),
+		graphStage(3, types.StageStatusNotStarted, 1),
+	}
+	next2, next3 := 2, 3
+	stages[0].NextStageID = &next2
+	stages[1].NextStageID = &next3
+	stages[1].RunInParallelWith = []int{3}
+
+	if got, want := stageAdjacency(buildPipelineGraph(stages, true, false)), map[int][]int{1: {2, 3}, 2: {}, 3: {}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("dag adjacency = %v, want %v", got, want)
+	}
+	// Without the dag_execution flag the scheduler runs the stages in id
+	// order, whatever they declare.
+	if got, want := stageAdjacency(buildPipelineGraph(stages, false, false)), map[int][]int{1: {2}, 2: {3}, 3: {}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("sequential adjacency = %v, want %v", got, want)
+	}
+}
+
+// TestBuildPipelineGraphFrontierFollowsScheduler pins the eligibleStagesQuery
+// rules the frontier mirrors beyond dependencies.
+func TestBuildPipelineGraphFrontierFollowsScheduler(t *testing.T) {
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Hour)
+	stages := []types.StageResponse{
+		graphStage(1, types.StageStatusCompleted),
+		graphStage(2, types.StageStatusRetryScheduled, 1),
+		graphStage(3, types.StageStatusRetryScheduled, 1),
+		graphStage(4, types.StageStatusNotStarted, 1),
+		graphStage(5, types.StageStatusNotStarted, 1),
+	}
+	stages[1].NextRetryAt = &past
+	stages[2].NextRetryAt = &future
+	stages[4].NextRetryAt = &future // deferred by a concurrency limit
+
+	if got, want := buildPipelineGraph(stages, true, false).Frontier, []int{2, 4}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Frontier = %v, want %v", got, want)
+	}
+
+	// A Pending stage holds back the whole pipeline.
+	stages[3].Status = types.StageStatusPending
+	if got := buildPipelineGraph(stages, true, false).Frontier; len(got) != 0 {
+		t.Fatalf("Frontier with a Pending stage = %v, want none", got)
+	}
+}
+
+// TestGetPipelineWithStagesStageGraphFollowsScheduler checks that the
+// stageGraph of a pipeline response only follows dependsOn when the
+// scheduler does: with dag_execution off the stages run in id order, and
+// the graph says so instead of promising parallel branches.
+func TestGetPipelineWithStagesStageGraphFollowsScheduler(t *testing.T) {
+	db := setupPostgresTestDB(t)
+	st := New(db, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	var pipelineID int
+	if err := db.QueryRow(`
+		INSERT INTO pipeline (application_id, name, status) VALUES (10, 'p', $1) RETURNING id
+	`, types.PipelineStatusRunning).Scan(&pipelineID); err != nil {
+		t.Fatalf("insert pipeline: %v", err)
+	}
+	ids := map[string]int{}
+	for _, stage := range []struct{ name, dependsOn string }{{"a", ""}, {"b", "a"}, {"c", "a"}} {
+		var id int
+		if err := db.QueryRow(`
+			INSERT INTO stage (pipeline_id, name, stage_handler_name, status) VALUES ($1, $2, 'h', $3) RETURNING id
+		`, pipelineID, stage.name, types.StageStatusNotStarted).Scan(&id); err != nil {
+			t.Fatalf("insert stage %s: %v", stage.name, err)
+		}
+		if _, err := db.Exec(`INSERT INTO stage_options (stage_id, depends_on) VALUES ($1, NULLIF($2, ''))`, id, stage.dependsOn); err != nil {
+			t.Fatalf("insert stage options %s: %v", stage.name, err)
+		}
+		ids[stage.name] = id
+	}
+	a, b, c := ids["a"], ids["b"], ids["c"]
+
+	pipeline, err := st.GetPipelineWithStages(ctx, pipelineID)
+	if err != nil {
+		t.Fatalf("GetPipelineWithStages() error = %v", err)
+	}
+	if want := map[int][]int{a: {b, c}, b: {}, c: {}}; !reflect.DeepEqual(pipeline.StageGraph, want) {
+		t.Fatalf("dag stageGraph = %v, want %v", pipeline.StageGraph, want)
+	}
+
+	if _, err := db.Exec(`INSERT INTO application_feature_flag (application_id, flag, enabled) VALUES (10, $1, false)`,
+		types.FeatureFlagDAGExecution); err != nil {
+		t.Fatalf("disable dag execution: %v", err)
+	}
+	pipeline, err = st.GetPipelineWithStages(ctx, pipelineID)
+	if err != nil {
+		t.Fatalf("GetPipelineWithStages() error = %v", err)
+	}
+	if want := map[int][]int{a: {b}, b: {c}, c: {}}; !reflect.DeepEqual(pipeline.StageGraph, want) {
+		t.Fatalf("sequential stageGraph = %v, want %v", pipeline.StageGraph, want)
+	}
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var errInvalidKeywordMatch = errors.New("invalid keyword match")
+
+func IsInvalidKeywordMatchError(err error) bool {
+	return errors.Is(err, errInvalidKeywordMatch)
+}
+
+// Keyword match modes of the pipelines listing.
+const (
+	keywordMatchAny = "any"
+	keywordMatchAll = "all"
+)
+
+// pipelineKeywordCondition builds the WHERE condition keeping pipelines that
+// have any (the default) or all of the keyword keys. Placeholders start at
+// argNum; it returns the condition, its args and the next placeholder number.
+// Repeated keys count once, so asking for all of [env, env] needs only env.
+func pipelineKeywordCondition(keys []string, match string, argNum int) (string, []interface{}, int, error) {
+	mode := strings.ToLower(strings.TrimSpace(match))
+	if mode == "" {
+		mode = keywordMatchAny
+	}
+	if mode != keywordMatchAny && mode != keywordMatchAll {
+		return "", nil, argNum, fmt.Errorf("%w: unknown keywordMatch %q (want any or all)", errInvalidKeywordMatch, match)
+	}
+
+	seen := make(map[string]struct{}, len(keys))
+	placeholders := make([]string, 0, len(keys))
+	args := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		placeholders = append(placeholders, fmt.Sprintf("$%d", argNum))
+		args = append(args, key)
+		argNum++
+	}
+	if len(args) == 0 {
+		return "", nil, argNum, nil
+	}
+
+	if mode == keywordMatchAny {
+		return fmt.Sprintf(`
+			EXISTS (
+				SELECT 1 FROM pipeline_keyword pk
+				JOIN keyword k ON k.id = pk.keyword_id
+				WHERE pk.pipeline_id = p.id AND k.key IN (%s)
+			)
+		`, strings.Join(placeholders, ",")), args, argNum, nil
+	}
+
+	// A pipeline can carry the same key several times (with different
+	// values), so count distinct keys.
+	return fmt.Sprintf(`
+			EXISTS (
+				SELECT 1 FROM pipeline_keyword pk
+				JOIN keyword k ON k.id = pk.keyword_id
+				WHERE pk.pipeline_id = p.id AND k.key IN (%s)
+				GROUP BY pk.pipeline_id
+				HAVING COUNT(DISTINCT k.key) = %d
+			)
+		`, strings.Join(placeholders, ","), len(args)), args, argNum, nil
+}
@@ -0,0 +1,128 @@
+package store
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+
+	"pipelogiq/internal/types"
+)
+
+func TestPipelineKeywordCondition(t *testing.T) {
+	tests := []struct {
+		name       string
+		keys       []string
+		match      string
+		wantArgs   []interface{}
+		wantNext   int
+		wantHaving string
+	}{
+		{"none", nil, "all", nil, 3, ""},
+		{"any by default", []string{"env", "team"}, "", []interface{}{"env", "team"}, 5, ""},
+		{"all", []string{"env", "team"}, "ALL", []interface{}{"env", "team"}, 5, "HAVING COUNT(DISTINCT k.key) = 2"},
+		{"all with repeated keys", []string{"env", "team", "env"}, "all", []interface{}{"env", "team"}, 5, "HAVING COUNT(DISTINCT k.key) = 2"},
+		{"any with repeated keys", []string{"env", "env"}, "any", []interface{}{"env"}, 4, ""},
+	}
+	for _, tt := range tests {
+		cond, args, next, err := pipelineKeywordCondition(tt.keys, tt.match, 3)
+		if err != nil {
+			t.Fatalf("%s: error = %v", tt.name, err)
+		}
+		if len(tt.wantArgs) == 0 {
+			if cond != "" || len(args) != 0 {
+				t.Errorf("%s: got condition %q args %v, want none", tt.name, cond, args)
+			}
+		} else if !reflect.DeepEqual(args, tt.wantArgs) {
+			t.Errorf("%s: args = %v, want %v", tt.name, args, tt.wantArgs)
+		}
+		if next != tt.wantNext {
+			t.Errorf("%s: next placeholder = %d, want %d", tt.name, next, tt.wantNext)
+		}
+		if tt.wantHaving != "" && !strings.Contains(cond, tt.wantHaving) {
+			t.Errorf("%s: condition %q does not contain %q", tt.name, cond, tt.wantHaving)
+		}
+		if tt.wantHaving == "" && strings.Contains(cond, "HAVING") {
+			t.Errorf("%s: condition %q has a HAVING clause", tt.name, cond)
+		}
+		if len(tt.wantArgs) > 0 && !strings.Contains(cond, "k.key IN ($3") {
+			t.Errorf("%s: condition %q does not start placeholders at $3", tt.name, cond)
+		}
+	}
+
+	if _, _, _, err := pipelineKeywordCondition([]string{"env"}, "some", 1); !IsInvalidKeywordMatchError(err) {
+		t.Fatalf("keywordMatch=some error = %v, want invalid keyword match", err)
+	}
+}
+
+func TestGetPipelinesKeywordMatchAll(t *testing.T) {
+	db := setupPostgresTestDB(t)
+	ctx := context.Background()
+	st := New(db, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if _, err := db.Exec(`INSERT INTO user_application (user_id, application_id) VALUES (1, 1)`); err != nil {
+		t.Fatalf("insert user application: %v", err)
+	}
+	insertPipeline := func(name string, keywords ...[2]string) int {
+		t.Helper()
+		var id int
+		if err := db.QueryRow(`INSERT INTO pipeline (application_id, name, status) VALUES (1, $1, 'Running') RETURNING id`, name).Scan(&id); err != nil {
+			t.Fatalf("insert pipeline: %v", err)
+		}
+		for _, kw := range keywords {
+			var keywordID int
+			if err := db.QueryRow(`INSERT INTO keyword (key, value) VALUES ($1, $2) RETURNING id`, kw[0], kw[1]).Scan(&keywordID); err != nil {
+				t.Fatalf("insert keyword: %v", err)
+			}
+			if _, err := db.Exec(`INSERT INTO pipeline_keyword (pipeline_id, keyword_id) VALUES ($1, $2)`, id, keywordID); err != nil {
+				t.Fatalf("insert pipeline keyword: %v", err)
+			}
+		}
+		return id
+	}
+	// "overlapping" carries env three times, which must not stand in for the
+	// missing team key.
+	overlapping := insertPipeline("overlapping", [2]string{"env", "prod"}, [2]string{"env", "staging"}, [2]string{"env", "dev"})
+	both := insertPipeline("both", [2]string{"env", "prod"}, [2]string{"env", "staging"}, [2]string{"team", "payments"})
+	insertPipeline("team only", [2]string{"team", "payments"})
+
+	list := func(match string, keys ...string) []int {
+		t.Helper()
+		result, err := st.GetPipelines(ctx, 1, types.GetPipelinesRequest{Keywords: keys, KeywordMatch: match})
+		if err != nil {
+			t.Fatalf("GetPipelines(%s %v) error = %v", match, keys, err)
+		}
+		if result.TotalCount != len(result.Items) {
+			t.Fatalf("GetPipelines(%s %v) totalCount = %d, want %d", match, keys, result.TotalCount, len(result.Items))
+		}
+		ids := []int{}
+		for _, p := range result.Items {
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+
+	if got := list("all", "env", "team"); !reflect.DeepEqual(got, []int{both}) {
+		t.Errorf("all of env, team = %v, want [%d]", got, both)
+	}
+	if got := list("all", "env"); len(got) != 2 || !containsInt(got, overlapping) || !containsInt(got, both) {
+		t.Errorf("all of env = %v, want %d and %d", got, overlapping, both)
+	}
+	if got := list("all", "env", "env"); len(got) != 2 {
+		t.Errorf("all of env, env = %v, want 2 pipelines", got)
+	}
+	if got := list("any", "env", "team"); len(got) != 3 {
+		t.Errorf("any of env, team = %v, want 3 pipelines", got)
+	}
+}
+
+func containsInt(values []int, want int) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var errInvalidPipelineLabel = errors.New("invalid pipeline label")
+
+func IsInvalidPipelineLabelError(err error) bool {
+	return errors.Is(err, errInvalidPipelineLabel)
+}
+
+// Limits on a pipeline's labels, which are meant to be a few short tags.
+const (
+	maxPipelineLabels     = 32
+	maxPipelineLabelKey   = 63
+	maxPipelineLabelValue = 255
+)
+
+// encodePipelineLabels normalizes labels and returns them as the JSON object
+// stored in pipeline.labels; no labels encode as {}.
+func encodePipelineLabels(labels map[string]string) (string, error) {
+	normalized, err := normalizePipelineLabels(labels)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := json.Marshal(normalized)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// normalizePipelineLabels trims label keys and values and checks them against
+// the limits.
+func normalizePipelineLabels(labels map[string]string) (map[string]string, error) {
+	if len(labels) > maxPipelineLabels {
+		return nil, fmt.Errorf("%w: at most %d labels are allowed", errInvalidPipelineLabel, maxPipelineLabels)
+	}
+	normalized := make(map[string]string, len(labels))
+	for key, value := range labels {
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if key == "" {
+			return nil, fmt.Errorf("%w: label key is required", errInvalidPipelineLabel)
+		}
+		if len(key) > maxPipelineLabelKey {
+			return nil, fmt.Errorf("%w: label key %q is longer than %d bytes", errInvalidPipelineLabel, key, maxPipelineLabelKey)
+		}
+		if len(value) > maxPipelineLabelValue {
+			return nil, fmt.Errorf("%w: label %q value is longer than %d bytes", errInvalidPipelineLabel, key, maxPipelineLabelValue)
+		}
+		if _, dup := normalized[key]; dup {
+			return nil, fmt.Errorf("%w: label key %q is repeated", errInvalidPipelineLabel, key)
+		}
+		normalized[key] = value
+	}
+	return normalized, nil
+}
+
+// pipelineLabelCondition builds the WHERE condition keeping pipelines that
+// carry every filter, each written "key=value". It uses JSONB containment so
+// the GIN index on pipeline.labels serves it. Placeholders start at argNum;
+// it returns the condition, its args and the next placeholder number.
+func pipelineLabelCondition(filters []string, argNum int) (string, []interface{}, int, error) {
+	if len(filters) == 0 {
+		return "", nil, argNum, nil
+	}
+	want := make(map[string]string, len(filters))
+	for _, filter := range filters {
+		key, value, ok := strings.Cut(filter, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return "", nil, argNum, fmt.Errorf("%w: label filter %q must be key=value", errInvalidPipelineLabel, filter)
+		}
+		value = strings.TrimSpace(value)
+		if existing, dup := want[key]; dup && existing != value {
+			return "", nil, argNum, fmt.Errorf("%w: label %q is filtered by two values", errInvalidPipelineLabel, key)
+		}
+		want[key] = value
+	}
+	encoded, err := json.Marshal(want)
+	if err != nil {
+		return "", nil, argNum, err
+	}
+	return fmt.Sprintf("p.labels @> $%d::jsonb", argNum), []interface{}{string(encoded)}, argNum + 1, nil
+}
+
+// decodePipelineLabels reads the labels column, treating NULL or unreadable
+// JSON as no labels.
+func decodePipelineLabels(raw []byte) map[string]string {
+	if len(raw) == 0 {
+		return nil
+	}
+	var labels map[string]string
+	if err := json.Unmarshal(raw, &labels); err != nil || len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
@@ -0,0 +1,60 @@
+package store
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncodePipelineLabels(t *testing.T) {
+	got, err := encodePipelineLabels(map[string]string{" env ": " prod ", "team": "payments"})
+	if err != nil {
+		t.Fatalf("encodePipelineLabels() error = %v", err)
+	}
+	if want := `{"env":"prod","team":"payments"}`; got != want {
+		t.Errorf("encodePipelineLabels() = %s, want %s", got, want)
+	}
+
+	if got, err := encodePipelineLabels(nil); err != nil || got != "{}" {
+		t.Errorf("encodePipelineLabels(nil) = %q, %v, want {}", got, err)
+	}
+
+	invalid := map[string]map[string]string{
+		"empty key":      {" ": "x"},
+		"long key":       {strings.Repeat("k", maxPipelineLabelKey+1): "x"},
+		"long value":     {"env": strings.Repeat("v", maxPipelineLabelValue+1)},
+		"key after trim": {"env": "a", " env": "b"},
+	}
+	for name, labels := range invalid {
+		if _, err := encodePipelineLabels(labels); !IsInvalidPipelineLabelError(err) {
+			t.Errorf("%s: error = %v, want invalid pipeline label", name, err)
+		}
+	}
+}
+
+func TestPipelineLabelCondition(t *testing.T) {
+	cond, args, next, err := pipelineLabelCondition(nil, 3)
+	if err != nil || cond != "" || len(args) != 0 || next != 3 {
+		t.Errorf("no filters: got %q %v %d %v, want nothing", cond, args, next, err)
+	}
+
+	cond, args, next, err = pipelineLabelCondition([]string{"env=prod", " team = payments", "env=prod"}, 3)
+	if err != nil {
+		t.Fatalf("pipelineLabelCondition() error = %v", err)
+	}
+	if cond != "p.labels @> $3::jsonb" {
+		t.Errorf("condition = %q", cond)
+	}
+	if want := []interface{}{`{"env":"prod","team":"payments"}`}; !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %v, want %v", args, want)
+	}
+	if next != 4 {
+		t.Errorf("next placeholder = %d, want 4", next)
+	}
+
+	for _, filters := range [][]string{{"env"}, {"=prod"}, {"env=prod", "env=dev"}} {
+		if _, _, _, err := pipelineLabelCondition(filters, 1); !IsInvalidPipelineLabelError(err) {
+			t.Errorf("%v: error = %v, want invalid pipeline label", filters, err)
+		}
+	}
+}
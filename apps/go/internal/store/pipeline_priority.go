@@ -0,0 +1,31 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+)
+
+var errInvalidPipelinePriority = errors.New("invalid pipeline priority")
+
+func IsInvalidPipelinePriorityError(err error) bool {
+	return errors.Is(err, errInvalidPipelinePriority)
+}
+
+// Pipeline priorities. The publisher claims stages of higher-priority
+// pipelines first; pipelines of equal priority go oldest first.
+const (
+	minPipelinePriority = 0
+	maxPipelinePriority = 9
+)
+
+// validatePipelinePriority checks a requested priority; nil means the default, 0.
+func validatePipelinePriority(priority *int) (int, error) {
+	if priority == nil {
+		return minPipelinePriority, nil
+	}
+	if *priority < minPipelinePriority || *priority > maxPipelinePriority {
+		return 0, fmt.Errorf("%w: priority must be between %d and %d, got %d",
+			errInvalidPipelinePriority, minPipelinePriority, maxPipelinePriority, *priority)
+	}
+	return *priority, nil
+}
@@ -0,0 +1,19 @@
+package store
+
+import "testing"
+
+func TestValidatePipelinePriority(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+
+	if got, err := validatePipelinePriority(nil); err != nil || got != 0 {
+		t.Errorf("validatePipelinePriority(nil) = %d, %v, want 0", got, err)
+	}
+	if got, err := validatePipelinePriority(intPtr(9)); err != nil || got != 9 {
+		t.Errorf("validatePipelinePriority(9) = %d, %v, want 9", got, err)
+	}
+	for _, priority := range []int{-1, 10} {
+		if _, err := validatePipelinePriority(intPtr(priority)); !IsInvalidPipelinePriorityError(err) {
+			t.Errorf("validatePipelinePriority(%d) error = %v, want invalid pipeline priority", priority, err)
+		}
+	}
+}
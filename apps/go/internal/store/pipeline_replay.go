@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"pipelogiq/internal/types"
+)
+
+var errPipelineStillRunning = errors.New("pipeline has not finished")
+
+func IsPipelineStillRunningError(err error) bool {
+	return errors.Is(err, errPipelineStillRunning)
+}
+
+// ClonePipeline starts a new run of a finished pipeline: its stages (with
+// their inputs and options), keywords, labels and priority are inserted as a
+// brand-new NotStarted pipeline with a fresh trace id, leaving the original
+// run and its history untouched. The context items the original run ended
+// with are copied only when copyContext is set, minus any traceparent, which
+// belongs to the old trace.
+func (s *Store) ClonePipeline(ctx context.Context, pipelineID int, copyContext bool) (*types.PipelineResponse, error) {
+	var source struct {
+		Name          string        `db:"name"`
+		ApplicationID sql.NullInt64 `db:"application_id"`
+		IsCompleted   bool          `db:"is_completed"`
+		Labels        []byte        `db:"labels"`
+		Priority      int           `db:"priority"`
+	}
+	if err := s.db.GetContext(ctx, &source, `
+		SELECT name, application_id, is_completed, labels, priority FROM pipeline WHERE id = $1
+	`, pipelineID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errPipelineNotFound
+		}
+		return nil, err
+	}
+	if !source.IsCompleted {
+		return nil, errPipelineStillRunning
+	}
+	if !source.ApplicationID.Valid {
+		return nil, fmt.Errorf("pipeline %d has no application", pipelineID)
+	}
+
+	stages, err := s.loadStageDefinitions(ctx, pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	keywords := []types.PipelineKeyword{}
+	if err := s.db.SelectContext(ctx, &keywords, `
+		SELECT k.key, k.value
+		FROM pipeline_keyword pk
+		JOIN keyword k ON k.id = pk.keyword_id
+		WHERE pk.pipeline_id = $1
+		ORDER BY pk.keyword_id
+	`, pipelineID); err != nil {
+		return nil, fmt.Errorf("load keywords: %w", err)
+	}
+
+	var contextItems []types.ContextItem
+	if copyContext {
+		items, err := s.GetPipelineContext(ctx, pipelineID)
+		if err != nil {
+			return nil, fmt.Errorf("load context: %w", err)
+		}
+		for _, item := range items {
+			if !strings.EqualFold(item.Key, "traceparent") {
+				contextItems = append(contextItems, item)
+			}
+		}
+	}
+
+	priority := source.Priority
+	return s.CreatePipeline(ctx, types.PipelineCreateRequest{
+		Name:             source.Name,
+		TraceID:          resolveTraceID("", nil),
+		Stages:           stages,
+		PipelineKeywords: keywords,
+		PipelineContext:  contextItems,
+		Labels:           decodePipelineLabels(source.Labels),
+		Priority:         &priority,
+	}, int(source.ApplicationID.Int64))
+}
+
+// loadStageDefinitions rebuilds the create request of each stage of the
+// pipeline, in creation order, from its row, input and latest options.
+func (s *Store) loadStageDefinitions(ctx context.Context, pipelineID int) ([]types.StageCreate, error) {
+	var rows []struct {
+		Name              string         `db:"name"`
+		Handler           string         `db:"stage_handler_name"`
+		Description       sql.NullString `db:"description"`
+		IsEvent           bool           `db:"is_event"`
+		Input             sql.NullString `db:"input"`
+		HasOptions        bool           `db:"has_options"`
+		RunNextIfFailed   *bool          `db:"run_next_if_failed"`
+		RetryInterval     *int           `db:"retry_interval"`
+		TimeOut           *int           `db:"time_out"`
+		MaxRetries        *int           `db:"max_retries"`
+		MaxAttempts       *int           `db:"max_attempts"`
+		DependsOn         sql.NullString `db:"depends_on"`
+		RunInParallelWith sql.NullString `db:"run_in_parallel_with"`
+		FailIfOutputEmpty *bool          `db:"fail_if_output_empty"`
+		NotifyOnFailure   *bool          `db:"notify_on_failure"`
+		RunAsUser         *string        `db:"run_as_user"`
+		OnTimeout         *string        `db:"on_timeout"`
+		ExpectedDuration  *int           `db:"expected_duration_ms"`
+	}
+	if err := s.db.SelectContext(ctx, &rows, `
+		SELECT s.name, s.stage_handler_name, s.description, COALESCE(s.is_event, false) AS is_event, io.input,
+			so.stage_id IS NOT NULL AS has_options,
+			so.run_next_if_failed, so.retry_interval, so.time_out, so.max_retries, so.depends_on,
+			so.run_in_parallel_with, so.fail_if_output_empty, so.notify_on_failure, so.run_as_user,
+			so.max_attempts, so.on_timeout, so.expected_duration_ms
+		FROM stage s
+		LEFT JOIN stage_io io ON io.stage_id = s.id
+		LEFT JOIN LATERAL (
+			SELECT * FROM stage_options WHERE stage_id = s.id ORDER BY id DESC LIMIT 1
+		) so ON true
+		WHERE s.pipeline_id = $1
+		ORDER BY s.id
+	`, pipelineID); err != nil {
+		return nil, fmt.Errorf("load stages: %w", err)
+	}
+
+	stages := make([]types.StageCreate, 0, len(rows))
+	for _, row := range rows {
+		stage := types.StageCreate{
+			Name:         row.Name,
+			StageHandler: row.Handler,
+			Description:  row.Description.String,
+			Input:        row.Input.String,
+			IsEvent:      row.IsEvent,
+		}
+		if row.HasOptions {
+			stage.Options = &types.StageOptions{
+				RunNextIfFailed:    row.RunNextIfFailed,
+				RetryInterval:      row.RetryInterval,
+				TimeOut:            row.TimeOut,
+				MaxRetries:         row.MaxRetries,
+				MaxAttempts:        row.MaxAttempts,
+				DependsOn:          splitList(row.DependsOn.String),
+				RunInParallelWith:  splitList(row.RunInParallelWith.String),
+				FailIfOutputEmpty:  row.FailIfOutputEmpty,
+				NotifyOnFailure:    row.NotifyOnFailure,
+				RunAsUser:          row.RunAsUser,
+				OnTimeout:          row.OnTimeout,
+				ExpectedDurationMs: row.ExpectedDuration,
+			}
+		}
+		stages = append(stages, stage)
+	}
+	return stages, nil
+}
@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"pipelogiq/internal/types"
+)
+
+func TestClonePipeline(t *testing.T) {
+	db := setupPostgresTestDB(t)
+	st := New(db, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	maxRetries := 2
+	priority := 3
+	source, err := st.CreatePipeline(ctx, types.PipelineCreateRequest{
+		Name: "import",
+		Stages: []types.StageCreate{
+			{Name: "fetch", StageHandler: "fetch", Input: `{"url":"u"}`},
+			{Name: "store", StageHandler: "store", Description: "write rows", Options: &types.StageOptions{
+				MaxRetries: &maxRetries,
+				DependsOn:  []string{"fetch"},
+			}},
+		},
+		PipelineKeywords: []types.PipelineKeyword{{Key: "customer", Value: "acme"}},
+		PipelineContext: []types.ContextItem{
+			{Key: "tenant", Value: "acme"},
+			{Key: "traceparent", Value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		},
+		Labels:   map[string]string{"team": "data"},
+		Priority: &priority,
+	}, 10)
+	if err != nil {
+		t.Fatalf("CreatePipeline() error = %v", err)
+	}
+
+	if _, err := st.ClonePipeline(ctx, source.ID, false); !IsPipelineStillRunningError(err) {
+		t.Fatalf("ClonePipeline(unfinished) error = %v, want still running", err)
+	}
+	if _, err := st.ClonePipeline(ctx, source.ID+100, false); !IsPipelineNotFoundError(err) {
+		t.Fatalf("ClonePipeline(missing) error = %v, want not found", err)
+	}
+	if _, err := db.Exec(`
+		UPDATE pipeline SET is_completed = true, status = $2 WHERE id = $1;
+	`, source.ID, types.PipelineStatusFailed); err != nil {
+		t.Fatalf("finish pipeline: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE stage SET status = $2 WHERE pipeline_id = $1`, source.ID, types.StageStatusFailed); err != nil {
+		t.Fatalf("fail stages: %v", err)
+	}
+
+	wantStages, err := st.loadStageDefinitions(ctx, source.ID)
+	if err != nil {
+		t.Fatalf("loadStageDefinitions() error = %v", err)
+	}
+	if len(wantStages) != 2 || wantStages[0].Input != `{"url":"u"}` || wantStages[0].Options != nil ||
+		wantStages[1].Description != "write rows" || wantStages[1].Options == nil ||
+		*wantStages[1].Options.MaxRetries != 2 || !reflect.DeepEqual(wantStages[1].Options.DependsOn, []string{"fetch"}) {
+		t.Fatalf("loadStageDefinitions() = %+v, want the created stages", wantStages)
+	}
+
+	for _, copyContext := range []bool{false, true} {
+		clone, err := st.ClonePipeline(ctx, source.ID, copyContext)
+		if err != nil {
+			t.Fatalf("ClonePipeline(copyContext=%v) error = %v", copyContext, err)
+		}
+		if clone.ID == source.ID || clone.TraceID == "" || clone.TraceID == source.TraceID {
+			t.Errorf("clone %d trace %q, want a new pipeline with a fresh trace", clone.ID, clone.TraceID)
+		}
+		if clone.Status != types.PipelineStatusNotStarted || *clone.ApplicationID != 10 ||
+			clone.Priority != 3 || clone.Labels["team"] != "data" {
+			t.Errorf("clone = %+v, want a NotStarted copy in application 10 with the labels and priority", clone)
+		}
+		gotStages, err := st.loadStageDefinitions(ctx, clone.ID)
+		if err != nil {
+			t.Fatalf("loadStageDefinitions(clone) error = %v", err)
+		}
+		if !reflect.DeepEqual(gotStages, wantStages) {
+			t.Errorf("clone stages = %+v, want %+v", gotStages, wantStages)
+		}
+		keywords, err := st.GetPipelineKeywords(ctx, clone.ID)
+		if err != nil || len(keywords) != 1 || keywords[0].Key != "customer" {
+			t.Errorf("clone keywords = %+v (%v), want customer", keywords, err)
+		}
+
+		var wantContext []string
+		if copyContext {
+			// The traceparent belongs to the original run's trace.
+			wantContext = []string{"tenant=acme"}
+		}
+		var gotContext []string
+		for _, item := range clone.PipelineContext {
+			gotContext = append(gotContext, item.Key+"="+item.Value)
+		}
+		if !reflect.DeepEqual(gotContext, wantContext) {
+			t.Errorf("clone context (copyContext=%v) = %v, want %v", copyContext, gotContext, wantContext)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var errInvalidPipelineSort = errors.New("invalid pipeline sort")
+
+func IsInvalidPipelineSortError(err error) bool {
+	return errors.Is(err, errInvalidPipelineSort)
+}
+
+// pipelineSortColumns whitelists the sortBy values of the pipelines listing
+// and the SQL expression each one orders by. Running pipelines have no
+// finished_at: their duration runs up to now, and a finishedAt sort puts them
+// last in either direction.
+var pipelineSortColumns = map[string]string{
+	"createdat":  "p.created_at",
+	"finishedat": "p.finished_at",
+	"duration":   "COALESCE(p.finished_at, NOW()) - p.created_at",
+	"status":     "COALESCE(p.status, 'NotStarted')",
+}
+
+// pipelineOrderBy builds the ORDER BY clause for a sortBy/sortDir pair. Empty
+// values fall back to created_at DESC. Ties are broken by id in the same
+// direction so paging stays stable.
+func pipelineOrderBy(sortBy, sortDir *string) (string, error) {
+	column := pipelineSortColumns["createdat"]
+	if sortBy != nil && strings.TrimSpace(*sortBy) != "" {
+		c, ok := pipelineSortColumns[strings.ToLower(strings.TrimSpace(*sortBy))]
+		if !ok {
+			return "", fmt.Errorf("%w: unknown sortBy %q (want createdAt, finishedAt, duration or status)",
+				errInvalidPipelineSort, *sortBy)
+		}
+		column = c
+	}
+
+	dir := "DESC"
+	if sortDir != nil && strings.TrimSpace(*sortDir) != "" {
+		switch strings.ToLower(strings.TrimSpace(*sortDir)) {
+		case "asc":
+			dir = "ASC"
+		case "desc":
+			dir = "DESC"
+		default:
+			return "", fmt.Errorf("%w: unknown sortDir %q (want asc or desc)", errInvalidPipelineSort, *sortDir)
+		}
+	}
+
+	return fmt.Sprintf("%s %s NULLS LAST, p.id %s", column, dir, dir), nil
+}
@@ -0,0 +1,38 @@
+package store
+
+import "testing"
+
+func TestPipelineOrderBy(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name    string
+		sortBy  *string
+		sortDir *string
+		want    string
+	}{
+		{"default", nil, nil, "p.created_at DESC NULLS LAST, p.id DESC"},
+		{"empty", str(""), str(""), "p.created_at DESC NULLS LAST, p.id DESC"},
+		{"finished asc", str("finishedAt"), str("asc"), "p.finished_at ASC NULLS LAST, p.id ASC"},
+		{"duration", str("DURATION"), str("Desc"), "COALESCE(p.finished_at, NOW()) - p.created_at DESC NULLS LAST, p.id DESC"},
+		{"status", str("status"), nil, "COALESCE(p.status, 'NotStarted') DESC NULLS LAST, p.id DESC"},
+	}
+	for _, tt := range tests {
+		got, err := pipelineOrderBy(tt.sortBy, tt.sortDir)
+		if err != nil {
+			t.Fatalf("%s: pipelineOrderBy() error = %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: pipelineOrderBy() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	for _, bad := range []struct{ sortBy, sortDir *string }{
+		{str("name; DROP TABLE pipeline"), nil},
+		{str("createdAt"), str("sideways")},
+	} {
+		if _, err := pipelineOrderBy(bad.sortBy, bad.sortDir); !IsInvalidPipelineSortError(err) {
+			t.Errorf("pipelineOrderBy(%v, %v) error = %v, want invalid sort", bad.sortBy, bad.sortDir, err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"pipelogiq/internal/types"
+)
+
+// pipelineStatsBuckets is how many throughput points a stats range is split
+// into.
+const pipelineStatsBuckets = 24
+
+// GetPipelineStats counts the application's pipelines created within the last
+// rangeDuration by status, with the success rate of finished pipelines, their
+// average duration and the number started per bucket of the range.
+func (s *Store) GetPipelineStats(ctx context.Context, appID int, rangeDuration time.Duration) (*types.PipelineStatsResponse, error) {
+	since := time.Now().UTC().Add(-rangeDuration)
+	width := pipelineStatsBucketWidth(rangeDuration)
+
+	stats := &types.PipelineStatsResponse{
+		ApplicationID:  appID,
+		Since:          since,
+		CountsByStatus: map[string]int{},
+		BucketSeconds:  int(width / time.Second),
+	}
+
+	var statusCounts []struct {
+		Status string `db:"status"`
+		Count  int    `db:"count"`
+	}
+	if err := s.db.SelectContext(ctx, &statusCounts, `
+		SELECT COALESCE(status, $3) AS status, COUNT(*) AS count
+		FROM pipeline
+		WHERE application_id = $1 AND created_at >= $2
+		GROUP BY 1
+	`, appID, since, types.PipelineStatusNotStarted); err != nil {
+		return nil, fmt.Errorf("count pipelines by status: %w", err)
+	}
+	for _, c := range statusCounts {
+		stats.CountsByStatus[c.Status] = c.Count
+		stats.Total += c.Count
+	}
+
+	completed := stats.CountsByStatus[types.PipelineStatusCompleted]
+	if finished := completed + stats.CountsByStatus[types.PipelineStatusFailed]; finished > 0 {
+		rate := float64(completed) / float64(finished)
+		stats.SuccessRate = &rate
+	}
+
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT AVG(EXTRACT(EPOCH FROM (finished_at - created_at))::float8 * 1000)
+		FROM pipeline
+		WHERE application_id = $1 AND created_at >= $2 AND finished_at IS NOT NULL
+	`, appID, since).Scan(&stats.AvgDurationMs); err != nil {
+		return nil, fmt.Errorf("average pipeline duration: %w", err)
+	}
+
+	var bucketCounts []struct {
+		Bucket int `db:"bucket"`
+		Count  int `db:"count"`
+	}
+	if err := s.db.SelectContext(ctx, &bucketCounts, `
+		SELECT FLOOR(EXTRACT(EPOCH FROM (created_at - $2))::float8 / $3::float8)::int AS bucket, COUNT(*) AS count
+		FROM pipeline
+		WHERE application_id = $1 AND created_at >= $2
+		GROUP BY 1
+	`, appID, since, width.Seconds()); err != nil {
+		return nil, fmt.Errorf("count pipeline executions: %w", err)
+	}
+	counts := make(map[int]int, len(bucketCounts))
+	for _, c := range bucketCounts {
+		counts[c.Bucket] = c.Count
+	}
+	stats.Executions = pipelineStatsPoints(since, rangeDuration, width, counts)
+
+	return stats, nil
+}
+
+// pipelineStatsBucketWidth splits a range into pipelineStatsBuckets whole
+// seconds, never narrower than a minute.
+func pipelineStatsBucketWidth(rangeDuration time.Duration) time.Duration {
+	width := (rangeDuration / pipelineStatsBuckets).Truncate(time.Second)
+	if width < time.Minute {
+		width = time.Minute
+	}
+	return width
+}
+
+// pipelineStatsPoints lays the per-bucket counts out over the whole range,
+// filling empty buckets with zero. Rows created in the instant after the range
+// was computed land in the last bucket.
+func pipelineStatsPoints(since time.Time, rangeDuration, width time.Duration, counts map[int]int) []types.PipelineStatsPoint {
+	n := int((rangeDuration + width - 1) / width)
+	if n < 1 {
+		n = 1
+	}
+	points := make([]types.PipelineStatsPoint, n)
+	for i := range points {
+		points[i].Start = since.Add(time.Duration(i) * width)
+	}
+	for bucket, count := range counts {
+		switch {
+		case bucket < 0:
+			continue
+		case bucket >= n:
+			bucket = n - 1
+		}
+		points[bucket].Count += count
+	}
+	return points
+}
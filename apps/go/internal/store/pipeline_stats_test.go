@@ -0,0 +1,35 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPipelineStatsBucketWidth(t *testing.T) {
+	tests := map[time.Duration]time.Duration{
+		15 * time.Minute:   time.Minute,
+		time.Hour:          150 * time.Second,
+		24 * time.Hour:     time.Hour,
+		7 * 24 * time.Hour: 7 * time.Hour,
+	}
+	for rangeDuration, want := range tests {
+		if got := pipelineStatsBucketWidth(rangeDuration); got != want {
+			t.Errorf("pipelineStatsBucketWidth(%s) = %s, want %s", rangeDuration, got, want)
+		}
+	}
+}
+
+func TestPipelineStatsPoints(t *testing.T) {
+	since := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	points := pipelineStatsPoints(since, 15*time.Minute, time.Minute, map[int]int{0: 2, 14: 1, 15: 3, -1: 9})
+	if len(points) != 15 {
+		t.Fatalf("len(points) = %d, want 15", len(points))
+	}
+	if points[0].Count != 2 || points[1].Count != 0 || points[14].Count != 4 {
+		t.Fatalf("counts = %d, %d, %d; want 2, 0, 4", points[0].Count, points[1].Count, points[14].Count)
+	}
+	if want := since.Add(14 * time.Minute); !points[14].Start.Equal(want) {
+		t.Fatalf("points[14].Start = %s, want %s", points[14].Start, want)
+	}
+}
@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"pipelogiq/internal/types"
+)
+
+// maxTimelineTransitions bounds the stage transitions GetPipelineTimeline
+// returns, oldest first.
+const maxTimelineTransitions = 5000
+
+// pipelineCreatedSource is the source of the first timeline event.
+const pipelineCreatedSource = "create_pipeline"
+
+type stageTransition struct {
+	StageID    int            `db:"stage_id"`
+	FromStatus sql.NullString `db:"from_status"`
+	ToStatus   string         `db:"to_status"`
+	Source     string         `db:"source"`
+	CreatedAt  time.Time      `db:"created_at"`
+}
+
+// GetPipelineTimeline returns the stage status changes LogStageChange
+// recorded for a pipeline, with the pipeline status changes they caused,
+// oldest first. Pipeline changes are derived from the stage statuses, so
+// changes made before stage transitions were recorded are missing.
+func (s *Store) GetPipelineTimeline(ctx context.Context, pipelineID int) (*types.PipelineTimelineResponse, error) {
+	var createdAt time.Time
+	if err := s.db.GetContext(ctx, &createdAt, `SELECT created_at FROM pipeline WHERE id=$1`, pipelineID); err != nil {
+		return nil, err
+	}
+
+	var stages []struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+	if err := s.db.SelectContext(ctx, &stages, `
+		SELECT id, COALESCE(name, '') AS name FROM stage WHERE pipeline_id=$1 ORDER BY id
+	`, pipelineID); err != nil {
+		return nil, err
+	}
+
+	transitions := []stageTransition{}
+	if err := s.db.SelectContext(ctx, &transitions, `
+		SELECT stage_id, from_status, to_status, source, created_at
+		FROM stage_transition
+		WHERE pipeline_id=$1
+		ORDER BY created_at, id
+		LIMIT $2
+	`, pipelineID, maxTimelineTransitions+1); err != nil {
+		return nil, err
+	}
+	truncated := len(transitions) > maxTimelineTransitions
+	if truncated {
+		transitions = transitions[:maxTimelineTransitions]
+	}
+
+	names := make(map[int]string, len(stages))
+	ids := make([]int, 0, len(stages))
+	for _, st := range stages {
+		names[st.ID] = st.Name
+		ids = append(ids, st.ID)
+	}
+	return &types.PipelineTimelineResponse{
+		PipelineID: pipelineID,
+		Events:     buildPipelineTimeline(createdAt, ids, names, transitions),
+		Truncated:  truncated,
+	}, nil
+}
+
+// buildPipelineTimeline turns stage transitions into timeline events. Stages
+// start NotStarted; after each transition the pipeline status is derived
+// from every stage's status by timelinePipelineStatus and an event added when
+// it changed. Transitions of stages not in stageIDs are skipped.
+func buildPipelineTimeline(createdAt time.Time, stageIDs []int, names map[int]string, transitions []stageTransition) []types.PipelineTimelineEvent {
+	index := make(map[int]int, len(stageIDs))
+	states := make([]string, len(stageIDs))
+	for i, id := range stageIDs {
+		index[id] = i
+		states[i] = types.StageStatusNotStarted
+	}
+
+	status := timelinePipelineStatus(states)
+	events := []types.PipelineTimelineEvent{{
+		Kind:     types.TimelineEventPipeline,
+		ToStatus: status,
+		Source:   pipelineCreatedSource,
+		At:       createdAt,
+	}}
+	for _, t := range transitions {
+		i, ok := index[t.StageID]
+		if !ok {
+			continue
+		}
+		stageID := t.StageID
+		events = append(events, types.PipelineTimelineEvent{
+			Kind:       types.TimelineEventStage,
+			StageID:    &stageID,
+			StageName:  names[stageID],
+			FromStatus: t.FromStatus.String,
+			ToStatus:   t.ToStatus,
+			Source:     t.Source,
+			At:         t.CreatedAt,
+		})
+
+		states[i] = t.ToStatus
+		if next := timelinePipelineStatus(states); next != status {
+			events = append(events, types.PipelineTimelineEvent{
+				Kind:       types.TimelineEventPipeline,
+				StageID:    &stageID,
+				StageName:  names[stageID],
+				FromStatus: status,
+				ToStatus:   next,
+				Source:     t.Source,
+				At:         t.CreatedAt,
+			})
+			status = next
+		}
+	}
+	return events
+}
+
+// timelinePipelineStatus is computePipelineStatus, except that a pipeline
+// part way through, with some stages finished and the rest not started,
+// stays Running instead of reading as NotStarted between stages.
+func timelinePipelineStatus(states []string) string {
+	status := computePipelineStatus(states)
+	if status != types.PipelineStatusNotStarted {
+		return status
+	}
+	for _, state := range states {
+		if state != types.StageStatusNotStarted {
+			return types.PipelineStatusRunning
+		}
+	}
+	return status
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"pipelogiq/internal/types"
+)
+
+func TestBuildPipelineTimeline(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	at := func(sec int) time.Time { return created.Add(time.Duration(sec) * time.Second) }
+	from := func(status string) sql.NullString { return sql.NullString{String: status, Valid: true} }
+	transitions := []stageTransition{
+		{StageID: 1, FromStatus: from(types.StageStatusNotStarted), ToStatus: types.StageStatusPending, Source: "publisher", CreatedAt: at(1)},
+		{StageID: 1, FromStatus: from(types.StageStatusPending), ToStatus: types.StageStatusCompleted, Source: "result_consumer", CreatedAt: at(2)},
+		{StageID: 9, FromStatus: from(types.StageStatusNotStarted), ToStatus: types.StageStatusPending, Source: "publisher", CreatedAt: at(3)},
+		{StageID: 2, FromStatus: from(types.StageStatusNotStarted), ToStatus: types.StageStatusPending, Source: "publisher", CreatedAt: at(4)},
+		{StageID: 2, FromStatus: from(types.StageStatusPending), ToStatus: types.StageStatusFailed, Source: "pending_watcher", CreatedAt: at(5)},
+	}
+
+	events := buildPipelineTimeline(created, []int{1, 2}, map[int]string{1: "fetch", 2: "store"}, transitions)
+
+	want := []struct {
+		kind, from, to, source string
+		stageID                int
+	}{
+		{types.TimelineEventPipeline, "", types.PipelineStatusNotStarted, pipelineCreatedSource, 0},
+		{types.TimelineEventStage, types.StageStatusNotStarted, types.StageStatusPending, "publisher", 1},
+		{types.TimelineEventPipeline, types.PipelineStatusNotStarted, types.PipelineStatusRunning, "publisher", 1},
+		{types.TimelineEventStage, types.StageStatusPending, types.StageStatusCompleted, "result_consumer", 1},
+		{types.TimelineEventStage, types.StageStatusNotStarted, types.StageStatusPending, "publisher", 2},
+		{types.TimelineEventStage, types.StageStatusPending, types.StageStatusFailed, "pending_watcher", 2},
+		{types.TimelineEventPipeline, types.PipelineStatusRunning, types.PipelineStatusFailed, "pending_watcher", 2},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, w := range want {
+		e := events[i]
+		stageID := 0
+		if e.StageID != nil {
+			stageID = *e.StageID
+		}
+		if e.Kind != w.kind || e.FromStatus != w.from || e.ToStatus != w.to || e.Source != w.source || stageID != w.stageID {
+			t.Errorf("event %d = %s %s->%s by %s (stage %d), want %s %s->%s by %s (stage %d)",
+				i, e.Kind, e.FromStatus, e.ToStatus, e.Source, stageID, w.kind, w.from, w.to, w.source, w.stageID)
+		}
+	}
+	if !events[0].At.Equal(created) || !events[len(events)-1].At.Equal(at(5)) {
+		t.Errorf("event times = %s .. %s, want %s .. %s", events[0].At, events[len(events)-1].At, created, at(5))
+	}
+}
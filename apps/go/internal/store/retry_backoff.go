@@ -0,0 +1,101 @@
+package store
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"pipelogiq/internal/types"
+)
+
+// RetryPolicyFunc returns the rule of the retry policy that wins for stageID,
+// or nil when none applies. UpdateStageResult consults it to space out the
+// retries a stage's own options schedule, before opening its transaction, so
+// the function may query the store.
+type RetryPolicyFunc func(ctx context.Context, stageID int) *types.PolicyRule
+
+// retryJitterSource draws the random part of jittered retry delays.
+var retryJitterSource = rand.Float64
+
+// SetRetryPolicy registers how retry policies are resolved for stages. It
+// must be called before the store is used concurrently.
+func (s *Store) SetRetryPolicy(policy RetryPolicyFunc) {
+	s.retryPolicy = policy
+}
+
+// retryBackoff spaces out the retries of a stage. Max of zero leaves the
+// delay uncapped; Jitter is "" for none, RetryJitterFull or
+// RetryJitterDecorrelated.
+type retryBackoff struct {
+	Exponential bool
+	Base        time.Duration
+	Max         time.Duration
+	Jitter      string
+}
+
+// stageRetryBackoff resolves the backoff of a stage: the retry policy rule
+// when one applies, otherwise a fixed retryInterval.
+func stageRetryBackoff(retryInterval time.Duration, rule *types.PolicyRule) retryBackoff {
+	if rule == nil || rule.BaseDelayMs == nil || *rule.BaseDelayMs <= 0 {
+		return retryBackoff{Base: retryInterval}
+	}
+	backoff := retryBackoff{
+		Exponential: rule.Backoff != nil && strings.EqualFold(*rule.Backoff, "exponential"),
+		Base:        time.Duration(*rule.BaseDelayMs) * time.Millisecond,
+	}
+	if rule.MaxDelayMs != nil && *rule.MaxDelayMs > 0 {
+		backoff.Max = time.Duration(*rule.MaxDelayMs) * time.Millisecond
+	}
+	if rule.Jitter != nil && *rule.Jitter {
+		backoff.Jitter = types.RetryJitterFull
+		if rule.JitterMode != nil && strings.EqualFold(*rule.JitterMode, types.RetryJitterDecorrelated) {
+			backoff.Jitter = types.RetryJitterDecorrelated
+		}
+	}
+	return backoff
+}
+
+// delay returns the wait before retry number attempt, 1 for the first retry.
+// Fixed backoff waits Base, exponential backoff doubles it with every attempt;
+// both are capped at Max. Full jitter waits a random part of that delay.
+// Decorrelated jitter ignores attempt and waits between Base and three times
+// prev, the previous delay, or three times Base for fixed backoff. rnd
+// returns a number in [0, 1).
+func (b retryBackoff) delay(attempt int, prev time.Duration, rnd func() float64) time.Duration {
+	if b.Base <= 0 {
+		return 0
+	}
+
+	if b.Jitter == types.RetryJitterDecorrelated {
+		upper := b.Base
+		if b.Exponential && attempt > 1 && prev > b.Base {
+			upper = prev
+		}
+		upper = min(upper, math.MaxInt64/3) * 3
+		return b.capped(b.Base + time.Duration(rnd()*float64(upper-b.Base)))
+	}
+
+	delay := b.Base
+	if b.Exponential {
+		for i := 1; i < attempt; i++ {
+			if (b.Max > 0 && delay >= b.Max) || delay > math.MaxInt64/2 {
+				break
+			}
+			delay *= 2
+		}
+	}
+	delay = b.capped(delay)
+	if b.Jitter == types.RetryJitterFull {
+		delay = time.Duration(rnd() * float64(delay))
+	}
+	return delay
+}
+
+func (b retryBackoff) capped(delay time.Duration) time.Duration {
+	if b.Max > 0 {
+		return min(delay, b.Max)
+	}
+	return delay
+}
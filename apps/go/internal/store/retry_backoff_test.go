@@ -0,0 +1,89 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"pipelogiq/internal/types"
+)
+
+func retryRule(backoff string, maxDelayMs int, jitter bool, jitterMode string) *types.PolicyRule {
+	base := 100
+	rule := &types.PolicyRule{Backoff: &backoff, BaseDelayMs: &base, Jitter: &jitter}
+	if maxDelayMs > 0 {
+		rule.MaxDelayMs = &maxDelayMs
+	}
+	if jitterMode != "" {
+		rule.JitterMode = &jitterMode
+	}
+	return rule
+}
+
+// retryDelays returns the delays of the first n retries, each one fed the
+// previous delay as UpdateStageResult does.
+func retryDelays(b retryBackoff, n int) []time.Duration {
+	half := func() float64 { return 0.5 }
+	var delays []time.Duration
+	var prev time.Duration
+	for attempt := 1; attempt <= n; attempt++ {
+		prev = b.delay(attempt, prev, half)
+		delays = append(delays, prev)
+	}
+	return delays
+}
+
+func TestRetryBackoffDelays(t *testing.T) {
+	ms := func(values ...float64) []time.Duration {
+		out := make([]time.Duration, len(values))
+		for i, v := range values {
+			out[i] = time.Duration(v * float64(time.Millisecond))
+		}
+		return out
+	}
+	cases := []struct {
+		name string
+		rule *types.PolicyRule
+		want []time.Duration
+	}{
+		{name: "no policy", want: ms(30000, 30000, 30000, 30000, 30000)},
+		{name: "fixed", rule: retryRule("fixed", 1000, false, ""), want: ms(100, 100, 100, 100, 100)},
+		{name: "exponential", rule: retryRule("exponential", 1000, false, ""), want: ms(100, 200, 400, 800, 1000)},
+		{name: "fixed full jitter", rule: retryRule("fixed", 1000, true, ""), want: ms(50, 50, 50, 50, 50)},
+		{name: "exponential full jitter", rule: retryRule("exponential", 1000, true, types.RetryJitterFull), want: ms(50, 100, 200, 400, 500)},
+		{name: "fixed decorrelated jitter", rule: retryRule("fixed", 1000, true, types.RetryJitterDecorrelated), want: ms(200, 200, 200, 200, 200)},
+		{name: "exponential decorrelated jitter", rule: retryRule("exponential", 1000, true, types.RetryJitterDecorrelated), want: ms(200, 350, 575, 912.5, 1000)},
+		{name: "jitter mode without jitter", rule: retryRule("exponential", 1000, false, types.RetryJitterDecorrelated), want: ms(100, 200, 400, 800, 1000)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			backoff := stageRetryBackoff(30*time.Second, tc.rule)
+			if got := retryDelays(backoff, len(tc.want)); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("delays = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRetryBackoffMaxDelayCapsGrowth(t *testing.T) {
+	capped := stageRetryBackoff(0, retryRule("exponential", 250, false, ""))
+	for attempt := 1; attempt <= 100; attempt++ {
+		if got := capped.delay(attempt, 0, nil); got > 250*time.Millisecond {
+			t.Fatalf("attempt %d delay = %v, want at most 250ms", attempt, got)
+		}
+	}
+
+	uncapped := stageRetryBackoff(0, retryRule("exponential", 0, false, ""))
+	if got, want := uncapped.delay(10, 0, nil), 51200*time.Millisecond; got != want {
+		t.Fatalf("uncapped attempt 10 delay = %v, want %v", got, want)
+	}
+	if got := uncapped.delay(1000, 0, nil); got <= 0 {
+		t.Fatalf("uncapped attempt 1000 delay = %v, want positive", got)
+	}
+
+	one := func() float64 { return 0.999999 }
+	decorrelated := stageRetryBackoff(0, retryRule("exponential", 250, true, types.RetryJitterDecorrelated))
+	if got := decorrelated.delay(5, time.Hour, one); got != 250*time.Millisecond {
+		t.Fatalf("decorrelated delay = %v, want 250ms", got)
+	}
+}
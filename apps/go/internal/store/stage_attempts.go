@@ -0,0 +1,43 @@
+package store
+
+import "database/sql"
+
+// defaultMaxStageAttempts is the stage attempt cap used until
+// SetMaxStageAttempts overrides it.
+const defaultMaxStageAttempts = 25
+
+// maxAttemptsExceededReason is the failure reason of a stage that failed
+// with retries left under its own schedule but had used up its attempts.
+const maxAttemptsExceededReason = "max_attempts_exceeded"
+
+// SetMaxStageAttempts sets the most attempts, first run included, any stage
+// gets regardless of its max_retries. A stage's own max_attempts can only
+// lower it. Zero or less disables the cap.
+func (s *Store) SetMaxStageAttempts(n int) {
+	s.maxStageAttempts = n
+}
+
+// stageAttemptCap returns the attempt cap of a stage: the lower of the global
+// cap and the stage's max_attempts, ignoring either when not positive. Zero
+// means uncapped.
+func stageAttemptCap(global int, stage sql.NullInt64) int {
+	limit := max(global, 0)
+	if stage.Valid && stage.Int64 > 0 && (limit == 0 || int(stage.Int64) < limit) {
+		limit = int(stage.Int64)
+	}
+	return limit
+}
+
+// allowStageRetry reports whether a stage whose attempt number retryAttempt
+// (zero for the first run) just failed may run again. scheduled is what the
+// stage's own retry options allow; capped reports that they allowed it but
+// attemptCap did not.
+func allowStageRetry(scheduled bool, retryAttempt, attemptCap int) (retry, capped bool) {
+	if !scheduled {
+		return false, false
+	}
+	if attemptCap > 0 && retryAttempt+1 >= attemptCap {
+		return false, true
+	}
+	return true, false
+}
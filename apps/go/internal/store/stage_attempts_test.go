@@ -0,0 +1,71 @@
+package store
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestStageAttemptCap(t *testing.T) {
+	cases := []struct {
+		global int
+		stage  sql.NullInt64
+		want   int
+	}{
+		{global: 25, want: 25},
+		{global: 25, stage: sql.NullInt64{Int64: 3, Valid: true}, want: 3},
+		{global: 5, stage: sql.NullInt64{Int64: 10, Valid: true}, want: 5},
+		{global: 0, stage: sql.NullInt64{Int64: 10, Valid: true}, want: 10},
+		{global: 5, stage: sql.NullInt64{Int64: 0, Valid: true}, want: 5},
+		{global: 0, want: 0},
+		{global: -1, want: 0},
+	}
+	for _, tc := range cases {
+		if got := stageAttemptCap(tc.global, tc.stage); got != tc.want {
+			t.Errorf("stageAttemptCap(%d, %v) = %d, want %d", tc.global, tc.stage, got, tc.want)
+		}
+	}
+}
+
+// TestAllowStageRetryAttempts fails a stage over and over, advancing
+// retry_attempt the way UpdateStageResult does, and counts the attempts it
+// gets before failing for good.
+func TestAllowStageRetryAttempts(t *testing.T) {
+	cases := []struct {
+		name         string
+		maxRetries   int
+		attemptCap   int
+		wantAttempts int
+		wantCapped   bool
+	}{
+		{name: "no retries", maxRetries: 0, attemptCap: 25, wantAttempts: 1},
+		{name: "retries under cap", maxRetries: 3, attemptCap: 25, wantAttempts: 4},
+		{name: "retries reach cap exactly", maxRetries: 3, attemptCap: 4, wantAttempts: 4},
+		{name: "cap cuts retries short", maxRetries: 1000, attemptCap: 25, wantAttempts: 25, wantCapped: true},
+		{name: "cap of one", maxRetries: 3, attemptCap: 1, wantAttempts: 1, wantCapped: true},
+		{name: "uncapped", maxRetries: 100, attemptCap: 0, wantAttempts: 101},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			retryAttempt := 0
+			for {
+				retry, capped := allowStageRetry(retryAttempt < tc.maxRetries, retryAttempt, tc.attemptCap)
+				if !retry {
+					if capped != tc.wantCapped {
+						t.Fatalf("capped = %v, want %v", capped, tc.wantCapped)
+					}
+					break
+				}
+				if capped {
+					t.Fatal("allowStageRetry() retried a capped stage")
+				}
+				retryAttempt++
+				if retryAttempt > 1000 {
+					t.Fatal("stage retried without end")
+				}
+			}
+			if attempts := retryAttempt + 1; attempts != tc.wantAttempts {
+				t.Fatalf("stage ran %d attempts, want %d", attempts, tc.wantAttempts)
+			}
+		})
+	}
+}
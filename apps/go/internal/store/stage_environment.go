@@ -0,0 +1,122 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"pipelogiq/internal/types"
+)
+
+// maxEnvironmentLen bounds an environment name so it fits in a queue name.
+const maxEnvironmentLen = 64
+
+// PipelineEnvironment returns the environment a pipeline's stages are routed
+// to: the value of its "environment" or "env" context item, else of a keyword
+// with one of those keys. Names are lowercased; a name that is too long or
+// uses characters other than letters, digits, '.', '_' and '-' is ignored,
+// as is a missing one, and the stages go to the default queue.
+func PipelineEnvironment(contextItems []types.ContextItem, keywords []types.PipelineKeyword) string {
+	for _, item := range contextItems {
+		if isEnvironmentKey(item.Key) {
+			return normalizeEnvironment(item.Value)
+		}
+	}
+	for _, kw := range keywords {
+		if isEnvironmentKey(kw.Key) {
+			return normalizeEnvironment(kw.Value)
+		}
+	}
+	return ""
+}
+
+func isEnvironmentKey(key string) bool {
+	key = strings.ToLower(strings.TrimSpace(key))
+	return key == "environment" || key == "env"
+}
+
+func normalizeEnvironment(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" || len(value) > maxEnvironmentLen {
+		return ""
+	}
+	for _, r := range value {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+		default:
+			return ""
+		}
+	}
+	return value
+}
+
+// StageQueueEnvironment is a handler's StageNext queue of one environment.
+type StageQueueEnvironment struct {
+	Handler     string `db:"handler"`
+	Environment string `db:"environment"`
+}
+
+// ListStageQueueEnvironments returns the handlers and environments of the
+// stages of unfinished pipelines that have an environment, i.e. the
+// per-environment StageNext queues that may hold messages.
+func (s *Store) ListStageQueueEnvironments(ctx context.Context) ([]StageQueueEnvironment, error) {
+	var rows []StageQueueEnvironment
+	if err := s.db.SelectContext(ctx, &rows, `
+		SELECT DISTINCT s.stage_handler_name AS handler, env.value AS environment
+		FROM stage s
+		JOIN pipeline p ON p.id = s.pipeline_id
+		CROSS JOIN LATERAL (
+			SELECT candidate.value
+			FROM (
+				SELECT ci.value, 0 AS rank
+				FROM pipeline_context_item ci
+				WHERE ci.pipeline_id = s.pipeline_id AND LOWER(TRIM(ci.key)) IN ('environment', 'env')
+				UNION ALL
+				SELECT k.value, 1 AS rank
+				FROM pipeline_keyword pk
+				JOIN keyword k ON k.id = pk.keyword_id
+				WHERE pk.pipeline_id = s.pipeline_id AND LOWER(TRIM(k.key)) IN ('environment', 'env')
+			) candidate
+			ORDER BY candidate.rank
+			LIMIT 1
+		) env
+		WHERE COALESCE(p.is_completed, false) = false
+		  AND COALESCE(s.stage_handler_name, '') <> ''
+	`); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[StageQueueEnvironment]bool, len(rows))
+	queues := make([]StageQueueEnvironment, 0, len(rows))
+	for _, row := range rows {
+		row.Environment = normalizeEnvironment(row.Environment)
+		if row.Environment == "" || seen[row] {
+			continue
+		}
+		seen[row] = true
+		queues = append(queues, row)
+	}
+	sort.Slice(queues, func(i, j int) bool {
+		if queues[i].Handler != queues[j].Handler {
+			return queues[i].Handler < queues[j].Handler
+		}
+		return queues[i].Environment < queues[j].Environment
+	})
+	return queues, nil
+}
+
+func (s *Store) getKeywordsTx(ctx context.Context, tx *sqlx.Tx, pipelineID int) ([]types.PipelineKeyword, error) {
+	keywords := []types.PipelineKeyword{}
+	if err := tx.SelectContext(ctx, &keywords, `
+		SELECT k.key, k.value
+		FROM pipeline_keyword pk
+		JOIN keyword k ON k.id = pk.keyword_id
+		WHERE pk.pipeline_id = $1
+		ORDER BY pk.keyword_id
+	`, pipelineID); err != nil {
+		return nil, err
+	}
+	return keywords, nil
+}
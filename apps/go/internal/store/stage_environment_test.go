@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+
+	"pipelogiq/internal/types"
+)
+
+func TestPipelineEnvironment(t *testing.T) {
+	tests := []struct {
+		name     string
+		context  []types.ContextItem
+		keywords []types.PipelineKeyword
+		want     string
+	}{
+		{"none", nil, nil, ""},
+		{"context", []types.ContextItem{{Key: "Environment", Value: " Prod "}}, nil, "prod"},
+		{"env alias", []types.ContextItem{{Key: "env", Value: "staging-eu.1"}}, nil, "staging-eu.1"},
+		{"keyword", nil, []types.PipelineKeyword{{Key: "env", Value: "staging"}}, "staging"},
+		{
+			"context wins",
+			[]types.ContextItem{{Key: "env", Value: "prod"}},
+			[]types.PipelineKeyword{{Key: "env", Value: "staging"}},
+			"prod",
+		},
+		{"unsafe name", []types.ContextItem{{Key: "env", Value: "prod/eu"}}, nil, ""},
+		{"too long", []types.ContextItem{{Key: "env", Value: strings.Repeat("e", maxEnvironmentLen+1)}}, nil, ""},
+	}
+	for _, tt := range tests {
+		if got := PipelineEnvironment(tt.context, tt.keywords); got != tt.want {
+			t.Errorf("%s: PipelineEnvironment() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestListStageQueueEnvironments(t *testing.T) {
+	db := setupPostgresTestDB(t)
+	st := New(db, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	insertPipeline := func(completed bool, handlers ...string) int {
+		t.Helper()
+		var id int
+		if err := db.QueryRow(`
+			INSERT INTO pipeline (application_id, name, status, is_completed) VALUES (1, 'env', $1, $2) RETURNING id
+		`, types.PipelineStatusRunning, completed).Scan(&id); err != nil {
+			t.Fatalf("insert pipeline: %v", err)
+		}
+		for _, handler := range handlers {
+			if _, err := db.Exec(`
+				INSERT INTO stage (pipeline_id, name, stage_handler_name, status) VALUES ($1, $2, $2, $3)
+			`, id, handler, types.StageStatusPending); err != nil {
+				t.Fatalf("insert stage: %v", err)
+			}
+		}
+		return id
+	}
+	addContext := func(pipelineID int, key, value string) {
+		t.Helper()
+		if _, err := db.Exec(`
+			INSERT INTO pipeline_context_item (pipeline_id, key, value, value_type) VALUES ($1, $2, $3, 'string')
+		`, pipelineID, key, value); err != nil {
+			t.Fatalf("insert context item: %v", err)
+		}
+	}
+	addKeyword := func(pipelineID int, key, value string) {
+		t.Helper()
+		if _, err := db.Exec(`
+			WITH k AS (INSERT INTO keyword (key, value) VALUES ($2, $3) RETURNING id)
+			INSERT INTO pipeline_keyword (pipeline_id, keyword_id) SELECT $1, id FROM k
+		`, pipelineID, key, value); err != nil {
+			t.Fatalf("insert keyword: %v", err)
+		}
+	}
+
+	prod := insertPipeline(false, "resize", "email")
+	addContext(prod, "Environment", " Prod ")
+	addKeyword(prod, "env", "staging")
+	staging := insertPipeline(false, "resize")
+	addKeyword(staging, "env", "staging")
+	unsafe := insertPipeline(false, "resize")
+	addContext(unsafe, "env", "prod/eu")
+	done := insertPipeline(true, "thumbnail")
+	addContext(done, "env", "dev")
+	insertPipeline(false, "plain")
+
+	got, err := st.ListStageQueueEnvironments(context.Background())
+	if err != nil {
+		t.Fatalf("ListStageQueueEnvironments() error = %v", err)
+	}
+	want := []StageQueueEnvironment{
+		{Handler: "email", Environment: "prod"},
+		{Handler: "resize", Environment: "prod"},
+		{Handler: "resize", Environment: "staging"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ListStageQueueEnvironments() = %+v, want %+v", got, want)
+	}
+}
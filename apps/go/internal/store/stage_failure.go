@@ -0,0 +1,16 @@
+package store
+
+import (
+	"encoding/json"
+
+	"pipelogiq/internal/types"
+)
+
+// marshalStageFailure encodes f for the stage.failure_detail column.
+func marshalStageFailure(f types.StageFailure) string {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"pipelogiq/internal/types"
+)
+
+var errStageNotFound = errors.New("stage not found")
+
+// IsStageNotFoundError reports whether err means the stage does not exist in
+// the requested pipeline.
+func IsStageNotFoundError(err error) bool {
+	return errors.Is(err, errStageNotFound)
+}
+
+// archiveStageExecutionsTx copies the current run of each stage in stageIDs
+// into stage_execution_history before a rerun resets it. Stages that never
+// ran are skipped. It must run in the transaction that does the reset, so a
+// run is never lost nor archived twice.
+func archiveStageExecutionsTx(ctx context.Context, tx *sqlx.Tx, stageIDs []int, source string) error {
+	if len(stageIDs) == 0 {
+		return nil
+	}
+	if err := execIn(ctx, tx, `
+		INSERT INTO stage_execution_history
+			(stage_id, attempt, retry_attempt, status, input, output, output_truncated, started_at, finished_at, source)
+		SELECT s.id,
+			COALESCE((SELECT MAX(h.attempt) FROM stage_execution_history h WHERE h.stage_id = s.id), 0) + 1,
+			COALESCE(s.retry_attempt, 0), s.status, io.input, io.output, COALESCE(io.output_truncated, false),
+			s.started_at, s.finished_at, ?
+		FROM stage s
+		LEFT JOIN stage_io io ON io.stage_id = s.id
+		WHERE s.id IN (?)
+		  AND s.status <> ?
+	`, source, stageIDs, types.StageStatusNotStarted); err != nil {
+		return fmt.Errorf("archive stage executions: %w", err)
+	}
+	return nil
+}
+
+// GetStageExecutionHistory returns the archived runs of a stage of
+// pipelineID, newest first.
+func (s *Store) GetStageExecutionHistory(ctx context.Context, pipelineID, stageID int) ([]types.StageExecutionHistoryEntry, error) {
+	var found int
+	if err := s.db.GetContext(ctx, &found, `
+		SELECT id FROM stage WHERE id = $1 AND pipeline_id = $2
+	`, stageID, pipelineID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errStageNotFound
+		}
+		return nil, fmt.Errorf("get stage: %w", err)
+	}
+
+	entries := []types.StageExecutionHistoryEntry{}
+	if err := s.db.SelectContext(ctx, &entries, `
+		SELECT id, attempt, retry_attempt, status, input, output, output_truncated,
+			started_at, finished_at, archived_at, source
+		FROM stage_execution_history
+		WHERE stage_id = $1
+		ORDER BY attempt DESC
+	`, stageID); err != nil {
+		return nil, fmt.Errorf("list stage execution history: %w", err)
+	}
+	return entries, nil
+}
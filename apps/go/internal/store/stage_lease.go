@@ -0,0 +1,204 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"pipelogiq/internal/types"
+)
+
+// maxOrphanedStagesPerCheck bounds how many stages one
+// ReconcileOrphanedStages call handles.
+const maxOrphanedStagesPerCheck = 500
+
+// LeaseStage records that the job gateway handed out the dispatch of stageID
+// identified by idempotencyKey, and that its worker holds it until
+// expiresAt. It reports false when that dispatch is stale: the stage is no
+// longer Pending or Running, or was dispatched again under another key since,
+// for example after ReconcileOrphanedStages reset it. The gateway then drops
+// the message instead of handing it out, so a stage is not run twice.
+func (s *Store) LeaseStage(ctx context.Context, stageID int, idempotencyKey string, expiresAt time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE stage SET lease_expires_at = $3
+		WHERE id = $1
+		  AND (dispatch_key IS NULL OR dispatch_key = $2)
+		  AND status IN ($4, $5)
+	`, stageID, idempotencyKey, expiresAt.UTC(), types.StageStatusPending, types.StageStatusRunning)
+	if err != nil {
+		return false, fmt.Errorf("lease stage %d: %w", stageID, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// ReconcileOrphanedStages recovers stages whose gateway lease lapsed more
+// than grace ago while they were still Pending or Running: the worker that
+// pulled them died without settling the job. A stage with retries left goes
+// back to NotStarted, using up an attempt, so the publisher dispatches it
+// again; one without, or at its attempt cap, fails along with its pipeline.
+// Only stages handed out through the gateway carry a lease; stages consumed
+// straight from the broker are redelivered by RabbitMQ itself and are left
+// alone.
+//
+// grace should exceed the gateway's sweep of expired tokens so the requeued
+// message has been released first; LeaseStage drops it when pulled again.
+// It returns the number of stages requeued and failed.
+func (s *Store) ReconcileOrphanedStages(ctx context.Context, grace time.Duration) (requeued, failed int, err error) {
+	var rows []struct {
+		ID           int           `db:"id"`
+		PipelineID   int           `db:"pipeline_id"`
+		Status       string        `db:"status"`
+		RetryAttempt int           `db:"retry_attempt"`
+		MaxRetries   sql.NullInt64 `db:"max_retries"`
+		MaxAttempts  sql.NullInt64 `db:"max_attempts"`
+		LeaseExpired time.Time     `db:"lease_expires_at"`
+	}
+	if err = s.db.SelectContext(ctx, &rows, `
+		SELECT s.id, s.pipeline_id, s.status, COALESCE(s.retry_attempt, 0) AS retry_attempt,
+			so.max_retries, so.max_attempts, s.lease_expires_at
+		FROM stage s
+		JOIN pipeline p ON p.id = s.pipeline_id
+		LEFT JOIN LATERAL (
+			SELECT max_retries, max_attempts FROM stage_options WHERE stage_id = s.id ORDER BY id DESC LIMIT 1
+		) so ON true
+		WHERE p.is_completed = false
+		  AND s.status IN ($1, $2)
+		  AND s.lease_expires_at < NOW() - make_interval(secs => $3)
+		ORDER BY s.lease_expires_at
+		LIMIT $4
+	`, types.StageStatusPending, types.StageStatusRunning, grace.Seconds(), maxOrphanedStagesPerCheck); err != nil {
+		return 0, 0, err
+	}
+
+	for _, row := range rows {
+		retry, capped := allowStageRetry(row.RetryAttempt < int(row.MaxRetries.Int64), row.RetryAttempt,
+			stageAttemptCap(s.maxStageAttempts, row.MaxAttempts))
+		var ok bool
+		if retry {
+			ok, err = s.requeueOrphanedStage(ctx, row.ID, row.PipelineID, row.Status)
+		} else {
+			ok, err = s.failOrphanedStage(ctx, row.ID, row.PipelineID, row.Status, row.LeaseExpired, grace, capped)
+		}
+		if err != nil {
+			return requeued, failed, err
+		}
+		if !ok {
+			continue
+		}
+		if retry {
+			s.LogStageChange(ctx, row.PipelineID, row.ID, row.Status, types.StageStatusNotStarted, "lease_reconciler")
+			requeued++
+		} else {
+			s.LogStageChange(ctx, row.PipelineID, row.ID, row.Status, types.StageStatusFailed, "lease_reconciler")
+			failed++
+		}
+	}
+	return requeued, failed, nil
+}
+
+// requeueOrphanedStage returns the stage to NotStarted unless it settled or
+// its lease was renewed since the scan. Clearing the dispatch key makes any
+// copy of the old job stale.
+func (s *Store) requeueOrphanedStage(ctx context.Context, stageID, pipelineID int, status string) (bool, error) {
+	tx, err := s.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var id int
+	err = tx.GetContext(ctx, &id, `
+		UPDATE stage
+		SET status = $1, retry_attempt = COALESCE(retry_attempt, 0) + 1, started_at = NULL,
+			next_retry_at = NULL, dispatch_key = NULL, lease_expires_at = NULL
+		WHERE id = $2 AND status = $3 AND lease_expires_at < NOW()
+		RETURNING id
+	`, types.StageStatusNotStarted, stageID, status)
+	if errors.Is(err, sql.ErrNoRows) {
+		_ = tx.Rollback()
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("requeue stage %d: %w", stageID, err)
+	}
+	if err = recomputePipelineStatus(ctx, tx, pipelineID); err != nil {
+		return false, err
+	}
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// failOrphanedStage fails a stage out of retries, and its pipeline, unless it
+// settled or its lease was renewed since the scan. capped marks a stage that
+// had retries left but reached its attempt cap. The failure records how long
+// the lease has been lapsed as its age and grace as its timeout.
+func (s *Store) failOrphanedStage(ctx context.Context, stageID, pipelineID int, status string, leaseExpired time.Time,
+	grace time.Duration, capped bool) (bool, error) {
+	tx, err := s.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	reason, left := "worker_lost", "the stage has no retries left"
+	if capped {
+		reason, left = maxAttemptsExceededReason, "the stage reached its attempt cap"
+	}
+	now := time.Now().UTC()
+	age := int64(now.Sub(leaseExpired).Seconds())
+	timeoutSeconds := int64(grace.Seconds())
+	failure := marshalStageFailure(types.StageFailure{
+		Category:       types.StageFailureTimeout,
+		Reason:         reason,
+		AgeSeconds:     &age,
+		TimeoutSeconds: &timeoutSeconds,
+		At:             now,
+	})
+	var id int
+	err = tx.GetContext(ctx, &id, `
+		UPDATE stage
+		SET status = $1, finished_at = NOW(), next_retry_at = NULL, lease_expires_at = NULL,
+			failure_category = $4, failure_detail = $5
+		WHERE id = $2 AND status = $3 AND lease_expires_at < NOW()
+		RETURNING id
+	`, types.StageStatusFailed, stageID, status, types.StageFailureTimeout, failure)
+	if errors.Is(err, sql.ErrNoRows) {
+		_ = tx.Rollback()
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("fail stage %d: %w", stageID, err)
+	}
+	if _, err = tx.ExecContext(ctx, `
+		UPDATE pipeline SET is_completed = true, finished_at = NOW(), status = $2 WHERE id = $1
+	`, pipelineID, types.PipelineStatusFailed); err != nil {
+		return false, err
+	}
+	msg := fmt.Sprintf("Stage worker stopped renewing its lease at %s and %s",
+		leaseExpired.UTC().Format(time.RFC3339), left)
+	if _, err = tx.ExecContext(ctx, `
+		UPDATE stage_io SET output = $1, output_truncated = false, output_bytes = NULL WHERE stage_id = $2
+	`, msg, stageID); err != nil {
+		return false, err
+	}
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"pipelogiq/internal/types"
+)
+
+func TestReconcileOrphanedStages(t *testing.T) {
+	db := setupPostgresTestDB(t)
+	ctx := context.Background()
+	seedSequentialPipelines(t, db, 1, 2)
+	st := New(db, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	claim := func() *types.StageNextMessage {
+		t.Helper()
+		msg, err := st.GetStageToExecute(ctx)
+		if err != nil || msg == nil {
+			t.Fatalf("GetStageToExecute() = %v, %v", msg, err)
+		}
+		return msg
+	}
+	stageStatus := func(stageID int) (status string, attempt int) {
+		t.Helper()
+		if err := db.QueryRow(`SELECT status, COALESCE(retry_attempt, 0) FROM stage WHERE id = $1`, stageID).
+			Scan(&status, &attempt); err != nil {
+			t.Fatalf("load stage: %v", err)
+		}
+		return status, attempt
+	}
+	lapsed := time.Now().Add(-5 * time.Minute)
+
+	first := claim()
+	if _, err := db.Exec(`INSERT INTO stage_options (stage_id, max_retries) VALUES ($1, 1)`, first.StageID); err != nil {
+		t.Fatalf("insert stage options: %v", err)
+	}
+	if live, err := st.LeaseStage(ctx, first.StageID, "other-dispatch", lapsed); err != nil || live {
+		t.Fatalf("LeaseStage(other key) = %v, %v, want stale", live, err)
+	}
+	if live, err := st.LeaseStage(ctx, first.StageID, first.IdempotencyKey, lapsed); err != nil || !live {
+		t.Fatalf("LeaseStage() = %v, %v, want live", live, err)
+	}
+
+	// A retry is left: the stage goes back to NotStarted and the old
+	// dispatch becomes stale.
+	requeued, failed, err := st.ReconcileOrphanedStages(ctx, time.Minute)
+	if err != nil || requeued != 1 || failed != 0 {
+		t.Fatalf("ReconcileOrphanedStages() = %d, %d, %v, want 1 requeued", requeued, failed, err)
+	}
+	if status, attempt := stageStatus(first.StageID); status != types.StageStatusNotStarted || attempt != 1 {
+		t.Fatalf("stage = %s attempt %d, want NotStarted attempt 1", status, attempt)
+	}
+	if live, err := st.LeaseStage(ctx, first.StageID, first.IdempotencyKey, time.Now().Add(time.Minute)); err != nil || live {
+		t.Fatalf("LeaseStage(old dispatch) = %v, %v, want stale", live, err)
+	}
+
+	// Out of retries: the stage and its pipeline fail.
+	second := claim()
+	if second.StageID != first.StageID {
+		t.Fatalf("claimed stage %d, want %d again", second.StageID, first.StageID)
+	}
+	if live, err := st.LeaseStage(ctx, second.StageID, second.IdempotencyKey, lapsed); err != nil || !live {
+		t.Fatalf("LeaseStage() = %v, %v, want live", live, err)
+	}
+	requeued, failed, err = st.ReconcileOrphanedStages(ctx, time.Minute)
+	if err != nil || requeued != 0 || failed != 1 {
+		t.Fatalf("ReconcileOrphanedStages() = %d, %d, %v, want 1 failed", requeued, failed, err)
+	}
+	if status, _ := stageStatus(second.StageID); status != types.StageStatusFailed {
+		t.Fatalf("stage = %s, want Failed", status)
+	}
+	var failure types.StageFailure
+	if err := db.QueryRow(`SELECT failure_detail FROM stage WHERE id = $1`, second.StageID).Scan(&failure); err != nil {
+		t.Fatalf("load failure: %v", err)
+	}
+	if failure.Reason != "worker_lost" || failure.AgeSeconds == nil || *failure.AgeSeconds < 5*60 ||
+		failure.TimeoutSeconds == nil || *failure.TimeoutSeconds != 60 {
+		t.Fatalf("failure = %+v, want worker_lost lapsed 5m with a 60s grace", failure)
+	}
+	var pipelineStatus string
+	if err := db.QueryRow(`SELECT status FROM pipeline WHERE id = $1`, *second.PipelineID).Scan(&pipelineStatus); err != nil {
+		t.Fatalf("load pipeline: %v", err)
+	}
+	if pipelineStatus != types.PipelineStatusFailed {
+		t.Fatalf("pipeline = %s, want Failed", pipelineStatus)
+	}
+}
@@ -0,0 +1,24 @@
+package store
+
+import "time"
+
+// StageResultObserver is told about every stage result UpdateStageResult
+// applies; duplicates and stale results are not reported.
+type StageResultObserver interface {
+	ObserveStageResult(outcome StageResultOutcome)
+}
+
+// StageResultOutcome describes one applied stage result. Duration runs from
+// the stage's start to the result and is zero when the start is unknown.
+type StageResultOutcome struct {
+	StageID  int
+	Handler  string
+	Status   string
+	Duration time.Duration
+}
+
+// SetStageResultObserver registers the observer for applied stage results.
+// It must be called before the store is used concurrently.
+func (s *Store) SetStageResultObserver(observer StageResultObserver) {
+	s.stageResultObserver = observer
+}
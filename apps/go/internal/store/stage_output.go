@@ -0,0 +1,31 @@
+package store
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// defaultStageOutputMaxBytes is the stage output cap used until
+// SetStageOutputMaxBytes overrides it.
+const defaultStageOutputMaxBytes = 1 << 20
+
+// SetStageOutputMaxBytes sets the largest stage output, in bytes, stored in
+// full. Longer outputs are cut to a prefix of that size; zero or less
+// disables the cap.
+func (s *Store) SetStageOutputMaxBytes(n int) {
+	s.stageOutputMaxBytes = n
+}
+
+// truncateStageOutput cuts output to at most maxBytes on a UTF-8 boundary and
+// appends a marker naming the original size. It returns output unchanged when
+// it fits or maxBytes disables the cap.
+func truncateStageOutput(output string, maxBytes int) (string, bool) {
+	if maxBytes <= 0 || len(output) <= maxBytes {
+		return output, false
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(output[cut]) {
+		cut--
+	}
+	return output[:cut] + fmt.Sprintf("\n...[output truncated: %d of %d bytes stored]", cut, len(output)), true
+}
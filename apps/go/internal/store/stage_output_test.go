@@ -0,0 +1,30 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateStageOutput(t *testing.T) {
+	if got, truncated := truncateStageOutput("short", 10); truncated || got != "short" {
+		t.Fatalf("truncateStageOutput(short) = (%q, %v), want unchanged", got, truncated)
+	}
+	if got, truncated := truncateStageOutput("unbounded output", 0); truncated || got != "unbounded output" {
+		t.Fatalf("truncateStageOutput(cap 0) = (%q, %v), want unchanged", got, truncated)
+	}
+
+	got, truncated := truncateStageOutput(strings.Repeat("a", 20), 8)
+	if !truncated {
+		t.Fatal("truncateStageOutput() did not truncate")
+	}
+	if !strings.HasPrefix(got, strings.Repeat("a", 8)+"\n") || !strings.Contains(got, "8 of 20 bytes") {
+		t.Fatalf("truncateStageOutput() = %q", got)
+	}
+
+	// "é" is two bytes; a cap inside it must not split the rune.
+	got, _ = truncateStageOutput("aé"+strings.Repeat("b", 10), 2)
+	if !utf8.ValidString(got) || !strings.HasPrefix(got, "a\n") {
+		t.Fatalf("truncateStageOutput() split a rune: %q", got)
+	}
+}
@@ -0,0 +1,151 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"pipelogiq/internal/types"
+)
+
+// StagePolicyTarget describes a dispatched stage in the terms policy
+// targeting uses.
+type StagePolicyTarget struct {
+	StageID       int    `db:"id"`
+	PipelineID    int    `db:"pipeline_id"`
+	ApplicationID int    `db:"application_id"`
+	Stage         string `db:"name"`
+	Handler       string `db:"handler"`
+	Environment   string `db:"environment"`
+	Tags          []string
+}
+
+// InFlightStageFilter selects the Pending and Running stages counted against
+// a concurrency limit. Zero-valued fields match every stage.
+type InFlightStageFilter struct {
+	Handler        string
+	ApplicationID  *int
+	Handlers       []string
+	Stages         []string
+	ExcludeStageID int
+}
+
+func (s *Store) GetStagePolicyTarget(ctx context.Context, stageID int) (*StagePolicyTarget, error) {
+	var target StagePolicyTarget
+	if err := s.db.GetContext(ctx, &target, `
+		SELECT s.id, s.pipeline_id, COALESCE(p.application_id, 0) AS application_id,
+			COALESCE(s.name, '') AS name, COALESCE(s.stage_handler_name, '') AS handler,
+			COALESCE((
+				SELECT LOWER(pci.value) FROM pipeline_context_item pci
+				WHERE pci.pipeline_id = p.id AND LOWER(pci.key) IN ('environment', 'env')
+				ORDER BY pci.id LIMIT 1
+			), '') AS environment
+		FROM stage s
+		JOIN pipeline p ON p.id = s.pipeline_id
+		WHERE s.id = $1
+	`, stageID); err != nil {
+		return nil, fmt.Errorf("load stage policy target: %w", err)
+	}
+
+	if err := s.db.SelectContext(ctx, &target.Tags, `
+		SELECT COALESCE(k.value, '')
+		FROM pipeline_keyword pk
+		JOIN keyword k ON k.id = pk.keyword_id
+		WHERE pk.pipeline_id = $1
+	`, target.PipelineID); err != nil {
+		return nil, fmt.Errorf("load pipeline tags: %w", err)
+	}
+	return &target, nil
+}
+
+// CountInFlightStages counts Pending and Running stages matching filter.
+// Handler and stage names compare case-insensitively.
+func (s *Store) CountInFlightStages(ctx context.Context, filter InFlightStageFilter) (int, error) {
+	conds := []string{"s.status IN (?, ?)", "s.id <> ?"}
+	args := []interface{}{types.StageStatusPending, types.StageStatusRunning, filter.ExcludeStageID}
+	if filter.Handler != "" {
+		conds = append(conds, "LOWER(s.stage_handler_name) = ?")
+		args = append(args, strings.ToLower(filter.Handler))
+	}
+	if filter.ApplicationID != nil {
+		conds = append(conds, "p.application_id = ?")
+		args = append(args, *filter.ApplicationID)
+	}
+	if len(filter.Handlers) > 0 {
+		conds = append(conds, "LOWER(s.stage_handler_name) IN (?)")
+		args = append(args, lowerAll(filter.Handlers))
+	}
+	if len(filter.Stages) > 0 {
+		conds = append(conds, "LOWER(s.name) IN (?)")
+		args = append(args, lowerAll(filter.Stages))
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT COUNT(*)
+		FROM stage s
+		JOIN pipeline p ON p.id = s.pipeline_id
+		WHERE `+strings.Join(conds, " AND "), args...)
+	if err != nil {
+		return 0, fmt.Errorf("build in-flight query: %w", err)
+	}
+
+	var count int
+	if err := s.db.GetContext(ctx, &count, s.db.Rebind(query), args...); err != nil {
+		return 0, fmt.Errorf("count in-flight stages: %w", err)
+	}
+	return count, nil
+}
+
+// DeferStage hands a stage claimed by GetStageToExecute back to the
+// scheduler: it returns to NotStarted and is not picked up again before until.
+func (s *Store) DeferStage(ctx context.Context, stageID int, until time.Time, source string) error {
+	return s.releaseClaimedStage(ctx, stageID, types.StageStatusNotStarted, until, source)
+}
+
+// HoldStageRetry hands a claimed retry back to the scheduler without using up
+// an attempt: it returns to RetryScheduled and is not picked up again before
+// until.
+func (s *Store) HoldStageRetry(ctx context.Context, stageID int, until time.Time, source string) error {
+	return s.releaseClaimedStage(ctx, stageID, types.StageStatusRetryScheduled, until, source)
+}
+
+func (s *Store) releaseClaimedStage(ctx context.Context, stageID int, status string, until time.Time, source string) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var pipelineID int
+	if err = tx.GetContext(ctx, &pipelineID, `
+		UPDATE stage SET status = $1, started_at = NULL, next_retry_at = $2
+		WHERE id = $3 AND status = $4
+		RETURNING pipeline_id
+	`, status, until, stageID, types.StageStatusPending); err != nil {
+		return fmt.Errorf("release stage: %w", err)
+	}
+	if err = recomputePipelineStatus(ctx, tx, pipelineID); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	s.LogStageChange(ctx, pipelineID, stageID, types.StageStatusPending, status, source)
+	return nil
+}
+
+func lowerAll(values []string) []string {
+	out := make([]string, len(values))
+	for i, value := range values {
+		out[i] = strings.ToLower(strings.TrimSpace(value))
+	}
+	return out
+}
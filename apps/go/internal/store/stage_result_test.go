@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"pipelogiq/internal/types"
+)
+
+func TestUpdateStageResultIdempotency(t *testing.T) {
+	db := setupPostgresTestDB(t)
+	ctx := context.Background()
+	seedSequentialPipelines(t, db, 1, 1)
+	st := New(db, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	claim := func(wantAttempt int) *types.StageNextMessage {
+		t.Helper()
+		msg, err := st.GetStageToExecute(ctx)
+		if err != nil || msg == nil {
+			t.Fatalf("GetStageToExecute() = %v, %v", msg, err)
+		}
+		if msg.Attempt != wantAttempt || !strings.HasPrefix(msg.IdempotencyKey, fmt.Sprintf("%d:%d:", msg.StageID, wantAttempt)) {
+			t.Fatalf("claimed attempt %d with key %q, want attempt %d", msg.Attempt, msg.IdempotencyKey, wantAttempt)
+		}
+		return msg
+	}
+	report := func(msg *types.StageNextMessage, success bool) {
+		t.Helper()
+		if _, _, err := st.UpdateStageResult(ctx, types.StageResultMessage{
+			PipelineID:     msg.PipelineID,
+			StageID:        msg.StageID,
+			Result:         "out",
+			IsSuccess:      success,
+			IdempotencyKey: msg.IdempotencyKey,
+		}); err != nil {
+			t.Fatalf("UpdateStageResult() error = %v", err)
+		}
+	}
+	stageState := func(stageID int) (status string, attempt int) {
+		t.Helper()
+		if err := db.QueryRow(`SELECT status, COALESCE(retry_attempt, 0) FROM stage WHERE id = $1`, stageID).
+			Scan(&status, &attempt); err != nil {
+			t.Fatalf("load stage: %v", err)
+		}
+		return status, attempt
+	}
+
+	first := claim(0)
+	if _, err := db.Exec(`INSERT INTO stage_options (stage_id, max_retries, retry_interval) VALUES ($1, 1, 1)`, first.StageID); err != nil {
+		t.Fatalf("insert stage options: %v", err)
+	}
+	report(first, false)
+	if status, attempt := stageState(first.StageID); status != types.StageStatusRetryScheduled || attempt != 1 {
+		t.Fatalf("stage = %s attempt %d, want RetryScheduled attempt 1", status, attempt)
+	}
+
+	// A stage run straight from the broker records no dispatch key, so only
+	// the applied keys tell a redelivered result of the first attempt apart.
+	if _, err := db.Exec(`UPDATE stage SET status = $2, dispatch_key = NULL WHERE id = $1`,
+		first.StageID, types.StageStatusRunning); err != nil {
+		t.Fatalf("restart stage: %v", err)
+	}
+	report(first, false)
+	if status, attempt := stageState(first.StageID); status != types.StageStatusRunning || attempt != 1 {
+		t.Fatalf("stage = %s attempt %d after a duplicate result, want Running attempt 1", status, attempt)
+	}
+
+	// Dispatched again: the first attempt's result is stale, the second's is
+	// applied.
+	if _, err := db.Exec(`UPDATE stage SET status = $2, next_retry_at = NOW() - INTERVAL '1 minute' WHERE id = $1`,
+		first.StageID, types.StageStatusRetryScheduled); err != nil {
+		t.Fatalf("schedule retry: %v", err)
+	}
+	second := claim(1)
+	if second.IdempotencyKey == first.IdempotencyKey {
+		t.Fatalf("retry reused idempotency key %q", first.IdempotencyKey)
+	}
+	report(first, true)
+	if status, _ := stageState(first.StageID); status != types.StageStatusPending {
+		t.Fatalf("stage = %s after a stale result, want Pending", status)
+	}
+	report(second, true)
+	if status, _ := stageState(first.StageID); status != types.StageStatusCompleted {
+		t.Fatalf("stage = %s, want Completed", status)
+	}
+
+	var keys int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM stage_result_key WHERE stage_id = $1`, first.StageID).Scan(&keys); err != nil {
+		t.Fatalf("count result keys: %v", err)
+	}
+	if keys != 2 {
+		t.Fatalf("recorded %d result keys, want one per applied attempt", keys)
+	}
+}
@@ -0,0 +1,155 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"pipelogiq/internal/types"
+)
+
+const (
+	// maxStageSLABreachesPerCheck bounds how many stages one
+	// DetectStageSLABreaches call reports.
+	maxStageSLABreachesPerCheck = 500
+	// minStageSLABaselineSamples is how many completed runs a handler needs
+	// before its median duration is trusted as a baseline.
+	minStageSLABaselineSamples = 5
+)
+
+// Where the expected duration of a StageSLABreachEvent comes from.
+const (
+	StageSLABasisExpectedDuration = "expected_duration"
+	StageSLABasisBaseline         = "baseline"
+)
+
+// DetectStageSLABreaches finds Pending and Running stages that have run for
+// longer than factor times their expected duration and emits a
+// StageSLABreachEvent for each to the sinks implementing StageSLAAlertSink.
+// The expected duration is the stage's own expected_duration_ms option or,
+// without one, the median duration of the last baselineSamples completed
+// runs of the same handler in the same application; handlers with fewer than
+// minStageSLABaselineSamples runs have no baseline. Unlike
+// MarkPendingTooLong it never changes a stage's status. Each dispatch is
+// reported at most once: its started_at is recorded on the stage in the same
+// statement, so several worker replicas do not report it twice. It returns
+// the number of stages reported.
+func (s *Store) DetectStageSLABreaches(ctx context.Context, factor float64, baselineSamples int) (int, error) {
+	var rows []struct {
+		PipelineID    int       `db:"pipeline_id"`
+		PipelineName  string    `db:"pipeline_name"`
+		ApplicationID int       `db:"application_id"`
+		StageID       int       `db:"stage_id"`
+		StageName     string    `db:"stage_name"`
+		StageHandler  string    `db:"stage_handler_name"`
+		StageStatus   string    `db:"stage_status"`
+		StartedAt     time.Time `db:"started_at"`
+		ExpectedMs    float64   `db:"expected_ms"`
+		ElapsedMs     float64   `db:"elapsed_ms"`
+		Basis         string    `db:"basis"`
+	}
+	if err := s.db.SelectContext(ctx, &rows, `
+		UPDATE stage s
+		SET sla_breach_started_at = breach.started_at
+		FROM (
+			SELECT
+				cur.id,
+				cur.started_at,
+				cur.pipeline_id,
+				COALESCE(p.name, '') AS pipeline_name,
+				COALESCE(p.application_id, 0) AS application_id,
+				expected.expected_ms,
+				expected.basis,
+				EXTRACT(EPOCH FROM (NOW() - cur.started_at)) * 1000 AS elapsed_ms
+			FROM stage cur
+			JOIN pipeline p ON p.id = cur.pipeline_id
+			LEFT JOIN LATERAL (
+				SELECT expected_duration_ms FROM stage_options WHERE stage_id = cur.id ORDER BY id DESC LIMIT 1
+			) so ON true
+			-- The baseline is only computed for stages without their own
+			-- expected duration.
+			LEFT JOIN LATERAL (
+				SELECT
+					percentile_cont(0.5) WITHIN GROUP (ORDER BY recent.duration_ms) AS median_ms,
+					COUNT(*) AS samples
+				FROM (
+					SELECT EXTRACT(EPOCH FROM (h.finished_at - h.started_at)) * 1000 AS duration_ms
+					FROM stage h
+					JOIN pipeline hp ON hp.id = h.pipeline_id
+					WHERE h.stage_handler_name = cur.stage_handler_name
+					  AND hp.application_id IS NOT DISTINCT FROM p.application_id
+					  AND h.status = $3
+					  AND h.started_at IS NOT NULL
+					  AND h.finished_at >= h.started_at
+					ORDER BY h.finished_at DESC
+					LIMIT $4
+				) recent
+			) baseline ON COALESCE(so.expected_duration_ms, 0) <= 0
+			CROSS JOIN LATERAL (
+				SELECT
+					CASE
+						WHEN so.expected_duration_ms > 0 THEN so.expected_duration_ms::float8
+						WHEN baseline.samples >= $5 THEN baseline.median_ms
+					END AS expected_ms,
+					CASE
+						WHEN so.expected_duration_ms > 0 THEN $6
+						ELSE $7
+					END AS basis
+			) expected
+			WHERE cur.status IN ($1, $2)
+			  AND cur.started_at IS NOT NULL
+			  AND COALESCE(cur.is_event, false) = false
+			  AND cur.sla_breach_started_at IS DISTINCT FROM cur.started_at
+			  AND expected.expected_ms > 0
+			  AND NOW() - cur.started_at > make_interval(secs => expected.expected_ms * $8 / 1000)
+			ORDER BY cur.started_at
+			LIMIT $9
+		) breach
+		WHERE s.id = breach.id
+		  -- Rechecked against the latest row version, so a replica that
+		  -- waited on this row does not report the same dispatch again.
+		  AND s.started_at = breach.started_at
+		  AND s.sla_breach_started_at IS DISTINCT FROM s.started_at
+		RETURNING
+			breach.pipeline_id,
+			breach.pipeline_name,
+			breach.application_id,
+			s.id AS stage_id,
+			COALESCE(s.name, '') AS stage_name,
+			COALESCE(s.stage_handler_name, '') AS stage_handler_name,
+			s.status AS stage_status,
+			s.started_at,
+			breach.expected_ms,
+			breach.elapsed_ms,
+			breach.basis
+	`, types.StageStatusPending, types.StageStatusRunning, types.StageStatusCompleted,
+		baselineSamples, minStageSLABaselineSamples,
+		StageSLABasisExpectedDuration, StageSLABasisBaseline,
+		factor, maxStageSLABreachesPerCheck); err != nil {
+		return 0, fmt.Errorf("detect stage sla breaches: %w", err)
+	}
+
+	now := time.Now().UTC()
+	for _, row := range rows {
+		s.emitStageSLABreachAlert(StageSLABreachEvent{
+			PipelineID:    row.PipelineID,
+			PipelineName:  row.PipelineName,
+			ApplicationID: row.ApplicationID,
+			StageID:       row.StageID,
+			StageName:     row.StageName,
+			StageHandler:  row.StageHandler,
+			StageStatus:   row.StageStatus,
+			StartedAt:     row.StartedAt.UTC(),
+			Expected:      msDuration(row.ExpectedMs),
+			Elapsed:       msDuration(row.ElapsedMs),
+			Basis:         row.Basis,
+			Factor:        factor,
+			TS:            now,
+		})
+	}
+	return len(rows), nil
+}
+
+func msDuration(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond)).Round(time.Millisecond)
+}
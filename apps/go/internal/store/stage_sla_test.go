@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"pipelogiq/internal/types"
+)
+
+type stageSLASink struct {
+	events chan StageSLABreachEvent
+}
+
+func (*stageSLASink) NotifyStageChange(context.Context, StageAlertEvent)  {}
+func (*stageSLASink) NotifyWorkerEvent(context.Context, WorkerAlertEvent) {}
+
+func (s *stageSLASink) NotifyStageSLABreach(_ context.Context, event StageSLABreachEvent) {
+	s.events <- event
+}
+
+func TestDetectStageSLABreaches(t *testing.T) {
+	db := setupPostgresTestDB(t)
+	ctx := context.Background()
+	st := New(db, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	sink := &stageSLASink{events: make(chan StageSLABreachEvent, 10)}
+	st.SetAlertSink(sink)
+
+	var pipelineID int
+	if err := db.QueryRow(`
+		INSERT INTO pipeline (application_id, name, status) VALUES (1, 'sla', $1) RETURNING id
+	`, types.PipelineStatusRunning).Scan(&pipelineID); err != nil {
+		t.Fatalf("insert pipeline: %v", err)
+	}
+	insertStage := func(name, handler, status string, startedAgo, ranFor time.Duration) int {
+		t.Helper()
+		var id int
+		var finishedAt *time.Time
+		startedAt := time.Now().Add(-startedAgo)
+		if ranFor > 0 {
+			finished := startedAt.Add(ranFor)
+			finishedAt = &finished
+		}
+		if err := db.QueryRow(`
+			INSERT INTO stage (pipeline_id, name, stage_handler_name, status, started_at, finished_at)
+			VALUES ($1, $2, $3, $4, $5, $6) RETURNING id
+		`, pipelineID, name, handler, status, startedAt, finishedAt).Scan(&id); err != nil {
+			t.Fatalf("insert stage: %v", err)
+		}
+		return id
+	}
+
+	// Baseline of "resize": a median of one second over five runs, with a
+	// failed run that is not counted.
+	for range minStageSLABaselineSamples {
+		insertStage("history", "resize", types.StageStatusCompleted, time.Hour, time.Second)
+	}
+	insertStage("history", "resize", types.StageStatusFailed, time.Hour, time.Minute)
+
+	slow := insertStage("slow", "resize", types.StageStatusRunning, 10*time.Second, 0)
+	withinExpected := insertStage("expected", "resize", types.StageStatusRunning, 10*time.Second, 0)
+	if _, err := db.Exec(`INSERT INTO stage_options (stage_id, expected_duration_ms) VALUES ($1, 60000)`, withinExpected); err != nil {
+		t.Fatalf("insert stage options: %v", err)
+	}
+	overExpected := insertStage("over", "render", types.StageStatusPending, 10*time.Second, 0)
+	if _, err := db.Exec(`INSERT INTO stage_options (stage_id, expected_duration_ms) VALUES ($1, 2000)`, overExpected); err != nil {
+		t.Fatalf("insert stage options: %v", err)
+	}
+	// No baseline yet and no expected duration.
+	insertStage("fresh", "thumbnail", types.StageStatusRunning, time.Hour, 0)
+
+	reported, err := st.DetectStageSLABreaches(ctx, 2, 50)
+	if err != nil || reported != 2 {
+		t.Fatalf("DetectStageSLABreaches() = %d, %v, want 2", reported, err)
+	}
+	events := map[int]StageSLABreachEvent{}
+	for range reported {
+		select {
+		case event := <-sink.events:
+			events[event.StageID] = event
+		case <-time.After(time.Second):
+			t.Fatal("sink was not notified")
+		}
+	}
+	if event := events[slow]; event.Basis != StageSLABasisBaseline || event.Expected != time.Second || event.Elapsed < 10*time.Second {
+		t.Fatalf("slow stage event = %+v, want baseline of 1s", event)
+	}
+	if event := events[overExpected]; event.Basis != StageSLABasisExpectedDuration || event.Expected != 2*time.Second {
+		t.Fatalf("over stage event = %+v, want expected duration of 2s", event)
+	}
+	var status string
+	if err := db.QueryRow(`SELECT status FROM stage WHERE id = $1`, slow).Scan(&status); err != nil {
+		t.Fatalf("load stage: %v", err)
+	}
+	if status != types.StageStatusRunning {
+		t.Fatalf("slow stage status = %s, want it left Running", status)
+	}
+
+	// Each dispatch is reported once.
+	if reported, err := st.DetectStageSLABreaches(ctx, 2, 50); err != nil || reported != 0 {
+		t.Fatalf("second DetectStageSLABreaches() = %d, %v, want 0", reported, err)
+	}
+
+	// A new dispatch of the same stage is reported again.
+	if _, err := db.Exec(`UPDATE stage SET started_at = $1 WHERE id = $2`, time.Now().Add(-5*time.Second), slow); err != nil {
+		t.Fatalf("redispatch stage: %v", err)
+	}
+	if reported, err := st.DetectStageSLABreaches(ctx, 2, 50); err != nil || reported != 1 {
+		t.Fatalf("DetectStageSLABreaches() after redispatch = %d, %v, want 1", reported, err)
+	}
+}
@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"pipelogiq/internal/types"
+)
+
+var errInvalidStageTimeoutAction = errors.New("invalid stage timeout action")
+
+func IsInvalidStageTimeoutActionError(err error) bool {
+	return errors.Is(err, errInvalidStageTimeoutAction)
+}
+
+// TimeoutActionFunc returns the timeout action a policy sets for stageID, or
+// "" when no policy applies. MarkPendingTooLong consults it only for stages
+// without an onTimeout option of their own.
+type TimeoutActionFunc func(ctx context.Context, stageID int) string
+
+// normalizeStageTimeoutAction checks a stage's onTimeout option; nil means
+// the watcher falls back to policies.
+func normalizeStageTimeoutAction(action *string) (*string, error) {
+	if action == nil {
+		return nil, nil
+	}
+	v := strings.ToLower(strings.TrimSpace(*action))
+	if v != types.TimeoutActionFail && v != types.TimeoutActionRetry {
+		return nil, fmt.Errorf("%w: onTimeout must be %s or %s, got %q",
+			errInvalidStageTimeoutAction, types.TimeoutActionFail, types.TimeoutActionRetry, *action)
+	}
+	return &v, nil
+}
+
+// stageTimeoutAction resolves what happens to a stage that exceeded its
+// timeout: its own on_timeout option wins, then the action policy returns,
+// then TimeoutActionFail. policy may be nil.
+func stageTimeoutAction(option sql.NullString, policy func() string) string {
+	if option.Valid && option.String != "" {
+		return option.String
+	}
+	if policy != nil && policy() == types.TimeoutActionRetry {
+		return types.TimeoutActionRetry
+	}
+	return types.TimeoutActionFail
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"database/sql"
+	"testing"
+
+	"pipelogiq/internal/types"
+)
+
+func TestStageTimeoutAction(t *testing.T) {
+	option := func(v string) sql.NullString { return sql.NullString{String: v, Valid: true} }
+	policy := func(v string) func() string { return func() string { return v } }
+	cases := []struct {
+		name   string
+		option sql.NullString
+		policy func() string
+		want   string
+	}{
+		{name: "default", want: types.TimeoutActionFail},
+		{name: "no policy applies", policy: policy(""), want: types.TimeoutActionFail},
+		{name: "policy retries", policy: policy(types.TimeoutActionRetry), want: types.TimeoutActionRetry},
+		{name: "policy fails", policy: policy(types.TimeoutActionFail), want: types.TimeoutActionFail},
+		{name: "option retries", option: option(types.TimeoutActionRetry), want: types.TimeoutActionRetry},
+		{name: "option overrides policy", option: option(types.TimeoutActionFail), policy: policy(types.TimeoutActionRetry), want: types.TimeoutActionFail},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := stageTimeoutAction(tc.option, tc.policy); got != tc.want {
+				t.Fatalf("stageTimeoutAction() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeStageTimeoutAction(t *testing.T) {
+	for _, in := range []string{"fail", " Retry "} {
+		if _, err := normalizeStageTimeoutAction(&in); err != nil {
+			t.Errorf("normalizeStageTimeoutAction(%q) error = %v", in, err)
+		}
+	}
+	bad := "skip"
+	if _, err := normalizeStageTimeoutAction(&bad); !IsInvalidStageTimeoutActionError(err) {
+		t.Errorf("normalizeStageTimeoutAction(%q) error = %v, want invalid stage timeout action", bad, err)
+	}
+	if got, err := normalizeStageTimeoutAction(nil); got != nil || err != nil {
+		t.Errorf("normalizeStageTimeoutAction(nil) = %v, %v, want nil, nil", got, err)
+	}
+}
@@ -11,19 +11,31 @@ import (
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 
 	"pipelogiq/internal/types"
 )
 
 type Store struct {
-	db        *sqlx.DB
-	logger    *slog.Logger
-	alertSink AlertSink
+	db                  *sqlx.DB
+	logger              *slog.Logger
+	alertSinks          []AlertSink
+	workerOfflineAfter  time.Duration
+	stageOutputMaxBytes int
+	stageResultObserver StageResultObserver
+	maxStageAttempts    int
+	retryPolicy         RetryPolicyFunc
 }
 
 func New(db *sqlx.DB, logger *slog.Logger) *Store {
-	return &Store{db: db, logger: logger}
+	return &Store{
+		db:                  db,
+		logger:              logger,
+		workerOfflineAfter:  defaultWorkerOfflineAfter,
+		stageOutputMaxBytes: defaultStageOutputMaxBytes,
+		maxStageAttempts:    defaultMaxStageAttempts,
+	}
 }
 
 type AlertSink interface {
@@ -39,6 +51,8 @@ type StageAlertEvent struct {
 	OldStatus    string
 	NewStatus    string
 	Source       string
+	// RetryAttempt is the stage's retry_attempt after the change.
+	RetryAttempt int
 	TS           time.Time
 }
 
@@ -49,10 +63,81 @@ type WorkerAlertEvent struct {
 	EventType string
 	Message   string
 	Details   map[string]any
+	// BootstrappedAt is when the worker's current session bootstrapped; zero if unknown.
+	BootstrappedAt time.Time
+	// ApplicationID and ApplicationName identify the worker's application;
+	// ApplicationID is zero if unknown.
+	ApplicationID   int
+	ApplicationName string
+}
+
+// PipelineAlertSink is implemented by alert sinks that also want
+// stuck-pipeline alerts; see DetectStuckPipelines.
+type PipelineAlertSink interface {
+	NotifyPipelineStuck(ctx context.Context, event PipelineStuckEvent)
+}
+
+type PipelineStuckEvent struct {
+	PipelineID    int
+	PipelineName  string
+	ApplicationID int
+	// The pipeline's first stage that is neither completed nor skipped, if any.
+	StageID      int
+	StageName    string
+	StageStatus  string
+	StageHandler string
+	LastChangeAt time.Time
+	TS           time.Time
+}
+
+// APIKeyAlertSink is implemented by alert sinks that also want API key
+// expiry alerts; see DetectExpiringAPIKeys.
+type APIKeyAlertSink interface {
+	NotifyAPIKeyExpiring(ctx context.Context, event APIKeyExpiringEvent)
+}
+
+type APIKeyExpiringEvent struct {
+	APIKeyID        int
+	KeyName         string
+	ApplicationID   int
+	ApplicationName string
+	ExpiresAt       time.Time
+	DaysRemaining   int
+	TS              time.Time
+}
+
+// StageSLAAlertSink is implemented by alert sinks that also want alerts on
+// stages running past their expected duration; see DetectStageSLABreaches.
+type StageSLAAlertSink interface {
+	NotifyStageSLABreach(ctx context.Context, event StageSLABreachEvent)
+}
+
+type StageSLABreachEvent struct {
+	PipelineID    int
+	PipelineName  string
+	ApplicationID int
+	StageID       int
+	StageName     string
+	StageHandler  string
+	StageStatus   string
+	StartedAt     time.Time
+	// Expected is the stage's expected duration and Basis where it comes
+	// from: StageSLABasisExpectedDuration or StageSLABasisBaseline.
+	Expected time.Duration
+	Basis    string
+	Elapsed  time.Duration
+	Factor   float64
+	TS       time.Time
 }
 
 func (s *Store) SetAlertSink(sink AlertSink) {
-	s.alertSink = sink
+	s.alertSinks = []AlertSink{sink}
+}
+
+// AddAlertSink registers an additional sink; every sink receives each event.
+// Sinks must be registered before the store is used concurrently.
+func (s *Store) AddAlertSink(sink AlertSink) {
+	s.alertSinks = append(s.alertSinks, sink)
 }
 
 // DB returns the underlying sqlx.DB for direct queries.
@@ -61,25 +146,65 @@ func (s *Store) DB() *sqlx.DB {
 }
 
 func (s *Store) emitStageAlert(event StageAlertEvent) {
-	if s.alertSink == nil {
-		return
+	for _, sink := range s.alertSinks {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			sink.NotifyStageChange(ctx, event)
+		}()
 	}
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		s.alertSink.NotifyStageChange(ctx, event)
-	}()
 }
 
 func (s *Store) emitWorkerAlert(event WorkerAlertEvent) {
-	if s.alertSink == nil {
-		return
+	for _, sink := range s.alertSinks {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			sink.NotifyWorkerEvent(ctx, event)
+		}()
+	}
+}
+
+func (s *Store) emitPipelineStuckAlert(event PipelineStuckEvent) {
+	for _, sink := range s.alertSinks {
+		pipelineSink, ok := sink.(PipelineAlertSink)
+		if !ok {
+			continue
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			pipelineSink.NotifyPipelineStuck(ctx, event)
+		}()
+	}
+}
+
+func (s *Store) emitAPIKeyExpiringAlert(event APIKeyExpiringEvent) {
+	for _, sink := range s.alertSinks {
+		keySink, ok := sink.(APIKeyAlertSink)
+		if !ok {
+			continue
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			keySink.NotifyAPIKeyExpiring(ctx, event)
+		}()
+	}
+}
+
+func (s *Store) emitStageSLABreachAlert(event StageSLABreachEvent) {
+	for _, sink := range s.alertSinks {
+		slaSink, ok := sink.(StageSLAAlertSink)
+		if !ok {
+			continue
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			slaSink.NotifyStageSLABreach(ctx, event)
+		}()
 	}
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		s.alertSink.NotifyWorkerEvent(ctx, event)
-	}()
 }
 
 func cloneAlertDetailsMap(input map[string]any) map[string]any {
@@ -93,29 +218,54 @@ func cloneAlertDetailsMap(input map[string]any) map[string]any {
 	return cloned
 }
 
-// ValidateAPIKey returns application id for a valid API key.
-func (s *Store) ValidateAPIKey(ctx context.Context, key string) (int, error) {
+// ValidateAPIKey returns the application id and scopes for a valid API key.
+// Keys of disabled applications are rejected.
+func (s *Store) ValidateAPIKey(ctx context.Context, key string) (*types.APIKeyAuth, error) {
 	if strings.TrimSpace(key) == "" {
-		return 0, errors.New("api key required")
+		return nil, errors.New("api key required")
 	}
 	var appID int
+	var scopes sql.NullString
 	err := s.db.QueryRowContext(ctx, `
-		SELECT application_id
-		FROM api_key
-		WHERE key=$1
-		  AND disabled_at IS NULL
-		  AND (expires_at IS NULL OR expires_at > NOW())
+		SELECT k.application_id, k.scopes
+		FROM api_key k
+		JOIN application a ON a.id = k.application_id
+		WHERE k.key=$1
+		  AND k.disabled_at IS NULL
+		  AND (k.expires_at IS NULL OR k.expires_at > NOW())
+		  AND a.disabled_at IS NULL
 		LIMIT 1
-	`, key).Scan(&appID)
+	`, key).Scan(&appID, &scopes)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return 0, errors.New("api key not found or disabled")
+			return nil, errors.New("api key not found or disabled")
 		}
-		return 0, err
+		return nil, err
 	}
 
 	_, _ = s.db.ExecContext(ctx, `UPDATE api_key SET last_used=NOW() WHERE key=$1`, key)
-	return appID, nil
+	return &types.APIKeyAuth{ApplicationID: appID, Scopes: splitList(scopes.String)}, nil
+}
+
+// APIKeyActive reports whether key would pass ValidateAPIKey, without
+// recording its use.
+func (s *Store) APIKeyActive(ctx context.Context, key string) (bool, error) {
+	var active bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM api_key k
+			JOIN application a ON a.id = k.application_id
+			WHERE k.key=$1
+			  AND k.disabled_at IS NULL
+			  AND (k.expires_at IS NULL OR k.expires_at > NOW())
+			  AND a.disabled_at IS NULL
+		)
+	`, key).Scan(&active)
+	if err != nil {
+		return false, fmt.Errorf("check api key: %w", err)
+	}
+	return active, nil
 }
 
 // CreatePipeline inserts pipeline, stages, keywords and context items in a single transaction.
@@ -131,14 +281,22 @@ func (s *Store) CreatePipeline(ctx context.Context, req types.PipelineCreateRequ
 	}()
 
 	traceID := resolveTraceID(req.TraceID, req.PipelineContext)
+	var labels string
+	if labels, err = encodePipelineLabels(req.Labels); err != nil {
+		return nil, err
+	}
+	var priority int
+	if priority, err = validatePipelinePriority(req.Priority); err != nil {
+		return nil, err
+	}
 
 	var pipelineID int
 	var createdAt time.Time
 	err = tx.QueryRowContext(ctx, `
-		INSERT INTO pipeline (application_id, name, status, created_at, is_completed, trace_id)
-		VALUES ($1, $2, $3, NOW(), false, $4)
+		INSERT INTO pipeline (application_id, name, status, created_at, is_completed, trace_id, labels, priority)
+		VALUES ($1, $2, $3, NOW(), false, $4, $5::jsonb, $6)
 		RETURNING id, created_at
-	`, appID, req.Name, types.PipelineStatusNotStarted, traceID).Scan(&pipelineID, &createdAt)
+	`, appID, req.Name, types.PipelineStatusNotStarted, traceID, labels, priority).Scan(&pipelineID, &createdAt)
 	if err != nil {
 		return nil, fmt.Errorf("insert pipeline: %w", err)
 	}
@@ -149,7 +307,15 @@ func (s *Store) CreatePipeline(ctx context.Context, req types.PipelineCreateRequ
 	if err = s.insertContextItems(ctx, tx, pipelineID, req.PipelineContext); err != nil {
 		return nil, err
 	}
-	if err = s.insertStages(ctx, tx, pipelineID, req.Stages); err != nil {
+	stages := req.Stages
+	var defaultRetries bool
+	if defaultRetries, err = featureEnabled(ctx, tx, appID, types.FeatureFlagDefaultStageRetries); err != nil {
+		return nil, err
+	}
+	if defaultRetries {
+		stages = withDefaultRetries(stages)
+	}
+	if err = s.insertStages(ctx, tx, pipelineID, stages); err != nil {
 		return nil, err
 	}
 
@@ -187,11 +353,15 @@ func (s *Store) insertKeywords(ctx context.Context, tx *sqlx.Tx, pipelineID int,
 }
 
 func (s *Store) insertContextItems(ctx context.Context, tx *sqlx.Tx, pipelineID int, contextItems []types.ContextItem) error {
+	contextItems, err := normalizeContextItems(contextItems)
+	if err != nil {
+		return err
+	}
 	for _, item := range contextItems {
 		if _, err := tx.ExecContext(ctx, `
 			INSERT INTO pipeline_context_item (key, value, value_type, pipeline_id)
 			VALUES ($1, $2, $3, $4)
-		`, item.Key, item.Value, valueTypeOrDefault(item.ValueType), pipelineID); err != nil {
+		`, item.Key, item.Value, item.ValueType, pipelineID); err != nil {
 			return fmt.Errorf("insert context item %s: %w", item.Key, err)
 		}
 	}
@@ -199,6 +369,9 @@ func (s *Store) insertContextItems(ctx context.Context, tx *sqlx.Tx, pipelineID
 }
 
 func (s *Store) insertStages(ctx context.Context, tx *sqlx.Tx, pipelineID int, stages []types.StageCreate) error {
+	if err := validateStageDependencies(stages); err != nil {
+		return err
+	}
 	for _, st := range stages {
 		b := make([]byte, 8)
 		_, _ = rand.Read(b)
@@ -236,13 +409,19 @@ func (s *Store) insertStageOptions(ctx context.Context, tx *sqlx.Tx, stageID int
 		return nil
 	}
 
-	_, err := tx.ExecContext(ctx, `
+	onTimeout, err := normalizeStageTimeoutAction(opt.OnTimeout)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
 		INSERT INTO stage_options
-			(run_next_if_failed, retry_interval, time_out, max_retries, depends_on, run_in_parallel_with, fail_if_output_empty, notify_on_failure, run_as_user, stage_id)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+			(run_next_if_failed, retry_interval, time_out, max_retries, depends_on, run_in_parallel_with, fail_if_output_empty, notify_on_failure, run_as_user, stage_id, max_attempts, on_timeout, expected_duration_ms)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
 	`, opt.RunNextIfFailed, opt.RetryInterval, opt.TimeOut, opt.MaxRetries,
 		joinList(opt.DependsOn), joinList(opt.RunInParallelWith),
-		opt.FailIfOutputEmpty, opt.NotifyOnFailure, opt.RunAsUser, stageID)
+		opt.FailIfOutputEmpty, opt.NotifyOnFailure, opt.RunAsUser, stageID, opt.MaxAttempts, onTimeout,
+		opt.ExpectedDurationMs)
 	return err
 }
 
@@ -251,11 +430,14 @@ func allNilStageOptions(opt *types.StageOptions) bool {
 		opt.RetryInterval == nil &&
 		opt.TimeOut == nil &&
 		opt.MaxRetries == nil &&
+		opt.MaxAttempts == nil &&
 		len(opt.DependsOn) == 0 &&
 		len(opt.RunInParallelWith) == 0 &&
 		opt.FailIfOutputEmpty == nil &&
 		opt.NotifyOnFailure == nil &&
-		opt.RunAsUser == nil
+		opt.RunAsUser == nil &&
+		opt.OnTimeout == nil &&
+		opt.ExpectedDurationMs == nil
 }
 
 func joinList(list []string) *string {
@@ -284,10 +466,13 @@ func (s *Store) GetPipeline(ctx context.Context, pipelineID int) (*types.Pipelin
 		FinishedAt    *time.Time `db:"finished_at"`
 		IsCompleted   bool       `db:"is_completed"`
 		ApplicationID *int       `db:"application_id"`
+		Labels        []byte     `db:"labels"`
+		Priority      int        `db:"priority"`
 	}
 
 	if err := s.db.GetContext(ctx, &row, `
-		SELECT id, name, COALESCE(trace_id, '') AS trace_id, status, created_at, finished_at, is_completed, application_id
+		SELECT id, name, COALESCE(trace_id, '') AS trace_id, status, created_at, finished_at, is_completed, application_id,
+			labels, priority
 		FROM pipeline WHERE id=$1
 	`, pipelineID); err != nil {
 		return nil, err
@@ -318,6 +503,8 @@ func (s *Store) GetPipeline(ctx context.Context, pipelineID int) (*types.Pipelin
 		FinishedAt:    row.FinishedAt,
 		ApplicationID: row.ApplicationID,
 		StageStatuses: states,
+		Labels:        decodePipelineLabels(row.Labels),
+		Priority:      row.Priority,
 		IsEvent:       isEvent,
 	}, nil
 }
@@ -333,6 +520,16 @@ func (s *Store) GetPipelineWithStages(ctx context.Context, pipelineID int) (*typ
 		s.logger.Error("get pipeline stages failed", "pipelineId", pipelineID, "err", err)
 	} else {
 		pipeline.Stages = stages
+		appID := 0
+		if pipeline.ApplicationID != nil {
+			appID = *pipeline.ApplicationID
+		}
+		if dag, err := s.scheduledAsDAG(ctx, appID, stages); err != nil {
+			s.logger.Error("check dag execution failed", "pipelineId", pipelineID, "err", err)
+		} else {
+			// Derived from the /graph view so both show the same edges.
+			pipeline.StageGraph = stageAdjacency(buildPipelineGraph(stages, dag, false))
+		}
 	}
 	ctxItems, err := s.GetPipelineContext(ctx, pipelineID)
 	if err != nil {
@@ -394,6 +591,7 @@ func (s *Store) getPipelineIsEvent(ctx context.Context, pipelineID int) *bool {
 func computePipelineStatus(stageStatuses []string) string {
 	hasFailed := false
 	hasRunning := false
+	hasCancelled := false
 	allFinished := len(stageStatuses) > 0
 	allNotStarted := len(stageStatuses) > 0
 
@@ -408,6 +606,9 @@ func computePipelineStatus(stageStatuses []string) string {
 			allFinished = false
 		case types.StageStatusCompleted, types.StageStatusSkipped:
 			allNotStarted = false
+		case types.StageStatusCancelled:
+			hasCancelled = true
+			allNotStarted = false
 		case types.StageStatusNotStarted:
 			allFinished = false
 		default:
@@ -417,6 +618,8 @@ func computePipelineStatus(stageStatuses []string) string {
 	}
 
 	switch {
+	case hasCancelled && !hasRunning:
+		return types.PipelineStatusCancelled
 	case hasFailed && !hasRunning:
 		return types.PipelineStatusFailed
 	case allFinished && !hasFailed:
@@ -444,10 +647,14 @@ func (s *Store) GetPipelineStages(ctx context.Context, pipelineID int) ([]types.
 			s.created_at AS created_at,
 			s.finished_at AS finished_at,
 			s.started_at AS started_at,
+			s.next_retry_at AS next_retry_at,
 			s.is_skipped AS is_skipped,
 			s.is_event AS is_event,
 			io.input AS input,
-			io.output AS output
+			io.output AS output,
+			COALESCE(io.output_truncated, false) AS output_truncated,
+			io.output_bytes AS output_bytes,
+			s.failure_detail AS failure_detail
 		FROM stage s
 		LEFT JOIN stage_io io ON io.stage_id = s.id
 		WHERE s.pipeline_id=$1
@@ -463,6 +670,10 @@ func (s *Store) GetPipelineStages(ctx context.Context, pipelineID int) ([]types.
 		}
 	}
 
+	if err := s.resolveStageLinks(ctx, rows); err != nil {
+		return nil, err
+	}
+
 	return rows, nil
 }
 
@@ -476,8 +687,99 @@ func (s *Store) GetPipelineContext(ctx context.Context, pipelineID int) ([]types
 	return items, nil
 }
 
+// eligibleStagesQuery selects every stage that may be dispatched now, with
+// its pipeline id. Parameters $1-$6 are the stage statuses and feature flag
+// bound by eligibleStagesArgs.
+const eligibleStagesQuery = `
+	SELECT s.id, s.pipeline_id
+	FROM stage s
+	JOIN pipeline p ON p.id = s.pipeline_id
+	-- Pipelines that declare dependencies are scheduled as a DAG unless
+	-- the application turned the dag_execution flag (default on) off.
+	CROSS JOIN LATERAL (
+		SELECT EXISTS (
+			SELECT 1 FROM stage sd
+			JOIN stage_options sod ON sod.stage_id = sd.id
+			WHERE sd.pipeline_id = p.id AND COALESCE(sod.depends_on, '') <> ''
+		) AND NOT EXISTS (
+			SELECT 1 FROM application_feature_flag ff
+			WHERE ff.application_id = p.application_id AND ff.flag = $6 AND ff.enabled = false
+		) AS dag
+	) sched
+	WHERE p.is_completed = false
+	  AND (
+		-- NotStarted stages may be deferred by a concurrency limit.
+		(s.status = $1 AND (s.next_retry_at IS NULL OR s.next_retry_at <= NOW()))
+		OR (s.status = $3 AND s.next_retry_at IS NOT NULL AND s.next_retry_at <= NOW())
+	  )
+	  AND COALESCE(s.is_skipped,false) = false
+	  AND COALESCE(s.is_event,false) = false
+	  AND NOT EXISTS (
+		SELECT 1 FROM stage sp WHERE sp.pipeline_id = p.id AND sp.status = $2
+	  )
+	  AND (
+		-- DAG: a stage is eligible once every stage it depends on is
+		-- done. An unknown dependency name is never satisfied.
+		(
+		  sched.dag
+		  AND NOT EXISTS (
+			SELECT 1
+			FROM stage_options so
+			CROSS JOIN LATERAL unnest(string_to_array(so.depends_on, ',')) AS dep(name)
+			WHERE so.stage_id = s.id
+			  AND TRIM(dep.name) <> ''
+			  AND NOT EXISTS (
+				SELECT 1 FROM stage sdep
+				WHERE sdep.pipeline_id = p.id
+				  AND sdep.name = TRIM(dep.name)
+				  AND sdep.status IN ($4, $5)
+			  )
+		  )
+		)
+		-- Otherwise stages run in id order.
+		OR (
+		  NOT sched.dag
+		  AND NOT EXISTS (
+			SELECT 1 FROM stage sb
+			WHERE sb.pipeline_id = p.id
+			  AND sb.id < s.id
+			  AND COALESCE(sb.is_event,false) = false
+			  AND sb.status NOT IN ($4, $5)
+		  )
+		)
+	  )`
+
+func eligibleStagesArgs(extra ...any) []any {
+	return append([]any{
+		types.StageStatusNotStarted, types.StageStatusPending, types.StageStatusRetryScheduled,
+		types.StageStatusCompleted, types.StageStatusSkipped, types.FeatureFlagDAGExecution,
+	}, extra...)
+}
+
 // GetStageToExecute picks the next stage atomically and marks it Pending.
+// Stages locked by another publisher are skipped rather than waited on, so
+// worker replicas polling concurrently claim distinct stages.
 func (s *Store) GetStageToExecute(ctx context.Context) (*types.StageNextMessage, error) {
+	msgs, err := s.GetStagesToExecute(ctx, 1)
+	if err != nil || len(msgs) == 0 {
+		return nil, err
+	}
+	return msgs[0], nil
+}
+
+// GetStagesToExecute claims up to limit eligible stages in one transaction
+// and marks them Pending. At most one stage is claimed per pipeline, its
+// lowest-id eligible stage, so ordering and blocking within a pipeline are
+// unchanged. Pipelines are served highest priority first, then oldest first.
+// Rows locked by another publisher are skipped.
+//
+// Stages whose handler has a reported capacity (see handlerCapacityQuery)
+// are held back while the handler's Pending and Running stages fill it.
+func (s *Store) GetStagesToExecute(ctx context.Context, limit int) ([]*types.StageNextMessage, error) {
+	if limit < 1 {
+		limit = 1
+	}
+
 	tx, err := s.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
 	if err != nil {
 		return nil, err
@@ -488,44 +790,89 @@ func (s *Store) GetStageToExecute(ctx context.Context) (*types.StageNextMessage,
 		}
 	}()
 
-	var stageID int
-	err = tx.QueryRowContext(ctx, `
-		WITH candidate AS (
-			SELECT s.id
-			FROM stage s
-			JOIN pipeline p ON p.id = s.pipeline_id
-			WHERE p.is_completed = false
-			  AND (
-				s.status = $1
-				OR (s.status = $3 AND s.next_retry_at IS NOT NULL AND s.next_retry_at <= NOW())
-			  )
-			  AND COALESCE(s.is_skipped,false) = false
-			  AND COALESCE(s.is_event,false) = false
-			  AND NOT EXISTS (
-				SELECT 1 FROM stage sp WHERE sp.pipeline_id = p.id AND sp.status = $2
-			  )
-			  AND NOT EXISTS (
-				SELECT 1 FROM stage sb
-				WHERE sb.pipeline_id = p.id
-				  AND sb.id < s.id
-				  AND COALESCE(sb.is_event,false) = false
-				  AND sb.status NOT IN ($4, $5)
-			  )
-			ORDER BY p.id, s.id
-			LIMIT 1
+	// The status check is repeated on the locked row: when another publisher
+	// claimed the stage after this snapshot was taken, Postgres re-evaluates
+	// it against the committed version and drops the row.
+	var candidates []struct {
+		ID            int           `db:"id"`
+		ApplicationID int           `db:"application_id"`
+		Handler       string        `db:"handler"`
+		Remaining     sql.NullInt64 `db:"remaining"`
+	}
+	args := append(eligibleStagesArgs(limit, types.StageStatusRunning), s.handlerCapacityArgs()...)
+	err = tx.SelectContext(ctx, &candidates, `
+		SELECT s.id, p.application_id, COALESCE(s.stage_handler_name, '') AS handler, cap.remaining
+		FROM stage s
+		JOIN pipeline p ON p.id = s.pipeline_id
+		LEFT JOIN LATERAL (
+			SELECT hc.max_concurrency - (
+				SELECT COUNT(*) FROM stage sd
+				JOIN pipeline pd ON pd.id = sd.pipeline_id
+				WHERE pd.application_id = hc.application_id
+				  AND sd.stage_handler_name = hc.handler
+				  AND sd.status IN ($2, $8)
+			) AS remaining
+			FROM (`+handlerCapacityQuery(9)+`
+			) hc
+			WHERE hc.application_id = p.application_id
+			  AND hc.handler = s.stage_handler_name
+			  AND hc.max_concurrency IS NOT NULL
+		) cap ON true
+		WHERE s.id IN (
+			SELECT DISTINCT ON (e.pipeline_id) e.id
+			FROM (`+eligibleStagesQuery+`
+			) e
+			ORDER BY e.pipeline_id, e.id
 		)
-		SELECT id FROM candidate
-	`, types.StageStatusNotStarted, types.StageStatusPending, types.StageStatusRetryScheduled,
-		types.StageStatusCompleted, types.StageStatusSkipped).Scan(&stageID)
-
-	if errors.Is(err, sql.ErrNoRows) {
+		  AND s.status IN ($1, $3)
+		  AND (cap.remaining IS NULL OR cap.remaining > 0)
+		ORDER BY p.priority DESC, s.pipeline_id
+		LIMIT $7
+		FOR UPDATE OF s SKIP LOCKED
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
 		_ = tx.Commit()
 		return nil, nil
 	}
-	if err != nil {
+
+	// Several candidates may share a handler; admit only as many as its
+	// remaining capacity. The rest stay eligible for a later poll.
+	admitted := map[string]int64{}
+	msgs := make([]*types.StageNextMessage, 0, len(candidates))
+	fromStatuses := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		if candidate.Remaining.Valid {
+			key := fmt.Sprintf("%d/%s", candidate.ApplicationID, candidate.Handler)
+			if admitted[key] >= candidate.Remaining.Int64 {
+				continue
+			}
+			admitted[key]++
+		}
+		msg, fromStatus, claimErr := s.claimStageTx(ctx, tx, candidate.ID)
+		if claimErr != nil {
+			err = claimErr
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+		fromStatuses = append(fromStatuses, fromStatus)
+	}
+
+	if err = tx.Commit(); err != nil {
 		return nil, err
 	}
 
+	for i, msg := range msgs {
+		s.LogStageChange(ctx, *msg.PipelineID, msg.StageID, fromStatuses[i], types.StageStatusPending, "publisher")
+	}
+	return msgs, nil
+}
+
+// claimStageTx locks stageID, marks it Pending and its pipeline Running, and
+// builds the StageNext message. It also returns the stage's previous status.
+func (s *Store) claimStageTx(ctx context.Context, tx *sqlx.Tx, stageID int) (*types.StageNextMessage, string, error) {
 	var row struct {
 		StageID          int            `db:"id"`
 		PipelineID       int            `db:"pipeline_id"`
@@ -535,43 +882,44 @@ func (s *Store) GetStageToExecute(ctx context.Context) (*types.StageNextMessage,
 		ApplicationID    sql.NullInt64  `db:"application_id"`
 		TraceID          sql.NullString `db:"trace_id"`
 		SpanID           sql.NullString `db:"span_id"`
+		RetryAttempt     int            `db:"retry_attempt"`
 	}
 
-	err = tx.GetContext(ctx, &row, `
+	if err := tx.GetContext(ctx, &row, `
 		SELECT s.id, s.pipeline_id, s.status AS stage_status, s.stage_handler_name, io.input, p.application_id,
-			p.trace_id, s.span_id
+			p.trace_id, s.span_id, COALESCE(s.retry_attempt, 0) AS retry_attempt
 		FROM stage s
 		JOIN pipeline p ON p.id = s.pipeline_id
 		LEFT JOIN stage_io io ON io.stage_id = s.id
 		WHERE s.id = $1
-		FOR UPDATE OF s
-	`, stageID)
-	if err != nil {
-		return nil, err
+		FOR UPDATE OF s SKIP LOCKED
+	`, stageID); err != nil {
+		return nil, "", err
 	}
 
-	if _, err = tx.ExecContext(ctx, `
+	if _, err := tx.ExecContext(ctx, `
 		UPDATE pipeline SET status=$1 WHERE id=$2
 	`, types.PipelineStatusRunning, row.PipelineID); err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	if _, err = tx.ExecContext(ctx, `
-		UPDATE stage SET status=$1, started_at=NOW(), finished_at=NULL, next_retry_at=NULL WHERE id=$2
-	`, types.StageStatusPending, row.StageID); err != nil {
-		return nil, err
+	idempotencyKey := NewStageIdempotencyKey(row.StageID, row.RetryAttempt)
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE stage
+		SET status=$1, started_at=NOW(), finished_at=NULL, next_retry_at=NULL, dispatch_key=$3, lease_expires_at=NULL
+		WHERE id=$2
+	`, types.StageStatusPending, row.StageID, idempotencyKey); err != nil {
+		return nil, "", err
 	}
 
 	ctxItems, err := s.getContextItemsTx(ctx, tx, row.PipelineID)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-
-	if err = tx.Commit(); err != nil {
-		return nil, err
+	keywords, err := s.getKeywordsTx(ctx, tx, row.PipelineID)
+	if err != nil {
+		return nil, "", err
 	}
 
-	s.LogStageChange(ctx, row.PipelineID, row.StageID, row.StageStatus, types.StageStatusPending, "publisher")
-
 	appID := int(row.ApplicationID.Int64)
 	msg := &types.StageNextMessage{
 		AppID:            appID,
@@ -582,8 +930,17 @@ func (s *Store) GetStageToExecute(ctx context.Context) (*types.StageNextMessage,
 		StageHandlerName: row.StageHandlerName.String,
 		Input:            row.Input.String,
 		ContextItems:     ctxItems,
+		Attempt:          row.RetryAttempt,
+		IdempotencyKey:   idempotencyKey,
+		Environment:      PipelineEnvironment(ctxItems, keywords),
 	}
-	return msg, nil
+	return msg, row.StageStatus, nil
+}
+
+// NewStageIdempotencyKey returns a key unique to a single dispatch of a stage.
+// Workers echo it back with the result; see UpdateStageResult.
+func NewStageIdempotencyKey(stageID, attempt int) string {
+	return fmt.Sprintf("%d:%d:%s", stageID, attempt, uuid.NewString())
 }
 
 func (s *Store) getContextItemsTx(ctx context.Context, tx *sqlx.Tx, pipelineID int) ([]types.ContextItem, error) {
@@ -596,62 +953,237 @@ func (s *Store) getContextItemsTx(ctx context.Context, tx *sqlx.Tx, pipelineID i
 	return items, nil
 }
 
-func (s *Store) MarkPendingTooLong(ctx context.Context, olderThan time.Duration) (int64, error) {
-	rows, err := s.db.QueryxContext(ctx, `
-		SELECT s.id, s.pipeline_id, EXTRACT(EPOCH FROM (NOW() - COALESCE(s.started_at, s.created_at))) AS age_seconds
-		FROM stage s
-		JOIN pipeline p ON p.id = s.pipeline_id
-		WHERE p.is_completed = false
-		  AND s.status = $1
-		  AND (NOW() - COALESCE(s.started_at, s.created_at)) >= $2::interval
-	`, types.StageStatusPending, olderThan.String())
+// ListStageHandlerNames returns the distinct handler names of all stages.
+func (s *Store) ListStageHandlerNames(ctx context.Context) ([]string, error) {
+	handlers := []string{}
+	if err := s.db.SelectContext(ctx, &handlers, `
+		SELECT DISTINCT stage_handler_name
+		FROM stage
+		WHERE COALESCE(stage_handler_name, '') <> ''
+		ORDER BY stage_handler_name
+	`); err != nil {
+		return nil, err
+	}
+	return handlers, nil
+}
+
+// MarkPendingTooLong handles stages that exceeded their timeout.
+// A stage's own stage_options.time_out (seconds) takes precedence over olderThan.
+// Pending stages fall back to olderThan; Running stages only time out when
+// they have an explicit time_out.
+//
+// A timed-out stage fails along with its pipeline, unless its timeout action
+// is TimeoutActionRetry and its max_retries, retry_interval and attempt cap
+// leave it another attempt: then it is scheduled for retry like a failed
+// result. The action comes from the stage's on_timeout option, else from
+// policyAction, which may be nil. It returns the number of stages failed and
+// retried.
+func (s *Store) MarkPendingTooLong(ctx context.Context, olderThan time.Duration, policyAction TimeoutActionFunc) (failed, retried int64, err error) {
+	var rows []struct {
+		ID             int            `db:"id"`
+		PipelineID     int            `db:"pipeline_id"`
+		Status         string         `db:"status"`
+		AgeSeconds     float64        `db:"age_seconds"`
+		TimeoutSeconds int64          `db:"timeout_seconds"`
+		RetryAttempt   int            `db:"retry_attempt"`
+		RetryInterval  sql.NullInt64  `db:"retry_interval"`
+		MaxRetries     sql.NullInt64  `db:"max_retries"`
+		MaxAttempts    sql.NullInt64  `db:"max_attempts"`
+		OnTimeout      sql.NullString `db:"on_timeout"`
+	}
+	if err = s.db.SelectContext(ctx, &rows, `
+		SELECT id, pipeline_id, status, age_seconds, timeout_seconds, retry_attempt,
+			retry_interval, max_retries, max_attempts, on_timeout
+		FROM (
+			SELECT
+				s.id,
+				s.pipeline_id,
+				s.status,
+				EXTRACT(EPOCH FROM (NOW() - COALESCE(s.started_at, s.created_at))) AS age_seconds,
+				CASE
+					WHEN so.time_out IS NOT NULL AND so.time_out > 0 THEN so.time_out
+					WHEN s.status = $1 THEN $3
+				END AS timeout_seconds,
+				COALESCE(s.retry_attempt, 0) AS retry_attempt,
+				so.retry_interval,
+				so.max_retries,
+				so.max_attempts,
+				so.on_timeout
+			FROM stage s
+			JOIN pipeline p ON p.id = s.pipeline_id
+			LEFT JOIN LATERAL (
+				SELECT time_out, retry_interval, max_retries, max_attempts, on_timeout
+				FROM stage_options WHERE stage_id = s.id ORDER BY id DESC LIMIT 1
+			) so ON true
+			WHERE p.is_completed = false
+			  AND s.status IN ($1, $2)
+		) candidates
+		WHERE timeout_seconds IS NOT NULL
+		  AND age_seconds >= timeout_seconds
+	`, types.StageStatusPending, types.StageStatusRunning, int64(olderThan.Seconds())); err != nil {
+		return 0, 0, err
+	}
+
+	for _, row := range rows {
+		msg := fmt.Sprintf("Stage has been pending for too long - %.0f seconds (timeout %ds)", row.AgeSeconds, row.TimeoutSeconds)
+		reason := "pending_timeout"
+		if row.Status == types.StageStatusRunning {
+			msg = fmt.Sprintf("Stage timed out after %.0f seconds (timeout %ds)", row.AgeSeconds, row.TimeoutSeconds)
+			reason = "run_timeout"
+		}
+
+		retry, capped := false, false
+		var policy func() string
+		if policyAction != nil {
+			stageID := row.ID
+			policy = func() string { return policyAction(ctx, stageID) }
+		}
+		if stageTimeoutAction(row.OnTimeout, policy) == types.TimeoutActionRetry {
+			scheduled := row.MaxRetries.Int64 > 0 && row.RetryInterval.Int64 > 0 && int64(row.RetryAttempt) < row.MaxRetries.Int64
+			retry, capped = allowStageRetry(scheduled, row.RetryAttempt, stageAttemptCap(s.maxStageAttempts, row.MaxAttempts))
+		}
+		if capped {
+			reason = maxAttemptsExceededReason
+		}
+
+		age := int64(row.AgeSeconds)
+		timeoutSeconds := row.TimeoutSeconds
+		failure := marshalStageFailure(types.StageFailure{
+			Category:       types.StageFailureTimeout,
+			Reason:         reason,
+			AgeSeconds:     &age,
+			TimeoutSeconds: &timeoutSeconds,
+			At:             time.Now().UTC(),
+		})
+
+		var ok bool
+		if retry {
+			retryAfter := time.Duration(row.RetryInterval.Int64) * time.Second
+			msg = fmt.Sprintf("%s; retry %d of %d scheduled in %s", msg, row.RetryAttempt+1, row.MaxRetries.Int64, retryAfter)
+			ok, err = s.retryTimedOutStage(ctx, row.ID, row.Status, time.Now().UTC().Add(retryAfter), failure, msg)
+		} else {
+			ok, err = s.failTimedOutStage(ctx, row.ID, row.PipelineID, row.Status, failure, msg)
+		}
+		if err != nil {
+			return failed, retried, err
+		}
+		// The stage reported a result since the scan; leave it alone.
+		if !ok {
+			continue
+		}
+		if retry {
+			s.LogStageChange(ctx, row.PipelineID, row.ID, row.Status, types.StageStatusRetryScheduled, "pending_watcher")
+			retried++
+		} else {
+			s.LogStageChange(ctx, row.PipelineID, row.ID, row.Status, types.StageStatusFailed, "pending_watcher")
+			failed++
+		}
+	}
+
+	return failed, retried, nil
+}
+
+// failTimedOutStage fails a timed-out stage and its pipeline unless the stage
+// left status since the scan.
+func (s *Store) failTimedOutStage(ctx context.Context, stageID, pipelineID int, status, failure, msg string) (bool, error) {
+	tx, err := s.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
 	if err != nil {
-		return 0, err
-	}
-	defer rows.Close()
-
-	var count int64
-	for rows.Next() {
-		var stageID, pipelineID int
-		var ageSeconds float64
-		if err := rows.Scan(&stageID, &pipelineID, &ageSeconds); err != nil {
-			return count, err
-		}
-		msg := fmt.Sprintf("Stage has been pending for too long - %.0f seconds", ageSeconds)
-		tx, errTx := s.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
-		if errTx != nil {
-			return count, errTx
-		}
-		_, errTx = tx.ExecContext(ctx, `
-				UPDATE stage SET status=$1, finished_at=NOW(), next_retry_at=NULL WHERE id=$2
-			`, types.StageStatusFailed, stageID)
-		if errTx == nil {
-			_, errTx = tx.ExecContext(ctx, `UPDATE pipeline SET is_completed=true, status=$2 WHERE id=$1`, pipelineID, types.PipelineStatusFailed)
-		}
-		if errTx == nil {
-			_, errTx = tx.ExecContext(ctx, `
-				UPDATE stage_io SET output=$1 WHERE stage_id=$2
-			`, msg, stageID)
-		}
-		if errTx != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
 			_ = tx.Rollback()
-			return count, errTx
 		}
-		if errTx = tx.Commit(); errTx != nil {
-			return count, errTx
-		}
-		s.LogStageChange(ctx, pipelineID, stageID, types.StageStatusPending, types.StageStatusFailed, "pending_watcher")
-		count++
+	}()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE stage
+		SET status=$1, finished_at=NOW(), next_retry_at=NULL, failure_category=$4, failure_detail=$5
+		WHERE id=$2 AND status=$3
+	`, types.StageStatusFailed, stageID, status, types.StageFailureTimeout, failure)
+	if err != nil {
+		return false, err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		_ = tx.Rollback()
+		return false, nil
 	}
+	if _, err = tx.ExecContext(ctx, `UPDATE pipeline SET is_completed=true, status=$2 WHERE id=$1`, pipelineID, types.PipelineStatusFailed); err != nil {
+		return false, err
+	}
+	if _, err = tx.ExecContext(ctx, `
+		UPDATE stage_io SET output=$1, output_truncated=false, output_bytes=NULL WHERE stage_id=$2
+	`, msg, stageID); err != nil {
+		return false, err
+	}
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
 
-	return count, nil
+// retryTimedOutStage schedules a timed-out stage for retry at nextRetryAt,
+// using up an attempt, unless the stage left status since the scan. Its
+// pipeline keeps running. A late result of the timed-out attempt is dropped
+// by UpdateStageResult.
+func (s *Store) retryTimedOutStage(ctx context.Context, stageID int, status string, nextRetryAt time.Time, failure, msg string) (bool, error) {
+	tx, err := s.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE stage
+		SET status=$1, finished_at=NOW(), retry_attempt=COALESCE(retry_attempt, 0) + 1, next_retry_at=$4,
+			lease_expires_at=NULL, failure_category=$5, failure_detail=$6
+		WHERE id=$2 AND status=$3
+	`, types.StageStatusRetryScheduled, stageID, status, nextRetryAt, types.StageFailureTimeout, failure)
+	if err != nil {
+		return false, err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		_ = tx.Rollback()
+		return false, nil
+	}
+	if _, err = tx.ExecContext(ctx, `
+		UPDATE stage_io SET output=$1, output_truncated=false, output_bytes=NULL WHERE stage_id=$2
+	`, msg, stageID); err != nil {
+		return false, err
+	}
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 // UpdateStageResult persists stage result and returns updated pipeline snapshot.
-func (s *Store) UpdateStageResult(ctx context.Context, msg types.StageResultMessage) (*types.PipelineResponse, error) {
+// completed reports whether this result moved the pipeline to a terminal
+// status; duplicates and stale results never report it.
+func (s *Store) UpdateStageResult(ctx context.Context, msg types.StageResultMessage) (pipeline *types.PipelineResponse, completed bool, err error) {
+	// A mistyped context item is dropped rather than failing the result, so
+	// the stage still finishes.
+	contextItems, rejected := partitionContextItems(msg.ContextItems)
+	for _, itemErr := range rejected {
+		s.logger.Warn("invalid context item dropped from stage result", "stageId", msg.StageID, "err", itemErr)
+	}
+
+	// The retry policy is resolved before the transaction: the resolver reads
+	// through the pool, and doing so while holding the stage's row lock and a
+	// pooled connection can exhaust the pool. Results that may turn into
+	// failures (failures and empty outputs) are the only ones that need it.
+	var retryRule *types.PolicyRule
+	if s.retryPolicy != nil && (!msg.IsSuccess || strings.TrimSpace(msg.Result) == "") {
+		retryRule = s.retryPolicy(ctx, msg.StageID)
+	}
+
 	tx, err := s.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	defer func() {
 		if err != nil {
@@ -666,8 +1198,15 @@ func (s *Store) UpdateStageResult(ctx context.Context, msg types.StageResultMess
 		StagePayload  sql.NullString `db:"input"`
 		ExistingOut   sql.NullString `db:"output"`
 		RetryAttempt  int            `db:"retry_attempt"`
+		RetryDelayMs  sql.NullInt64  `db:"retry_delay_ms"`
 		RetryInterval sql.NullInt64  `db:"retry_interval"`
 		MaxRetries    sql.NullInt64  `db:"max_retries"`
+		MaxAttempts   sql.NullInt64  `db:"max_attempts"`
+		FailIfEmpty   sql.NullBool   `db:"fail_if_output_empty"`
+		ApplicationID sql.NullInt64  `db:"application_id"`
+		Handler       sql.NullString `db:"stage_handler_name"`
+		StartedAt     sql.NullTime   `db:"started_at"`
+		DispatchKey   sql.NullString `db:"dispatch_key"`
 	}
 
 	err = tx.GetContext(ctx, &stage, `
@@ -678,9 +1217,17 @@ func (s *Store) UpdateStageResult(ctx context.Context, msg types.StageResultMess
 			io.input,
 			io.output,
 			COALESCE(s.retry_attempt, 0) AS retry_attempt,
+			s.retry_delay_ms,
 			so.retry_interval,
-			so.max_retries
+			so.max_retries,
+			so.max_attempts,
+			so.fail_if_output_empty,
+			p.application_id,
+			s.stage_handler_name,
+			s.started_at,
+			s.dispatch_key
 		FROM stage s
+		JOIN pipeline p ON p.id = s.pipeline_id
 		LEFT JOIN stage_io io ON io.stage_id = s.id
 		LEFT JOIN stage_options so ON so.stage_id = s.id
 		WHERE s.id = $1
@@ -689,19 +1236,69 @@ func (s *Store) UpdateStageResult(ctx context.Context, msg types.StageResultMess
 		FOR UPDATE OF s
 	`, msg.StageID)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	// idempotency: process only active stage executions.
+	// idempotency: process only active stage executions. Results for stages
+	// that were cancelled while a worker held them are dropped here.
 	if stage.Status != types.StageStatusPending && stage.Status != types.StageStatusRunning {
 		err = tx.Commit()
 		if err != nil {
-			return nil, err
+			return nil, false, err
+		}
+		pipeline, err = s.GetPipeline(ctx, stage.PipelineID)
+		return pipeline, false, err
+	}
+
+	// idempotency: drop a result of an earlier dispatch of a stage that has
+	// been dispatched again since, e.g. after its worker was presumed dead.
+	if msg.IdempotencyKey != "" && stage.DispatchKey.Valid && stage.DispatchKey.String != msg.IdempotencyKey {
+		if err = tx.Commit(); err != nil {
+			return nil, false, err
+		}
+		s.logger.Info("stale stage result ignored", "stageId", msg.StageID, "idempotencyKey", msg.IdempotencyKey)
+		pipeline, err = s.GetPipeline(ctx, stage.PipelineID)
+		return pipeline, false, err
+	}
+
+	// idempotency: apply a result at most once per dispatched attempt. The
+	// status guard above does not catch a redelivered result that lands after
+	// the stage has been re-dispatched for a retry.
+	if msg.IdempotencyKey != "" {
+		var res sql.Result
+		res, err = tx.ExecContext(ctx, `
+			INSERT INTO stage_result_key (stage_id, idempotency_key, applied_at)
+			VALUES ($1,$2,NOW())
+			ON CONFLICT (stage_id, idempotency_key) DO NOTHING
+		`, msg.StageID, msg.IdempotencyKey)
+		if err != nil {
+			return nil, false, fmt.Errorf("record stage result key: %w", err)
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			if err = tx.Commit(); err != nil {
+				return nil, false, err
+			}
+			s.logger.Info("duplicate stage result ignored", "stageId", msg.StageID, "idempotencyKey", msg.IdempotencyKey)
+			pipeline, err = s.GetPipeline(ctx, stage.PipelineID)
+			return pipeline, false, err
+		}
+	}
+
+	failureReason := ""
+	if msg.IsSuccess && stage.FailIfEmpty.Bool && strings.TrimSpace(msg.Result) == "" {
+		var enforce bool
+		if enforce, err = featureEnabled(ctx, tx, int(stage.ApplicationID.Int64), types.FeatureFlagFailIfOutputEmpty); err != nil {
+			return nil, false, err
+		}
+		if enforce {
+			s.logger.Info("stage returned empty output, treating as failure", "stageId", msg.StageID)
+			msg.IsSuccess = false
+			failureReason = "empty_output"
 		}
-		return s.GetPipeline(ctx, stage.PipelineID)
 	}
 
 	newStatus := types.StageStatusFailed
+	attemptCap, attemptsExceeded := 0, false
 	if msg.IsSuccess {
 		newStatus = types.StageStatusCompleted
 	} else {
@@ -714,33 +1311,81 @@ func (s *Store) UpdateStageResult(ctx context.Context, msg types.StageResultMess
 			retryIntervalSeconds = int(stage.RetryInterval.Int64)
 		}
 
-		if maxRetries > 0 && retryIntervalSeconds > 0 && stage.RetryAttempt < maxRetries {
+		scheduled := maxRetries > 0 && retryIntervalSeconds > 0 && stage.RetryAttempt < maxRetries
+		attemptCap = stageAttemptCap(s.maxStageAttempts, stage.MaxAttempts)
+		var retry bool
+		retry, attemptsExceeded = allowStageRetry(scheduled, stage.RetryAttempt, attemptCap)
+		if retry {
 			newStatus = types.StageStatusRetryScheduled
 		}
+		if attemptsExceeded {
+			failureReason = maxAttemptsExceededReason
+		}
+	}
+
+	// A failure, retried or final, records its category; success clears it.
+	var failureCategory, failureDetail *string
+	if !msg.IsSuccess {
+		category := types.NormalizeStageFailureCategory(msg.FailureCategory)
+		detail := marshalStageFailure(types.StageFailure{Category: category, Reason: failureReason, At: time.Now().UTC()})
+		failureCategory, failureDetail = &category, &detail
 	}
 
 	if newStatus == types.StageStatusRetryScheduled {
-		retryAfter := int(stage.RetryInterval.Int64)
-		nextRetryAt := time.Now().UTC().Add(time.Duration(retryAfter) * time.Second)
+		// The stage's retry_interval spaces its retries unless a retry
+		// policy sets the backoff.
+		backoff := stageRetryBackoff(time.Duration(stage.RetryInterval.Int64)*time.Second, retryRule)
+		prev := time.Duration(stage.RetryDelayMs.Int64) * time.Millisecond
+		retryAfter := backoff.delay(stage.RetryAttempt+1, prev, retryJitterSource)
+		nextRetryAt := time.Now().UTC().Add(retryAfter)
 		if _, err = tx.ExecContext(ctx, `
 			UPDATE stage
-			SET status=$1, finished_at=NOW(), retry_attempt=retry_attempt + 1, next_retry_at=$2
+			SET status=$1, finished_at=NOW(), retry_attempt=retry_attempt + 1, next_retry_at=$2,
+				failure_category=$4, failure_detail=$5, retry_delay_ms=$6
 			WHERE id=$3
-		`, newStatus, nextRetryAt, msg.StageID); err != nil {
-			return nil, err
+		`, newStatus, nextRetryAt, msg.StageID, failureCategory, failureDetail, retryAfter.Milliseconds()); err != nil {
+			return nil, false, err
 		}
 	} else {
 		if _, err = tx.ExecContext(ctx, `
-			UPDATE stage SET status=$1, finished_at=NOW(), next_retry_at=NULL WHERE id=$2
-		`, newStatus, msg.StageID); err != nil {
-			return nil, err
+			UPDATE stage SET status=$1, finished_at=NOW(), next_retry_at=NULL, failure_category=$3, failure_detail=$4
+			WHERE id=$2
+		`, newStatus, msg.StageID, failureCategory, failureDetail); err != nil {
+			return nil, false, err
 		}
 	}
 
+	output, truncated := truncateStageOutput(msg.Result, s.stageOutputMaxBytes)
+	var outputBytes *int
+	if truncated {
+		size := len(msg.Result)
+		outputBytes = &size
+	}
 	if _, err = tx.ExecContext(ctx, `
-		UPDATE stage_io SET output=$1 WHERE stage_id=$2
-	`, msg.Result, msg.StageID); err != nil {
-		return nil, err
+		UPDATE stage_io SET output=$1, output_truncated=$2, output_bytes=$3 WHERE stage_id=$4
+	`, output, truncated, outputBytes, msg.StageID); err != nil {
+		return nil, false, err
+	}
+	if truncated {
+		s.logger.Warn("stage output truncated", "stageId", msg.StageID, "bytes", len(msg.Result), "maxBytes", s.stageOutputMaxBytes)
+		if _, err = tx.ExecContext(ctx, `
+			INSERT INTO stage_log (log, log_level, created_at, stage_id)
+			VALUES ($1,$2,$3,$4)
+		`, fmt.Sprintf("Stage output of %d bytes exceeded the %d byte limit and was truncated", len(msg.Result), s.stageOutputMaxBytes),
+			"Warning", time.Now().UTC(), msg.StageID); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if attemptsExceeded {
+		s.logger.Warn("stage failed after reaching its attempt cap", "stageId", msg.StageID, "attempts", stage.RetryAttempt+1, "maxAttempts", attemptCap)
+		if _, err = tx.ExecContext(ctx, `
+			INSERT INTO stage_log (log, log_level, created_at, stage_id)
+			VALUES ($1,$2,$3,$4)
+		`, fmt.Sprintf("Stage failed permanently after %d attempts: the cap of %d attempts was reached before its remaining retries", stage.RetryAttempt+1, attemptCap),
+			"Warning", time.Now().UTC(), msg.StageID); err != nil {
+			return nil, false, err
+		}
 	}
 
 	for _, log := range msg.Logs {
@@ -748,26 +1393,25 @@ func (s *Store) UpdateStageResult(ctx context.Context, msg types.StageResultMess
 			INSERT INTO stage_log (log, log_level, created_at, stage_id)
 			VALUES ($1,$2,$3,$4)
 		`, log.Message, log.LogLevel, log.Created, msg.StageID); err != nil {
-			return nil, err
+			return nil, false, err
 		}
 	}
 
-	for _, item := range msg.ContextItems {
-		valueType := valueTypeOrDefault(item.ValueType)
+	for _, item := range contextItems {
 		res, errExec := tx.ExecContext(ctx, `
 			UPDATE pipeline_context_item SET value=$1, value_type=$2
 			WHERE pipeline_id=$3 AND key=$4
-		`, item.Value, valueType, stage.PipelineID, item.Key)
+		`, item.Value, item.ValueType, stage.PipelineID, item.Key)
 		if errExec != nil {
-			return nil, errExec
+			return nil, false, errExec
 		}
 		affected, _ := res.RowsAffected()
 		if affected == 0 {
 			if _, errExec = tx.ExecContext(ctx, `
 				INSERT INTO pipeline_context_item (key, value, value_type, pipeline_id)
 				VALUES ($1,$2,$3,$4)
-			`, item.Key, item.Value, valueType, stage.PipelineID); errExec != nil {
-				return nil, errExec
+			`, item.Key, item.Value, item.ValueType, stage.PipelineID); errExec != nil {
+				return nil, false, errExec
 			}
 		}
 	}
@@ -776,17 +1420,25 @@ func (s *Store) UpdateStageResult(ctx context.Context, msg types.StageResultMess
 		if _, err = tx.ExecContext(ctx, `
 			UPDATE pipeline SET is_completed=false, finished_at=NULL, status=$2 WHERE id=$1
 		`, stage.PipelineID, types.PipelineStatusRunning); err != nil {
-			return nil, err
+			return nil, false, err
 		}
 	} else {
-		// Mark pipeline completed when failed or when this is last stage.
-		var lastStageID int
-		if err = tx.GetContext(ctx, &lastStageID, `SELECT MAX(id) FROM stage WHERE pipeline_id=$1`, stage.PipelineID); err != nil {
-			return nil, err
+		// Mark pipeline completed when failed or when no other stage is left to run.
+		// Dependency-ordered pipelines may finish their highest-id stage first.
+		var remaining int
+		if err = tx.GetContext(ctx, &remaining, `
+			SELECT COUNT(*) FROM stage
+			WHERE pipeline_id=$1
+			  AND id <> $2
+			  AND COALESCE(is_event,false) = false
+			  AND COALESCE(is_skipped,false) = false
+			  AND status NOT IN ($3, $4)
+		`, stage.PipelineID, msg.StageID, types.StageStatusCompleted, types.StageStatusSkipped); err != nil {
+			return nil, false, err
 		}
 
-		completePipeline := !msg.IsSuccess || msg.StageID == lastStageID
-		if completePipeline {
+		completed = !msg.IsSuccess || remaining == 0
+		if completed {
 			pStatus := types.PipelineStatusCompleted
 			if !msg.IsSuccess {
 				pStatus = types.PipelineStatusFailed
@@ -794,25 +1446,26 @@ func (s *Store) UpdateStageResult(ctx context.Context, msg types.StageResultMess
 			if _, err = tx.ExecContext(ctx, `
 				UPDATE pipeline SET is_completed=true, finished_at=NOW(), status=$2 WHERE id=$1
 			`, stage.PipelineID, pStatus); err != nil {
-				return nil, err
+				return nil, false, err
 			}
 		}
 	}
 
 	if err = tx.Commit(); err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	s.LogStageChange(ctx, stage.PipelineID, msg.StageID, stage.Status, newStatus, "result_consumer")
-
-	return s.GetPipelineWithStages(ctx, stage.PipelineID)
-}
-
-func valueTypeOrDefault(vt string) string {
-	if vt == "" {
-		return "string"
+	if s.stageResultObserver != nil {
+		outcome := StageResultOutcome{StageID: msg.StageID, Handler: stage.Handler.String, Status: newStatus}
+		if stage.StartedAt.Valid {
+			outcome.Duration = max(time.Now().UTC().Sub(stage.StartedAt.Time.UTC()), 0)
+		}
+		s.stageResultObserver.ObserveStageResult(outcome)
 	}
-	return vt
+
+	pipeline, err = s.GetPipelineWithStages(ctx, stage.PipelineID)
+	return pipeline, completed, err
 }
 
 // UpdateStageStatus updates status and returns pipeline snapshot.
@@ -0,0 +1,251 @@
+// Package storetest provides the Postgres database the store and API tests
+// run against.
+package storetest
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+)
+
+// DatabaseEnv names a Postgres database the tests may create a scratch
+// schema in. SKIP LOCKED and the other Postgres-only queries have no SQLite
+// equivalent, so the tests are skipped when it is unset.
+const DatabaseEnv = "PIPELOGIQ_TEST_DATABASE_URL"
+
+// NewDB creates a scratch schema with the tables the store uses in the
+// database named by DatabaseEnv, and returns a connection to it. The schema
+// is dropped when the test ends. The test is skipped when DatabaseEnv is
+// unset.
+func NewDB(t testing.TB) *sqlx.DB {
+	t.Helper()
+
+	dsn := os.Getenv(DatabaseEnv)
+	if dsn == "" {
+		t.Skipf("%s not set", DatabaseEnv)
+	}
+
+	admin, err := sqlx.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+	schema := fmt.Sprintf("store_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		_ = admin.Close()
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		_, _ = admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`)
+		_ = admin.Close()
+	})
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("parse %s: %v", DatabaseEnv, err)
+	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+
+	db, err := sqlx.Open("pgx", u.String())
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	ddl := `
+	CREATE TABLE pipeline (
+		id SERIAL PRIMARY KEY,
+		application_id INT,
+		name TEXT,
+		status TEXT,
+		is_completed BOOLEAN NOT NULL DEFAULT false,
+		finished_at TIMESTAMPTZ,
+		trace_id TEXT,
+		priority INT NOT NULL DEFAULT 0,
+		labels JSONB NOT NULL DEFAULT '{}',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE TABLE stage (
+		id SERIAL PRIMARY KEY,
+		pipeline_id INT NOT NULL REFERENCES pipeline(id),
+		name TEXT,
+		stage_handler_name TEXT,
+		description TEXT,
+		status TEXT NOT NULL,
+		is_skipped BOOLEAN,
+		is_event BOOLEAN,
+		span_id TEXT,
+		retry_attempt INT,
+		next_retry_at TIMESTAMPTZ,
+		retry_delay_ms BIGINT,
+		started_at TIMESTAMPTZ,
+		finished_at TIMESTAMPTZ,
+		dispatch_key TEXT,
+		lease_expires_at TIMESTAMPTZ,
+		failure_category TEXT,
+		failure_detail TEXT,
+		sla_breach_started_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE TABLE stage_options (
+		id SERIAL PRIMARY KEY,
+		stage_id INT NOT NULL,
+		run_next_if_failed BOOLEAN,
+		retry_interval INT,
+		depends_on TEXT,
+		run_in_parallel_with TEXT,
+		time_out INT,
+		max_retries INT,
+		max_attempts INT,
+		fail_if_output_empty BOOLEAN,
+		notify_on_failure BOOLEAN,
+		run_as_user TEXT,
+		on_timeout TEXT,
+		expected_duration_ms INT
+	);
+	CREATE TABLE stage_io (
+		id SERIAL PRIMARY KEY,
+		stage_id INT NOT NULL,
+		input TEXT,
+		output TEXT,
+		output_truncated BOOLEAN NOT NULL DEFAULT false,
+		output_bytes INT
+	);
+	CREATE TABLE stage_result_key (
+		id SERIAL PRIMARY KEY,
+		stage_id INT NOT NULL,
+		idempotency_key TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		UNIQUE (stage_id, idempotency_key)
+	);
+	CREATE TABLE dead_letter_message (
+		id SERIAL PRIMARY KEY,
+		queue TEXT NOT NULL,
+		stage_id INT,
+		pipeline_id INT,
+		handler TEXT,
+		reason TEXT NOT NULL,
+		message_id TEXT,
+		headers_json TEXT NOT NULL DEFAULT '{}',
+		dead_lettered_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE TABLE application_feature_flag (application_id INT, flag TEXT, enabled BOOLEAN);
+	CREATE TABLE "user" (
+		id SERIAL PRIMARY KEY,
+		first_name TEXT NOT NULL,
+		last_name TEXT,
+		email TEXT NOT NULL,
+		password TEXT NOT NULL DEFAULT '',
+		role TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE TABLE application (
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL,
+		description TEXT,
+		webhook_url TEXT,
+		webhook_secret TEXT,
+		disabled_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE TABLE api_key (
+		id SERIAL PRIMARY KEY,
+		user_id INT NOT NULL,
+		application_id INT NOT NULL,
+		name TEXT NOT NULL,
+		key TEXT NOT NULL,
+		scopes TEXT,
+		expires_at TIMESTAMPTZ,
+		expiry_notified_on DATE,
+		last_used TIMESTAMPTZ,
+		disabled_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE TABLE user_application (id SERIAL PRIMARY KEY, user_id INT NOT NULL, application_id INT NOT NULL);
+	CREATE TABLE keyword (id SERIAL PRIMARY KEY, key TEXT, value TEXT);
+	CREATE TABLE pipeline_keyword (pipeline_id INT, keyword_id INT);
+	CREATE TABLE pipeline_context_item (id SERIAL PRIMARY KEY, pipeline_id INT, key TEXT, value TEXT, value_type TEXT);
+	CREATE TABLE log (id SERIAL PRIMARY KEY, application_id INT, log TEXT, log_level TEXT, created_at TIMESTAMPTZ NOT NULL DEFAULT NOW());
+	CREATE TABLE stage_log (id SERIAL PRIMARY KEY, log TEXT, log_level TEXT, created_at TIMESTAMPTZ, stage_id INT);
+	CREATE TABLE stage_transition (id BIGSERIAL PRIMARY KEY, pipeline_id INT NOT NULL, stage_id INT NOT NULL, from_status TEXT, to_status TEXT NOT NULL, source TEXT NOT NULL, created_at TIMESTAMP NOT NULL DEFAULT NOW());
+	CREATE TABLE worker_client (
+		id TEXT PRIMARY KEY,
+		application_id INT NOT NULL,
+		app_runtime_id TEXT NOT NULL DEFAULT '',
+		worker_name TEXT NOT NULL DEFAULT '',
+		instance_id TEXT NOT NULL DEFAULT '',
+		worker_version TEXT,
+		sdk_version TEXT,
+		environment TEXT,
+		host_name TEXT,
+		pid INT,
+		state TEXT NOT NULL DEFAULT 'starting',
+		status_reason TEXT,
+		broker_type TEXT NOT NULL DEFAULT 'rabbitmq',
+		broker_connected BOOLEAN NOT NULL DEFAULT FALSE,
+		in_flight_jobs INT NOT NULL DEFAULT 0,
+		jobs_processed BIGINT NOT NULL DEFAULT 0,
+		jobs_failed BIGINT NOT NULL DEFAULT 0,
+		queue_lag INT,
+		cpu_percent DOUBLE PRECISION,
+		memory_mb DOUBLE PRECISION,
+		last_error TEXT,
+		supported_handlers_json TEXT NOT NULL DEFAULT '[]',
+		capabilities_json TEXT NOT NULL DEFAULT '{}',
+		metadata_json TEXT NOT NULL DEFAULT '{}',
+		session_token TEXT NOT NULL DEFAULT '',
+		session_expires_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		stopped_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		bootstrapped_at TIMESTAMPTZ,
+		bootstrap_config_json TEXT,
+		config_version TEXT
+	);
+	CREATE TABLE worker_heartbeat (
+		id SERIAL PRIMARY KEY,
+		worker_id TEXT NOT NULL,
+		ts TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		state TEXT NOT NULL,
+		broker_connected BOOLEAN NOT NULL DEFAULT FALSE,
+		in_flight_jobs INT NOT NULL DEFAULT 0,
+		jobs_processed BIGINT NOT NULL DEFAULT 0,
+		jobs_failed BIGINT NOT NULL DEFAULT 0,
+		queue_lag INT,
+		cpu_percent DOUBLE PRECISION,
+		memory_mb DOUBLE PRECISION,
+		last_error TEXT,
+		payload_json TEXT
+	);
+	CREATE TABLE worker_event (
+		id SERIAL PRIMARY KEY,
+		worker_id TEXT NOT NULL,
+		ts TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		level TEXT NOT NULL,
+		event_type TEXT NOT NULL,
+		message TEXT NOT NULL,
+		details_json TEXT
+	);
+	CREATE TABLE config_version (
+		id INT PRIMARY KEY,
+		version BIGINT NOT NULL DEFAULT 1,
+		broker_fingerprint TEXT,
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	INSERT INTO config_version (id, version) VALUES (1, 1);
+	`
+	if _, err := db.Exec(ddl); err != nil {
+		t.Fatalf("create tables: %v", err)
+	}
+	return db
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"pipelogiq/internal/types"
+)
+
+// maxStuckPipelinesPerCheck bounds how many pipelines one
+// DetectStuckPipelines call reports.
+const maxStuckPipelinesPerCheck = 500
+
+// DetectStuckPipelines finds Running pipelines none of whose stages changed
+// status for longer than olderThan and emits a PipelineStuckEvent for each to
+// the sinks implementing PipelineAlertSink. A stage change is any stage
+// being created, started or finished, or a stage_log entry, which
+// LogStageChange writes for every status transition. Unlike
+// MarkPendingTooLong it changes nothing: it also catches pipelines whose
+// current stage never became Pending. It returns the number of pipelines found.
+func (s *Store) DetectStuckPipelines(ctx context.Context, olderThan time.Duration) (int, error) {
+	var rows []struct {
+		PipelineID    int            `db:"pipeline_id"`
+		PipelineName  string         `db:"pipeline_name"`
+		ApplicationID int            `db:"application_id"`
+		LastChangeAt  time.Time      `db:"last_change_at"`
+		StageID       sql.NullInt64  `db:"stage_id"`
+		StageName     sql.NullString `db:"stage_name"`
+		StageStatus   sql.NullString `db:"stage_status"`
+		StageHandler  sql.NullString `db:"stage_handler_name"`
+	}
+	if err := s.db.SelectContext(ctx, &rows, `
+		SELECT
+			p.id AS pipeline_id,
+			COALESCE(p.name, '') AS pipeline_name,
+			COALESCE(p.application_id, 0) AS application_id,
+			activity.last_change_at,
+			cur.id AS stage_id,
+			cur.name AS stage_name,
+			cur.status AS stage_status,
+			cur.stage_handler_name
+		FROM pipeline p
+		-- GREATEST ignores NULLs, so unset timestamps and stages without logs
+		-- do not hide the others.
+		CROSS JOIN LATERAL (
+			SELECT GREATEST(
+				(SELECT MAX(GREATEST(s.created_at, s.started_at, s.finished_at))
+				 FROM stage s WHERE s.pipeline_id = p.id),
+				(SELECT MAX(sl.created_at)
+				 FROM stage_log sl JOIN stage s ON s.id = sl.stage_id
+				 WHERE s.pipeline_id = p.id)
+			) AS last_change_at
+		) activity
+		LEFT JOIN LATERAL (
+			SELECT s.id, s.name, s.status, s.stage_handler_name
+			FROM stage s
+			WHERE s.pipeline_id = p.id
+			  AND s.status NOT IN ($2, $3)
+			  AND COALESCE(s.is_event, false) = false
+			ORDER BY s.id
+			LIMIT 1
+		) cur ON true
+		WHERE p.is_completed = false
+		  AND p.status = $1
+		  AND activity.last_change_at < NOW() - make_interval(secs => $4)
+		ORDER BY activity.last_change_at
+		LIMIT $5
+	`, types.PipelineStatusRunning, types.StageStatusCompleted, types.StageStatusSkipped,
+		olderThan.Seconds(), maxStuckPipelinesPerCheck); err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UTC()
+	for _, row := range rows {
+		s.emitPipelineStuckAlert(PipelineStuckEvent{
+			PipelineID:    row.PipelineID,
+			PipelineName:  row.PipelineName,
+			ApplicationID: row.ApplicationID,
+			StageID:       int(row.StageID.Int64),
+			StageName:     row.StageName.String,
+			StageStatus:   row.StageStatus.String,
+			StageHandler:  row.StageHandler.String,
+			LastChangeAt:  row.LastChangeAt.UTC(),
+			TS:            now,
+		})
+	}
+	return len(rows), nil
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"pipelogiq/internal/types"
+)
+
+// GetApplicationWebhook returns the completion webhook for the application,
+// or nil when none is configured.
+func (s *Store) GetApplicationWebhook(ctx context.Context, appID int) (*types.ApplicationWebhook, error) {
+	var row struct {
+		URL    sql.NullString `db:"webhook_url"`
+		Secret sql.NullString `db:"webhook_secret"`
+	}
+	err := s.db.GetContext(ctx, &row, `
+		SELECT webhook_url, webhook_secret FROM application WHERE id = $1
+	`, appID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load application webhook: %w", err)
+	}
+	if !row.URL.Valid || row.URL.String == "" {
+		return nil, nil
+	}
+	return &types.ApplicationWebhook{
+		ApplicationID: appID,
+		URL:           row.URL.String,
+		Secret:        row.Secret.String,
+	}, nil
+}
+
+// SetApplicationWebhook stores the webhook URL and, when secret is non-nil,
+// its signing secret. An empty URL clears both.
+func (s *Store) SetApplicationWebhook(ctx context.Context, appID int, url string, secret *string) error {
+	var err error
+	switch {
+	case url == "":
+		_, err = s.db.ExecContext(ctx, `
+			UPDATE application SET webhook_url = NULL, webhook_secret = NULL WHERE id = $1
+		`, appID)
+	case secret != nil:
+		_, err = s.db.ExecContext(ctx, `
+			UPDATE application SET webhook_url = $1, webhook_secret = NULLIF($2, '') WHERE id = $3
+		`, url, *secret, appID)
+	default:
+		_, err = s.db.ExecContext(ctx, `
+			UPDATE application SET webhook_url = $1 WHERE id = $2
+		`, url, appID)
+	}
+	if err != nil {
+		return fmt.Errorf("save application webhook: %w", err)
+	}
+	return nil
+}
+
+// SaveWebhookDeadLetter records a webhook delivery that exhausted its retries
+// as an error entry in the application log.
+func (s *Store) SaveWebhookDeadLetter(ctx context.Context, appID, pipelineID int, url string, attempts int, cause error) error {
+	message := fmt.Sprintf("webhook delivery failed after %d attempts: pipeline %d to %s: %v", attempts, pipelineID, url, cause)
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO log (log, log_level, created_at, application_id)
+		VALUES ($1, $2, $3, $4)
+	`, message, "Error", time.Now().UTC(), appID)
+	if err != nil {
+		return fmt.Errorf("insert webhook dead letter: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"pipelogiq/internal/types"
+)
+
+var errInvalidWorkerEventCursor = errors.New("invalid worker event cursor")
+
+// FormatWorkerEventCursor encodes a cursor as "<RFC3339 timestamp>,<id>".
+func FormatWorkerEventCursor(cursor types.WorkerEventCursor) string {
+	return cursor.TS.UTC().Format(time.RFC3339Nano) + "," + strconv.FormatInt(cursor.ID, 10)
+}
+
+// ParseWorkerEventCursor decodes a cursor from FormatWorkerEventCursor. A bare
+// RFC3339 timestamp is accepted too and matches only events strictly before
+// or after it.
+func ParseWorkerEventCursor(value string) (*types.WorkerEventCursor, error) {
+	value = strings.TrimSpace(value)
+	tsPart, idPart, hasID := strings.Cut(value, ",")
+	ts, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(tsPart))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", errInvalidWorkerEventCursor, value)
+	}
+	cursor := &types.WorkerEventCursor{TS: ts.UTC()}
+	if hasID {
+		id, err := strconv.ParseInt(strings.TrimSpace(idPart), 10, 64)
+		if err != nil || id < 0 {
+			return nil, fmt.Errorf("%w: %q", errInvalidWorkerEventCursor, value)
+		}
+		cursor.ID = id
+	}
+	return cursor, nil
+}
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"pipelogiq/internal/types"
+)
+
+func TestWorkerEventCursorRoundTrip(t *testing.T) {
+	want := types.WorkerEventCursor{TS: time.Date(2026, 3, 4, 5, 6, 7, 123456000, time.UTC), ID: 42}
+	got, err := ParseWorkerEventCursor(FormatWorkerEventCursor(want))
+	if err != nil {
+		t.Fatalf("ParseWorkerEventCursor() error = %v", err)
+	}
+	if !got.TS.Equal(want.TS) || got.ID != want.ID {
+		t.Fatalf("ParseWorkerEventCursor() = %+v, want %+v", *got, want)
+	}
+
+	bare, err := ParseWorkerEventCursor("2026-03-04T05:06:07Z")
+	if err != nil || bare.ID != 0 {
+		t.Fatalf("ParseWorkerEventCursor(timestamp) = %+v, %v", bare, err)
+	}
+
+	for _, value := range []string{"", "yesterday", "2026-03-04T05:06:07Z,abc", "2026-03-04T05:06:07Z,-1"} {
+		if _, err := ParseWorkerEventCursor(value); err == nil {
+			t.Errorf("ParseWorkerEventCursor(%q) error = nil, want error", value)
+		}
+	}
+}
+
+func TestLogLevelsAtLeast(t *testing.T) {
+	tests := map[string][]string{
+		"":        nil,
+		"warning": {"WARN", "ERROR"},
+		"ERROR":   {"ERROR"},
+		"trace":   {"TRACE", "DEBUG", "INFO", "WARN", "ERROR"},
+	}
+	for level, want := range tests {
+		if got := logLevelsAtLeast(level); !reflect.DeepEqual(got, want) {
+			t.Errorf("logLevelsAtLeast(%q) = %v, want %v", level, got, want)
+		}
+	}
+}
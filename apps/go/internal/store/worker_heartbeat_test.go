@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"pipelogiq/internal/types"
+)
+
+func TestUpdateWorkerHeartbeatReportsStaleConfig(t *testing.T) {
+	db := setupPostgresTestDB(t)
+	st := New(db, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+	if _, err := db.Exec(`
+		INSERT INTO application (id, name) VALUES (1, 'billing');
+		INSERT INTO worker_client (id, application_id, state, session_token, session_expires_at)
+		VALUES ('w1', 1, 'ready', 'tok', $1);
+	`, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("insert worker: %v", err)
+	}
+
+	heartbeat := func(version *string) bool {
+		t.Helper()
+		stale, err := st.UpdateWorkerHeartbeat(ctx, "tok", types.WorkerHeartbeatRequest{
+			WorkerID: "w1", State: types.WorkerStateReady, ConfigVersion: version,
+		})
+		if err != nil {
+			t.Fatalf("UpdateWorkerHeartbeat() error = %v", err)
+		}
+		return stale
+	}
+	version := func(v string) *string { return &v }
+
+	if heartbeat(nil) {
+		t.Error("heartbeat without configVersion reported stale")
+	}
+	if heartbeat(version("1")) {
+		t.Error("heartbeat with the current configVersion reported stale")
+	}
+	if !heartbeat(version("0")) {
+		t.Error("heartbeat with an old configVersion not reported stale")
+	}
+	if _, err := db.Exec(`UPDATE config_version SET version = 2 WHERE id = 1`); err != nil {
+		t.Fatalf("bump config version: %v", err)
+	}
+	if !heartbeat(version("1")) {
+		t.Error("heartbeat after a config change not reported stale")
+	}
+
+	var stored string
+	if err := db.Get(&stored, `SELECT config_version FROM worker_client WHERE id = 'w1'`); err != nil {
+		t.Fatalf("load worker: %v", err)
+	}
+	if stored != "1" {
+		t.Fatalf("stored config_version = %q, want the last reported 1", stored)
+	}
+
+	if _, err := st.UpdateWorkerHeartbeat(ctx, "other", types.WorkerHeartbeatRequest{WorkerID: "w1"}); !IsInvalidWorkerSessionError(err) {
+		t.Fatalf("UpdateWorkerHeartbeat(wrong token) error = %v, want invalid session", err)
+	}
+}
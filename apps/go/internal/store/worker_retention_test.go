@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"pipelogiq/internal/types"
+)
+
+func TestPruneWorkersKeepsLiveWorkers(t *testing.T) {
+	db := setupPostgresTestDB(t)
+	st := New(db, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	st.SetWorkerOfflineAfter(time.Minute)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	workers := []struct {
+		id       string
+		state    string
+		lastSeen time.Time
+		stopped  *time.Time
+	}{
+		{"stopped-old", types.WorkerStateStopped, now.Add(-3 * time.Hour), timePtr(now.Add(-2 * time.Hour))},
+		{"offline-old", types.WorkerStateOffline, now.Add(-2 * time.Hour), nil},
+		{"silent-old", types.WorkerStateReady, now.Add(-2 * time.Hour), nil},
+		{"stopped-recent", types.WorkerStateStopped, now.Add(-time.Minute), timePtr(now.Add(-time.Minute))},
+		{"live", types.WorkerStateReady, now.Add(-10 * time.Second), nil},
+	}
+	for _, w := range workers {
+		if _, err := db.Exec(`
+			INSERT INTO worker_client (id, application_id, state, last_seen_at, stopped_at) VALUES ($1, 1, $2, $3, $4)
+		`, w.id, w.state, w.lastSeen, w.stopped); err != nil {
+			t.Fatalf("insert worker %s: %v", w.id, err)
+		}
+		if _, err := db.Exec(`INSERT INTO worker_heartbeat (worker_id, ts, state) VALUES ($1, $2, $3)`,
+			w.id, w.lastSeen, w.state); err != nil {
+			t.Fatalf("insert heartbeat %s: %v", w.id, err)
+		}
+		if _, err := db.Exec(`INSERT INTO worker_event (worker_id, ts, level, event_type, message) VALUES ($1, $2, 'info', 'state', 'x')`,
+			w.id, w.lastSeen); err != nil {
+			t.Fatalf("insert event %s: %v", w.id, err)
+		}
+	}
+
+	// A retention shorter than the offline threshold must not reap a worker
+	// that is still heartbeating.
+	pruned, err := st.PruneWorkers(ctx, time.Second)
+	if err != nil {
+		t.Fatalf("PruneWorkers() error = %v", err)
+	}
+	if pruned != 4 {
+		t.Fatalf("PruneWorkers() = %d, want 4", pruned)
+	}
+
+	for _, table := range []string{"worker_client", "worker_heartbeat", "worker_event"} {
+		column := "worker_id"
+		if table == "worker_client" {
+			column = "id"
+		}
+		var ids []string
+		if err := db.Select(&ids, `SELECT `+column+` FROM `+table+` ORDER BY 1`); err != nil {
+			t.Fatalf("list %s: %v", table, err)
+		}
+		if len(ids) != 1 || ids[0] != "live" {
+			t.Errorf("%s after prune = %v, want [live]", table, ids)
+		}
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -52,6 +52,9 @@ type workerClientSnapshot struct {
 	CapabilitiesJSON string          `db:"capabilities_json"`
 	MetadataJSON     string          `db:"metadata_json"`
 	SessionExpiresAt time.Time       `db:"session_expires_at"`
+	BootstrappedAt   sql.NullTime    `db:"bootstrapped_at"`
+	ConfigVersion    sql.NullString  `db:"config_version"`
+	ConfigOutdated   bool            `db:"config_outdated"`
 }
 
 func (s *Store) GetApplicationNameByID(ctx context.Context, appID int) (string, error) {
@@ -164,10 +167,11 @@ func (s *Store) RegisterWorkerSession(
 			last_seen_at,
 			created_at,
 			updated_at,
+			bootstrapped_at,
 			stopped_at
 		)
 		VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, false, 0, 0, 0, $13, $14, $15, $16, $17, $18, $18, $18, $18, NULL
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, false, 0, 0, 0, $13, $14, $15, $16, $17, $18, $18, $18, $18, $18, NULL
 		)
 		ON CONFLICT (application_id, instance_id) DO UPDATE SET
 			app_runtime_id = EXCLUDED.app_runtime_id,
@@ -193,6 +197,8 @@ func (s *Store) RegisterWorkerSession(
 			session_expires_at = EXCLUDED.session_expires_at,
 			last_seen_at = EXCLUDED.last_seen_at,
 			updated_at = EXCLUDED.updated_at,
+			bootstrapped_at = EXCLUDED.bootstrapped_at,
+			bootstrap_config_json = NULL,
 			stopped_at = NULL
 		RETURNING id
 	`
@@ -232,26 +238,31 @@ func (s *Store) RegisterWorkerSession(
 	}
 	_ = s.insertWorkerEvent(ctx, persistedID, now, "INFO", "worker.bootstrap", "Worker bootstrap completed", bootstrapDetails)
 	s.emitWorkerAlert(WorkerAlertEvent{
-		WorkerID:  persistedID,
-		TS:        now.UTC(),
-		Level:     "INFO",
-		EventType: "worker.bootstrap",
-		Message:   "Worker bootstrap completed",
-		Details:   cloneAlertDetailsMap(bootstrapDetails),
+		WorkerID:       persistedID,
+		TS:             now.UTC(),
+		Level:          "INFO",
+		EventType:      "worker.bootstrap",
+		Message:        "Worker bootstrap completed",
+		Details:        cloneAlertDetailsMap(bootstrapDetails),
+		BootstrappedAt: now.UTC(),
+		ApplicationID:  appID,
 	})
 
 	return persistedID, nil
 }
 
-func (s *Store) UpdateWorkerHeartbeat(ctx context.Context, token string, req types.WorkerHeartbeatRequest) error {
+// UpdateWorkerHeartbeat records a heartbeat of the worker holding token. When
+// the heartbeat carries a configVersion other than the current one it
+// reports configStale, telling the worker to bootstrap again.
+func (s *Store) UpdateWorkerHeartbeat(ctx context.Context, token string, req types.WorkerHeartbeatRequest) (configStale bool, err error) {
 	workerID := strings.TrimSpace(req.WorkerID)
 	if workerID == "" || strings.TrimSpace(token) == "" {
-		return errWorkerSessionInvalid
+		return false, errWorkerSessionInvalid
 	}
 
 	tx, err := s.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return err
+		return false, err
 	}
 	defer func() {
 		if err != nil {
@@ -291,7 +302,8 @@ func (s *Store) UpdateWorkerHeartbeat(ctx context.Context, token string, req typ
 			wc.supported_handlers_json,
 			wc.capabilities_json,
 			wc.metadata_json,
-			wc.session_expires_at
+			wc.session_expires_at,
+			wc.bootstrapped_at
 		FROM worker_client wc
 		JOIN application a ON a.id = wc.application_id
 		WHERE wc.id = $1 AND wc.session_token = $2
@@ -299,14 +311,14 @@ func (s *Store) UpdateWorkerHeartbeat(ctx context.Context, token string, req typ
 	`
 	if err = tx.GetContext(ctx, &snapshot, selectQuery, workerID, token); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return errWorkerSessionInvalid
+			return false, errWorkerSessionInvalid
 		}
-		return err
+		return false, err
 	}
 
 	now := time.Now().UTC()
 	if snapshot.SessionExpiresAt.Before(now) {
-		return errWorkerSessionInvalid
+		return false, errWorkerSessionInvalid
 	}
 
 	nextState := sanitizeWorkerState(req.State, snapshot.State)
@@ -367,7 +379,19 @@ func (s *Store) UpdateWorkerHeartbeat(ctx context.Context, token string, req typ
 
 	metadataJSON, marshalErr := toJSONText(req.Metadata, snapshot.MetadataJSON)
 	if marshalErr != nil {
-		return marshalErr
+		return false, marshalErr
+	}
+
+	reportedVersion := ""
+	if req.ConfigVersion != nil {
+		reportedVersion = strings.TrimSpace(*req.ConfigVersion)
+	}
+	if reportedVersion != "" {
+		var current string
+		if err = tx.GetContext(ctx, &current, `SELECT version::text FROM config_version WHERE id = 1`); err != nil {
+			return false, err
+		}
+		configStale = reportedVersion != current
 	}
 
 	var stoppedAt any
@@ -393,7 +417,8 @@ func (s *Store) UpdateWorkerHeartbeat(ctx context.Context, token string, req typ
 			metadata_json = $13,
 			last_seen_at = $14,
 			updated_at = $14,
-			stopped_at = $15
+			stopped_at = $15,
+			config_version = COALESCE($16::varchar, config_version)
 		WHERE id = $1 AND session_token = $2
 	`
 
@@ -413,8 +438,9 @@ func (s *Store) UpdateWorkerHeartbeat(ctx context.Context, token string, req typ
 		metadataJSON,
 		now,
 		stoppedAt,
+		nullableStringVal(reportedVersion),
 	); err != nil {
-		return err
+		return false, err
 	}
 
 	heartbeatPayload := map[string]any{
@@ -424,7 +450,7 @@ func (s *Store) UpdateWorkerHeartbeat(ctx context.Context, token string, req typ
 	}
 	payloadJSON, marshalErr := toJSONText(heartbeatPayload, "{}")
 	if marshalErr != nil {
-		return marshalErr
+		return false, marshalErr
 	}
 
 	insertHeartbeat := `
@@ -458,7 +484,7 @@ func (s *Store) UpdateWorkerHeartbeat(ctx context.Context, token string, req typ
 		nullableStringVal(lastError),
 		payloadJSON,
 	); err != nil {
-		return err
+		return false, err
 	}
 
 	stateChanged := snapshot.State != nextState
@@ -471,24 +497,27 @@ func (s *Store) UpdateWorkerHeartbeat(ctx context.Context, token string, req typ
 			fmt.Sprintf("Worker state changed from %s to %s", snapshot.State, nextState),
 			stateChangeDetails,
 		); err != nil {
-			return err
+			return false, err
 		}
 	}
 
 	if err = tx.Commit(); err != nil {
-		return err
+		return false, err
 	}
 	if stateChanged {
 		s.emitWorkerAlert(WorkerAlertEvent{
-			WorkerID:  workerID,
-			TS:        now.UTC(),
-			Level:     "INFO",
-			EventType: "worker.state_changed",
-			Message:   fmt.Sprintf("Worker state changed from %s to %s", snapshot.State, nextState),
-			Details:   cloneAlertDetailsMap(stateChangeDetails),
+			WorkerID:        workerID,
+			TS:              now.UTC(),
+			Level:           "INFO",
+			EventType:       "worker.state_changed",
+			Message:         fmt.Sprintf("Worker state changed from %s to %s", snapshot.State, nextState),
+			Details:         cloneAlertDetailsMap(stateChangeDetails),
+			BootstrappedAt:  snapshot.BootstrappedAt.Time,
+			ApplicationID:   snapshot.ApplicationID,
+			ApplicationName: snapshot.ApplicationName,
 		})
 	}
-	return nil
+	return configStale, nil
 }
 
 func (s *Store) SaveWorkerEvents(
@@ -503,11 +532,17 @@ func (s *Store) SaveWorkerEvents(
 		return errWorkerSessionInvalid
 	}
 
-	var expiresAt time.Time
-	err := s.db.GetContext(ctx, &expiresAt, `
-		SELECT session_expires_at
-		FROM worker_client
-		WHERE id = $1 AND session_token = $2
+	var session struct {
+		ExpiresAt       time.Time    `db:"session_expires_at"`
+		BootstrappedAt  sql.NullTime `db:"bootstrapped_at"`
+		ApplicationID   int          `db:"application_id"`
+		ApplicationName string       `db:"application_name"`
+	}
+	err := s.db.GetContext(ctx, &session, `
+		SELECT wc.session_expires_at, wc.bootstrapped_at, wc.application_id, a.name AS application_name
+		FROM worker_client wc
+		JOIN application a ON a.id = wc.application_id
+		WHERE wc.id = $1 AND wc.session_token = $2
 		LIMIT 1
 	`, workerID, token)
 	if err != nil {
@@ -516,7 +551,7 @@ func (s *Store) SaveWorkerEvents(
 		}
 		return err
 	}
-	if expiresAt.Before(time.Now().UTC()) {
+	if session.ExpiresAt.Before(time.Now().UTC()) {
 		return errWorkerSessionInvalid
 	}
 
@@ -550,12 +585,15 @@ func (s *Store) SaveWorkerEvents(
 			return err
 		}
 		alertEvents = append(alertEvents, WorkerAlertEvent{
-			WorkerID:  workerID,
-			TS:        eventTS.UTC(),
-			Level:     level,
-			EventType: eventType,
-			Message:   message,
-			Details:   cloneAlertDetailsMap(event.Details),
+			WorkerID:        workerID,
+			TS:              eventTS.UTC(),
+			Level:           level,
+			EventType:       eventType,
+			Message:         message,
+			Details:         cloneAlertDetailsMap(event.Details),
+			BootstrappedAt:  session.BootstrappedAt.Time,
+			ApplicationID:   session.ApplicationID,
+			ApplicationName: session.ApplicationName,
 		})
 	}
 
@@ -577,6 +615,21 @@ func (s *Store) SaveWorkerEvents(
 	return nil
 }
 
+// WorkerSessionActive reports whether token belongs to an unexpired worker
+// session.
+func (s *Store) WorkerSessionActive(ctx context.Context, token string) (bool, error) {
+	var active bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM worker_client WHERE session_token = $1 AND session_expires_at > NOW()
+		)
+	`, token).Scan(&active)
+	if err != nil {
+		return false, fmt.Errorf("check worker session: %w", err)
+	}
+	return active, nil
+}
+
 func (s *Store) StopWorkerSession(ctx context.Context, workerID string, token string, reason string) error {
 	workerID = strings.TrimSpace(workerID)
 	token = strings.TrimSpace(token)
@@ -623,17 +676,9 @@ func (s *Store) StopWorkerSession(ctx context.Context, workerID string, token st
 	return nil
 }
 
-func (s *Store) ListWorkers(ctx context.Context, req types.WorkerListRequest) ([]types.WorkerStatusResponse, error) {
-	limit := req.Limit
-	if limit <= 0 {
-		limit = 100
-	}
-	if limit > 500 {
-		limit = 500
-	}
-
-	queryBuilder := strings.Builder{}
-	queryBuilder.WriteString(`
+// workerStatusSelect selects the worker_client columns scanned into
+// workerClientSnapshot by toWorkerStatusResponse.
+const workerStatusSelect = `
 		SELECT
 			wc.id,
 			wc.application_id,
@@ -663,9 +708,25 @@ func (s *Store) ListWorkers(ctx context.Context, req types.WorkerListRequest) ([
 			wc.updated_at,
 			wc.supported_handlers_json,
 			wc.capabilities_json,
-			wc.metadata_json
+			wc.metadata_json,
+			wc.config_version,
+			COALESCE(wc.config_version <> cv.version::text, false) AS config_outdated
 		FROM worker_client wc
 		JOIN application a ON a.id = wc.application_id
+		LEFT JOIN config_version cv ON cv.id = 1
+`
+
+func (s *Store) ListWorkers(ctx context.Context, req types.WorkerListRequest) ([]types.WorkerStatusResponse, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	if limit > 500 {
+		limit = 500
+	}
+
+	queryBuilder := strings.Builder{}
+	queryBuilder.WriteString(workerStatusSelect + `
 		WHERE 1 = 1
 	`)
 
@@ -686,6 +747,13 @@ func (s *Store) ListWorkers(ctx context.Context, req types.WorkerListRequest) ([
 			len(args), len(args), len(args),
 		))
 	}
+	if req.StoppedAfter != nil {
+		args = append(args, types.WorkerStateStopped, req.StoppedAfter.UTC())
+		queryBuilder.WriteString(fmt.Sprintf(
+			" AND NOT (wc.state = $%d AND COALESCE(wc.stopped_at, wc.last_seen_at) < $%d)",
+			len(args)-1, len(args),
+		))
+	}
 
 	args = append(args, limit)
 	queryBuilder.WriteString(fmt.Sprintf(" ORDER BY wc.last_seen_at DESC LIMIT $%d", len(args)))
@@ -707,7 +775,181 @@ func (s *Store) ListWorkers(ctx context.Context, req types.WorkerListRequest) ([
 	return result, nil
 }
 
-func (s *Store) ListWorkerEvents(ctx context.Context, req types.WorkerEventListRequest) ([]types.WorkerEventResponse, error) {
+// GetWorkerDetail returns a worker with its most recent heartbeats, oldest
+// first. historyLimit is clamped to 1..1000 and defaults to 120. It returns
+// nil when the worker does not exist.
+func (s *Store) GetWorkerDetail(ctx context.Context, workerID string, historyLimit int) (*types.WorkerDetailResponse, error) {
+	if historyLimit <= 0 {
+		historyLimit = 120
+	}
+	if historyLimit > 1000 {
+		historyLimit = 1000
+	}
+
+	var row workerClientSnapshot
+	if err := s.db.GetContext(ctx, &row, workerStatusSelect+" WHERE wc.id = $1", workerID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	worker, err := toWorkerStatusResponse(row)
+	if err != nil {
+		return nil, err
+	}
+
+	type heartbeatRow struct {
+		TS              time.Time       `db:"ts"`
+		State           string          `db:"state"`
+		BrokerConnected bool            `db:"broker_connected"`
+		InFlightJobs    int             `db:"in_flight_jobs"`
+		JobsProcessed   int64           `db:"jobs_processed"`
+		JobsFailed      int64           `db:"jobs_failed"`
+		QueueLag        sql.NullInt32   `db:"queue_lag"`
+		CPUPercent      sql.NullFloat64 `db:"cpu_percent"`
+		MemoryMB        sql.NullFloat64 `db:"memory_mb"`
+	}
+	rows := []heartbeatRow{}
+	if err := s.db.SelectContext(ctx, &rows, `
+		SELECT ts, state, broker_connected, in_flight_jobs, jobs_processed, jobs_failed, queue_lag, cpu_percent, memory_mb
+		FROM (
+			SELECT * FROM worker_heartbeat
+			WHERE worker_id = $1
+			ORDER BY ts DESC
+			LIMIT $2
+		) recent
+		ORDER BY ts ASC
+	`, workerID, historyLimit); err != nil {
+		return nil, err
+	}
+
+	heartbeats := make([]types.WorkerHeartbeatPoint, 0, len(rows))
+	for _, hb := range rows {
+		point := types.WorkerHeartbeatPoint{
+			TS:              hb.TS.UTC().Format(time.RFC3339),
+			State:           hb.State,
+			BrokerConnected: hb.BrokerConnected,
+			InFlightJobs:    hb.InFlightJobs,
+			JobsProcessed:   hb.JobsProcessed,
+			JobsFailed:      hb.JobsFailed,
+		}
+		if hb.QueueLag.Valid {
+			value := int(hb.QueueLag.Int32)
+			point.QueueLag = &value
+		}
+		if hb.CPUPercent.Valid {
+			value := hb.CPUPercent.Float64
+			point.CPUPercent = &value
+		}
+		if hb.MemoryMB.Valid {
+			value := hb.MemoryMB.Float64
+			point.MemoryMB = &value
+		}
+		heartbeats = append(heartbeats, point)
+	}
+
+	return &types.WorkerDetailResponse{WorkerStatusResponse: worker, Heartbeats: heartbeats}, nil
+}
+
+// SaveWorkerBootstrapConfig records the bootstrap response handed to a worker
+// so operators can later see the configuration it runs with, along with the
+// config version it carries. Callers strip secrets first; the snapshot is
+// replaced on every bootstrap.
+func (s *Store) SaveWorkerBootstrapConfig(ctx context.Context, workerID string, config types.WorkerBootstrapResponse) error {
+	raw, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE worker_client SET bootstrap_config_json = $2, config_version = $3 WHERE id = $1
+	`, workerID, string(raw), nullableStringVal(config.ConfigVersion))
+	return err
+}
+
+// GetWorkerBootstrapConfig returns the bootstrap snapshot saved for a worker,
+// or nil when the worker is unknown or bootstrapped before snapshots were
+// recorded.
+func (s *Store) GetWorkerBootstrapConfig(ctx context.Context, workerID string) (*types.WorkerConfigResponse, error) {
+	var row struct {
+		ConfigJSON     sql.NullString `db:"bootstrap_config_json"`
+		BootstrappedAt sql.NullTime   `db:"bootstrapped_at"`
+	}
+	if err := s.db.GetContext(ctx, &row, `
+		SELECT bootstrap_config_json, bootstrapped_at FROM worker_client WHERE id = $1
+	`, workerID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !row.ConfigJSON.Valid || strings.TrimSpace(row.ConfigJSON.String) == "" {
+		return nil, nil
+	}
+
+	resp := &types.WorkerConfigResponse{WorkerID: workerID}
+	if err := json.Unmarshal([]byte(row.ConfigJSON.String), &resp.Config); err != nil {
+		return nil, fmt.Errorf("decode bootstrap config of worker %s: %w", workerID, err)
+	}
+	if row.BootstrappedAt.Valid {
+		value := row.BootstrappedAt.Time.UTC().Format(time.RFC3339)
+		resp.BootstrappedAt = &value
+	}
+	return resp, nil
+}
+
+// PruneWorkers deletes stopped or offline workers whose last stop or sighting
+// is older than now-retention, together with their heartbeats and events. A
+// worker whose recorded state is still live is only pruned once it has also
+// been silent for longer than the offline threshold. It returns the number of
+// workers removed.
+func (s *Store) PruneWorkers(ctx context.Context, retention time.Duration) (int64, error) {
+	now := time.Now().UTC()
+	cutoff := now.Add(-retention)
+	silentCutoff := cutoff
+	if offline := now.Add(-s.workerOfflineAfter); offline.Before(silentCutoff) {
+		silentCutoff = offline
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	staleWorkers := `
+		SELECT id FROM worker_client
+		WHERE (state IN ($1, $2) AND COALESCE(stopped_at, last_seen_at) < $3)
+		   OR last_seen_at < $4
+	`
+	args := []any{types.WorkerStateStopped, types.WorkerStateOffline, cutoff, silentCutoff}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM worker_heartbeat WHERE worker_id IN (`+staleWorkers+`)`, args...); err != nil {
+		return 0, fmt.Errorf("prune worker heartbeats: %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM worker_event WHERE worker_id IN (`+staleWorkers+`)`, args...); err != nil {
+		return 0, fmt.Errorf("prune worker events: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM worker_client WHERE id IN (`+staleWorkers+`)`, args...)
+	if err != nil {
+		return 0, fmt.Errorf("prune worker clients: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	affected, _ := res.RowsAffected()
+	return affected, nil
+}
+
+// ListWorkerEvents returns worker events newest first, paged by the
+// request's Before/After cursors.
+func (s *Store) ListWorkerEvents(ctx context.Context, req types.WorkerEventListRequest) (*types.WorkerEventListResponse, error) {
 	limit := req.Limit
 	if limit <= 0 {
 		limit = 200
@@ -735,7 +977,7 @@ func (s *Store) ListWorkerEvents(ctx context.Context, req types.WorkerEventListR
 		WHERE 1 = 1
 	`)
 
-	args := make([]any, 0, 3)
+	args := make([]any, 0, 8)
 	if req.WorkerID != nil && strings.TrimSpace(*req.WorkerID) != "" {
 		args = append(args, strings.TrimSpace(*req.WorkerID))
 		queryBuilder.WriteString(fmt.Sprintf(" AND we.worker_id = $%d", len(args)))
@@ -744,8 +986,35 @@ func (s *Store) ListWorkerEvents(ctx context.Context, req types.WorkerEventListR
 		args = append(args, *req.ApplicationID)
 		queryBuilder.WriteString(fmt.Sprintf(" AND wc.application_id = $%d", len(args)))
 	}
+	if levels := logLevelsAtLeast(req.MinLevel); len(levels) > 0 {
+		placeholders := make([]string, 0, len(levels))
+		for _, level := range levels {
+			args = append(args, level)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+		}
+		queryBuilder.WriteString(" AND we.level IN (" + strings.Join(placeholders, ", ") + ")")
+	}
+	if eventType := strings.TrimSpace(req.EventType); eventType != "" {
+		args = append(args, eventType)
+		queryBuilder.WriteString(fmt.Sprintf(" AND we.event_type = $%d", len(args)))
+	}
+	if req.Before != nil {
+		args = append(args, req.Before.TS.UTC())
+		queryBuilder.WriteString(workerEventCursorFilter("<", len(args), req.Before.ID, &args))
+	}
+	// Paging forward reads the oldest events past the cursor first so a
+	// full page does not skip any; the rows are reversed below.
+	ascending := req.After != nil && req.Before == nil
+	if req.After != nil {
+		args = append(args, req.After.TS.UTC())
+		queryBuilder.WriteString(workerEventCursorFilter(">", len(args), req.After.ID, &args))
+	}
 	args = append(args, limit)
-	queryBuilder.WriteString(fmt.Sprintf(" ORDER BY we.ts DESC LIMIT $%d", len(args)))
+	if ascending {
+		queryBuilder.WriteString(fmt.Sprintf(" ORDER BY we.ts ASC, we.id ASC LIMIT $%d", len(args)))
+	} else {
+		queryBuilder.WriteString(fmt.Sprintf(" ORDER BY we.ts DESC, we.id DESC LIMIT $%d", len(args)))
+	}
 
 	type workerEventRow struct {
 		ID              int64     `db:"id"`
@@ -764,8 +1033,13 @@ func (s *Store) ListWorkerEvents(ctx context.Context, req types.WorkerEventListR
 	if err := s.db.SelectContext(ctx, &rows, queryBuilder.String(), args...); err != nil {
 		return nil, err
 	}
+	if ascending {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
 
-	result := make([]types.WorkerEventResponse, 0, len(rows))
+	result := &types.WorkerEventListResponse{Items: make([]types.WorkerEventResponse, 0, len(rows))}
 	for _, row := range rows {
 		details := map[string]any{}
 		_ = json.Unmarshal([]byte(strings.TrimSpace(row.DetailsJSON)), &details)
@@ -773,7 +1047,7 @@ func (s *Store) ListWorkerEvents(ctx context.Context, req types.WorkerEventListR
 			details = nil
 		}
 
-		result = append(result, types.WorkerEventResponse{
+		result.Items = append(result.Items, types.WorkerEventResponse{
 			ID:              row.ID,
 			WorkerID:        row.WorkerID,
 			WorkerName:      row.WorkerName,
@@ -787,9 +1061,31 @@ func (s *Store) ListWorkerEvents(ctx context.Context, req types.WorkerEventListR
 		})
 	}
 
+	if len(rows) > 0 {
+		newest, oldest := rows[0], rows[len(rows)-1]
+		result.PrevCursor = FormatWorkerEventCursor(types.WorkerEventCursor{TS: newest.TS, ID: newest.ID})
+		// A page paging forward never has older events left to fetch.
+		if len(rows) == limit && !ascending {
+			result.NextCursor = FormatWorkerEventCursor(types.WorkerEventCursor{TS: oldest.TS, ID: oldest.ID})
+		}
+	} else if req.After != nil {
+		result.PrevCursor = FormatWorkerEventCursor(*req.After)
+	}
+
 	return result, nil
 }
 
+// workerEventCursorFilter compares events with a cursor whose timestamp is
+// bound at $tsArg. Without an id (event ids start at 1) only the timestamp is
+// compared; otherwise the id breaks ties and is appended to args.
+func workerEventCursorFilter(op string, tsArg int, id int64, args *[]any) string {
+	if id <= 0 {
+		return fmt.Sprintf(" AND we.ts %s $%d", op, tsArg)
+	}
+	*args = append(*args, id)
+	return fmt.Sprintf(" AND (we.ts %s $%d OR (we.ts = $%d AND we.id %s $%d))", op, tsArg, tsArg, op, len(*args))
+}
+
 func (s *Store) insertWorkerEvent(
 	ctx context.Context,
 	workerID string,
@@ -855,6 +1151,25 @@ func sanitizeWorkerState(state string, fallback string) string {
 	}
 }
 
+// workerEventLevels lists the normalized event levels from least to most
+// severe.
+var workerEventLevels = []string{"TRACE", "DEBUG", "INFO", "WARN", "ERROR"}
+
+// logLevelsAtLeast returns the normalized levels at or above level, or nil
+// when level is empty.
+func logLevelsAtLeast(level string) []string {
+	if strings.TrimSpace(level) == "" {
+		return nil
+	}
+	min := normalizeLogLevel(level)
+	for i, candidate := range workerEventLevels {
+		if candidate == min {
+			return workerEventLevels[i:]
+		}
+	}
+	return nil
+}
+
 func normalizeLogLevel(level string) string {
 	switch strings.ToUpper(strings.TrimSpace(level)) {
 	case "TRACE":
@@ -911,6 +1226,11 @@ func toWorkerStatusResponse(row workerClientSnapshot) (types.WorkerStatusRespons
 		SupportedHandlers: supportedHandlers,
 		Capabilities:      capabilities,
 		Metadata:          metadata,
+		ConfigOutdated:    row.ConfigOutdated,
+	}
+	if row.ConfigVersion.Valid {
+		value := row.ConfigVersion.String
+		resp.ConfigVersion = &value
 	}
 	if row.WorkerVersion.Valid {
 		value := row.WorkerVersion.String
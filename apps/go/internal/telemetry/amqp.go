@@ -7,6 +7,7 @@ import (
 	amqp "github.com/rabbitmq/amqp091-go"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type amqpCarrier struct {
@@ -64,6 +65,32 @@ func ExtractAMQPContext(ctx context.Context, headers amqp.Table) context.Context
 	return otel.GetTextMapPropagator().Extract(ctx, propagation.TextMapCarrier(amqpCarrier{headers: headers}))
 }
 
+// ContextWithStageSpan returns ctx with a stage's span as its remote parent,
+// so the RabbitMQ publish span, and through the traceparent header the
+// consume and handler spans, land under the pipeline's trace. The parent is
+// flagged sampled by the trace-id ratio, so all stages of a pipeline share
+// one sampling decision. Malformed ids leave ctx unchanged.
+func ContextWithStageSpan(ctx context.Context, traceID, spanID string) context.Context {
+	tid, err := trace.TraceIDFromHex(traceID)
+	if err != nil {
+		return ctx
+	}
+	sid, err := trace.SpanIDFromHex(spanID)
+	if err != nil {
+		return ctx
+	}
+	var flags trace.TraceFlags
+	if sampleTrace(tid) {
+		flags = trace.FlagsSampled
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    tid,
+		SpanID:     sid,
+		TraceFlags: flags,
+		Remote:     true,
+	}))
+}
+
 func CloneAMQPTable(headers amqp.Table) amqp.Table {
 	if headers == nil {
 		return amqp.Table{}
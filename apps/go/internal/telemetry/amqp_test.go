@@ -0,0 +1,101 @@
+package telemetry
+
+import (
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// setSampleRatio stands in for the ratio Init stores; nil means Init has not
+// run.
+func setSampleRatio(t *testing.T, ratio *float64) {
+	t.Helper()
+	prev := traceSampleRatio.Load()
+	traceSampleRatio.Store(ratio)
+	t.Cleanup(func() { traceSampleRatio.Store(prev) })
+}
+
+func TestContextWithStageSpanParentsChildSpans(t *testing.T) {
+	setSampleRatio(t, nil)
+	prevPropagator := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(prevPropagator) })
+
+	const traceID, spanID = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	ctx := ContextWithStageSpan(t.Context(), traceID, spanID)
+
+	headers := InjectAMQPContext(ctx, amqp.Table{})
+	if got, want := headers["traceparent"], "00-"+traceID+"-"+spanID+"-01"; got != want {
+		t.Fatalf("traceparent = %v, want %s", got, want)
+	}
+
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.NeverSample())))
+	defer func() { _ = tp.Shutdown(t.Context()) }()
+	_, span := tp.Tracer("test").Start(ctx, "rabbitmq.publish")
+	defer span.End()
+	child, ok := span.(sdktrace.ReadOnlySpan)
+	if !ok {
+		t.Fatalf("span %T is not an SDK span", span)
+	}
+	if child.SpanContext().TraceID().String() != traceID || child.Parent().SpanID().String() != spanID {
+		t.Fatalf("child span = trace %s parent %s, want trace %s parent %s",
+			child.SpanContext().TraceID(), child.Parent().SpanID(), traceID, spanID)
+	}
+	if !child.SpanContext().IsSampled() {
+		t.Fatal("child span not sampled under a sampled stage parent")
+	}
+}
+
+func TestContextWithStageSpanSamplesByTraceRatio(t *testing.T) {
+	// The ratio sampler reads the last 8 bytes of the trace id as a number and
+	// keeps the trace when it falls under the ratio, so at 0.5 a low id is
+	// kept and an all-ones id is dropped.
+	const lowTraceID, highTraceID = "0000000000000001000000000000000f", "ffffffffffffffffffffffffffffffff"
+	half, zero, one := 0.5, 0.0, 1.0
+
+	tests := []struct {
+		name    string
+		ratio   *float64
+		traceID string
+		want    bool
+	}{
+		{"before Init", nil, highTraceID, true},
+		{"ratio 1", &one, highTraceID, true},
+		{"ratio 0", &zero, lowTraceID, false},
+		{"ratio 0.5 keeps low id", &half, lowTraceID, true},
+		{"ratio 0.5 drops high id", &half, highTraceID, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setSampleRatio(t, tt.ratio)
+			sc := trace.SpanContextFromContext(ContextWithStageSpan(t.Context(), tt.traceID, "00f067aa0ba902b7"))
+			if !sc.IsValid() || !sc.IsRemote() {
+				t.Fatalf("span context = %+v, want a valid remote parent", sc)
+			}
+			if sc.IsSampled() != tt.want {
+				t.Fatalf("sampled = %v, want %v", sc.IsSampled(), tt.want)
+			}
+			// Every stage of the pipeline gets the same decision.
+			again := trace.SpanContextFromContext(ContextWithStageSpan(t.Context(), tt.traceID, "b7ad6b7169203331"))
+			if again.IsSampled() != sc.IsSampled() {
+				t.Fatal("stages of one trace got different sampling decisions")
+			}
+		})
+	}
+}
+
+func TestContextWithStageSpanIgnoresMalformedIDs(t *testing.T) {
+	for _, ids := range [][2]string{
+		{"not-hex", "00f067aa0ba902b7"},
+		{"00000000000000000000000000000000", "00f067aa0ba902b7"},
+		{"4bf92f3577b34da6a3ce929d0e0e4736", ""},
+	} {
+		if sc := trace.SpanContextFromContext(ContextWithStageSpan(t.Context(), ids[0], ids[1])); sc.IsValid() {
+			t.Fatalf("ContextWithStageSpan(%q, %q) set parent %+v", ids[0], ids[1], sc)
+		}
+	}
+}
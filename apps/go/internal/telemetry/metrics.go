@@ -0,0 +1,107 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	prombridge "go.opentelemetry.io/contrib/bridges/prometheus"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+const defaultMetricsHTTPPath = "/v1/metrics"
+
+// metricsEnabled reports whether the OTLP metrics pipeline should run.
+// OTEL_METRICS_EXPORTER=none keeps tracing on while disabling metrics.
+func metricsEnabled() bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("OTEL_METRICS_EXPORTER"))) {
+	case "none", "false", "off":
+		return false
+	default:
+		return true
+	}
+}
+
+// newMeterProvider builds a meter provider that periodically pushes to the
+// OTLP collector. The Prometheus default registry is bridged into the reader
+// so the counters served on /metrics are exported without re-instrumenting.
+// The export interval honors OTEL_METRIC_EXPORT_INTERVAL (milliseconds).
+func newMeterProvider(
+	ctx context.Context,
+	protocol string,
+	rawEndpoint string,
+	headers map[string]string,
+	res *resource.Resource,
+) (*sdkmetric.MeterProvider, string, error) {
+	exporter, resolvedEndpoint, err := newMetricExporter(ctx, protocol, rawEndpoint, headers)
+	if err != nil {
+		return nil, "", err
+	}
+
+	reader := sdkmetric.NewPeriodicReader(
+		exporter,
+		sdkmetric.WithProducer(prombridge.NewMetricProducer()),
+	)
+
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(res),
+		sdkmetric.WithReader(reader),
+	)
+	return mp, resolvedEndpoint, nil
+}
+
+func newMetricExporter(
+	ctx context.Context,
+	protocol string,
+	rawEndpoint string,
+	headers map[string]string,
+) (sdkmetric.Exporter, string, error) {
+	switch protocol {
+	case "http":
+		host, path, insecure, err := normalizeHTTPEndpoint(rawEndpoint, defaultMetricsHTTPPath)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid OTLP HTTP endpoint: %w", err)
+		}
+		opts := []otlpmetrichttp.Option{
+			otlpmetrichttp.WithEndpoint(host),
+			otlpmetrichttp.WithURLPath(path),
+			otlpmetrichttp.WithTimeout(5 * time.Second),
+		}
+		if insecure {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		}
+		if len(headers) > 0 {
+			opts = append(opts, otlpmetrichttp.WithHeaders(headers))
+		}
+		exporter, err := otlpmetrichttp.New(ctx, opts...)
+		if err != nil {
+			return nil, "", fmt.Errorf("create OTLP HTTP metric exporter: %w", err)
+		}
+		return exporter, host + path, nil
+	default:
+		hostPort, insecure, err := normalizeGRPCEndpoint(rawEndpoint)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid OTLP gRPC endpoint: %w", err)
+		}
+		opts := []otlpmetricgrpc.Option{
+			otlpmetricgrpc.WithEndpoint(hostPort),
+			otlpmetricgrpc.WithTimeout(5 * time.Second),
+		}
+		if insecure {
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		}
+		if len(headers) > 0 {
+			opts = append(opts, otlpmetricgrpc.WithHeaders(headers))
+		}
+		exporter, err := otlpmetricgrpc.New(ctx, opts...)
+		if err != nil {
+			return nil, "", fmt.Errorf("create OTLP gRPC metric exporter: %w", err)
+		}
+		return exporter, hostPort, nil
+	}
+}
@@ -0,0 +1,80 @@
+package telemetry
+
+import (
+	"context"
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type peerAddrKey struct{}
+
+// CapturePeerAddr records the address of the connection before middleware
+// such as chi's RealIP replaces r.RemoteAddr with client-supplied
+// X-Forwarded-For or X-Real-IP headers. It must run first.
+func CapturePeerAddr(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), peerAddrKey{}, r.RemoteAddr)))
+	})
+}
+
+// PeerAddr returns the connection address recorded by CapturePeerAddr, or
+// r.RemoteAddr when it did not run.
+func PeerAddr(r *http.Request) string {
+	if addr, ok := r.Context().Value(peerAddrKey{}).(string); ok {
+		return addr
+	}
+	return r.RemoteAddr
+}
+
+// GuardMetrics wraps the /metrics handler with the optional scrape guards.
+// A non-empty token must be sent as "Authorization: Bearer <token>", and a
+// non-empty allowlist must contain the connection's address (see PeerAddr),
+// never a forwarded one; with both set a scrape has to pass both. With
+// neither, next is returned unchanged so in-cluster scrapers keep working
+// without configuration.
+func GuardMetrics(next http.Handler, token string, allowed []netip.Prefix) http.Handler {
+	if token == "" && len(allowed) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(allowed) > 0 && !addrAllowed(PeerAddr(r), allowed) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if token != "" && !bearerMatches(r.Header.Get("Authorization"), token) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func addrAllowed(remoteAddr string, allowed []netip.Prefix) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range allowed {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func bearerMatches(header, token string) bool {
+	scheme, value, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimSpace(value)), []byte(token)) == 1
+}
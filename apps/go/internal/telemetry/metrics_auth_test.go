@@ -0,0 +1,47 @@
+package telemetry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func TestGuardMetricsIgnoresForwardedAddress(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	allowed := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	// The order the API server mounts them in.
+	handler := CapturePeerAddr(middleware.RealIP(GuardMetrics(ok, "", allowed)))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     string
+		value      string
+		want       int
+	}{
+		{"allowed peer", "10.1.2.3:5000", "", "", http.StatusOK},
+		{"allowed peer behind spoofed header", "10.1.2.3:5000", "X-Forwarded-For", "203.0.113.9", http.StatusOK},
+		{"spoofed X-Forwarded-For", "203.0.113.9:5000", "X-Forwarded-For", "10.0.0.1", http.StatusForbidden},
+		{"spoofed X-Real-IP", "203.0.113.9:5000", "X-Real-IP", "10.0.0.1", http.StatusForbidden},
+		{"spoofed True-Client-IP", "203.0.113.9:5000", "True-Client-IP", "10.0.0.1", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.header != "" {
+				r.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
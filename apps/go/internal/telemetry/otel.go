@@ -2,6 +2,7 @@ package telemetry
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/url"
@@ -25,8 +26,9 @@ const (
 	defaultHTTPPath     = "/v1/traces"
 )
 
-// Init configures OpenTelemetry tracing for the running service.
-// It is a no-op when OTEL_EXPORTER_OTLP_ENDPOINT is not set.
+// Init configures OpenTelemetry tracing and metrics for the running service.
+// It is a no-op when OTEL_EXPORTER_OTLP_ENDPOINT is not set. The returned
+// shutdown func flushes pending spans and metrics.
 func Init(ctx context.Context, serviceName string, logger *slog.Logger) (func(context.Context) error, error) {
 	if logger == nil {
 		logger = slog.Default()
@@ -40,6 +42,7 @@ func Init(ctx context.Context, serviceName string, logger *slog.Logger) (func(co
 				propagation.Baggage{},
 			),
 		)
+		tracingDisabled.Store(true)
 		logger.Info("opentelemetry disabled", "reason", "OTEL_EXPORTER_OTLP_ENDPOINT not set")
 		return func(context.Context) error { return nil }, nil
 	}
@@ -73,13 +76,42 @@ func Init(ctx context.Context, serviceName string, logger *slog.Logger) (func(co
 		return nil, fmt.Errorf("build otel resource: %w", err)
 	}
 
+	sampling := buildSampler()
+	var processor sdktrace.SpanProcessor = sdktrace.NewBatchSpanProcessor(exporter)
+	if sampling.captureErrors {
+		processor = errorSpanProcessor{next: processor}
+	}
 	tp := sdktrace.NewTracerProvider(
 		sdktrace.WithResource(res),
-		sdktrace.WithSampler(buildSampler()),
-		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sampling.sampler),
+		sdktrace.WithSpanProcessor(processor),
 	)
+	tracingDisabled.Store(sampling.disabled)
+	ratio := sampling.traceRatio()
+	traceSampleRatio.Store(&ratio)
 
 	otel.SetTracerProvider(tp)
+
+	shutdown := tp.Shutdown
+	if metricsEnabled() {
+		mp, metricsEndpoint, err := newMeterProvider(ctx, protocol, endpoint, headers, res)
+		if err != nil {
+			_ = tp.Shutdown(ctx)
+			return nil, err
+		}
+		otel.SetMeterProvider(mp)
+		shutdown = func(ctx context.Context) error {
+			// Both providers flush on shutdown; report every failure.
+			return errors.Join(mp.Shutdown(ctx), tp.Shutdown(ctx))
+		}
+		logger.Info(
+			"opentelemetry metrics enabled",
+			"service", serviceName,
+			"protocol", protocol,
+			"endpoint", metricsEndpoint,
+		)
+	}
+
 	otel.SetTextMapPropagator(
 		propagation.NewCompositeTextMapPropagator(
 			propagation.TraceContext{},
@@ -92,9 +124,12 @@ func Init(ctx context.Context, serviceName string, logger *slog.Logger) (func(co
 		"service", serviceName,
 		"protocol", protocol,
 		"endpoint", resolvedEndpoint,
+		"sampler", sampling.name,
+		"ratio", sampling.ratio,
+		"sample_errors", sampling.captureErrors,
 	)
 
-	return tp.Shutdown, nil
+	return shutdown, nil
 }
 
 func newExporter(
@@ -105,7 +140,7 @@ func newExporter(
 ) (sdktrace.SpanExporter, string, error) {
 	switch protocol {
 	case "http":
-		host, path, insecure, err := normalizeHTTPEndpoint(rawEndpoint)
+		host, path, insecure, err := normalizeHTTPEndpoint(rawEndpoint, defaultHTTPPath)
 		if err != nil {
 			return nil, "", fmt.Errorf("invalid OTLP HTTP endpoint: %w", err)
 		}
@@ -193,13 +228,13 @@ func normalizeGRPCEndpoint(raw string) (hostPort string, insecure bool, err erro
 	return candidate, insecure, nil
 }
 
-func normalizeHTTPEndpoint(raw string) (host string, path string, insecure bool, err error) {
+func normalizeHTTPEndpoint(raw string, defaultPath string) (host string, path string, insecure bool, err error) {
 	candidate := strings.TrimSpace(raw)
 	if candidate == "" {
 		return "", "", false, fmt.Errorf("endpoint is required")
 	}
 
-	path = defaultHTTPPath
+	path = defaultPath
 	if strings.Contains(candidate, "://") {
 		parsed, parseErr := url.Parse(candidate)
 		if parseErr != nil {
@@ -211,6 +246,10 @@ func normalizeHTTPEndpoint(raw string) (host string, path string, insecure bool,
 		host = parsed.Host
 		if parsed.Path != "" && parsed.Path != "/" {
 			path = parsed.Path
+			// An endpoint pointing at the trace path is reused for other signals.
+			if defaultPath != defaultHTTPPath && strings.HasSuffix(path, defaultHTTPPath) {
+				path = strings.TrimSuffix(path, defaultHTTPPath) + defaultPath
+			}
 		}
 		if parsed.Scheme == "http" {
 			insecure = true
@@ -233,29 +272,6 @@ func normalizeHTTPEndpoint(raw string) (host string, path string, insecure bool,
 	return host, path, insecure, nil
 }
 
-func buildSampler() sdktrace.Sampler {
-	ratio := 1.0
-	if raw := strings.TrimSpace(os.Getenv("OTEL_TRACES_SAMPLER_ARG")); raw != "" {
-		if parsed, err := strconv.ParseFloat(raw, 64); err == nil {
-			ratio = clamp(parsed, 0, 1)
-		}
-	}
-
-	samplerName := strings.ToLower(strings.TrimSpace(os.Getenv("OTEL_TRACES_SAMPLER")))
-	switch samplerName {
-	case "always_off", "alwaysoff":
-		return sdktrace.NeverSample()
-	case "always_on", "alwayson":
-		return sdktrace.AlwaysSample()
-	case "traceidratio":
-		return sdktrace.TraceIDRatioBased(ratio)
-	case "", "parentbased_traceidratio":
-		fallthrough
-	default:
-		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))
-	}
-}
-
 func parseHeadersEnv(raw string) map[string]string {
 	headers := map[string]string{}
 	for _, pair := range strings.Split(raw, ",") {
@@ -0,0 +1,179 @@
+package telemetry
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const defaultSampleRatio = 1.0
+
+var tracingDisabled atomic.Bool
+
+// traceSampleRatio is the ratio Init configured, so parents built outside the
+// SDK can be flagged the way the sampler would flag a new trace. Nil means
+// Init has not run and everything samples.
+var traceSampleRatio atomic.Pointer[float64]
+
+// TracingEnabled reports whether spans can be sampled at all. Hot paths use
+// it to skip span creation when no exporter is configured or the sampler
+// never records (ratio 0 or always_off).
+func TracingEnabled() bool {
+	return !tracingDisabled.Load()
+}
+
+// samplingConfig is the sampler resolved from OTEL_TRACES_SAMPLER and
+// OTEL_TRACES_SAMPLER_ARG. When captureErrors is set, spans the sampler drops
+// are still recorded and exported if they end with an error status.
+type samplingConfig struct {
+	name          string
+	ratio         float64
+	sampler       sdktrace.Sampler
+	captureErrors bool
+	disabled      bool
+}
+
+func buildSampler() samplingConfig {
+	ratio := defaultSampleRatio
+	if raw := strings.TrimSpace(os.Getenv("OTEL_TRACES_SAMPLER_ARG")); raw != "" {
+		if parsed, err := strconv.ParseFloat(raw, 64); err == nil {
+			ratio = clamp(parsed, 0, 1)
+		}
+	}
+
+	captureErrors := true
+	if value, ok := parseBoolEnv("OTEL_TRACES_SAMPLE_ERRORS"); ok {
+		captureErrors = value
+	}
+
+	cfg := samplingConfig{ratio: ratio}
+	samplerName := strings.ToLower(strings.TrimSpace(os.Getenv("OTEL_TRACES_SAMPLER")))
+	switch samplerName {
+	case "always_off", "alwaysoff":
+		cfg.name = "always_off"
+		cfg.sampler = sdktrace.NeverSample()
+		cfg.disabled = true
+		return cfg
+	case "parentbased_always_off":
+		cfg.name = "parentbased_always_off"
+		cfg.sampler = sdktrace.ParentBased(sdktrace.NeverSample())
+		return cfg
+	case "always_on", "alwayson":
+		cfg.name = "always_on"
+		cfg.sampler = sdktrace.AlwaysSample()
+		return cfg
+	case "parentbased_always_on":
+		cfg.name = "parentbased_always_on"
+		cfg.sampler = sdktrace.ParentBased(sdktrace.AlwaysSample())
+		return cfg
+	case "traceidratio":
+		cfg.name = "traceidratio"
+		cfg.sampler = sdktrace.TraceIDRatioBased(ratio)
+	case "", "parentbased_traceidratio":
+		fallthrough
+	default:
+		cfg.name = "parentbased_traceidratio"
+		cfg.sampler = sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))
+	}
+
+	// A zero ratio starts no traces of its own, and does not record every
+	// span just to catch errors. The parent-based sampler still follows
+	// sampled upstream traces, so only the plain ratio sampler turns tracing
+	// off entirely.
+	if ratio <= 0 {
+		if cfg.name == "traceidratio" {
+			cfg.sampler = sdktrace.NeverSample()
+			cfg.disabled = true
+		}
+		return cfg
+	}
+	if captureErrors && ratio < 1 {
+		cfg.sampler = recordDroppedSampler{base: cfg.sampler}
+		cfg.captureErrors = true
+	}
+	return cfg
+}
+
+// traceRatio is the share of traces the sampler keeps when it decides on its
+// own, without a sampled parent.
+func (c samplingConfig) traceRatio() float64 {
+	switch {
+	case c.disabled || c.name == "parentbased_always_off":
+		return 0
+	case c.name == "always_on" || c.name == "parentbased_always_on":
+		return 1
+	}
+	return c.ratio
+}
+
+// sampleTrace reports whether the configured ratio keeps traceID. The
+// decision depends on the trace id alone, so every service agrees on it.
+func sampleTrace(traceID trace.TraceID) bool {
+	ratio := traceSampleRatio.Load()
+	if ratio == nil {
+		return true
+	}
+	result := sdktrace.TraceIDRatioBased(*ratio).ShouldSample(sdktrace.SamplingParameters{TraceID: traceID})
+	return result.Decision == sdktrace.RecordAndSample
+}
+
+// recordDroppedSampler downgrades Drop decisions to RecordOnly so that
+// errorSpanProcessor can still export spans that fail.
+type recordDroppedSampler struct {
+	base sdktrace.Sampler
+}
+
+func (s recordDroppedSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	result := s.base.ShouldSample(p)
+	if result.Decision == sdktrace.Drop {
+		result.Decision = sdktrace.RecordOnly
+	}
+	return result
+}
+
+func (s recordDroppedSampler) Description() string {
+	return "RecordDropped{" + s.base.Description() + "}"
+}
+
+// errorSpanProcessor forwards sampled spans to next unchanged and promotes
+// unsampled spans that ended with an error, so failures are always exported.
+type errorSpanProcessor struct {
+	next sdktrace.SpanProcessor
+}
+
+func (p errorSpanProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	p.next.OnStart(parent, s)
+}
+
+func (p errorSpanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if !s.SpanContext().IsSampled() {
+		if s.Status().Code != codes.Error {
+			return
+		}
+		s = promotedSpan{ReadOnlySpan: s}
+	}
+	p.next.OnEnd(s)
+}
+
+func (p errorSpanProcessor) Shutdown(ctx context.Context) error {
+	return p.next.Shutdown(ctx)
+}
+
+func (p errorSpanProcessor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
+
+type promotedSpan struct {
+	sdktrace.ReadOnlySpan
+}
+
+func (s promotedSpan) SpanContext() trace.SpanContext {
+	sc := s.ReadOnlySpan.SpanContext()
+	return sc.WithTraceFlags(sc.TraceFlags().WithSampled(true))
+}
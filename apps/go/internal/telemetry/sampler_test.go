@@ -0,0 +1,67 @@
+package telemetry
+
+import (
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestBuildSampler(t *testing.T) {
+	sampledParent := trace.ContextWithSpanContext(t.Context(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	}))
+	// All-ones trace id: the ratio samplers drop it for any ratio below 1,
+	// so only a parent-based sampler keeps it under a sampled parent.
+	rootTraceID := trace.TraceID{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+
+	tests := []struct {
+		sampler, arg, sampleErrors string
+		wantName                   string
+		wantDisabled               bool
+		wantCaptureErrors          bool
+		wantRatio                  float64
+		wantRoot                   sdktrace.SamplingDecision
+		wantSampledParent          sdktrace.SamplingDecision
+	}{
+		{"", "", "", "parentbased_traceidratio", false, false, 1, sdktrace.RecordAndSample, sdktrace.RecordAndSample},
+		{"parentbased_traceidratio", "0.5", "", "parentbased_traceidratio", false, true, 0.5, sdktrace.RecordOnly, sdktrace.RecordAndSample},
+		{"parentbased_traceidratio", "0.5", "false", "parentbased_traceidratio", false, false, 0.5, sdktrace.Drop, sdktrace.RecordAndSample},
+		// A zero ratio keeps following sampled upstream traces.
+		{"parentbased_traceidratio", "0", "", "parentbased_traceidratio", false, false, 0, sdktrace.Drop, sdktrace.RecordAndSample},
+		{"traceidratio", "0", "", "traceidratio", true, false, 0, sdktrace.Drop, sdktrace.Drop},
+		{"traceidratio", "0.5", "", "traceidratio", false, true, 0.5, sdktrace.RecordOnly, sdktrace.RecordOnly},
+		{"always_on", "0", "", "always_on", false, false, 1, sdktrace.RecordAndSample, sdktrace.RecordAndSample},
+		{"parentbased_always_on", "", "", "parentbased_always_on", false, false, 1, sdktrace.RecordAndSample, sdktrace.RecordAndSample},
+		{"always_off", "", "", "always_off", true, false, 0, sdktrace.Drop, sdktrace.Drop},
+		{"parentbased_always_off", "", "", "parentbased_always_off", false, false, 0, sdktrace.Drop, sdktrace.RecordAndSample},
+		{"unknown", "2", "", "parentbased_traceidratio", false, false, 1, sdktrace.RecordAndSample, sdktrace.RecordAndSample},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sampler+"/"+tt.arg+"/"+tt.sampleErrors, func(t *testing.T) {
+			t.Setenv("OTEL_TRACES_SAMPLER", tt.sampler)
+			t.Setenv("OTEL_TRACES_SAMPLER_ARG", tt.arg)
+			t.Setenv("OTEL_TRACES_SAMPLE_ERRORS", tt.sampleErrors)
+
+			cfg := buildSampler()
+			if cfg.name != tt.wantName || cfg.disabled != tt.wantDisabled || cfg.captureErrors != tt.wantCaptureErrors {
+				t.Fatalf("buildSampler() = {name: %q, disabled: %v, captureErrors: %v}, want {%q, %v, %v}",
+					cfg.name, cfg.disabled, cfg.captureErrors, tt.wantName, tt.wantDisabled, tt.wantCaptureErrors)
+			}
+			if got := cfg.traceRatio(); got != tt.wantRatio {
+				t.Fatalf("traceRatio() = %v, want %v", got, tt.wantRatio)
+			}
+			root := cfg.sampler.ShouldSample(sdktrace.SamplingParameters{ParentContext: t.Context(), TraceID: rootTraceID})
+			if root.Decision != tt.wantRoot {
+				t.Fatalf("root decision = %v, want %v", root.Decision, tt.wantRoot)
+			}
+			child := cfg.sampler.ShouldSample(sdktrace.SamplingParameters{ParentContext: sampledParent, TraceID: rootTraceID})
+			if child.Decision != tt.wantSampledParent {
+				t.Fatalf("sampled parent decision = %v, want %v", child.Decision, tt.wantSampledParent)
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package types
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 // Pipeline types
 
@@ -11,6 +15,14 @@ type PipelineCreateRequest struct {
 	Stages           []StageCreate     `json:"stages"`
 	PipelineKeywords []PipelineKeyword `json:"pipelineKeywords,omitempty"`
 	PipelineContext  []ContextItem     `json:"pipelineContextItems,omitempty"`
+	// Labels are free-form key/value tags the pipelines listing can filter by.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Priority from 0 (the default) to 9; stages of higher-priority
+	// pipelines are dispatched first.
+	Priority *int `json:"priority,omitempty"`
+	// StrictHandlers rejects the pipeline when a non-event stage's handler
+	// has no online worker. Unset uses the server default.
+	StrictHandlers *bool `json:"strictHandlers,omitempty"`
 }
 
 type StageCreate struct {
@@ -24,16 +36,26 @@ type StageCreate struct {
 }
 
 type StageOptions struct {
-	RunNextIfFailed   *bool    `json:"runNextIfFailed,omitempty"`
-	RetryInterval     *int     `json:"retryInterval,omitempty"` // seconds
-	TimeOut           *int     `json:"timeOut,omitempty"`       // seconds
-	MaxRetries        *int     `json:"maxRetries,omitempty"`
-	DependsOn         []string `json:"dependsOn,omitempty"`
-	RunInParallelWith []string `json:"runInParallelWith,omitempty"`
-	FailIfOutputEmpty *bool    `json:"failIfOutputEmpty,omitempty"`
-	NotifyOnFailure   *bool    `json:"notifyOnFailure,omitempty"`
-	RunAsUser         *string  `json:"runAsUser,omitempty"`
-}
+	RunNextIfFailed    *bool    `json:"runNextIfFailed,omitempty"`
+	RetryInterval      *int     `json:"retryInterval,omitempty"` // seconds
+	TimeOut            *int     `json:"timeOut,omitempty"`       // seconds
+	MaxRetries         *int     `json:"maxRetries,omitempty"`
+	MaxAttempts        *int     `json:"maxAttempts,omitempty"` // first run included; only lowers STAGE_MAX_ATTEMPTS
+	DependsOn          []string `json:"dependsOn,omitempty"`
+	RunInParallelWith  []string `json:"runInParallelWith,omitempty"`
+	FailIfOutputEmpty  *bool    `json:"failIfOutputEmpty,omitempty"`
+	NotifyOnFailure    *bool    `json:"notifyOnFailure,omitempty"`
+	RunAsUser          *string  `json:"runAsUser,omitempty"`
+	OnTimeout          *string  `json:"onTimeout,omitempty"`          // TimeoutActionFail or TimeoutActionRetry
+	ExpectedDurationMs *int     `json:"expectedDurationMs,omitempty"` // overrides the handler baseline of the SLA watcher
+}
+
+// Actions the pending watcher takes on a stage that exceeded its timeout;
+// see StageOptions.OnTimeout and PolicyRule.OnTimeout.
+const (
+	TimeoutActionFail  = "fail"
+	TimeoutActionRetry = "retry"
+)
 
 type PipelineResponse struct {
 	ID               int               `json:"id"`
@@ -47,27 +69,160 @@ type PipelineResponse struct {
 	Stages           []StageResponse   `json:"stages,omitempty"`
 	PipelineContext  []ContextItem     `json:"pipelineContextItems,omitempty"`
 	PipelineKeywords []PipelineKeyword `json:"pipelineKeywords,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	Priority         int               `json:"priority"`
 	IsEvent          *bool             `json:"isEvent,omitempty"`
+	StageGraph       map[int][]int     `json:"stageGraph,omitempty"`
+	// Warnings lists non-fatal problems found while creating the pipeline.
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 type StageResponse struct {
-	ID               int           `json:"id" db:"id"`
-	PipelineID       int           `json:"pipelineId" db:"pipeline_id"`
-	SpanID           string        `json:"spanId,omitempty" db:"span_id"`
-	Name             string        `json:"name" db:"name"`
-	StageHandlerName string        `json:"stageHandlerName,omitempty" db:"stage_handler_name"`
-	Description      string        `json:"description,omitempty" db:"description"`
-	Status           string        `json:"status,omitempty" db:"status"`
-	CreatedAt        time.Time     `json:"createdAt" db:"created_at"`
-	FinishedAt       *time.Time    `json:"finishedAt,omitempty" db:"finished_at"`
-	StartedAt        *time.Time    `json:"startedAt,omitempty" db:"started_at"`
-	Output           *string       `json:"output,omitempty" db:"output"`
-	Input            *string       `json:"input,omitempty" db:"input"`
-	IsSkipped        *bool         `json:"isSkipped,omitempty" db:"is_skipped"`
-	IsEvent          *bool         `json:"isEvent,omitempty" db:"is_event"`
-	NextStageID      *int          `json:"nextStageId,omitempty"`
-	Logs             []StageLog    `json:"logs,omitempty"`
-	Options          *StageOptions `json:"options,omitempty"`
+	ID                int           `json:"id" db:"id"`
+	PipelineID        int           `json:"pipelineId" db:"pipeline_id"`
+	SpanID            string        `json:"spanId,omitempty" db:"span_id"`
+	Name              string        `json:"name" db:"name"`
+	StageHandlerName  string        `json:"stageHandlerName,omitempty" db:"stage_handler_name"`
+	Description       string        `json:"description,omitempty" db:"description"`
+	Status            string        `json:"status,omitempty" db:"status"`
+	CreatedAt         time.Time     `json:"createdAt" db:"created_at"`
+	FinishedAt        *time.Time    `json:"finishedAt,omitempty" db:"finished_at"`
+	StartedAt         *time.Time    `json:"startedAt,omitempty" db:"started_at"`
+	NextRetryAt       *time.Time    `json:"nextRetryAt,omitempty" db:"next_retry_at"`
+	Output            *string       `json:"output,omitempty" db:"output"`
+	OutputTruncated   bool          `json:"outputTruncated,omitempty" db:"output_truncated"`
+	OutputBytes       *int          `json:"outputBytes,omitempty" db:"output_bytes"`
+	Input             *string       `json:"input,omitempty" db:"input"`
+	IsSkipped         *bool         `json:"isSkipped,omitempty" db:"is_skipped"`
+	IsEvent           *bool         `json:"isEvent,omitempty" db:"is_event"`
+	NextStageID       *int          `json:"nextStageId,omitempty"`
+	DependsOn         []int         `json:"dependsOn,omitempty"`
+	RunInParallelWith []int         `json:"runInParallelWith,omitempty"`
+	Logs              []StageLog    `json:"logs,omitempty"`
+	Options           *StageOptions `json:"options,omitempty"`
+	Failure           *StageFailure `json:"failure,omitempty" db:"failure_detail"`
+}
+
+// StageFailure describes why a stage last failed. Reason is a finer code
+// within the category, such as pending_timeout; AgeSeconds and
+// TimeoutSeconds are set for timeouts.
+type StageFailure struct {
+	Category       string    `json:"category"`
+	Reason         string    `json:"reason,omitempty"`
+	AgeSeconds     *int64    `json:"ageSeconds,omitempty"`
+	TimeoutSeconds *int64    `json:"timeoutSeconds,omitempty"`
+	At             time.Time `json:"at"`
+}
+
+// Scan reads a StageFailure stored as JSON.
+func (f *StageFailure) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("scan stage failure: unsupported type %T", src)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, f)
+}
+
+// StageExecutionHistoryEntry is a stage run archived when the stage was
+// rerun. Attempt numbers the archived runs of the stage from 1.
+type StageExecutionHistoryEntry struct {
+	ID              int        `json:"id" db:"id"`
+	Attempt         int        `json:"attempt" db:"attempt"`
+	RetryAttempt    int        `json:"retryAttempt" db:"retry_attempt"`
+	Status          *string    `json:"status,omitempty" db:"status"`
+	Input           *string    `json:"input,omitempty" db:"input"`
+	Output          *string    `json:"output,omitempty" db:"output"`
+	OutputTruncated bool       `json:"outputTruncated,omitempty" db:"output_truncated"`
+	StartedAt       *time.Time `json:"startedAt,omitempty" db:"started_at"`
+	FinishedAt      *time.Time `json:"finishedAt,omitempty" db:"finished_at"`
+	ArchivedAt      time.Time  `json:"archivedAt" db:"archived_at"`
+	Source          string     `json:"source" db:"source"`
+}
+
+// StageExecutionHistoryResponse lists a stage's archived runs, newest first.
+type StageExecutionHistoryResponse struct {
+	StageID int                          `json:"stageId"`
+	Items   []StageExecutionHistoryEntry `json:"items"`
+}
+
+// Pipeline graph modes: how the scheduler orders a pipeline's stages.
+const (
+	PipelineGraphModeDAG        = "dag"
+	PipelineGraphModeSequential = "sequential"
+)
+
+// Pipeline graph edge kinds. A dependsOn and a sequence edge point from the
+// stage that runs first; a runInParallelWith edge has no direction.
+const (
+	PipelineGraphEdgeDependsOn         = "dependsOn"
+	PipelineGraphEdgeRunInParallelWith = "runInParallelWith"
+	PipelineGraphEdgeSequence          = "sequence"
+)
+
+// PipelineGraph is a pipeline's stages and the links between them, for
+// rendering. Frontier lists the stages that are dispatched next, and Cycles
+// the dependency cycles, whose stages never run.
+type PipelineGraph struct {
+	PipelineID int                 `json:"pipelineId"`
+	Mode       string              `json:"mode"`
+	Nodes      []PipelineGraphNode `json:"nodes"`
+	Edges      []PipelineGraphEdge `json:"edges"`
+	Frontier   []int               `json:"frontier"`
+	Cycles     [][]int             `json:"cycles,omitempty"`
+}
+
+type PipelineGraphNode struct {
+	ID               int    `json:"id"`
+	Name             string `json:"name"`
+	StageHandlerName string `json:"stageHandlerName,omitempty"`
+	Status           string `json:"status"`
+	IsEvent          bool   `json:"isEvent,omitempty"`
+	IsSkipped        bool   `json:"isSkipped,omitempty"`
+	InCycle          bool   `json:"inCycle,omitempty"`
+	Frontier         bool   `json:"frontier,omitempty"`
+}
+
+type PipelineGraphEdge struct {
+	From int    `json:"from"`
+	To   int    `json:"to"`
+	Kind string `json:"kind"`
+}
+
+// Pipeline timeline event kinds.
+const (
+	TimelineEventPipeline = "pipeline"
+	TimelineEventStage    = "stage"
+)
+
+// PipelineTimelineEvent is one status change of a pipeline or one of its
+// stages. Source names what made it, such as publisher or rerun_stage; a
+// pipeline change carries the stage change that caused it.
+type PipelineTimelineEvent struct {
+	Kind       string    `json:"kind"`
+	StageID    *int      `json:"stageId,omitempty"`
+	StageName  string    `json:"stageName,omitempty"`
+	FromStatus string    `json:"fromStatus,omitempty"`
+	ToStatus   string    `json:"toStatus"`
+	Source     string    `json:"source"`
+	At         time.Time `json:"at"`
+}
+
+// PipelineTimelineResponse lists a pipeline's status changes oldest first.
+// Truncated reports that the newest ones were left out.
+type PipelineTimelineResponse struct {
+	PipelineID int                     `json:"pipelineId"`
+	Events     []PipelineTimelineEvent `json:"events"`
+	Truncated  bool                    `json:"truncated,omitempty"`
 }
 
 type StageLog struct {
@@ -85,12 +240,17 @@ type GetPipelinesRequest struct {
 	PageSize          *int     `json:"pageSize"`
 	ApplicationID     *int     `json:"applicationId"`
 	Search            *string  `json:"search"`
+	TraceID           *string  `json:"traceId"`
 	Keywords          []string `json:"keywords"`
+	KeywordMatch      string   `json:"keywordMatch"`
+	Labels            []string `json:"labels"` // key=value, all must match
 	PipelineStartFrom *string  `json:"pipelineStartFrom"`
 	PipelineStartTo   *string  `json:"pipelineStartTo"`
 	PipelineEndFrom   *string  `json:"pipelineEndFrom"`
 	PipelineEndTo     *string  `json:"pipelineEndTo"`
 	Statuses          []string `json:"statuses"`
+	SortBy            *string  `json:"sortBy"`
+	SortDir           *string  `json:"sortDir"`
 }
 
 type PagedResult[T any] struct {
@@ -111,6 +271,24 @@ type SkipStageRequest struct {
 	StageID int `json:"stageId"`
 }
 
+// BulkStageRequest selects stages either by id or by pipeline, optionally
+// narrowed to a single stage status (e.g. all failed stages).
+type BulkStageRequest struct {
+	StageIDs   []int  `json:"stageIds,omitempty"`
+	PipelineID *int   `json:"pipelineId,omitempty"`
+	Status     string `json:"status,omitempty"`
+}
+
+type BulkStageResult struct {
+	StageID int    `json:"stageId"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+type BulkStageResponse struct {
+	Results []BulkStageResult `json:"results"`
+}
+
 // Auth types
 
 type UserResponse struct {
@@ -122,6 +300,10 @@ type UserResponse struct {
 	CreatedAt time.Time `json:"createdAt" db:"created_at"`
 }
 
+// UserRoleAdmin is the role of users allowed to operate shared broker
+// resources such as dead-letter queues.
+const UserRoleAdmin = "Admin"
+
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
@@ -133,9 +315,30 @@ type ApplicationResponse struct {
 	ID          int              `json:"id" db:"id"`
 	Name        string           `json:"name" db:"name"`
 	Description *string          `json:"description,omitempty" db:"description"`
+	DisabledAt  *time.Time       `json:"disabledAt,omitempty" db:"disabled_at"`
 	ApiKeys     []ApiKeyResponse `json:"apiKeys,omitempty"`
 }
 
+// PipelineStatsResponse summarizes the pipelines an application started within
+// a time range.
+type PipelineStatsResponse struct {
+	ApplicationID  int                  `json:"applicationId"`
+	Range          string               `json:"range"`
+	Since          time.Time            `json:"since"`
+	Total          int                  `json:"total"`
+	CountsByStatus map[string]int       `json:"countsByStatus"`
+	SuccessRate    *float64             `json:"successRate"`
+	AvgDurationMs  *float64             `json:"avgDurationMs"`
+	BucketSeconds  int                  `json:"bucketSeconds"`
+	Executions     []PipelineStatsPoint `json:"executions"`
+}
+
+// PipelineStatsPoint counts the pipelines started in one bucket of the range.
+type PipelineStatsPoint struct {
+	Start time.Time `json:"start"`
+	Count int       `json:"count"`
+}
+
 type SaveApplicationRequest struct {
 	ID          *int    `json:"id,omitempty"`
 	Name        string  `json:"name"`
@@ -144,6 +347,39 @@ type SaveApplicationRequest struct {
 
 // ApiKey types
 
+// API key scopes restrict which external endpoints a key may call. A key
+// without explicit scopes may call all of them.
+const (
+	APIKeyScopePipelinesWrite  = "pipelines:write"
+	APIKeyScopeJobsConsume     = "jobs:consume"
+	APIKeyScopeWorkersRegister = "workers:register"
+)
+
+var APIKeyScopes = []string{
+	APIKeyScopePipelinesWrite,
+	APIKeyScopeJobsConsume,
+	APIKeyScopeWorkersRegister,
+}
+
+// APIKeyAuth is the result of validating an API key.
+type APIKeyAuth struct {
+	ApplicationID int
+	Scopes        []string
+}
+
+// HasScope reports whether the key grants scope.
+func (a APIKeyAuth) HasScope(scope string) bool {
+	if len(a.Scopes) == 0 {
+		return true
+	}
+	for _, s := range a.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
 type ApiKeyResponse struct {
 	ID            int        `json:"id" db:"id"`
 	ApplicationID int        `json:"applicationId" db:"application_id"`
@@ -153,6 +389,19 @@ type ApiKeyResponse struct {
 	DisabledAt    *time.Time `json:"disabledAt,omitempty" db:"disabled_at"`
 	ExpiresAt     *time.Time `json:"expiresAt,omitempty" db:"expires_at"`
 	LastUsed      *time.Time `json:"lastUsed,omitempty" db:"last_used"`
+	ScopeList     *string    `json:"-" db:"scopes"`
+	Scopes        []string   `json:"scopes,omitempty"`
+}
+
+// ExpiringApiKeyResponse is an enabled API key that expires soon. The key
+// itself is left out.
+type ExpiringApiKeyResponse struct {
+	ID              int       `json:"id" db:"id"`
+	ApplicationID   int       `json:"applicationId" db:"application_id"`
+	ApplicationName string    `json:"applicationName" db:"application_name"`
+	Name            *string   `json:"name,omitempty" db:"name"`
+	ExpiresAt       time.Time `json:"expiresAt" db:"expires_at"`
+	DaysRemaining   int       `json:"daysRemaining" db:"-"`
 }
 
 type GenerateApiKeyRequest struct {
@@ -161,6 +410,7 @@ type GenerateApiKeyRequest struct {
 	NewApplication *ApiKeyNewApplication `json:"newApplication,omitempty"`
 	Name           *string               `json:"name,omitempty"`
 	ExpiresAt      *time.Time            `json:"expiresAt,omitempty"`
+	Scopes         []string              `json:"scopes,omitempty"`
 }
 
 type ApiKeyNewApplication struct {
@@ -213,13 +463,25 @@ type WorkerBrokerInfo struct {
 	TopologyOwnership string `json:"topologyOwnership"`
 	DLQEnabled        bool   `json:"dlqEnabled"`
 	DLQTTLSec         int64  `json:"dlqTtlSec"`
+	// QueueType is x-queue-type for every stage queue; MaxLength and
+	// Overflow apply to the per-handler stage queues only. Clients owning
+	// the topology must declare the same arguments.
+	QueueType string `json:"queueType,omitempty"`
+	MaxLength int    `json:"maxLength,omitempty"`
+	Overflow  string `json:"overflow,omitempty"`
 }
 
 type WorkerQueueTopology struct {
 	StageResult        string `json:"stageResult"`
+	StageResultShards  int    `json:"stageResultShards"`
+	StageResultPattern string `json:"stageResultPattern,omitempty"`
 	StageSetStatus     string `json:"stageSetStatus"`
 	StageUpdatedFanout string `json:"stageUpdatedFanout"`
 	StageNextPattern   string `json:"stageNextPattern"`
+	// StageNextEnvPattern names the StageNext queues of pipelines with an
+	// environment; a worker with an environment consumes those instead. It
+	// is empty unless STAGE_ENVIRONMENT_ROUTING is on.
+	StageNextEnvPattern string `json:"stageNextEnvPattern,omitempty"`
 }
 
 type WorkerHeartbeatContract struct {
@@ -232,6 +494,72 @@ type WorkerObservabilityInfo struct {
 	LogsLinkTemplate  string `json:"logsLinkTemplate,omitempty"`
 }
 
+// RuntimeConfigResponse lists the non-secret settings the API process runs
+// with. Durations are in seconds.
+type RuntimeConfigResponse struct {
+	AppID    string                   `json:"appId"`
+	Logging  RuntimeLoggingConfig     `json:"logging"`
+	Broker   RuntimeBrokerConfig      `json:"broker"`
+	Gateway  RuntimeGatewayConfig     `json:"gateway"`
+	Workers  RuntimeWorkerConfig      `json:"workers"`
+	External RuntimeExternalAPIConfig `json:"externalApi"`
+	Policies RuntimePolicyConfig      `json:"policies"`
+}
+
+type RuntimeLoggingConfig struct {
+	Level             string `json:"level"`
+	Format            string `json:"format"`
+	AccessLogLevel    string `json:"accessLogLevel"`
+	AccessLogBodySize bool   `json:"accessLogBodySize"`
+}
+
+type RuntimeBrokerConfig struct {
+	Prefetch                int     `json:"prefetch"`
+	TopologyOwnership       string  `json:"topologyOwnership"`
+	DLQEnabled              bool    `json:"dlqEnabled"`
+	DLQTTLSec               int64   `json:"dlqTtlSec"`
+	QueueType               string  `json:"queueType,omitempty"`
+	MaxLength               int     `json:"maxLength,omitempty"`
+	Overflow                string  `json:"overflow,omitempty"`
+	ResultQueueShards       int     `json:"resultQueueShards"`
+	PublisherConfirms       bool    `json:"publisherConfirms"`
+	ExposeURL               bool    `json:"exposeUrl"`
+	WorkerCredentials       bool    `json:"workerCredentials"` // RABBIT_WORKER_USERNAME/PASSWORD are set
+	ReconnectMaxIntervalSec int64   `json:"reconnectMaxIntervalSec"`
+	ReconnectJitter         float64 `json:"reconnectJitter"`
+}
+
+type RuntimeGatewayConfig struct {
+	VisibilityTimeoutSec int64          `json:"visibilityTimeoutSec"`
+	MaxInFlight          int            `json:"maxInFlight"`
+	PullPrefetch         int            `json:"pullPrefetch"`
+	QueuePrefetch        map[string]int `json:"queuePrefetch,omitempty"`
+}
+
+type RuntimeWorkerConfig struct {
+	HeartbeatIntervalSec int64 `json:"heartbeatIntervalSec"`
+	OfflineAfterSec      int64 `json:"offlineAfterSec"`
+	SessionTTLSec        int64 `json:"sessionTtlSec"`
+	EventsMaxBatch       int   `json:"eventsMaxBatch"`
+	ListStaleAfterSec    int64 `json:"listStaleAfterSec"`
+	IngestMaxBodyBytes   int   `json:"ingestMaxBodyBytes"`
+	GRPCEnabled          bool  `json:"grpcEnabled"`
+}
+
+type RuntimeExternalAPIConfig struct {
+	RateLimitRPS              int    `json:"rateLimitRps"`
+	RateLimitBurst            int    `json:"rateLimitBurst"`
+	RateLimitStore            string `json:"rateLimitStore"`
+	PipelineStrictHandlers    bool   `json:"pipelineStrictHandlers"`
+	APIKeyExpiryWarnWithinSec int64  `json:"apiKeyExpiryWarnWithinSec"`
+}
+
+type RuntimePolicyConfig struct {
+	TargetOptionsLimit       int    `json:"targetOptionsLimit"`
+	TargetOptionsCacheTTLSec int64  `json:"targetOptionsCacheTtlSec"`
+	PreviewEnvMatch          string `json:"previewEnvMatch"`
+}
+
 type WorkerHeartbeatRequest struct {
 	WorkerID        string         `json:"workerId"`
 	State           string         `json:"state"`
@@ -246,6 +574,8 @@ type WorkerHeartbeatRequest struct {
 	LastError       *string        `json:"lastError,omitempty"`
 	Message         *string        `json:"message,omitempty"`
 	Metadata        map[string]any `json:"metadata,omitempty"`
+	// ConfigVersion is the version from the worker's bootstrap response.
+	ConfigVersion *string `json:"configVersion,omitempty"`
 }
 
 type WorkerEventsRequest struct {
@@ -297,6 +627,10 @@ type WorkerStatusResponse struct {
 	SupportedHandlers []string       `json:"supportedHandlers,omitempty"`
 	Capabilities      map[string]any `json:"capabilities,omitempty"`
 	Metadata          map[string]any `json:"metadata,omitempty"`
+	// ConfigVersion is the config version the worker last reported or was
+	// handed; ConfigOutdated is set when it is older than the current one.
+	ConfigVersion  *string `json:"configVersion,omitempty"`
+	ConfigOutdated bool    `json:"configOutdated"`
 }
 
 type WorkerStatusListResponse struct {
@@ -308,6 +642,66 @@ type WorkerStatusListResponse struct {
 	OfflineAfterSec int64                  `json:"offlineAfterSec"`
 }
 
+// WorkerDetailResponse is a worker with its recent heartbeats, oldest first.
+type WorkerDetailResponse struct {
+	WorkerStatusResponse
+	Heartbeats      []WorkerHeartbeatPoint `json:"heartbeats"`
+	OfflineAfterSec int64                  `json:"offlineAfterSec"`
+}
+
+// WorkerConfigResponse is the bootstrap response a worker last received,
+// with the session token left out and the broker password masked.
+type WorkerConfigResponse struct {
+	WorkerID       string                  `json:"workerId"`
+	BootstrappedAt *string                 `json:"bootstrappedAt,omitempty"`
+	Config         WorkerBootstrapResponse `json:"config"`
+}
+
+type WorkerHeartbeatPoint struct {
+	TS              string   `json:"ts"`
+	State           string   `json:"state"`
+	BrokerConnected bool     `json:"brokerConnected"`
+	InFlightJobs    int      `json:"inFlightJobs"`
+	JobsProcessed   int64    `json:"jobsProcessed"`
+	JobsFailed      int64    `json:"jobsFailed"`
+	QueueLag        *int     `json:"queueLag,omitempty"`
+	CPUPercent      *float64 `json:"cpuPercent,omitempty"`
+	MemoryMB        *float64 `json:"memoryMb,omitempty"`
+}
+
+// HandlerCapacity is the aggregate capacity of the online workers serving
+// one handler of an application. MaxConcurrency and Utilization are nil when
+// any of those workers does not report a maxConcurrency capability.
+type HandlerCapacity struct {
+	ApplicationID    int      `json:"applicationId"`
+	ApplicationName  string   `json:"applicationName"`
+	Handler          string   `json:"handler"`
+	OnlineWorkers    int      `json:"onlineWorkers"`
+	MaxConcurrency   *int     `json:"maxConcurrency,omitempty"`
+	InFlightJobs     int      `json:"inFlightJobs"`
+	DispatchedStages int      `json:"dispatchedStages"`
+	Utilization      *float64 `json:"utilization,omitempty"`
+}
+
+type HandlerCapacityListResponse struct {
+	Items           []HandlerCapacity `json:"items"`
+	OfflineAfterSec int64             `json:"offlineAfterSec"`
+}
+
+// HandlerRegistryEntry is a handler referenced by stages, advertised by
+// online workers, or both.
+type HandlerRegistryEntry struct {
+	Handler       string     `json:"handler" db:"handler"`
+	OnlineWorkers int        `json:"onlineWorkers" db:"online_workers"`
+	SeenInStages  bool       `json:"seenInStages" db:"seen_in_stages"`
+	LastStageAt   *time.Time `json:"lastStageAt,omitempty" db:"last_stage_at"`
+}
+
+type HandlerRegistryResponse struct {
+	Items           []HandlerRegistryEntry `json:"items"`
+	OfflineAfterSec int64                  `json:"offlineAfterSec"`
+}
+
 type WorkerEventResponse struct {
 	ID              int64          `json:"id" db:"id"`
 	WorkerID        string         `json:"workerId" db:"worker_id"`
@@ -321,17 +715,57 @@ type WorkerEventResponse struct {
 	Details         map[string]any `json:"details,omitempty"`
 }
 
+const (
+	QueueEventBacklogHigh = "backlog_high"
+	QueueEventDLQMessage  = "dlq_message"
+)
+
+// QueueDepthEvent reports a queue whose depth met an alerting condition.
+type QueueDepthEvent struct {
+	Type      string
+	Queue     string
+	Depth     int
+	Threshold int
+	TS        time.Time
+}
+
 type WorkerListRequest struct {
 	ApplicationID *int
 	State         *string
 	Search        *string
 	Limit         int
+	// StoppedAfter hides stopped workers whose stopped_at is before it.
+	StoppedAfter *time.Time
 }
 
 type WorkerEventListRequest struct {
 	WorkerID      *string
 	ApplicationID *int
-	Limit         int
+	// MinLevel keeps events at this level or above (TRACE < DEBUG < INFO <
+	// WARN < ERROR); empty keeps all levels.
+	MinLevel string
+	// EventType keeps only events of this type, e.g. worker.state_changed.
+	EventType string
+	// Before and After page through events older or newer than a cursor.
+	Before *WorkerEventCursor
+	After  *WorkerEventCursor
+	Limit  int
+}
+
+// WorkerEventCursor marks a position in the worker event stream; ID breaks
+// ties between events with the same timestamp.
+type WorkerEventCursor struct {
+	TS time.Time
+	ID int64
+}
+
+// WorkerEventListResponse lists events newest first. NextCursor is set when
+// older events may remain and is passed back as before=; PrevCursor marks the
+// newest event returned and is passed back as after= to poll for new ones.
+type WorkerEventListResponse struct {
+	Items      []WorkerEventResponse `json:"items"`
+	NextCursor string                `json:"nextCursor,omitempty"`
+	PrevCursor string                `json:"prevCursor,omitempty"`
 }
 
 // Log types
@@ -353,3 +787,40 @@ type LogResponse struct {
 	CreatedAt     *time.Time        `json:"created,omitempty" db:"created_at"`
 	Keywords      []PipelineKeyword `json:"keywords,omitempty"`
 }
+
+// Dead letter types
+
+// DeadLetterRecord is a message a consumer dead-lettered, with the error that
+// made it fail. StageID, PipelineID and Handler are set when the message
+// names a stage.
+type DeadLetterRecord struct {
+	ID             int64          `json:"id"`
+	Queue          string         `json:"queue"`
+	StageID        *int           `json:"stageId,omitempty"`
+	PipelineID     *int           `json:"pipelineId,omitempty"`
+	Handler        *string        `json:"handler,omitempty"`
+	Reason         string         `json:"reason"`
+	MessageID      string         `json:"messageId,omitempty"`
+	Headers        map[string]any `json:"headers,omitempty"`
+	DeadLetteredAt time.Time      `json:"deadLetteredAt"`
+}
+
+type DeadLetterListRequest struct {
+	Queue   string
+	Handler string
+	StageID *int
+	Limit   int
+}
+
+type DeadLetterListResponse struct {
+	Items []DeadLetterRecord `json:"items"`
+}
+
+// Health types
+
+// ReadinessResponse reports each dependency checked by the readiness
+// endpoint as "ok" or "unavailable".
+type ReadinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
@@ -0,0 +1,24 @@
+package types
+
+import "testing"
+
+func TestAPIKeyAuthHasScope(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []string
+		scope  string
+		want   bool
+	}{
+		{"no scopes grant all", nil, APIKeyScopeJobsConsume, true},
+		{"listed scope", []string{APIKeyScopePipelinesWrite, APIKeyScopeJobsConsume}, APIKeyScopeJobsConsume, true},
+		{"unlisted scope", []string{APIKeyScopePipelinesWrite}, APIKeyScopeJobsConsume, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := APIKeyAuth{Scopes: tt.scopes}
+			if got := auth.HasScope(tt.scope); got != tt.want {
+				t.Fatalf("HasScope(%q) = %v, want %v", tt.scope, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package types
+
+import "time"
+
+// Feature flags gate behavior changes per application so they can be rolled
+// out gradually instead of deployment-wide.
+const (
+	FeatureFlagDAGExecution        = "dag_execution"
+	FeatureFlagDefaultStageRetries = "default_stage_retries"
+	FeatureFlagFailIfOutputEmpty   = "fail_if_output_empty"
+)
+
+// FeatureFlagDefaults lists every known flag with the value used when an
+// application has no explicit override.
+var FeatureFlagDefaults = map[string]bool{
+	FeatureFlagDAGExecution:        true,
+	FeatureFlagDefaultStageRetries: false,
+	FeatureFlagFailIfOutputEmpty:   false,
+}
+
+type FeatureFlag struct {
+	Flag      string     `json:"flag"`
+	Enabled   bool       `json:"enabled"`
+	Default   bool       `json:"default"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+}
+
+type SetFeatureFlagRequest struct {
+	Enabled bool `json:"enabled"`
+}
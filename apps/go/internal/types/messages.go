@@ -12,6 +12,13 @@ type StageNextMessage struct {
 	Input            string        `json:"input,omitempty"`
 	PrevStageOutput  string        `json:"prevStageOutput,omitempty"`
 	ContextItems     []ContextItem `json:"contextItems,omitempty"`
+	Attempt          int           `json:"attempt"`
+	IdempotencyKey   string        `json:"idempotencyKey,omitempty"`
+	// ResultQueue is where the worker should publish the StageResultMessage.
+	ResultQueue string `json:"resultQueue,omitempty"`
+	// Environment is the pipeline's environment, which picks the StageNext
+	// queue the stage is published to; empty means the default queue.
+	Environment string `json:"environment,omitempty"`
 }
 
 type StageResultMessage struct {
@@ -23,6 +30,12 @@ type StageResultMessage struct {
 	RunNextIfCurrentFailed bool              `json:"runNextIfCurrentFailed"`
 	Logs                   []StageLogMessage `json:"logs,omitempty"`
 	ContextItems           []ContextItem     `json:"contextItems,omitempty"`
+	// IdempotencyKey echoes StageNextMessage.IdempotencyKey so that a result
+	// redelivered for the same attempt is applied at most once.
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+	// FailureCategory classifies a failed result (timeout, handler_error,
+	// circuit_open, cancelled); empty or unknown values mean handler_error.
+	FailureCategory string `json:"failureCategory,omitempty"`
 }
 
 type StageLogMessage struct {
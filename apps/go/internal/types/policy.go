@@ -5,10 +5,11 @@ import "time"
 type PolicyType string
 
 const (
-	PolicyTypeRateLimit      PolicyType = "rate_limit"
-	PolicyTypeRetry          PolicyType = "retry"
-	PolicyTypeTimeout        PolicyType = "timeout"
-	PolicyTypeCircuitBreaker PolicyType = "circuit_breaker"
+	PolicyTypeRateLimit        PolicyType = "rate_limit"
+	PolicyTypeRetry            PolicyType = "retry"
+	PolicyTypeTimeout          PolicyType = "timeout"
+	PolicyTypeCircuitBreaker   PolicyType = "circuit_breaker"
+	PolicyTypeConcurrencyLimit PolicyType = "concurrency_limit"
 )
 
 type PolicyStatus string
@@ -35,6 +36,7 @@ type Policy struct {
 	Type        PolicyType        `json:"type"`
 	Status      PolicyStatus      `json:"status"`
 	Environment PolicyEnvironment `json:"environment"`
+	Priority    int               `json:"priority"`
 	Targeting   PolicyTargeting   `json:"targeting"`
 	Rule        PolicyRule        `json:"rule"`
 	CreatedAt   time.Time         `json:"createdAt"`
@@ -45,11 +47,28 @@ type Policy struct {
 }
 
 type PolicyTargeting struct {
-	Pipelines   []string `json:"pipelines"`
-	Stages      []string `json:"stages"`
-	Handlers    []string `json:"handlers"`
-	TagsInclude []string `json:"tagsInclude"`
-	TagsExclude []string `json:"tagsExclude"`
+	Pipelines   []string        `json:"pipelines"`
+	Stages      []string        `json:"stages"`
+	Handlers    []string        `json:"handlers"`
+	TagsInclude []string        `json:"tagsInclude"`
+	TagsExclude []string        `json:"tagsExclude"`
+	Schedule    *PolicySchedule `json:"schedule,omitempty"`
+}
+
+// PolicySchedule limits a policy to recurring time windows. A policy without
+// a schedule is always in effect.
+type PolicySchedule struct {
+	Timezone string                 `json:"timezone"`
+	Windows  []PolicyScheduleWindow `json:"windows"`
+}
+
+// PolicyScheduleWindow is active on Days (mon..sun, empty means every day)
+// from Start to End in HH:MM. An End before Start spans midnight into the
+// following day.
+type PolicyScheduleWindow struct {
+	Days  []string `json:"days,omitempty"`
+	Start string   `json:"start"`
+	End   string   `json:"end"`
 }
 
 type RetryOnRule struct {
@@ -67,14 +86,24 @@ type PolicyRule struct {
 	BaseDelayMs      *int         `json:"baseDelayMs,omitempty"`
 	MaxDelayMs       *int         `json:"maxDelayMs,omitempty"`
 	Jitter           *bool        `json:"jitter,omitempty"`
+	JitterMode       *string      `json:"jitterMode,omitempty"` // RetryJitterFull (default) or RetryJitterDecorrelated
 	RetryOn          *RetryOnRule `json:"retryOn,omitempty"`
 	TimeoutMs        *int         `json:"timeoutMs,omitempty"`
 	AppliesTo        *string      `json:"appliesTo,omitempty"`
+	OnTimeout        *string      `json:"onTimeout,omitempty"`
 	FailureThreshold *int         `json:"failureThreshold,omitempty"`
 	OpenSeconds      *int         `json:"openSeconds,omitempty"`
 	HalfOpenMaxCalls *int         `json:"halfOpenMaxCalls,omitempty"`
+	MaxConcurrent    *int         `json:"maxConcurrent,omitempty"`
 }
 
+// How a retry policy with jitter randomizes its delays; see
+// PolicyRule.JitterMode.
+const (
+	RetryJitterFull         = "full"
+	RetryJitterDecorrelated = "decorrelated"
+)
+
 type PolicyEventType string
 
 const (
@@ -101,11 +130,16 @@ type PolicyListItem struct {
 	Policy
 	LastTriggeredAt     *time.Time `json:"lastTriggeredAt,omitempty"`
 	TriggerCountInRange int        `json:"triggerCountInRange"`
+	InactiveBySchedule  bool       `json:"inactiveBySchedule"`
 }
 
+// PolicyListResponse holds one page of policies. TotalCount is the number of
+// policies matching the filter across all pages; Limit is 0 when unpaged.
 type PolicyListResponse struct {
 	Items      []PolicyListItem `json:"items"`
 	TotalCount int              `json:"totalCount"`
+	Limit      int              `json:"limit"`
+	Offset     int              `json:"offset"`
 }
 
 type PolicyInsightsTopPolicy struct {
@@ -116,6 +150,7 @@ type PolicyInsightsTopPolicy struct {
 
 type PolicyInsightsResponse struct {
 	ActivePoliciesCount     int                      `json:"activePoliciesCount"`
+	InactiveByScheduleCount int                      `json:"inactiveByScheduleCount"`
 	PoliciesTriggered       int                      `json:"policiesTriggered"`
 	ActionsBlockedThrottled int                      `json:"actionsBlockedThrottled"`
 	TopPolicy               *PolicyInsightsTopPolicy `json:"topPolicy,omitempty"`
@@ -137,15 +172,103 @@ type PolicyTargetOptionsResponse struct {
 	Stages       []string             `json:"stages"`
 	Handlers     []string             `json:"handlers"`
 	Tags         []string             `json:"tags"`
+	// GeneratedAt is when the lists were read; responses are cached briefly.
+	GeneratedAt time.Time `json:"generatedAt"`
+}
+
+// PolicyTriggerRequest is sent by workers/SDKs after they applied a policy.
+type PolicyTriggerRequest struct {
+	Blocked    bool           `json:"blocked"`
+	Reason     string         `json:"reason,omitempty"`
+	Action     string         `json:"action,omitempty"`
+	PipelineID *int           `json:"pipelineId,omitempty"`
+	StageID    *int           `json:"stageId,omitempty"`
+	Details    map[string]any `json:"details,omitempty"`
+}
+
+// PolicyTriggerMessage is published by the worker when it enforced a policy;
+// the API records it as a triggered event.
+type PolicyTriggerMessage struct {
+	PolicyID string         `json:"policyId"`
+	Details  map[string]any `json:"details"`
+}
+
+// PolicySimulateRequest describes a sample event evaluated against a policy
+// without recording anything. Timestamps are the request times for rate
+// limits or the failure times for circuit breakers.
+type PolicySimulateRequest struct {
+	PipelineID  string            `json:"pipelineId,omitempty"`
+	Stage       string            `json:"stage,omitempty"`
+	Handler     string            `json:"handler,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Environment PolicyEnvironment `json:"environment,omitempty"`
+	Timestamps  []time.Time       `json:"timestamps,omitempty"`
+	Attempt     int               `json:"attempt,omitempty"`
+	ErrorCode   string            `json:"errorCode,omitempty"`
+	HTTPStatus  *int              `json:"httpStatus,omitempty"`
+	DurationMs  *int              `json:"durationMs,omitempty"`
+	InFlight    *int              `json:"inFlight,omitempty"`
+	At          *time.Time        `json:"at,omitempty"`
 }
 
+// PolicyDecision is the outcome of evaluating a policy for one event.
+type PolicyDecision struct {
+	PolicyID  string `json:"policyId"`
+	Active    bool   `json:"active"`
+	InWindow  bool   `json:"inWindow"`
+	Matched   bool   `json:"matched"`
+	Triggered bool   `json:"triggered"`
+	Blocked   bool   `json:"blocked"`
+	Reason    string `json:"reason,omitempty"`
+
+	AllowedCount     *int    `json:"allowedCount,omitempty"`
+	ThrottledCount   *int    `json:"throttledCount,omitempty"`
+	Throttled        []bool  `json:"throttled,omitempty"`
+	ShouldRetry      *bool   `json:"shouldRetry,omitempty"`
+	NextRetryDelayMs *int    `json:"nextRetryDelayMs,omitempty"`
+	CircuitState     *string `json:"circuitState,omitempty"`
+}
+
+// PolicyEffectiveEntry is the policy that wins for one type, along with the
+// lower-priority matches it overrides.
+type PolicyEffectiveEntry struct {
+	Type       PolicyType `json:"type"`
+	Policy     Policy     `json:"policy"`
+	Overridden []string   `json:"overridden"`
+}
+
+type PolicyEffectiveResponse struct {
+	Policies []PolicyEffectiveEntry `json:"policies"`
+}
+
+// Environment match modes for policy previews. Exact requires the inferred
+// environment to equal the policy's; contains also accepts values such as
+// "production" for "prod".
+const (
+	PolicyEnvironmentMatchExact    = "exact"
+	PolicyEnvironmentMatchContains = "contains"
+)
+
 type PolicyPreviewRequest struct {
 	Environment PolicyEnvironment `json:"environment"`
 	Targeting   PolicyTargeting   `json:"targeting"`
+	// EnvironmentMatch is exact or contains; empty uses the server default.
+	EnvironmentMatch string `json:"environmentMatch,omitempty"`
 }
 
 type PolicyPreviewResponse struct {
-	Pipelines int `json:"pipelines"`
-	Stages    int `json:"stages"`
-	Handlers  int `json:"handlers"`
+	Pipelines int                     `json:"pipelines"`
+	Stages    int                     `json:"stages"`
+	Handlers  int                     `json:"handlers"`
+	Total     int                     `json:"total"`
+	Excluded  PolicyPreviewExclusions `json:"excluded"`
+}
+
+// PolicyPreviewExclusions counts the pipelines a preview left out, by the
+// first filter each one failed, so Pipelines plus these adds up to Total.
+type PolicyPreviewExclusions struct {
+	Pipelines   int `json:"pipelines"`
+	Environment int `json:"environment"`
+	Tags        int `json:"tags"`
+	Stages      int `json:"stages"`
 }
@@ -1,5 +1,7 @@
 package types
 
+import "strings"
+
 const (
 	StageStatusNotStarted     = "NotStarted"
 	StageStatusRunning        = "Running"
@@ -8,13 +10,35 @@ const (
 	StageStatusCompleted      = "Completed"
 	StageStatusFailed         = "Failed"
 	StageStatusSkipped        = "Skipped"
+	StageStatusCancelled      = "Cancelled"
 )
 
+// Stage failure categories, stored on a stage with its latest failure so
+// failures can be broken down by cause.
+const (
+	StageFailureTimeout      = "timeout"
+	StageFailureHandlerError = "handler_error"
+	StageFailureCircuitOpen  = "circuit_open"
+	StageFailureCancelled    = "cancelled"
+)
+
+// NormalizeStageFailureCategory returns category if it is a known failure
+// category and StageFailureHandlerError otherwise.
+func NormalizeStageFailureCategory(category string) string {
+	switch category = strings.ToLower(strings.TrimSpace(category)); category {
+	case StageFailureTimeout, StageFailureHandlerError, StageFailureCircuitOpen, StageFailureCancelled:
+		return category
+	default:
+		return StageFailureHandlerError
+	}
+}
+
 const (
 	PipelineStatusNotStarted = "NotStarted"
 	PipelineStatusRunning    = "Running"
 	PipelineStatusCompleted  = "Completed"
 	PipelineStatusFailed     = "Failed"
+	PipelineStatusCancelled  = "Cancelled"
 )
 
 const (
@@ -0,0 +1,32 @@
+package types
+
+// Outgoing webhook headers and event names for pipeline completion callbacks.
+// WebhookSignatureHeader carries webhooksig.SignBody; the callbacks are also
+// signed like alert webhooks, see webhooksig.Sign.
+const (
+	WebhookSignatureHeader = "X-Signature"
+	WebhookEventHeader     = "X-Pipelogiq-Event"
+	WebhookDeliveryHeader  = "X-Pipelogiq-Delivery"
+
+	WebhookEventPipelineCompleted = "pipeline.completed"
+)
+
+// ApplicationWebhook is the per-application completion webhook. The secret
+// signs each payload with HMAC-SHA256; it is never returned by the API.
+type ApplicationWebhook struct {
+	ApplicationID int    `db:"application_id"`
+	URL           string `db:"webhook_url"`
+	Secret        string `db:"webhook_secret"`
+}
+
+type ApplicationWebhookResponse struct {
+	URL       string `json:"url"`
+	HasSecret bool   `json:"hasSecret"`
+}
+
+// SaveApplicationWebhookRequest replaces the webhook config. An empty URL
+// removes the webhook; a nil secret keeps the stored one.
+type SaveApplicationWebhookRequest struct {
+	URL    string  `json:"url"`
+	Secret *string `json:"secret,omitempty"`
+}
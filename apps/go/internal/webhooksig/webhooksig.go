@@ -0,0 +1,40 @@
+// Package webhooksig signs outgoing webhooks so receivers can verify that a
+// request came from pipelogiq. Alert webhooks and pipeline completion
+// webhooks share it.
+package webhooksig
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Headers of a timestamped signature. SignatureHeader carries the unix
+// timestamp the request was signed at and the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the secret, as "t=<ts>,v1=<hex>".
+// Receivers should reject timestamps far from their own clock so a captured
+// request cannot be replayed later.
+const (
+	SignatureHeader = "X-Pipelogiq-Signature"
+	TimestampHeader = "X-Pipelogiq-Timestamp"
+)
+
+// Sign returns the SignatureHeader value for body sent at timestamp (unix
+// seconds).
+func Sign(secret string, timestamp int64, body []byte) string {
+	ts := strconv.FormatInt(timestamp, 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignBody returns "sha256=" followed by the hex HMAC-SHA256 of body alone,
+// the untimestamped scheme of the pipeline webhook's X-Signature header.
+func SignBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,23 @@
+package webhooksig
+
+import "testing"
+
+// TestSign pins the test vector published in docs/observability.md.
+func TestSign(t *testing.T) {
+	body := []byte(`{"source":"pipelogiq","channel":"webhook","alert":{"event":"test_alert"}}`)
+	got := Sign("whsec_test", 1700000000, body)
+	want := "t=1700000000,v1=8881159284fe8b8a7fa8c3b21a3f1b0b12c57e3d7af7da2e9de67a39347fdcca"
+	if got != want {
+		t.Fatalf("Sign = %q, want %q", got, want)
+	}
+}
+
+// TestSignBody pins the X-Signature test vector published in
+// docs/observability.md.
+func TestSignBody(t *testing.T) {
+	got := SignBody("whsec_test", []byte(`{"id":42,"status":"Completed"}`))
+	want := "sha256=d63ace6bb62e835d8d0f7c4ec08d41e7af8914ed22e144d821322b31c84e5a0b"
+	if got != want {
+		t.Fatalf("SignBody = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,164 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"pipelogiq/internal/policyengine"
+	"pipelogiq/internal/store"
+	"pipelogiq/internal/types"
+)
+
+const (
+	// circuitStateHalfOpen is reported while a circuit lets probe retries
+	// through after its open period.
+	circuitStateHalfOpen = "half_open"
+	// maxTrackedFailures bounds the failure log kept per handler.
+	maxTrackedFailures = 1000
+	// halfOpenHoldDelay is how long a retry waits when the half-open probes
+	// are already in flight.
+	halfOpenHoldDelay = 5 * time.Second
+)
+
+// circuitTracker is the failure accounting shared by the retry and circuit
+// breaker logic. Stage results feed it per handler, and the publisher asks it
+// whether a retry may go out under the circuit breaker policy that wins for
+// the stage. Counts are per worker process.
+type circuitTracker struct {
+	mu       sync.Mutex
+	handlers map[string]*handlerCircuit
+}
+
+type handlerCircuit struct {
+	failures []time.Time
+	// openedAt is the failure that opened the circuit; zero while closed.
+	openedAt time.Time
+	// probes counts retries let through since the circuit half-opened, and
+	// probeAt is when the last of them went out.
+	probes  int
+	probeAt time.Time
+}
+
+func newCircuitTracker() *circuitTracker {
+	return &circuitTracker{handlers: make(map[string]*handlerCircuit)}
+}
+
+// recordResult counts a finished attempt. A failed probe reopens the
+// circuit; any success closes it.
+func (t *circuitTracker) recordResult(handler string, failed bool, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := t.handlers[handler]
+	if !failed {
+		if c != nil && !c.openedAt.IsZero() {
+			delete(t.handlers, handler)
+		}
+		return
+	}
+	if c == nil {
+		c = &handlerCircuit{}
+		t.handlers[handler] = c
+	}
+	c.failures = append(c.failures, now)
+	if len(c.failures) > maxTrackedFailures {
+		c.failures = c.failures[len(c.failures)-maxTrackedFailures:]
+	}
+	if c.probes > 0 {
+		c.openedAt = now
+		c.probes = 0
+		c.probeAt = time.Time{}
+	}
+}
+
+// admitRetry reports whether a retry of handler may be dispatched at now
+// under rule. When it may not, retryAt is when to consider it again.
+func (t *circuitTracker) admitRetry(handler string, rule types.PolicyRule, now time.Time) (ok bool, state string, retryAt time.Time) {
+	if rule.FailureThreshold == nil || rule.WindowSeconds == nil {
+		return true, policyengine.CircuitStateClosed, time.Time{}
+	}
+	window := time.Duration(*rule.WindowSeconds) * time.Second
+	openFor := window
+	if rule.OpenSeconds != nil && *rule.OpenSeconds > 0 {
+		openFor = time.Duration(*rule.OpenSeconds) * time.Second
+	}
+	maxProbes := 1
+	if rule.HalfOpenMaxCalls != nil && *rule.HalfOpenMaxCalls > 0 {
+		maxProbes = *rule.HalfOpenMaxCalls
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := t.handlers[handler]
+	if c == nil {
+		return true, policyengine.CircuitStateClosed, time.Time{}
+	}
+	if c.openedAt.IsZero() {
+		c.failures = pruneFailures(c.failures, now.Add(-window))
+		if len(c.failures) < *rule.FailureThreshold {
+			return true, policyengine.CircuitStateClosed, time.Time{}
+		}
+		c.openedAt = c.failures[len(c.failures)-1]
+		c.probes = 0
+	}
+
+	if reopenAt := c.openedAt.Add(openFor); now.Before(reopenAt) {
+		return false, policyengine.CircuitStateOpen, reopenAt
+	}
+	if c.probes < maxProbes {
+		c.probes++
+		c.probeAt = now
+		return true, circuitStateHalfOpen, time.Time{}
+	}
+	// A probe whose result never reaches this process (consumed by another
+	// replica, cancelled or timed out) would hold retries forever, so the
+	// circuit reopens when no result arrived within an open period.
+	if !now.Before(c.probeAt.Add(openFor)) {
+		c.openedAt = now
+		c.probes = 0
+		c.probeAt = time.Time{}
+		return false, policyengine.CircuitStateOpen, now.Add(openFor)
+	}
+	return false, circuitStateHalfOpen, now.Add(halfOpenHoldDelay)
+}
+
+func pruneFailures(failures []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(failures) && !failures[i].After(cutoff) {
+		i++
+	}
+	return failures[i:]
+}
+
+// holdRetry keeps a retry of stage back while the circuit breaker policy
+// that wins for it is open, so retries do not pile onto a failing
+// downstream. The held stage stays RetryScheduled and keeps its attempt.
+func (w *Worker) holdRetry(ctx context.Context, policy types.Policy, target *store.StagePolicyTarget, attempt int, now time.Time) bool {
+	ok, state, retryAt := w.circuits.admitRetry(target.Handler, policy.Rule, now)
+	if ok {
+		return false
+	}
+
+	if err := w.store.HoldStageRetry(ctx, target.StageID, retryAt, "circuit_breaker"); err != nil {
+		w.logger.Error("hold retry failed", "stageId", target.StageID, "err", err)
+		return false
+	}
+
+	w.metrics.stageThrottled.Inc()
+	w.logger.Info("stage retry held by circuit breaker",
+		"stageId", target.StageID, "policyId", policy.ID, "handler", target.Handler, "circuitState", state, "until", retryAt)
+	w.reportPolicyTrigger(ctx, policy, map[string]any{
+		"blocked":      true,
+		"reason":       "circuit open",
+		"action":       "retry_held",
+		"circuitState": state,
+		"pipelineId":   target.PipelineID,
+		"stageId":      target.StageID,
+		"handler":      target.Handler,
+		"attempt":      attempt,
+		"retryAt":      retryAt.Format(time.RFC3339),
+	})
+	return true
+}
@@ -0,0 +1,107 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"pipelogiq/internal/policyengine"
+	"pipelogiq/internal/types"
+)
+
+func TestCircuitTrackerPausesRetriesWhileOpen(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+	rule := types.PolicyRule{
+		FailureThreshold: intPtr(3),
+		WindowSeconds:    intPtr(60),
+		OpenSeconds:      intPtr(30),
+		HalfOpenMaxCalls: intPtr(1),
+	}
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tracker := newCircuitTracker()
+
+	admit := func(at time.Duration) (bool, string, time.Time) {
+		t.Helper()
+		return tracker.admitRetry("charge-card", rule, start.Add(at))
+	}
+
+	tracker.recordResult("charge-card", true, start)
+	tracker.recordResult("charge-card", true, start.Add(time.Second))
+	if ok, state, _ := admit(2 * time.Second); !ok || state != policyengine.CircuitStateClosed {
+		t.Fatalf("below threshold: admitRetry() = %v, %q; want admitted while closed", ok, state)
+	}
+
+	tracker.recordResult("charge-card", true, start.Add(10*time.Second))
+	ok, state, retryAt := admit(11 * time.Second)
+	if ok || state != policyengine.CircuitStateOpen {
+		t.Fatalf("at threshold: admitRetry() = %v, %q; want held while open", ok, state)
+	}
+	if want := start.Add(40 * time.Second); !retryAt.Equal(want) {
+		t.Fatalf("retryAt = %v, want %v", retryAt, want)
+	}
+	if ok, _, _ := tracker.admitRetry("send-email", rule, start.Add(11*time.Second)); !ok {
+		t.Fatal("other handler's retries are held")
+	}
+	if ok, _, _ := admit(39 * time.Second); ok {
+		t.Fatal("retry admitted before the open period ended")
+	}
+
+	// Half-open lets one probe through and holds the rest.
+	if ok, state, _ := admit(40 * time.Second); !ok || state != circuitStateHalfOpen {
+		t.Fatalf("after open period: admitRetry() = %v, %q; want probe admitted", ok, state)
+	}
+	if ok, state, _ := admit(41 * time.Second); ok || state != circuitStateHalfOpen {
+		t.Fatalf("second probe: admitRetry() = %v, %q; want held", ok, state)
+	}
+
+	// A failed probe reopens the circuit for another open period.
+	tracker.recordResult("charge-card", true, start.Add(45*time.Second))
+	if ok, state, retryAt := admit(50 * time.Second); ok || state != policyengine.CircuitStateOpen || !retryAt.Equal(start.Add(75*time.Second)) {
+		t.Fatalf("after failed probe: admitRetry() = %v, %q, %v; want held until %v", ok, state, retryAt, start.Add(75*time.Second))
+	}
+
+	// A successful probe closes it and retries resume.
+	if ok, _, _ := admit(75 * time.Second); !ok {
+		t.Fatal("probe after reopen not admitted")
+	}
+	tracker.recordResult("charge-card", false, start.Add(76*time.Second))
+	if ok, state, _ := admit(77 * time.Second); !ok || state != policyengine.CircuitStateClosed {
+		t.Fatalf("after successful probe: admitRetry() = %v, %q; want admitted while closed", ok, state)
+	}
+}
+
+func TestCircuitTrackerReopensAfterLostProbe(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+	rule := types.PolicyRule{
+		FailureThreshold: intPtr(1),
+		WindowSeconds:    intPtr(60),
+		OpenSeconds:      intPtr(30),
+		HalfOpenMaxCalls: intPtr(1),
+	}
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tracker := newCircuitTracker()
+	admit := func(at time.Duration) (bool, string, time.Time) {
+		t.Helper()
+		return tracker.admitRetry("charge-card", rule, start.Add(at))
+	}
+
+	tracker.recordResult("charge-card", true, start)
+	if ok, state, _ := admit(time.Second); ok || state != policyengine.CircuitStateOpen {
+		t.Fatalf("after failure: admitRetry() = %v, %q; want held while open", ok, state)
+	}
+	if ok, state, _ := admit(30 * time.Second); !ok || state != circuitStateHalfOpen {
+		t.Fatalf("after open period: admitRetry() = %v, %q; want probe admitted", ok, state)
+	}
+
+	// The probe's result never arrives. Retries wait for it for one open
+	// period, then the circuit reopens instead of holding them forever.
+	if ok, state, _ := admit(59 * time.Second); ok || state != circuitStateHalfOpen {
+		t.Fatalf("probe in flight: admitRetry() = %v, %q; want held while half-open", ok, state)
+	}
+	ok, state, retryAt := admit(60 * time.Second)
+	if ok || state != policyengine.CircuitStateOpen || !retryAt.Equal(start.Add(90*time.Second)) {
+		t.Fatalf("probe lost: admitRetry() = %v, %q, %v; want reopened until %v", ok, state, retryAt, start.Add(90*time.Second))
+	}
+	if ok, state, _ := admit(90 * time.Second); !ok || state != circuitStateHalfOpen {
+		t.Fatalf("after reopen: admitRetry() = %v, %q; want a new probe admitted", ok, state)
+	}
+}
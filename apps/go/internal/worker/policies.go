@@ -0,0 +1,287 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"pipelogiq/internal/constants"
+	"pipelogiq/internal/mq"
+	"pipelogiq/internal/policyengine"
+	"pipelogiq/internal/ratelimit"
+	"pipelogiq/internal/store"
+	"pipelogiq/internal/types"
+)
+
+const (
+	// policyReloadInterval bounds how often the policy store file is checked
+	// for changes made through the API.
+	policyReloadInterval = 10 * time.Second
+	// policyStoreWait is how long the worker waits at startup for the API to
+	// create the policy store file.
+	policyStoreWait = 30 * time.Second
+	// concurrencyDeferDelay is how long a stage held back by a concurrency
+	// limit waits before the scheduler considers it again, and the shortest
+	// wait of a rate limited stage.
+	concurrencyDeferDelay = 2 * time.Second
+)
+
+// SetLimiterStore counts rate limit policy windows in store instead of
+// process memory, so all worker replicas share them. It must be called before
+// Run.
+func (w *Worker) SetLimiterStore(store ratelimit.LimiterStore) {
+	w.limiter = store
+}
+
+// throttleStage applies the concurrency limit and rate limit policies that
+// win for stage, and for retries the circuit breaker policy. When one holds
+// the stage back it is handed back to the scheduler and true is returned so
+// the caller skips publishing it. Errors fail open.
+func (w *Worker) throttleStage(ctx context.Context, stage *types.StageNextMessage) bool {
+	all, err := w.policies.Policies()
+	if err != nil {
+		w.logger.Warn("load policies failed; using last known policies", "err", err)
+	}
+
+	now := time.Now().UTC()
+	var limits []types.Policy
+	for _, policy := range all {
+		switch policy.Type {
+		case types.PolicyTypeConcurrencyLimit, types.PolicyTypeRateLimit:
+			limits = append(limits, policy)
+		case types.PolicyTypeCircuitBreaker:
+			if stage.Attempt > 0 {
+				limits = append(limits, policy)
+			}
+		}
+	}
+	if len(limits) == 0 {
+		return false
+	}
+
+	target, err := w.store.GetStagePolicyTarget(ctx, stage.StageID)
+	if err != nil {
+		w.logger.Error("load stage policy target failed", "stageId", stage.StageID, "err", err)
+		return false
+	}
+
+	event := stagePolicyEvent(target)
+	effective := make(map[types.PolicyType]types.Policy)
+	for _, entry := range policyengine.ResolveEffective(limits, event, now) {
+		effective[entry.Type] = entry.Policy
+	}
+
+	// The circuit breaker and the concurrency limit go first: they only
+	// count, while the rate limit records the dispatch in its window.
+	if policy, ok := effective[types.PolicyTypeCircuitBreaker]; ok && w.holdRetry(ctx, policy, target, stage.Attempt, now) {
+		return true
+	}
+	if policy, ok := effective[types.PolicyTypeConcurrencyLimit]; ok && w.limitConcurrency(ctx, policy, target, event, now) {
+		return true
+	}
+	if policy, ok := effective[types.PolicyTypeRateLimit]; ok && w.limitRate(ctx, policy, target, now) {
+		return true
+	}
+	return false
+}
+
+// timeoutPolicyAction returns the onTimeout action of the step timeout
+// policy that wins for stageID, or "" when none applies. Errors fall back to
+// "", which fails the stage as before.
+func (w *Worker) timeoutPolicyAction(ctx context.Context, stageID int) string {
+	all, err := w.policies.Policies()
+	if err != nil {
+		w.logger.Warn("load policies failed; using last known policies", "err", err)
+	}
+
+	var timeouts []types.Policy
+	for _, policy := range all {
+		if policy.Type == types.PolicyTypeTimeout && strings.EqualFold(stringValue(policy.Rule.AppliesTo), "step") {
+			timeouts = append(timeouts, policy)
+		}
+	}
+	if len(timeouts) == 0 {
+		return ""
+	}
+
+	target, err := w.store.GetStagePolicyTarget(ctx, stageID)
+	if err != nil {
+		w.logger.Error("load stage policy target failed", "stageId", stageID, "err", err)
+		return ""
+	}
+	for _, entry := range policyengine.ResolveEffective(timeouts, stagePolicyEvent(target), time.Now().UTC()) {
+		if entry.Type == types.PolicyTypeTimeout {
+			return strings.ToLower(stringValue(entry.Policy.Rule.OnTimeout))
+		}
+	}
+	return ""
+}
+
+// retryPolicy returns the rule of the retry policy that wins for stageID, or
+// nil when none applies. Errors fall back to nil, which keeps the stage's
+// fixed retry interval.
+func (w *Worker) retryPolicy(ctx context.Context, stageID int) *types.PolicyRule {
+	all, err := w.policies.Policies()
+	if err != nil {
+		w.logger.Warn("load policies failed; using last known policies", "err", err)
+	}
+
+	var retries []types.Policy
+	for _, policy := range all {
+		if policy.Type == types.PolicyTypeRetry {
+			retries = append(retries, policy)
+		}
+	}
+	if len(retries) == 0 {
+		return nil
+	}
+
+	target, err := w.store.GetStagePolicyTarget(ctx, stageID)
+	if err != nil {
+		w.logger.Error("load stage policy target failed", "stageId", stageID, "err", err)
+		return nil
+	}
+	for _, entry := range policyengine.ResolveEffective(retries, stagePolicyEvent(target), time.Now().UTC()) {
+		if entry.Type == types.PolicyTypeRetry {
+			rule := entry.Policy.Rule
+			return &rule
+		}
+	}
+	return nil
+}
+
+// stagePolicyEvent describes target the way policy targeting matches it.
+func stagePolicyEvent(target *store.StagePolicyTarget) types.PolicySimulateRequest {
+	return types.PolicySimulateRequest{
+		PipelineID:  strconv.Itoa(target.PipelineID),
+		Stage:       target.Stage,
+		Handler:     target.Handler,
+		Tags:        target.Tags,
+		Environment: types.PolicyEnvironment(target.Environment),
+	}
+}
+
+func (w *Worker) limitConcurrency(ctx context.Context, policy types.Policy, target *store.StagePolicyTarget, event types.PolicySimulateRequest, now time.Time) bool {
+	filter := store.InFlightStageFilter{
+		Handlers:       policy.Targeting.Handlers,
+		Stages:         policy.Targeting.Stages,
+		ExcludeStageID: target.StageID,
+	}
+	switch strings.ToLower(stringValue(policy.Rule.KeyBy)) {
+	case policyengine.ConcurrencyKeyHandler:
+		filter.Handler = target.Handler
+	case policyengine.ConcurrencyKeyTenant:
+		filter.ApplicationID = &target.ApplicationID
+	}
+	inFlight, err := w.store.CountInFlightStages(ctx, filter)
+	if err != nil {
+		w.logger.Error("count in-flight stages failed", "policyId", policy.ID, "err", err)
+		return false
+	}
+	event.InFlight = &inFlight
+
+	decision, err := policyengine.Evaluate(policy, event, now)
+	if err != nil || !decision.Blocked {
+		return false
+	}
+
+	if err := w.store.DeferStage(ctx, target.StageID, now.Add(concurrencyDeferDelay), "concurrency_limit"); err != nil {
+		w.logger.Error("defer throttled stage failed", "stageId", target.StageID, "err", err)
+		return false
+	}
+
+	w.metrics.stageThrottled.Inc()
+	w.logger.Info("stage deferred by concurrency limit",
+		"stageId", target.StageID, "policyId", policy.ID, "inFlight", inFlight)
+	w.reportPolicyTrigger(ctx, policy, map[string]any{
+		"blocked":       true,
+		"reason":        policyengine.ReasonThrottle,
+		"action":        "deferred",
+		"pipelineId":    target.PipelineID,
+		"stageId":       target.StageID,
+		"handler":       target.Handler,
+		"inFlight":      inFlight,
+		"maxConcurrent": *policy.Rule.MaxConcurrent,
+	})
+	return true
+}
+
+// limitRate counts the dispatch against the policy's window in the shared
+// LimiterStore. Only global and tenant keys are known at dispatch; policies
+// keyed by user or custom keys are left to the SDKs.
+func (w *Worker) limitRate(ctx context.Context, policy types.Policy, target *store.StagePolicyTarget, now time.Time) bool {
+	if policy.Rule.Limit == nil || policy.Rule.WindowSeconds == nil {
+		return false
+	}
+	key := "policy:" + policy.ID
+	switch strings.ToLower(stringValue(policy.Rule.KeyBy)) {
+	case "global":
+	case "tenant":
+		key += ":app:" + strconv.Itoa(target.ApplicationID)
+	default:
+		return false
+	}
+	limit := *policy.Rule.Limit
+	if policy.Rule.Burst != nil {
+		limit += *policy.Rule.Burst
+	}
+	window := time.Duration(*policy.Rule.WindowSeconds) * time.Second
+
+	res, err := w.limiter.Allow(ctx, key, limit, window)
+	if err != nil {
+		w.logger.Error("rate limit check failed", "policyId", policy.ID, "err", err)
+		return false
+	}
+	if res.Allowed {
+		return false
+	}
+
+	if err := w.store.DeferStage(ctx, target.StageID, now.Add(max(res.RetryAfter, concurrencyDeferDelay)), "rate_limit"); err != nil {
+		w.logger.Error("defer rate limited stage failed", "stageId", target.StageID, "err", err)
+		return false
+	}
+
+	w.metrics.stageThrottled.Inc()
+	w.logger.Info("stage deferred by rate limit",
+		"stageId", target.StageID, "policyId", policy.ID, "retryAfter", res.RetryAfter)
+	w.reportPolicyTrigger(ctx, policy, map[string]any{
+		"blocked":       true,
+		"reason":        policyengine.ReasonThrottle,
+		"action":        "deferred",
+		"pipelineId":    target.PipelineID,
+		"stageId":       target.StageID,
+		"handler":       target.Handler,
+		"limit":         limit,
+		"windowSeconds": *policy.Rule.WindowSeconds,
+	})
+	return true
+}
+
+// reportPolicyTrigger hands a trigger to the API, which owns the policy
+// store, via the PolicyTriggered queue.
+func (w *Worker) reportPolicyTrigger(ctx context.Context, policy types.Policy, details map[string]any) {
+	body, err := json.Marshal(types.PolicyTriggerMessage{PolicyID: policy.ID, Details: details})
+	if err != nil {
+		w.logger.Error("marshal policy trigger failed", "policyId", policy.ID, "err", err)
+		return
+	}
+
+	opts := mq.QueueOptions{
+		Durable:     true,
+		DLQEnabled:  w.cfg.QueueDLQEnabled,
+		DLQTTL:      w.cfg.QueueDLQMessageTTL,
+		ContentType: "application/json",
+	}
+	if err := w.mq.PublishWithRetry(ctx, constants.PolicyTrigger, body, opts, nil); err != nil {
+		w.logger.Error("publish policy trigger failed", "policyId", policy.ID, "err", err)
+	}
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return strings.TrimSpace(*value)
+}
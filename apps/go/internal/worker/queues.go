@@ -0,0 +1,164 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"pipelogiq/internal/constants"
+	"pipelogiq/internal/mq"
+	"pipelogiq/internal/store"
+	"pipelogiq/internal/types"
+)
+
+// QueueAlertSink receives queue depth alerts from the queue monitor.
+type QueueAlertSink interface {
+	NotifyQueueEvent(ctx context.Context, event types.QueueDepthEvent)
+}
+
+// SetQueueAlertSink registers the sink for queue depth alerts. It must be
+// called before Run.
+func (w *Worker) SetQueueAlertSink(sink QueueAlertSink) {
+	w.queueAlerts = sink
+}
+
+// runQueueMonitor periodically reads the depth of the stage queues and, when
+// dead-lettering is enabled, their DLQs.
+func (w *Worker) runQueueMonitor(ctx context.Context) error {
+	ticker := time.NewTicker(w.cfg.QueueMonitorInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			w.checkQueueDepths(ctx)
+		}
+	}
+}
+
+func (w *Worker) checkQueueDepths(ctx context.Context) {
+	queues, err := w.monitoredQueues(ctx)
+	if err != nil {
+		w.logger.Error("list monitored queues failed", "err", err)
+		return
+	}
+
+	now := time.Now().UTC()
+	for _, queue := range queues {
+		depth, err := w.mq.QueueDepth(ctx, queue.name)
+		if err != nil {
+			if !errors.Is(err, mq.ErrQueueNotFound) {
+				w.logger.Warn("read queue depth failed", "queue", queue.name, "err", err)
+			} else if queue.handler != "" {
+				w.metrics.stageQueueDepth.DeleteLabelValues(queue.handler, queue.environment)
+			}
+		} else {
+			if queue.handler != "" {
+				w.metrics.stageQueueDepth.WithLabelValues(queue.handler, queue.environment).Set(float64(depth))
+			}
+			if threshold := w.cfg.QueueBacklogThreshold; threshold > 0 && depth > threshold {
+				w.emitQueueEvent(ctx, types.QueueDepthEvent{
+					Type: types.QueueEventBacklogHigh, Queue: queue.name, Depth: depth, Threshold: threshold, TS: now,
+				})
+			}
+		}
+
+		if !w.cfg.QueueDLQEnabled {
+			continue
+		}
+		dlq := mq.DLQName(queue.name)
+		depth, err = w.mq.QueueDepth(ctx, dlq)
+		if err != nil {
+			if !errors.Is(err, mq.ErrQueueNotFound) {
+				w.logger.Warn("read queue depth failed", "queue", dlq, "err", err)
+			} else {
+				w.metrics.dlqDepth.DeleteLabelValues(dlq)
+			}
+			continue
+		}
+		w.metrics.dlqDepth.WithLabelValues(dlq).Set(float64(depth))
+		if depth > 0 {
+			w.emitQueueEvent(ctx, types.QueueDepthEvent{
+				Type: types.QueueEventDLQMessage, Queue: dlq, Depth: depth, TS: now,
+			})
+		}
+	}
+}
+
+// monitoredQueue is a queue watched by the queue monitor; handler is set for
+// StageNext queues, and environment for the per-environment ones.
+type monitoredQueue struct {
+	name        string
+	handler     string
+	environment string
+}
+
+// monitoredQueues returns the StageNext queue of every known handler, with
+// STAGE_ENVIRONMENT_ROUTING also those of the environments of unfinished
+// pipelines, and the StageResult and StageSetStatus queues.
+func (w *Worker) monitoredQueues(ctx context.Context) ([]monitoredQueue, error) {
+	handlers, err := w.store.ListStageHandlerNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var envQueues []store.StageQueueEnvironment
+	if w.cfg.StageEnvironmentRouting {
+		if envQueues, err = w.store.ListStageQueueEnvironments(ctx); err != nil {
+			return nil, err
+		}
+	}
+	queues := make([]monitoredQueue, 0, len(handlers)+len(envQueues)+w.cfg.ResultQueueShards+2)
+	for _, handler := range handlers {
+		queues = append(queues, monitoredQueue{name: stageQueueName(w.cfg.AppID, handler, ""), handler: handler})
+	}
+	for _, q := range envQueues {
+		queues = append(queues, monitoredQueue{
+			name: stageQueueName(w.cfg.AppID, q.Handler, q.Environment), handler: q.Handler, environment: q.Environment,
+		})
+	}
+	queues = append(queues, monitoredQueue{name: constants.StageResult}, monitoredQueue{name: constants.StageSetStatus})
+	if w.cfg.ResultQueueShards > 1 {
+		for _, shard := range mq.ShardQueueNames(constants.StageResult, w.cfg.ResultQueueShards) {
+			queues = append(queues, monitoredQueue{name: shard})
+		}
+	}
+	return queues, nil
+}
+
+func (w *Worker) emitQueueEvent(ctx context.Context, event types.QueueDepthEvent) {
+	w.logger.Warn("queue depth alert", "type", event.Type, "queue", event.Queue, "depth", event.Depth)
+	if w.queueAlerts != nil {
+		w.queueAlerts.NotifyQueueEvent(ctx, event)
+	}
+}
+
+// recordDeadLetter returns a Consume hook that stores why a delivery from
+// queue was dead-lettered, keyed by the stage its body names.
+func (w *Worker) recordDeadLetter(queue string) func(context.Context, amqp.Delivery, error) {
+	return func(ctx context.Context, d amqp.Delivery, cause error) {
+		var ref struct {
+			StageID int `json:"stageId"`
+		}
+		_ = json.Unmarshal(d.Body, &ref)
+
+		rec := types.DeadLetterRecord{
+			Queue:     queue,
+			Reason:    cause.Error(),
+			MessageID: d.MessageId,
+			Headers:   map[string]any(d.Headers),
+		}
+		if ref.StageID > 0 {
+			rec.StageID = &ref.StageID
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		if err := w.store.RecordDeadLetter(ctx, rec); err != nil {
+			w.logger.Warn("record dead letter failed", "queue", queue, "stageId", ref.StageID, "err", err)
+		}
+	}
+}
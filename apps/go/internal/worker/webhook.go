@@ -0,0 +1,120 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/google/uuid"
+
+	"pipelogiq/internal/types"
+	"pipelogiq/internal/webhooksig"
+)
+
+const (
+	webhookRequestTimeout  = 10 * time.Second
+	webhookInitialInterval = time.Second
+	webhookMaxInterval     = 30 * time.Second
+	webhookMaxElapsed      = 5 * time.Minute
+)
+
+var webhookClient = &http.Client{Timeout: webhookRequestTimeout}
+
+// notifyPipelineCompleted POSTs the final pipeline snapshot to the owning
+// application's webhook. Delivery runs in the background with exponential
+// backoff; when every attempt fails the payload is recorded as an error in
+// the application log.
+func (w *Worker) notifyPipelineCompleted(ctx context.Context, pipeline *types.PipelineResponse) {
+	if pipeline == nil || pipeline.ApplicationID == nil {
+		return
+	}
+	appID := *pipeline.ApplicationID
+
+	hook, err := w.store.GetApplicationWebhook(ctx, appID)
+	if err != nil {
+		w.logger.Error("load application webhook failed", "applicationId", appID, "err", err)
+		return
+	}
+	if hook == nil {
+		return
+	}
+
+	payload, err := json.Marshal(pipeline)
+	if err != nil {
+		w.logger.Error("marshal webhook payload failed", "pipelineId", pipeline.ID, "err", err)
+		return
+	}
+
+	// The result consumer's context ends with the handler; delivery outlives it.
+	go w.deliverWebhook(context.WithoutCancel(ctx), *hook, pipeline.ID, payload)
+}
+
+func (w *Worker) deliverWebhook(ctx context.Context, hook types.ApplicationWebhook, pipelineID int, payload []byte) {
+	exp := backoff.NewExponentialBackOff()
+	exp.InitialInterval = webhookInitialInterval
+	exp.MaxInterval = webhookMaxInterval
+	exp.MaxElapsedTime = webhookMaxElapsed
+
+	deliveryID := uuid.NewString()
+	attempts := 0
+	err := backoff.Retry(func() error {
+		attempts++
+		return postWebhook(ctx, hook, deliveryID, payload)
+	}, backoff.WithContext(exp, ctx))
+	if err == nil {
+		w.metrics.webhookDelivered.Inc()
+		w.logger.Info("pipeline webhook delivered", "pipelineId", pipelineID, "applicationId", hook.ApplicationID, "attempts", attempts)
+		return
+	}
+
+	w.metrics.webhookFailed.Inc()
+	w.logger.Error("pipeline webhook delivery failed",
+		"pipelineId", pipelineID, "applicationId", hook.ApplicationID, "url", hook.URL, "attempts", attempts, "err", err)
+
+	logCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if dlErr := w.store.SaveWebhookDeadLetter(logCtx, hook.ApplicationID, pipelineID, hook.URL, attempts, err); dlErr != nil {
+		w.logger.Error("record webhook dead letter failed", "pipelineId", pipelineID, "err", dlErr)
+	}
+}
+
+// postWebhook sends one delivery attempt. Client errors other than 408 and
+// 429 are permanent; everything else is retried.
+func postWebhook(ctx context.Context, hook types.ApplicationWebhook, deliveryID string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return backoff.Permanent(fmt.Errorf("build webhook request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(types.WebhookEventHeader, types.WebhookEventPipelineCompleted)
+	req.Header.Set(types.WebhookDeliveryHeader, deliveryID)
+	if hook.Secret != "" {
+		timestamp := time.Now().Unix()
+		req.Header.Set(types.WebhookSignatureHeader, webhooksig.SignBody(hook.Secret, payload))
+		req.Header.Set(webhooksig.TimestampHeader, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(webhooksig.SignatureHeader, webhooksig.Sign(hook.Secret, timestamp, payload))
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return backoff.Permanent(err)
+	}
+	return err
+}
@@ -0,0 +1,43 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"pipelogiq/internal/types"
+	"pipelogiq/internal/webhooksig"
+)
+
+func TestPostWebhookSignsPayload(t *testing.T) {
+	var header http.Header
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	hook := types.ApplicationWebhook{URL: srv.URL, Secret: "whsec_test"}
+	payload := []byte(`{"id":42,"status":"Completed"}`)
+	if err := postWebhook(context.Background(), hook, "delivery-1", payload); err != nil {
+		t.Fatalf("postWebhook() error = %v", err)
+	}
+
+	if got, want := header.Get(types.WebhookSignatureHeader), webhooksig.SignBody(hook.Secret, body); got != want {
+		t.Fatalf("X-Signature = %q, want %q", got, want)
+	}
+	timestamp, err := strconv.ParseInt(header.Get(webhooksig.TimestampHeader), 10, 64)
+	if err != nil {
+		t.Fatalf("timestamp header = %q: %v", header.Get(webhooksig.TimestampHeader), err)
+	}
+	if got, want := header.Get(webhooksig.SignatureHeader), webhooksig.Sign(hook.Secret, timestamp, body); got != want {
+		t.Fatalf("signature header = %q, want %q", got, want)
+	}
+	if got := header.Get(types.WebhookDeliveryHeader); got != "delivery-1" {
+		t.Fatalf("delivery header = %q, want delivery-1", got)
+	}
+}
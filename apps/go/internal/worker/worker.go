@@ -6,6 +6,8 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -15,15 +17,29 @@ import (
 	"pipelogiq/internal/config"
 	"pipelogiq/internal/constants"
 	"pipelogiq/internal/mq"
+	"pipelogiq/internal/policyengine"
+	"pipelogiq/internal/ratelimit"
 	"pipelogiq/internal/store"
+	"pipelogiq/internal/telemetry"
 	"pipelogiq/internal/types"
 )
 
 type Worker struct {
-	cfg    config.WorkerConfig
-	store  *store.Store
-	mq     *mq.Client
-	logger *slog.Logger
+	cfg      config.WorkerConfig
+	store    *store.Store
+	mq       *mq.Client
+	policies *policyengine.Cache
+	limiter  ratelimit.LimiterStore
+	circuits *circuitTracker
+	logger   *slog.Logger
+
+	queueAlerts QueueAlertSink
+
+	// inflight counts consumer handlers currently running; handlerAbort is
+	// cancelled when the drain timeout expires.
+	inflight     atomic.Int64
+	handlerAbort context.Context
+	abort        context.CancelFunc
 
 	metrics workerMetrics
 }
@@ -34,6 +50,17 @@ type workerMetrics struct {
 	stageResultFailed    prometheus.Counter
 	stageStatusUpdated   prometheus.Counter
 	pendingMarkedFailed  prometheus.Counter
+	pendingRetried       prometheus.Counter
+	workersPruned        prometheus.Counter
+	stageThrottled       prometheus.Counter
+	webhookDelivered     prometheus.Counter
+	webhookFailed        prometheus.Counter
+	stageQueueDepth      *prometheus.GaugeVec
+	dlqDepth             *prometheus.GaugeVec
+	stageDuration        *prometheus.HistogramVec
+	stageRetries         *prometheus.CounterVec
+	orphanedStages       *prometheus.CounterVec
+	stageSLABreaches     prometheus.Counter
 }
 
 func New(cfg config.WorkerConfig, st *store.Store, mqClient *mq.Client, logger *slog.Logger) *Worker {
@@ -56,7 +83,52 @@ func New(cfg config.WorkerConfig, st *store.Store, mqClient *mq.Client, logger *
 		}),
 		pendingMarkedFailed: prometheus.NewCounter(prometheus.CounterOpts{
 			Name: "pending_marked_failed_total",
-			Help: "Number of pending stages marked as failed due to timeout",
+			Help: "Number of Pending or Running stages marked as failed due to timeout",
+		}),
+		pendingRetried: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "pending_timeout_retried_total",
+			Help: "Number of timed out stages scheduled for retry instead of failed",
+		}),
+		workersPruned: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "workers_pruned_total",
+			Help: "Number of stopped or offline worker rows removed by retention",
+		}),
+		stageThrottled: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "stage_throttled_total",
+			Help: "Number of stage dispatches deferred by a concurrency limit or rate limit policy",
+		}),
+		webhookDelivered: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "pipeline_webhook_delivered_total",
+			Help: "Number of pipeline completion webhooks delivered",
+		}),
+		webhookFailed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "pipeline_webhook_failed_total",
+			Help: "Number of pipeline completion webhooks that exhausted their retries",
+		}),
+		stageQueueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "stage_queue_depth",
+			Help: "Ready messages in each handler's StageNext queue, as of the last queue monitor pass",
+		}, []string{"handler", "environment"}),
+		dlqDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "dlq_depth",
+			Help: "Messages in each dead-letter queue, as of the last queue monitor pass",
+		}, []string{"queue"}),
+		stageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "stage_duration_seconds",
+			Help:    "Time from stage start to its result, by handler and resulting stage status",
+			Buckets: []float64{0.1, 0.5, 1, 5, 15, 30, 60, 300, 900, 1800, 3600},
+		}, []string{"handler", "status"}),
+		stageRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "stage_retries_total",
+			Help: "Number of failed stage results that scheduled a retry, not counting timeout retries",
+		}, []string{"handler"}),
+		orphanedStages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "stage_orphaned_total",
+			Help: "Number of gateway-leased stages whose lease lapsed, by outcome (requeued or failed)",
+		}, []string{"outcome"}),
+		stageSLABreaches: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "stage_sla_breaches_total",
+			Help: "Number of stage dispatches reported for running past their expected duration",
 		}),
 	}
 	prometheus.MustRegister(
@@ -65,32 +137,176 @@ func New(cfg config.WorkerConfig, st *store.Store, mqClient *mq.Client, logger *
 		metrics.stageResultFailed,
 		metrics.stageStatusUpdated,
 		metrics.pendingMarkedFailed,
+		metrics.pendingRetried,
+		metrics.workersPruned,
+		metrics.stageThrottled,
+		metrics.webhookDelivered,
+		metrics.webhookFailed,
+		metrics.stageQueueDepth,
+		metrics.dlqDepth,
+		metrics.stageDuration,
+		metrics.stageRetries,
+		metrics.orphanedStages,
+		metrics.stageSLABreaches,
 	)
 
-	return &Worker{
-		cfg:     cfg,
-		store:   st,
-		mq:      mqClient,
-		logger:  logger,
-		metrics: metrics,
+	handlerAbort, abort := context.WithCancel(context.Background())
+	w := &Worker{
+		cfg:          cfg,
+		store:        st,
+		mq:           mqClient,
+		policies:     policyengine.NewCache(policyengine.StorePath(), policyReloadInterval),
+		limiter:      ratelimit.NewMemoryStore(),
+		circuits:     newCircuitTracker(),
+		logger:       logger,
+		handlerAbort: handlerAbort,
+		abort:        abort,
+		metrics:      metrics,
+	}
+	st.SetStageResultObserver(w)
+	st.SetRetryPolicy(w.retryPolicy)
+	return w
+}
+
+// ObserveStageResult records the duration of every applied stage result,
+// counts the results that scheduled a retry and feeds the circuit tracker.
+func (w *Worker) ObserveStageResult(outcome store.StageResultOutcome) {
+	switch outcome.Status {
+	case types.StageStatusCompleted:
+		w.circuits.recordResult(outcome.Handler, false, time.Now().UTC())
+	case types.StageStatusFailed, types.StageStatusRetryScheduled:
+		w.circuits.recordResult(outcome.Handler, true, time.Now().UTC())
+	}
+	if outcome.Duration > 0 {
+		w.metrics.stageDuration.WithLabelValues(outcome.Handler, outcome.Status).Observe(outcome.Duration.Seconds())
+	}
+	if outcome.Status == types.StageStatusRetryScheduled {
+		w.metrics.stageRetries.WithLabelValues(outcome.Handler).Inc()
 	}
 }
 
+// Run starts the publisher, consumers and background loops and blocks until
+// ctx is cancelled. Shutdown then drains: the publisher stops first, the
+// consumers stop taking deliveries, and handlers already running get up to
+// DrainTimeout to finish. Run then closes the rate limit store and returns,
+// and the caller closes MQ. Run fails at once when the policy store file the
+// API writes does not show up.
 func (w *Worker) Run(ctx context.Context) error {
-	go w.withRecover(ctx, "publisher", w.runPublisher)
-	go w.withRecover(ctx, "stage-result-consumer", w.runStageResultConsumer)
-	go w.withRecover(ctx, "stage-status-consumer", w.runStageStatusConsumer)
+	if err := w.policies.Wait(ctx, policyStoreWait); err != nil {
+		return err
+	}
+
+	// Consumers outlive ctx so they can be stopped after the publisher.
+	consumeCtx, stopConsumers := context.WithCancel(context.WithoutCancel(ctx))
+	defer stopConsumers()
+
+	publisherDone := make(chan struct{})
+	go func() {
+		defer close(publisherDone)
+		w.withRecover(ctx, "publisher", w.runPublisher)
+	}()
+	go w.withRecover(consumeCtx, "stage-result-consumer", w.stageResultConsumer(constants.StageResult))
+	if w.cfg.ResultQueueShards > 1 {
+		for _, queue := range mq.ShardQueueNames(constants.StageResult, w.cfg.ResultQueueShards) {
+			go w.withRecover(consumeCtx, "stage-result-consumer-"+queue, w.stageResultConsumer(queue))
+		}
+	}
+	go w.withRecover(consumeCtx, "stage-status-consumer", w.runStageStatusConsumer)
 	go w.withRecover(ctx, "pending-watcher", w.runPendingWatcher)
+	if w.cfg.StageLeaseGrace > 0 {
+		go w.withRecover(ctx, "lease-reconciler", w.runLeaseReconciler)
+	}
+	if w.cfg.WorkerRetention > 0 && w.cfg.WorkerRetentionEvery > 0 {
+		go w.withRecover(ctx, "worker-retention", w.runWorkerRetention)
+	}
+	if w.cfg.DeadLetterRetention > 0 {
+		go w.withRecover(ctx, "dead-letter-retention", w.runDeadLetterRetention)
+	}
+	if w.cfg.QueueMonitorInterval > 0 {
+		go w.withRecover(ctx, "queue-monitor", w.runQueueMonitor)
+	}
+	if w.cfg.PipelineStuckAfter > 0 {
+		go w.withRecover(ctx, "stuck-pipeline-watcher", w.runStuckPipelineWatcher)
+	}
+	if w.cfg.APIKeyExpiryWarnWithin > 0 && w.cfg.APIKeyExpiryInterval > 0 {
+		go w.withRecover(ctx, "api-key-expiry-watcher", w.runAPIKeyExpiryWatcher)
+	}
+	if w.cfg.StageSLACheckInterval > 0 {
+		go w.withRecover(ctx, "stage-sla-watcher", w.runStageSLAWatcher)
+	}
 
 	if w.cfg.MetricsAddr != "" {
 		go w.runMetricsServer(ctx)
 	}
 
 	<-ctx.Done()
-	w.logger.Info("worker shutting down")
+	w.logger.Info("worker shutting down", "drainTimeout", w.cfg.DrainTimeout)
+	w.drain(publisherDone, stopConsumers)
+	if w.limiter != nil {
+		if err := ratelimit.Close(w.limiter); err != nil {
+			w.logger.Warn("close rate limit store failed", "err", err)
+		}
+	}
 	return ctx.Err()
 }
 
+// drainPollInterval is how often drain re-checks the in-flight handler count.
+const drainPollInterval = 50 * time.Millisecond
+
+// drain waits for the publisher to stop, then stops the consumers and waits
+// for in-flight handlers until DrainTimeout. Handlers still running at the
+// deadline have their contexts cancelled and are reported as abandoned.
+func (w *Worker) drain(publisherDone <-chan struct{}, stopConsumers context.CancelFunc) {
+	started := time.Now()
+	drainCtx, cancel := context.WithTimeout(context.Background(), w.cfg.DrainTimeout)
+	defer cancel()
+
+	select {
+	case <-publisherDone:
+	case <-drainCtx.Done():
+		w.logger.Warn("publisher did not stop before drain timeout")
+	}
+
+	stopConsumers()
+	atShutdown := w.inflight.Load()
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+wait:
+	for w.inflight.Load() > 0 {
+		select {
+		case <-ticker.C:
+		case <-drainCtx.Done():
+			break wait
+		}
+	}
+
+	abandoned := w.inflight.Load()
+	w.abort()
+	completed := max(atShutdown-abandoned, 0)
+	w.logger.Info("worker drained",
+		"inflightAtShutdown", atShutdown,
+		"completed", completed,
+		"abandoned", abandoned,
+		"elapsed", time.Since(started).Round(time.Millisecond),
+	)
+}
+
+// trackHandler wraps a consumer handler so drain can wait for it and cancel
+// it once the drain timeout expires.
+func (w *Worker) trackHandler(handler func(context.Context, amqp.Delivery) error) func(context.Context, amqp.Delivery) error {
+	return func(ctx context.Context, d amqp.Delivery) error {
+		w.inflight.Add(1)
+		defer w.inflight.Add(-1)
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		stop := context.AfterFunc(w.handlerAbort, cancel)
+		defer stop()
+		return handler(ctx, d)
+	}
+}
+
 const workerRestartDelay = 10 * time.Second
 
 // withRecover runs fn in a loop, recovering from panics and non-context errors.
@@ -130,7 +346,7 @@ func (w *Worker) withRecover(ctx context.Context, name string, fn func(context.C
 func (w *Worker) runMetricsServer(ctx context.Context) {
 	srv := &http.Server{
 		Addr:    w.cfg.MetricsAddr,
-		Handler: promhttp.Handler(),
+		Handler: telemetry.GuardMetrics(promhttp.Handler(), w.cfg.MetricsToken, w.cfg.MetricsAllowedCIDRs),
 	}
 	go func() {
 		<-ctx.Done()
@@ -149,13 +365,13 @@ func (w *Worker) runPublisher(ctx context.Context) error {
 			return ctx.Err()
 		}
 
-		stage, err := w.store.GetStageToExecute(ctx)
+		stages, err := w.store.GetStagesToExecute(ctx, w.cfg.PublishBatchSize)
 		if err != nil {
 			if ctx.Err() != nil {
 				w.logger.Error("runPublisher return", "err", ctx.Err())
 				return ctx.Err()
 			}
-			w.logger.Error("get stage to execute failed", "err", err)
+			w.logger.Error("get stages to execute failed", "err", err)
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
@@ -164,7 +380,7 @@ func (w *Worker) runPublisher(ctx context.Context) error {
 			continue
 		}
 
-		if stage == nil {
+		if len(stages) == 0 {
 			select {
 			case <-ctx.Done():
 				w.logger.Error("runPublisher return", "err", ctx.Err())
@@ -174,49 +390,92 @@ func (w *Worker) runPublisher(ctx context.Context) error {
 			continue
 		}
 
-		queue := stageQueueName(w.cfg.AppID, stage.StageHandlerName)
-		body, _ := json.Marshal(stage)
-		opts := mq.QueueOptions{
-			Durable:     true,
-			DLQEnabled:  w.cfg.QueueDLQEnabled,
-			DLQTTL:      w.cfg.QueueDLQMessageTTL,
-			ContentType: "application/json",
-		}
+		w.publishStages(ctx, stages)
+	}
+}
 
-		if err := w.mq.PublishWithRetry(ctx, queue, body, opts, nil); err != nil {
-			if ctx.Err() != nil {
-				w.logger.Error("runPublisher return", "err", ctx.Err())
-				return ctx.Err()
-			}
+// publishStages publishes a claimed batch with at most PublishFanout stages
+// in flight. A batch holds at most one stage per pipeline, so publishing it
+// concurrently does not reorder stages within a pipeline.
+func (w *Worker) publishStages(ctx context.Context, stages []*types.StageNextMessage) {
+	sem := make(chan struct{}, max(w.cfg.PublishFanout, 1))
+	var wg sync.WaitGroup
+	for _, stage := range stages {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			w.publishStage(ctx, stage)
+		}()
+	}
+	wg.Wait()
+}
+
+func (w *Worker) publishStage(ctx context.Context, stage *types.StageNextMessage) {
+	if w.throttleStage(ctx, stage) {
+		return
+	}
+
+	if !w.cfg.StageEnvironmentRouting {
+		stage.Environment = ""
+	}
+	queue := stageQueueName(w.cfg.AppID, stage.StageHandlerName, stage.Environment)
+	stage.ResultQueue = mq.ShardQueueName(constants.StageResult, stage.StageHandlerName, w.cfg.ResultQueueShards)
+	body, _ := json.Marshal(stage)
+	opts := mq.QueueOptions{
+		Durable:     true,
+		DLQEnabled:  w.cfg.QueueDLQEnabled,
+		DLQTTL:      w.cfg.QueueDLQMessageTTL,
+		ContentType: "application/json",
+		Confirm:     w.cfg.PublishConfirms,
+		QueueType:   w.cfg.QueueType,
+		MaxLength:   w.cfg.QueueMaxLength,
+		Overflow:    w.cfg.QueueOverflow,
+	}
+
+	pubCtx := telemetry.ContextWithStageSpan(ctx, stage.TraceID, stage.SpanID)
+	if err := w.mq.PublishWithRetry(pubCtx, queue, body, opts, nil); err != nil {
+		if ctx.Err() == nil {
 			w.logger.Error("publish stage next failed", "queue", queue, "err", err)
-			continue
 		}
+		return
+	}
 
-		if stage.PipelineID != nil {
-			pipeline, err := w.store.GetPipelineWithStages(ctx, *stage.PipelineID)
-			if err != nil {
-				w.logger.Error("load pipeline snapshot for ws update failed", "pipelineId", *stage.PipelineID, "err", err)
-			} else {
-				w.publishPipelineUpdate(ctx, pipeline)
-			}
+	if stage.PipelineID != nil {
+		pipeline, err := w.store.GetPipelineWithStages(ctx, *stage.PipelineID)
+		if err != nil {
+			w.logger.Error("load pipeline snapshot for ws update failed", "pipelineId", *stage.PipelineID, "err", err)
+		} else {
+			w.publishPipelineUpdate(ctx, pipeline)
 		}
+	}
 
-		w.metrics.stagePublished.Inc()
-		w.logger.Info("published stage", "queue", queue, "stageId", stage.StageID, "pipelineId", stage.PipelineID)
+	w.metrics.stagePublished.Inc()
+	w.logger.Info("published stage", "queue", queue, "stageId", stage.StageID, "pipelineId", stage.PipelineID)
+}
+
+// stageResultConsumer consumes one result queue. The unsharded StageResult
+// queue is always consumed so SDKs that ignore ResultQueue keep working.
+func (w *Worker) stageResultConsumer(queue string) func(context.Context) error {
+	return func(ctx context.Context) error {
+		return w.runStageResultConsumer(ctx, queue)
 	}
 }
 
-func (w *Worker) runStageResultConsumer(ctx context.Context) error {
+func (w *Worker) runStageResultConsumer(ctx context.Context, queue string) error {
 	opts := mq.ConsumeOptions{
 		QueueOptions: mq.QueueOptions{
 			Durable:     true,
 			DLQEnabled:  w.cfg.QueueDLQEnabled,
 			DLQTTL:      w.cfg.QueueDLQMessageTTL,
-			Prefetch:    w.cfg.Prefetch,
+			Prefetch:    w.cfg.StageResultPrefetch,
 			ContentType: "application/json",
+			QueueType:   w.cfg.QueueType,
 		},
 		HandlerTimeout:   30 * time.Second,
 		DeadLetterOnFail: true,
+		OnDeadLetter:     w.recordDeadLetter(queue),
 	}
 
 	handler := func(ctx context.Context, d amqp.Delivery) error {
@@ -224,19 +483,22 @@ func (w *Worker) runStageResultConsumer(ctx context.Context) error {
 		if err := json.Unmarshal(d.Body, &msg); err != nil {
 			return err
 		}
-		pipeline, err := w.store.UpdateStageResult(ctx, msg)
+		pipeline, completed, err := w.store.UpdateStageResult(ctx, msg)
 		if err != nil {
 			w.metrics.stageResultFailed.Inc()
 			return err
 		}
 
 		w.publishPipelineUpdate(ctx, pipeline)
+		if completed {
+			w.notifyPipelineCompleted(ctx, pipeline)
+		}
 		w.metrics.stageResultProcessed.Inc()
 		return nil
 	}
 
-	w.logger.Info("starting StageResult consumer")
-	return w.mq.Consume(ctx, constants.StageResult, opts, handler)
+	w.logger.Info("starting StageResult consumer", "queue", queue)
+	return w.mq.Consume(ctx, queue, opts, w.trackHandler(handler))
 }
 
 func (w *Worker) runStageStatusConsumer(ctx context.Context) error {
@@ -245,11 +507,13 @@ func (w *Worker) runStageStatusConsumer(ctx context.Context) error {
 			Durable:     true,
 			DLQEnabled:  w.cfg.QueueDLQEnabled,
 			DLQTTL:      w.cfg.QueueDLQMessageTTL,
-			Prefetch:    w.cfg.Prefetch,
+			Prefetch:    w.cfg.StageStatusPrefetch,
 			ContentType: "application/json",
+			QueueType:   w.cfg.QueueType,
 		},
 		HandlerTimeout:   15 * time.Second,
 		DeadLetterOnFail: true,
+		OnDeadLetter:     w.recordDeadLetter(constants.StageSetStatus),
 	}
 
 	handler := func(ctx context.Context, d amqp.Delivery) error {
@@ -267,32 +531,201 @@ func (w *Worker) runStageStatusConsumer(ctx context.Context) error {
 	}
 
 	w.logger.Info("starting StageSetStatus consumer")
-	return w.mq.Consume(ctx, constants.StageSetStatus, opts, handler)
+	return w.mq.Consume(ctx, constants.StageSetStatus, opts, w.trackHandler(handler))
 }
 
+// pendingWatchMaxInterval bounds the watcher tick so short per-stage
+// timeouts are enforced promptly even with a long global default.
+const pendingWatchMaxInterval = 15 * time.Second
+
 func (w *Worker) runPendingWatcher(ctx context.Context) error {
-	ticker := time.NewTicker(w.cfg.StagePendingTimeout / 2)
+	interval := w.cfg.StagePendingTimeout / 2
+	if interval <= 0 || interval > pendingWatchMaxInterval {
+		interval = pendingWatchMaxInterval
+	}
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-ticker.C:
-			affected, err := w.store.MarkPendingTooLong(ctx, w.cfg.StagePendingTimeout)
+			failed, retried, err := w.store.MarkPendingTooLong(ctx, w.cfg.StagePendingTimeout, w.timeoutPolicyAction)
 			if err != nil {
 				w.logger.Error("mark pending too long failed", "err", err)
 				continue
 			}
-			if affected > 0 {
-				w.metrics.pendingMarkedFailed.Add(float64(affected))
-				w.logger.Warn("marked pending stages as failed", "count", affected)
+			if failed > 0 {
+				w.metrics.pendingMarkedFailed.Add(float64(failed))
+				w.logger.Warn("marked timed out stages as failed", "count", failed)
+			}
+			if retried > 0 {
+				w.metrics.pendingRetried.Add(float64(retried))
+				w.logger.Warn("scheduled timed out stages for retry", "count", retried)
+			}
+		}
+	}
+}
+
+// runLeaseReconciler requeues or fails stages whose gateway lease lapsed
+// StageLeaseGrace ago; see store.ReconcileOrphanedStages.
+func (w *Worker) runLeaseReconciler(ctx context.Context) error {
+	interval := w.cfg.StageLeaseGrace / 2
+	if interval <= 0 || interval > pendingWatchMaxInterval {
+		interval = pendingWatchMaxInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			requeued, failed, err := w.store.ReconcileOrphanedStages(ctx, w.cfg.StageLeaseGrace)
+			if err != nil {
+				w.logger.Error("reconcile orphaned stages failed", "err", err)
+			}
+			if requeued > 0 {
+				w.metrics.orphanedStages.WithLabelValues("requeued").Add(float64(requeued))
+			}
+			if failed > 0 {
+				w.metrics.orphanedStages.WithLabelValues("failed").Add(float64(failed))
+			}
+			if requeued > 0 || failed > 0 {
+				w.logger.Warn("reconciled stages with lapsed leases", "requeued", requeued, "failed", failed)
+			}
+		}
+	}
+}
+
+// stuckWatchMaxInterval bounds the stuck-pipeline watcher tick.
+const stuckWatchMaxInterval = time.Minute
+
+func (w *Worker) runStuckPipelineWatcher(ctx context.Context) error {
+	interval := w.cfg.PipelineStuckAfter / 2
+	if interval <= 0 || interval > stuckWatchMaxInterval {
+		interval = stuckWatchMaxInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			stuck, err := w.store.DetectStuckPipelines(ctx, w.cfg.PipelineStuckAfter)
+			if err != nil {
+				w.logger.Error("detect stuck pipelines failed", "err", err)
+				continue
+			}
+			if stuck > 0 {
+				w.logger.Warn("detected stuck pipelines", "count", stuck, "stuckAfter", w.cfg.PipelineStuckAfter)
 			}
 		}
 	}
 }
 
-func stageQueueName(appID string, handler string) string {
-	return appID + "_" + handler + "_" + constants.StageNext
+// runAPIKeyExpiryWatcher reports API keys expiring within
+// APIKeyExpiryWarnWithin; each key is reported at most once a day.
+func (w *Worker) runAPIKeyExpiryWatcher(ctx context.Context) error {
+	ticker := time.NewTicker(w.cfg.APIKeyExpiryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			expiring, err := w.store.DetectExpiringAPIKeys(ctx, w.cfg.APIKeyExpiryWarnWithin)
+			if err != nil {
+				w.logger.Error("detect expiring api keys failed", "err", err)
+				continue
+			}
+			if expiring > 0 {
+				w.logger.Warn("api keys expiring soon", "count", expiring, "within", w.cfg.APIKeyExpiryWarnWithin)
+			}
+		}
+	}
+}
+
+// runStageSLAWatcher reports Pending and Running stages that have run for
+// longer than StageSLABreachFactor times their expected duration; see
+// store.DetectStageSLABreaches. The stages themselves are left alone.
+func (w *Worker) runStageSLAWatcher(ctx context.Context) error {
+	ticker := time.NewTicker(w.cfg.StageSLACheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			breached, err := w.store.DetectStageSLABreaches(ctx, w.cfg.StageSLABreachFactor, w.cfg.StageSLABaselineSamples)
+			if err != nil {
+				w.logger.Error("detect stage sla breaches failed", "err", err)
+				continue
+			}
+			if breached > 0 {
+				w.metrics.stageSLABreaches.Add(float64(breached))
+				w.logger.Warn("stages running past their expected duration", "count", breached, "factor", w.cfg.StageSLABreachFactor)
+			}
+		}
+	}
+}
+
+func (w *Worker) runWorkerRetention(ctx context.Context) error {
+	ticker := time.NewTicker(w.cfg.WorkerRetentionEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			pruned, err := w.store.PruneWorkers(ctx, w.cfg.WorkerRetention)
+			if err != nil {
+				w.logger.Error("prune workers failed", "err", err)
+				continue
+			}
+			if pruned > 0 {
+				w.metrics.workersPruned.Add(float64(pruned))
+				w.logger.Info("pruned stale workers", "count", pruned, "retention", w.cfg.WorkerRetention)
+			}
+		}
+	}
+}
+
+// deadLetterPruneInterval is how often dead letters past
+// DeadLetterRetention are deleted.
+const deadLetterPruneInterval = time.Hour
+
+func (w *Worker) runDeadLetterRetention(ctx context.Context) error {
+	ticker := time.NewTicker(deadLetterPruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			pruned, err := w.store.PruneDeadLetters(ctx, w.cfg.DeadLetterRetention)
+			if err != nil {
+				w.logger.Error("prune dead letters failed", "err", err)
+				continue
+			}
+			if pruned > 0 {
+				w.logger.Info("pruned old dead letters", "count", pruned, "retention", w.cfg.DeadLetterRetention)
+			}
+		}
+	}
+}
+
+// stageQueueName returns the StageNext queue of a handler. With
+// STAGE_ENVIRONMENT_ROUTING, stages of a pipeline with an environment go to a
+// queue of their own, so workers of other environments never pick them up;
+// the rest keep the default queue.
+func stageQueueName(appID, handler, environment string) string {
+	queue := appID + "_" + handler + "_" + constants.StageNext
+	if environment != "" {
+		queue += "." + environment
+	}
+	return queue
 }
 
 func (w *Worker) publishPipelineUpdate(ctx context.Context, pipeline *types.PipelineResponse) {
@@ -311,6 +744,7 @@ func (w *Worker) publishPipelineUpdate(ctx context.Context, pipeline *types.Pipe
 		DLQEnabled:  w.cfg.QueueDLQEnabled,
 		DLQTTL:      w.cfg.QueueDLQMessageTTL,
 		ContentType: "application/json",
+		QueueType:   w.cfg.QueueType,
 	}
 
 	if err := w.mq.PublishWithRetry(ctx, constants.StageUpdated, payload, pubOpts, nil); err != nil {
@@ -3,6 +3,10 @@ import type {
   LoginRequest,
   PipelineResponse,
   StageResponse,
+  StageExecutionHistoryResponse,
+  PipelineGraph,
+  PipelineTimelineResponse,
+  RuntimeConfig,
   ContextItem,
   PagedResult,
   GetPipelinesParams,
@@ -10,17 +14,25 @@ import type {
   SkipStageRequest,
   ApplicationResponse,
   SaveApplicationRequest,
+  PipelineStatsResponse,
   ApiKeyResponse,
+  ExpiringApiKeyResponse,
   GenerateApiKeyRequest,
   DisableApiKeyRequest,
   StageLog,
   WorkerStatusListResponse,
-  WorkerEventResponse,
+  HandlerCapacityListResponse,
+  HandlerRegistryResponse,
+  WorkerDetailResponse,
+  WorkerConfigResponse,
+  WorkerEventListResponse,
 } from '@/types/api';
 import type {
   ObservabilityConfig,
   ObservabilityStatus,
   ObservabilityInsights,
+  AlertDeliveryEntry,
+  QueueStatsResponse,
   TraceEntry,
   TestConnectionResult,
   SaveIntegrationConfigRequest,
@@ -49,12 +61,39 @@ class ApiError extends Error {
   constructor(
     public status: number,
     message: string,
+    public code?: string,
+    public details?: unknown,
   ) {
     super(message);
     this.name = 'ApiError';
   }
 }
 
+// withErrorDetails appends the offending config key or field, when the
+// server reports one, so dialogs can show what to fix.
+function withErrorDetails(message: string, details: unknown): string {
+  if (!details || typeof details !== 'object') return message;
+  const { missingKey, field } = details as { missingKey?: unknown; field?: unknown };
+  if (typeof missingKey === 'string' && missingKey) return `${message} (missing ${missingKey})`;
+  if (typeof field === 'string' && field) return `${message} (${field})`;
+  return message;
+}
+
+// Error responses use the envelope {"error": {"code", "message", "details"}}.
+async function toApiError(response: Response): Promise<ApiError> {
+  const text = await response.text();
+  try {
+    const body = JSON.parse(text);
+    if (body?.error && typeof body.error.message === 'string') {
+      const { code, details } = body.error;
+      return new ApiError(response.status, withErrorDetails(body.error.message, details), code, details);
+    }
+  } catch {
+    // Not JSON; fall back to the raw body.
+  }
+  return new ApiError(response.status, text || `HTTP ${response.status}`);
+}
+
 async function request<T>(
   endpoint: string,
   options: RequestInit = {},
@@ -69,8 +108,7 @@ async function request<T>(
   });
 
   if (!response.ok) {
-    const message = await response.text();
-    throw new ApiError(response.status, message || `HTTP ${response.status}`);
+    throw await toApiError(response);
   }
 
   const contentLength = response.headers.get('content-length');
@@ -116,13 +154,18 @@ export const pipelinesApi = {
     if (params?.pageSize) searchParams.set('pageSize', String(params.pageSize));
     if (params?.applicationId) searchParams.set('applicationId', String(params.applicationId));
     if (params?.search) searchParams.set('search', params.search);
+    if (params?.traceId) searchParams.set('traceId', params.traceId);
     if (params?.pipelineStartFrom) searchParams.set('pipelineStartFrom', params.pipelineStartFrom);
     if (params?.pipelineStartTo) searchParams.set('pipelineStartTo', params.pipelineStartTo);
     if (params?.pipelineEndFrom) searchParams.set('pipelineEndFrom', params.pipelineEndFrom);
     if (params?.pipelineEndTo) searchParams.set('pipelineEndTo', params.pipelineEndTo);
+    if (params?.sortBy) searchParams.set('sortBy', params.sortBy);
+    if (params?.sortDir) searchParams.set('sortDir', params.sortDir);
 
     // Handle array params
     params?.keywords?.forEach(k => searchParams.append('keywords', k));
+    if (params?.keywordMatch) searchParams.set('keywordMatch', params.keywordMatch);
+    params?.labels?.forEach(l => searchParams.append('labels', l));
     params?.statuses?.forEach(s => searchParams.append('statuses', s));
 
     const queryString = searchParams.toString();
@@ -137,6 +180,18 @@ export const pipelinesApi = {
     return request<StageResponse[]>(`/pipelines/${pipelineId}/stages`);
   },
 
+  getPipelineGraph: async (pipelineId: number): Promise<PipelineGraph> => {
+    return request<PipelineGraph>(`/pipelines/${pipelineId}/graph`);
+  },
+
+  getPipelineTimeline: async (pipelineId: number): Promise<PipelineTimelineResponse> => {
+    return request<PipelineTimelineResponse>(`/pipelines/${pipelineId}/timeline`);
+  },
+
+  getStageHistory: async (pipelineId: number, stageId: number): Promise<StageExecutionHistoryResponse> => {
+    return request<StageExecutionHistoryResponse>(`/pipelines/${pipelineId}/stages/${stageId}/history`);
+  },
+
   getContext: async (pipelineId: number): Promise<ContextItem[]> => {
     return request<ContextItem[]>(`/pipelines/${pipelineId}/context`);
   },
@@ -161,6 +216,13 @@ export const pipelinesApi = {
       body: JSON.stringify(data),
     });
   },
+
+  replay: async (pipelineId: number, options?: { copyContext?: boolean }): Promise<PipelineResponse> => {
+    return request<PipelineResponse>(`/pipelines/${pipelineId}/replay`, {
+      method: 'POST',
+      body: JSON.stringify({ copyContext: options?.copyContext ?? false }),
+    });
+  },
 };
 
 // Applications API
@@ -175,6 +237,22 @@ export const applicationsApi = {
       body: JSON.stringify(data),
     });
   },
+
+  disable: async (applicationId: number): Promise<void> => {
+    await request<void>(`/applications/${applicationId}/disable`, {
+      method: 'PUT',
+    });
+  },
+
+  enable: async (applicationId: number): Promise<void> => {
+    await request<void>(`/applications/${applicationId}/enable`, {
+      method: 'PUT',
+    });
+  },
+
+  getStats: async (applicationId: number, range: TimeRange = '24h'): Promise<PipelineStatsResponse> => {
+    return request<PipelineStatsResponse>(`/applications/${applicationId}/stats?range=${encodeURIComponent(range)}`);
+  },
 };
 
 // API Keys API
@@ -183,6 +261,11 @@ export const apiKeysApi = {
     return request<ApiKeyResponse[]>(`/apiKeys?applicationId=${applicationId}`);
   },
 
+  getExpiring: async (withinDays?: number): Promise<ExpiringApiKeyResponse[]> => {
+    const query = withinDays ? `?withinDays=${withinDays}` : '';
+    return request<ExpiringApiKeyResponse[]>(`/apiKeys/expiring${query}`);
+  },
+
   generate: async (data: GenerateApiKeyRequest): Promise<ApiKeyResponse> => {
     return request<ApiKeyResponse>('/apiKeys', {
       method: 'POST',
@@ -205,6 +288,13 @@ export const keywordsApi = {
   },
 };
 
+// Runtime config API
+export const configApi = {
+  getRuntimeConfig: async (): Promise<RuntimeConfig> => {
+    return request<RuntimeConfig>('/config');
+  },
+};
+
 // Workers API
 export const workersApi = {
   getStatus: async (params?: { state?: string; applicationId?: number; search?: string; limit?: number }): Promise<WorkerStatusListResponse> => {
@@ -217,13 +307,44 @@ export const workersApi = {
     return request<WorkerStatusListResponse>(`/workers${qs ? `?${qs}` : ''}`);
   },
 
-  getEvents: async (params?: { workerId?: string; applicationId?: number; limit?: number }): Promise<WorkerEventResponse[]> => {
+  getEvents: async (params?: {
+    workerId?: string;
+    applicationId?: number;
+    level?: string;
+    eventType?: string;
+    before?: string;
+    after?: string;
+    limit?: number;
+  }): Promise<WorkerEventListResponse> => {
     const searchParams = new URLSearchParams();
     if (params?.workerId) searchParams.set('workerId', params.workerId);
     if (params?.applicationId) searchParams.set('applicationId', String(params.applicationId));
+    if (params?.level) searchParams.set('level', params.level);
+    if (params?.eventType) searchParams.set('eventType', params.eventType);
+    if (params?.before) searchParams.set('before', params.before);
+    if (params?.after) searchParams.set('after', params.after);
     if (params?.limit) searchParams.set('limit', String(params.limit));
     const qs = searchParams.toString();
-    return request<WorkerEventResponse[]>(`/workers/events${qs ? `?${qs}` : ''}`);
+    return request<WorkerEventListResponse>(`/workers/events${qs ? `?${qs}` : ''}`);
+  },
+
+  getWorker: async (workerId: string, params?: { limit?: number }): Promise<WorkerDetailResponse> => {
+    const qs = params?.limit ? `?limit=${params.limit}` : '';
+    return request<WorkerDetailResponse>(`/workers/${encodeURIComponent(workerId)}${qs}`);
+  },
+
+  getWorkerConfig: async (workerId: string): Promise<WorkerConfigResponse> => {
+    return request<WorkerConfigResponse>(`/workers/${encodeURIComponent(workerId)}/config`);
+  },
+
+  getCapacity: async (params?: { applicationId?: number }): Promise<HandlerCapacityListResponse> => {
+    const qs = params?.applicationId ? `?applicationId=${params.applicationId}` : '';
+    return request<HandlerCapacityListResponse>(`/workers/capacity${qs}`);
+  },
+
+  getHandlers: async (params?: { applicationId?: number }): Promise<HandlerRegistryResponse> => {
+    const qs = params?.applicationId ? `?applicationId=${params.applicationId}` : '';
+    return request<HandlerRegistryResponse>(`/handlers${qs}`);
   },
 };
 
@@ -264,6 +385,20 @@ export const observabilityApi = {
     const qs = timeRange ? `?timeRange=${timeRange}` : '';
     return request<ObservabilityInsights>(`/observability/insights${qs}`);
   },
+
+  getAlertHistory: async (params?: { event?: string; status?: string; timeRange?: TimeRange; limit?: number }): Promise<AlertDeliveryEntry[]> => {
+    const searchParams = new URLSearchParams();
+    if (params?.event) searchParams.set('event', params.event);
+    if (params?.status) searchParams.set('status', params.status);
+    if (params?.timeRange) searchParams.set('timeRange', params.timeRange);
+    if (params?.limit) searchParams.set('limit', String(params.limit));
+    const qs = searchParams.toString();
+    return request<AlertDeliveryEntry[]>(`/observability/alerts/history${qs ? `?${qs}` : ''}`);
+  },
+
+  getQueueStats: async (): Promise<QueueStatsResponse> => {
+    return request<QueueStatsResponse>('/observability/queues');
+  },
 };
 
 // Policies API
@@ -279,6 +414,8 @@ export const policiesApi = {
     if (params?.range) searchParams.set('range', params.range);
     if (params?.sortBy) searchParams.set('sortBy', params.sortBy);
     if (params?.sortDir) searchParams.set('sortDir', params.sortDir);
+    if (params?.limit) searchParams.set('limit', String(params.limit));
+    if (params?.offset) searchParams.set('offset', String(params.offset));
 
     const queryString = searchParams.toString();
     return request<PolicyListResponse>(`/policies${queryString ? `?${queryString}` : ''}`);
@@ -347,8 +484,12 @@ export const policiesApi = {
     return request<PolicyInsightsResponse>(`/policies/insights?range=${range}`);
   },
 
-  getTargetOptions: async (): Promise<PolicyTargetOptionsResponse> => {
-    return request<PolicyTargetOptionsResponse>('/policies/targets');
+  getTargetOptions: async (search?: string, limit?: number): Promise<PolicyTargetOptionsResponse> => {
+    const params = new URLSearchParams();
+    if (search) params.set('search', search);
+    if (limit) params.set('limit', String(limit));
+    const query = params.toString();
+    return request<PolicyTargetOptionsResponse>(`/policies/targets${query ? `?${query}` : ''}`);
   },
 
   previewTargets: async (payload: PolicyPreviewRequest): Promise<PolicyPreviewResponse> => {
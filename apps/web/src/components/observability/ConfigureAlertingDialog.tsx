@@ -41,10 +41,12 @@ const CHANNEL_OPTIONS: Array<{ value: AlertChannel; label: string; hint: string
 
 const EVENT_OPTIONS: Array<{ value: AlertEvent; label: string }> = [
   { value: "stage_failed", label: "Stage failed" },
+  { value: "stage_timeout_retry", label: "Stage timed out (retry scheduled)" },
   { value: "stage_rerun_manual", label: "Stage rerun (manual)" },
   { value: "stage_skipped_manual", label: "Stage skipped (manual)" },
   { value: "pipeline_failed", label: "Pipeline failed" },
   { value: "pipeline_stuck", label: "Pipeline stuck / timeout" },
+  { value: "stage_sla_breach", label: "Stage running past expected duration" },
   { value: "worker_started", label: "Worker started" },
   { value: "worker_failed", label: "Worker failed" },
   { value: "worker_stopped", label: "Worker stopped" },
@@ -53,6 +55,7 @@ const EVENT_OPTIONS: Array<{ value: AlertEvent; label: string }> = [
   { value: "policy_changed", label: "Policy changed" },
   { value: "queue_backlog_high", label: "Queue backlog high" },
   { value: "dlq_message_detected", label: "DLQ message detected" },
+  { value: "api_key_expiring", label: "API key expiring" },
 ];
 
 function toStringArray(value: unknown): string[] {
@@ -80,6 +83,7 @@ type AlertingFormState = {
   enabledEvents: AlertEvent[];
   sendResolved: boolean;
   dedupeWindowSeconds: string;
+  maxAlertsPerMinute: string;
   telegramBotToken: string;
   telegramChatId: string;
   whatsappWebhookUrl: string;
@@ -99,6 +103,7 @@ function buildAlertingFormState(cfg: Partial<AlertingConfig>): AlertingFormState
       : ["stage_failed", "stage_rerun_manual", "stage_skipped_manual", "worker_failed", "policy_triggered"]) as AlertEvent[],
     sendResolved: Boolean(cfg.sendResolved ?? true),
     dedupeWindowSeconds: String(cfg.dedupeWindowSeconds ?? 300),
+    maxAlertsPerMinute: String(cfg.maxAlertsPerMinute ?? 60),
     telegramBotToken: cfg.telegramBotToken || "",
     telegramChatId: cfg.telegramChatId || "",
     whatsappWebhookUrl: cfg.whatsappWebhookUrl || "",
@@ -130,7 +135,7 @@ export function ConfigureAlertingDialog({ open, onOpenChange, integration }: Con
 
   // Convenience destructure so the JSX below is unchanged
   const {
-    channels, enabledEvents, sendResolved, dedupeWindowSeconds,
+    channels, enabledEvents, sendResolved, dedupeWindowSeconds, maxAlertsPerMinute,
     telegramBotToken, telegramChatId, whatsappWebhookUrl, slackWebhookUrl,
     teamsWebhookUrl, webhookUrl, emailRecipients, pagerdutyRoutingKey,
   } = form;
@@ -141,6 +146,7 @@ export function ConfigureAlertingDialog({ open, onOpenChange, integration }: Con
     setForm(p => ({ ...p, enabledEvents: typeof updater === "function" ? updater(p.enabledEvents) : updater }));
   const setSendResolved = (v: boolean) => setForm(p => ({ ...p, sendResolved: v }));
   const setDedupeWindowSeconds = (v: string) => setForm(p => ({ ...p, dedupeWindowSeconds: v }));
+  const setMaxAlertsPerMinute = (v: string) => setForm(p => ({ ...p, maxAlertsPerMinute: v }));
   const setTelegramBotToken = (v: string) => setForm(p => ({ ...p, telegramBotToken: v }));
   const setTelegramChatId = (v: string) => setForm(p => ({ ...p, telegramChatId: v }));
   const setWhatsappWebhookUrl = (v: string) => setForm(p => ({ ...p, whatsappWebhookUrl: v }));
@@ -158,6 +164,18 @@ export function ConfigureAlertingDialog({ open, onOpenChange, integration }: Con
     enabledEvents,
     sendResolved,
     dedupeWindowSeconds: Number(dedupeWindowSeconds) || 300,
+    maxAlertsPerMinute: maxAlertsPerMinute.trim() === "" ? 60 : Math.max(0, Math.floor(Number(maxAlertsPerMinute) || 0)),
+    // Per-event overrides, send retry settings, worker offline aggregation,
+    // message templates, extra webhook endpoints and the webhook signing
+    // secret have no form fields yet; keep whatever is stored.
+    dedupeWindowSecondsByEvent: existing.dedupeWindowSecondsByEvent,
+    maxAlertsPerMinuteByEvent: existing.maxAlertsPerMinuteByEvent,
+    sendMaxRetries: existing.sendMaxRetries,
+    sendTimeoutSeconds: existing.sendTimeoutSeconds,
+    workerOfflineAggregateSeconds: existing.workerOfflineAggregateSeconds,
+    messageTemplates: existing.messageTemplates,
+    webhooks: existing.webhooks,
+    webhookSecret: existing.webhookSecret,
     telegramBotToken: telegramBotToken.trim(),
     telegramChatId: telegramChatId.trim(),
     whatsappWebhookUrl: whatsappWebhookUrl.trim(),
@@ -241,6 +259,17 @@ export function ConfigureAlertingDialog({ open, onOpenChange, integration }: Con
               />
             </div>
 
+            <div className="space-y-2">
+              <Label htmlFor="alerts-max-per-minute">Max Alerts / min</Label>
+              <Input
+                id="alerts-max-per-minute"
+                type="number"
+                min={0}
+                value={maxAlertsPerMinute}
+                onChange={(e) => setMaxAlertsPerMinute(e.target.value)}
+              />
+            </div>
+
             <div className="space-y-2">
               <Label htmlFor="alerts-send-resolved">Send Resolved</Label>
               <div className="flex h-10 items-center gap-3">
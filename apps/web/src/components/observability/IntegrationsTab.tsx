@@ -99,7 +99,7 @@ OTEL_SERVICE_NAME=pipelogiq`;
   const statusToVariant = (s: string) => {
     switch (s) {
       case "connected": return "success" as const;
-      case "configured": return "warning" as const;
+      case "configured": case "stale": return "warning" as const;
       case "disconnected":
       case "error":
         return "error" as const;
@@ -111,6 +111,7 @@ OTEL_SERVICE_NAME=pipelogiq`;
   const statusLabel = (s: string) => {
     switch (s) {
       case "connected": return "Connected";
+      case "stale": return "Stale";
       case "configured": return "Configured";
       case "disconnected": return "Disconnected";
       case "error": return "Error";
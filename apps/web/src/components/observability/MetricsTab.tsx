@@ -122,6 +122,43 @@ export function MetricsTab({ timeRange }: MetricsTabProps) {
         </div>
       </div>
 
+      {/* Handler breakdown table */}
+      <div>
+        <h3 className="text-sm font-semibold text-muted-foreground uppercase tracking-wider mb-3">
+          Handlers
+        </h3>
+        <div className="rounded-xl border border-border bg-card overflow-hidden">
+          <table className="w-full text-sm">
+            <thead>
+              <tr className="border-b border-border bg-muted/50">
+                <th className="px-5 py-3 text-left font-semibold text-muted-foreground">Handler</th>
+                <th className="px-5 py-3 text-right font-semibold text-muted-foreground">Pipelines</th>
+                <th className="px-5 py-3 text-right font-semibold text-muted-foreground">Runs</th>
+                <th className="px-5 py-3 text-right font-semibold text-muted-foreground">Avg</th>
+                <th className="px-5 py-3 text-right font-semibold text-muted-foreground">p95 Duration</th>
+                <th className="px-5 py-3 text-right font-semibold text-muted-foreground">Failure Rate</th>
+              </tr>
+            </thead>
+            <tbody className="divide-y divide-border">
+              {[...(insights.errorProneHandlers ?? []), ...(insights.slowestHandlers ?? [])]
+                .filter((handler, idx, all) => all.findIndex((h) => h.handlerName === handler.handlerName) === idx)
+                .map((handler) => (
+                  <tr key={handler.handlerName} className="hover:bg-muted/50 transition-colors">
+                    <td className="px-5 py-3 font-medium font-mono">{handler.handlerName}</td>
+                    <td className="px-5 py-3 text-right text-muted-foreground">{handler.pipelines}</td>
+                    <td className="px-5 py-3 text-right text-muted-foreground">{handler.runs}</td>
+                    <td className="px-5 py-3 text-right font-mono">{formatMs(Math.round(handler.avgMs))}</td>
+                    <td className="px-5 py-3 text-right font-mono font-semibold">{formatMs(handler.p95Ms)}</td>
+                    <td className={`px-5 py-3 text-right font-semibold ${handler.failureRate > 10 ? "text-red-600" : handler.failureRate > 5 ? "text-amber-600" : ""}`}>
+                      {handler.failureRate.toFixed(1)}%
+                    </td>
+                  </tr>
+                ))}
+            </tbody>
+          </table>
+        </div>
+      </div>
+
       {/* Metrics / Grafana / Tempo info */}
       <div className="rounded-xl border border-border bg-card p-5">
         <h4 className="mb-2 text-sm font-semibold">Metrics / Grafana / Tempo</h4>
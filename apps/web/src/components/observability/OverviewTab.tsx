@@ -72,7 +72,7 @@ OTEL_SERVICE_NAME=pipelogiq`;
   const statusToVariant = (s?: string) => {
     switch (s) {
       case "connected": return "success" as const;
-      case "configured": return "warning" as const;
+      case "configured": case "stale": return "warning" as const;
       case "disconnected": case "error": return "error" as const;
       default: return "default" as const;
     }
@@ -81,6 +81,7 @@ OTEL_SERVICE_NAME=pipelogiq`;
   const statusLabel = (s?: string) => {
     switch (s) {
       case "connected": return "Connected";
+      case "stale": return "Stale";
       case "configured": return "Configured";
       case "disconnected": return "Disconnected";
       case "error": return "Error";
@@ -83,6 +83,16 @@ const MOCK_INSIGHTS: ObservabilityInsights = {
     { pipelineName: 'betting-settlement', stageName: 'payout-transfer', failureRate: 4.7, avgRetries: 1.5 },
     { pipelineName: 'notification-dispatch', stageName: 'sms-send', failureRate: 3.2, avgRetries: 1.2 },
   ],
+  slowestHandlers: [
+    { handlerName: 'odds-calculator', runs: 412, pipelines: 2, avgMs: 5100, p95Ms: 8450, failureRate: 1.2, avgRetries: 0.2 },
+    { handlerName: 'ocr', runs: 220, pipelines: 1, avgMs: 2900, p95Ms: 4800, failureRate: 0.9, avgRetries: 0.1 },
+    { handlerName: 'http-fetch', runs: 980, pipelines: 4, avgMs: 640, p95Ms: 2700, failureRate: 7.8, avgRetries: 2.4 },
+  ],
+  errorProneHandlers: [
+    { handlerName: 'id-validator', runs: 310, pipelines: 1, avgMs: 820, p95Ms: 1900, failureRate: 12.5, avgRetries: 2.1 },
+    { handlerName: 'http-fetch', runs: 980, pipelines: 4, avgMs: 640, p95Ms: 2700, failureRate: 7.8, avgRetries: 2.4 },
+    { handlerName: 'sms-gateway', runs: 1540, pipelines: 3, avgMs: 210, p95Ms: 600, failureRate: 3.2, avgRetries: 1.2 },
+  ],
   summary: {
     executionsPerMin: 24.5,
     failuresPerMin: 1.8,
@@ -42,10 +42,10 @@ export function usePolicyAudit(policyId: string | null) {
   });
 }
 
-export function usePolicyTargetOptions() {
+export function usePolicyTargetOptions(search?: string) {
   return useQuery({
-    queryKey: ['policy-target-options'],
-    queryFn: () => policiesApi.getTargetOptions(),
+    queryKey: ['policy-target-options', search ?? ''],
+    queryFn: () => policiesApi.getTargetOptions(search),
   });
 }
 
@@ -9,7 +9,7 @@ export function useWorkerStatus(params?: { state?: string; applicationId?: numbe
   });
 }
 
-export function useWorkerEvents(params?: { workerId?: string; applicationId?: number; limit?: number }) {
+export function useWorkerEvents(params?: { workerId?: string; applicationId?: number; level?: string; eventType?: string; limit?: number }) {
   return useQuery({
     queryKey: ['workers', 'events', params],
     queryFn: () => workersApi.getEvents(params),
@@ -15,7 +15,8 @@ import type { WorkerState, WorkerStatusResponse } from "@/types/api";
 
 export default function Dashboard() {
   const { data: workers, isLoading: workersLoading, error: workersError } = useWorkerStatus({ limit: 50 });
-  const { data: events, isLoading: eventsLoading, error: eventsError } = useWorkerEvents({ limit: 80 });
+  const { data: eventsPage, isLoading: eventsLoading, error: eventsError } = useWorkerEvents({ limit: 80 });
+  const events = eventsPage?.items;
 
   const status = workers ?? {
     items: [],
@@ -166,6 +167,11 @@ function WorkerRow({ worker }: { worker: WorkerStatusResponse }) {
         <div className="text-xs text-muted-foreground">
           {worker.hostName || "unknown-host"}
           {worker.workerVersion ? ` • ${worker.workerVersion}` : ""}
+          {worker.configOutdated ? (
+            <span className="ml-1 text-amber-700" title={`Running config version ${worker.configVersion}`}>
+              • outdated config
+            </span>
+          ) : null}
         </div>
       </td>
       <td className="px-4 py-3">
@@ -5,6 +5,7 @@ import {
   CheckCircle2,
   ChevronDown,
   Clock,
+  Layers,
   Loader2,
   MoreHorizontal,
   Pause,
@@ -86,6 +87,7 @@ import {
 } from '@/hooks/use-policies';
 import type {
   CircuitBreakerRule,
+  ConcurrencyLimitRule,
   CreatePolicyRequest,
   Policy,
   PolicyEnvironment,
@@ -112,6 +114,7 @@ interface PolicyDraft {
   retryRule: RetryRule;
   timeoutRule: TimeoutRule;
   circuitBreakerRule: CircuitBreakerRule;
+  concurrencyLimitRule: ConcurrencyLimitRule;
 }
 
 const rangeOptions: Array<{ value: PolicyRange; label: string }> = [
@@ -146,6 +149,12 @@ const policyTypes: Array<{ value: PolicyType; label: string; description: string
     description: 'Open on repeated failures and protect dependencies.',
     icon: Shield,
   },
+  {
+    value: 'concurrency_limit',
+    label: 'Concurrency limit',
+    description: 'Cap simultaneous in-flight stages per handler or tenant.',
+    icon: Layers,
+  },
 ];
 
 const policyStatusOptions: Array<{ value: PolicyStatus | 'all'; label: string }> = [
@@ -192,6 +201,7 @@ function createDefaultDraft(): PolicyDraft {
       baseDelayMs: 500,
       maxDelayMs: 5000,
       jitter: true,
+      jitterMode: 'full',
       retryOn: {
         httpStatus: [429, 500, 502, 503],
         errorCodes: [],
@@ -207,6 +217,10 @@ function createDefaultDraft(): PolicyDraft {
       openSeconds: 30,
       halfOpenMaxCalls: 3,
     },
+    concurrencyLimitRule: {
+      maxConcurrent: 10,
+      keyBy: 'handler',
+    },
   };
 }
 
@@ -254,6 +268,12 @@ function mapPolicyToDraft(policy: Policy): PolicyDraft {
         ...policy.rule,
       };
       break;
+    case 'concurrency_limit':
+      draft.concurrencyLimitRule = {
+        ...draft.concurrencyLimitRule,
+        ...policy.rule,
+      };
+      break;
   }
 
   return draft;
@@ -276,6 +296,7 @@ function getPolicyRuleFromDraft(draft: PolicyDraft): PolicyRule {
         baseDelayMs: Math.max(1, draft.retryRule.baseDelayMs),
         maxDelayMs: draft.retryRule.maxDelayMs && draft.retryRule.maxDelayMs > 0 ? draft.retryRule.maxDelayMs : undefined,
         jitter: Boolean(draft.retryRule.jitter),
+        jitterMode: draft.retryRule.jitter ? draft.retryRule.jitterMode ?? 'full' : undefined,
         retryOn: {
           httpStatus: (draft.retryRule.retryOn?.httpStatus ?? []).filter(code => Number.isFinite(code)),
           errorCodes: (draft.retryRule.retryOn?.errorCodes ?? []).filter(Boolean),
@@ -296,6 +317,12 @@ function getPolicyRuleFromDraft(draft: PolicyDraft): PolicyRule {
         halfOpenMaxCalls: Math.max(1, draft.circuitBreakerRule.halfOpenMaxCalls),
       };
     }
+    case 'concurrency_limit': {
+      return {
+        maxConcurrent: Math.max(1, draft.concurrencyLimitRule.maxConcurrent),
+        keyBy: draft.concurrencyLimitRule.keyBy,
+      };
+    }
   }
 }
 
@@ -326,6 +353,8 @@ function buildPolicyPayload(draft: PolicyDraft): CreatePolicyRequest {
       return { ...base, type: 'timeout', rule: rule as TimeoutRule };
     case 'circuit_breaker':
       return { ...base, type: 'circuit_breaker', rule: rule as CircuitBreakerRule };
+    case 'concurrency_limit':
+      return { ...base, type: 'concurrency_limit', rule: rule as ConcurrencyLimitRule };
   }
 }
 
@@ -353,6 +382,8 @@ function isDraftStepValid(draft: PolicyDraft, step: number): boolean {
           draft.circuitBreakerRule.openSeconds > 0 &&
           draft.circuitBreakerRule.halfOpenMaxCalls > 0
         );
+      case 'concurrency_limit':
+        return draft.concurrencyLimitRule.maxConcurrent > 0;
       default:
         return false;
     }
@@ -419,6 +450,8 @@ function humanizePolicyEffect(policy: Policy): string {
       return `Terminate ${policy.rule.appliesTo === 'step' ? 'step' : 'external call'} after ${policy.rule.timeoutMs}ms.`;
     case 'circuit_breaker':
       return `Open circuit after ${policy.rule.failureThreshold} failures in ${policy.rule.windowSeconds}s and stay open for ${policy.rule.openSeconds}s.`;
+    case 'concurrency_limit':
+      return `Allow at most ${policy.rule.maxConcurrent} in-flight stages, keyed by ${policy.rule.keyBy}.`;
     default:
       return 'No effect summary available.';
   }
@@ -1100,7 +1133,7 @@ export default function Policies() {
                           ['Backoff strategy', selectedPolicy.rule.backoff],
                           ['Base delay', `${selectedPolicy.rule.baseDelayMs}ms`],
                           ['Max delay', selectedPolicy.rule.maxDelayMs ? `${selectedPolicy.rule.maxDelayMs}ms` : '—'],
-                          ['Jitter', selectedPolicy.rule.jitter ? 'Enabled' : 'Disabled'],
+                          ['Jitter', selectedPolicy.rule.jitter ? selectedPolicy.rule.jitterMode ?? 'full' : 'Disabled'],
                           ['Retryable HTTP status', (selectedPolicy.rule.retryOn?.httpStatus ?? []).join(', ') || '—'],
                           ['Retryable error codes', (selectedPolicy.rule.retryOn?.errorCodes ?? []).join(', ') || '—'],
                         ]}
@@ -1126,6 +1159,15 @@ export default function Policies() {
                         ]}
                       />
                     ) : null}
+
+                    {selectedPolicy.type === 'concurrency_limit' ? (
+                      <RuleList
+                        rows={[
+                          ['Max concurrent', String(selectedPolicy.rule.maxConcurrent)],
+                          ['Keying', selectedPolicy.rule.keyBy],
+                        ]}
+                      />
+                    ) : null}
                   </TabsContent>
 
                   <TabsContent value="applied" className="space-y-4 px-6 py-5">
@@ -1476,6 +1518,32 @@ export default function Policies() {
                       />
                     </div>
 
+                    {draft.retryRule.jitter ? (
+                      <div className="space-y-2">
+                        <Label>Jitter mode</Label>
+                        <Select
+                          value={draft.retryRule.jitterMode ?? 'full'}
+                          onValueChange={value =>
+                            setDraft(previous => ({
+                              ...previous,
+                              retryRule: {
+                                ...previous.retryRule,
+                                jitterMode: value as RetryRule['jitterMode'],
+                              },
+                            }))
+                          }
+                        >
+                          <SelectTrigger>
+                            <SelectValue />
+                          </SelectTrigger>
+                          <SelectContent>
+                            <SelectItem value="full">Full (random up to the delay)</SelectItem>
+                            <SelectItem value="decorrelated">Decorrelated (random from the previous delay)</SelectItem>
+                          </SelectContent>
+                        </Select>
+                      </div>
+                    ) : null}
+
                     <div className="grid gap-4 md:grid-cols-2">
                       <div className="space-y-2">
                         <Label>Retryable HTTP status (comma separated)</Label>
@@ -1621,6 +1689,49 @@ export default function Policies() {
                     />
                   </div>
                 ) : null}
+
+                {draft.type === 'concurrency_limit' ? (
+                  <div className="grid gap-4 md:grid-cols-2">
+                    <NumberField
+                      label="Max concurrent"
+                      value={draft.concurrencyLimitRule.maxConcurrent}
+                      onChange={value =>
+                        setDraft(previous => ({
+                          ...previous,
+                          concurrencyLimitRule: {
+                            ...previous.concurrencyLimitRule,
+                            maxConcurrent: value,
+                          },
+                        }))
+                      }
+                    />
+
+                    <div className="space-y-2">
+                      <Label>Key by</Label>
+                      <Select
+                        value={draft.concurrencyLimitRule.keyBy}
+                        onValueChange={value =>
+                          setDraft(previous => ({
+                            ...previous,
+                            concurrencyLimitRule: {
+                              ...previous.concurrencyLimitRule,
+                              keyBy: value as ConcurrencyLimitRule['keyBy'],
+                            },
+                          }))
+                        }
+                      >
+                        <SelectTrigger>
+                          <SelectValue />
+                        </SelectTrigger>
+                        <SelectContent>
+                          <SelectItem value="global">Global</SelectItem>
+                          <SelectItem value="handler">Per handler</SelectItem>
+                          <SelectItem value="tenant">Per tenant</SelectItem>
+                        </SelectContent>
+                      </Select>
+                    </div>
+                  </div>
+                ) : null}
               </div>
             ) : null}
 
@@ -1738,6 +1849,13 @@ export default function Policies() {
                       {previewMutation.data.pipelines} pipelines, {previewMutation.data.stages} stages, {previewMutation.data.handlers} handlers currently match.
                     </p>
                   ) : null}
+                  {previewMutation.data && previewMutation.data.total > previewMutation.data.pipelines ? (
+                    <p className="mt-1 text-xs text-muted-foreground">
+                      Of {previewMutation.data.total} pipelines, excluded by pipeline IDs: {previewMutation.data.excluded.pipelines},
+                      environment: {previewMutation.data.excluded.environment}, tags: {previewMutation.data.excluded.tags},
+                      stage filters: {previewMutation.data.excluded.stages}.
+                    </p>
+                  ) : null}
                 </div>
               </div>
             ) : null}
@@ -26,6 +26,9 @@ export interface PipelineResponse {
   stages?: StageResponse[];
   pipelineContextItems?: ContextItem[];
   pipelineKeywords?: PipelineKeyword[];
+  labels?: Record<string, string>;
+  /** 0 (default) to 9; higher-priority pipelines are dispatched first. */
+  priority?: number;
   isEvent?: boolean;
 }
 
@@ -41,12 +44,91 @@ export interface StageResponse {
   finishedAt?: string;
   startedAt?: string;
   output?: string;
+  /** Output exceeded the stored size cap; `output` holds only a prefix of it. */
+  outputTruncated?: boolean;
+  /** Original output size in bytes, set when the output was truncated. */
+  outputBytes?: number;
   input?: string;
   isSkipped?: boolean;
   isEvent?: boolean;
   nextStageId?: number;
   logs?: StageLog[];
   options?: StageOptions;
+  /** Why the stage last failed; cleared on success and rerun. */
+  failure?: StageFailure;
+}
+
+export type StageFailureCategory = 'timeout' | 'handler_error' | 'circuit_open' | 'cancelled';
+
+export interface StageFailure {
+  category: StageFailureCategory;
+  /** Finer cause, e.g. pending_timeout, run_timeout, empty_output, pipeline_cancelled. */
+  reason?: string;
+  ageSeconds?: number;
+  timeoutSeconds?: number;
+  at: string;
+}
+
+/** A stage run archived when the stage was rerun. */
+export interface StageExecutionHistoryEntry {
+  id: number;
+  attempt: number;
+  retryAttempt: number;
+  status?: StageStatus;
+  input?: string;
+  output?: string;
+  outputTruncated?: boolean;
+  startedAt?: string;
+  finishedAt?: string;
+  archivedAt: string;
+  source: string;
+}
+
+export interface StageExecutionHistoryResponse {
+  stageId: number;
+  items: StageExecutionHistoryEntry[];
+}
+
+export interface PipelineGraphNode {
+  id: number;
+  name: string;
+  stageHandlerName?: string;
+  status: string;
+  isEvent?: boolean;
+  isSkipped?: boolean;
+  inCycle?: boolean;
+  frontier?: boolean;
+}
+
+export interface PipelineGraphEdge {
+  from: number;
+  to: number;
+  kind: 'dependsOn' | 'runInParallelWith' | 'sequence';
+}
+
+export interface PipelineGraph {
+  pipelineId: number;
+  mode: 'dag' | 'sequential';
+  nodes: PipelineGraphNode[];
+  edges: PipelineGraphEdge[];
+  frontier: number[];
+  cycles?: number[][];
+}
+
+export interface PipelineTimelineEvent {
+  kind: 'pipeline' | 'stage';
+  stageId?: number;
+  stageName?: string;
+  fromStatus?: string;
+  toStatus: string;
+  source: string;
+  at: string;
+}
+
+export interface PipelineTimelineResponse {
+  pipelineId: number;
+  events: PipelineTimelineEvent[];
+  truncated?: boolean;
 }
 
 export interface StageLog {
@@ -62,11 +144,14 @@ export interface StageOptions {
   retryInterval?: number;
   timeOut?: number;
   maxRetries?: number;
+  maxAttempts?: number;
   dependsOn?: string[];
   runInParallelWith?: string[];
   failIfOutputEmpty?: boolean;
   notifyOnFailure?: boolean;
   runAsUser?: string;
+  onTimeout?: 'fail' | 'retry';
+  expectedDurationMs?: number;
 }
 
 export interface ContextItem {
@@ -93,12 +178,18 @@ export interface GetPipelinesParams {
   pageSize?: number;
   applicationId?: number;
   search?: string;
+  traceId?: string;
   keywords?: string[];
+  keywordMatch?: 'any' | 'all';
+  /** key=value pairs; a pipeline must carry all of them. */
+  labels?: string[];
   statuses?: string[];
   pipelineStartFrom?: string;
   pipelineStartTo?: string;
   pipelineEndFrom?: string;
   pipelineEndTo?: string;
+  sortBy?: 'createdAt' | 'finishedAt' | 'duration' | 'status';
+  sortDir?: 'asc' | 'desc';
 }
 
 // Stage actions
@@ -116,9 +207,27 @@ export interface ApplicationResponse {
   id: number;
   name: string;
   description?: string;
+  disabledAt?: string;
   apiKeys?: ApiKeyResponse[];
 }
 
+export interface PipelineStatsPoint {
+  start: string;
+  count: number;
+}
+
+export interface PipelineStatsResponse {
+  applicationId: number;
+  range: string;
+  since: string;
+  total: number;
+  countsByStatus: Record<string, number>;
+  successRate: number | null;
+  avgDurationMs: number | null;
+  bucketSeconds: number;
+  executions: PipelineStatsPoint[];
+}
+
 export interface SaveApplicationRequest {
   id?: number;
   name: string;
@@ -137,6 +246,15 @@ export interface ApiKeyResponse {
   lastUsed?: string;
 }
 
+export interface ExpiringApiKeyResponse {
+  id: number;
+  applicationId: number;
+  applicationName: string;
+  name?: string;
+  expiresAt: string;
+  daysRemaining: number;
+}
+
 export interface GenerateApiKeyRequest {
   apiKeyId?: number;
   applicationId?: number;
@@ -162,6 +280,58 @@ export type WorkerState =
   | 'error'
   | 'offline';
 
+export interface RuntimeConfig {
+  appId: string;
+  logging: {
+    level: string;
+    format: string;
+    accessLogLevel: string;
+    accessLogBodySize: boolean;
+  };
+  broker: {
+    prefetch: number;
+    topologyOwnership: string;
+    dlqEnabled: boolean;
+    dlqTtlSec: number;
+    queueType?: string;
+    maxLength?: number;
+    overflow?: string;
+    resultQueueShards: number;
+    publisherConfirms: boolean;
+    exposeUrl: boolean;
+    workerCredentials: boolean;
+    reconnectMaxIntervalSec: number;
+    reconnectJitter: number;
+  };
+  gateway: {
+    visibilityTimeoutSec: number;
+    maxInFlight: number;
+    pullPrefetch: number;
+    queuePrefetch?: Record<string, number>;
+  };
+  workers: {
+    heartbeatIntervalSec: number;
+    offlineAfterSec: number;
+    sessionTtlSec: number;
+    eventsMaxBatch: number;
+    listStaleAfterSec: number;
+    ingestMaxBodyBytes: number;
+    grpcEnabled: boolean;
+  };
+  externalApi: {
+    rateLimitRps: number;
+    rateLimitBurst: number;
+    rateLimitStore: string;
+    pipelineStrictHandlers: boolean;
+    apiKeyExpiryWarnWithinSec: number;
+  };
+  policies: {
+    targetOptionsLimit: number;
+    targetOptionsCacheTtlSec: number;
+    previewEnvMatch: string;
+  };
+}
+
 export interface WorkerStatusResponse {
   id: string;
   applicationId: number;
@@ -193,6 +363,8 @@ export interface WorkerStatusResponse {
   supportedHandlers?: string[];
   capabilities?: Record<string, unknown>;
   metadata?: Record<string, unknown>;
+  configVersion?: string;
+  configOutdated: boolean;
 }
 
 export interface WorkerStatusListResponse {
@@ -204,6 +376,85 @@ export interface WorkerStatusListResponse {
   offlineAfterSec: number;
 }
 
+export interface WorkerHeartbeatPoint {
+  ts: string;
+  state: string;
+  brokerConnected: boolean;
+  inFlightJobs: number;
+  jobsProcessed: number;
+  jobsFailed: number;
+  queueLag?: number;
+  cpuPercent?: number;
+  memoryMb?: number;
+}
+
+export interface WorkerDetailResponse extends WorkerStatusResponse {
+  heartbeats: WorkerHeartbeatPoint[];
+  offlineAfterSec: number;
+}
+
+export interface WorkerBootstrapConfig {
+  workerId: string;
+  configVersion: string;
+  application: { applicationId: number; applicationName: string; appId: string };
+  messageBroker: {
+    type: string;
+    connectionString: string;
+    prefetch: number;
+    topologyOwnership: string;
+    dlqEnabled: boolean;
+    dlqTtlSec: number;
+    queueType?: string;
+    maxLength?: number;
+    overflow?: string;
+  };
+  queues: {
+    stageResult: string;
+    stageResultShards: number;
+    stageResultPattern?: string;
+    stageSetStatus: string;
+    stageUpdatedFanout: string;
+    stageNextPattern: string;
+    stageNextEnvPattern?: string;
+  };
+  heartbeat: { intervalSec: number; offlineAfterSec: number };
+  observability: { traceLinkTemplate?: string; logsLinkTemplate?: string };
+}
+
+export interface WorkerConfigResponse {
+  workerId: string;
+  bootstrappedAt?: string;
+  config: WorkerBootstrapConfig;
+}
+
+export interface HandlerCapacity {
+  applicationId: number;
+  applicationName: string;
+  handler: string;
+  onlineWorkers: number;
+  maxConcurrency?: number;
+  inFlightJobs: number;
+  dispatchedStages: number;
+  utilization?: number;
+}
+
+export interface HandlerCapacityListResponse {
+  items: HandlerCapacity[];
+  offlineAfterSec: number;
+}
+
+export interface HandlerRegistryEntry {
+  handler: string;
+  onlineWorkers: number;
+  seenInStages: boolean;
+  lastStageAt?: string;
+}
+
+export interface HandlerRegistryResponse {
+  items: HandlerRegistryEntry[];
+  offlineAfterSec: number;
+}
+
 export interface WorkerEventResponse {
   id: number;
   workerId: string;
@@ -217,9 +468,17 @@ export interface WorkerEventResponse {
   details?: Record<string, unknown>;
 }
 
+export interface WorkerEventListResponse {
+  items: WorkerEventResponse[];
+  /** Pass as `before` to fetch older events; absent on the last page. */
+  nextCursor?: string;
+  /** Pass as `after` to fetch events newer than this page. */
+  prevCursor?: string;
+}
+
 // Status types
-export type PipelineStatus = 'NotStarted' | 'Running' | 'Completed' | 'Failed';
-export type StageStatus = 'NotStarted' | 'Running' | 'Pending' | 'RetryScheduled' | 'Completed' | 'Failed' | 'Skipped';
+export type PipelineStatus = 'NotStarted' | 'Running' | 'Completed' | 'Failed' | 'Cancelled';
+export type StageStatus = 'NotStarted' | 'Running' | 'Pending' | 'RetryScheduled' | 'Completed' | 'Failed' | 'Skipped' | 'Cancelled';
 
 // UI status mapping (map backend status to UI status)
 export type UIStatus = 'success' | 'error' | 'running' | 'waiting' | 'throttled' | 'paused' | 'queued' | 'skipped';
@@ -232,6 +491,8 @@ export function mapPipelineStatusToUI(status: PipelineStatus): UIStatus {
       return 'error';
     case 'Running':
       return 'running';
+    case 'Cancelled':
+      return 'skipped';
     case 'NotStarted':
       return 'queued';
     default:
@@ -252,6 +513,7 @@ export function mapStageStatusToUI(status?: StageStatus): UIStatus {
     case 'RetryScheduled':
       return 'waiting';
     case 'Skipped':
+    case 'Cancelled':
       return 'skipped';
     case 'NotStarted':
       return 'queued';
@@ -1,5 +1,5 @@
-// Integration status lifecycle: not_configured → configured → connected ↔ disconnected / error
-export type IntegrationStatus = 'not_configured' | 'configured' | 'connected' | 'disconnected' | 'error';
+// Integration status lifecycle: not_configured → configured → connected ↔ stale / disconnected / error
+export type IntegrationStatus = 'not_configured' | 'configured' | 'connected' | 'stale' | 'disconnected' | 'error';
 
 export type IntegrationType = 'opentelemetry' | 'alerting' | 'grafana' | 'sentry' | 'datadog' | 'graylog';
 
@@ -24,10 +24,12 @@ export type AlertChannel =
 
 export type AlertEvent =
   | 'stage_failed'
+  | 'stage_timeout_retry'
   | 'stage_rerun_manual'
   | 'stage_skipped_manual'
   | 'pipeline_failed'
   | 'pipeline_stuck'
+  | 'stage_sla_breach'
   | 'worker_started'
   | 'worker_failed'
   | 'worker_stopped'
@@ -35,13 +37,32 @@ export type AlertEvent =
   | 'policy_triggered'
   | 'policy_changed'
   | 'queue_backlog_high'
-  | 'dlq_message_detected';
+  | 'dlq_message_detected'
+  | 'api_key_expiring';
+
+export interface AlertWebhookEndpoint {
+  url: string;
+  /** Label in the delivery history; defaults to the URL host. */
+  name?: string;
+  /** Signs requests to this endpoint; overrides webhookSecret. */
+  secret?: string;
+}
 
 export interface AlertingConfig {
   channels: AlertChannel[];
   enabledEvents: AlertEvent[];
   sendResolved: boolean;
   dedupeWindowSeconds: number;
+  dedupeWindowSecondsByEvent?: Partial<Record<AlertEvent, number>>;
+  maxAlertsPerMinute?: number;
+  maxAlertsPerMinuteByEvent?: Partial<Record<AlertEvent, number>>;
+  /** Retries per channel after a failed send (default 2). */
+  sendMaxRetries?: number;
+  /** Timeout of each send attempt in seconds (default 4). */
+  sendTimeoutSeconds?: number;
+  /** Collects worker_heartbeat_lost alerts per application this long and sends one summary (default 0, off). */
+  workerOfflineAggregateSeconds?: number;
+  messageTemplates?: Partial<Record<AlertChannel, string>>;
   healthEndpoint?: string;
   telegramBotToken?: string;
   telegramChatId?: string;
@@ -49,6 +70,10 @@ export interface AlertingConfig {
   slackWebhookUrl?: string;
   teamsWebhookUrl?: string;
   webhookUrl?: string;
+  /** Signs webhook requests with HMAC-SHA256 (X-Pipelogiq-Signature). */
+  webhookSecret?: string;
+  /** Additional webhook endpoints; each is sent every alert. */
+  webhooks?: AlertWebhookEndpoint[];
   emailRecipients?: string; // comma-separated
   pagerdutyRoutingKey?: string;
 }
@@ -65,6 +90,8 @@ export interface SentryConfig {
 export interface DatadogConfig {
   site: string; // e.g. datadoghq.com
   apiKey: string;
+  sendLogs?: boolean; // forward stage and worker events as logs
+  tags?: string[]; // extra tags such as env:prod
 }
 
 export interface LogsConfig {
@@ -102,6 +129,7 @@ export interface ObservabilityConfig {
 // GET /api/observability/status
 export interface ObservabilityStatus {
   otel: {
+    status: IntegrationStatus;
     configured: boolean;
     connected: boolean;
     lastSuccessExportAt?: string;
@@ -110,17 +138,20 @@ export interface ObservabilityStatus {
     lastError?: string;
   };
   prometheus: {
+    status: IntegrationStatus;
     configured: boolean;
     connected: boolean;
     scrapeEndpoint?: string;
     lastScrapeAt?: string;
   };
   logs: {
+    status: IntegrationStatus;
     configured: boolean;
     provider?: string;
     linkTemplateConfigured: boolean;
   };
   alerting: {
+    status: IntegrationStatus;
     configured: boolean;
     channels: string[];
     events: string[];
@@ -145,6 +176,37 @@ export interface TraceEntry {
   timestamp: string;
 }
 
+// GET /api/observability/alerts/history
+export interface AlertDeliveryEntry {
+  id: number;
+  event: string;
+  channel?: string;
+  dedupeKey?: string;
+  status: 'sent' | 'failed' | 'suppressed';
+  /** Why a suppressed alert was not sent: dedupe, rate_limit or startup_grace. */
+  reason?: string;
+  error?: string;
+  attempts: number;
+  timestamp: string;
+}
+
+export interface QueueStats {
+  queue: string;
+  handler?: string;
+  /** Set for the per-environment StageNext queues. */
+  environment?: string;
+  messages: number;
+  consumers: number;
+  /** Set when dead-lettering is enabled. */
+  dlq?: string;
+  dlqMessages: number;
+}
+
+export interface QueueStatsResponse {
+  queues: QueueStats[];
+  collectedAt: string;
+}
+
 // GET /api/observability/insights
 export interface SlowestStage {
   pipelineName: string;
@@ -157,6 +219,20 @@ export interface ErrorHotspot {
   stageName: string;
   failureRate: number; // 0-100
   avgRetries: number;
+  /** Failed runs by failure category; "unknown" for runs before categories were recorded. */
+  failuresByCategory?: Record<string, number>;
+}
+
+/** Runs of one stage handler aggregated across every pipeline that uses it. */
+export interface HandlerInsight {
+  handlerName: string;
+  runs: number;
+  pipelines: number;
+  avgMs: number;
+  p95Ms: number;
+  failureRate: number; // 0-100
+  avgRetries: number;
+  failuresByCategory?: Record<string, number>;
 }
 
 export interface InsightsSummary {
@@ -169,6 +245,8 @@ export interface InsightsSummary {
 export interface ObservabilityInsights {
   slowestStages: SlowestStage[];
   errorHotspots: ErrorHotspot[];
+  slowestHandlers: HandlerInsight[];
+  errorProneHandlers: HandlerInsight[];
   summary: InsightsSummary;
 }
 
@@ -1,4 +1,4 @@
-export type PolicyType = 'rate_limit' | 'retry' | 'timeout' | 'circuit_breaker';
+export type PolicyType = 'rate_limit' | 'retry' | 'timeout' | 'circuit_breaker' | 'concurrency_limit';
 
 export type PolicyStatus = 'active' | 'paused' | 'disabled';
 
@@ -10,6 +10,20 @@ export interface PolicyTargeting {
   handlers: string[];
   tagsInclude: string[];
   tagsExclude: string[];
+  schedule?: PolicySchedule;
+}
+
+export type PolicyScheduleDay = 'mon' | 'tue' | 'wed' | 'thu' | 'fri' | 'sat' | 'sun';
+
+export interface PolicyScheduleWindow {
+  days?: PolicyScheduleDay[];
+  start: string;
+  end: string;
+}
+
+export interface PolicySchedule {
+  timezone: string;
+  windows: PolicyScheduleWindow[];
 }
 
 export interface RateLimitRule {
@@ -25,6 +39,7 @@ export interface RetryRule {
   baseDelayMs: number;
   maxDelayMs?: number;
   jitter?: boolean;
+  jitterMode?: 'full' | 'decorrelated';
   retryOn?: {
     httpStatus?: number[];
     errorCodes?: string[];
@@ -34,6 +49,7 @@ export interface RetryRule {
 export interface TimeoutRule {
   timeoutMs: number;
   appliesTo: 'step' | 'external_call';
+  onTimeout?: 'fail' | 'retry';
 }
 
 export interface CircuitBreakerRule {
@@ -43,12 +59,18 @@ export interface CircuitBreakerRule {
   halfOpenMaxCalls: number;
 }
 
+export interface ConcurrencyLimitRule {
+  maxConcurrent: number;
+  keyBy: 'global' | 'handler' | 'tenant';
+}
+
 interface BasePolicy {
   id: string;
   name: string;
   description?: string;
   status: PolicyStatus;
   environment: PolicyEnvironment;
+  priority: number;
   targeting: PolicyTargeting;
   createdAt: string;
   createdBy: string;
@@ -61,7 +83,8 @@ export type Policy =
   | (BasePolicy & { type: 'rate_limit'; rule: RateLimitRule })
   | (BasePolicy & { type: 'retry'; rule: RetryRule })
   | (BasePolicy & { type: 'timeout'; rule: TimeoutRule })
-  | (BasePolicy & { type: 'circuit_breaker'; rule: CircuitBreakerRule });
+  | (BasePolicy & { type: 'circuit_breaker'; rule: CircuitBreakerRule })
+  | (BasePolicy & { type: 'concurrency_limit'; rule: ConcurrencyLimitRule });
 
 export type PolicyRule = Policy['rule'];
 
@@ -87,16 +110,20 @@ export interface PolicyEvent {
 export type PolicyListItem = Policy & {
   lastTriggeredAt?: string;
   triggerCountInRange: number;
+  inactiveBySchedule: boolean;
 };
 
 export interface PolicyListResponse {
   items: PolicyListItem[];
   totalCount: number;
+  limit: number;
+  offset: number;
 }
 
 export type PolicyDetailResponse = Policy & {
   lastTriggeredAt?: string;
   triggerCountInRange: number;
+  inactiveBySchedule: boolean;
 };
 
 export interface PolicyAuditResponse {
@@ -112,6 +139,7 @@ export interface PolicyInsightsTopPolicy {
 
 export interface PolicyInsightsResponse {
   activePoliciesCount: number;
+  inactiveByScheduleCount: number;
   policiesTriggered: number;
   actionsBlockedThrottled: number;
   topPolicy?: PolicyInsightsTopPolicy;
@@ -128,17 +156,31 @@ export interface PolicyTargetOptionsResponse {
   stages: string[];
   handlers: string[];
   tags: string[];
+  /** When the lists were read; the server caches them for a few seconds. */
+  generatedAt: string;
 }
 
 export interface PolicyPreviewRequest {
   environment: PolicyEnvironment;
   targeting: PolicyTargeting;
+  /** How inferred environments are compared; defaults to the server setting. */
+  environmentMatch?: 'exact' | 'contains';
+}
+
+/** Pipelines left out of a preview, by the first filter each one failed. */
+export interface PolicyPreviewExclusions {
+  pipelines: number;
+  environment: number;
+  tags: number;
+  stages: number;
 }
 
 export interface PolicyPreviewResponse {
   pipelines: number;
   stages: number;
   handlers: number;
+  total: number;
+  excluded: PolicyPreviewExclusions;
 }
 
 export type PolicyRange = '15m' | '1h' | '24h' | '7d';
@@ -152,12 +194,16 @@ export interface ListPoliciesParams {
   range?: PolicyRange;
   sortBy?: 'triggers' | 'lastTriggered' | 'updatedAt';
   sortDir?: 'asc' | 'desc';
+  limit?: number;
+  offset?: number;
 }
 
-export type CreatePolicyRequest = Omit<Policy, 'id' | 'createdAt' | 'createdBy' | 'updatedAt' | 'updatedBy' | 'version'> & {
+export type CreatePolicyRequest = Omit<Policy, 'id' | 'createdAt' | 'createdBy' | 'updatedAt' | 'updatedBy' | 'version' | 'priority'> & {
   status?: PolicyStatus;
+  priority?: number;
 };
 
-export type UpdatePolicyRequest = Omit<Policy, 'id' | 'createdAt' | 'createdBy' | 'updatedAt' | 'updatedBy' | 'version'> & {
+export type UpdatePolicyRequest = Omit<Policy, 'id' | 'createdAt' | 'createdBy' | 'updatedAt' | 'updatedBy' | 'version' | 'priority'> & {
   status?: PolicyStatus;
+  priority?: number;
 };